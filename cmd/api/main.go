@@ -13,7 +13,9 @@ import (
 	"github.com/jonwraymond/prompt-alchemy/internal/domain/prompt"
 	"github.com/jonwraymond/prompt-alchemy/internal/engine"
 	"github.com/jonwraymond/prompt-alchemy/internal/learning"
+	"github.com/jonwraymond/prompt-alchemy/internal/log"
 	"github.com/jonwraymond/prompt-alchemy/internal/observability/metrics"
+	"github.com/jonwraymond/prompt-alchemy/internal/observability/tracing"
 	"github.com/jonwraymond/prompt-alchemy/internal/ranking"
 	"github.com/jonwraymond/prompt-alchemy/internal/storage"
 	"github.com/jonwraymond/prompt-alchemy/pkg/providers"
@@ -72,27 +74,48 @@ func main() {
 		logger.WithError(err).Fatal("Failed to initialize metrics")
 	}
 
+	// Initialize tracing
+	tracingConfig := tracing.Config{
+		Enabled:      viper.GetBool("tracing.enabled"),
+		OTLPEndpoint: viper.GetString("tracing.otlp_endpoint"),
+		Insecure:     viper.GetBool("tracing.insecure"),
+		ServiceName:  viper.GetString("tracing.service_name"),
+		SampleRatio:  viper.GetFloat64("tracing.sample_ratio"),
+	}
+	appTracing, err := tracing.NewTracing(ctx, tracingConfig, logger)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to initialize tracing")
+	}
+
 	// Initialize storage
 	storage, err := initStorage(ctx, logger)
 	if err != nil {
 		logger.WithError(err).Fatal("Failed to initialize storage")
 	}
 	defer storage.Close()
+	storage.SetRetryObserver(appMetrics.RecordStorageRetry)
 
 	// Initialize provider registry
 	registry := providers.NewRegistry()
+	registry.SetRateLimitObserver(appMetrics.SetProviderRateLimitUtilization)
+	registry.SetEmbeddingCacheObserver(appMetrics.RecordEmbeddingCacheLookup)
 	if err := registerProviders(registry, logger); err != nil {
 		logger.WithError(err).Fatal("Failed to register providers")
 	}
+	registry.StartHealthChecks(ctx, providers.LoadHealthConfig())
 
 	// Initialize engine
 	engine := engine.NewEngine(registry, logger)
 	engine.SetStorage(storage)
+	engine.SetContextRetriever(storage)
+	engine.SetInFlightObserver(appMetrics.SetGenerationsInFlight)
+	engine.SetGenerationObserver(appMetrics)
 
 	// Initialize ranker (optional)
 	var ranker *ranking.Ranker
 	if viper.GetBool("ranking.enabled") {
 		ranker = ranking.NewRanker(storage, registry, logger)
+		engine.SetShadowScorer(ranker)
 		logger.Info("Ranking system initialized")
 	}
 
@@ -108,13 +131,18 @@ func main() {
 
 	// Setup router configuration
 	routerConfig := v1.RouterConfig{
-		EnableCORS:      viper.GetBool("http.enable_cors"),
-		CORSOrigins:     viper.GetStringSlice("http.cors_origins"),
-		EnableAuth:      viper.GetBool("http.enable_auth"),
-		APIKeys:         viper.GetStringSlice("http.api_keys"),
-		EnableRateLimit: viper.GetBool("http.enable_rate_limit"),
-		RequestsPerMin:  viper.GetInt("http.rate_limit.requests_per_minute"),
-		Burst:           viper.GetInt("http.rate_limit.burst"),
+		EnableCORS:       viper.GetBool("http.enable_cors"),
+		CORSOrigins:      viper.GetStringSlice("http.cors_origins"),
+		EnableAuth:       viper.GetBool("http.enable_auth"),
+		APIKeys:          viper.GetStringSlice("http.api_keys"),
+		EnableRateLimit:  viper.GetBool("http.enable_rate_limit"),
+		RequestsPerMin:   viper.GetInt("http.rate_limit.requests_per_minute"),
+		Burst:            viper.GetInt("http.rate_limit.burst"),
+		RateLimitBackend: viper.GetString("http.rate_limit.backend"),
+		MaxBodyBytes:     viper.GetInt64("http.max_body_bytes"),
+
+		MaxConcurrentGenerations: viper.GetInt("generation.max_concurrent"),
+		GenerationQueueTimeout:   viper.GetDuration("generation.queue_timeout"),
 	}
 
 	// Set defaults for rate limiting
@@ -206,6 +234,9 @@ func main() {
 	} else {
 		logger.Info("Server exited gracefully")
 	}
+	if err := appTracing.Shutdown(shutdownCtx); err != nil {
+		logger.WithError(err).Warn("Failed to flush traces")
+	}
 }
 
 // initConfig initializes application configuration
@@ -230,12 +261,27 @@ func initConfig() error {
 	viper.SetDefault("http.enable_rate_limit", true)
 	viper.SetDefault("http.rate_limit.requests_per_minute", 60)
 	viper.SetDefault("http.rate_limit.burst", 100)
+	viper.SetDefault("http.rate_limit.backend", "memory")
+
+	viper.SetDefault("embeddings.cache.size", 1000)
+	viper.SetDefault("embeddings.cache.ttl", "1h")
+
+	viper.SetDefault("maintenance.decay_rate", 0.01)
+	viper.SetDefault("maintenance.max_prompts", 10000)
+	viper.SetDefault("maintenance.min_relevance_score", 0.1)
+	viper.SetDefault("maintenance.min_age", "168h")
 
 	viper.SetDefault("metrics.enabled", true)
 	viper.SetDefault("metrics.path", "/metrics")
 	viper.SetDefault("metrics.namespace", "prompt_alchemy")
 	viper.SetDefault("metrics.subsystem", "api")
 
+	viper.SetDefault("tracing.enabled", false)
+	viper.SetDefault("tracing.otlp_endpoint", tracing.DefaultConfig().OTLPEndpoint)
+	viper.SetDefault("tracing.insecure", tracing.DefaultConfig().Insecure)
+	viper.SetDefault("tracing.service_name", tracing.DefaultConfig().ServiceName)
+	viper.SetDefault("tracing.sample_ratio", tracing.DefaultConfig().SampleRatio)
+
 	viper.SetDefault("ranking.enabled", false)
 	viper.SetDefault("learning.enabled", false)
 
@@ -277,6 +323,7 @@ func initLogger() *logrus.Logger {
 			FullTimestamp: true,
 		})
 	}
+	log.SetIncludeCaller(logger, viper.GetBool("log.include_caller"))
 
 	return logger
 }
@@ -379,6 +426,100 @@ func registerProviders(registry *providers.Registry, logger *logrus.Logger) erro
 		logger.Info("Registered Grok provider")
 	}
 
+	// Register Mistral provider
+	if apiKey := viper.GetString("providers.mistral.api_key"); apiKey != "" {
+		config := providers.Config{
+			APIKey:         apiKey,
+			Model:          viper.GetString("providers.mistral.model"),
+			EmbeddingModel: viper.GetString("providers.mistral.embedding_model"),
+			BaseURL:        viper.GetString("providers.mistral.base_url"),
+			Timeout:        int(viper.GetDuration("providers.mistral.timeout").Seconds()),
+		}
+		provider := providers.NewMistralProvider(config)
+		registry.Register(providers.ProviderMistral, provider)
+		logger.Info("Registered Mistral provider")
+	}
+
+	// Register DeepSeek provider
+	if apiKey := viper.GetString("providers.deepseek.api_key"); apiKey != "" {
+		config := providers.Config{
+			APIKey:  apiKey,
+			Model:   viper.GetString("providers.deepseek.model"),
+			BaseURL: viper.GetString("providers.deepseek.base_url"),
+			Timeout: int(viper.GetDuration("providers.deepseek.timeout").Seconds()),
+		}
+		provider := providers.NewDeepSeekProvider(config)
+		registry.Register(providers.ProviderDeepSeek, provider)
+		logger.Info("Registered DeepSeek provider")
+	}
+
+	// Register Cohere provider
+	if apiKey := viper.GetString("providers.cohere.api_key"); apiKey != "" {
+		config := providers.Config{
+			APIKey:         apiKey,
+			Model:          viper.GetString("providers.cohere.model"),
+			EmbeddingModel: viper.GetString("providers.cohere.embedding_model"),
+			RerankModel:    viper.GetString("providers.cohere.rerank_model"),
+			BaseURL:        viper.GetString("providers.cohere.base_url"),
+			Timeout:        int(viper.GetDuration("providers.cohere.timeout").Seconds()),
+		}
+		provider := providers.NewCohereProvider(config)
+		registry.Register(providers.ProviderCohere, provider)
+		logger.Info("Registered Cohere provider")
+	}
+
+	// Register Perplexity provider
+	if apiKey := viper.GetString("providers.perplexity.api_key"); apiKey != "" {
+		config := providers.Config{
+			APIKey:  apiKey,
+			Model:   viper.GetString("providers.perplexity.model"),
+			BaseURL: viper.GetString("providers.perplexity.base_url"),
+			Timeout: int(viper.GetDuration("providers.perplexity.timeout").Seconds()),
+		}
+		provider := providers.NewPerplexityProvider(config)
+		registry.Register(providers.ProviderPerplexity, provider)
+		logger.Info("Registered Perplexity provider")
+	}
+
+	// Register Azure OpenAI provider
+	if apiKey := viper.GetString("providers.azure.api_key"); apiKey != "" {
+		config := providers.Config{
+			APIKey:              apiKey,
+			Endpoint:            viper.GetString("providers.azure.endpoint"),
+			Deployment:          viper.GetString("providers.azure.deployment"),
+			EmbeddingDeployment: viper.GetString("providers.azure.embedding_deployment"),
+			APIVersion:          viper.GetString("providers.azure.api_version"),
+			Timeout:             int(viper.GetDuration("providers.azure.timeout").Seconds()),
+		}
+		provider := providers.NewAzureOpenAIProvider(config)
+		registry.Register(providers.ProviderAzure, provider)
+		logger.Info("Registered Azure OpenAI provider")
+	}
+
+	// Register AWS Bedrock provider
+	if region := viper.GetString("providers.bedrock.region"); region != "" {
+		config := providers.Config{
+			Region:         region,
+			Model:          viper.GetString("providers.bedrock.model"),
+			EmbeddingModel: viper.GetString("providers.bedrock.embedding_model"),
+			BaseURL:        viper.GetString("providers.bedrock.base_url"),
+			ModelMap:       viper.GetStringMapString("providers.bedrock.model_map"),
+			Timeout:        int(viper.GetDuration("providers.bedrock.timeout").Seconds()),
+		}
+		provider := providers.NewBedrockProvider(config)
+		registry.Register(providers.ProviderBedrock, provider)
+		logger.Info("Registered AWS Bedrock provider")
+	}
+
+	// Register custom OpenAI-compatible providers
+	names, err := providers.RegisterCustomProviders(registry)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		logger.WithField("provider", name).Info("Registered custom OpenAI-compatible provider")
+	}
+
 	// Check if at least one provider is registered
 	if len(registry.ListProviders()) == 0 {
 		logger.Warn("No providers registered - API will have limited functionality")
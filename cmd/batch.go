@@ -34,43 +34,6 @@ var (
 	batchSkipErrors  bool
 )
 
-// BatchInput represents a single batch generation request
-type BatchInput struct {
-	ID          string            `json:"id" csv:"id"`
-	Input       string            `json:"input" csv:"input"`
-	Phases      string            `json:"phases,omitempty" csv:"phases"`
-	Count       int               `json:"count,omitempty" csv:"count"`
-	Temperature float64           `json:"temperature,omitempty" csv:"temperature"`
-	MaxTokens   int               `json:"max_tokens,omitempty" csv:"max_tokens"`
-	Tags        string            `json:"tags,omitempty" csv:"tags"`
-	Provider    string            `json:"provider,omitempty" csv:"provider"`
-	Persona     string            `json:"persona,omitempty" csv:"persona"`
-	Metadata    map[string]string `json:"metadata,omitempty" csv:"-"`
-}
-
-// BatchResult represents the result of a batch generation
-type BatchResult struct {
-	ID        string          `json:"id"`
-	Input     BatchInput      `json:"input"`
-	Success   bool            `json:"success"`
-	Error     string          `json:"error,omitempty"`
-	Prompts   []models.Prompt `json:"prompts,omitempty"`
-	Duration  time.Duration   `json:"duration"`
-	Timestamp time.Time       `json:"timestamp"`
-}
-
-// BatchSummary provides overall batch operation statistics
-type BatchSummary struct {
-	TotalInputs     int           `json:"total_inputs"`
-	SuccessfulJobs  int           `json:"successful_jobs"`
-	FailedJobs      int           `json:"failed_jobs"`
-	TotalPrompts    int           `json:"total_prompts"`
-	TotalDuration   time.Duration `json:"total_duration"`
-	AverageDuration time.Duration `json:"average_duration"`
-	StartTime       time.Time     `json:"start_time"`
-	EndTime         time.Time     `json:"end_time"`
-}
-
 // batchCmd represents the batch command
 var batchCmd = &cobra.Command{
 	Use:   "batch",
@@ -188,7 +151,7 @@ func detectInputFormat(filename string) string {
 	}
 }
 
-func parseBatchInputs(filename, format string) ([]BatchInput, error) {
+func parseBatchInputs(filename, format string) ([]models.BatchInput, error) {
 	logger := log.GetLogger()
 	logger.Debugf("Parsing batch inputs from %s (format: %s)", filename, format)
 
@@ -214,8 +177,8 @@ func parseBatchInputs(filename, format string) ([]BatchInput, error) {
 	}
 }
 
-func parseJSONInputs(file *os.File) ([]BatchInput, error) {
-	var inputs []BatchInput
+func parseJSONInputs(file *os.File) ([]models.BatchInput, error) {
+	var inputs []models.BatchInput
 	decoder := json.NewDecoder(file)
 
 	if err := decoder.Decode(&inputs); err != nil {
@@ -225,7 +188,7 @@ func parseJSONInputs(file *os.File) ([]BatchInput, error) {
 	return inputs, nil
 }
 
-func parseCSVInputs(file *os.File) ([]BatchInput, error) {
+func parseCSVInputs(file *os.File) ([]models.BatchInput, error) {
 	reader := csv.NewReader(file)
 	records, err := reader.ReadAll()
 	if err != nil {
@@ -238,10 +201,10 @@ func parseCSVInputs(file *os.File) ([]BatchInput, error) {
 
 	// Parse header
 	header := records[0]
-	var inputs []BatchInput
+	var inputs []models.BatchInput
 
 	for i, record := range records[1:] {
-		input := BatchInput{
+		input := models.BatchInput{
 			ID: fmt.Sprintf("csv_%d", i+1),
 		}
 
@@ -292,8 +255,8 @@ func parseCSVInputs(file *os.File) ([]BatchInput, error) {
 	return inputs, nil
 }
 
-func parseTextInputs(file *os.File) ([]BatchInput, error) {
-	var inputs []BatchInput
+func parseTextInputs(file *os.File) ([]models.BatchInput, error) {
+	var inputs []models.BatchInput
 	scanner := bufio.NewScanner(file)
 	lineNum := 0
 
@@ -304,7 +267,7 @@ func parseTextInputs(file *os.File) ([]BatchInput, error) {
 		}
 
 		lineNum++
-		inputs = append(inputs, BatchInput{
+		inputs = append(inputs, models.BatchInput{
 			ID:    fmt.Sprintf("text_%d", lineNum),
 			Input: line,
 		})
@@ -317,7 +280,7 @@ func parseTextInputs(file *os.File) ([]BatchInput, error) {
 	return inputs, nil
 }
 
-func validateBatchInputs(inputs []BatchInput) error {
+func validateBatchInputs(inputs []models.BatchInput) error {
 	logger := log.GetLogger()
 	logger.Debug("Validating batch inputs")
 
@@ -356,7 +319,7 @@ func validateBatchInputs(inputs []BatchInput) error {
 	return nil
 }
 
-func runDryRun(inputs []BatchInput) error {
+func runDryRun(inputs []models.BatchInput) error {
 	logger := log.GetLogger()
 	logger.Info("Running dry-run validation")
 
@@ -364,7 +327,7 @@ func runDryRun(inputs []BatchInput) error {
 	_ = providers.NewRegistry()
 
 	validProviders := make(map[string]bool)
-	for name := range viper.GetStringMap("providers") {
+	for name := range configuredProviders() {
 		validProviders[name] = true
 	}
 
@@ -389,7 +352,7 @@ func runDryRun(inputs []BatchInput) error {
 		}
 
 		// Apply defaults and validate final parameters
-		finalInput := applyBatchDefaults(input)
+		finalInput := engine.ApplyBatchDefaults(input)
 		if finalInput.Count == 0 {
 			finalInput.Count = 3
 		}
@@ -417,7 +380,7 @@ func runDryRun(inputs []BatchInput) error {
 	return nil
 }
 
-func processBatch(inputs []BatchInput) error {
+func processBatch(inputs []models.BatchInput) error {
 	logger := log.GetLogger()
 	startTime := time.Now()
 
@@ -448,8 +411,8 @@ func processBatch(inputs []BatchInput) error {
 	logger.Infof("Results will be saved to: %s", outputFile)
 
 	// Create worker pool
-	inputChan := make(chan BatchInput, len(inputs))
-	resultChan := make(chan BatchResult, len(inputs))
+	inputChan := make(chan models.BatchInput, len(inputs))
+	resultChan := make(chan models.BatchResult, len(inputs))
 
 	// Start workers
 	var wg sync.WaitGroup
@@ -467,7 +430,7 @@ func processBatch(inputs []BatchInput) error {
 	}()
 
 	// Collect results
-	var results []BatchResult
+	var results []models.BatchResult
 	var completed int
 	total := len(inputs)
 
@@ -500,7 +463,7 @@ func processBatch(inputs []BatchInput) error {
 	}
 
 	// Generate summary
-	summary := generateBatchSummary(results, startTime)
+	summary := models.SummarizeBatch(results, startTime)
 	displayBatchSummary(summary)
 
 	logger.Infof("Batch processing completed successfully")
@@ -509,7 +472,7 @@ func processBatch(inputs []BatchInput) error {
 	return nil
 }
 
-func batchWorker(workerID int, inputChan <-chan BatchInput, resultChan chan<- BatchResult, promptEngine *engine.Engine, wg *sync.WaitGroup) {
+func batchWorker(workerID int, inputChan <-chan models.BatchInput, resultChan chan<- models.BatchResult, promptEngine *engine.Engine, wg *sync.WaitGroup) {
 	defer wg.Done()
 	logger := log.GetLogger()
 
@@ -523,18 +486,18 @@ func batchWorker(workerID int, inputChan <-chan BatchInput, resultChan chan<- Ba
 	}
 }
 
-func processBatchInput(workerID int, input BatchInput, promptEngine *engine.Engine) BatchResult {
+func processBatchInput(workerID int, input models.BatchInput, promptEngine *engine.Engine) models.BatchResult {
 	logger := log.GetLogger()
 	startTime := time.Now()
 
-	result := BatchResult{
+	result := models.BatchResult{
 		ID:        input.ID,
 		Input:     input,
 		Timestamp: startTime,
 	}
 
 	// Apply defaults
-	finalInput := applyBatchDefaults(input)
+	finalInput := engine.ApplyBatchDefaults(input)
 
 	// Parse phases
 	phaseList := []models.Phase{models.PhasePrimaMaterial, models.PhaseSolutio, models.PhaseCoagulatio}
@@ -589,36 +552,6 @@ func processBatchInput(workerID int, input BatchInput, promptEngine *engine.Engi
 	return result
 }
 
-func applyBatchDefaults(input BatchInput) BatchInput {
-	// Apply defaults from configuration or command-line flags
-	if input.Count == 0 {
-		input.Count = viper.GetInt("generation.default_count")
-		if input.Count == 0 {
-			input.Count = 3
-		}
-	}
-
-	if input.Temperature == 0 {
-		input.Temperature = viper.GetFloat64("generation.default_temperature")
-		if input.Temperature == 0 {
-			input.Temperature = 0.7
-		}
-	}
-
-	if input.MaxTokens == 0 {
-		input.MaxTokens = viper.GetInt("generation.default_max_tokens")
-		if input.MaxTokens == 0 {
-			input.MaxTokens = 2000
-		}
-	}
-
-	if input.Provider == "" {
-		input.Provider = viper.GetString("generation.default_provider")
-	}
-
-	return input
-}
-
 func batchParsePhases(phasesStr string) []models.Phase {
 	parts := strings.Split(phasesStr, ",")
 	var phases []models.Phase
@@ -642,12 +575,12 @@ func batchParsePhases(phasesStr string) []models.Phase {
 	return phases
 }
 
-func saveBatchResults(filename string, results []BatchResult, startTime time.Time) error {
-	summary := generateBatchSummary(results, startTime)
+func saveBatchResults(filename string, results []models.BatchResult, startTime time.Time) error {
+	summary := models.SummarizeBatch(results, startTime)
 
 	output := struct {
-		Summary BatchSummary  `json:"summary"`
-		Results []BatchResult `json:"results"`
+		Summary models.BatchSummary  `json:"summary"`
+		Results []models.BatchResult `json:"results"`
 	}{
 		Summary: summary,
 		Results: results,
@@ -661,33 +594,7 @@ func saveBatchResults(filename string, results []BatchResult, startTime time.Tim
 	return os.WriteFile(filename, data, 0600)
 }
 
-func generateBatchSummary(results []BatchResult, startTime time.Time) BatchSummary {
-	summary := BatchSummary{
-		TotalInputs: len(results),
-		StartTime:   startTime,
-		EndTime:     time.Now(),
-	}
-
-	var totalDuration time.Duration
-	for _, result := range results {
-		if result.Success {
-			summary.SuccessfulJobs++
-			summary.TotalPrompts += len(result.Prompts)
-		} else {
-			summary.FailedJobs++
-		}
-		totalDuration += result.Duration
-	}
-
-	summary.TotalDuration = summary.EndTime.Sub(startTime)
-	if summary.TotalInputs > 0 {
-		summary.AverageDuration = totalDuration / time.Duration(summary.TotalInputs)
-	}
-
-	return summary
-}
-
-func displayBatchSummary(summary BatchSummary) {
+func displayBatchSummary(summary models.BatchSummary) {
 	logger := log.GetLogger()
 
 	logger.Info("📊 Batch Processing Summary")
@@ -712,7 +619,7 @@ func runInteractiveBatch() error {
 	logger.Info("Starting interactive batch mode")
 	logger.Info("Enter prompts one per line. Type 'END' to finish, 'HELP' for commands.")
 
-	var inputs []BatchInput
+	var inputs []models.BatchInput
 	scanner := bufio.NewScanner(os.Stdin)
 	inputNum := 1
 
@@ -744,7 +651,7 @@ func runInteractiveBatch() error {
 			return nil
 		}
 
-		inputs = append(inputs, BatchInput{
+		inputs = append(inputs, models.BatchInput{
 			ID:    fmt.Sprintf("interactive_%d", inputNum),
 			Input: line,
 		})
@@ -771,8 +678,8 @@ func resumeBatch(resumeFile string) error {
 	}
 
 	var resumeData struct {
-		Summary BatchSummary  `json:"summary"`
-		Results []BatchResult `json:"results"`
+		Summary models.BatchSummary  `json:"summary"`
+		Results []models.BatchResult `json:"results"`
 	}
 
 	if err := json.Unmarshal(data, &resumeData); err != nil {
@@ -780,7 +687,7 @@ func resumeBatch(resumeFile string) error {
 	}
 
 	// Find failed jobs
-	var failedInputs []BatchInput
+	var failedInputs []models.BatchInput
 	for _, result := range resumeData.Results {
 		if !result.Success {
 			failedInputs = append(failedInputs, result.Input)
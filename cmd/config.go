@@ -41,7 +41,7 @@ and generation parameters.`,
 		logger.Infof("Data directory: %s", dataDir)
 
 		// Show provider configurations
-		providers := viper.GetStringMap("providers")
+		providers := configuredProviders()
 		if len(providers) > 0 {
 			logger.Info("Configured Providers:")
 			for name := range providers {
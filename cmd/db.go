@@ -101,37 +101,28 @@ func runDBStats(cmd *cobra.Command, args []string) error {
 		}
 	}()
 
-	// Get basic counts
-	logger.Debug("Fetching all prompts for statistics")
-	prompts, err := store.GetHighQualityHistoricalPrompts(cmd.Context(), 10000) // Get all prompts for counting
+	stats, err := store.Statistics(cmd.Context(), storage.StatisticsOptions{})
 	if err != nil {
-		logger.Errorf("Failed to list prompts: %v", err)
-		return fmt.Errorf("failed to list prompts: %w", err)
+		logger.Errorf("Failed to compute statistics: %v", err)
+		return fmt.Errorf("failed to compute statistics: %w", err)
 	}
 
 	fmt.Printf("Database Statistics:\n")
 	fmt.Printf("==================\n")
-	fmt.Printf("Total Prompts: %d\n", len(prompts))
-
-	// Count by provider
-	providerCounts := make(map[string]int)
-	phaseCounts := make(map[string]int)
-
-	for _, prompt := range prompts {
-		providerCounts[prompt.Provider]++
-		phaseCounts[string(prompt.Phase)]++
-	}
+	fmt.Printf("Total Prompts: %d\n", stats.TotalPrompts)
 
 	fmt.Printf("\nBy Provider:\n")
-	for provider, count := range providerCounts {
+	for provider, count := range stats.ByProvider {
 		fmt.Printf("  %s: %d\n", provider, count)
 	}
 
 	fmt.Printf("\nBy Phase:\n")
-	for phase, count := range phaseCounts {
+	for phase, count := range stats.ByPhase {
 		fmt.Printf("  %s: %d\n", phase, count)
 	}
 
+	fmt.Printf("\nEmbedding Coverage: %.1f%% (%d of %d)\n", stats.Embeddings.CoveragePercent, stats.Embeddings.Embedded, stats.TotalPrompts)
+
 	logger.Info("Successfully generated database statistics")
 	return nil
 }
@@ -0,0 +1,306 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jonwraymond/prompt-alchemy/internal/storage"
+	"github.com/jonwraymond/prompt-alchemy/pkg/providers"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	doctorOutput  string
+	doctorOffline bool
+	doctorTimeout time.Duration
+)
+
+// DoctorCheck is the outcome of a single diagnostic check
+type DoctorCheck struct {
+	Name    string   `json:"name"`
+	Passed  bool     `json:"passed"`
+	Details []string `json:"details,omitempty"`
+	Hint    string   `json:"hint,omitempty"`
+}
+
+// DoctorReport aggregates the results of all diagnostic checks
+type DoctorReport struct {
+	Healthy bool          `json:"healthy"`
+	Checks  []DoctorCheck `json:"checks"`
+}
+
+// doctorCmd represents the doctor command
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose configuration, providers, storage and embeddings",
+	Long: `Run a self-test of the local Prompt Alchemy installation and report what
+works and what does not, with a remediation hint for every failed check.
+
+Checks performed:
+  - Configuration: the same rules as 'prompt-alchemy validate'
+  - Providers: each configured provider answers a minimal request
+  - Storage: the database in data_dir can be opened and queried
+  - Embeddings: at least one provider can produce an embedding
+
+Examples:
+  # Run all checks
+  prompt-alchemy doctor
+
+  # Skip checks that call provider APIs
+  prompt-alchemy doctor --offline
+
+  # Machine-readable report
+  prompt-alchemy doctor --output json`,
+	RunE: runDoctor,
+}
+
+func init() {
+	doctorCmd.Flags().StringVar(&doctorOutput, "output", "text", "Output format (text, json)")
+	doctorCmd.Flags().BoolVar(&doctorOffline, "offline", false, "Skip checks that make network calls to providers")
+	doctorCmd.Flags().DurationVar(&doctorTimeout, "timeout", 15*time.Second, "Timeout for each network check")
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	registry := providers.NewRegistry()
+	return doctor(ctx, registry, initializeProviders(registry))
+}
+
+// doctor runs every check against registry, prints the report and returns an
+// error, and so a non-zero exit status, when any check failed.
+func doctor(ctx context.Context, registry *providers.Registry, providerErr error) error {
+	report := DoctorReport{
+		Checks: []DoctorCheck{
+			doctorCheckConfig(),
+			doctorCheckProviders(ctx, registry, providerErr),
+			doctorCheckStorage(ctx),
+			doctorCheckEmbeddings(ctx, registry),
+		},
+	}
+	report.Healthy = true
+	for _, check := range report.Checks {
+		if !check.Passed {
+			report.Healthy = false
+		}
+	}
+
+	if doctorOutput == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return fmt.Errorf("failed to encode doctor report: %w", err)
+		}
+	} else {
+		outputDoctorText(report)
+	}
+
+	if !report.Healthy {
+		return fmt.Errorf("doctor found problems with the installation")
+	}
+	return nil
+}
+
+// doctorCheckConfig reuses the validate command's rules; only critical
+// issues fail the check, warnings are reported as details.
+func doctorCheckConfig() DoctorCheck {
+	check := DoctorCheck{Name: "Configuration", Passed: true}
+
+	if configFile := viper.ConfigFileUsed(); configFile != "" {
+		check.Details = append(check.Details, fmt.Sprintf("config file: %s", configFile))
+	} else {
+		check.Details = append(check.Details, "no config file found, using defaults and environment")
+	}
+
+	result := validateConfiguration()
+	for _, issue := range result.Issues {
+		if issue.Severity == "info" {
+			continue
+		}
+		check.Details = append(check.Details, fmt.Sprintf("%s %s: %s", getIssueIcon(issue.Severity), issue.Field, issue.Message))
+		if issue.Severity == "critical" {
+			check.Passed = false
+			if check.Hint == "" {
+				check.Hint = issue.Fix
+			}
+		}
+	}
+	if !check.Passed && check.Hint == "" {
+		check.Hint = "Run 'prompt-alchemy validate --verbose' for details"
+	}
+	return check
+}
+
+// doctorCheckProviders verifies that every configured provider is available
+// and, unless running offline, that it answers a minimal generation request.
+func doctorCheckProviders(ctx context.Context, registry *providers.Registry, initErr error) DoctorCheck {
+	check := DoctorCheck{Name: "Providers", Passed: true}
+	if initErr != nil {
+		check.Passed = false
+		check.Details = append(check.Details, initErr.Error())
+		check.Hint = "Check the providers section of your config file"
+		return check
+	}
+
+	names := registry.ListProviders()
+	sort.Strings(names)
+	if len(names) == 0 {
+		check.Passed = false
+		check.Hint = "Set at least one API key, e.g. PROMPT_ALCHEMY_PROVIDERS_OPENAI_API_KEY, or configure Ollama"
+		return check
+	}
+
+	var failed []string
+	for _, name := range names {
+		provider, err := registry.Get(name)
+		if err != nil {
+			continue
+		}
+		if !provider.IsAvailable() {
+			failed = append(failed, name)
+			check.Details = append(check.Details, fmt.Sprintf("❌ %s: not available", name))
+			continue
+		}
+		if doctorOffline {
+			check.Details = append(check.Details, fmt.Sprintf("⏭️ %s: configured (connectivity not tested)", name))
+			continue
+		}
+
+		callCtx, cancel := context.WithTimeout(ctx, doctorTimeout)
+		start := time.Now()
+		_, err = provider.Generate(callCtx, providers.GenerateRequest{
+			Prompt:    "Reply with OK.",
+			MaxTokens: 5,
+		})
+		cancel()
+		if err != nil {
+			failed = append(failed, name)
+			check.Details = append(check.Details, fmt.Sprintf("❌ %s: %v", name, err))
+			continue
+		}
+		check.Details = append(check.Details, fmt.Sprintf("✅ %s: responded in %s", name, time.Since(start).Round(time.Millisecond)))
+	}
+
+	if len(failed) > 0 {
+		check.Passed = false
+		check.Hint = fmt.Sprintf("Verify the API key, base_url and model for: %s", strings.Join(failed, ", "))
+	}
+	return check
+}
+
+// doctorCheckStorage opens the database in data_dir and runs a trivial query
+func doctorCheckStorage(ctx context.Context) DoctorCheck {
+	dataDir := viper.GetString("data_dir")
+	check := DoctorCheck{Name: "Storage", Passed: true}
+	check.Details = append(check.Details, fmt.Sprintf("data_dir: %s", dataDir))
+
+	if _, err := os.Stat(dataDir); err != nil {
+		check.Passed = false
+		check.Details = append(check.Details, err.Error())
+		check.Hint = fmt.Sprintf("Create the data directory with 'mkdir -p %s' or set data_dir in your config", dataDir)
+		return check
+	}
+
+	store, err := storage.NewStorage(dataDir, logger)
+	if err != nil {
+		check.Passed = false
+		check.Details = append(check.Details, err.Error())
+		check.Hint = "Make sure the data directory is writable and not locked by another process"
+		return check
+	}
+	defer func() {
+		if err := store.Close(); err != nil {
+			logger.WithError(err).Warn("Failed to close storage")
+		}
+	}()
+
+	count, err := store.GetPromptsCount(ctx)
+	if err != nil {
+		check.Passed = false
+		check.Details = append(check.Details, err.Error())
+		check.Hint = "The database may be corrupt or from an older version; try 'prompt-alchemy migrate'"
+		return check
+	}
+	check.Details = append(check.Details, fmt.Sprintf("%d prompts stored", count))
+	return check
+}
+
+// doctorCheckEmbeddings verifies at least one provider can produce embeddings,
+// which semantic search and ranking depend on.
+func doctorCheckEmbeddings(ctx context.Context, registry *providers.Registry) DoctorCheck {
+	check := DoctorCheck{Name: "Embeddings", Passed: true}
+
+	capable := registry.ListEmbeddingCapableProviders()
+	sort.Strings(capable)
+	if len(capable) == 0 {
+		check.Passed = false
+		check.Hint = "Configure OpenAI or Ollama; semantic search and ranking need an embedding-capable provider"
+		return check
+	}
+	check.Details = append(check.Details, fmt.Sprintf("embedding-capable providers: %s", strings.Join(capable, ", ")))
+
+	if doctorOffline {
+		return check
+	}
+
+	provider, err := registry.Get(capable[0])
+	if err != nil {
+		check.Passed = false
+		check.Details = append(check.Details, err.Error())
+		return check
+	}
+
+	callCtx, cancel := context.WithTimeout(ctx, doctorTimeout)
+	defer cancel()
+	embedding, err := provider.GetEmbedding(callCtx, "prompt-alchemy doctor", registry)
+	if err != nil {
+		check.Passed = false
+		check.Details = append(check.Details, fmt.Sprintf("❌ %s: %v", capable[0], err))
+		check.Hint = fmt.Sprintf("Check that the embedding model configured for %s exists and is accessible", capable[0])
+		return check
+	}
+	check.Details = append(check.Details, fmt.Sprintf("✅ %s: %d dimensions", capable[0], len(embedding)))
+
+	if expected := viper.GetInt("embeddings.standard_dimensions"); expected > 0 && expected != len(embedding) {
+		check.Passed = false
+		check.Hint = fmt.Sprintf("embeddings.standard_dimensions is %d but %s returns %d; align the model and dimensions", expected, capable[0], len(embedding))
+	}
+	return check
+}
+
+func outputDoctorText(report DoctorReport) {
+	fmt.Println("🩺 Prompt Alchemy Doctor")
+	fmt.Println("========================")
+	fmt.Println()
+
+	for _, check := range report.Checks {
+		icon := "✅"
+		if !check.Passed {
+			icon = "❌"
+		}
+		fmt.Printf("%s %s\n", icon, check.Name)
+		for _, detail := range check.Details {
+			fmt.Printf("   %s\n", detail)
+		}
+		if !check.Passed && check.Hint != "" {
+			fmt.Printf("   Hint: %s\n", check.Hint)
+		}
+		fmt.Println()
+	}
+
+	if report.Healthy {
+		fmt.Println("All checks passed.")
+	} else {
+		fmt.Println("Some checks failed. Fix the issues above and run 'prompt-alchemy doctor' again.")
+	}
+}
@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jonwraymond/prompt-alchemy/pkg/providers"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// doctorProvider answers generation and embedding requests with err, or
+// successfully when it is nil
+type doctorProvider struct {
+	name       string
+	available  bool
+	embeddings bool
+	err        error
+}
+
+func (p *doctorProvider) Generate(ctx context.Context, req providers.GenerateRequest) (*providers.GenerateResponse, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &providers.GenerateResponse{Content: "OK"}, nil
+}
+
+func (p *doctorProvider) GetEmbedding(ctx context.Context, text string, registry providers.RegistryInterface) ([]float32, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	return []float32{0.1, 0.2, 0.3}, nil
+}
+
+func (p *doctorProvider) Name() string             { return p.name }
+func (p *doctorProvider) IsAvailable() bool        { return p.available }
+func (p *doctorProvider) SupportsEmbeddings() bool { return p.embeddings }
+func (p *doctorProvider) SupportsStreaming() bool  { return false }
+
+func (p *doctorProvider) Ping(ctx context.Context) (time.Duration, error) {
+	return time.Millisecond, p.err
+}
+
+// setupDoctor points the configuration at a config file in a temp dir and
+// returns its data dir
+func setupDoctor(t *testing.T) string {
+	t.Helper()
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	if logger == nil {
+		logger = logrus.New()
+	}
+	logger.SetLevel(logrus.ErrorLevel)
+	doctorOutput, doctorOffline, doctorTimeout = "json", false, time.Second
+
+	dir := t.TempDir()
+	dataDir := filepath.Join(dir, "data")
+	require.NoError(t, os.Mkdir(dataDir, 0o755))
+	configFile := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte(`
+data_dir: `+dataDir+`
+providers:
+  openai:
+    model: gpt-4o-mini
+`), 0o600))
+	viper.SetConfigFile(configFile)
+	require.NoError(t, viper.ReadInConfig())
+	// Keys belong in the environment, not the config file
+	viper.Set("providers.openai.api_key", "sk-test-key")
+	return dataDir
+}
+
+func doctorRegistry(t *testing.T, provider *doctorProvider) *providers.Registry {
+	t.Helper()
+	registry := providers.NewRegistry()
+	require.NoError(t, registry.Register(provider.name, provider))
+	return registry
+}
+
+func TestDoctorCheckConfig(t *testing.T) {
+	t.Run("valid config passes", func(t *testing.T) {
+		setupDoctor(t)
+		check := doctorCheckConfig()
+		assert.True(t, check.Passed, check.Details)
+	})
+
+	t.Run("missing config file fails", func(t *testing.T) {
+		setupDoctor(t)
+		viper.Reset()
+		check := doctorCheckConfig()
+		assert.False(t, check.Passed)
+		assert.NotEmpty(t, check.Hint)
+	})
+}
+
+func TestDoctorCheckProviders(t *testing.T) {
+	setupDoctor(t)
+	ctx := context.Background()
+
+	t.Run("responding provider passes", func(t *testing.T) {
+		registry := doctorRegistry(t, &doctorProvider{name: "openai", available: true})
+		check := doctorCheckProviders(ctx, registry, nil)
+		assert.True(t, check.Passed, check.Details)
+	})
+
+	t.Run("failing provider fails", func(t *testing.T) {
+		registry := doctorRegistry(t, &doctorProvider{name: "openai", available: true, err: errors.New("invalid API key")})
+		check := doctorCheckProviders(ctx, registry, nil)
+		assert.False(t, check.Passed)
+		assert.Contains(t, check.Details[0], "invalid API key")
+		assert.Contains(t, check.Hint, "openai")
+	})
+
+	t.Run("unavailable provider fails", func(t *testing.T) {
+		registry := doctorRegistry(t, &doctorProvider{name: "openai"})
+		check := doctorCheckProviders(ctx, registry, nil)
+		assert.False(t, check.Passed)
+	})
+
+	t.Run("no providers fails", func(t *testing.T) {
+		check := doctorCheckProviders(ctx, providers.NewRegistry(), nil)
+		assert.False(t, check.Passed)
+	})
+
+	t.Run("offline skips the request", func(t *testing.T) {
+		doctorOffline = true
+		defer func() { doctorOffline = false }()
+		registry := doctorRegistry(t, &doctorProvider{name: "openai", available: true, err: errors.New("unreachable")})
+		check := doctorCheckProviders(ctx, registry, nil)
+		assert.True(t, check.Passed, check.Details)
+	})
+}
+
+func TestDoctorCheckStorage(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("data dir with a database passes", func(t *testing.T) {
+		setupDoctor(t)
+		check := doctorCheckStorage(ctx)
+		assert.True(t, check.Passed, check.Details)
+		assert.Contains(t, check.Details, "0 prompts stored")
+	})
+
+	t.Run("missing data dir fails", func(t *testing.T) {
+		dataDir := setupDoctor(t)
+		viper.Set("data_dir", filepath.Join(dataDir, "missing"))
+		check := doctorCheckStorage(ctx)
+		assert.False(t, check.Passed)
+		assert.Contains(t, check.Hint, "mkdir -p")
+	})
+}
+
+func TestDoctor(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("healthy installation succeeds", func(t *testing.T) {
+		setupDoctor(t)
+		registry := doctorRegistry(t, &doctorProvider{name: "openai", available: true, embeddings: true})
+		assert.NoError(t, doctor(ctx, registry, nil))
+	})
+
+	t.Run("failed check exits non-zero", func(t *testing.T) {
+		setupDoctor(t)
+		registry := doctorRegistry(t, &doctorProvider{name: "openai", available: true, embeddings: true, err: errors.New("invalid API key")})
+		assert.Error(t, doctor(ctx, registry, nil))
+	})
+
+	t.Run("provider initialization error exits non-zero", func(t *testing.T) {
+		setupDoctor(t)
+		registry := doctorRegistry(t, &doctorProvider{name: "openai", available: true, embeddings: true})
+		assert.Error(t, doctor(ctx, registry, errors.New("invalid custom provider")))
+	})
+}
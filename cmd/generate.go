@@ -18,6 +18,7 @@ import (
 	"github.com/jonwraymond/prompt-alchemy/internal/helpers"
 	log "github.com/jonwraymond/prompt-alchemy/internal/log"
 	"github.com/jonwraymond/prompt-alchemy/internal/ranking"
+	"github.com/jonwraymond/prompt-alchemy/internal/selection"
 	"github.com/jonwraymond/prompt-alchemy/internal/storage"
 	"github.com/jonwraymond/prompt-alchemy/pkg/client"
 	"github.com/jonwraymond/prompt-alchemy/pkg/models"
@@ -34,12 +35,14 @@ var (
 	provider            string
 	outputFormat        string
 	savePrompt          bool
+	savePhases          []string
 	persona             string
 	targetModel         string
 	embeddingDimensions int
 	optimize            bool
 	optimizeTargetScore float64
 	optimizeMaxIter     int
+	seed                int
 )
 
 // generateCmd represents the generate command
@@ -64,12 +67,14 @@ func init() {
 	generateCmd.Flags().StringVar(&provider, "provider", "", "Override default provider for all phases")
 	generateCmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "Output format (text, json, yaml)")
 	generateCmd.Flags().BoolVar(&savePrompt, "save", true, "Save generated prompts to database")
-	generateCmd.Flags().StringVar(&persona, "persona", "code", "AI persona to use (code, writing, analysis, generic)")
+	generateCmd.Flags().StringSliceVar(&savePhases, "save-phases", nil, "Only save prompts from these phases; use 'selected' for the selected prompt (default: all, or generation.save_phases)")
+	generateCmd.Flags().StringVar(&persona, "persona", "code", "AI persona to use (code, writing, analysis, generic, or a custom persona)")
 	generateCmd.Flags().StringVar(&targetModel, "target-model", "", "Target model family for optimization (claude-4-sonnet-20250522, o4-mini, gemini-2.5-flash, etc.)")
 	generateCmd.Flags().IntVar(&embeddingDimensions, "embedding-dimensions", 0, "Embedding dimensions for similarity search (uses config default if not specified)")
 	generateCmd.Flags().BoolVar(&optimize, "optimize", false, "Enable AI-powered optimization with LLM-as-Judge and meta-prompting")
 	generateCmd.Flags().Float64Var(&optimizeTargetScore, "optimize-target-score", 8.5, "Target quality score for optimization (1-10)")
 	generateCmd.Flags().IntVar(&optimizeMaxIter, "optimize-max-iterations", 3, "Maximum optimization iterations per phase")
+	generateCmd.Flags().IntVar(&seed, "seed", 0, "Seed for deterministic sampling, forwarded to providers that support it")
 
 	// Client mode flag (overrides config)
 	generateCmd.Flags().String("server", "", "Server URL for client mode (overrides config and enables client mode)")
@@ -144,6 +149,7 @@ func runGenerateClient(cmd *cobra.Command, args []string, input string) error {
 		Tags:        tagList,
 		Persona:     persona,
 		TargetModel: targetModel,
+		SavePhases:  savePhases,
 	}
 
 	// Generate via server
@@ -178,7 +184,14 @@ func runGenerateLocal(cmd *cobra.Command, args []string, input string) error {
 	}
 	logger.Debugf("Context files: %v", contextFiles)
 
-	// Validate and load persona
+	// Validate and load persona, which may be a custom one
+	presets, err := selection.LoadWeightPresets()
+	if err != nil {
+		return err
+	}
+	if err := loadPersonas(presets, logger); err != nil {
+		return err
+	}
 	personaType := models.PersonaType(persona)
 	personaObj, err := models.GetPersona(personaType)
 	if err != nil {
@@ -186,6 +199,13 @@ func runGenerateLocal(cmd *cobra.Command, args []string, input string) error {
 	}
 	logger.Debugf("Using persona: %s", persona)
 
+	if !cmd.Flags().Changed("save-phases") {
+		savePhases = viper.GetStringSlice("generation.save_phases")
+	}
+	if err := models.ValidateSavePhases(savePhases); err != nil {
+		return err
+	}
+
 	// Validate temperature range (general validation, providers may have stricter limits)
 	if temperature < 0 || temperature > 2.0 {
 		return fmt.Errorf("temperature must be between 0 and 2.0, got %f", temperature)
@@ -269,6 +289,15 @@ func runGenerateLocal(cmd *cobra.Command, args []string, input string) error {
 	if store != nil {
 		eng.SetStorage(store)
 	}
+	ranker := ranking.NewRanker(store, registry, logger)
+	defer func() {
+		if err := ranker.Close(); err != nil {
+			logger.WithError(err).Warn("Failed to close ranker")
+		}
+	}()
+	eng.SetShadowScorer(ranker)
+	// Let shadow generations log their comparisons before the command exits
+	defer eng.WaitForShadows()
 
 	// Create request
 	request := models.PromptRequest{
@@ -281,6 +310,10 @@ func runGenerateLocal(cmd *cobra.Command, args []string, input string) error {
 		Context:     contextList,
 	}
 
+	if cmd.Flags().Changed("seed") {
+		request.Seed = &seed
+	}
+
 	sessionID := uuid.New()
 	request.SessionID = sessionID // Assuming PromptRequest has SessionID field; add if not
 
@@ -311,12 +344,6 @@ func runGenerateLocal(cmd *cobra.Command, args []string, input string) error {
 
 	// Rank prompts
 	logger.Info("Ranking prompts...")
-	ranker := ranking.NewRanker(store, registry, logger)
-	defer func() {
-		if err := ranker.Close(); err != nil {
-			logger.WithError(err).Warn("Failed to close ranker")
-		}
-	}()
 	rankings, err := ranker.RankPrompts(ctx, result.Prompts, input)
 	if err != nil {
 		logger.WithError(err).Warn("Failed to rank prompts")
@@ -328,8 +355,12 @@ func runGenerateLocal(cmd *cobra.Command, args []string, input string) error {
 	// Save prompts if requested
 	if savePrompt {
 		logger.Info("Saving prompts...")
-		for _, prompt := range result.Prompts {
-			if err := store.SavePrompt(cmd.Context(), &prompt); err != nil {
+		selected := result.Selected
+		if selected == nil && len(result.Rankings) > 0 {
+			selected = result.Rankings[0].Prompt
+		}
+		for _, prompt := range models.PromptsToSave(result.Prompts, selected, savePhases) {
+			if err := store.SavePrompt(cmd.Context(), prompt); err != nil {
 				logger.WithError(err).Warn("Failed to save prompt")
 			}
 		}
@@ -452,6 +483,102 @@ func initializeProviders(registry *providers.Registry) error {
 		}
 	}
 
+	// Initialize Mistral
+	if apiKey := viper.GetString("providers.mistral.api_key"); apiKey != "" {
+		logger.Debug("Initializing Mistral provider")
+		config := providers.Config{
+			APIKey:         apiKey,
+			Model:          viper.GetString("providers.mistral.model"),
+			EmbeddingModel: viper.GetString("providers.mistral.embedding_model"),
+			BaseURL:        viper.GetString("providers.mistral.base_url"),
+			Timeout:        viper.GetInt("providers.mistral.timeout"),
+		}
+		if err := registry.Register(providers.ProviderMistral, providers.NewMistralProvider(config)); err != nil {
+			logger.Warn("Failed to register Mistral provider", "error", err)
+		}
+	}
+
+	// Initialize DeepSeek
+	if apiKey := viper.GetString("providers.deepseek.api_key"); apiKey != "" {
+		logger.Debug("Initializing DeepSeek provider")
+		config := providers.Config{
+			APIKey:  apiKey,
+			Model:   viper.GetString("providers.deepseek.model"),
+			BaseURL: viper.GetString("providers.deepseek.base_url"),
+			Timeout: viper.GetInt("providers.deepseek.timeout"),
+		}
+		if err := registry.Register(providers.ProviderDeepSeek, providers.NewDeepSeekProvider(config)); err != nil {
+			logger.Warn("Failed to register DeepSeek provider", "error", err)
+		}
+	}
+
+	// Initialize Cohere
+	if apiKey := viper.GetString("providers.cohere.api_key"); apiKey != "" {
+		logger.Debug("Initializing Cohere provider")
+		config := providers.Config{
+			APIKey:         apiKey,
+			Model:          viper.GetString("providers.cohere.model"),
+			EmbeddingModel: viper.GetString("providers.cohere.embedding_model"),
+			RerankModel:    viper.GetString("providers.cohere.rerank_model"),
+			BaseURL:        viper.GetString("providers.cohere.base_url"),
+			Timeout:        viper.GetInt("providers.cohere.timeout"),
+		}
+		if err := registry.Register(providers.ProviderCohere, providers.NewCohereProvider(config)); err != nil {
+			logger.Warn("Failed to register Cohere provider", "error", err)
+		}
+	}
+
+	// Initialize Perplexity
+	if apiKey := viper.GetString("providers.perplexity.api_key"); apiKey != "" {
+		logger.Debug("Initializing Perplexity provider")
+		config := providers.Config{
+			APIKey:  apiKey,
+			Model:   viper.GetString("providers.perplexity.model"),
+			BaseURL: viper.GetString("providers.perplexity.base_url"),
+			Timeout: viper.GetInt("providers.perplexity.timeout"),
+		}
+		if err := registry.Register(providers.ProviderPerplexity, providers.NewPerplexityProvider(config)); err != nil {
+			logger.Warn("Failed to register Perplexity provider", "error", err)
+		}
+	}
+
+	// Initialize Azure OpenAI
+	if apiKey := viper.GetString("providers.azure.api_key"); apiKey != "" {
+		logger.Debug("Initializing Azure OpenAI provider")
+		config := providers.Config{
+			APIKey:              apiKey,
+			Endpoint:            viper.GetString("providers.azure.endpoint"),
+			Deployment:          viper.GetString("providers.azure.deployment"),
+			EmbeddingDeployment: viper.GetString("providers.azure.embedding_deployment"),
+			APIVersion:          viper.GetString("providers.azure.api_version"),
+			Timeout:             viper.GetInt("providers.azure.timeout"),
+		}
+		if err := registry.Register(providers.ProviderAzure, providers.NewAzureOpenAIProvider(config)); err != nil {
+			logger.Warn("Failed to register Azure OpenAI provider", "error", err)
+		}
+	}
+
+	// Initialize AWS Bedrock
+	if region := viper.GetString("providers.bedrock.region"); region != "" {
+		logger.Debug("Initializing AWS Bedrock provider")
+		config := providers.Config{
+			Region:         region,
+			Model:          viper.GetString("providers.bedrock.model"),
+			EmbeddingModel: viper.GetString("providers.bedrock.embedding_model"),
+			BaseURL:        viper.GetString("providers.bedrock.base_url"),
+			ModelMap:       viper.GetStringMapString("providers.bedrock.model_map"),
+			Timeout:        viper.GetInt("providers.bedrock.timeout"),
+		}
+		if err := registry.Register(providers.ProviderBedrock, providers.NewBedrockProvider(config)); err != nil {
+			logger.Warn("Failed to register AWS Bedrock provider", "error", err)
+		}
+	}
+
+	// Initialize custom OpenAI-compatible endpoints
+	if _, err := providers.RegisterCustomProviders(registry); err != nil {
+		return err
+	}
+
 	// Check if at least one provider is available
 	if len(registry.ListAvailable()) == 0 {
 		logger.Error("no providers configured")
@@ -269,6 +269,11 @@ func startServices(ctx context.Context, serviceRegistry interfaces.ServiceRegist
 	var wg sync.WaitGroup
 	errChan := make(chan error, 10)
 
+	// Probe providers in the background so generation skips dead ones
+	if providerRegistry, err := serviceRegistry.GetService("providers"); err == nil {
+		providerRegistry.(*providers.Registry).StartHealthChecks(ctx, providers.LoadHealthConfig())
+	}
+
 	// Start HTTP API server if enabled
 	if flags.ShouldStartService("api") {
 		wg.Add(1)
@@ -456,6 +461,14 @@ func initConfig() {
 	viper.SetDefault("generation.default_temperature", 0.7)
 	viper.SetDefault("generation.default_max_tokens", 2000)
 	viper.SetDefault("generation.default_count", 3)
+	viper.SetDefault("generation.phase_selection", "all")
+	viper.SetDefault("embeddings.cache.size", 1000)
+	viper.SetDefault("embeddings.cache.ttl", "1h")
+
+	viper.SetDefault("maintenance.decay_rate", 0.01)
+	viper.SetDefault("maintenance.max_prompts", 10000)
+	viper.SetDefault("maintenance.min_relevance_score", 0.1)
+	viper.SetDefault("maintenance.min_age", "168h")
 
 	// Read config file
 	if err := viper.ReadInConfig(); err != nil {
@@ -507,6 +520,75 @@ func registerProviders(registry *providers.Registry, logger *logrus.Logger) erro
 		logger.Info("Registered Grok provider")
 	}
 
+	if apiKey := viper.GetString("providers.mistral.api_key"); apiKey != "" {
+		config := providers.Config{
+			APIKey:         apiKey,
+			Model:          viper.GetString("providers.mistral.model"),
+			EmbeddingModel: viper.GetString("providers.mistral.embedding_model"),
+		}
+		mistral := providers.NewMistralProvider(config)
+		_ = registry.Register(providers.ProviderMistral, mistral)
+		logger.Info("Registered Mistral provider")
+	}
+
+	if apiKey := viper.GetString("providers.deepseek.api_key"); apiKey != "" {
+		config := providers.Config{
+			APIKey: apiKey,
+			Model:  viper.GetString("providers.deepseek.model"),
+		}
+		deepseek := providers.NewDeepSeekProvider(config)
+		_ = registry.Register(providers.ProviderDeepSeek, deepseek)
+		logger.Info("Registered DeepSeek provider")
+	}
+
+	if apiKey := viper.GetString("providers.cohere.api_key"); apiKey != "" {
+		config := providers.Config{
+			APIKey:         apiKey,
+			Model:          viper.GetString("providers.cohere.model"),
+			EmbeddingModel: viper.GetString("providers.cohere.embedding_model"),
+			RerankModel:    viper.GetString("providers.cohere.rerank_model"),
+		}
+		cohere := providers.NewCohereProvider(config)
+		_ = registry.Register(providers.ProviderCohere, cohere)
+		logger.Info("Registered Cohere provider")
+	}
+
+	if apiKey := viper.GetString("providers.perplexity.api_key"); apiKey != "" {
+		config := providers.Config{
+			APIKey: apiKey,
+			Model:  viper.GetString("providers.perplexity.model"),
+		}
+		perplexity := providers.NewPerplexityProvider(config)
+		_ = registry.Register(providers.ProviderPerplexity, perplexity)
+		logger.Info("Registered Perplexity provider")
+	}
+
+	if apiKey := viper.GetString("providers.azure.api_key"); apiKey != "" {
+		config := providers.Config{
+			APIKey:              apiKey,
+			Endpoint:            viper.GetString("providers.azure.endpoint"),
+			Deployment:          viper.GetString("providers.azure.deployment"),
+			EmbeddingDeployment: viper.GetString("providers.azure.embedding_deployment"),
+			APIVersion:          viper.GetString("providers.azure.api_version"),
+		}
+		azure := providers.NewAzureOpenAIProvider(config)
+		_ = registry.Register(providers.ProviderAzure, azure)
+		logger.Info("Registered Azure OpenAI provider")
+	}
+
+	if region := viper.GetString("providers.bedrock.region"); region != "" {
+		config := providers.Config{
+			Region:         region,
+			Model:          viper.GetString("providers.bedrock.model"),
+			EmbeddingModel: viper.GetString("providers.bedrock.embedding_model"),
+			BaseURL:        viper.GetString("providers.bedrock.base_url"),
+			ModelMap:       viper.GetStringMapString("providers.bedrock.model_map"),
+		}
+		bedrock := providers.NewBedrockProvider(config)
+		_ = registry.Register(providers.ProviderBedrock, bedrock)
+		logger.Info("Registered AWS Bedrock provider")
+	}
+
 	// Always register Ollama if base URL is configured
 	if baseURL := viper.GetString("providers.ollama.base_url"); baseURL != "" {
 		config := providers.Config{
@@ -528,5 +610,13 @@ func registerProviders(registry *providers.Registry, logger *logrus.Logger) erro
 		logger.Info("Registered OpenRouter provider")
 	}
 
+	names, err := providers.RegisterCustomProviders(registry)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		logger.WithField("provider", name).Info("Registered custom OpenAI-compatible provider")
+	}
+
 	return nil
 }
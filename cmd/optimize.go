@@ -24,6 +24,7 @@ var (
 	optimizeJudgeProvider       string
 	optimizeCmdMaxIter          int
 	optimizeCmdTargetScore      float64
+	optimizeAcceptFirstPassing  bool
 	optimizeEmbeddingDimensions int
 )
 
@@ -48,6 +49,7 @@ func init() {
 	optimizeCmd.Flags().StringVar(&optimizeTargetModel, "target-model", "", "Target model for optimization (auto-detected if not specified)")
 	optimizeCmd.Flags().IntVar(&optimizeCmdMaxIter, "max-iterations", 5, "Maximum optimization iterations")
 	optimizeCmd.Flags().Float64Var(&optimizeCmdTargetScore, "target-score", 8.5, "Target quality score (1-10)")
+	optimizeCmd.Flags().BoolVar(&optimizeAcceptFirstPassing, "accept-first-passing", false, "Return the first prompt that meets the target score, including the original")
 	optimizeCmd.Flags().StringVarP(&optimizeTask, "task", "t", "", "Task description for testing (required)")
 	optimizeCmd.Flags().StringVar(&optimizeProvider, "provider", "", "Provider to use for optimization")
 	optimizeCmd.Flags().StringVar(&optimizeJudgeProvider, "judge-provider", "", "Provider to use for evaluation (defaults to main provider)")
@@ -172,14 +174,15 @@ func runOptimize(cmd *cobra.Command, args []string) error {
 
 	// Create optimization request
 	request := &optimizer.OptimizationRequest{
-		OriginalPrompt:  optimizePrompt,
-		TaskDescription: optimizeTask,
-		Examples:        []optimizer.OptimizationExample{}, // Could be loaded from file
-		Constraints:     []string{"Maintain clarity", "Preserve intent", "Improve effectiveness"},
-		ModelFamily:     modelFamily,
-		PersonaType:     personaType,
-		MaxIterations:   optimizeCmdMaxIter,
-		TargetScore:     optimizeCmdTargetScore,
+		OriginalPrompt:     optimizePrompt,
+		TaskDescription:    optimizeTask,
+		Examples:           []optimizer.OptimizationExample{}, // Could be loaded from file
+		Constraints:        []string{"Maintain clarity", "Preserve intent", "Improve effectiveness"},
+		ModelFamily:        modelFamily,
+		PersonaType:        personaType,
+		MaxIterations:      optimizeCmdMaxIter,
+		TargetScore:        optimizeCmdTargetScore,
+		AcceptFirstPassing: optimizeAcceptFirstPassing,
 		OptimizationGoals: map[string]float64{
 			"factual_accuracy": 0.3,
 			"code_quality":     0.3,
@@ -217,6 +220,8 @@ func displayOptimizationResults(result *optimizer.OptimizationResult, persona *m
 	fmt.Printf("Iterations: %d", len(result.Iterations))
 	if result.ConvergedAt > 0 {
 		fmt.Printf(" (converged at iteration %d)", result.ConvergedAt)
+	} else if result.ConvergedAt == 0 {
+		fmt.Print(" (original prompt met the target score)")
 	}
 	fmt.Println()
 
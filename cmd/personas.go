@@ -0,0 +1,20 @@
+package cmd
+
+import (
+	"github.com/jonwraymond/prompt-alchemy/internal/personas"
+	"github.com/jonwraymond/prompt-alchemy/internal/selection"
+	"github.com/sirupsen/logrus"
+)
+
+// loadPersonas registers the custom personas from personas.custom and
+// personas.dir so requests can name them
+func loadPersonas(presets selection.WeightPresets, logger *logrus.Logger) error {
+	loaded, err := personas.Load(presets)
+	if err != nil {
+		return err
+	}
+	for _, persona := range loaded {
+		logger.WithField("persona", persona.Type).Info("Loaded custom persona")
+	}
+	return nil
+}
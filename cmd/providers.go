@@ -56,7 +56,7 @@ func runProviders(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to write separator: %w", err)
 	}
 
-	allProviders := []string{"openai", "openrouter", "anthropic", "google", "ollama", "grok"}
+	allProviders := []string{"openai", "openrouter", "anthropic", "google", "ollama", "grok", "mistral", "deepseek", "cohere", "perplexity", "azure", "bedrock"}
 
 	for _, providerName := range allProviders {
 		provider, err := registry.Get(providerName)
@@ -89,6 +89,12 @@ func runProviders(cmd *cobra.Command, args []string) error {
 					if embeddingModel == "" {
 						embeddingModel = "nomic-embed-text"
 					}
+				case "mistral":
+					embeddingModel = viper.GetString("providers.mistral.embedding_model")
+				case "azure":
+					embeddingModel = viper.GetString("providers.azure.embedding_deployment")
+				case "bedrock":
+					embeddingModel = viper.GetString("providers.bedrock.embedding_model")
 				}
 			} else {
 				embeddings = "❌ (fallback available)"
@@ -109,6 +115,18 @@ func runProviders(cmd *cobra.Command, args []string) error {
 				model = viper.GetString("providers.ollama.model")
 			case "grok":
 				model = viper.GetString("providers.grok.model")
+			case "mistral":
+				model = viper.GetString("providers.mistral.model")
+			case "deepseek":
+				model = viper.GetString("providers.deepseek.model")
+			case "cohere":
+				model = viper.GetString("providers.cohere.model")
+			case "perplexity":
+				model = viper.GetString("providers.perplexity.model")
+			case "azure":
+				model = viper.GetString("providers.azure.deployment")
+			case "bedrock":
+				model = viper.GetString("providers.bedrock.model")
 			}
 
 			if model == "" {
@@ -167,3 +185,15 @@ func runProviders(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+// configuredProviders returns the provider sections under "providers",
+// leaving out shared settings such as fallback_chain
+func configuredProviders() map[string]interface{} {
+	configured := viper.GetStringMap("providers")
+	for name := range configured {
+		if providers.IsSettingsKey(name) {
+			delete(configured, name)
+		}
+	}
+	return configured
+}
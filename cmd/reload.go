@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/jonwraymond/prompt-alchemy/pkg/providers"
+	"github.com/sirupsen/logrus"
+)
+
+// newReloader returns a Reloader that re-registers the configured providers
+// into registry
+func newReloader(registry *providers.Registry, logger *logrus.Logger) *providers.Reloader {
+	return providers.NewReloader(registry, func(next *providers.Registry) error {
+		return registerProviders(next, logger)
+	})
+}
+
+// reloadOnSIGHUP reloads the configuration each time the process receives
+// SIGHUP, until ctx is done. A failed reload leaves the running
+// configuration in place.
+func reloadOnSIGHUP(ctx context.Context, reloader *providers.Reloader, logger *logrus.Logger) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(hup)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				logger.Info("SIGHUP received, reloading configuration")
+				if _, err := reloader.Reload(); err != nil {
+					logger.WithError(err).Error("Failed to reload configuration")
+				}
+			}
+		}
+	}()
+}
@@ -105,9 +105,26 @@ func init() {
 	viper.SetDefault("generation.default_max_tokens", 2000)
 	viper.SetDefault("generation.default_count", 3)
 	viper.SetDefault("generation.use_parallel", true)
+	viper.SetDefault("generation.max_concurrency", 8)
+	viper.SetDefault("generation.phase_timeout", "0s") // 0 = a phase may use the whole request's time
 	viper.SetDefault("generation.default_target_model", "claude-4-sonnet-20250522")
 	viper.SetDefault("generation.default_embedding_model", "text-embedding-3-small")
 	viper.SetDefault("generation.default_embedding_dimensions", 1536)
+	viper.SetDefault("generation.min_judge_confidence", 0.0)      // 0 disables the confidence check
+	viper.SetDefault("generation.judge_fallback_to_ranker", true) // Keep the ranker's pick when the judge is unsure
+	viper.SetDefault("generation.phase_selection", "all")         // HTTP default: all, best or cascade
+	viper.SetDefault("generation.max_concurrent", 0)              // 0 = unlimited in-flight generations per server
+	viper.SetDefault("generation.queue_timeout", "10s")           // Wait for a free slot before answering 429
+
+	viper.SetDefault("embeddings.backfill_batch_size", 20)
+	viper.SetDefault("embeddings.backfill_batch_delay", "1s") // Pause between backfill batches
+	viper.SetDefault("embeddings.cache.size", 1000)           // Embeddings kept in memory, 0 disables the cache
+	viper.SetDefault("embeddings.cache.ttl", "1h")            // 0 = cached embeddings never expire
+
+	viper.SetDefault("maintenance.decay_rate", 0.01)         // Relevance lost per idle day, compounding
+	viper.SetDefault("maintenance.max_prompts", 10000)       // 0 = no cap on stored prompts
+	viper.SetDefault("maintenance.min_relevance_score", 0.1) // Old prompts scoring below it are removed
+	viper.SetDefault("maintenance.min_age", "168h")          // Newer prompts are never removed
 
 	viper.SetDefault("phases.idea.provider", "openai")
 	viper.SetDefault("phases.human.provider", "anthropic")
@@ -164,6 +181,7 @@ func init() {
 	rootCmd.AddCommand(metricsCmd)
 	rootCmd.AddCommand(configCmd)
 	rootCmd.AddCommand(validateCmd)
+	rootCmd.AddCommand(doctorCmd)
 	rootCmd.AddCommand(providersCmd)
 	rootCmd.AddCommand(optimizeCmd)
 	rootCmd.AddCommand(updateCmd)
@@ -126,27 +126,15 @@ func runSemanticSearch(ctx context.Context, store *storage.Storage, query string
 		return fmt.Errorf("failed to initialize providers: %w", err)
 	}
 
-	// Get embedding for the query
-	embeddingProvider, err := registry.Get(providers.ProviderOpenAI) // Default to OpenAI for embeddings
-	if err != nil {
-		return fmt.Errorf("failed to get embedding provider: %w", err)
-	}
-
-	if !embeddingProvider.SupportsEmbeddings() {
-		return fmt.Errorf("embedding provider does not support embeddings")
-	}
-
-	queryEmbedding, err := embeddingProvider.GetEmbedding(ctx, query, registry)
-	if err != nil {
-		return fmt.Errorf("failed to get query embedding: %w", err)
-	}
-
-	prompts, err := store.SearchSimilarPrompts(ctx, queryEmbedding, searchLimit)
+	outcome, err := store.SearchWithFallback(ctx, query, searchLimit, true, providers.NewQueryEmbedder(registry))
 	if err != nil {
 		return fmt.Errorf("semantic search failed: %w", err)
 	}
+	if outcome.Fallback {
+		logger.Warnf("Embeddings unavailable (%s), showing text search results instead", outcome.FallbackReason)
+	}
 
-	return outputSearchResults(prompts, "semantic")
+	return outputSearchResults(outcome.Prompts, outcome.SearchType)
 }
 
 func outputSearchResults(prompts []*models.Prompt, searchType string) error {
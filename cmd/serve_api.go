@@ -7,7 +7,9 @@ import (
 	"github.com/jonwraymond/prompt-alchemy/internal/engine"
 	"github.com/jonwraymond/prompt-alchemy/internal/http"
 	"github.com/jonwraymond/prompt-alchemy/internal/learning"
+	"github.com/jonwraymond/prompt-alchemy/internal/observability/metrics"
 	"github.com/jonwraymond/prompt-alchemy/internal/ranking"
+	"github.com/jonwraymond/prompt-alchemy/internal/selection"
 	"github.com/jonwraymond/prompt-alchemy/internal/storage"
 	"github.com/jonwraymond/prompt-alchemy/pkg/providers"
 	"github.com/spf13/cobra"
@@ -34,6 +36,15 @@ func runServeAPI(cmd *cobra.Command, args []string) error {
 	// Initialize logger
 	logger := setupLogger()
 
+	// Fail fast on judge weights that would otherwise be replaced at runtime
+	presets, err := selection.LoadWeightPresets()
+	if err != nil {
+		return err
+	}
+	if err := loadPersonas(presets, logger); err != nil {
+		return err
+	}
+
 	// Initialize storage
 	store, err := storage.NewStorage(viper.GetString("data_dir"), logger)
 	if err != nil {
@@ -47,11 +58,25 @@ func runServeAPI(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to register providers: %w", err)
 	}
 
+	// Reload providers on SIGHUP or POST /api/v1/admin/reload
+	reloader := newReloader(registry, logger)
+	reloadOnSIGHUP(ctx, reloader, logger)
+
 	// Initialize engine
 	engine := engine.NewEngine(registry, logger)
+	engine.SetContextRetriever(store)
+
+	// Generations are exported at /metrics
+	appMetrics, err := metrics.NewMetrics(metrics.LoadConfig(), logger)
+	if err != nil {
+		return fmt.Errorf("failed to initialize metrics: %w", err)
+	}
+	engine.SetGenerationObserver(appMetrics)
+	store.SetRetryObserver(appMetrics.RecordStorageRetry)
 
 	// Initialize ranker
 	ranker := ranking.NewRanker(store, registry, logger)
+	engine.SetShadowScorer(ranker)
 
 	// Initialize learner (optional)
 	var learner *learning.LearningEngine
@@ -73,6 +98,8 @@ func runServeAPI(cmd *cobra.Command, args []string) error {
 
 	// Create and start HTTP server
 	server := http.NewSimpleServer(store, registry, engine, ranker, learner, logger)
+	server.SetReloader(reloader)
+	server.SetMetrics(appMetrics)
 
 	logger.WithField("port", viper.GetInt("http.port")).Info("Starting HTTP API server")
 
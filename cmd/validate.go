@@ -166,7 +166,7 @@ func validateConfiguration() ValidationResult {
 
 func validateProviders() []ValidationIssue {
 	var issues []ValidationIssue
-	providers := viper.GetStringMap("providers")
+	providers := configuredProviders()
 
 	if len(providers) == 0 {
 		issues = append(issues, ValidationIssue{
@@ -282,7 +282,7 @@ func validateProviderSpecific(providerName, providerPath string) []ValidationIss
 func validatePhases() []ValidationIssue {
 	var issues []ValidationIssue
 	phases := viper.GetStringMap("phases")
-	providers := viper.GetStringMap("providers")
+	providers := configuredProviders()
 
 	if len(phases) == 0 {
 		issues = append(issues, ValidationIssue{
@@ -602,7 +602,7 @@ func validateDataDirectory() []ValidationIssue {
 // Suggestion functions
 func suggestProviderOptimizations() []ValidationSuggestion {
 	var suggestions []ValidationSuggestion
-	providers := viper.GetStringMap("providers")
+	providers := configuredProviders()
 
 	// Suggest additional providers for redundancy
 	if len(providers) == 1 {
@@ -22,6 +22,7 @@ type WebServer struct {
 	templates  *template.Template
 	apiBaseURL string
 	httpClient *http.Client
+	upstream   UpstreamConfig
 }
 
 // Provider represents a prompt generation provider
@@ -89,9 +90,11 @@ func main() {
 		apiBaseURL = "http://localhost:8080"
 	}
 
+	// Timeouts are applied per request based on the endpoint, see UpstreamConfig
 	server := &WebServer{
 		apiBaseURL: apiBaseURL,
-		httpClient: &http.Client{Timeout: 150 * time.Second},
+		httpClient: &http.Client{},
+		upstream:   LoadUpstreamConfig(),
 	}
 
 	// Load alchemical templates with custom functions
@@ -151,6 +154,12 @@ func (s *WebServer) handleHome(w http.ResponseWriter, r *http.Request) {
 			{Name: "anthropic", DisplayName: "Anthropic (Claude)", Available: true},
 			{Name: "google", DisplayName: "Google (Gemini)", Available: true},
 			{Name: "grok", DisplayName: "Grok (xAI)", Available: true},
+			{Name: "mistral", DisplayName: "Mistral", Available: true},
+			{Name: "deepseek", DisplayName: "DeepSeek", Available: true},
+			{Name: "cohere", DisplayName: "Cohere", Available: true},
+			{Name: "perplexity", DisplayName: "Perplexity", Available: true},
+			{Name: "azure", DisplayName: "Azure OpenAI", Available: true},
+			{Name: "bedrock", DisplayName: "AWS Bedrock", Available: true},
 			{Name: "openrouter", DisplayName: "OpenRouter", Available: true},
 			{Name: "ollama", DisplayName: "Ollama (Local)", Available: false},
 		},
@@ -269,8 +278,18 @@ func (s *WebServer) handleGenerate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	apiURL := fmt.Sprintf("%s/api/v1/prompts/generate", s.apiBaseURL)
-	resp, err := s.httpClient.Post(apiURL, "application/json", bytes.NewBuffer(jsonData))
+	apiPath := "/api/v1/prompts/generate"
+	ctx, cancel := s.withUpstreamTimeout(r.Context(), apiPath)
+	defer cancel()
+
+	apiReq, err := http.NewRequestWithContext(ctx, http.MethodPost, s.apiBaseURL+apiPath, bytes.NewBuffer(jsonData))
+	if err != nil {
+		http.Error(w, "Failed to create API request", http.StatusInternalServerError)
+		return
+	}
+	apiReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.doUpstream(apiReq)
 	if err != nil {
 		s.renderError(w, fmt.Sprintf("API request failed: %v", err))
 		return
@@ -313,8 +332,16 @@ func (s *WebServer) handleGenerate(w http.ResponseWriter, r *http.Request) {
 
 // handleGetProviders returns available providers
 func (s *WebServer) handleGetProviders(w http.ResponseWriter, r *http.Request) {
-	apiURL := fmt.Sprintf("%s/api/v1/providers", s.apiBaseURL)
-	resp, err := s.httpClient.Get(apiURL)
+	apiPath := "/api/v1/providers"
+	ctx, cancel := s.withUpstreamTimeout(r.Context(), apiPath)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.apiBaseURL+apiPath, nil)
+	if err != nil {
+		http.Error(w, "Failed to get providers", http.StatusInternalServerError)
+		return
+	}
+	resp, err := s.doUpstream(req)
 	if err != nil {
 		http.Error(w, "Failed to get providers", http.StatusInternalServerError)
 		return
@@ -364,8 +391,11 @@ func (s *WebServer) proxyToAPI(w http.ResponseWriter, r *http.Request) {
 	// Debug logging
 	log.Printf("Proxying %s %s to %s", r.Method, r.URL.Path, targetURL)
 
+	ctx, cancel := s.withUpstreamTimeout(r.Context(), targetPath)
+	defer cancel()
+
 	// Create new request
-	req, err := http.NewRequest(r.Method, targetURL, r.Body)
+	req, err := http.NewRequestWithContext(ctx, r.Method, targetURL, r.Body)
 	if err != nil {
 		log.Printf("Failed to create proxy request: %v", err)
 		http.Error(w, "Failed to create proxy request", http.StatusInternalServerError)
@@ -380,7 +410,7 @@ func (s *WebServer) proxyToAPI(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Make the request
-	resp, err := s.httpClient.Do(req)
+	resp, err := s.doUpstream(req)
 	if err != nil {
 		log.Printf("API request failed: %v", err)
 		http.Error(w, "API request failed", http.StatusBadGateway)
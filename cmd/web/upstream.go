@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// UpstreamConfig controls timeouts and retries for requests to the API server.
+// Status polls get a short budget so a hung API shows up quickly in health
+// checks, while generation gets the long budget it needs to run every phase.
+type UpstreamConfig struct {
+	StatusTimeout   time.Duration
+	GenerateTimeout time.Duration
+	DefaultTimeout  time.Duration
+	MaxRetries      int
+	RetryBackoff    time.Duration
+}
+
+// DefaultUpstreamConfig returns the timeouts and retry policy used when no
+// environment overrides are set
+func DefaultUpstreamConfig() UpstreamConfig {
+	return UpstreamConfig{
+		StatusTimeout:   5 * time.Second,
+		GenerateTimeout: 150 * time.Second,
+		DefaultTimeout:  30 * time.Second,
+		MaxRetries:      2,
+		RetryBackoff:    250 * time.Millisecond,
+	}
+}
+
+// LoadUpstreamConfig reads overrides from WEB_API_STATUS_TIMEOUT,
+// WEB_API_GENERATE_TIMEOUT, WEB_API_TIMEOUT, WEB_API_MAX_RETRIES and
+// WEB_API_RETRY_BACKOFF. Durations use Go syntax ("5s", "2m"); invalid
+// values are logged and the default is kept.
+func LoadUpstreamConfig() UpstreamConfig {
+	cfg := DefaultUpstreamConfig()
+	cfg.StatusTimeout = envDuration("WEB_API_STATUS_TIMEOUT", cfg.StatusTimeout)
+	cfg.GenerateTimeout = envDuration("WEB_API_GENERATE_TIMEOUT", cfg.GenerateTimeout)
+	cfg.DefaultTimeout = envDuration("WEB_API_TIMEOUT", cfg.DefaultTimeout)
+	cfg.RetryBackoff = envDuration("WEB_API_RETRY_BACKOFF", cfg.RetryBackoff)
+
+	if value := os.Getenv("WEB_API_MAX_RETRIES"); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+			cfg.MaxRetries = n
+		} else {
+			log.Printf("Ignoring invalid WEB_API_MAX_RETRIES %q", value)
+		}
+	}
+	return cfg
+}
+
+func envDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.Printf("Ignoring invalid %s %q", key, value)
+		return fallback
+	}
+	return d
+}
+
+// TimeoutFor picks the timeout for an API path. Generation and the SSE
+// streams get the long budget; health, status and provider polls the short one.
+func (c UpstreamConfig) TimeoutFor(path string) time.Duration {
+	switch {
+	case strings.Contains(path, "/feature/"):
+		return c.DefaultTimeout
+	case strings.Contains(path, "/generate"),
+		strings.Contains(path, "/optimize"),
+		strings.Contains(path, "/judge"),
+		strings.Contains(path, "/batch"),
+		strings.HasSuffix(path, "/flow-events"),
+		strings.HasSuffix(path, "-stream"):
+		return c.GenerateTimeout
+	case strings.HasSuffix(path, "/health"),
+		strings.HasSuffix(path, "status"),
+		strings.Contains(path, "/connection/"),
+		strings.HasSuffix(path, "/providers"):
+		return c.StatusTimeout
+	default:
+		return c.DefaultTimeout
+	}
+}
+
+// withUpstreamTimeout derives a request context bounded by the path's timeout
+func (s *WebServer) withUpstreamTimeout(ctx context.Context, path string) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, s.upstream.TimeoutFor(path))
+}
+
+// doUpstream sends req to the API server, retrying idempotent requests
+// without a body on network errors and 502/503/504 responses. Requests are
+// not retried once their context is done, so a timeout is never multiplied.
+func (s *WebServer) doUpstream(req *http.Request) (*http.Response, error) {
+	retries := 0
+	if isRetryableRequest(req) {
+		retries = s.upstream.MaxRetries
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := s.httpClient.Do(req)
+		if attempt >= retries || req.Context().Err() != nil || !shouldRetryUpstream(resp, err) {
+			return resp, err
+		}
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		wait := s.upstream.RetryBackoff << attempt
+		log.Printf("Retrying %s %s in %s (attempt %d/%d)", req.Method, req.URL.Path, wait, attempt+1, retries)
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+func isRetryableRequest(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return req.Body == nil || req.Body == http.NoBody
+	default:
+		return false
+	}
+}
+
+func shouldRetryUpstream(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadUpstreamConfig(t *testing.T) {
+	t.Setenv("WEB_API_STATUS_TIMEOUT", "2s")
+	t.Setenv("WEB_API_GENERATE_TIMEOUT", "5m")
+	t.Setenv("WEB_API_TIMEOUT", "not-a-duration")
+	t.Setenv("WEB_API_MAX_RETRIES", "0")
+
+	cfg := LoadUpstreamConfig()
+	defaults := DefaultUpstreamConfig()
+	assert.Equal(t, 2*time.Second, cfg.StatusTimeout)
+	assert.Equal(t, 5*time.Minute, cfg.GenerateTimeout)
+	assert.Equal(t, defaults.DefaultTimeout, cfg.DefaultTimeout)
+	assert.Equal(t, 0, cfg.MaxRetries)
+	assert.Equal(t, defaults.RetryBackoff, cfg.RetryBackoff)
+}
+
+func TestUpstreamConfig_TimeoutFor(t *testing.T) {
+	cfg := DefaultUpstreamConfig()
+
+	assert.Equal(t, cfg.GenerateTimeout, cfg.TimeoutFor("/api/v1/prompts/generate"))
+	assert.Equal(t, cfg.GenerateTimeout, cfg.TimeoutFor("/api/v1/optimize"))
+	assert.Equal(t, cfg.GenerateTimeout, cfg.TimeoutFor("/api/thinking-stream"))
+	assert.Equal(t, cfg.StatusTimeout, cfg.TimeoutFor("/api/connection-status"))
+	assert.Equal(t, cfg.StatusTimeout, cfg.TimeoutFor("/api/v1/status"))
+	assert.Equal(t, cfg.StatusTimeout, cfg.TimeoutFor("/api/v1/providers"))
+	assert.Equal(t, cfg.StatusTimeout, cfg.TimeoutFor("/health"))
+	assert.Equal(t, cfg.DefaultTimeout, cfg.TimeoutFor("/api/v1/prompts"))
+	assert.Equal(t, cfg.DefaultTimeout, cfg.TimeoutFor("/api/feature/judge"))
+}
+
+func newTestWebServer(apiURL string, cfg UpstreamConfig) *WebServer {
+	return &WebServer{apiBaseURL: apiURL, httpClient: &http.Client{}, upstream: cfg}
+}
+
+func TestDoUpstream_RetriesIdempotentRequests(t *testing.T) {
+	var calls int32
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer api.Close()
+
+	cfg := DefaultUpstreamConfig()
+	cfg.RetryBackoff = time.Millisecond
+	s := newTestWebServer(api.URL, cfg)
+
+	req, err := http.NewRequest(http.MethodGet, api.URL+"/api/v1/providers", nil)
+	require.NoError(t, err)
+	resp, err := s.doUpstream(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestDoUpstream_DoesNotRetryPost(t *testing.T) {
+	var calls int32
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer api.Close()
+
+	cfg := DefaultUpstreamConfig()
+	cfg.RetryBackoff = time.Millisecond
+	s := newTestWebServer(api.URL, cfg)
+
+	req, err := http.NewRequest(http.MethodPost, api.URL+"/api/v1/prompts/generate", strings.NewReader(`{}`))
+	require.NoError(t, err)
+	resp, err := s.doUpstream(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestDoUpstream_StatusTimeout(t *testing.T) {
+	release := make(chan struct{})
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer api.Close()
+	defer close(release)
+
+	cfg := DefaultUpstreamConfig()
+	cfg.StatusTimeout = 50 * time.Millisecond
+	s := newTestWebServer(api.URL, cfg)
+
+	ctx, cancel := s.withUpstreamTimeout(context.Background(), "/api/v1/providers")
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, api.URL+"/api/v1/providers", nil)
+	require.NoError(t, err)
+
+	start := time.Now()
+	_, err = s.doUpstream(req)
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+}
@@ -3,6 +3,7 @@ package v1
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -10,6 +11,7 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"github.com/jonwraymond/prompt-alchemy/internal/engine"
+	"github.com/jonwraymond/prompt-alchemy/internal/helpers"
 	"github.com/jonwraymond/prompt-alchemy/internal/httputil"
 	"github.com/jonwraymond/prompt-alchemy/internal/learning"
 	"github.com/jonwraymond/prompt-alchemy/internal/ranking"
@@ -17,6 +19,7 @@ import (
 	"github.com/jonwraymond/prompt-alchemy/pkg/models"
 	"github.com/jonwraymond/prompt-alchemy/pkg/providers"
 	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
 )
 
 // V1Handler contains all dependencies for v1 API handlers
@@ -157,6 +160,9 @@ func (h *V1Handler) HandleStatus(w http.ResponseWriter, r *http.Request) {
 		"learning_mode": h.learner != nil,
 		"uptime":        time.Since(time.Now()).String(),
 	}
+	if h.registry != nil {
+		response["providers"] = h.registry.Health()
+	}
 	h.writeJSON(w, http.StatusOK, response)
 }
 
@@ -185,23 +191,20 @@ func (h *V1Handler) HandleGeneratePrompts(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	// Validate required fields
-	if req.Input == "" {
-		httputil.BadRequest(w, "Input is required")
-		return
+	var knownPhase func(models.Phase) bool
+	if h.engine != nil {
+		knownPhase = h.engine.KnownPhase
 	}
-
-	// Validate ranges
-	if req.Count < 0 {
-		httputil.BadRequest(w, "Count must be non-negative")
+	var fields models.FieldErrors
+	if err := models.ValidateGenerateRequest(req, knownPhase); errors.As(err, &fields) {
+		httputil.ValidationFailed(w, err.Error(), fields)
 		return
 	}
-	if req.Temperature < 0 {
-		httputil.BadRequest(w, "Temperature must be non-negative")
-		return
+	if req.SavePhases == nil {
+		req.SavePhases = viper.GetStringSlice("generation.save_phases")
 	}
-	if req.MaxTokens < 0 {
-		httputil.BadRequest(w, "MaxTokens must be non-negative")
+	if err := models.ValidateSavePhases(req.SavePhases); err != nil {
+		httputil.BadRequest(w, err.Error())
 		return
 	}
 
@@ -242,17 +245,19 @@ func (h *V1Handler) HandleGeneratePrompts(w http.ResponseWriter, r *http.Request
 	}
 
 	// Convert providers map
-	providers := make(map[models.Phase]string)
+	phaseProviders := make(map[models.Phase]string)
 	for phaseStr, provider := range req.Providers {
-		providers[models.Phase(phaseStr)] = provider
+		phaseProviders[models.Phase(phaseStr)] = provider
 	}
 
 	// Build PhaseConfigs from providers map or use defaults
 	phaseConfigs := make([]models.PhaseConfig, len(phases))
 	for i, phase := range phases {
 		provider := "openai" // Default to openai for tests and API calls without provider config
-		if providerName, exists := providers[phase]; exists && providerName != "" {
+		if providerName, exists := phaseProviders[phase]; exists && providerName != "" {
 			provider = providerName
+		} else if configured := helpers.PhaseProvider(phase); configured != "" {
+			provider = configured
 		}
 		phaseConfigs[i] = models.PhaseConfig{
 			Phase:    phase,
@@ -260,22 +265,39 @@ func (h *V1Handler) HandleGeneratePrompts(w http.ResponseWriter, r *http.Request
 		}
 	}
 
+	systemPrompts, err := models.SystemPromptOverrides(req.SystemPrompts)
+	if err != nil {
+		httputil.BadRequest(w, err.Error())
+		return
+	}
+
 	// Create models.GenerateOptions from the HTTP request
 	generateOpts := models.GenerateOptions{
 		Request: models.PromptRequest{
 			Input:         req.Input,
 			Phases:        phases,
 			Count:         req.Count,
-			Providers:     providers,
+			Providers:     phaseProviders,
 			Context:       req.Context,
 			Tags:          req.Tags,
 			Temperature:   req.Temperature,
 			MaxTokens:     req.MaxTokens,
 			Persona:       req.Persona,
 			TargetUseCase: req.TargetUseCase,
+
+			Variables:             req.Variables,
+			AllowMissingVariables: req.AllowMissing,
+			Seed:                  req.Seed,
 		},
-		PhaseConfigs: phaseConfigs,
-		UseParallel:  req.UseParallel,
+		PhaseConfigs:         phaseConfigs,
+		UseParallel:          req.UseParallel,
+		AutoSummarizeContext: req.AutoSummarizeContext,
+		SystemPrompts:        systemPrompts,
+		TruncateOnOverflow:   req.TruncateOnOverflow,
+	}
+	if _, err := generateOpts.Request.ResolveInput(); err != nil {
+		httputil.BadRequest(w, err.Error())
+		return
 	}
 
 	// Generate prompts using the engine
@@ -286,6 +308,20 @@ func (h *V1Handler) HandleGeneratePrompts(w http.ResponseWriter, r *http.Request
 	result, err := h.engine.Generate(ctx, generateOpts)
 	if err != nil {
 		h.logger.WithError(err).Error("Failed to generate prompts")
+		var overflow *providers.ContextTooLongError
+		if errors.As(err, &overflow) {
+			details := "phase " + string(overflow.Phase) + " overflowed the context window of " + overflow.Provider
+			if tokens := overflow.OverflowTokens(); tokens > 0 {
+				details += " by about " + strconv.Itoa(tokens) + " tokens"
+			}
+			httputil.WriteErrorWithDetails(w, http.StatusRequestEntityTooLarge, "CONTEXT_TOO_LONG", overflow.Error(), details)
+			return
+		}
+		var timedOut *engine.PhaseTimeoutError
+		if errors.As(err, &timedOut) {
+			httputil.WriteError(w, http.StatusGatewayTimeout, "PHASE_TIMEOUT", timedOut.Error())
+			return
+		}
 		httputil.InternalServerError(w, "Failed to generate prompts")
 		return
 	}
@@ -299,10 +335,15 @@ func (h *V1Handler) HandleGeneratePrompts(w http.ResponseWriter, r *http.Request
 		}
 	}
 
-	// Save prompts if requested
+	// Save prompts if requested, limited to the phases asked for
 	if req.Save {
-		for _, prompt := range result.Prompts {
-			// Set target use case on the prompt
+		var selected *models.Prompt
+		if len(rankings) > 0 {
+			selected = rankings[0].Prompt
+		}
+		for _, saved := range models.PromptsToSave(result.Prompts, selected, req.SavePhases) {
+			// Save a copy with the request's use case and persona attached
+			prompt := *saved
 			prompt.TargetUseCase = req.TargetUseCase
 			prompt.PersonaUsed = req.Persona
 
@@ -330,9 +371,12 @@ func (h *V1Handler) HandleGeneratePrompts(w http.ResponseWriter, r *http.Request
 			Rankings:  rankings,
 			SessionID: uuid.New(),
 			Metadata: models.GenerateMetadata{
-				Duration:       time.Since(start).String(),
-				PhaseCount:     len(phases),
-				GenerationTime: time.Now().Format(time.RFC3339),
+				Duration:           time.Since(start).String(),
+				PhaseCount:         len(phases),
+				GenerationTime:     time.Now().Format(time.RFC3339),
+				ContextSummarized:  result.ContextSummarized,
+				ContextTokensSaved: result.ContextTokensSaved,
+				PhaseTimeouts:      result.PhaseTimeouts,
 			},
 		}
 
@@ -441,14 +485,27 @@ func (h *V1Handler) ListPrompts(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// For now, return empty list since storage interface needs to be updated
-	prompts := []models.Prompt{}
-
 	// Calculate pagination
 	pagination := httputil.CalculatePagination(page, limit, total)
 
-	// Return paginated response
-	httputil.WritePaginatedJSON(w, http.StatusOK, prompts, pagination)
+	// Stream prompts straight from storage so large pages are never fully buffered
+	fields := httputil.ParseFields(r)
+	stream := httputil.NewArrayStreamer(w, http.StatusOK)
+	offset := (page - 1) * limit
+	err = h.storage.StreamPrompts(r.Context(), limit, offset, func(p *models.Prompt) error {
+		item, err := fields.Project(p)
+		if err != nil {
+			return err
+		}
+		return stream.Encode(item)
+	})
+	if err != nil {
+		h.logger.WithError(err).WithField("streamed", stream.Count()).Error("Failed to stream prompts")
+		_ = stream.Abort("INTERNAL_SERVER_ERROR", "Failed to list prompts")
+		return
+	}
+
+	_ = stream.Close(pagination)
 }
 
 // CreatePrompt handles POST /api/v1/prompts
@@ -568,12 +625,29 @@ func (h *V1Handler) SearchPrompts(w http.ResponseWriter, r *http.Request) {
 		"limit":    limit,
 	}).Debug("Searching prompts")
 
-	// For now, return empty results
+	outcome, err := h.storage.SearchWithFallback(r.Context(), query, limit, semantic, providers.NewQueryEmbedder(h.registry))
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to search prompts")
+		httputil.InternalServerError(w, "Failed to search prompts")
+		return
+	}
+
+	prompts, err := httputil.ParseFields(r).Project(outcome.Prompts)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to project prompt fields")
+		httputil.InternalServerError(w, "Failed to search prompts")
+		return
+	}
+
 	response := map[string]interface{}{
-		"prompts":  []models.Prompt{},
+		"prompts":  prompts,
 		"query":    query,
-		"count":    0,
+		"count":    len(outcome.Prompts),
 		"semantic": semantic,
+		"metadata": map[string]interface{}{
+			"search_type":     outcome.SearchType,
+			"search_fallback": outcome.Fallback,
+		},
 	}
 
 	httputil.OK(w, response)
@@ -669,6 +743,29 @@ func (h *V1Handler) SubmitFeedback(w http.ResponseWriter, r *http.Request) {
 	httputil.NotImplemented(w, "Learning feedback not implemented yet")
 }
 
+// StartReindex handles POST /api/v1/admin/reindex. The search index is
+// rebuilt in the background; poll GET /api/v1/admin/reindex/status.
+func (h *V1Handler) StartReindex(w http.ResponseWriter, r *http.Request) {
+	err := h.storage.StartReindex(r.Context(), providers.NewQueryEmbedder(h.registry))
+	switch {
+	case errors.Is(err, storage.ErrReindexRunning), errors.Is(err, storage.ErrBackfillRunning):
+		httputil.WriteError(w, http.StatusConflict, "CONFLICT", err.Error())
+		return
+	case err != nil:
+		h.logger.WithError(err).Warn("Failed to start search index rebuild")
+		httputil.WriteError(w, http.StatusServiceUnavailable, "SERVICE_UNAVAILABLE", err.Error())
+		return
+	}
+
+	h.logger.Info("Started search index rebuild")
+	httputil.WriteJSON(w, http.StatusAccepted, h.storage.ReindexStatus())
+}
+
+// GetReindexStatus handles GET /api/v1/admin/reindex/status
+func (h *V1Handler) GetReindexStatus(w http.ResponseWriter, r *http.Request) {
+	httputil.OK(w, h.storage.ReindexStatus())
+}
+
 // Request/Response types for API handlers
 type CreatePromptRequest struct {
 	Content     string   `json:"content"`
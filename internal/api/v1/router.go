@@ -26,6 +26,16 @@ type RouterConfig struct {
 	EnableRateLimit bool
 	RequestsPerMin  int
 	Burst           int
+	// RateLimitBackend is "memory" (per instance) or "storage" (shared by
+	// every instance using the same database)
+	RateLimitBackend string
+	// MaxBodyBytes bounds request bodies (0 = DefaultMaxBodyBytes)
+	MaxBodyBytes int64
+
+	// MaxConcurrentGenerations caps in-flight generations (0 = unlimited)
+	MaxConcurrentGenerations int
+	// GenerationQueueTimeout is how long excess generations wait for a slot
+	GenerationQueueTimeout time.Duration
 }
 
 // RouterDependencies contains all dependencies needed by the router
@@ -49,6 +59,9 @@ type Router struct {
 	promptHandler   *V1Handler
 	systemHandler   *SystemHandler
 	providerHandler *ProviderHandler
+
+	// generationLimiter is shared by every route that runs generations
+	generationLimiter *httpMiddleware.ConcurrencyLimiter
 }
 
 // NewRouter creates a new v1 API router with all dependencies
@@ -72,6 +85,11 @@ func NewRouter(config RouterConfig, deps RouterDependencies) *Router {
 		promptHandler:   promptHandler,
 		systemHandler:   systemHandler,
 		providerHandler: providerHandler,
+		generationLimiter: httpMiddleware.NewConcurrencyLimiter(
+			config.MaxConcurrentGenerations,
+			config.GenerationQueueTimeout,
+			deps.Logger,
+		),
 	}
 }
 
@@ -88,6 +106,20 @@ func (rt *Router) SetupRoutes() http.Handler {
 		EnableRateLimit: rt.config.EnableRateLimit,
 		RequestsPerMin:  rt.config.RequestsPerMin,
 		Burst:           rt.config.Burst,
+		MaxBodyBytes:    rt.config.MaxBodyBytes,
+	}
+
+	if rt.config.EnableRateLimit {
+		var counter httpMiddleware.RateLimitCounter
+		if rt.deps.Storage != nil {
+			counter = rt.deps.Storage
+		}
+		limiter, err := httpMiddleware.NewRateLimiter(rt.config.RateLimitBackend, counter, rt.config.RequestsPerMin, rt.config.Burst)
+		if err != nil {
+			rt.deps.Logger.WithError(err).Error("Invalid rate limit backend, limiting in memory")
+		} else {
+			middlewareConfig.RateLimiter = limiter
+		}
 	}
 
 	middlewares := httpMiddleware.SetupMiddleware(rt.deps.Logger, middlewareConfig)
@@ -132,6 +164,9 @@ func (rt *Router) mountSystemRoutes(r chi.Router) {
 
 // mountV1Routes mounts all v1 API routes
 func (rt *Router) mountV1Routes(r chi.Router) {
+	// All v1 request bodies are JSON
+	r.Use(httpMiddleware.RequireJSON())
+
 	// System endpoints
 	r.Get("/status", rt.systemHandler.GetStatus)
 	r.Get("/info", rt.systemHandler.GetInfo)
@@ -150,7 +185,7 @@ func (rt *Router) mountV1Routes(r chi.Router) {
 		r.Post("/", rt.promptHandler.CreatePrompt)
 
 		// Generate prompts (main functionality)
-		r.Post("/generate", rt.promptHandler.HandleGeneratePrompts)
+		r.With(rt.generationLimiter.Middleware).Post("/generate", rt.promptHandler.HandleGeneratePrompts)
 
 		// Search prompts
 		r.Get("/search", rt.promptHandler.SearchPrompts)
@@ -180,7 +215,7 @@ func (rt *Router) mountV1Routes(r chi.Router) {
 
 	// Batch processing endpoints
 	r.Route("/batch", func(r chi.Router) {
-		r.Post("/generate", rt.promptHandler.BatchGenerate)
+		r.With(rt.generationLimiter.Middleware).Post("/generate", rt.promptHandler.BatchGenerate)
 	})
 
 	// Analytics endpoints
@@ -197,6 +232,12 @@ func (rt *Router) mountV1Routes(r chi.Router) {
 		})
 	}
 
+	// Admin endpoints
+	r.Route("/admin", func(r chi.Router) {
+		r.Post("/reindex", rt.promptHandler.StartReindex)
+		r.Get("/reindex/status", rt.promptHandler.GetReindexStatus)
+	})
+
 	// Node activation endpoint
 	r.Post("/node/activate", rt.promptHandler.ActivateNode)
 
@@ -414,7 +455,22 @@ func (h *ProviderHandler) getProviderModels(providerName string) []string {
 		return []string{"anthropic/claude-3-opus", "openai/gpt-4-turbo", "google/gemini-pro"}
 	case providers.ProviderGrok:
 		return []string{"grok-1", "grok-2", "grok-4"}
+	case providers.ProviderMistral:
+		return []string{"mistral-large-latest", "mistral-medium-latest", "mistral-small-latest", "codestral-latest", "ministral-8b-latest", "open-mistral-nemo"}
+	case providers.ProviderDeepSeek:
+		return []string{providers.DefaultDeepSeekModel, providers.DeepSeekReasonerModel}
+	case providers.ProviderCohere:
+		return []string{providers.DefaultCohereModel, "command-r-plus-08-2024", "command-r-08-2024", "command-r7b-12-2024"}
+	case providers.ProviderPerplexity:
+		return providers.PerplexityModels()
+	case providers.ProviderAzure:
+		return providers.AzureDeployments()
+	case providers.ProviderBedrock:
+		return providers.BedrockModels()
 	default:
+		if custom := providers.CustomProviderModels(providerName); custom != nil {
+			return custom
+		}
 		return []string{}
 	}
 }
@@ -422,12 +478,13 @@ func (h *ProviderHandler) getProviderModels(providerName string) []string {
 // DefaultRouterConfig returns default router configuration
 func DefaultRouterConfig() RouterConfig {
 	return RouterConfig{
-		EnableCORS:      true,
-		CORSOrigins:     []string{"*"},
-		EnableAuth:      false,
-		APIKeys:         []string{},
-		EnableRateLimit: true,
-		RequestsPerMin:  60,
-		Burst:           100,
+		EnableCORS:       true,
+		CORSOrigins:      []string{"*"},
+		EnableAuth:       false,
+		APIKeys:          []string{},
+		EnableRateLimit:  true,
+		RequestsPerMin:   60,
+		Burst:            100,
+		RateLimitBackend: httpMiddleware.RateLimitBackendMemory,
 	}
 }
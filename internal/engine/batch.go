@@ -0,0 +1,145 @@
+package engine
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jonwraymond/prompt-alchemy/internal/helpers"
+	"github.com/jonwraymond/prompt-alchemy/internal/log"
+	"github.com/jonwraymond/prompt-alchemy/internal/phases"
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/spf13/viper"
+)
+
+// errBatchStopped is the error reported for jobs skipped after a failure
+const errBatchStopped = "not run: an earlier job failed"
+
+// BatchOptions controls how GenerateBatch runs its jobs
+type BatchOptions struct {
+	Workers    int           // jobs run at once, at least 1
+	SkipErrors bool          // keep running jobs after one fails
+	JobTimeout time.Duration // bound on each job, none if zero
+}
+
+// ApplyBatchDefaults fills unset fields of input from the generation
+// defaults in the configuration
+func ApplyBatchDefaults(input models.BatchInput) models.BatchInput {
+	// Apply defaults from configuration or command-line flags
+	if input.Count == 0 {
+		input.Count = viper.GetInt("generation.default_count")
+		if input.Count == 0 {
+			input.Count = 3
+		}
+	}
+
+	if input.Temperature == 0 {
+		input.Temperature = viper.GetFloat64("generation.default_temperature")
+		if input.Temperature == 0 {
+			input.Temperature = 0.7
+		}
+	}
+
+	if input.MaxTokens == 0 {
+		input.MaxTokens = viper.GetInt("generation.default_max_tokens")
+		if input.MaxTokens == 0 {
+			input.MaxTokens = 2000
+		}
+	}
+
+	if input.Provider == "" {
+		input.Provider = viper.GetString("generation.default_provider")
+	}
+
+	return input
+}
+
+// GenerateBatch runs every input through Generate on a pool of
+// opts.Workers workers and returns one result per input, in input order.
+// Unless opts.SkipErrors is set, the first failure stops the jobs that
+// haven't started yet; they are reported as failed.
+func (e *Engine) GenerateBatch(ctx context.Context, inputs []models.BatchInput, opts BatchOptions) []models.BatchResult {
+	workers := opts.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(inputs) {
+		workers = len(inputs)
+	}
+
+	results := make([]models.BatchResult, len(inputs))
+	jobs := make(chan int)
+	var failed atomic.Bool
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if failed.Load() && !opts.SkipErrors {
+					results[i] = models.BatchResult{ID: inputs[i].ID, Input: inputs[i], Error: errBatchStopped, Timestamp: time.Now()}
+					continue
+				}
+				results[i] = e.generateBatchInput(ctx, inputs[i], opts.JobTimeout)
+				if !results[i].Success {
+					failed.Store(true)
+				}
+			}
+		}()
+	}
+
+	for i := range inputs {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+// generateBatchInput runs a single batch job
+func (e *Engine) generateBatchInput(ctx context.Context, input models.BatchInput, timeout time.Duration) models.BatchResult {
+	logger := log.WithContext(ctx, e.logger).WithField("batch_id", input.ID)
+	startTime := time.Now()
+	result := models.BatchResult{
+		ID:        input.ID,
+		Input:     input,
+		Timestamp: startTime,
+	}
+
+	input = ApplyBatchDefaults(input)
+	phaseList := helpers.ParsePhases(input.Phases)
+	if len(phaseList) == 0 {
+		phaseList = append([]models.Phase(nil), phases.BuiltinPhases...)
+	}
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	generated, err := e.Generate(ctx, models.GenerateOptions{
+		Request: models.PromptRequest{
+			Input:       input.Input,
+			Phases:      phaseList,
+			Count:       input.Count,
+			Temperature: input.Temperature,
+			MaxTokens:   input.MaxTokens,
+			Tags:        helpers.ParseTags(input.Tags),
+		},
+		PhaseConfigs: helpers.BuildPhaseConfigs(phaseList, input.Provider),
+		Persona:      input.Persona,
+	})
+	result.Duration = time.Since(startTime)
+	if err != nil {
+		logger.WithError(err).Warn("Batch job failed")
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Success = true
+	result.Prompts = generated.Prompts
+	logger.Debugf("Generated %d prompts in %v", len(generated.Prompts), result.Duration)
+	return result
+}
@@ -0,0 +1,72 @@
+package engine
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/spf13/viper"
+)
+
+// defaultMaxConcurrency bounds parallel provider calls when
+// generation.max_concurrency is unset
+const defaultMaxConcurrency = 8
+
+// callLimiter is a semaphore bounding the provider calls the engine's
+// parallel path runs at once. It is shared by every generation the engine
+// serves, so the limit holds however many phases, variants and requests are
+// in flight.
+type callLimiter struct {
+	slots    chan struct{}
+	inFlight atomic.Int64
+	observe  func(inFlight int) // see Engine.SetInFlightObserver
+}
+
+// newCallLimiter reads generation.max_concurrency, falling back to
+// defaultMaxConcurrency when it is unset or not positive
+func newCallLimiter() *callLimiter {
+	limit := viper.GetInt("generation.max_concurrency")
+	if limit <= 0 {
+		limit = defaultMaxConcurrency
+	}
+	return &callLimiter{slots: make(chan struct{}, limit)}
+}
+
+// acquire waits for a free slot, giving up when ctx is done
+func (l *callLimiter) acquire(ctx context.Context) error {
+	select {
+	case l.slots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	l.report(l.inFlight.Add(1))
+	return nil
+}
+
+// release frees a slot taken by acquire
+func (l *callLimiter) release() {
+	l.report(l.inFlight.Add(-1))
+	<-l.slots
+}
+
+func (l *callLimiter) report(inFlight int64) {
+	if l.observe != nil {
+		l.observe(int(inFlight))
+	}
+}
+
+// InFlight returns the number of parallel provider calls running now
+func (e *Engine) InFlight() int {
+	return int(e.calls.inFlight.Load())
+}
+
+// MaxConcurrency returns the most parallel provider calls run at once
+func (e *Engine) MaxConcurrency() int {
+	return cap(e.calls.slots)
+}
+
+// SetInFlightObserver sets a function told the number of parallel provider
+// calls each time it changes, e.g. to export it as a metric. Set it before
+// generating.
+func (e *Engine) SetInFlightObserver(observe func(inFlight int)) {
+	e.calls.observe = observe
+}
@@ -0,0 +1,59 @@
+package engine
+
+import (
+	"unicode/utf8"
+
+	"github.com/jonwraymond/prompt-alchemy/pkg/providers"
+	"github.com/spf13/viper"
+)
+
+// overflowMargin is the share of the computed budget a truncated input may
+// use, leaving room for the gap between our estimate and the tokenizer
+const overflowMargin = 0.9
+
+// truncateOnOverflow reports whether a phase input that overflowed the
+// provider's context window is retried truncated, as the request asked or
+// generation.truncate_on_overflow sets by default
+func truncateOnOverflow(requested bool) bool {
+	return requested || viper.GetBool("generation.truncate_on_overflow")
+}
+
+// truncateToFit cuts input so that the request it is part of fits the
+// overflowing context window. sent is everything the failed request sent
+// (system prompt and prompt content, input included) and maxTokens the
+// output it asked for. Token counts reported by the provider calibrate the
+// characters per token; otherwise calculateInputTokens' estimate is used.
+// It returns false when the window is unknown or nothing of the input
+// would be left.
+func truncateToFit(input string, sent string, maxTokens int, overflow *providers.ContextTooLongError) (string, bool) {
+	if overflow.ContextWindow <= 0 || input == "" {
+		return "", false
+	}
+
+	charsPerToken := 4.0
+	if overflow.InputTokens > 0 {
+		charsPerToken = float64(len(sent)) / float64(overflow.InputTokens)
+	}
+	overheadTokens := float64(len(sent)-len(input)) / charsPerToken
+	budget := (float64(overflow.ContextWindow-maxTokens) - overheadTokens) * overflowMargin
+	keep := int(budget * charsPerToken)
+	if keep <= 0 {
+		return "", false
+	}
+	if keep >= len(input) {
+		// Our estimate says it fits; cut by the reported overflow instead
+		keep = len(input) - int(float64(overflow.OverflowTokens())*charsPerToken/overflowMargin)
+		if keep <= 0 || keep >= len(input) {
+			return "", false
+		}
+	}
+
+	// Cut on a rune boundary
+	for keep > 0 && !utf8.RuneStart(input[keep]) {
+		keep--
+	}
+	if keep == 0 {
+		return "", false
+	}
+	return input[:keep], true
+}
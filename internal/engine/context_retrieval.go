@@ -0,0 +1,74 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jonwraymond/prompt-alchemy/internal/log"
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/jonwraymond/prompt-alchemy/pkg/providers"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// DefaultContextTopK is how many chunks of the requested context documents
+// are added to the context when context.retrieval.top_k is not set
+const DefaultContextTopK = 4
+
+// ContextRetriever finds the chunks of ingested context documents most
+// similar to an embedding, see storage.Storage.SearchContextChunks
+type ContextRetriever interface {
+	SearchContextChunks(ctx context.Context, documentIDs []uuid.UUID, embedding []float32, limit int, minSimilarity float64) ([]models.ContextChunk, error)
+}
+
+// SetContextRetriever sets where the chunks of a request's
+// ContextDocumentIDs are retrieved from. Without it, requests naming
+// context documents fail.
+func (e *Engine) SetContextRetriever(retriever ContextRetriever) {
+	e.contextRetriever = retriever
+}
+
+// retrieveContext returns the context.retrieval.top_k chunks of documentIDs
+// most relevant to input, leaving out those less similar than
+// context.retrieval.min_similarity, and the context entries to add for
+// them
+func (e *Engine) retrieveContext(ctx context.Context, input string, documentIDs []uuid.UUID) ([]models.ContextChunk, []string, error) {
+	if len(documentIDs) == 0 {
+		return nil, nil, nil
+	}
+	if e.contextRetriever == nil {
+		return nil, nil, errors.New("context documents are not available without storage")
+	}
+	embed := providers.NewQueryEmbedder(e.registry)
+	if embed == nil {
+		return nil, nil, errors.New("context documents need an embedding provider")
+	}
+
+	embedding, err := embed(ctx, input)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to embed input for context retrieval: %w", err)
+	}
+	topK := viper.GetInt("context.retrieval.top_k")
+	if topK <= 0 {
+		topK = DefaultContextTopK
+	}
+	chunks, err := e.contextRetriever.SearchContextChunks(ctx, documentIDs, embedding, topK, viper.GetFloat64("context.retrieval.min_similarity"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to retrieve context: %w", err)
+	}
+
+	entries := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		entries[i] = chunk.Content
+		if chunk.DocumentTitle != "" {
+			entries[i] = fmt.Sprintf("From %s:\n%s", chunk.DocumentTitle, chunk.Content)
+		}
+	}
+	log.WithContext(ctx, e.logger).WithFields(logrus.Fields{
+		"documents": len(documentIDs),
+		"chunks":    len(chunks),
+	}).Info("Retrieved context from documents")
+	return chunks, entries, nil
+}
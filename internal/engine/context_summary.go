@@ -0,0 +1,80 @@
+package engine
+
+import (
+	"context"
+
+	"github.com/jonwraymond/prompt-alchemy/internal/log"
+	"github.com/jonwraymond/prompt-alchemy/internal/summarization"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// DefaultContextTokenBudget is the context size, in estimated tokens, kept
+// verbatim when generation.context_token_budget is not set
+const DefaultContextTokenBudget = 2000
+
+// contextSummaryWords bounds each summary of an overflowing context entry
+const contextSummaryWords = 60
+
+// SetSummarizer sets the summarizer used for AutoSummarizeContext. Without
+// one, a summarizer configured from summarization.* is created on first use.
+func (e *Engine) SetSummarizer(summarizer *summarization.Summarizer) {
+	e.summarizer = summarizer
+}
+
+func (e *Engine) contextSummarizer() *summarization.Summarizer {
+	e.summarizerOnce.Do(func() {
+		if e.summarizer == nil {
+			e.summarizer = summarization.NewSummarizer(e.logger)
+		}
+	})
+	return e.summarizer
+}
+
+// summarizeContext keeps context entries verbatim, in order, while they fit
+// in the token budget and replaces each entry past it with a summary. It
+// returns the new entries and the tokens saved; when the context already
+// fits, it is returned unchanged. A failed summary keeps the original entry.
+func (e *Engine) summarizeContext(ctx context.Context, entries []string) ([]string, int) {
+	budget := viper.GetInt("generation.context_token_budget")
+	if budget <= 0 {
+		budget = DefaultContextTokenBudget
+	}
+
+	before := 0
+	for _, entry := range entries {
+		before += calculateInputTokens(entry)
+	}
+	if before <= budget {
+		return entries, 0
+	}
+
+	logger := log.WithContext(ctx, e.logger)
+	summarizer := e.contextSummarizer()
+	summarized := make([]string, 0, len(entries))
+	used := 0
+	for _, entry := range entries {
+		tokens := calculateInputTokens(entry)
+		if used+tokens <= budget {
+			summarized = append(summarized, entry)
+			used += tokens
+			continue
+		}
+
+		resp, err := summarizer.Summarize(ctx, summarization.SummaryRequest{Text: entry, MaxWords: contextSummaryWords})
+		if err != nil {
+			logger.WithError(err).Warn("Failed to summarize context, keeping it as is")
+		} else if summaryTokens := calculateInputTokens(resp.Summary); summaryTokens < tokens {
+			entry, tokens = resp.Summary, summaryTokens
+		}
+		summarized = append(summarized, entry)
+		used += tokens
+	}
+
+	logger.WithFields(logrus.Fields{
+		"budget":        budget,
+		"tokens_before": before,
+		"tokens_after":  used,
+	}).Info("Summarized context over the token budget")
+	return summarized, before - used
+}
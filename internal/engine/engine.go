@@ -2,42 +2,155 @@ package engine
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"github.com/jonwraymond/prompt-alchemy/internal/helpers"
+	"github.com/jonwraymond/prompt-alchemy/internal/log"
+	"github.com/jonwraymond/prompt-alchemy/internal/observability/tracing"
 	"github.com/jonwraymond/prompt-alchemy/internal/phases"
 	"github.com/jonwraymond/prompt-alchemy/internal/selection"
 	"github.com/jonwraymond/prompt-alchemy/internal/storage"
+	"github.com/jonwraymond/prompt-alchemy/internal/summarization"
 	"github.com/jonwraymond/prompt-alchemy/pkg/models"
 	"github.com/jonwraymond/prompt-alchemy/pkg/providers"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // This Engine struct represents the Transmutation Core, central hub for prompt generation and phase orchestration
 type Engine struct {
 	registry      *providers.Registry
 	phaseHandlers map[models.Phase]phases.PhaseHandler
+	customPhases  map[models.Phase]*phases.CustomPhase
+	systemPrompts map[models.Phase]string // phases.<name>.system_prompt overrides
 	logger        *logrus.Logger
 	storage       storage.StorageInterface
 	optimizer     *OptimizationIntegrator
+
+	// Summarizes over-budget context, see context_summary.go
+	summarizer     *summarization.Summarizer
+	summarizerOnce sync.Once
+
+	// Shadow generations against a candidate provider, see shadow.go
+	shadow       ShadowConfig
+	shadowScorer PromptScorer
+	shadowWG     sync.WaitGroup
+
+	// Bounds parallel provider calls, see concurrency.go
+	calls *callLimiter
+
+	// Told about each prompt generated, see observer.go
+	observer GenerationObserver
+
+	// Scores generated prompts for toxicity, see moderation.go
+	moderation ModerationConfig
+	toxicity   ToxicityScorer
+
+	// Retrieves chunks of context documents, see context_retrieval.go
+	contextRetriever ContextRetriever
+
+	// Reuses generations for similar inputs, see semantic_cache.go
+	semanticCache *semanticCache
 }
 
 // NewEngine initializes the Transmutation Core with providers and logging
 func NewEngine(registry *providers.Registry, logger *logrus.Logger) *Engine {
-	return &Engine{
+	e := &Engine{
 		registry: registry,
 		phaseHandlers: map[models.Phase]phases.PhaseHandler{
 			models.PhasePrimaMaterial: &phases.PrimaMateria{},
 			models.PhaseSolutio:       &phases.Solutio{},
 			models.PhaseCoagulatio:    &phases.Coagulatio{},
 		},
-		logger: logger,
+		logger:        logger,
+		shadow:        LoadShadowConfig(),
+		calls:         newCallLimiter(),
+		moderation:    LoadModerationConfig(),
+		semanticCache: newSemanticCache(LoadSemanticCacheConfig()),
+	}
+
+	custom, err := phases.LoadCustomPhases()
+	if err != nil {
+		logger.WithError(err).Error("Invalid custom phases, only built-in phases are available")
+	}
+	for name, phase := range custom {
+		e.phaseHandlers[name] = phase
+	}
+	e.customPhases = custom
+
+	names := make([]models.Phase, 0, len(e.phaseHandlers))
+	for name := range e.phaseHandlers {
+		names = append(names, name)
+	}
+	e.systemPrompts = loadSystemPrompts(names, logger)
+	return e
+}
+
+// KnownPhase reports whether phase is built in or defined under
+// phases.custom
+func (e *Engine) KnownPhase(phase models.Phase) bool {
+	_, ok := e.phaseHandlers[phase]
+	return ok || phases.IsBuiltin(phase)
+}
+
+// ValidatePhases checks that every phase is built in or defined under
+// phases.custom, listing any that are neither
+func (e *Engine) ValidatePhases(list []models.Phase) error {
+	var unknown []string
+	for _, phase := range list {
+		if !e.KnownPhase(phase) {
+			unknown = append(unknown, string(phase))
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+
+	known := make([]string, 0, len(phases.BuiltinPhases)+len(e.customPhases))
+	for _, phase := range phases.BuiltinPhases {
+		known = append(known, string(phase))
+	}
+	custom := make([]string, 0, len(e.customPhases))
+	for phase := range e.customPhases {
+		custom = append(custom, string(phase))
 	}
+	sort.Strings(custom)
+	known = append(known, custom...)
+	return fmt.Errorf("unknown phases: %s (available: %s)", strings.Join(unknown, ", "), strings.Join(known, ", "))
+}
+
+// phaseConfigs returns a copy of configs in which every custom phase in list
+// without a provider gets the phase's default provider
+func (e *Engine) phaseConfigs(configs []models.PhaseConfig, list []models.Phase) []models.PhaseConfig {
+	result := append([]models.PhaseConfig(nil), configs...)
+	for _, phase := range list {
+		custom, ok := e.customPhases[phase]
+		if !ok || custom.Provider == "" {
+			continue
+		}
+		found := false
+		for i := range result {
+			if result[i].Phase == phase {
+				found = true
+				if result[i].Provider == "" {
+					result[i].Provider = custom.Provider
+				}
+			}
+		}
+		if !found {
+			result = append(result, models.PhaseConfig{Phase: phase, Provider: custom.Provider})
+		}
+	}
+	return result
 }
 
 // SetStorage sets the storage interface for the engine
@@ -48,9 +161,43 @@ func (e *Engine) SetStorage(storage storage.StorageInterface) {
 	}
 }
 
-// Generate is the core method of the Transmutation Core, processing inputs through alchemical phases
+// Generate is the core method of the Transmutation Core, processing inputs through alchemical phases.
+// opts.PhaseSelection picks the strategy, see generateSelected. With
+// generation.semantic_cache enabled, an earlier generation for a similar
+// input may be returned instead, see lookupSemanticCache.
 func (e *Engine) Generate(ctx context.Context, opts models.GenerateOptions) (*models.GenerationResult, error) {
-	e.logger.Info("Starting prompt generation engine")
+	ctx, span := tracing.Start(ctx, "engine.Generate",
+		attribute.Int("count", opts.Request.Count),
+		attribute.Int("phases", len(opts.Request.Phases)),
+		attribute.String("persona", opts.Persona),
+	)
+	cached, remember := e.lookupSemanticCache(ctx, opts)
+	if cached != nil {
+		span.SetAttributes(attribute.Bool("cached", true))
+		tracing.End(span, nil)
+		return cached, nil
+	}
+
+	var result *models.GenerationResult
+	var err error
+	switch opts.PhaseSelection {
+	case models.PhaseSelectionBest, models.PhaseSelectionCascade:
+		span.SetAttributes(attribute.String("phase_selection", opts.PhaseSelection))
+		result, err = e.generateSelected(ctx, opts)
+	default:
+		result, err = e.generate(ctx, opts)
+	}
+	if err == nil && remember != nil {
+		remember(result)
+	}
+	tracing.End(span, err)
+	return result, err
+}
+
+// generate runs Generate inside its span, with one child span per phase
+func (e *Engine) generate(ctx context.Context, opts models.GenerateOptions) (*models.GenerationResult, error) {
+	logger := log.WithContext(ctx, e.logger)
+	logger.Info("Starting prompt generation engine")
 	result := &models.GenerationResult{
 		Prompts:  make([]models.Prompt, 0),
 		Rankings: make([]models.PromptRanking, 0),
@@ -64,39 +211,154 @@ func (e *Engine) Generate(ctx context.Context, opts models.GenerateOptions) (*mo
 		return nil, fmt.Errorf("count cannot exceed 100, got %d", opts.Request.Count)
 	}
 
+	if err := e.ValidatePhases(opts.Request.Phases); err != nil {
+		return nil, err
+	}
+	phaseConfigs := e.phaseConfigs(opts.PhaseConfigs, opts.Request.Phases)
+	for phase, systemPrompt := range opts.SystemPrompts {
+		if err := models.ValidateSystemPrompt(phase, systemPrompt); err != nil {
+			return nil, err
+		}
+	}
+
+	// Fill in template variables; the phases only see the resolved input
+	inputTemplate := opts.Request.Input
+	resolved, err := opts.Request.ResolveInput()
+	if err != nil {
+		return nil, err
+	}
+	opts.Request.Input = resolved
+
+	chunks, entries, err := e.retrieveContext(ctx, resolved, opts.Request.ContextDocumentIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) > 0 {
+		opts.Request.Context = append(slices.Clone(opts.Request.Context), entries...)
+	}
+	result.RetrievedContext = chunks
+
+	if opts.AutoSummarizeContext {
+		var saved int
+		opts.Request.Context, saved = e.summarizeContext(ctx, opts.Request.Context)
+		result.ContextSummarized = saved > 0
+		result.ContextTokensSaved = saved
+	}
+
 	// Start with the base input
 	basePrompts := make([]string, opts.Request.Count)
 	for i := 0; i < opts.Request.Count; i++ {
 		basePrompts[i] = opts.Request.Input
 	}
 
-	// Process through each phase
-	for _, phase := range opts.Request.Phases {
-		e.logger.WithField("phase", phase).Info("Processing phase")
+	// Process through each phase; previousPrompts[i] is the prompt that fed basePrompts[i]
+	var previousPrompts []models.Prompt
+	timeout := phaseTimeout()
+	finalPhase := len(opts.Request.Phases) - 1
+	for phaseIndex, phase := range opts.Request.Phases {
+		// Stop before calling any more providers once the caller has gone away
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("generation cancelled before phase %s: %w", phase, err)
+		}
+		logger.WithField("phase", phase).Info("Processing phase")
+		if opts.OnPhaseStart != nil {
+			opts.OnPhaseStart(phase)
+		}
+		phaseCtx, phaseSpan := tracing.Start(ctx, "engine.phase", attribute.String("phase", string(phase)))
 
-		provider, err := providers.GetProviderForPhase(opts.PhaseConfigs, phase, e.registry)
+		provider, err := providers.GetProviderForPhase(phaseConfigs, phase, e.registry)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get provider for phase %s: %w", phase, err)
+			err = fmt.Errorf("failed to get provider for phase %s: %w", phase, err)
+			tracing.End(phaseSpan, err)
+			return nil, err
+		}
+		logger.Debugf("Using provider %s for phase %s", provider.Name(), phase)
+		phaseSpan.SetAttributes(attribute.String("provider", provider.Name()))
+		if systemPrompt, source := e.systemPromptOverride(phase, opts); systemPrompt != "" {
+			logger.WithFields(logrus.Fields{
+				"phase":  phase,
+				"source": source,
+				"length": len(systemPrompt),
+			}).Info("Using system prompt override")
+		}
+
+		if requested, applied := e.phaseTemperature(phase, provider, opts); applied != requested {
+			logger.WithFields(logrus.Fields{
+				"phase":                phase,
+				"provider":             provider.Name(),
+				"original_temperature": requested,
+				"adjusted_temperature": applied,
+			}).Warn("Temperature adjusted to the provider's supported range")
+			result.TemperatureAdjustments = append(result.TemperatureAdjustments, models.TemperatureAdjustment{
+				Phase:     phase,
+				Provider:  provider.Name(),
+				Requested: requested,
+				Applied:   applied,
+			})
 		}
-		e.logger.Debugf("Using provider %s for phase %s", provider.Name(), phase)
 
-		// Generate variants for this phase
-		phasePrompts, err := e.processPhase(ctx, phase, provider, basePrompts, opts)
+		// Generate variants for this phase. A phase that runs past its
+		// timeout is skipped; the next phase works from its input.
+		// Only the final phase's tokens are streamed.
+		timeoutCtx, cancel := withPhaseTimeout(phaseCtx, timeout)
+		phaseOpts := opts
+		if phaseIndex != finalPhase {
+			phaseOpts.OnToken = nil
+		}
+		phasePrompts, err := e.processPhase(timeoutCtx, phase, provider, basePrompts, phaseOpts)
+		if phaseTimedOut(ctx, timeoutCtx, err) {
+			cancel()
+			logger.WithError(err).WithFields(logrus.Fields{
+				"phase":    phase,
+				"provider": provider.Name(),
+				"timeout":  timeout,
+			}).Warn("Phase timed out, skipping it")
+			result.PhaseTimeouts = append(result.PhaseTimeouts, models.PhaseTimeout{
+				Phase:    phase,
+				Provider: provider.Name(),
+				Timeout:  timeout.String(),
+			})
+			tracing.End(phaseSpan, fmt.Errorf("phase %s timed out after %s: %w", phase, timeout, err))
+			continue
+		}
 		if err != nil {
-			return nil, fmt.Errorf("failed to process phase %s: %w", phase, err)
+			cancel()
+			err = fmt.Errorf("failed to process phase %s: %w", phase, err)
+			tracing.End(phaseSpan, err)
+			return nil, err
 		}
 
 		// Optimize phase prompts if enabled
 		if e.optimizer != nil && opts.Optimize {
 			for i, prompt := range phasePrompts {
-				optimized, err := e.optimizer.OptimizePhaseOutput(ctx, &prompt, opts)
+				optimized, err := e.optimizer.OptimizePhaseOutput(timeoutCtx, &prompt, opts)
 				if err != nil {
-					e.logger.WithError(err).Warn("Optimization failed, using original prompt")
+					logger.WithError(err).Warn("Optimization failed, using original prompt")
 				} else {
 					phasePrompts[i] = *optimized
 				}
 			}
 		}
+		cancel()
+
+		// Record cascade lineage: each output derives from the prompt that fed it.
+		// This also replaces the optimizer's link to the discarded unoptimized draft.
+		for i := range phasePrompts {
+			if i < len(previousPrompts) {
+				parentID := previousPrompts[i].ID
+				phasePrompts[i].ParentID = &parentID
+			}
+			if len(opts.Request.Variables) > 0 {
+				phasePrompts[i].InputTemplate = inputTemplate
+				phasePrompts[i].InputVariables = opts.Request.Variables
+			}
+		}
+
+		// Moderate before the prompts feed the next phase, so dropped ones
+		// aren't refined further
+		var dropped int
+		phasePrompts, dropped = e.moderate(phaseCtx, phasePrompts)
+		result.ModerationDropped += dropped
 
 		// Update base prompts for next phase
 		basePrompts = make([]string, len(phasePrompts))
@@ -104,6 +366,17 @@ func (e *Engine) Generate(ctx context.Context, opts models.GenerateOptions) (*mo
 			basePrompts[i] = prompt.Content
 			result.Prompts = append(result.Prompts, prompt)
 		}
+		previousPrompts = phasePrompts
+		phaseSpan.SetAttributes(attribute.Int("prompts", len(phasePrompts)))
+		tracing.End(phaseSpan, nil)
+
+		if opts.OnPhaseComplete != nil {
+			opts.OnPhaseComplete(phase, phasePrompts)
+		}
+	}
+
+	if len(result.Prompts) == 0 && len(result.PhaseTimeouts) > 0 {
+		return nil, &PhaseTimeoutError{Timeouts: result.PhaseTimeouts}
 	}
 
 	if opts.AutoSelect {
@@ -119,7 +392,7 @@ func (e *Engine) Generate(ctx context.Context, opts models.GenerateOptions) (*mo
 		}
 	}
 
-	e.logger.Info("Prompt generation engine finished")
+	logger.Info("Prompt generation engine finished")
 	return result, nil
 }
 
@@ -154,30 +427,34 @@ func (e *Engine) GenerateFromParams(ctx context.Context, input string, phasesStr
 
 // processPhase handles generation for a single phase
 func (e *Engine) processPhase(ctx context.Context, phase models.Phase, provider providers.Provider, inputs []string, opts models.GenerateOptions) ([]models.Prompt, error) {
-	e.logger.Debugf("Processing phase %s with %d inputs", phase, len(inputs))
+	logger := log.WithContext(ctx, e.logger)
+	logger.Debugf("Processing phase %s with %d inputs", phase, len(inputs))
 	prompts := make([]models.Prompt, 0, len(inputs))
 
 	if opts.UseParallel {
-		// Process in parallel
-		e.logger.Debug("Processing phase in parallel")
+		// Process in parallel, at most generation.max_concurrency calls at once
+		logger.Debugf("Processing phase in parallel, up to %d at once", e.MaxConcurrency())
 		var wg sync.WaitGroup
-		var mu sync.Mutex
 		errors := make([]error, len(inputs))
+		// Results keep input order so outputs line up with the prompts that fed them
+		results := make([]*models.Prompt, len(inputs))
 
 		for i, input := range inputs {
+			if err := e.calls.acquire(ctx); err != nil {
+				errors[i] = err
+				break
+			}
 			wg.Add(1)
 			go func(idx int, content string) {
 				defer wg.Done()
+				defer e.calls.release()
 
-				prompt, err := e.generateSinglePrompt(ctx, phase, provider, content, opts)
+				prompt, err := e.generateSinglePrompt(ctx, phase, provider, content, opts, tokenSink(phase, idx, opts))
 				if err != nil {
 					errors[idx] = err
 					return
 				}
-
-				mu.Lock()
-				prompts = append(prompts, *prompt)
-				mu.Unlock()
+				results[idx] = prompt
 			}(i, input)
 		}
 
@@ -189,11 +466,14 @@ func (e *Engine) processPhase(ctx context.Context, phase models.Phase, provider
 				return nil, fmt.Errorf("failed to generate prompt %d: %w", i, err)
 			}
 		}
+		for _, prompt := range results {
+			prompts = append(prompts, *prompt)
+		}
 	} else {
 		// Process sequentially
-		e.logger.Debug("Processing phase sequentially")
-		for _, input := range inputs {
-			prompt, err := e.generateSinglePrompt(ctx, phase, provider, input, opts)
+		logger.Debug("Processing phase sequentially")
+		for i, input := range inputs {
+			prompt, err := e.generateSinglePrompt(ctx, phase, provider, input, opts, tokenSink(phase, i, opts))
 			if err != nil {
 				return nil, err
 			}
@@ -204,9 +484,33 @@ func (e *Engine) processPhase(ctx context.Context, phase models.Phase, provider
 	return prompts, nil
 }
 
-// generateSinglePrompt generates a single prompt for a phase
-func (e *Engine) generateSinglePrompt(ctx context.Context, phase models.Phase, provider providers.Provider, input string, opts models.GenerateOptions) (*models.Prompt, error) {
-	e.logger.Debugf("Generating single prompt for phase %s", phase)
+// providerGenerate calls the phase's provider inside a trace span,
+// streaming its output to onToken when set
+func (e *Engine) providerGenerate(ctx context.Context, phase models.Phase, provider providers.Provider, req providers.GenerateRequest, onToken func(delta string)) (*providers.GenerateResponse, error) {
+	genCtx, genSpan := tracing.Start(ctx, "provider.Generate",
+		attribute.String("provider", provider.Name()),
+		attribute.String("phase", string(phase)),
+		attribute.Bool("stream", onToken != nil),
+	)
+	var resp *providers.GenerateResponse
+	var err error
+	if onToken != nil {
+		resp, err = streamGenerate(genCtx, provider, req, onToken)
+	} else {
+		resp, err = provider.Generate(genCtx, req)
+	}
+	if err == nil {
+		genSpan.SetAttributes(attribute.String("model", resp.Model), attribute.Int("tokens", resp.TokensUsed))
+	}
+	tracing.End(genSpan, err)
+	return resp, err
+}
+
+// generateSinglePrompt generates a single prompt for a phase, streaming its
+// text to onToken when set
+func (e *Engine) generateSinglePrompt(ctx context.Context, phase models.Phase, provider providers.Provider, input string, opts models.GenerateOptions, onToken func(delta string)) (*models.Prompt, error) {
+	logger := log.WithContext(ctx, e.logger)
+	logger.Debugf("Generating single prompt for phase %s", phase)
 	startTime := time.Now()
 
 	// Get the template for this phase
@@ -216,9 +520,10 @@ func (e *Engine) generateSinglePrompt(ctx context.Context, phase models.Phase, p
 	}
 
 	template := handler.GetTemplate()
+	_, temperature := e.phaseTemperature(phase, provider, opts)
 
-	// Build the system prompt based on phase
-	systemPrompt := handler.BuildSystemPrompt(opts)
+	// Build the system prompt based on phase, unless it is overridden
+	systemPrompt := e.buildSystemPrompt(phase, opts)
 
 	// Enhance input with historical data if storage is available
 	enhancedInput := input
@@ -231,11 +536,11 @@ func (e *Engine) generateSinglePrompt(ctx context.Context, phase models.Phase, p
 				historyEnhancer := NewHistoryEnhancer(storageImpl, embeddingProvider)
 				enhancedContext, err := historyEnhancer.EnhanceWithHistory(ctx, input, phase)
 				if err != nil {
-					e.logger.WithError(err).Warn("Failed to enhance with historical data, using original input")
+					logger.WithError(err).Warn("Failed to enhance with historical data, using original input")
 				} else if enhancedContext != nil {
 					// Use enhanced prompt that includes historical insights
 					enhancedInput = historyEnhancer.BuildEnhancedPrompt(input, enhancedContext, phase)
-					e.logger.WithFields(logrus.Fields{
+					logger.WithFields(logrus.Fields{
 						"original_length": len(input),
 						"enhanced_length": len(enhancedInput),
 						"patterns_found":  len(enhancedContext.ExtractedPatterns),
@@ -249,25 +554,76 @@ func (e *Engine) generateSinglePrompt(ctx context.Context, phase models.Phase, p
 
 	// Prepare the prompt content with enhanced input
 	promptContent := handler.PreparePromptContent(enhancedInput, opts)
-	e.logger.Debugf("Prompt content for provider: %s", promptContent)
+	logger.Debugf("Prompt content for provider: %s", promptContent)
 
 	// Generate using the provider
-	resp, err := provider.Generate(ctx, providers.GenerateRequest{
+	req := providers.GenerateRequest{
 		Prompt:       promptContent,
 		SystemPrompt: systemPrompt,
-		Temperature:  opts.Request.Temperature,
+		Temperature:  temperature,
 		MaxTokens:    opts.Request.MaxTokens,
-	})
+		Seed:         opts.Request.Seed,
+	}
+	resp, err := e.providerGenerate(ctx, phase, provider, req, onToken)
+
+	var overflow *providers.ContextTooLongError
+	if errors.As(err, &overflow) && truncateOnOverflow(opts.TruncateOnOverflow) {
+		if truncated, ok := truncateToFit(enhancedInput, systemPrompt+promptContent, opts.Request.MaxTokens, overflow); ok {
+			logger.WithFields(logrus.Fields{
+				"provider":         provider.Name(),
+				"phase":            phase,
+				"context_window":   overflow.ContextWindow,
+				"overflow_tokens":  overflow.OverflowTokens(),
+				"original_length":  len(enhancedInput),
+				"truncated_length": len(truncated),
+			}).Warn("Input overflowed the context window, retrying truncated")
+			req.Prompt = handler.PreparePromptContent(truncated, opts)
+			resp, err = e.providerGenerate(ctx, phase, provider, req, onToken)
+		}
+	}
+	if errors.As(err, &overflow) {
+		overflow.Phase = phase
+	}
 
 	if err != nil {
-		e.logger.WithFields(logrus.Fields{
+		logger.WithFields(logrus.Fields{
 			"provider": provider.Name(),
 			"phase":    phase,
 		}).Errorf("Provider generation failed: %v", err)
+		e.observeError(provider.Name(), err)
 		return nil, fmt.Errorf("provider generation failed: %w", err)
 	}
 
-	processingTime := int(time.Since(startTime).Milliseconds())
+	// The phase's provider may have fallen back to another one in the chain
+	servedBy := provider.Name()
+	if resp.Provider != "" {
+		servedBy = resp.Provider
+	}
+	if resp.FallbackFrom != "" {
+		logger.WithFields(logrus.Fields{
+			"provider":      servedBy,
+			"fallback_from": resp.FallbackFrom,
+			"phase":         phase,
+		}).Warn("Generated with fallback provider")
+	}
+	if resp.ModelAliasedFrom != "" {
+		logger.WithFields(logrus.Fields{
+			"provider":           servedBy,
+			"model":              resp.Model,
+			"model_aliased_from": resp.ModelAliasedFrom,
+			"phase":              phase,
+		}).Warn("Generated with aliased model")
+	}
+	seedIgnored := req.Seed != nil && !providers.SupportsSeed(servedBy)
+	if seedIgnored {
+		logger.WithFields(logrus.Fields{
+			"provider": servedBy,
+			"phase":    phase,
+		}).Debug("Provider does not support seeding, ignoring seed")
+	}
+
+	duration := time.Since(startTime)
+	processingTime := int(duration.Milliseconds())
 	promptID := uuid.New()
 
 	// Create the prompt model
@@ -275,9 +631,9 @@ func (e *Engine) generateSinglePrompt(ctx context.Context, phase models.Phase, p
 		ID:           promptID,
 		Content:      resp.Content,
 		Phase:        phase,
-		Provider:     provider.Name(),
+		Provider:     servedBy,
 		Model:        resp.Model, // Model from response
-		Temperature:  opts.Request.Temperature,
+		Temperature:  temperature,
 		MaxTokens:    opts.Request.MaxTokens,
 		ActualTokens: resp.TokensUsed, // Actual tokens used
 		Tags:         opts.Request.Tags,
@@ -300,7 +656,7 @@ func (e *Engine) generateSinglePrompt(ctx context.Context, phase models.Phase, p
 	// Set generation context as string array
 	prompt.GenerationContext = []string{
 		fmt.Sprintf("phase=%s", phase),
-		fmt.Sprintf("provider=%s", provider.Name()),
+		fmt.Sprintf("provider=%s", servedBy),
 		fmt.Sprintf("template=%s", func() string {
 			if len(template) > 50 {
 				return template[:50] + "..."
@@ -320,10 +676,12 @@ func (e *Engine) generateSinglePrompt(ctx context.Context, phase models.Phase, p
 		embeddingProvider := providers.GetEmbeddingProvider(provider, e.registry)
 
 		if embeddingProvider.SupportsEmbeddings() {
-			e.logger.Debugf("Getting embedding from provider: %s", embeddingProvider.Name())
-			embedding, err := embeddingProvider.GetEmbedding(ctx, resp.Content, e.registry)
+			logger.Debugf("Getting embedding from provider: %s", embeddingProvider.Name())
+			embedCtx, embedSpan := tracing.Start(ctx, "provider.GetEmbedding", attribute.String("provider", embeddingProvider.Name()))
+			embedding, err := embeddingProvider.GetEmbedding(embedCtx, resp.Content, e.registry)
+			tracing.End(embedSpan, err)
 			if err != nil {
-				e.logger.WithError(err).WithFields(logrus.Fields{
+				logger.WithError(err).WithFields(logrus.Fields{
 					"primary_provider":   provider.Name(),
 					"embedding_provider": embeddingProvider.Name(),
 				}).Warn("Failed to get embedding")
@@ -336,14 +694,14 @@ func (e *Engine) generateSinglePrompt(ctx context.Context, phase models.Phase, p
 
 				// Log successful embedding with fallback info
 				if provider.Name() != embeddingProvider.Name() {
-					e.logger.WithFields(logrus.Fields{
+					logger.WithFields(logrus.Fields{
 						"primary_provider":   provider.Name(),
 						"embedding_provider": embeddingProviderName,
 					}).Info("Using fallback provider for embeddings")
 				}
 			}
 		} else {
-			e.logger.WithField("provider", provider.Name()).Info("Provider does not support embeddings, skipping embedding generation")
+			logger.WithField("provider", provider.Name()).Info("Provider does not support embeddings, skipping embedding generation")
 		}
 	}
 
@@ -352,24 +710,37 @@ func (e *Engine) generateSinglePrompt(ctx context.Context, phase models.Phase, p
 		ID:                 uuid.New(),
 		PromptID:           promptID,
 		GenerationModel:    resp.Model,
-		GenerationProvider: provider.Name(),
+		GenerationProvider: servedBy,
+		FallbackFrom:       resp.FallbackFrom,
+		ModelAliasedFrom:   resp.ModelAliasedFrom,
+		Seed:               req.Seed,
+		SeedIgnored:        seedIgnored,
+		SystemFingerprint:  resp.SystemFingerprint,
+		Citations:          resp.Citations,
 		EmbeddingModel:     embeddingModel,
 		EmbeddingProvider:  embeddingProviderName,
 		ProcessingTime:     processingTime,
-		InputTokens:        calculateInputTokens(promptContent), // Estimate
-		OutputTokens:       resp.TokensUsed,
-		TotalTokens:        resp.TokensUsed, // For now, same as output tokens
 		CreatedAt:          time.Now(),
 	}
+	setUsage(prompt.ModelMetadata, resp, promptContent)
+	e.observePrompt(ctx, prompt, opts.Persona, duration)
 
-	// Set cost if we can calculate it
-	if cost := calculateCost(provider.Name(), resp.Model, resp.TokensUsed); cost > 0 {
-		prompt.ModelMetadata.Cost = cost
-	}
+	e.maybeShadow(ctx, req, prompt, opts.Request.Input)
 
 	return prompt, nil
 }
 
+// phaseTemperature returns the temperature requested for a phase, a custom
+// phase's own if it sets one, and the temperature applied: the requested one
+// clamped to the range the phase's provider accepts
+func (e *Engine) phaseTemperature(phase models.Phase, provider providers.Provider, opts models.GenerateOptions) (requested, applied float64) {
+	requested = opts.Request.Temperature
+	if custom, ok := e.phaseHandlers[phase].(*phases.CustomPhase); ok && custom.Temperature > 0 {
+		requested = custom.Temperature
+	}
+	return requested, providers.ClampTemperature(provider.Name(), requested)
+}
+
 // getEmbeddingModelName returns the embedding model name for a provider
 func getEmbeddingModelName(providerName string) string {
 	// Use standardized embedding model for all providers to ensure compatibility
@@ -394,44 +765,27 @@ func calculateInputTokens(content string) int {
 	return len(content) / 4
 }
 
-// calculateCost estimates the cost based on provider and usage
-func calculateCost(provider, model string, tokens int) float64 {
-	// These are approximate costs - should be updated with current pricing
-	costPerToken := 0.0
-
-	switch provider {
-	case providers.ProviderOpenAI:
-		switch model {
-		case "gpt-4-turbo-preview", "gpt-4-1106-preview":
-			costPerToken = 0.00003 // $0.03 per 1K tokens (output)
-		case "o4-mini":
-			costPerToken = 0.00000015 // $0.00015 per 1K tokens
-		case "gpt-3.5-turbo":
-			costPerToken = 0.000002 // $0.002 per 1K tokens
-		default:
-			costPerToken = 0.00002
-		}
-	case providers.ProviderOpenRouter:
-		// OpenRouter has variable pricing
-		costPerToken = 0.00002
-	case providers.ProviderAnthropic:
-		switch model {
-		case "claude-3-opus-20240229":
-			costPerToken = 0.000075 // $0.075 per 1K tokens (output)
-		case "claude-3-sonnet-20240229", "claude-3-5-sonnet-20241022":
-			costPerToken = 0.000015 // $0.015 per 1K tokens (output)
-		default:
-			costPerToken = 0.00003
-		}
-	case providers.ProviderGoogle:
-		costPerToken = 0.000002 // Gemini Pro pricing
-	case providers.ProviderGrok:
-		costPerToken = 0.000002 // Grok pricing (approximate)
-	case providers.ProviderOllama:
-		costPerToken = 0.0 // Local models are free
+// setUsage records token counts and cost on meta. Cost is only computed from
+// usage the provider reported; otherwise input tokens are estimated, cost is
+// left at 0 and marked estimated.
+func setUsage(meta *models.ModelMetadata, resp *providers.GenerateResponse, promptContent string) {
+	if resp.InputTokens == 0 && resp.OutputTokens == 0 {
+		meta.InputTokens = calculateInputTokens(promptContent)
+		meta.OutputTokens = resp.TokensUsed
+		meta.TotalTokens = resp.TokensUsed
+		meta.CostEstimated = true
+		return
 	}
 
-	return float64(tokens) * costPerToken
+	meta.InputTokens = resp.InputTokens
+	meta.OutputTokens = resp.OutputTokens
+	meta.TotalTokens = resp.InputTokens + resp.OutputTokens
+	pricing, ok := providers.LookupPricing(meta.GenerationProvider, meta.GenerationModel)
+	if !ok {
+		meta.CostEstimated = true
+		return
+	}
+	meta.Cost = pricing.Cost(resp.InputTokens, resp.OutputTokens)
 }
 
 // StreamGenerate handles real-time generation for server mode
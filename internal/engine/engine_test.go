@@ -1,16 +1,30 @@
 package engine
 
 import (
+	"cmp"
 	"context"
 	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/google/uuid"
+	"github.com/jonwraymond/prompt-alchemy/internal/phases"
 	"github.com/jonwraymond/prompt-alchemy/pkg/models"
 	"github.com/jonwraymond/prompt-alchemy/pkg/providers"
 
 	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 const failedToRegisterTestProvider = "Failed to register test provider: %v"
@@ -51,6 +65,8 @@ func (m *MockProvider) GetEmbedding(ctx context.Context, text string, registry p
 
 func (m *MockProvider) SupportsStreaming() bool { return false }
 
+func (m *MockProvider) Ping(ctx context.Context) (time.Duration, error) { return 0, nil }
+
 func TestNewEngine(t *testing.T) {
 	logger := logrus.New()
 	logger.SetLevel(logrus.ErrorLevel)
@@ -241,6 +257,187 @@ func TestEngine_Generate_WithTags(t *testing.T) {
 	assert.Equal(t, []string{"api", "backend", "test"}, result.Prompts[0].Tags)
 }
 
+func TestEngine_Generate_Variables(t *testing.T) {
+	engine, registry := setupTestEngine(t)
+
+	var calls atomic.Int32
+	var prompts []string
+	mockProvider := &MockProvider{
+		name:      "test-provider",
+		available: true,
+		generateFunc: func(ctx context.Context, req providers.GenerateRequest) (*providers.GenerateResponse, error) {
+			calls.Add(1)
+			prompts = append(prompts, req.Prompt)
+			return &providers.GenerateResponse{Content: "Generated prompt", TokensUsed: 10, Model: "test-model"}, nil
+		},
+	}
+	require.NoError(t, registry.Register("test-provider", mockProvider))
+
+	variables := map[string]string{"language": "Go", "framework": "chi"}
+	opts := models.GenerateOptions{
+		Request: models.PromptRequest{
+			Input:     "Build a {{language}} API with {{framework}}",
+			Variables: variables,
+			Phases:    []models.Phase{models.PhaseIdea},
+			Count:     1,
+		},
+		PhaseConfigs: []models.PhaseConfig{
+			{Phase: models.PhaseIdea, Provider: "test-provider"},
+		},
+	}
+
+	result, err := engine.Generate(context.Background(), opts)
+	require.NoError(t, err)
+	require.Len(t, result.Prompts, 1)
+	require.Len(t, prompts, 1)
+	assert.Contains(t, prompts[0], "Build a Go API with chi")
+	assert.NotContains(t, prompts[0], "{{")
+
+	prompt := result.Prompts[0]
+	assert.Equal(t, "Build a Go API with chi", prompt.OriginalInput)
+	assert.Equal(t, "Build a {{language}} API with {{framework}}", prompt.InputTemplate)
+	assert.Equal(t, variables, prompt.InputVariables)
+
+	// A missing variable fails before any provider is called
+	opts.Request.Input = "Build a {{language}} API with {{database}}"
+	_, err = engine.Generate(context.Background(), opts)
+	require.ErrorIs(t, err, models.ErrUnresolvedVariables)
+	assert.Contains(t, err.Error(), "database")
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestEngine_Generate_SystemPromptOverrides(t *testing.T) {
+	viper.Set("phases.prima-materia.system_prompt", "Configured prima materia instructions")
+	viper.Set("phases.solutio.system_prompt", "Configured solutio instructions")
+	defer viper.Reset()
+	engine, registry := setupTestEngine(t)
+
+	var calls atomic.Int32
+	mockProvider := &MockProvider{
+		name:      "test-provider",
+		available: true,
+		generateFunc: func(ctx context.Context, req providers.GenerateRequest) (*providers.GenerateResponse, error) {
+			calls.Add(1)
+			return &providers.GenerateResponse{Content: "output of " + req.SystemPrompt, TokensUsed: 10, Model: "test-model"}, nil
+		},
+	}
+	require.NoError(t, registry.Register("test-provider", mockProvider))
+
+	allPhases := []models.Phase{models.PhasePrimaMaterial, models.PhaseSolutio, models.PhaseCoagulatio}
+	opts := models.GenerateOptions{
+		Request: models.PromptRequest{Input: "Build a CLI", Phases: allPhases, Count: 1},
+		SystemPrompts: map[models.Phase]string{
+			models.PhaseSolutio: "Requested solutio instructions",
+		},
+	}
+	for _, phase := range allPhases {
+		opts.PhaseConfigs = append(opts.PhaseConfigs, models.PhaseConfig{Phase: phase, Provider: "test-provider"})
+	}
+
+	result, err := engine.Generate(context.Background(), opts)
+	require.NoError(t, err)
+	require.Len(t, result.Prompts, 3)
+
+	// Each phase's system prompt shows up in its output
+	assert.Equal(t, "output of Configured prima materia instructions", findResultByPhase(result.Prompts, models.PhasePrimaMaterial).Content)
+	assert.Equal(t, "output of Requested solutio instructions", findResultByPhase(result.Prompts, models.PhaseSolutio).Content)
+	defaultPrompt := (&phases.Coagulatio{}).BuildSystemPrompt(opts)
+	require.NotEmpty(t, defaultPrompt)
+	assert.Equal(t, "output of "+defaultPrompt, findResultByPhase(result.Prompts, models.PhaseCoagulatio).Content)
+
+	// Oversized overrides are rejected before any provider call
+	opts.SystemPrompts[models.PhaseSolutio] = strings.Repeat("x", models.MaxSystemPromptLength+1)
+	_, err = engine.Generate(context.Background(), opts)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "system prompt for phase solutio")
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestEngine_Generate_ContextTooLong(t *testing.T) {
+	engine, registry := setupTestEngine(t)
+
+	require.NoError(t, registry.Register("test-provider", &MockProvider{name: "test-provider", available: true}))
+	require.NoError(t, registry.Register("small-provider", &MockProvider{
+		name:      "small-provider",
+		available: true,
+		generateFunc: func(ctx context.Context, req providers.GenerateRequest) (*providers.GenerateResponse, error) {
+			return nil, &providers.ContextTooLongError{
+				Provider:      "small-provider",
+				Model:         "small-model",
+				InputTokens:   10192,
+				ContextWindow: 8192,
+				Err:           errors.New("maximum context length is 8192 tokens"),
+			}
+		},
+	}))
+
+	opts := models.GenerateOptions{
+		Request: models.PromptRequest{
+			Input:  "Build a CLI",
+			Phases: []models.Phase{models.PhasePrimaMaterial, models.PhaseSolutio},
+			Count:  1,
+		},
+		PhaseConfigs: []models.PhaseConfig{
+			{Phase: models.PhasePrimaMaterial, Provider: "test-provider"},
+			{Phase: models.PhaseSolutio, Provider: "small-provider"},
+		},
+	}
+
+	_, err := engine.Generate(context.Background(), opts)
+	require.ErrorIs(t, err, providers.ErrContextTooLong)
+	var overflow *providers.ContextTooLongError
+	require.ErrorAs(t, err, &overflow)
+	assert.Equal(t, models.PhaseSolutio, overflow.Phase)
+	assert.Equal(t, "small-provider", overflow.Provider)
+	assert.Equal(t, 2000, overflow.OverflowTokens())
+	assert.Contains(t, err.Error(), "phase solutio")
+}
+
+func TestEngine_Generate_TruncateOnOverflow(t *testing.T) {
+	engine, registry := setupTestEngine(t)
+
+	// The provider counts 4 characters per token and fits 4000 with the output
+	const contextWindow = 4000
+	var prompts []string
+	require.NoError(t, registry.Register("small-provider", &MockProvider{
+		name:      "small-provider",
+		available: true,
+		generateFunc: func(ctx context.Context, req providers.GenerateRequest) (*providers.GenerateResponse, error) {
+			prompts = append(prompts, req.Prompt)
+			tokens := (len(req.SystemPrompt) + len(req.Prompt)) / 4
+			if tokens+req.MaxTokens > contextWindow {
+				return nil, &providers.ContextTooLongError{Provider: "small-provider", InputTokens: tokens, ContextWindow: contextWindow}
+			}
+			return &providers.GenerateResponse{Content: "fits", TokensUsed: tokens, Model: "small-model"}, nil
+		},
+	}))
+
+	opts := models.GenerateOptions{
+		Request: models.PromptRequest{
+			Input:     strings.Repeat("word ", 4000),
+			Phases:    []models.Phase{models.PhasePrimaMaterial},
+			Count:     1,
+			MaxTokens: 500,
+		},
+		PhaseConfigs: []models.PhaseConfig{{Phase: models.PhasePrimaMaterial, Provider: "small-provider"}},
+	}
+
+	// Without truncation the overflow is returned
+	_, err := engine.Generate(context.Background(), opts)
+	require.ErrorIs(t, err, providers.ErrContextTooLong)
+	require.Len(t, prompts, 1)
+
+	// With it the phase is retried once with a shorter input
+	prompts = nil
+	opts.TruncateOnOverflow = true
+	result, err := engine.Generate(context.Background(), opts)
+	require.NoError(t, err)
+	require.Len(t, result.Prompts, 1)
+	assert.Equal(t, "fits", result.Prompts[0].Content)
+	require.Len(t, prompts, 2)
+	assert.Less(t, len(prompts[1]), len(prompts[0]))
+}
+
 func TestEngine_Generate_ProviderError(t *testing.T) {
 	engine, registry := setupTestEngine(t)
 
@@ -342,6 +539,619 @@ func TestEngine_Generate_NonExistentProvider(t *testing.T) {
 
 // Helper functions
 
+func TestEngine_Generate_CascadeLineage(t *testing.T) {
+	engine, registry := setupTestEngine(t)
+
+	var ideaCount int32
+	ideaProvider := &MockProvider{
+		name:      "idea-provider",
+		available: true,
+		generateFunc: func(ctx context.Context, req providers.GenerateRequest) (*providers.GenerateResponse, error) {
+			n := atomic.AddInt32(&ideaCount, 1)
+			return &providers.GenerateResponse{Content: fmt.Sprintf("idea-%d", n), Model: "idea-model"}, nil
+		},
+	}
+	humanProvider := &MockProvider{
+		name:      "human-provider",
+		available: true,
+		generateFunc: func(ctx context.Context, req providers.GenerateRequest) (*providers.GenerateResponse, error) {
+			return &providers.GenerateResponse{Content: "refined: " + req.Prompt, Model: "human-model"}, nil
+		},
+	}
+	require.NoError(t, registry.Register("idea-provider", ideaProvider))
+	require.NoError(t, registry.Register("human-provider", humanProvider))
+
+	opts := models.GenerateOptions{
+		Request: models.PromptRequest{
+			Input:  "Create a caching layer",
+			Phases: []models.Phase{models.PhaseIdea, models.PhaseHuman},
+			Count:  4,
+		},
+		PhaseConfigs: []models.PhaseConfig{
+			{Phase: models.PhaseIdea, Provider: "idea-provider"},
+			{Phase: models.PhaseHuman, Provider: "human-provider"},
+		},
+		UseParallel: true,
+	}
+
+	result, err := engine.Generate(context.Background(), opts)
+	require.NoError(t, err)
+	require.Len(t, result.Prompts, 8)
+
+	byID := make(map[uuid.UUID]models.Prompt)
+	for _, p := range result.Prompts {
+		byID[p.ID] = p
+	}
+
+	for _, p := range result.Prompts {
+		switch p.Phase {
+		case models.PhaseIdea:
+			assert.Nil(t, p.ParentID)
+		case models.PhaseHuman:
+			require.NotNil(t, p.ParentID)
+			parent, ok := byID[*p.ParentID]
+			require.True(t, ok, "parent must be part of the result")
+			assert.Equal(t, models.PhaseIdea, parent.Phase)
+			assert.Contains(t, p.Content, parent.Content, "output must derive from the prompt that fed it")
+		}
+	}
+}
+
+func TestEngine_Generate_MaxConcurrency(t *testing.T) {
+	viper.Set("generation.max_concurrency", 2)
+	defer viper.Reset()
+	engine, registry := setupTestEngine(t)
+
+	var running, peak int32
+	require.NoError(t, registry.Register("slow-provider", &MockProvider{
+		name:      "slow-provider",
+		available: true,
+		generateFunc: func(ctx context.Context, req providers.GenerateRequest) (*providers.GenerateResponse, error) {
+			n := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			return &providers.GenerateResponse{Content: "response: " + req.Prompt, Model: "slow-model"}, nil
+		},
+	}))
+
+	var reports int32
+	engine.SetInFlightObserver(func(inFlight int) {
+		atomic.AddInt32(&reports, 1)
+		assert.LessOrEqual(t, inFlight, 2)
+	})
+
+	opts := models.GenerateOptions{
+		Request: models.PromptRequest{
+			Input:  "Write a migration plan",
+			Phases: []models.Phase{models.PhaseIdea, models.PhaseHuman},
+			Count:  6,
+		},
+		PhaseConfigs: []models.PhaseConfig{
+			{Phase: models.PhaseIdea, Provider: "slow-provider"},
+			{Phase: models.PhaseHuman, Provider: "slow-provider"},
+		},
+		UseParallel: true,
+	}
+
+	result, err := engine.Generate(context.Background(), opts)
+	require.NoError(t, err)
+	assert.Len(t, result.Prompts, 12)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&peak), "no more than 2 provider calls may overlap")
+	assert.Equal(t, 2, engine.MaxConcurrency())
+	assert.Equal(t, 0, engine.InFlight())
+	assert.Equal(t, int32(24), atomic.LoadInt32(&reports), "one report per acquire and release")
+}
+
+func TestEngine_Generate_OnPhaseComplete(t *testing.T) {
+	engine, registry := setupTestEngine(t)
+	require.NoError(t, registry.Register("test-provider", &MockProvider{name: "test-provider", available: true}))
+
+	var started, completed []models.Phase
+	opts := models.GenerateOptions{
+		Request: models.PromptRequest{
+			Input:  "Summarize a log file",
+			Phases: []models.Phase{models.PhasePrimaMaterial, models.PhaseSolutio},
+			Count:  2,
+		},
+		PhaseConfigs: []models.PhaseConfig{
+			{Phase: models.PhasePrimaMaterial, Provider: "test-provider"},
+			{Phase: models.PhaseSolutio, Provider: "test-provider"},
+		},
+		OnPhaseStart: func(phase models.Phase) {
+			assert.Len(t, started, len(completed), "a phase starts only after the previous one completes")
+			started = append(started, phase)
+		},
+		OnPhaseComplete: func(phase models.Phase, prompts []models.Prompt) {
+			completed = append(completed, phase)
+			assert.Len(t, prompts, 2)
+			for _, p := range prompts {
+				assert.Equal(t, phase, p.Phase)
+			}
+		},
+	}
+
+	_, err := engine.Generate(context.Background(), opts)
+	require.NoError(t, err)
+	assert.Equal(t, []models.Phase{models.PhasePrimaMaterial, models.PhaseSolutio}, started)
+	assert.Equal(t, []models.Phase{models.PhasePrimaMaterial, models.PhaseSolutio}, completed)
+}
+
+// streamingMockProvider streams its words one at a time, waiting until each
+// has been delivered before producing the next
+type streamingMockProvider struct {
+	MockProvider
+	words     []string
+	delivered chan struct{}
+}
+
+func (m *streamingMockProvider) SupportsStreaming() bool { return true }
+
+func (m *streamingMockProvider) GenerateStream(ctx context.Context, req providers.GenerateRequest) (<-chan providers.GenerateResponseChunk, error) {
+	if !req.Stream {
+		return nil, errors.New("request is not marked as streaming")
+	}
+	chunks := make(chan providers.GenerateResponseChunk)
+	go func() {
+		defer close(chunks)
+		for _, word := range m.words {
+			chunks <- providers.GenerateResponseChunk{ContentDelta: word, Model: "stream-model"}
+			select {
+			case <-m.delivered:
+			case <-time.After(time.Second):
+				chunks <- providers.GenerateResponseChunk{Done: true, Error: errors.New("token was not delivered before the next one was produced")}
+				return
+			}
+		}
+		chunks <- providers.GenerateResponseChunk{Model: "stream-model", TokensUsed: 42, Done: true}
+	}()
+	return chunks, nil
+}
+
+func TestEngine_Generate_OnToken(t *testing.T) {
+	engine, registry := setupTestEngine(t)
+	streaming := &streamingMockProvider{
+		MockProvider: MockProvider{name: "streaming", available: true},
+		words:        []string{"Write ", "a ", "CSV ", "parser"},
+		delivered:    make(chan struct{}, 1),
+	}
+	require.NoError(t, registry.Register("streaming", streaming))
+	require.NoError(t, registry.Register("test-provider", &MockProvider{name: "test-provider", available: true}))
+
+	type token struct {
+		phase models.Phase
+		index int
+		delta string
+	}
+	var tokens []token
+	opts := models.GenerateOptions{
+		Request: models.PromptRequest{
+			Input:  "Summarize a log file",
+			Phases: []models.Phase{models.PhasePrimaMaterial, models.PhaseSolutio},
+			Count:  1,
+		},
+		PhaseConfigs: []models.PhaseConfig{
+			{Phase: models.PhasePrimaMaterial, Provider: "test-provider"},
+			{Phase: models.PhaseSolutio, Provider: "streaming"},
+		},
+		OnToken: func(phase models.Phase, index int, delta string) {
+			tokens = append(tokens, token{phase, index, delta})
+			streaming.delivered <- struct{}{}
+		},
+	}
+
+	result, err := engine.Generate(context.Background(), opts)
+	require.NoError(t, err)
+	assert.Equal(t, []token{
+		{models.PhaseSolutio, 0, "Write "},
+		{models.PhaseSolutio, 0, "a "},
+		{models.PhaseSolutio, 0, "CSV "},
+		{models.PhaseSolutio, 0, "parser"},
+	}, tokens, "only the final phase is streamed, token by token")
+
+	solutio := findResultByPhase(result.Prompts, models.PhaseSolutio)
+	require.NotNil(t, solutio)
+	assert.Equal(t, "Write a CSV parser", solutio.Content)
+	assert.Equal(t, "stream-model", solutio.Model)
+	assert.Equal(t, 42, solutio.ActualTokens)
+
+	t.Run("non-streaming providers send their output in one piece", func(t *testing.T) {
+		tokens = nil
+		opts.Request.Count = 2
+		opts.PhaseConfigs[1].Provider = "test-provider"
+		opts.OnToken = func(phase models.Phase, index int, delta string) {
+			tokens = append(tokens, token{phase, index, delta})
+		}
+
+		result, err := engine.Generate(context.Background(), opts)
+		require.NoError(t, err)
+		require.Len(t, tokens, 2)
+		for i, tok := range tokens {
+			assert.Equal(t, models.PhaseSolutio, tok.phase)
+			assert.Equal(t, i, tok.index)
+			assert.Equal(t, result.Prompts[2+i].Content, tok.delta)
+		}
+	})
+}
+
+func TestEngine_Generate_CustomPhases(t *testing.T) {
+	viper.Set("phases.custom", map[string]interface{}{
+		"critique": map[string]interface{}{
+			"template":      "Critique this prompt for {{.Persona}}: {{.Prompt}}",
+			"system_prompt": "You are a demanding reviewer.",
+			"provider":      "test-provider",
+			"temperature":   0.2,
+		},
+		"format": map[string]interface{}{
+			"template": "Format as markdown: {{.Prompt}}",
+			"provider": "test-provider",
+		},
+	})
+	defer viper.Reset()
+
+	engine, registry := setupTestEngine(t)
+	var requests []providers.GenerateRequest
+	require.NoError(t, registry.Register("test-provider", &MockProvider{
+		name:      "test-provider",
+		available: true,
+		generateFunc: func(ctx context.Context, req providers.GenerateRequest) (*providers.GenerateResponse, error) {
+			requests = append(requests, req)
+			return &providers.GenerateResponse{Content: "out(" + req.Prompt + ")", Model: "test-model"}, nil
+		},
+	}))
+
+	// No phase configs: each custom phase runs on its default provider
+	result, err := engine.Generate(context.Background(), models.GenerateOptions{
+		Request: models.PromptRequest{
+			Input:       "Write a changelog",
+			Phases:      []models.Phase{"critique", "format"},
+			Count:       1,
+			Temperature: 0.7,
+		},
+		Persona: "maintainers",
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Prompts, 2)
+	require.Len(t, requests, 2)
+
+	assert.Equal(t, "Critique this prompt for maintainers: Write a changelog", requests[0].Prompt)
+	assert.Equal(t, "You are a demanding reviewer.", requests[0].SystemPrompt)
+	assert.Equal(t, 0.2, requests[0].Temperature, "the phase's temperature overrides the request's")
+	assert.Equal(t, "Format as markdown: "+result.Prompts[0].Content, requests[1].Prompt, "each phase builds on the previous one")
+	assert.Equal(t, 0.7, requests[1].Temperature)
+
+	assert.Equal(t, models.Phase("critique"), result.Prompts[0].Phase)
+	assert.Equal(t, models.Phase("format"), result.Prompts[1].Phase)
+	require.NotNil(t, result.Prompts[1].ParentID)
+	assert.Equal(t, result.Prompts[0].ID, *result.Prompts[1].ParentID)
+
+	t.Run("unknown phases are listed", func(t *testing.T) {
+		_, err := engine.Generate(context.Background(), models.GenerateOptions{
+			Request: models.PromptRequest{
+				Input:  "Write a changelog",
+				Phases: []models.Phase{"critique", "polish", models.PhaseSolutio, "translate"},
+				Count:  1,
+			},
+		})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unknown phases: polish, translate")
+		assert.Contains(t, err.Error(), "critique, format")
+	})
+}
+
+func TestEngine_Generate_AutoSummarizeContext(t *testing.T) {
+	viper.Set("generation.context_token_budget", 50)
+	defer viper.Reset()
+
+	engine, registry := setupTestEngine(t)
+	require.NoError(t, registry.Register("test-provider", &MockProvider{name: "test-provider", available: true}))
+
+	generate := func(contextEntries []string, summarize bool) *models.GenerationResult {
+		result, err := engine.Generate(context.Background(), models.GenerateOptions{
+			Request: models.PromptRequest{
+				Input:   "Write a release note",
+				Phases:  []models.Phase{models.PhasePrimaMaterial},
+				Count:   1,
+				Context: contextEntries,
+			},
+			PhaseConfigs:         []models.PhaseConfig{{Phase: models.PhasePrimaMaterial, Provider: "test-provider"}},
+			AutoSummarizeContext: summarize,
+		})
+		require.NoError(t, err)
+		require.Len(t, result.Prompts, 1)
+		return result
+	}
+
+	// The request's context follows the engine's own generation details
+	requestContext := func(prompt models.Prompt, n int) []string {
+		require.GreaterOrEqual(t, len(prompt.GenerationContext), n)
+		return prompt.GenerationContext[len(prompt.GenerationContext)-n:]
+	}
+
+	short := "The release adds batch generation."
+	long := strings.Repeat("The processing pipeline now retries failed provider calls with backoff. ", 20)
+
+	t.Run("under budget", func(t *testing.T) {
+		result := generate([]string{short}, true)
+		assert.False(t, result.ContextSummarized)
+		assert.Zero(t, result.ContextTokensSaved)
+		assert.Equal(t, []string{short}, requestContext(result.Prompts[0], 1))
+	})
+
+	t.Run("over budget", func(t *testing.T) {
+		result := generate([]string{short, long}, true)
+		assert.True(t, result.ContextSummarized)
+		assert.Greater(t, result.ContextTokensSaved, 0)
+
+		entries := requestContext(result.Prompts[0], 2)
+		assert.Equal(t, short, entries[0], "context within the budget is kept verbatim")
+		assert.Less(t, len(entries[1]), len(long))
+		assert.Equal(t, calculateInputTokens(long)-calculateInputTokens(entries[1]), result.ContextTokensSaved)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		result := generate([]string{short, long}, false)
+		assert.False(t, result.ContextSummarized)
+		assert.Equal(t, []string{short, long}, requestContext(result.Prompts[0], 2))
+	})
+}
+
+func TestEngine_Generate_Tracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(previous)
+
+	engine, registry := setupTestEngine(t)
+	require.NoError(t, registry.Register("test-provider", &MockProvider{name: "test-provider", available: true}))
+
+	_, err := engine.Generate(context.Background(), models.GenerateOptions{
+		Request: models.PromptRequest{
+			Input:  "Write a release note",
+			Phases: []models.Phase{models.PhasePrimaMaterial, models.PhaseSolutio},
+			Count:  2,
+		},
+		PhaseConfigs: []models.PhaseConfig{
+			{Phase: models.PhasePrimaMaterial, Provider: "test-provider"},
+			{Phase: models.PhaseSolutio, Provider: "test-provider"},
+		},
+	})
+	require.NoError(t, err)
+
+	spans := recorder.Ended()
+	var root sdktrace.ReadOnlySpan
+	var phaseSpans, providerSpans []sdktrace.ReadOnlySpan
+	for _, span := range spans {
+		switch span.Name() {
+		case "engine.Generate":
+			root = span
+		case "engine.phase":
+			phaseSpans = append(phaseSpans, span)
+		case "provider.Generate":
+			providerSpans = append(providerSpans, span)
+		}
+	}
+	require.NotNil(t, root)
+	assert.False(t, root.Parent().IsValid(), "engine.Generate is the root span")
+
+	// One span per phase under the generation, in order
+	require.Len(t, phaseSpans, 2)
+	phaseIDs := map[string]string{}
+	for i, phase := range []models.Phase{models.PhasePrimaMaterial, models.PhaseSolutio} {
+		span := phaseSpans[i]
+		assert.Equal(t, root.SpanContext().SpanID(), span.Parent().SpanID())
+		assert.Equal(t, root.SpanContext().TraceID(), span.SpanContext().TraceID())
+		assert.Contains(t, span.Attributes(), attribute.String("phase", string(phase)))
+		phaseIDs[span.SpanContext().SpanID().String()] = string(phase)
+	}
+
+	// Each provider call sits under the phase that made it
+	require.Len(t, providerSpans, 4)
+	perPhase := map[string]int{}
+	for _, span := range providerSpans {
+		phase, ok := phaseIDs[span.Parent().SpanID().String()]
+		require.True(t, ok, "provider span %s has no phase parent", span.SpanContext().SpanID())
+		assert.Contains(t, span.Attributes(), attribute.String("phase", phase))
+		assert.Contains(t, span.Attributes(), attribute.String("provider", "test-provider"))
+		perPhase[phase]++
+	}
+	assert.Equal(t, map[string]int{string(models.PhasePrimaMaterial): 2, string(models.PhaseSolutio): 2}, perPhase)
+
+	t.Run("failures are recorded", func(t *testing.T) {
+		recorder := tracetest.NewSpanRecorder()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+		_, err := engine.Generate(context.Background(), models.GenerateOptions{
+			Request: models.PromptRequest{
+				Input:  "Write a release note",
+				Phases: []models.Phase{models.PhasePrimaMaterial},
+				Count:  1,
+			},
+			PhaseConfigs: []models.PhaseConfig{{Phase: models.PhasePrimaMaterial, Provider: "missing"}},
+		})
+		require.Error(t, err)
+
+		for _, span := range recorder.Ended() {
+			assert.Equal(t, codes.Error, span.Status().Code, span.Name())
+		}
+		assert.Len(t, recorder.Ended(), 2)
+	})
+}
+
+func TestEngine_Generate_CancelledContext(t *testing.T) {
+	engine, registry := setupTestEngine(t)
+
+	var calls int32
+	require.NoError(t, registry.Register("test-provider", &MockProvider{
+		name:      "test-provider",
+		available: true,
+		generateFunc: func(ctx context.Context, req providers.GenerateRequest) (*providers.GenerateResponse, error) {
+			atomic.AddInt32(&calls, 1)
+			return &providers.GenerateResponse{Content: "ok", Model: "test-model"}, nil
+		},
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	opts := models.GenerateOptions{
+		Request: models.PromptRequest{
+			Input:  "Summarize a log file",
+			Phases: []models.Phase{models.PhasePrimaMaterial, models.PhaseSolutio},
+			Count:  1,
+		},
+		PhaseConfigs: []models.PhaseConfig{
+			{Phase: models.PhasePrimaMaterial, Provider: "test-provider"},
+			{Phase: models.PhaseSolutio, Provider: "test-provider"},
+		},
+		// Simulate the client disconnecting after the first phase
+		OnPhaseComplete: func(phase models.Phase, prompts []models.Prompt) { cancel() },
+	}
+
+	result, err := engine.Generate(ctx, opts)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Nil(t, result)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls), "no provider calls after cancellation")
+}
+
+func TestEngine_Generate_PhaseTimeout(t *testing.T) {
+	viper.Set("generation.phase_timeout", "50ms")
+	defer viper.Reset()
+	engine, registry := setupTestEngine(t)
+
+	var cancelled int32
+	require.NoError(t, registry.Register("stalled", &MockProvider{
+		name:      "stalled",
+		available: true,
+		generateFunc: func(ctx context.Context, req providers.GenerateRequest) (*providers.GenerateResponse, error) {
+			select {
+			case <-ctx.Done():
+				atomic.AddInt32(&cancelled, 1)
+				return nil, ctx.Err()
+			case <-time.After(5 * time.Second):
+				return &providers.GenerateResponse{Content: "too late", Model: "stalled-model"}, nil
+			}
+		},
+	}))
+	require.NoError(t, registry.Register("test-provider", &MockProvider{name: "test-provider", available: true}))
+
+	opts := models.GenerateOptions{
+		Request: models.PromptRequest{
+			Input:  "Summarize a log file",
+			Phases: []models.Phase{models.PhasePrimaMaterial, models.PhaseSolutio, models.PhaseCoagulatio},
+			Count:  2,
+		},
+		PhaseConfigs: []models.PhaseConfig{
+			{Phase: models.PhasePrimaMaterial, Provider: "test-provider"},
+			{Phase: models.PhaseSolutio, Provider: "stalled"},
+			{Phase: models.PhaseCoagulatio, Provider: "test-provider"},
+		},
+		UseParallel: true,
+	}
+
+	start := time.Now()
+	result, err := engine.Generate(context.Background(), opts)
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), 2*time.Second, "the stalled phase must not hold up the generation")
+	assert.Equal(t, int32(2), atomic.LoadInt32(&cancelled), "every call of the stalled phase is cancelled")
+
+	assert.Equal(t, []models.PhaseTimeout{{Phase: models.PhaseSolutio, Provider: "stalled", Timeout: "50ms"}}, result.PhaseTimeouts)
+	require.Len(t, result.Prompts, 4)
+	for _, prompt := range result.Prompts {
+		assert.NotEqual(t, models.PhaseSolutio, prompt.Phase)
+	}
+	// coagulatio picks up where prima-materia left off
+	coagulatio := findResultByPhase(result.Prompts, models.PhaseCoagulatio)
+	require.NotNil(t, coagulatio)
+	require.NotNil(t, coagulatio.ParentID)
+	assert.Equal(t, result.Prompts[0].ID, *coagulatio.ParentID)
+
+	t.Run("every phase timed out", func(t *testing.T) {
+		opts.PhaseConfigs = []models.PhaseConfig{
+			{Phase: models.PhasePrimaMaterial, Provider: "stalled"},
+			{Phase: models.PhaseSolutio, Provider: "stalled"},
+			{Phase: models.PhaseCoagulatio, Provider: "stalled"},
+		}
+		result, err := engine.Generate(context.Background(), opts)
+		assert.Nil(t, result)
+		var timedOut *PhaseTimeoutError
+		require.ErrorAs(t, err, &timedOut)
+		assert.Len(t, timedOut.Timeouts, 3)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+// lengthScorer scores prompts by content length
+type lengthScorer struct{}
+
+func (lengthScorer) RankPrompts(ctx context.Context, prompts []models.Prompt, originalInput string) ([]models.PromptRanking, error) {
+	rankings := make([]models.PromptRanking, len(prompts))
+	for i := range prompts {
+		rankings[i] = models.PromptRanking{Prompt: &prompts[i], Score: float64(len(prompts[i].Content))}
+	}
+	return rankings, nil
+}
+
+func TestEngine_Generate_Shadow(t *testing.T) {
+	engine, registry := setupTestEngine(t)
+	logger, hook := logtest.NewNullLogger()
+	engine.logger = logger
+	engine.SetShadowScorer(lengthScorer{})
+
+	var shadowCalls int32
+	require.NoError(t, registry.Register("test-provider", &MockProvider{name: "test-provider", available: true}))
+	require.NoError(t, registry.Register("candidate", &MockProvider{
+		name:      "candidate",
+		available: true,
+		generateFunc: func(ctx context.Context, req providers.GenerateRequest) (*providers.GenerateResponse, error) {
+			atomic.AddInt32(&shadowCalls, 1)
+			return &providers.GenerateResponse{Content: "short", TokensUsed: 5, Model: "candidate-model"}, nil
+		},
+	}))
+
+	opts := models.GenerateOptions{
+		Request: models.PromptRequest{
+			Input:  "Summarize a log file",
+			Phases: []models.Phase{models.PhasePrimaMaterial},
+			Count:  2,
+		},
+		PhaseConfigs: []models.PhaseConfig{
+			{Phase: models.PhasePrimaMaterial, Provider: "test-provider"},
+		},
+	}
+
+	// Disabled by default
+	_, err := engine.Generate(context.Background(), opts)
+	require.NoError(t, err)
+	engine.WaitForShadows()
+	assert.Equal(t, int32(0), atomic.LoadInt32(&shadowCalls))
+
+	engine.shadow = ShadowConfig{Provider: "candidate", SampleRate: 1, Timeout: time.Second}
+	result, err := engine.Generate(context.Background(), opts)
+	require.NoError(t, err)
+	engine.WaitForShadows()
+
+	// Shadow output is compared, never returned
+	assert.Equal(t, int32(2), atomic.LoadInt32(&shadowCalls))
+	for _, prompt := range result.Prompts {
+		assert.Equal(t, "test-provider", prompt.Provider)
+	}
+
+	var comparisons []*logrus.Entry
+	for _, entry := range hook.AllEntries() {
+		if entry.Message == "Shadow generation comparison" {
+			comparisons = append(comparisons, entry)
+		}
+	}
+	require.Len(t, comparisons, 2)
+	assert.Equal(t, "candidate", comparisons[0].Data["shadow_provider"])
+	assert.Equal(t, 5.0, comparisons[0].Data["shadow_score"])
+	assert.Less(t, comparisons[0].Data["score_delta"], 0.0)
+}
+
 func setupTestEngine(t *testing.T) (*Engine, *providers.Registry) {
 	logger := logrus.New()
 	logger.SetLevel(logrus.ErrorLevel)
@@ -446,3 +1256,267 @@ func BenchmarkEngine_Generate_MultiplePhases(b *testing.B) {
 		}
 	}
 }
+
+func TestSetUsage(t *testing.T) {
+	meta := &models.ModelMetadata{GenerationProvider: providers.ProviderAnthropic, GenerationModel: "claude-3-5-sonnet-20241022"}
+	setUsage(meta, &providers.GenerateResponse{TokensUsed: 1500, InputTokens: 1000, OutputTokens: 500}, "ignored")
+	assert.Equal(t, 1000, meta.InputTokens)
+	assert.Equal(t, 500, meta.OutputTokens)
+	assert.Equal(t, 1500, meta.TotalTokens)
+	assert.InDelta(t, 0.0105, meta.Cost, 1e-9)
+	assert.False(t, meta.CostEstimated)
+
+	// Without reported usage the input is estimated and no cost is charged
+	meta = &models.ModelMetadata{GenerationProvider: providers.ProviderOllama, GenerationModel: "llama3"}
+	setUsage(meta, &providers.GenerateResponse{}, "twelve chars")
+	assert.Equal(t, 3, meta.InputTokens)
+	assert.Zero(t, meta.Cost)
+	assert.True(t, meta.CostEstimated)
+
+	// Reported usage for an unpriced model
+	meta = &models.ModelMetadata{GenerationProvider: providers.ProviderOpenAI, GenerationModel: "davinci-002"}
+	setUsage(meta, &providers.GenerateResponse{InputTokens: 10, OutputTokens: 20}, "")
+	assert.Equal(t, 30, meta.TotalTokens)
+	assert.Zero(t, meta.Cost)
+	assert.True(t, meta.CostEstimated)
+}
+
+func TestEngine_Generate_TemperaturePerProvider(t *testing.T) {
+	engine, registry := setupTestEngine(t)
+	temperatures := map[models.Phase]float64{}
+	for _, name := range []string{providers.ProviderOpenAI, providers.ProviderAnthropic} {
+		require.NoError(t, registry.Register(name, &MockProvider{name: name, available: true}))
+	}
+	opts := models.GenerateOptions{
+		Request: models.PromptRequest{
+			Input:       "Design a rate limiter",
+			Phases:      []models.Phase{models.PhasePrimaMaterial, models.PhaseSolutio, models.PhaseCoagulatio},
+			Count:       1,
+			Temperature: 1.5,
+		},
+		// The first phase's provider accepts 1.5; the second's does not
+		PhaseConfigs: []models.PhaseConfig{
+			{Phase: models.PhasePrimaMaterial, Provider: providers.ProviderOpenAI},
+			{Phase: models.PhaseSolutio, Provider: providers.ProviderAnthropic},
+			{Phase: models.PhaseCoagulatio, Provider: providers.ProviderOpenAI},
+		},
+		OnPhaseComplete: func(phase models.Phase, prompts []models.Prompt) {
+			temperatures[phase] = prompts[0].Temperature
+		},
+	}
+
+	result, err := engine.Generate(context.Background(), opts)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[models.Phase]float64{
+		models.PhasePrimaMaterial: 1.5,
+		models.PhaseSolutio:       1.0,
+		models.PhaseCoagulatio:    1.5,
+	}, temperatures)
+	assert.Equal(t, []models.TemperatureAdjustment{{
+		Phase:     models.PhaseSolutio,
+		Provider:  providers.ProviderAnthropic,
+		Requested: 1.5,
+		Applied:   1.0,
+	}}, result.TemperatureAdjustments)
+
+	t.Run("in-range temperatures are not adjusted", func(t *testing.T) {
+		opts.Request.Temperature = 0.7
+		result, err := engine.Generate(context.Background(), opts)
+		require.NoError(t, err)
+		assert.Empty(t, result.TemperatureAdjustments)
+		assert.Equal(t, 0.7, temperatures[models.PhaseSolutio])
+	})
+}
+
+func TestEngine_Generate_Seed(t *testing.T) {
+	engine, registry := setupTestEngine(t)
+	seeds := map[string]*int{}
+	for _, name := range []string{providers.ProviderOpenAI, providers.ProviderAnthropic} {
+		name := name
+		require.NoError(t, registry.Register(name, &MockProvider{
+			name:      name,
+			available: true,
+			generateFunc: func(ctx context.Context, req providers.GenerateRequest) (*providers.GenerateResponse, error) {
+				seeds[name] = req.Seed
+				return &providers.GenerateResponse{
+					Content:           "Seeded response",
+					Model:             name + "-model",
+					SystemFingerprint: "fp_" + name,
+				}, nil
+			},
+		}))
+	}
+	seed := 42
+	opts := models.GenerateOptions{
+		Request: models.PromptRequest{
+			Input:  "Design a rate limiter",
+			Phases: []models.Phase{models.PhasePrimaMaterial, models.PhaseSolutio},
+			Count:  1,
+			Seed:   &seed,
+		},
+		PhaseConfigs: []models.PhaseConfig{
+			{Phase: models.PhasePrimaMaterial, Provider: providers.ProviderOpenAI},
+			{Phase: models.PhaseSolutio, Provider: providers.ProviderAnthropic},
+		},
+	}
+
+	result, err := engine.Generate(context.Background(), opts)
+	require.NoError(t, err)
+	require.Len(t, result.Prompts, 2)
+
+	require.NotNil(t, seeds[providers.ProviderOpenAI])
+	assert.Equal(t, 42, *seeds[providers.ProviderOpenAI])
+	for _, prompt := range result.Prompts {
+		require.NotNil(t, prompt.ModelMetadata)
+		require.NotNil(t, prompt.ModelMetadata.Seed)
+		assert.Equal(t, 42, *prompt.ModelMetadata.Seed)
+		assert.Equal(t, "fp_"+prompt.Provider, prompt.ModelMetadata.SystemFingerprint)
+		// Anthropic has no seed parameter, so the seed is noted as ignored
+		assert.Equal(t, prompt.Provider == providers.ProviderAnthropic, prompt.ModelMetadata.SeedIgnored)
+	}
+
+	t.Run("no seed", func(t *testing.T) {
+		opts.Request.Seed = nil
+		result, err := engine.Generate(context.Background(), opts)
+		require.NoError(t, err)
+		assert.Nil(t, seeds[providers.ProviderOpenAI])
+		for _, prompt := range result.Prompts {
+			assert.Nil(t, prompt.ModelMetadata.Seed)
+			assert.False(t, prompt.ModelMetadata.SeedIgnored)
+		}
+	})
+}
+
+func TestEngine_Generate_Citations(t *testing.T) {
+	engine, registry := setupTestEngine(t)
+	require.NoError(t, registry.Register(providers.ProviderPerplexity, &MockProvider{
+		name:      providers.ProviderPerplexity,
+		available: true,
+		generateFunc: func(ctx context.Context, req providers.GenerateRequest) (*providers.GenerateResponse, error) {
+			return &providers.GenerateResponse{
+				Content:   "Grounded response",
+				Model:     providers.DefaultPerplexityModel,
+				Citations: []string{"https://go.dev/doc/go1.23"},
+			}, nil
+		},
+	}))
+
+	result, err := engine.Generate(context.Background(), models.GenerateOptions{
+		Request:      models.PromptRequest{Input: "What changed in Go 1.23?", Phases: []models.Phase{models.PhasePrimaMaterial}, Count: 1},
+		PhaseConfigs: []models.PhaseConfig{{Phase: models.PhasePrimaMaterial, Provider: providers.ProviderPerplexity}},
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Prompts, 1)
+	assert.Equal(t, []string{"https://go.dev/doc/go1.23"}, result.Prompts[0].ModelMetadata.Citations)
+}
+
+func TestEngine_Generate_ModelAlias(t *testing.T) {
+	defer viper.Reset()
+	viper.Set("providers.openai.model", "gpt-4")
+	viper.Set("providers.openai.model_aliases", map[string]string{"gpt-4": "gpt-4o"})
+	engine, registry := setupTestEngine(t)
+	var requested []string
+	require.NoError(t, registry.Register(providers.ProviderOpenAI, &MockProvider{
+		name:      providers.ProviderOpenAI,
+		available: true,
+		generateFunc: func(ctx context.Context, req providers.GenerateRequest) (*providers.GenerateResponse, error) {
+			model := cmp.Or(req.Model, "gpt-4")
+			requested = append(requested, model)
+			if model == "gpt-4" {
+				return nil, errors.New("status code 404: The model `gpt-4` does not exist")
+			}
+			return &providers.GenerateResponse{Content: "Aliased response", Model: model}, nil
+		},
+	}))
+	opts := models.GenerateOptions{
+		Request: models.PromptRequest{
+			Input:  "Design a rate limiter",
+			Phases: []models.Phase{models.PhasePrimaMaterial},
+			Count:  1,
+		},
+		PhaseConfigs: []models.PhaseConfig{{Phase: models.PhasePrimaMaterial, Provider: providers.ProviderOpenAI}},
+	}
+
+	result, err := engine.Generate(context.Background(), opts)
+	require.NoError(t, err)
+	require.Len(t, result.Prompts, 1)
+	assert.Equal(t, []string{"gpt-4", "gpt-4o"}, requested)
+
+	prompt := result.Prompts[0]
+	assert.Equal(t, "gpt-4o", prompt.Model)
+	require.NotNil(t, prompt.ModelMetadata)
+	assert.Equal(t, "gpt-4o", prompt.ModelMetadata.GenerationModel)
+	assert.Equal(t, "gpt-4", prompt.ModelMetadata.ModelAliasedFrom)
+}
+
+// fakeContextRetriever returns the chunks it was given, recording the
+// search it was asked for
+type fakeContextRetriever struct {
+	chunks      []models.ContextChunk
+	documentIDs []uuid.UUID
+	embedding   []float32
+	limit       int
+}
+
+func (f *fakeContextRetriever) SearchContextChunks(ctx context.Context, documentIDs []uuid.UUID, embedding []float32, limit int, minSimilarity float64) ([]models.ContextChunk, error) {
+	f.documentIDs, f.embedding, f.limit = documentIDs, embedding, limit
+	return f.chunks, nil
+}
+
+func TestEngine_Generate_ContextDocuments(t *testing.T) {
+	engine, registry := setupTestEngine(t)
+	var prompts []string
+	require.NoError(t, registry.Register(providers.ProviderOpenAI, &MockProvider{
+		name:               providers.ProviderOpenAI,
+		available:          true,
+		supportsEmbeddings: true,
+		generateFunc: func(ctx context.Context, req providers.GenerateRequest) (*providers.GenerateResponse, error) {
+			prompts = append(prompts, req.Prompt)
+			return &providers.GenerateResponse{Content: "Grounded response", Model: "gpt-4"}, nil
+		},
+		embeddingFunc: func(ctx context.Context, text string, registry providers.RegistryInterface) ([]float32, error) {
+			return []float32{1, 0}, nil
+		},
+	}))
+
+	documentID := uuid.New()
+	retriever := &fakeContextRetriever{chunks: []models.ContextChunk{{
+		DocumentID:    documentID,
+		DocumentTitle: "Runbook",
+		Content:       "Rate limits are enforced per API key.",
+		Similarity:    0.9,
+	}}}
+	opts := models.GenerateOptions{
+		Request: models.PromptRequest{
+			Input:              "Design a rate limiter",
+			Phases:             []models.Phase{models.PhasePrimaMaterial},
+			Count:              1,
+			ContextDocumentIDs: []uuid.UUID{documentID},
+		},
+		PhaseConfigs: []models.PhaseConfig{{Phase: models.PhasePrimaMaterial, Provider: providers.ProviderOpenAI}},
+	}
+
+	t.Run("without a retriever", func(t *testing.T) {
+		_, err := engine.Generate(context.Background(), opts)
+		assert.ErrorContains(t, err, "context documents")
+	})
+
+	t.Run("relevant chunks are added to the context", func(t *testing.T) {
+		engine.SetContextRetriever(retriever)
+		result, err := engine.Generate(context.Background(), opts)
+		require.NoError(t, err)
+
+		assert.Equal(t, []uuid.UUID{documentID}, retriever.documentIDs)
+		assert.Equal(t, []float32{1, 0}, retriever.embedding)
+		assert.Equal(t, DefaultContextTopK, retriever.limit)
+		assert.Equal(t, retriever.chunks, result.RetrievedContext)
+
+		entry := "From Runbook:\nRate limits are enforced per API key."
+		require.Len(t, result.Prompts, 1)
+		assert.Contains(t, result.Prompts[0].GenerationContext, entry)
+		require.NotEmpty(t, prompts)
+		assert.Contains(t, prompts[len(prompts)-1], "Rate limits are enforced per API key.")
+		assert.Nil(t, opts.Request.Context, "the caller's context is left unchanged")
+	})
+}
@@ -0,0 +1,73 @@
+package engine
+
+import (
+	"fmt"
+
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/jonwraymond/prompt-alchemy/pkg/providers"
+)
+
+// Estimate projects the providers, models, tokens and cost of a generation
+// without calling any provider. Phases resolve their providers the same way
+// Generate does. The first phase's input is the request's own; later phases
+// are fed the previous phase's output, estimated at max_tokens. Contexts are
+// counted as is, even with AutoSummarizeContext set.
+func (e *Engine) Estimate(opts models.GenerateOptions) (*models.GenerationEstimate, error) {
+	if opts.Request.Count < 1 {
+		return nil, fmt.Errorf("count must be at least 1, got %d", opts.Request.Count)
+	}
+	if opts.Request.Count > 100 {
+		return nil, fmt.Errorf("count cannot exceed 100, got %d", opts.Request.Count)
+	}
+	if err := e.ValidatePhases(opts.Request.Phases); err != nil {
+		return nil, err
+	}
+	phaseConfigs := e.phaseConfigs(opts.PhaseConfigs, opts.Request.Phases)
+
+	input, err := opts.Request.ResolveInput()
+	if err != nil {
+		return nil, err
+	}
+	opts.Request.Input = input
+
+	estimate := &models.GenerationEstimate{Phases: make([]models.PhaseEstimate, 0, len(opts.Request.Phases))}
+	for i, phase := range opts.Request.Phases {
+		provider, err := providers.GetProviderForPhase(phaseConfigs, phase, e.registry)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get provider for phase %s: %w", phase, err)
+		}
+		handler, exists := e.phaseHandlers[phase]
+		if !exists {
+			return nil, fmt.Errorf("no handler for phase %s", phase)
+		}
+
+		// Each call sends the system prompt and the phase's rendering of its input
+		perCall := calculateInputTokens(e.buildSystemPrompt(phase, opts))
+		if i == 0 {
+			perCall += calculateInputTokens(handler.PreparePromptContent(input, opts))
+		} else {
+			perCall += calculateInputTokens(handler.PreparePromptContent("", opts)) + opts.Request.MaxTokens
+		}
+
+		phaseEstimate := models.PhaseEstimate{
+			Phase:        phase,
+			Provider:     provider.Name(),
+			Model:        providers.ConfiguredModel(provider.Name()),
+			Calls:        opts.Request.Count,
+			InputTokens:  perCall * opts.Request.Count,
+			OutputTokens: opts.Request.MaxTokens * opts.Request.Count,
+		}
+		if pricing, ok := providers.LookupPricing(phaseEstimate.Provider, phaseEstimate.Model); ok {
+			phaseEstimate.CostUSD = pricing.Cost(phaseEstimate.InputTokens, phaseEstimate.OutputTokens)
+			phaseEstimate.Priced = true
+		} else {
+			estimate.CostEstimated = true
+		}
+
+		estimate.Phases = append(estimate.Phases, phaseEstimate)
+		estimate.InputTokens += phaseEstimate.InputTokens
+		estimate.OutputTokens += phaseEstimate.OutputTokens
+		estimate.CostUSD += phaseEstimate.CostUSD
+	}
+	return estimate, nil
+}
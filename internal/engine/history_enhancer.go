@@ -39,7 +39,7 @@ type EnhancedContext struct {
 
 // EnhanceWithHistory enhances the input with historical context using RAG
 func (h *HistoryEnhancer) EnhanceWithHistory(ctx context.Context, input string, phase models.Phase) (*EnhancedContext, error) {
-	logger := log.GetLogger().WithFields(map[string]interface{}{
+	logger := log.FromContext(ctx).WithFields(map[string]interface{}{
 		"phase":        phase,
 		"input_length": len(input),
 	})
@@ -0,0 +1,205 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/jonwraymond/prompt-alchemy/internal/log"
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/jonwraymond/prompt-alchemy/pkg/providers"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// Configuration keys for the moderation pass
+const (
+	ModerationEnabledKey   = "moderation.enabled"
+	ModerationThresholdKey = "moderation.threshold"
+	ModerationActionKey    = "moderation.action"
+	ModerationProviderKey  = "moderation.provider"
+
+	// What happens to prompts scored at or above the threshold
+	ModerationActionFlag = "flag"
+	ModerationActionDrop = "drop"
+
+	DefaultModerationThreshold = 0.5
+)
+
+// ModerationConfig scores every generated prompt for toxicity and flags or
+// drops those at or above Threshold
+type ModerationConfig struct {
+	Enabled   bool
+	Threshold float64 // 0-1
+	Action    string  // flag or drop
+	Provider  string  // scores the prompts; empty picks one, see toxicityScorer
+}
+
+// LoadModerationConfig reads moderation.enabled, moderation.threshold,
+// moderation.action and moderation.provider. An unset threshold is
+// DefaultModerationThreshold, and actions other than drop flag.
+func LoadModerationConfig() ModerationConfig {
+	cfg := ModerationConfig{
+		Enabled:   viper.GetBool(ModerationEnabledKey),
+		Threshold: viper.GetFloat64(ModerationThresholdKey),
+		Action:    viper.GetString(ModerationActionKey),
+		Provider:  viper.GetString(ModerationProviderKey),
+	}
+	if cfg.Threshold <= 0 {
+		cfg.Threshold = DefaultModerationThreshold
+	}
+	cfg.Threshold = math.Min(cfg.Threshold, 1)
+	if cfg.Action != ModerationActionDrop {
+		cfg.Action = ModerationActionFlag
+	}
+	return cfg
+}
+
+// ToxicityScorer rates how likely a text is harmful, from 0 (safe) to 1
+type ToxicityScorer interface {
+	ScoreToxicity(ctx context.Context, text string) (float64, error)
+}
+
+// SetToxicityScorer sets the scorer used by moderation. Without it, a
+// provider from the registry scores the prompts.
+func (e *Engine) SetToxicityScorer(scorer ToxicityScorer) {
+	e.toxicity = scorer
+}
+
+// moderate scores prompts when moderation is enabled, marking those at or
+// above the threshold as flagged. It returns the prompts to keep, without
+// the flagged ones under the drop action, and how many were dropped.
+// Prompts whose scoring fails are kept unscored.
+func (e *Engine) moderate(ctx context.Context, prompts []models.Prompt) ([]models.Prompt, int) {
+	cfg := e.moderation
+	if !cfg.Enabled || len(prompts) == 0 {
+		return prompts, 0
+	}
+	logger := log.WithContext(ctx, e.logger)
+	scorer := e.toxicityScorer()
+	if scorer == nil {
+		logger.Warn("No provider available for moderation, keeping prompts unmoderated")
+		return prompts, 0
+	}
+
+	kept := make([]models.Prompt, 0, len(prompts))
+	dropped := 0
+	for _, prompt := range prompts {
+		score, err := scorer.ScoreToxicity(ctx, prompt.Content)
+		if err != nil {
+			logger.WithError(err).WithField("prompt_id", prompt.ID).Warn("Failed to moderate prompt, keeping it unscored")
+			kept = append(kept, prompt)
+			continue
+		}
+		prompt.ModerationScore = &score
+		prompt.Flagged = score >= cfg.Threshold
+		if prompt.Flagged {
+			logger.WithFields(logrus.Fields{
+				"prompt_id": prompt.ID,
+				"phase":     prompt.Phase,
+				"score":     score,
+				"threshold": cfg.Threshold,
+				"action":    cfg.Action,
+			}).Warn("Prompt flagged by moderation")
+			if cfg.Action == ModerationActionDrop {
+				dropped++
+				continue
+			}
+		}
+		kept = append(kept, prompt)
+	}
+	return kept, dropped
+}
+
+// toxicityScorer returns the scorer set by SetToxicityScorer, or one backed
+// by moderation.provider, the first available provider with a moderation
+// endpoint, or generation.default_provider, in that order. Providers without
+// a moderation endpoint are asked to classify each prompt. It returns nil if
+// none is available.
+func (e *Engine) toxicityScorer() ToxicityScorer {
+	if e.toxicity != nil {
+		return e.toxicity
+	}
+	scorerFor := func(name string) ToxicityScorer {
+		provider, err := e.registry.Get(name)
+		if err != nil || !provider.IsAvailable() {
+			return nil
+		}
+		if moderator, ok := providers.AsModerator(provider); ok {
+			return &moderatorScorer{moderator: moderator}
+		}
+		return &classifierScorer{provider: provider}
+	}
+
+	if name := e.moderation.Provider; name != "" {
+		return scorerFor(name)
+	}
+	available := e.registry.ListAvailable()
+	sort.Strings(available)
+	for _, name := range available {
+		if provider, err := e.registry.Get(name); err == nil {
+			if moderator, ok := providers.AsModerator(provider); ok {
+				return &moderatorScorer{moderator: moderator}
+			}
+		}
+	}
+	if name := viper.GetString("generation.default_provider"); name != "" {
+		return scorerFor(name)
+	}
+	return nil
+}
+
+// moderatorScorer scores text with a provider's moderation endpoint
+type moderatorScorer struct {
+	moderator providers.Moderator
+}
+
+func (s *moderatorScorer) ScoreToxicity(ctx context.Context, text string) (float64, error) {
+	score, err := s.moderator.Moderate(ctx, text)
+	if err != nil {
+		return 0, fmt.Errorf("failed to moderate with %s: %w", s.moderator.Name(), err)
+	}
+	return math.Max(0, math.Min(score, 1)), nil
+}
+
+// classifierScorer asks an LLM to rate how harmful a text is out of 10
+type classifierScorer struct {
+	provider providers.Provider
+}
+
+const toxicityPrompt = `Rate how toxic, hateful, harassing, violent, sexual or otherwise unsafe the text below is, from 0 (safe) to 10 (severe).
+Answer with the number only.
+
+Text:
+%s`
+
+var toxicityScorePattern = regexp.MustCompile(`\d+(\.\d+)?`)
+
+func (s *classifierScorer) ScoreToxicity(ctx context.Context, text string) (float64, error) {
+	response, err := s.provider.Generate(ctx, providers.GenerateRequest{
+		Prompt:      fmt.Sprintf(toxicityPrompt, text),
+		Temperature: 0.0, // Deterministic scoring
+		MaxTokens:   10,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get toxicity score from %s: %w", s.provider.Name(), err)
+	}
+	return parseToxicityScore(response.Content)
+}
+
+// parseToxicityScore reads the first number in an LLM's answer as a score
+// out of 10 and scales it to [0, 1]
+func parseToxicityScore(content string) (float64, error) {
+	match := toxicityScorePattern.FindString(content)
+	if match == "" {
+		return 0, fmt.Errorf("no score in response %q", content)
+	}
+	score, err := strconv.ParseFloat(match, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid score %q: %w", match, err)
+	}
+	return math.Max(0, math.Min(score, 10)) / 10, nil
+}
@@ -0,0 +1,162 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/jonwraymond/prompt-alchemy/pkg/providers"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// moderatingProvider is a provider with a moderation endpoint that scores
+// text containing "toxic" as 0.9 and anything else as 0.1
+type moderatingProvider struct {
+	MockProvider
+	calls int32
+}
+
+func (m *moderatingProvider) Moderate(ctx context.Context, text string) (float64, error) {
+	atomic.AddInt32(&m.calls, 1)
+	if strings.Contains(text, "toxic") {
+		return 0.9, nil
+	}
+	return 0.1, nil
+}
+
+func TestLoadModerationConfig(t *testing.T) {
+	defer viper.Reset()
+
+	cfg := LoadModerationConfig()
+	assert.False(t, cfg.Enabled)
+	assert.Equal(t, DefaultModerationThreshold, cfg.Threshold)
+	assert.Equal(t, ModerationActionFlag, cfg.Action)
+
+	viper.Set(ModerationEnabledKey, true)
+	viper.Set(ModerationThresholdKey, 0.8)
+	viper.Set(ModerationActionKey, ModerationActionDrop)
+	viper.Set(ModerationProviderKey, "openai")
+	assert.Equal(t, ModerationConfig{Enabled: true, Threshold: 0.8, Action: ModerationActionDrop, Provider: "openai"}, LoadModerationConfig())
+
+	viper.Set(ModerationActionKey, "block")
+	assert.Equal(t, ModerationActionFlag, LoadModerationConfig().Action, "unknown actions flag")
+}
+
+func TestEngine_Generate_Moderation(t *testing.T) {
+	setup := func(t *testing.T, cfg ModerationConfig) (*Engine, *moderatingProvider) {
+		engine, registry := setupTestEngine(t)
+		var generated int32
+		require.NoError(t, registry.Register("test-provider", &MockProvider{
+			name:      "test-provider",
+			available: true,
+			generateFunc: func(ctx context.Context, req providers.GenerateRequest) (*providers.GenerateResponse, error) {
+				// The first prompt of each phase is toxic
+				n := atomic.AddInt32(&generated, 1)
+				content := fmt.Sprintf("friendly prompt %d", n)
+				if n%2 == 1 {
+					content = fmt.Sprintf("toxic prompt %d", n)
+				}
+				return &providers.GenerateResponse{Content: content, TokensUsed: 10, Model: "test-model"}, nil
+			},
+		}))
+		moderator := &moderatingProvider{MockProvider: MockProvider{name: "moderator", available: true}}
+		require.NoError(t, registry.Register("moderator", moderator))
+		engine.moderation = cfg
+		return engine, moderator
+	}
+	opts := models.GenerateOptions{
+		Request: models.PromptRequest{
+			Input:  "Write a greeting",
+			Phases: []models.Phase{models.PhasePrimaMaterial},
+			Count:  2,
+		},
+		PhaseConfigs: []models.PhaseConfig{
+			{Phase: models.PhasePrimaMaterial, Provider: "test-provider"},
+		},
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		engine, moderator := setup(t, ModerationConfig{Threshold: 0.5, Action: ModerationActionDrop})
+		result, err := engine.Generate(context.Background(), opts)
+		require.NoError(t, err)
+		require.Len(t, result.Prompts, 2)
+		for _, prompt := range result.Prompts {
+			assert.Nil(t, prompt.ModerationScore)
+			assert.False(t, prompt.Flagged)
+		}
+		assert.Zero(t, atomic.LoadInt32(&moderator.calls))
+	})
+
+	t.Run("flag keeps every prompt", func(t *testing.T) {
+		engine, moderator := setup(t, ModerationConfig{Enabled: true, Threshold: 0.5, Action: ModerationActionFlag})
+		result, err := engine.Generate(context.Background(), opts)
+		require.NoError(t, err)
+		require.Len(t, result.Prompts, 2)
+		assert.Zero(t, result.ModerationDropped)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&moderator.calls))
+
+		toxic, friendly := result.Prompts[0], result.Prompts[1]
+		require.Contains(t, toxic.Content, "toxic")
+		require.NotNil(t, toxic.ModerationScore)
+		assert.Equal(t, 0.9, *toxic.ModerationScore)
+		assert.True(t, toxic.Flagged)
+		require.NotNil(t, friendly.ModerationScore)
+		assert.Equal(t, 0.1, *friendly.ModerationScore)
+		assert.False(t, friendly.Flagged)
+	})
+
+	t.Run("drop removes flagged prompts", func(t *testing.T) {
+		engine, _ := setup(t, ModerationConfig{Enabled: true, Threshold: 0.5, Action: ModerationActionDrop})
+		result, err := engine.Generate(context.Background(), opts)
+		require.NoError(t, err)
+		require.Len(t, result.Prompts, 1)
+		assert.Equal(t, 1, result.ModerationDropped)
+		assert.Contains(t, result.Prompts[0].Content, "friendly")
+		assert.False(t, result.Prompts[0].Flagged)
+	})
+
+	t.Run("dropped prompts don't feed the next phase", func(t *testing.T) {
+		engine, _ := setup(t, ModerationConfig{Enabled: true, Threshold: 0.5, Action: ModerationActionDrop})
+		multi := opts
+		multi.Request.Phases = []models.Phase{models.PhasePrimaMaterial, models.PhaseSolutio}
+		multi.PhaseConfigs = append(multi.PhaseConfigs, models.PhaseConfig{Phase: models.PhaseSolutio, Provider: "test-provider"})
+
+		result, err := engine.Generate(context.Background(), multi)
+		require.NoError(t, err)
+		// One prima-materia prompt survives and feeds solutio, whose only output is toxic
+		assert.Equal(t, 2, result.ModerationDropped)
+		require.Len(t, result.Prompts, 1)
+		assert.Equal(t, models.PhasePrimaMaterial, result.Prompts[0].Phase)
+	})
+
+	t.Run("a threshold above every score flags nothing", func(t *testing.T) {
+		engine, _ := setup(t, ModerationConfig{Enabled: true, Threshold: 0.95, Action: ModerationActionDrop})
+		result, err := engine.Generate(context.Background(), opts)
+		require.NoError(t, err)
+		assert.Len(t, result.Prompts, 2)
+		assert.Zero(t, result.ModerationDropped)
+	})
+}
+
+func TestToxicityScorer_Classifier(t *testing.T) {
+	engine, registry := setupTestEngine(t)
+	require.NoError(t, registry.Register("judge", &MockProvider{
+		name:      "judge",
+		available: true,
+		generateFunc: func(ctx context.Context, req providers.GenerateRequest) (*providers.GenerateResponse, error) {
+			return &providers.GenerateResponse{Content: "7"}, nil
+		},
+	}))
+	engine.moderation = ModerationConfig{Enabled: true, Threshold: 0.5, Action: ModerationActionFlag, Provider: "judge"}
+
+	prompts, dropped := engine.moderate(context.Background(), []models.Prompt{{Content: "anything"}})
+	assert.Zero(t, dropped)
+	require.NotNil(t, prompts[0].ModerationScore)
+	assert.InDelta(t, 0.7, *prompts[0].ModerationScore, 1e-9)
+	assert.True(t, prompts[0].Flagged)
+}
@@ -0,0 +1,44 @@
+package engine
+
+import (
+	"context"
+	"time"
+
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/jonwraymond/prompt-alchemy/pkg/providers"
+)
+
+// GenerationObserver is told the outcome of each provider call the engine
+// makes for a prompt, e.g. to export them as metrics. The observability
+// metrics implement it.
+type GenerationObserver interface {
+	// RecordPromptGeneration is called for each prompt generated, with the
+	// provider that served it and the cost in USD, 0 when unpriced
+	RecordPromptGeneration(ctx context.Context, phase, provider, model, persona string, duration time.Duration, tokens int, cost float64)
+	// RecordProviderError is called for each failed generation, with the
+	// class from providers.ErrorClass
+	RecordProviderError(provider, errorType string)
+}
+
+// SetGenerationObserver sets the observer told about each prompt generated
+// and each provider error. Set it before generating.
+func (e *Engine) SetGenerationObserver(observer GenerationObserver) {
+	e.observer = observer
+}
+
+// observePrompt reports a generated prompt to the observer, if any
+func (e *Engine) observePrompt(ctx context.Context, prompt *models.Prompt, persona string, duration time.Duration) {
+	if e.observer == nil {
+		return
+	}
+	meta := prompt.ModelMetadata
+	e.observer.RecordPromptGeneration(ctx, string(prompt.Phase), prompt.Provider, prompt.Model, persona, duration, meta.TotalTokens, meta.Cost)
+}
+
+// observeError reports a failed generation to the observer, if any
+func (e *Engine) observeError(provider string, err error) {
+	if e.observer == nil {
+		return
+	}
+	e.observer.RecordProviderError(provider, providers.ErrorClass(err))
+}
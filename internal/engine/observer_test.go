@@ -0,0 +1,74 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jonwraymond/prompt-alchemy/internal/observability/metrics"
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/jonwraymond/prompt-alchemy/pkg/providers"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEngine_GenerationObserver(t *testing.T) {
+	engine, registry := setupTestEngine(t)
+	require.NoError(t, registry.Register(providers.ProviderOpenAI, &MockProvider{
+		name:      providers.ProviderOpenAI,
+		available: true,
+		generateFunc: func(ctx context.Context, req providers.GenerateRequest) (*providers.GenerateResponse, error) {
+			return &providers.GenerateResponse{
+				Content:     "Observed response",
+				Model:       "gpt-4o-mini",
+				TokensUsed:  1000000,
+				InputTokens: 1000000,
+			}, nil
+		},
+	}))
+	require.NoError(t, registry.Register(providers.ProviderAnthropic, &MockProvider{
+		name:      providers.ProviderAnthropic,
+		available: true,
+		generateFunc: func(ctx context.Context, req providers.GenerateRequest) (*providers.GenerateResponse, error) {
+			return nil, fmt.Errorf("anthropic: %w", providers.ErrRateLimited)
+		},
+	}))
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	config := metrics.DefaultConfig()
+	appMetrics, err := metrics.NewMetrics(config, logger)
+	require.NoError(t, err)
+	engine.SetGenerationObserver(appMetrics)
+
+	generate := func(provider string) error {
+		_, err := engine.Generate(context.Background(), models.GenerateOptions{
+			Request: models.PromptRequest{
+				Input:   "Design a rate limiter",
+				Phases:  []models.Phase{models.PhasePrimaMaterial},
+				Count:   1,
+				Persona: "code",
+			},
+			Persona:      "code",
+			PhaseConfigs: []models.PhaseConfig{{Phase: models.PhasePrimaMaterial, Provider: provider}},
+		})
+		return err
+	}
+	require.NoError(t, generate(providers.ProviderOpenAI))
+	require.Error(t, generate(providers.ProviderAnthropic))
+
+	recorder := httptest.NewRecorder()
+	appMetrics.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", config.Path, nil))
+	body, err := io.ReadAll(recorder.Body)
+	require.NoError(t, err)
+	scraped := string(body)
+
+	assert.Contains(t, scraped, `prompt_alchemy_api_prompts_generated_total{persona="code",phase="prima-materia",provider="openai"} 1`)
+	assert.Contains(t, scraped, `prompt_alchemy_api_generation_duration_seconds_count{phase="prima-materia",provider="openai"} 1`)
+	assert.Contains(t, scraped, `prompt_alchemy_api_tokens_used_total{model="gpt-4o-mini",provider="openai"} 1e+06`)
+	assert.Contains(t, scraped, `prompt_alchemy_api_generation_cost_usd_total{model="gpt-4o-mini",provider="openai"} 0.15`)
+	assert.Contains(t, scraped, `prompt_alchemy_api_provider_errors_total{error_type="rate_limited",provider="anthropic"} 1`)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/jonwraymond/prompt-alchemy/internal/log"
 	"github.com/jonwraymond/prompt-alchemy/internal/optimizer"
 	"github.com/jonwraymond/prompt-alchemy/internal/storage"
 	"github.com/jonwraymond/prompt-alchemy/pkg/models"
@@ -29,11 +30,12 @@ func NewOptimizationIntegrator(logger *logrus.Logger, storage storage.StorageInt
 
 // OptimizePhaseOutput optimizes a phase output if optimization is enabled
 func (o *OptimizationIntegrator) OptimizePhaseOutput(ctx context.Context, prompt *models.Prompt, opts models.GenerateOptions) (*models.Prompt, error) {
+	logger := log.WithContext(ctx, o.logger)
 	if !opts.Optimize {
 		return prompt, nil
 	}
 
-	o.logger.WithFields(logrus.Fields{
+	logger.WithFields(logrus.Fields{
 		"phase":        prompt.Phase,
 		"prompt_id":    prompt.ID,
 		"target_score": opts.OptimizeTargetScore,
@@ -43,12 +45,12 @@ func (o *OptimizationIntegrator) OptimizePhaseOutput(ctx context.Context, prompt
 	// Get provider for optimization
 	provider, err := o.getOptimizationProvider(prompt.Provider)
 	if err != nil {
-		o.logger.WithError(err).Warn("Failed to get optimization provider, skipping optimization")
+		logger.WithError(err).Warn("Failed to get optimization provider, skipping optimization")
 		return prompt, nil
 	}
 
 	// Get judge provider (try to use different provider to avoid bias)
-	judgeProvider := o.getJudgeProvider(provider)
+	judgeProvider := o.getJudgeProvider(provider, logger)
 
 	// Create meta-prompt optimizer with storage and registry for historical learning
 	metaOptimizer := optimizer.NewMetaPromptOptimizer(provider, judgeProvider, o.storage, o.registry)
@@ -57,7 +59,7 @@ func (o *OptimizationIntegrator) OptimizePhaseOutput(ctx context.Context, prompt
 	personaType := models.PersonaType(opts.Persona)
 	_, err = models.GetPersona(personaType)
 	if err != nil {
-		o.logger.WithError(err).Warn("Failed to get persona for optimization")
+		logger.WithError(err).Warn("Failed to get persona for optimization")
 		personaType = models.PersonaGeneric
 	}
 
@@ -88,13 +90,13 @@ func (o *OptimizationIntegrator) OptimizePhaseOutput(ctx context.Context, prompt
 	// Run optimization
 	result, err := metaOptimizer.OptimizePrompt(ctx, request)
 	if err != nil {
-		o.logger.WithError(err).Warn("Optimization failed, using original prompt")
+		logger.WithError(err).Warn("Optimization failed, using original prompt")
 		return prompt, nil
 	}
 
 	// Check if optimization actually improved the prompt
 	if result.FinalScore <= result.OriginalScore {
-		o.logger.WithFields(logrus.Fields{
+		logger.WithFields(logrus.Fields{
 			"original_score": result.OriginalScore,
 			"final_score":    result.FinalScore,
 		}).Info("Optimization did not improve prompt, using original")
@@ -114,7 +116,7 @@ func (o *OptimizationIntegrator) OptimizePhaseOutput(ctx context.Context, prompt
 		fmt.Sprintf("optimization_improvement=%.2f", result.Improvement),
 	)
 
-	o.logger.WithFields(logrus.Fields{
+	logger.WithFields(logrus.Fields{
 		"phase":          prompt.Phase,
 		"original_score": result.OriginalScore,
 		"final_score":    result.FinalScore,
@@ -150,7 +152,7 @@ func (o *OptimizationIntegrator) getOptimizationProvider(preferredProvider strin
 }
 
 // getJudgeProvider gets a different provider for judging to avoid bias
-func (o *OptimizationIntegrator) getJudgeProvider(optimizationProvider providers.Provider) providers.Provider {
+func (o *OptimizationIntegrator) getJudgeProvider(optimizationProvider providers.Provider, logger *logrus.Entry) providers.Provider {
 	available := o.registry.ListAvailable()
 
 	// Try to find a different provider
@@ -158,14 +160,14 @@ func (o *OptimizationIntegrator) getJudgeProvider(optimizationProvider providers
 		if name != optimizationProvider.Name() {
 			provider, err := o.registry.Get(name)
 			if err == nil {
-				o.logger.WithField("judge_provider", name).Debug("Using different provider for judging")
+				logger.WithField("judge_provider", name).Debug("Using different provider for judging")
 				return provider
 			}
 		}
 	}
 
 	// Fallback to same provider
-	o.logger.Debug("Using same provider for optimization and judging")
+	logger.Debug("Using same provider for optimization and judging")
 	return optimizationProvider
 }
 
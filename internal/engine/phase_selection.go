@@ -0,0 +1,159 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/jonwraymond/prompt-alchemy/internal/log"
+	"github.com/jonwraymond/prompt-alchemy/internal/selection"
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// generateSelected runs each phase of opts on its own and keeps its best
+// prompt, chosen by SelectBestPrompt. With the cascade strategy each phase's
+// best prompt is the input of the next phase. A failed phase is skipped by
+// best and ends a cascade; the failures are only returned when no phase
+// produced a prompt.
+func (e *Engine) generateSelected(ctx context.Context, opts models.GenerateOptions) (*models.GenerationResult, error) {
+	logger := log.WithContext(ctx, e.logger).WithField("phase_selection", opts.PhaseSelection)
+	cascade := opts.PhaseSelection == models.PhaseSelectionCascade
+	result := &models.GenerationResult{
+		Prompts:    make([]models.Prompt, 0),
+		Rankings:   make([]models.PromptRanking, 0),
+		Candidates: make([]models.Prompt, 0),
+	}
+
+	if err := e.ValidatePhases(opts.Request.Phases); err != nil {
+		return nil, err
+	}
+	input, err := opts.Request.ResolveInput()
+	if err != nil {
+		return nil, err
+	}
+
+	// Retrieve once, rather than again for every phase
+	chunks, entries, err := e.retrieveContext(ctx, input, opts.Request.ContextDocumentIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) > 0 {
+		opts.Request.Context = append(slices.Clone(opts.Request.Context), entries...)
+	}
+	opts.Request.ContextDocumentIDs = nil
+	result.RetrievedContext = chunks
+
+	// Summarize once, rather than again for every phase
+	if opts.AutoSummarizeContext {
+		var saved int
+		opts.Request.Context, saved = e.summarizeContext(ctx, opts.Request.Context)
+		opts.AutoSummarizeContext = false
+		result.ContextSummarized = saved > 0
+		result.ContextTokensSaved = saved
+	}
+
+	var errs []error
+	var previous *models.Prompt
+	for _, phase := range opts.Request.Phases {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, fmt.Errorf("generation cancelled before phase %s: %w", phase, err))
+			break
+		}
+
+		phaseOpts := opts
+		phaseOpts.AutoSelect = false
+		phaseOpts.Request.Phases = []models.Phase{phase}
+		if previous != nil {
+			// The previous phase's output is never treated as a template
+			phaseOpts.Request.Input = previous.Content
+			phaseOpts.Request.Variables = nil
+			input = previous.Content
+		}
+
+		phaseResult, err := e.generate(ctx, phaseOpts)
+		if err != nil {
+			logger.WithError(err).WithField("phase", phase).Error("Failed to generate phase")
+			errs = append(errs, err)
+			if cascade {
+				break
+			}
+			continue
+		}
+		result.TemperatureAdjustments = append(result.TemperatureAdjustments, phaseResult.TemperatureAdjustments...)
+		result.PhaseTimeouts = append(result.PhaseTimeouts, phaseResult.PhaseTimeouts...)
+		result.ModerationDropped += phaseResult.ModerationDropped
+		if len(phaseResult.Prompts) == 0 {
+			continue
+		}
+
+		if previous != nil {
+			for i := range phaseResult.Prompts {
+				parentID := previous.ID
+				phaseResult.Prompts[i].ParentID = &parentID
+			}
+		}
+		result.Candidates = append(result.Candidates, phaseResult.Prompts...)
+
+		best := e.SelectBestPrompt(ctx, phaseResult.Prompts, phase, input, opts.Persona)
+		result.Prompts = append(result.Prompts, best)
+		logger.WithFields(logrus.Fields{
+			"phase":    phase,
+			"selected": best.ID,
+			"from":     len(phaseResult.Prompts),
+		}).Info("Selected best prompt of phase")
+		if cascade {
+			previous = &best
+		}
+	}
+
+	if len(result.Prompts) == 0 && len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return result, nil
+}
+
+// SelectBestPrompt returns the best of prompts, generated for phase from
+// input. The AI selector judges them with the provider that generated them,
+// or with generation.judge_providers when set. Should it fail, the prompt
+// the scorer set by SetShadowScorer ranks highest is returned, and without
+// a scorer the first.
+func (e *Engine) SelectBestPrompt(ctx context.Context, prompts []models.Prompt, phase models.Phase, input, persona string) models.Prompt {
+	if len(prompts) == 1 {
+		return prompts[0]
+	}
+	logger := log.WithContext(ctx, e.logger).WithField("phase", phase)
+
+	selector := selection.NewAISelector(e.registry)
+	selected, err := selector.Select(ctx, prompts, selection.SelectionCriteria{
+		TaskDescription:    input,
+		Persona:            persona,
+		Requirements:       []string{"relevance to the " + string(phase) + " phase"},
+		EvaluationProvider: prompts[0].Provider,
+		Weights:            selection.DefaultWeightFactors(),
+		JudgeProviders:     viper.GetStringSlice("generation.judge_providers"),
+	})
+	if err == nil && selected.SelectedPrompt != nil {
+		logger.WithField("selected", selected.SelectedPrompt.ID).Debug("AI selector chose best prompt")
+		return *selected.SelectedPrompt
+	}
+	logger.WithError(err).Warn("AI selection failed, falling back to the scorer")
+
+	if e.shadowScorer != nil {
+		rankings, err := e.shadowScorer.RankPrompts(ctx, prompts, input)
+		if err == nil {
+			var best *models.PromptRanking
+			for i := range rankings {
+				if rankings[i].Prompt != nil && (best == nil || rankings[i].Score > best.Score) {
+					best = &rankings[i]
+				}
+			}
+			if best != nil {
+				return *best.Prompt
+			}
+		}
+	}
+	return prompts[0]
+}
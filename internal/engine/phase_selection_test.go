@@ -0,0 +1,160 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/jonwraymond/prompt-alchemy/pkg/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// judgingProvider numbers the variants it generates and, asked to evaluate
+// prompts, scores each by its number, so the last variant of a phase is
+// always the best. Generation prompts are recorded.
+func judgingProvider(received *[]string, judgeErr error) *MockProvider {
+	variant := 0
+	return &MockProvider{
+		name:      "test-provider",
+		available: true,
+		generateFunc: func(ctx context.Context, req providers.GenerateRequest) (*providers.GenerateResponse, error) {
+			if !strings.HasPrefix(req.Prompt, "Please evaluate") {
+				*received = append(*received, req.Prompt)
+				variant++
+				return &providers.GenerateResponse{Content: fmt.Sprintf("variant-%d", variant), Model: "test-model"}, nil
+			}
+			if judgeErr != nil {
+				return nil, judgeErr
+			}
+
+			var scores []map[string]interface{}
+			for _, block := range strings.Split(req.Prompt, "---\nPrompt ID: ")[1:] {
+				id, content, _ := strings.Cut(strings.TrimSpace(block), "\n")
+				n, _ := strconv.Atoi(strings.TrimPrefix(content, "variant-"))
+				scores = append(scores, map[string]interface{}{"promptId": id, "score": float64(n), "confidence": 0.9})
+			}
+			body, _ := json.Marshal(scores)
+			return &providers.GenerateResponse{Content: string(body)}, nil
+		},
+	}
+}
+
+// stubScorer ranks the prompt with the given content highest
+type stubScorer struct{ best string }
+
+func (s stubScorer) RankPrompts(ctx context.Context, prompts []models.Prompt, originalInput string) ([]models.PromptRanking, error) {
+	rankings := make([]models.PromptRanking, len(prompts))
+	for i := range prompts {
+		rankings[i] = models.PromptRanking{Prompt: &prompts[i], Score: 0.1}
+		if prompts[i].Content == s.best {
+			rankings[i].Score = 0.9
+		}
+	}
+	return rankings, nil
+}
+
+func phaseSelectionOptions(strategy string) models.GenerateOptions {
+	return models.GenerateOptions{
+		Request: models.PromptRequest{
+			Input:  "Create a caching layer",
+			Phases: []models.Phase{models.PhasePrimaMaterial, models.PhaseSolutio},
+			Count:  3,
+		},
+		PhaseConfigs: []models.PhaseConfig{
+			{Phase: models.PhasePrimaMaterial, Provider: "test-provider"},
+			{Phase: models.PhaseSolutio, Provider: "test-provider"},
+		},
+		PhaseSelection: strategy,
+	}
+}
+
+func contents(prompts []models.Prompt) []string {
+	out := make([]string, len(prompts))
+	for i, p := range prompts {
+		out[i] = p.Content
+	}
+	return out
+}
+
+func TestEngine_Generate_PhaseSelection(t *testing.T) {
+	t.Run("all returns every prompt", func(t *testing.T) {
+		engine, registry := setupTestEngine(t)
+		var received []string
+		require.NoError(t, registry.Register("test-provider", judgingProvider(&received, nil)))
+
+		result, err := engine.Generate(context.Background(), phaseSelectionOptions(models.PhaseSelectionAll))
+		require.NoError(t, err)
+		assert.Len(t, result.Prompts, 6)
+		assert.Nil(t, result.Candidates)
+	})
+
+	t.Run("best keeps the judged best of each phase", func(t *testing.T) {
+		engine, registry := setupTestEngine(t)
+		var received []string
+		require.NoError(t, registry.Register("test-provider", judgingProvider(&received, nil)))
+
+		result, err := engine.Generate(context.Background(), phaseSelectionOptions(models.PhaseSelectionBest))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"variant-3", "variant-6"}, contents(result.Prompts))
+		assert.Equal(t, models.PhasePrimaMaterial, result.Prompts[0].Phase)
+		assert.Equal(t, models.PhaseSolutio, result.Prompts[1].Phase)
+		assert.Len(t, result.Candidates, 6)
+
+		require.Len(t, received, 6)
+		for _, prompt := range received {
+			assert.Contains(t, prompt, "Create a caching layer", "every phase starts from the input")
+		}
+	})
+
+	t.Run("cascade feeds each best prompt to the next phase", func(t *testing.T) {
+		engine, registry := setupTestEngine(t)
+		var received []string
+		require.NoError(t, registry.Register("test-provider", judgingProvider(&received, nil)))
+
+		result, err := engine.Generate(context.Background(), phaseSelectionOptions(models.PhaseSelectionCascade))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"variant-3", "variant-6"}, contents(result.Prompts))
+		assert.Len(t, result.Candidates, 6)
+
+		require.Len(t, received, 6)
+		for _, prompt := range received[3:] {
+			assert.Contains(t, prompt, "variant-3")
+			assert.NotContains(t, prompt, "Create a caching layer")
+		}
+		require.NotNil(t, result.Prompts[1].ParentID)
+		assert.Equal(t, result.Prompts[0].ID, *result.Prompts[1].ParentID)
+	})
+
+	t.Run("falls back to the scorer when the judge fails", func(t *testing.T) {
+		engine, registry := setupTestEngine(t)
+		var received []string
+		require.NoError(t, registry.Register("test-provider", judgingProvider(&received, errors.New("judge unavailable"))))
+		engine.SetShadowScorer(stubScorer{best: "variant-2"})
+
+		opts := phaseSelectionOptions(models.PhaseSelectionBest)
+		opts.Request.Phases = opts.Request.Phases[:1]
+		result, err := engine.Generate(context.Background(), opts)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"variant-2"}, contents(result.Prompts))
+	})
+
+	t.Run("fails when no phase produced a prompt", func(t *testing.T) {
+		engine, registry := setupTestEngine(t)
+		require.NoError(t, registry.Register("test-provider", &MockProvider{
+			name:      "test-provider",
+			available: true,
+			generateFunc: func(ctx context.Context, req providers.GenerateRequest) (*providers.GenerateResponse, error) {
+				return nil, errors.New("provider down")
+			},
+		}))
+
+		_, err := engine.Generate(context.Background(), phaseSelectionOptions(models.PhaseSelectionBest))
+		assert.ErrorContains(t, err, "provider down")
+	})
+}
@@ -0,0 +1,48 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/spf13/viper"
+)
+
+// PhaseTimeoutError is returned when every phase of a generation ran past
+// generation.phase_timeout, so there is nothing to return
+type PhaseTimeoutError struct {
+	Timeouts []models.PhaseTimeout
+}
+
+func (e *PhaseTimeoutError) Error() string {
+	phases := make([]string, len(e.Timeouts))
+	for i, timeout := range e.Timeouts {
+		phases[i] = fmt.Sprintf("%s (%s after %s)", timeout.Phase, timeout.Provider, timeout.Timeout)
+	}
+	return "every phase timed out: " + strings.Join(phases, ", ")
+}
+
+func (e *PhaseTimeoutError) Unwrap() error { return context.DeadlineExceeded }
+
+// phaseTimeout is how long a phase's provider calls may take altogether,
+// from generation.phase_timeout; zero means no limit beyond the caller's
+func phaseTimeout() time.Duration {
+	return viper.GetDuration("generation.phase_timeout")
+}
+
+// withPhaseTimeout derives the context a phase runs in
+func withPhaseTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// phaseTimedOut reports whether a phase failed because it ran past its own
+// timeout, rather than because the whole generation was cancelled
+func phaseTimedOut(ctx, phaseCtx context.Context, err error) bool {
+	return err != nil && ctx.Err() == nil && errors.Is(phaseCtx.Err(), context.DeadlineExceeded)
+}
@@ -0,0 +1,280 @@
+package engine
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jonwraymond/prompt-alchemy/internal/log"
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/jonwraymond/prompt-alchemy/pkg/providers"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// Configuration keys for the semantic cache
+const (
+	SemanticCacheEnabledKey   = "generation.semantic_cache.enabled"
+	SemanticCacheThresholdKey = "generation.semantic_cache.threshold"
+	SemanticCacheSizeKey      = "generation.semantic_cache.size"
+	SemanticCacheTTLKey       = "generation.semantic_cache.ttl"
+
+	DefaultSemanticCacheThreshold = 0.95
+	DefaultSemanticCacheSize      = 100
+	DefaultSemanticCacheTTL       = time.Hour
+)
+
+// SemanticCacheConfig reuses a generation for a later request with the same
+// options whose input embeds at least Threshold similar
+type SemanticCacheConfig struct {
+	Enabled   bool
+	Threshold float64       // minimum cosine similarity of the inputs, 0-1
+	Size      int           // generations kept, least recently used evicted first
+	TTL       time.Duration // how long a generation is reused, forever if zero
+}
+
+// LoadSemanticCacheConfig reads generation.semantic_cache.enabled,
+// threshold, size and ttl. Unset or out of range values get the defaults;
+// a ttl of 0 keeps generations until they are evicted.
+func LoadSemanticCacheConfig() SemanticCacheConfig {
+	cfg := SemanticCacheConfig{
+		Enabled:   viper.GetBool(SemanticCacheEnabledKey),
+		Threshold: viper.GetFloat64(SemanticCacheThresholdKey),
+		Size:      viper.GetInt(SemanticCacheSizeKey),
+		TTL:       max(viper.GetDuration(SemanticCacheTTLKey), 0),
+	}
+	if cfg.Threshold <= 0 || cfg.Threshold > 1 {
+		cfg.Threshold = DefaultSemanticCacheThreshold
+	}
+	if cfg.Size <= 0 {
+		cfg.Size = DefaultSemanticCacheSize
+	}
+	if !viper.IsSet(SemanticCacheTTLKey) {
+		cfg.TTL = DefaultSemanticCacheTTL
+	}
+	return cfg
+}
+
+type semanticCacheEntry struct {
+	key       [sha256.Size]byte // hash of the options other than the input
+	embedding []float32         // of the resolved input
+	result    *models.GenerationResult
+	expires   time.Time // zero when the entry never expires
+}
+
+// semanticCache keeps recent generations by the embedding of their input,
+// safe for concurrent use. Lookups compare against every entry, which is
+// fine for the few hundred a cache holds.
+type semanticCache struct {
+	mu      sync.Mutex
+	config  SemanticCacheConfig
+	entries []*semanticCacheEntry // most recently used first
+}
+
+// newSemanticCache creates a cache for config, or returns nil when it is
+// disabled
+func newSemanticCache(config SemanticCacheConfig) *semanticCache {
+	if !config.Enabled {
+		return nil
+	}
+	return &semanticCache{config: config}
+}
+
+// get returns a copy of the generation stored under key whose input is the
+// most similar to embedding, at least the threshold, and that similarity
+func (c *semanticCache) get(key [sha256.Size]byte, embedding []float32) (*models.GenerationResult, float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	c.entries = slices.DeleteFunc(c.entries, func(entry *semanticCacheEntry) bool {
+		return !entry.expires.IsZero() && now.After(entry.expires)
+	})
+
+	best, bestSimilarity := -1, 0.0
+	for i, entry := range c.entries {
+		if entry.key != key {
+			continue
+		}
+		if similarity := cosineSimilarity(embedding, entry.embedding); similarity >= c.config.Threshold && similarity > bestSimilarity {
+			best, bestSimilarity = i, similarity
+		}
+	}
+	if best < 0 {
+		return nil, 0
+	}
+
+	entry := c.entries[best]
+	copy(c.entries[1:best+1], c.entries[:best])
+	c.entries[0] = entry
+	return cloneGenerationResult(entry.result), bestSimilarity
+}
+
+// add stores a copy of result under key and the embedding of its input,
+// evicting the least recently used generation when the cache is full
+func (c *semanticCache) add(key [sha256.Size]byte, embedding []float32, result *models.GenerationResult) {
+	entry := &semanticCacheEntry{key: key, embedding: embedding, result: cloneGenerationResult(result)}
+	if c.config.TTL > 0 {
+		entry.expires = time.Now().Add(c.config.TTL)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = append([]*semanticCacheEntry{entry}, c.entries...)
+	if len(c.entries) > c.config.Size {
+		c.entries = c.entries[:c.config.Size]
+	}
+}
+
+// cloneGenerationResult copies result and its prompt lists, so callers can
+// change the prompts of a cached generation without changing the cache
+func cloneGenerationResult(result *models.GenerationResult) *models.GenerationResult {
+	clone := *result
+	clone.Prompts = slices.Clone(result.Prompts)
+	clone.Rankings = slices.Clone(result.Rankings)
+	clone.Candidates = slices.Clone(result.Candidates)
+	if result.Selected != nil {
+		selected := *result.Selected
+		clone.Selected = &selected
+	}
+	return &clone
+}
+
+// semanticCacheKey hashes the options of a generation other than its
+// input, so only generations asked for in the same way are reused
+func semanticCacheKey(opts models.GenerateOptions) ([sha256.Size]byte, error) {
+	request := opts.Request
+	request.Input = ""
+	request.Variables = nil
+	request.AllowMissingVariables = false
+	request.SessionID = uuid.Nil
+
+	data, err := json.Marshal(struct {
+		Request              models.PromptRequest
+		PhaseConfigs         []models.PhaseConfig
+		Persona              string
+		TargetModel          string
+		SystemPrompts        map[models.Phase]string
+		PhaseSelection       string
+		AutoSummarizeContext bool
+		TruncateOnOverflow   bool
+	}{
+		Request:              request,
+		PhaseConfigs:         opts.PhaseConfigs,
+		Persona:              opts.Persona,
+		TargetModel:          opts.TargetModel,
+		SystemPrompts:        opts.SystemPrompts,
+		PhaseSelection:       opts.PhaseSelection,
+		AutoSummarizeContext: opts.AutoSummarizeContext,
+		TruncateOnOverflow:   opts.TruncateOnOverflow,
+	})
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+	return sha256.Sum256(data), nil
+}
+
+// lookupSemanticCache returns a cached generation for opts, flagged as
+// cached, when the semantic cache holds one. Its prompts keep their IDs and
+// the session of the request that generated them. Otherwise it returns a
+// function that caches the generation made for opts, or nil when it can't
+// be cached: the cache is disabled or bypassed, or the input can't be
+// embedded.
+func (e *Engine) lookupSemanticCache(ctx context.Context, opts models.GenerateOptions) (*models.GenerationResult, func(*models.GenerationResult)) {
+	if e.semanticCache == nil || opts.BypassSemanticCache {
+		return nil, nil
+	}
+	logger := log.WithContext(ctx, e.logger)
+
+	input, err := opts.Request.ResolveInput()
+	if err != nil {
+		return nil, nil
+	}
+	embed := providers.NewQueryEmbedder(e.registry)
+	if embed == nil {
+		logger.Debug("No embedding provider, skipping the semantic cache")
+		return nil, nil
+	}
+	key, err := semanticCacheKey(opts)
+	if err != nil {
+		logger.WithError(err).Warn("Failed to key the semantic cache, skipping it")
+		return nil, nil
+	}
+	embedding, err := embed(ctx, input)
+	if err != nil {
+		logger.WithError(err).Warn("Failed to embed input for the semantic cache, skipping it")
+		return nil, nil
+	}
+
+	if cached, similarity := e.semanticCache.get(key, embedding); cached != nil {
+		cached.Cached = true
+		cached.CacheSimilarity = similarity
+		// The prompts stay in the session of the request that generated them
+		for i := range cached.Prompts {
+			cached.Prompts[i].SessionID = cached.SessionID
+		}
+		logger.WithFields(logrus.Fields{
+			"similarity": similarity,
+			"prompts":    len(cached.Prompts),
+		}).Info("Returning cached generation for a similar input")
+		replayGeneration(opts, cached)
+		return cached, nil
+	}
+	return nil, func(result *models.GenerationResult) {
+		result.SessionID = opts.Request.SessionID
+		if len(result.Prompts) > 0 {
+			e.semanticCache.add(key, embedding, result)
+		}
+	}
+}
+
+// replayGeneration calls the phase callbacks of opts for a cached
+// generation as if its phases had just run, the final phase's text sent in
+// one piece per variant
+func replayGeneration(opts models.GenerateOptions, result *models.GenerationResult) {
+	var order []models.Phase
+	byPhase := make(map[models.Phase][]models.Prompt)
+	for _, prompt := range result.Prompts {
+		if _, ok := byPhase[prompt.Phase]; !ok {
+			order = append(order, prompt.Phase)
+		}
+		byPhase[prompt.Phase] = append(byPhase[prompt.Phase], prompt)
+	}
+
+	for i, phase := range order {
+		if opts.OnPhaseStart != nil {
+			opts.OnPhaseStart(phase)
+		}
+		if opts.OnToken != nil && i == len(order)-1 {
+			for index, prompt := range byPhase[phase] {
+				opts.OnToken(phase, index, prompt.Content)
+			}
+		}
+		if opts.OnPhaseComplete != nil {
+			opts.OnPhaseComplete(phase, byPhase[phase])
+		}
+	}
+}
+
+// cosineSimilarity returns the cosine of the angle between a and b, 0 when
+// either is zero or their lengths differ
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,163 @@
+package engine
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/jonwraymond/prompt-alchemy/pkg/providers"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadSemanticCacheConfig(t *testing.T) {
+	defer viper.Reset()
+
+	cfg := LoadSemanticCacheConfig()
+	assert.False(t, cfg.Enabled)
+	assert.Equal(t, DefaultSemanticCacheThreshold, cfg.Threshold)
+	assert.Equal(t, DefaultSemanticCacheSize, cfg.Size)
+	assert.Equal(t, DefaultSemanticCacheTTL, cfg.TTL)
+	assert.Nil(t, newSemanticCache(cfg))
+
+	viper.Set(SemanticCacheEnabledKey, true)
+	viper.Set(SemanticCacheThresholdKey, 0.9)
+	viper.Set(SemanticCacheSizeKey, 10)
+	viper.Set(SemanticCacheTTLKey, "0s")
+	cfg = LoadSemanticCacheConfig()
+	assert.Equal(t, SemanticCacheConfig{Enabled: true, Threshold: 0.9, Size: 10}, cfg)
+	assert.NotNil(t, newSemanticCache(cfg))
+
+	viper.Set(SemanticCacheThresholdKey, 1.5)
+	assert.Equal(t, DefaultSemanticCacheThreshold, LoadSemanticCacheConfig().Threshold)
+}
+
+func TestEngine_Generate_SemanticCache(t *testing.T) {
+	defer viper.Reset()
+	viper.Set(SemanticCacheEnabledKey, true)
+	viper.Set(SemanticCacheThresholdKey, 0.9)
+
+	engine, registry := setupTestEngine(t)
+	var generations int32
+	keywords := []string{"rate", "limiter", "api", "poem", "autumn"}
+	require.NoError(t, registry.Register(providers.ProviderOpenAI, &MockProvider{
+		name:               providers.ProviderOpenAI,
+		available:          true,
+		supportsEmbeddings: true,
+		generateFunc: func(ctx context.Context, req providers.GenerateRequest) (*providers.GenerateResponse, error) {
+			atomic.AddInt32(&generations, 1)
+			return &providers.GenerateResponse{Content: "Generated for: " + req.Prompt, Model: "gpt-4"}, nil
+		},
+		embeddingFunc: func(ctx context.Context, text string, registry providers.RegistryInterface) ([]float32, error) {
+			embedding := make([]float32, len(keywords))
+			for i, keyword := range keywords {
+				embedding[i] = float32(strings.Count(strings.ToLower(text), keyword))
+			}
+			return embedding, nil
+		},
+	}))
+
+	options := func(input string) models.GenerateOptions {
+		return models.GenerateOptions{
+			Request: models.PromptRequest{
+				Input:  input,
+				Phases: []models.Phase{models.PhasePrimaMaterial},
+				Count:  1,
+			},
+			PhaseConfigs: []models.PhaseConfig{{Phase: models.PhasePrimaMaterial, Provider: providers.ProviderOpenAI}},
+		}
+	}
+
+	firstOpts := options("Design a rate limiter for an API")
+	firstOpts.Request.SessionID = uuid.New()
+	first, err := engine.Generate(context.Background(), firstOpts)
+	require.NoError(t, err)
+	require.Len(t, first.Prompts, 1)
+	assert.False(t, first.Cached)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&generations))
+
+	t.Run("near-duplicate input hits the cache", func(t *testing.T) {
+		var completed []models.Phase
+		opts := options("design a rate limiter for the API")
+		opts.OnPhaseComplete = func(phase models.Phase, prompts []models.Prompt) {
+			completed = append(completed, phase)
+		}
+
+		result, err := engine.Generate(context.Background(), opts)
+		require.NoError(t, err)
+		assert.True(t, result.Cached)
+		assert.InDelta(t, 1, result.CacheSimilarity, 1e-9)
+		require.Len(t, result.Prompts, 1)
+		assert.Equal(t, first.Prompts[0].ID, result.Prompts[0].ID)
+		assert.Equal(t, firstOpts.Request.SessionID, result.SessionID)
+		assert.Equal(t, firstOpts.Request.SessionID, result.Prompts[0].SessionID)
+		assert.Equal(t, first.Prompts[0].Content, result.Prompts[0].Content)
+		assert.Equal(t, []models.Phase{models.PhasePrimaMaterial}, completed)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&generations))
+	})
+
+	t.Run("cached prompts are copies", func(t *testing.T) {
+		result, err := engine.Generate(context.Background(), options("Design a rate limiter for an API"))
+		require.NoError(t, err)
+		require.True(t, result.Cached)
+		result.Prompts[0].Content = "changed by the caller"
+
+		again, err := engine.Generate(context.Background(), options("Design a rate limiter for an API"))
+		require.NoError(t, err)
+		assert.Equal(t, first.Prompts[0].Content, again.Prompts[0].Content)
+	})
+
+	t.Run("dissimilar input misses the cache", func(t *testing.T) {
+		result, err := engine.Generate(context.Background(), options("Write a poem about autumn"))
+		require.NoError(t, err)
+		assert.False(t, result.Cached)
+		assert.Zero(t, result.CacheSimilarity)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&generations))
+	})
+
+	t.Run("different options miss the cache", func(t *testing.T) {
+		opts := options("Design a rate limiter for an API")
+		opts.Request.Temperature = 0.2
+		result, err := engine.Generate(context.Background(), opts)
+		require.NoError(t, err)
+		assert.False(t, result.Cached)
+		assert.Equal(t, int32(3), atomic.LoadInt32(&generations))
+	})
+
+	t.Run("bypassed per request", func(t *testing.T) {
+		opts := options("Design a rate limiter for an API")
+		opts.BypassSemanticCache = true
+		result, err := engine.Generate(context.Background(), opts)
+		require.NoError(t, err)
+		assert.False(t, result.Cached)
+		assert.Equal(t, int32(4), atomic.LoadInt32(&generations))
+	})
+}
+
+func TestSemanticCache_Eviction(t *testing.T) {
+	cache := newSemanticCache(SemanticCacheConfig{Enabled: true, Threshold: 0.9, Size: 2, TTL: time.Hour})
+	key, err := semanticCacheKey(models.GenerateOptions{})
+	require.NoError(t, err)
+
+	result := func(content string) *models.GenerationResult {
+		return &models.GenerationResult{Prompts: []models.Prompt{{Content: content}}}
+	}
+	cache.add(key, []float32{1, 0, 0}, result("x"))
+	cache.add(key, []float32{0, 1, 0}, result("y"))
+
+	// Using x makes y the least recently used, evicted by z
+	cached, _ := cache.get(key, []float32{1, 0, 0})
+	require.NotNil(t, cached)
+	cache.add(key, []float32{0, 0, 1}, result("z"))
+
+	cached, _ = cache.get(key, []float32{0, 1, 0})
+	assert.Nil(t, cached)
+	cached, _ = cache.get(key, []float32{1, 0, 0})
+	require.NotNil(t, cached)
+	assert.Equal(t, "x", cached.Prompts[0].Content)
+}
@@ -0,0 +1,151 @@
+package engine
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/jonwraymond/prompt-alchemy/internal/log"
+	"github.com/jonwraymond/prompt-alchemy/internal/observability/tracing"
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/jonwraymond/prompt-alchemy/pkg/providers"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// DefaultShadowTimeout bounds a shadow generation when
+// providers.shadow.timeout is unset
+const DefaultShadowTimeout = 60 * time.Second
+
+// ShadowConfig sends a sample of generations to a candidate provider as
+// well, so it can be compared on real traffic before switching to it
+type ShadowConfig struct {
+	Provider   string
+	SampleRate float64 // fraction of generations to shadow, 0-1
+	Timeout    time.Duration
+}
+
+// LoadShadowConfig reads providers.shadow.provider,
+// providers.shadow.sample_rate and providers.shadow.timeout
+func LoadShadowConfig() ShadowConfig {
+	cfg := ShadowConfig{
+		Provider:   viper.GetString("providers.shadow.provider"),
+		SampleRate: viper.GetFloat64("providers.shadow.sample_rate"),
+		Timeout:    viper.GetDuration("providers.shadow.timeout"),
+	}
+	if cfg.SampleRate > 1 {
+		cfg.SampleRate = 1
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultShadowTimeout
+	}
+	return cfg
+}
+
+// Enabled reports whether any generations are shadowed
+func (c ShadowConfig) Enabled() bool {
+	return c.Provider != "" && c.SampleRate > 0
+}
+
+// PromptScorer scores prompts against the input they were generated from.
+// ranking.Ranker satisfies it.
+type PromptScorer interface {
+	RankPrompts(ctx context.Context, prompts []models.Prompt, originalInput string) ([]models.PromptRanking, error)
+}
+
+// SetShadowScorer sets the scorer used to compare shadow generations with
+// the primary ones, which SelectBestPrompt also falls back on. Without it,
+// shadow comparisons log tokens and latency only.
+func (e *Engine) SetShadowScorer(scorer PromptScorer) {
+	e.shadowScorer = scorer
+}
+
+// WaitForShadows blocks until in-flight shadow generations finish, so
+// short-lived commands don't exit before logging their comparisons
+func (e *Engine) WaitForShadows() {
+	e.shadowWG.Wait()
+}
+
+// maybeShadow runs req against the shadow provider in the background for a
+// sample of generations and logs how its output compares with primary. The
+// shadow result is never returned or saved.
+func (e *Engine) maybeShadow(ctx context.Context, req providers.GenerateRequest, primary *models.Prompt, input string) {
+	cfg := e.shadow
+	if !cfg.Enabled() || cfg.Provider == primary.Provider || rand.Float64() >= cfg.SampleRate {
+		return
+	}
+
+	logger := log.WithContext(ctx, e.logger).WithFields(logrus.Fields{
+		"phase":           primary.Phase,
+		"provider":        primary.Provider,
+		"shadow_provider": cfg.Provider,
+	})
+	shadow, err := e.registry.Get(cfg.Provider)
+	if err != nil || !shadow.IsAvailable() {
+		logger.Debug("Shadow provider unavailable, skipping shadow generation")
+		return
+	}
+
+	// Copy the primary prompt, which the caller goes on to modify
+	snapshot := *primary
+	var primaryLatency int
+	if primary.ModelMetadata != nil {
+		primaryLatency = primary.ModelMetadata.ProcessingTime
+	}
+
+	// The shadow outlives the request, but keeps its request ID for logging
+	// and joins its trace
+	shadowCtx := tracing.WithSpanFrom(log.WithRequestID(context.Background(), log.RequestIDFromContext(ctx)), ctx)
+	e.shadowWG.Add(1)
+	go func() {
+		defer e.shadowWG.Done()
+		shadowCtx, cancel := context.WithTimeout(shadowCtx, cfg.Timeout)
+		defer cancel()
+
+		start := time.Now()
+		resp, err := shadow.Generate(shadowCtx, req)
+		latency := time.Since(start)
+		if err != nil {
+			logger.WithError(err).Warn("Shadow generation failed")
+			return
+		}
+
+		fields := logrus.Fields{
+			"primary_model":      snapshot.Model,
+			"shadow_model":       resp.Model,
+			"primary_tokens":     snapshot.ActualTokens,
+			"shadow_tokens":      resp.TokensUsed,
+			"primary_latency_ms": primaryLatency,
+			"shadow_latency_ms":  latency.Milliseconds(),
+		}
+		if e.shadowScorer != nil {
+			candidate := models.Prompt{
+				ID:          snapshot.ID,
+				Content:     resp.Content,
+				Phase:       snapshot.Phase,
+				Provider:    cfg.Provider,
+				Model:       resp.Model,
+				Temperature: snapshot.Temperature,
+			}
+			primaryScore, primaryErr := e.scorePrompt(shadowCtx, snapshot, input)
+			shadowScore, shadowErr := e.scorePrompt(shadowCtx, candidate, input)
+			if primaryErr == nil && shadowErr == nil {
+				fields["primary_score"] = primaryScore
+				fields["shadow_score"] = shadowScore
+				fields["score_delta"] = shadowScore - primaryScore
+			} else {
+				logger.Debug("Failed to score shadow comparison")
+			}
+		}
+		logger.WithFields(fields).Info("Shadow generation comparison")
+	}()
+}
+
+// scorePrompt returns the shadow scorer's score for a single prompt
+func (e *Engine) scorePrompt(ctx context.Context, prompt models.Prompt, input string) (float64, error) {
+	rankings, err := e.shadowScorer.RankPrompts(ctx, []models.Prompt{prompt}, input)
+	if err != nil || len(rankings) == 0 {
+		return 0, err
+	}
+	return rankings[0].Score, nil
+}
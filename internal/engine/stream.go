@@ -0,0 +1,72 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/jonwraymond/prompt-alchemy/pkg/providers"
+)
+
+// tokenSink returns the callback streaming the index'th variant of phase to
+// opts.OnToken, or nil when opts has none. Generate clears OnToken for every
+// phase but the last, so only the final phase is streamed.
+func tokenSink(phase models.Phase, index int, opts models.GenerateOptions) func(delta string) {
+	if opts.OnToken == nil {
+		return nil
+	}
+	return func(delta string) {
+		opts.OnToken(phase, index, delta)
+	}
+}
+
+// streamGenerate generates with provider, calling onToken with the text as
+// it arrives and returning the assembled response. Providers that can't
+// stream have their whole output sent as one piece.
+func streamGenerate(ctx context.Context, provider providers.Provider, req providers.GenerateRequest, onToken func(delta string)) (*providers.GenerateResponse, error) {
+	streamer, ok := providers.AsStreaming(provider)
+	if !ok {
+		resp, err := provider.Generate(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.Content != "" {
+			onToken(resp.Content)
+		}
+		return resp, nil
+	}
+
+	req.Stream = true
+	chunks, err := streamer.GenerateStream(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	resp := &providers.GenerateResponse{}
+	var content strings.Builder
+	for chunk := range chunks {
+		if chunk.ContentDelta != "" {
+			content.WriteString(chunk.ContentDelta)
+			onToken(chunk.ContentDelta)
+		}
+		if chunk.Model != "" {
+			resp.Model = chunk.Model
+		}
+		if chunk.Done {
+			if chunk.Error != nil {
+				return nil, chunk.Error
+			}
+			resp.Content = content.String()
+			resp.TokensUsed = chunk.TokensUsed
+			resp.SystemFingerprint = chunk.SystemFingerprint
+			resp.Provider = chunk.Provider
+			resp.FallbackFrom = chunk.FallbackFrom
+			return resp, nil
+		}
+	}
+	// The provider stops sending without a final chunk once ctx is done
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return nil, errors.New("stream ended before generation finished")
+}
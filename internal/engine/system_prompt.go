@@ -0,0 +1,57 @@
+package engine
+
+import (
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// loadSystemPrompts reads the phases.<name>.system_prompt overrides of the
+// given phases. Overrides over models.MaxSystemPromptLength are logged and
+// ignored, leaving the phase's default.
+func loadSystemPrompts(list []models.Phase, logger *logrus.Logger) map[models.Phase]string {
+	overrides := make(map[models.Phase]string)
+	for _, phase := range list {
+		systemPrompt := viper.GetString("phases." + string(phase) + ".system_prompt")
+		if systemPrompt == "" {
+			continue
+		}
+		if err := models.ValidateSystemPrompt(phase, systemPrompt); err != nil {
+			logger.WithError(err).Error("Invalid system prompt override, using the phase's default")
+			continue
+		}
+		overrides[phase] = systemPrompt
+	}
+	return overrides
+}
+
+// systemPromptOverride returns the system prompt replacing phase's default
+// and where it came from, "request" or "config". It is empty when the
+// default applies.
+func (e *Engine) systemPromptOverride(phase models.Phase, opts models.GenerateOptions) (string, string) {
+	if systemPrompt := opts.SystemPrompts[phase]; systemPrompt != "" {
+		return systemPrompt, "request"
+	}
+	if systemPrompt := e.systemPrompts[phase]; systemPrompt != "" {
+		return systemPrompt, "config"
+	}
+	return "", ""
+}
+
+// buildSystemPrompt returns the system prompt phase runs with: its override
+// if there is one, otherwise the handler's default, followed by a custom
+// persona's fragment. Built-in personas shape the phase templates instead.
+func (e *Engine) buildSystemPrompt(phase models.Phase, opts models.GenerateOptions) string {
+	systemPrompt, _ := e.systemPromptOverride(phase, opts)
+	if systemPrompt == "" {
+		systemPrompt = e.phaseHandlers[phase].BuildSystemPrompt(opts)
+	}
+	persona, ok := models.CustomPersona(models.PersonaType(opts.Persona))
+	if !ok {
+		return systemPrompt
+	}
+	if systemPrompt == "" {
+		return persona.SystemPrompt
+	}
+	return systemPrompt + "\n\n" + persona.SystemPrompt
+}
@@ -23,6 +23,12 @@ type FeatureFlags struct {
 	EnableOllama     bool `json:"enable_ollama"`
 	EnableOpenRouter bool `json:"enable_openrouter"`
 	EnableGrok       bool `json:"enable_grok"`
+	EnableMistral    bool `json:"enable_mistral"`
+	EnableDeepSeek   bool `json:"enable_deepseek"`
+	EnableCohere     bool `json:"enable_cohere"`
+	EnablePerplexity bool `json:"enable_perplexity"`
+	EnableAzure      bool `json:"enable_azure"`
+	EnableBedrock    bool `json:"enable_bedrock"`
 
 	// Engine Features
 	EnableParallelPhases  bool `json:"enable_parallel_phases"`
@@ -67,6 +73,12 @@ func DefaultFeatureFlags() *FeatureFlags {
 		EnableOllama:     true,
 		EnableOpenRouter: true,
 		EnableGrok:       true,
+		EnableMistral:    true,
+		EnableDeepSeek:   true,
+		EnableCohere:     true,
+		EnablePerplexity: true,
+		EnableAzure:      true,
+		EnableBedrock:    true,
 
 		// Engine Features - conservative defaults
 		EnableParallelPhases:  true,
@@ -109,6 +121,12 @@ func LoadFeatureFlags() *FeatureFlags {
 	flags.EnableOllama = getEnvBool("ENABLE_OLLAMA", flags.EnableOllama)
 	flags.EnableOpenRouter = getEnvBool("ENABLE_OPENROUTER", flags.EnableOpenRouter)
 	flags.EnableGrok = getEnvBool("ENABLE_GROK", flags.EnableGrok)
+	flags.EnableMistral = getEnvBool("ENABLE_MISTRAL", flags.EnableMistral)
+	flags.EnableDeepSeek = getEnvBool("ENABLE_DEEPSEEK", flags.EnableDeepSeek)
+	flags.EnableCohere = getEnvBool("ENABLE_COHERE", flags.EnableCohere)
+	flags.EnablePerplexity = getEnvBool("ENABLE_PERPLEXITY", flags.EnablePerplexity)
+	flags.EnableAzure = getEnvBool("ENABLE_AZURE", flags.EnableAzure)
+	flags.EnableBedrock = getEnvBool("ENABLE_BEDROCK", flags.EnableBedrock)
 
 	// Engine Features
 	flags.EnableParallelPhases = getEnvBool("ENABLE_PARALLEL_PHASES", flags.EnableParallelPhases)
@@ -164,6 +182,18 @@ func (f *FeatureFlags) IsEnabled(feature string) bool {
 		return f.EnableOpenRouter
 	case "grok":
 		return f.EnableGrok
+	case "mistral":
+		return f.EnableMistral
+	case "deepseek":
+		return f.EnableDeepSeek
+	case "cohere":
+		return f.EnableCohere
+	case "perplexity":
+		return f.EnablePerplexity
+	case "azure":
+		return f.EnableAzure
+	case "bedrock":
+		return f.EnableBedrock
 
 	// Engine Features
 	case "parallel_phases":
@@ -229,6 +259,18 @@ func (f *FeatureFlags) SetFeature(feature string, enabled bool) {
 		f.EnableOpenRouter = enabled
 	case "grok":
 		f.EnableGrok = enabled
+	case "mistral":
+		f.EnableMistral = enabled
+	case "deepseek":
+		f.EnableDeepSeek = enabled
+	case "cohere":
+		f.EnableCohere = enabled
+	case "perplexity":
+		f.EnablePerplexity = enabled
+	case "azure":
+		f.EnableAzure = enabled
+	case "bedrock":
+		f.EnableBedrock = enabled
 
 	// Engine Features
 	case "parallel_phases":
@@ -287,6 +329,24 @@ func (f *FeatureFlags) GetEnabledProviders() []string {
 	if f.EnableGrok {
 		providers = append(providers, "grok")
 	}
+	if f.EnableMistral {
+		providers = append(providers, "mistral")
+	}
+	if f.EnableDeepSeek {
+		providers = append(providers, "deepseek")
+	}
+	if f.EnableCohere {
+		providers = append(providers, "cohere")
+	}
+	if f.EnablePerplexity {
+		providers = append(providers, "perplexity")
+	}
+	if f.EnableAzure {
+		providers = append(providers, "azure")
+	}
+	if f.EnableBedrock {
+		providers = append(providers, "bedrock")
+	}
 
 	return providers
 }
@@ -364,6 +424,12 @@ func (f *FeatureFlags) Copy() *FeatureFlags {
 		EnableOllama:          f.EnableOllama,
 		EnableOpenRouter:      f.EnableOpenRouter,
 		EnableGrok:            f.EnableGrok,
+		EnableMistral:         f.EnableMistral,
+		EnableDeepSeek:        f.EnableDeepSeek,
+		EnableCohere:          f.EnableCohere,
+		EnablePerplexity:      f.EnablePerplexity,
+		EnableAzure:           f.EnableAzure,
+		EnableBedrock:         f.EnableBedrock,
 		EnableParallelPhases:  f.EnableParallelPhases,
 		EnableBatchGeneration: f.EnableBatchGeneration,
 		EnableStreaming:       f.EnableStreaming,
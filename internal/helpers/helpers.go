@@ -4,9 +4,13 @@ import (
 	"strings"
 
 	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/jonwraymond/prompt-alchemy/pkg/providers"
 	"github.com/spf13/viper"
 )
 
+// ParsePhases parses a comma-separated phase list, accepting the legacy
+// names of the built-in phases. Other names are kept as given so custom
+// phases can be requested; the engine rejects names it doesn't know.
 func ParsePhases(phasesStr string) []models.Phase {
 	parts := strings.Split(phasesStr, ",")
 	phases := make([]models.Phase, 0, len(parts))
@@ -20,6 +24,9 @@ func ParsePhases(phasesStr string) []models.Phase {
 			phases = append(phases, models.PhaseSolutio)
 		case "coagulatio", "precision":
 			phases = append(phases, models.PhaseCoagulatio)
+		case "":
+		default:
+			phases = append(phases, models.Phase(phase))
 		}
 	}
 	return phases
@@ -45,7 +52,7 @@ func BuildPhaseConfigs(phases []models.Phase, overrideProvider string) []models.
 	for _, phase := range phases {
 		provider := overrideProvider
 		if provider == "" {
-			provider = viper.GetString("phases." + string(phase) + ".provider")
+			provider = PhaseProvider(phase)
 			// Fallback to ollama if viper returns empty (configuration issue)
 			if provider == "" {
 				provider = "ollama"
@@ -55,3 +62,17 @@ func BuildPhaseConfigs(phases []models.Phase, overrideProvider string) []models.
 	}
 	return configs
 }
+
+// PhaseProvider returns the provider configured for a phase under
+// phases.<name>.provider, the first of its pool under
+// phases.<name>.providers, or a custom phase's default provider under
+// phases.custom.<name>.provider. It is empty if none is set.
+func PhaseProvider(phase models.Phase) string {
+	if provider := viper.GetString("phases." + string(phase) + ".provider"); provider != "" {
+		return provider
+	}
+	if pool := providers.LoadBalanceConfig(phase); len(pool.Providers) > 0 {
+		return pool.Providers[0].Name
+	}
+	return viper.GetString("phases.custom." + string(phase) + ".provider")
+}
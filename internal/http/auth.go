@@ -0,0 +1,114 @@
+package http
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// APIKeyScope is what requests an API key may make
+type APIKeyScope string
+
+const (
+	// ScopeRead allows GET, HEAD and OPTIONS requests only
+	ScopeRead APIKeyScope = "read"
+	// ScopeWrite allows every request
+	ScopeWrite APIKeyScope = "write"
+)
+
+// authExemptPaths are served without an API key so probes keep working
+var authExemptPaths = map[string]bool{
+	"/health":  true,
+	"/healthz": true,
+	"/readyz":  true,
+	"/version": true,
+}
+
+// apiKey is a configured key and its scope
+type apiKey struct {
+	key   string
+	scope APIKeyScope
+}
+
+// apiKeys returns the configured keys: APIKeys with write scope and
+// ReadOnlyAPIKeys with read scope
+func (c *Config) apiKeys() []apiKey {
+	keys := make([]apiKey, 0, len(c.APIKeys)+len(c.ReadOnlyAPIKeys))
+	for _, key := range c.APIKeys {
+		keys = append(keys, apiKey{key, ScopeWrite})
+	}
+	for _, key := range c.ReadOnlyAPIKeys {
+		keys = append(keys, apiKey{key, ScopeRead})
+	}
+	return keys
+}
+
+// requireAPIKey rejects requests without a valid key in the X-API-Key or
+// Authorization: Bearer header with 401, and requests a read-only key may
+// not make with 403
+func (s *SimpleServer) requireAPIKey(next http.Handler) http.Handler {
+	keys := s.config.apiKeys()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if authExemptPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		presented := requestAPIKey(r)
+		if presented == "" {
+			s.writeError(w, http.StatusUnauthorized, "API key required")
+			return
+		}
+
+		scope, ok := lookupAPIKey(keys, presented)
+		if !ok {
+			s.requestLogger(r.Context()).WithField("remote_addr", r.RemoteAddr).Warn("Invalid API key")
+			s.writeError(w, http.StatusUnauthorized, "Invalid API key")
+			return
+		}
+		if scope != ScopeWrite && !isReadRequest(r) {
+			s.writeError(w, http.StatusForbidden, "API key is read-only")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestAPIKey returns the key sent in X-API-Key or as a bearer token
+func requestAPIKey(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
+
+// lookupAPIKey compares presented against every key in constant time
+func lookupAPIKey(keys []apiKey, presented string) (APIKeyScope, bool) {
+	var scope APIKeyScope
+	found := false
+	for _, k := range keys {
+		if subtle.ConstantTimeCompare([]byte(k.key), []byte(presented)) == 1 && !found {
+			scope, found = k.scope, true
+		}
+	}
+	return scope, found
+}
+
+// isReadRequest reports whether r only reads, so a read-scoped key may make it
+func isReadRequest(r *http.Request) bool {
+	return isReadMethod(r.Method)
+}
+
+// isReadMethod reports whether requests with method only read
+func isReadMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
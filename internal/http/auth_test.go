@@ -0,0 +1,70 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireAPIKey(t *testing.T) {
+	defer viper.Reset()
+	viper.Set("http.enable_auth", true)
+	viper.Set("http.api_keys", []string{"write-key"})
+	viper.Set("http.read_only_api_keys", []string{"read-key"})
+
+	server, _ := newTestServer(t)
+
+	send := func(method, path string, header http.Header) int {
+		req := httptest.NewRequest(method, path, nil)
+		if method == http.MethodPost {
+			// Malformed on purpose: a 400 shows the request got past auth
+			req = httptest.NewRequest(method, path, strings.NewReader("{"))
+			req.Header.Set("Content-Type", "application/json")
+		}
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		recorder := httptest.NewRecorder()
+		server.Router().ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+	withKey := func(key string) http.Header { return http.Header{"X-Api-Key": {key}} }
+	withBearer := func(key string) http.Header { return http.Header{"Authorization": {"Bearer " + key}} }
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		header http.Header
+		want   int
+	}{
+		{name: "health is exempt", method: http.MethodGet, path: "/health", want: http.StatusOK},
+		{name: "version is exempt", method: http.MethodGet, path: "/version", want: http.StatusOK},
+		{name: "missing key", method: http.MethodGet, path: "/api/v1/providers", want: http.StatusUnauthorized},
+		{name: "invalid key", method: http.MethodGet, path: "/api/v1/providers", header: withKey("nope"), want: http.StatusUnauthorized},
+		{name: "read key may read", method: http.MethodGet, path: "/api/v1/providers", header: withKey("read-key"), want: http.StatusOK},
+		{name: "read key may not write", method: http.MethodPost, path: "/api/v1/prompts/", header: withKey("read-key"), want: http.StatusForbidden},
+		{name: "read key may not delete", method: http.MethodDelete, path: "/api/v1/prompts/not-a-uuid", header: withBearer("read-key"), want: http.StatusForbidden},
+		{name: "write key may read", method: http.MethodGet, path: "/api/v1/providers", header: withBearer("write-key"), want: http.StatusOK},
+		{name: "write key may write", method: http.MethodPost, path: "/api/v1/prompts/", header: withKey("write-key"), want: http.StatusBadRequest},
+		{name: "write key may delete", method: http.MethodDelete, path: "/api/v1/prompts/not-a-uuid", header: withKey("write-key"), want: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, send(tt.method, tt.path, tt.header))
+		})
+	}
+}
+
+func TestAuthDisabledByDefault(t *testing.T) {
+	server, _ := newTestServer(t)
+
+	recorder := httptest.NewRecorder()
+	server.Router().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/providers", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+}
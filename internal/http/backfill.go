@@ -0,0 +1,73 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/jonwraymond/prompt-alchemy/internal/storage"
+	"github.com/jonwraymond/prompt-alchemy/pkg/providers"
+)
+
+// BackfillDryRunResponse counts the prompts a backfill would embed
+type BackfillDryRunResponse struct {
+	DryRun  bool `json:"dry_run"`
+	Missing int  `json:"missing"`
+}
+
+// handleStartBackfill embeds, in the background, the prompts saved without
+// an embedding. ?dry_run=true only counts them; ?batch_size= overrides
+// embeddings.backfill_batch_size. Progress is reported by
+// handleBackfillStatus.
+func (s *SimpleServer) handleStartBackfill(w http.ResponseWriter, r *http.Request) {
+	logger := s.requestLogger(r.Context())
+
+	if dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run")); dryRun {
+		missing, err := s.store.CountPromptsWithoutEmbeddings(r.Context())
+		if err != nil {
+			logger.WithError(err).Error("Failed to count prompts without embeddings")
+			s.writeError(w, http.StatusInternalServerError, "Failed to count prompts without embeddings")
+			return
+		}
+		s.writeJSON(w, http.StatusOK, BackfillDryRunResponse{DryRun: true, Missing: missing})
+		return
+	}
+
+	opts := s.backfillOptions()
+	if value := r.URL.Query().Get("batch_size"); value != "" {
+		batchSize, err := strconv.Atoi(value)
+		if err != nil || batchSize < 1 {
+			s.writeError(w, http.StatusBadRequest, "batch_size must be a positive integer")
+			return
+		}
+		opts.BatchSize = batchSize
+	}
+
+	err := s.store.StartBackfill(r.Context(), opts)
+	switch {
+	case errors.Is(err, storage.ErrBackfillRunning), errors.Is(err, storage.ErrReindexRunning):
+		s.writeError(w, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		logger.WithError(err).Warn("Failed to start embedding backfill")
+		s.writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+
+	logger.Info("Started embedding backfill")
+	s.writeJSON(w, http.StatusAccepted, s.store.BackfillStatus())
+}
+
+// handleBackfillStatus reports the progress of the current or last backfill
+func (s *SimpleServer) handleBackfillStatus(w http.ResponseWriter, r *http.Request) {
+	s.writeJSON(w, http.StatusOK, s.store.BackfillStatus())
+}
+
+// backfillOptions embeds with the provider semantic search uses, batched as
+// configured under embeddings
+func (s *SimpleServer) backfillOptions() storage.BackfillOptions {
+	opts := storage.LoadBackfillOptions()
+	opts.Embed = providers.NewQueryEmbedder(s.registry)
+	opts.Provider = providers.QueryEmbedderName(s.registry)
+	return opts
+}
@@ -0,0 +1,230 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/jonwraymond/prompt-alchemy/internal/storage"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// backupTimeFormat names backups after the UTC time they were taken
+const backupTimeFormat = "20060102-150405.000"
+
+// BackupResponse describes a backup written to storage.backup_dir
+type BackupResponse struct {
+	File      string    `json:"file"`
+	Path      string    `json:"path"`
+	SizeBytes int64     `json:"size_bytes"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RestoreRequest names the backup in storage.backup_dir to restore
+type RestoreRequest struct {
+	File string `json:"file"`
+}
+
+// RestoreResponse describes the restored backup
+type RestoreResponse struct {
+	File       string    `json:"file"`
+	Prompts    int       `json:"prompts"`
+	RestoredAt time.Time `json:"restored_at"`
+}
+
+// generationGate keeps restores and generations apart. Each generation
+// holds it shared and a restore exclusively, so a restore is refused while
+// any generation is in flight and generations are refused during a restore.
+type generationGate struct {
+	mu sync.RWMutex
+}
+
+// Middleware wraps a generation route with the gate
+func (g *generationGate) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !g.mu.TryRLock() {
+			w.Header().Set("Retry-After", "5")
+			writeErrorResponse(w, errorResponse(w, &APIError{
+				Status:  http.StatusServiceUnavailable,
+				Code:    ErrCodeUnavailable,
+				Message: "A database restore is in progress",
+			}))
+			return
+		}
+		defer g.mu.RUnlock()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleBackup snapshots the database while the server keeps running. With
+// storage.backup_dir set the backup is written there and described in the
+// response; otherwise it is streamed as the response body. Like the other
+// admin routes that expose or replace all data, it is only served with API
+// key authentication enabled.
+func (s *SimpleServer) handleBackup(w http.ResponseWriter, r *http.Request) {
+	logger := s.requestLogger(r.Context())
+
+	if !s.config.EnableAuth {
+		s.writeError(w, http.StatusForbidden, "Backups require API key authentication (http.enable_auth)")
+		return
+	}
+
+	createdAt := time.Now().UTC()
+	file := "prompts-" + createdAt.Format(backupTimeFormat) + ".db"
+	dir := viper.GetString("storage.backup_dir")
+	if dir == "" {
+		s.streamBackup(w, r, file)
+		return
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		logger.WithError(err).WithField("dir", dir).Error("Failed to create backup directory")
+		s.writeError(w, http.StatusInternalServerError, "Failed to create backup directory")
+		return
+	}
+	path := filepath.Join(dir, file)
+	if err := s.store.Backup(r.Context(), path); err != nil {
+		if errors.Is(err, storage.ErrBackupExists) {
+			s.writeError(w, http.StatusConflict, "A backup with the same name already exists, retry")
+			return
+		}
+		logger.WithError(err).Error("Failed to back up database")
+		s.writeError(w, http.StatusInternalServerError, "Failed to back up database")
+		return
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		logger.WithError(err).Error("Failed to stat backup")
+		s.writeError(w, http.StatusInternalServerError, "Failed to back up database")
+		return
+	}
+
+	logger.WithFields(logrus.Fields{
+		"path":       path,
+		"size_bytes": info.Size(),
+	}).Info("Database backed up via HTTP API")
+	s.writeJSON(w, http.StatusCreated, BackupResponse{
+		File:      file,
+		Path:      path,
+		SizeBytes: info.Size(),
+		CreatedAt: createdAt,
+	})
+}
+
+// streamBackup backs up to a temporary file and sends it as an attachment
+// named file
+func (s *SimpleServer) streamBackup(w http.ResponseWriter, r *http.Request, file string) {
+	logger := s.requestLogger(r.Context())
+
+	dir, err := os.MkdirTemp("", "prompt-alchemy-backup-")
+	if err != nil {
+		logger.WithError(err).Error("Failed to create temporary backup directory")
+		s.writeError(w, http.StatusInternalServerError, "Failed to back up database")
+		return
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	path := filepath.Join(dir, file)
+	if err := s.store.Backup(r.Context(), path); err != nil {
+		logger.WithError(err).Error("Failed to back up database")
+		s.writeError(w, http.StatusInternalServerError, "Failed to back up database")
+		return
+	}
+	backup, err := os.Open(path)
+	if err != nil {
+		logger.WithError(err).Error("Failed to open backup")
+		s.writeError(w, http.StatusInternalServerError, "Failed to back up database")
+		return
+	}
+	defer func() { _ = backup.Close() }()
+	info, err := backup.Stat()
+	if err != nil {
+		logger.WithError(err).Error("Failed to stat backup")
+		s.writeError(w, http.StatusInternalServerError, "Failed to back up database")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/vnd.sqlite3")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", file))
+	w.Header().Set("Content-Length", fmt.Sprint(info.Size()))
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, backup); err != nil {
+		logger.WithError(err).Warn("Failed to stream backup")
+		return
+	}
+	logger.WithField("size_bytes", info.Size()).Info("Database backup streamed via HTTP API")
+}
+
+// handleRestore replaces the database with a backup from
+// storage.backup_dir, after checking it is an intact prompt-alchemy
+// database. It is refused while generations are in flight, and generations
+// are refused until it finishes. It requires API key authentication.
+func (s *SimpleServer) handleRestore(w http.ResponseWriter, r *http.Request) {
+	logger := s.requestLogger(r.Context())
+
+	if !s.config.EnableAuth {
+		s.writeError(w, http.StatusForbidden, "Restores require API key authentication (http.enable_auth)")
+		return
+	}
+	dir := viper.GetString("storage.backup_dir")
+	if dir == "" {
+		s.writeError(w, http.StatusConflict, "Restores read backups from storage.backup_dir, which is not set")
+		return
+	}
+
+	var req RestoreRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid JSON in request body")
+		return
+	}
+	// Only backups directly inside the backup directory can be restored
+	if req.File == "" || filepath.Base(req.File) != req.File || req.File == "." || req.File == ".." {
+		s.writeValidationError(w, "Invalid restore request", map[string]string{
+			"file": "file must name a backup in storage.backup_dir",
+		})
+		return
+	}
+	path := filepath.Join(dir, req.File)
+	if _, err := os.Stat(path); err != nil {
+		s.writeError(w, http.StatusNotFound, "Backup not found")
+		return
+	}
+
+	if !s.generations.mu.TryLock() {
+		s.writeError(w, http.StatusConflict, "Generations are in progress, retry the restore once they finish")
+		return
+	}
+	defer s.generations.mu.Unlock()
+
+	count, err := s.store.Restore(r.Context(), path)
+	switch {
+	case errors.Is(err, storage.ErrInvalidBackup):
+		logger.WithError(err).WithField("path", path).Warn("Rejected invalid backup")
+		s.writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	case errors.Is(err, storage.ErrReindexRunning), errors.Is(err, storage.ErrBackfillRunning):
+		s.writeError(w, http.StatusConflict, err.Error()+", retry the restore once it finishes")
+		return
+	case err != nil:
+		logger.WithError(err).WithField("path", path).Error("Failed to restore database")
+		s.writeError(w, http.StatusInternalServerError, "Failed to restore database")
+		return
+	}
+
+	logger.WithFields(logrus.Fields{
+		"path":    path,
+		"prompts": count,
+	}).Warn("Database restored via HTTP API")
+	s.writeJSON(w, http.StatusOK, RestoreResponse{
+		File:       req.File,
+		Prompts:    count,
+		RestoredAt: time.Now().UTC(),
+	})
+}
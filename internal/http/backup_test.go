@@ -0,0 +1,119 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupAndRestore(t *testing.T) {
+	defer viper.Reset()
+	viper.Set("http.enable_auth", true)
+	viper.Set("http.api_keys", []string{"write-key"})
+	backupDir := t.TempDir()
+	viper.Set("storage.backup_dir", backupDir)
+	server, store := newTestServer(t)
+
+	ctx := context.Background()
+	original := &models.Prompt{Content: "In the backup", Phase: models.PhasePrimaMaterial}
+	require.NoError(t, store.SavePrompt(ctx, original))
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-API-Key", "write-key")
+		recorder := httptest.NewRecorder()
+		server.Router().ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	recorder := post("/api/v1/admin/backup", "")
+	require.Equal(t, http.StatusCreated, recorder.Code, recorder.Body.String())
+	var backup BackupResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &backup))
+	assert.Equal(t, filepath.Join(backupDir, backup.File), backup.Path)
+	assert.Positive(t, backup.SizeBytes)
+	assert.FileExists(t, backup.Path)
+
+	// Change the database after the snapshot
+	added := &models.Prompt{Content: "Not in the backup", Phase: models.PhaseSolutio}
+	require.NoError(t, store.SavePrompt(ctx, added))
+	require.NoError(t, store.DeletePrompt(ctx, original.ID.String()))
+
+	t.Run("rejects files outside the backup directory", func(t *testing.T) {
+		for _, file := range []string{"", "../prompts.db", "sub/backup.db", ".."} {
+			recorder := post("/api/v1/admin/restore", `{"file":"`+file+`"}`)
+			assert.Equal(t, http.StatusBadRequest, recorder.Code, file)
+		}
+		assert.Equal(t, http.StatusNotFound, post("/api/v1/admin/restore", `{"file":"missing.db"}`).Code)
+	})
+
+	t.Run("rejects invalid backups", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filepath.Join(backupDir, "garbage.db"), []byte("not a database"), 0o600))
+		recorder := post("/api/v1/admin/restore", `{"file":"garbage.db"}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code, recorder.Body.String())
+	})
+
+	t.Run("waits for generations in flight", func(t *testing.T) {
+		server.generations.mu.RLock()
+		recorder := post("/api/v1/admin/restore", `{"file":"`+backup.File+`"}`)
+		server.generations.mu.RUnlock()
+		assert.Equal(t, http.StatusConflict, recorder.Code, recorder.Body.String())
+		_, err := store.GetPrompt(ctx, added.ID.String())
+		assert.NoError(t, err, "nothing was restored")
+	})
+
+	t.Run("refuses generations during a restore", func(t *testing.T) {
+		server.generations.mu.Lock()
+		recorder := post("/api/v1/prompts/generate", `{"input":"hello"}`)
+		server.generations.mu.Unlock()
+		assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+		assert.NotEmpty(t, recorder.Header().Get("Retry-After"))
+	})
+
+	recorder = post("/api/v1/admin/restore", `{"file":"`+backup.File+`"}`)
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	var restored RestoreResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &restored))
+	assert.Equal(t, 1, restored.Prompts)
+
+	prompt, err := store.GetPrompt(ctx, original.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, original.Content, prompt.Content)
+	_, err = store.GetPrompt(ctx, added.ID.String())
+	assert.Error(t, err, "prompts saved after the backup are gone")
+
+	t.Run("streams the backup without a backup directory", func(t *testing.T) {
+		viper.Set("storage.backup_dir", "")
+		recorder := post("/api/v1/admin/backup", "")
+		require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+		assert.Equal(t, "application/vnd.sqlite3", recorder.Header().Get("Content-Type"))
+		assert.Contains(t, recorder.Header().Get("Content-Disposition"), "attachment")
+		assert.True(t, strings.HasPrefix(recorder.Body.String(), "SQLite format 3\x00"))
+
+		assert.Equal(t, http.StatusConflict, post("/api/v1/admin/restore", `{"file":"`+backup.File+`"}`).Code)
+	})
+}
+
+func TestBackup_RequiresAuth(t *testing.T) {
+	defer viper.Reset()
+	viper.Set("storage.backup_dir", t.TempDir())
+	server, _ := newTestServer(t)
+	for _, path := range []string{"/api/v1/admin/backup", "/api/v1/admin/restore"} {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"file":"backup.db"}`))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		server.Router().ServeHTTP(recorder, req)
+		assert.Equal(t, http.StatusForbidden, recorder.Code, path)
+	}
+}
@@ -0,0 +1,170 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+	"github.com/jonwraymond/prompt-alchemy/internal/engine"
+	"github.com/jonwraymond/prompt-alchemy/internal/helpers"
+	"github.com/jonwraymond/prompt-alchemy/internal/log"
+	"github.com/jonwraymond/prompt-alchemy/internal/observability/tracing"
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// Batch limits. Workers above maxBatchWorkers are capped rather than
+// rejected; each job gets DefaultBatchJobTimeout unless
+// generation.batch_job_timeout is set.
+const (
+	defaultBatchWorkers    = 3
+	maxBatchWorkers        = 10
+	maxBatchInputs         = 100
+	DefaultBatchJobTimeout = 2 * time.Minute
+)
+
+// BatchGenerateRequest is the body of POST /api/v1/prompts/batch. Save and
+// SavePhases apply to every input, as they do for a single generation.
+type BatchGenerateRequest struct {
+	Inputs     []models.BatchInput `json:"inputs"`
+	Workers    int                 `json:"workers,omitempty"`
+	SkipErrors bool                `json:"skip_errors,omitempty"`
+	Save       bool                `json:"save,omitempty"`
+	SavePhases []string            `json:"save_phases,omitempty"`
+}
+
+// BatchGenerateResponse holds one result per input, in input order
+type BatchGenerateResponse struct {
+	Results []models.BatchResult `json:"results"`
+	Summary models.BatchSummary  `json:"summary"`
+}
+
+// handleBatchGenerate runs several generate requests on a bounded worker
+// pool. Every input is validated before any job runs. Failed jobs are
+// reported in their result rather than failing the request; without
+// skip_errors, jobs not yet started when one fails are skipped.
+func (s *SimpleServer) handleBatchGenerate(w http.ResponseWriter, r *http.Request) {
+	var req BatchGenerateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+	if len(req.Inputs) == 0 {
+		s.writeError(w, http.StatusBadRequest, "At least one input is required")
+		return
+	}
+	if len(req.Inputs) > maxBatchInputs {
+		s.writeError(w, http.StatusBadRequest, fmt.Sprintf("A batch may have at most %d inputs, got %d", maxBatchInputs, len(req.Inputs)))
+		return
+	}
+	fields := models.FieldErrors{}
+	for i := range req.Inputs {
+		input := &req.Inputs[i]
+		if input.ID == "" {
+			input.ID = fmt.Sprintf("%d", i+1)
+		}
+		var inputFields models.FieldErrors
+		if err := s.validateBatchInput(*input); errors.As(err, &inputFields) {
+			for field, problem := range inputFields {
+				fields[fmt.Sprintf("inputs[%d].%s", i, field)] = problem
+			}
+		}
+	}
+	if len(fields) > 0 {
+		s.writeValidationError(w, fields.Error(), fields)
+		return
+	}
+	// Save defaults to true, as for a single generation
+	if !r.URL.Query().Has("save") {
+		req.Save = true
+	}
+	if req.SavePhases == nil {
+		req.SavePhases = viper.GetStringSlice("generation.save_phases")
+	}
+	if err := models.ValidateSavePhases(req.SavePhases); err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	workers := req.Workers
+	if workers <= 0 {
+		workers = defaultBatchWorkers
+	}
+	if workers > maxBatchWorkers {
+		workers = maxBatchWorkers
+	}
+	timeout := viper.GetDuration("generation.batch_job_timeout")
+	if timeout <= 0 {
+		timeout = DefaultBatchJobTimeout
+	}
+
+	// Like single generations, jobs are bounded by their own timeout rather
+	// than the router's request timeout
+	ctx := tracing.WithSpanFrom(log.WithRequestID(context.Background(), middleware.GetReqID(r.Context())), r.Context())
+	startTime := time.Now()
+	results := s.engine.GenerateBatch(ctx, req.Inputs, engine.BatchOptions{
+		Workers:    workers,
+		SkipErrors: req.SkipErrors,
+		JobTimeout: timeout,
+	})
+	if req.Save {
+		s.saveBatchResults(ctx, results, req.SavePhases)
+	}
+	summary := models.SummarizeBatch(results, startTime)
+
+	s.requestLogger(r.Context()).WithFields(logrus.Fields{
+		"inputs":     summary.TotalInputs,
+		"successful": summary.SuccessfulJobs,
+		"failed":     summary.FailedJobs,
+		"workers":    workers,
+		"duration":   summary.TotalDuration,
+	}).Info("Batch generation completed")
+
+	s.writeJSON(w, http.StatusOK, BatchGenerateResponse{Results: results, Summary: summary})
+}
+
+// validateBatchInput checks a batch input with models.ValidateGenerateRequest,
+// as handleGeneratePrompts checks a single request
+func (s *SimpleServer) validateBatchInput(input models.BatchInput) error {
+	var phases []string
+	for _, phase := range helpers.ParsePhases(input.Phases) {
+		phases = append(phases, string(phase))
+	}
+	return models.ValidateGenerateRequest(models.GenerateRequest{
+		Input:       strings.TrimSpace(input.Input),
+		Phases:      phases,
+		Count:       input.Count,
+		Temperature: input.Temperature,
+		MaxTokens:   input.MaxTokens,
+		Persona:     input.Persona,
+	}, s.engine.KnownPhase)
+}
+
+// saveBatchResults saves the prompts of each successful job, limited to
+// savePhases, with each job's prompts grouped in a session of their own
+func (s *SimpleServer) saveBatchResults(ctx context.Context, results []models.BatchResult, savePhases []string) {
+	for i := range results {
+		if !results[i].Success {
+			continue
+		}
+		sessionID := uuid.New()
+		for j := range results[i].Prompts {
+			results[i].Prompts[j].SessionID = sessionID
+		}
+		for _, prompt := range models.PromptsToSave(results[i].Prompts, nil, savePhases) {
+			if err := s.store.SavePrompt(ctx, prompt); err != nil {
+				s.logger.WithError(err).WithFields(logrus.Fields{
+					"batch_id":  results[i].ID,
+					"prompt_id": prompt.ID,
+				}).Error("Failed to save prompt")
+			}
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jonwraymond/prompt-alchemy/pkg/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// batchProvider fails every generation whose prompt mentions FAIL and
+// numbers the others
+type batchProvider struct {
+	pingProvider
+	generated atomic.Int64
+}
+
+func (p *batchProvider) Generate(ctx context.Context, req providers.GenerateRequest) (*providers.GenerateResponse, error) {
+	if strings.Contains(req.Prompt, "FAIL") {
+		return nil, errors.New("scripted failure")
+	}
+	return &providers.GenerateResponse{Content: fmt.Sprintf("generated %d", p.generated.Add(1)), Model: "batch-model"}, nil
+}
+
+func TestHandleBatchGenerate(t *testing.T) {
+	server, store := newTestServer(t)
+	require.NoError(t, server.registry.Register("batch", &batchProvider{pingProvider: pingProvider{name: "batch", available: true}}))
+
+	sendTo := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		server.Router().ServeHTTP(recorder, req)
+		return recorder
+	}
+	send := func(body string) *httptest.ResponseRecorder {
+		return sendTo("/api/v1/prompts/batch", body)
+	}
+	stored := func() int {
+		count, err := store.GetPromptsCount(context.Background())
+		require.NoError(t, err)
+		return count
+	}
+	decode := func(recorder *httptest.ResponseRecorder) BatchGenerateResponse {
+		require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+		var response BatchGenerateResponse
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		return response
+	}
+
+	t.Run("partial failure with skip_errors", func(t *testing.T) {
+		response := decode(send(`{"skip_errors":true,"workers":2,"inputs":[
+			{"id":"a","input":"summarize logs","phases":"prima-materia","count":1,"provider":"batch"},
+			{"id":"b","input":"FAIL here","phases":"prima-materia","count":1,"provider":"batch"},
+			{"id":"c","input":"draft an email","phases":"prima-materia","count":2,"provider":"batch"}
+		]}`))
+
+		require.Len(t, response.Results, 3)
+		assert.Equal(t, "a", response.Results[0].ID, "results keep input order")
+		assert.True(t, response.Results[0].Success)
+		assert.Len(t, response.Results[0].Prompts, 1)
+		assert.False(t, response.Results[1].Success)
+		assert.Contains(t, response.Results[1].Error, "scripted failure")
+		assert.True(t, response.Results[2].Success)
+		assert.Len(t, response.Results[2].Prompts, 2)
+
+		assert.Equal(t, 3, response.Summary.TotalInputs)
+		assert.Equal(t, 2, response.Summary.SuccessfulJobs)
+		assert.Equal(t, 1, response.Summary.FailedJobs)
+		assert.Equal(t, 3, response.Summary.TotalPrompts)
+
+		assert.Equal(t, 3, stored(), "prompts of successful jobs are saved")
+		assert.Equal(t, response.Results[2].Prompts[0].SessionID, response.Results[2].Prompts[1].SessionID)
+		assert.NotEqual(t, response.Results[0].Prompts[0].SessionID, response.Results[2].Prompts[0].SessionID)
+	})
+
+	t.Run("save=false keeps the prompts out of storage", func(t *testing.T) {
+		before := stored()
+		recorder := sendTo("/api/v1/prompts/batch?save=false", `{"inputs":[{"input":"write a limerick","phases":"prima-materia","count":1,"provider":"batch"}]}`)
+		response := decode(recorder)
+		require.True(t, response.Results[0].Success)
+		assert.Equal(t, before, stored())
+	})
+
+	t.Run("failure stops remaining jobs without skip_errors", func(t *testing.T) {
+		response := decode(send(`{"workers":1,"inputs":[
+			{"input":"FAIL first","phases":"prima-materia","count":1,"provider":"batch"},
+			{"input":"draft an email","phases":"prima-materia","count":1,"provider":"batch"}
+		]}`))
+
+		require.Len(t, response.Results, 2)
+		assert.Equal(t, "1", response.Results[0].ID, "inputs without an ID are numbered")
+		assert.False(t, response.Results[0].Success)
+		assert.False(t, response.Results[1].Success)
+		assert.Contains(t, response.Results[1].Error, "not run")
+	})
+
+	t.Run("invalid batches", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, send(`{"inputs":[]}`).Code)
+		assert.Equal(t, http.StatusBadRequest, send(`{"inputs":[{"input":"  "}]}`).Code)
+		assert.Equal(t, http.StatusBadRequest, send(`{"inputs":`).Code)
+		assert.Equal(t, http.StatusBadRequest, send(`{"inputs":[{"input":"x"}],"save_phases":["drafts"]}`).Code)
+
+		for field, input := range map[string]string{
+			"count":       `{"input":"x","count":-1}`,
+			"temperature": `{"input":"x","temperature":3}`,
+			"max_tokens":  `{"input":"x","max_tokens":-5}`,
+			"phases":      `{"input":"x","phases":"brainstorm"}`,
+		} {
+			recorder := send(`{"inputs":[{"id":"ok","input":"fine"},{"id":"bad",` + input[1:] + `]}`)
+			assert.Equal(t, http.StatusBadRequest, recorder.Code, field)
+			var response struct {
+				Fields map[string]string `json:"fields"`
+			}
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+			assert.Contains(t, response.Fields, "inputs[1]."+field, field)
+			assert.Len(t, response.Fields, 1, field)
+		}
+	})
+}
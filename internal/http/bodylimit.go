@@ -0,0 +1,56 @@
+package http
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// DefaultMaxBodyBytes bounds request bodies when http.max_body_bytes is unset
+const DefaultMaxBodyBytes = 1 << 20
+
+// bodyLimitExemptPaths stream their bodies and bound each line instead
+var bodyLimitExemptPaths = map[string]bool{
+	"/api/v1/prompts/import": true,
+}
+
+// LimitBody rejects request bodies larger than maxBytes, or
+// DefaultMaxBodyBytes when it isn't positive, with 413 Request Entity Too
+// Large before a handler decodes them. A declared Content-Length over the
+// limit is rejected without reading the body; other bodies are read up to
+// the limit, so a chunked upload can't exceed it either. writeError writes
+// the rejection.
+func LimitBody(maxBytes int64, writeError func(w http.ResponseWriter, status int, message string)) func(next http.Handler) http.Handler {
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBodyBytes
+	}
+	message := fmt.Sprintf("Request body is larger than the %d bytes allowed", maxBytes)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Body == nil || r.Body == http.NoBody || bodyLimitExemptPaths[r.URL.Path] {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if r.ContentLength > maxBytes {
+				writeError(w, http.StatusRequestEntityTooLarge, message)
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBytes))
+			var tooLarge *http.MaxBytesError
+			switch {
+			case errors.As(err, &tooLarge):
+				writeError(w, http.StatusRequestEntityTooLarge, message)
+				return
+			case err != nil:
+				writeError(w, http.StatusBadRequest, "Failed to read request body")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
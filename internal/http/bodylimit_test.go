@@ -0,0 +1,62 @@
+package http
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestLimits(t *testing.T) {
+	defer viper.Reset()
+	viper.Set("http.max_body_bytes", 1024)
+	server, _ := newTestServer(t)
+
+	post := func(body io.Reader) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/prompts/generate", body)
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		server.Router().ServeHTTP(recorder, req)
+		return recorder
+	}
+	oversized := `{"input":"` + strings.Repeat("a", 2048) + `"}`
+
+	t.Run("oversized body", func(t *testing.T) {
+		recorder := post(strings.NewReader(oversized))
+		assert.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "larger than the 1024 bytes allowed")
+	})
+
+	t.Run("oversized body without a length", func(t *testing.T) {
+		// Hiding the reader's type leaves the request's ContentLength unknown
+		recorder := post(struct{ io.Reader }{strings.NewReader(oversized)})
+		assert.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+	})
+
+	t.Run("count over the limit", func(t *testing.T) {
+		recorder := post(strings.NewReader(`{"input":"Write a CLI","count":1000}`))
+		require.Equal(t, http.StatusBadRequest, recorder.Code)
+		var response struct {
+			Fields map[string]string `json:"fields"`
+		}
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		assert.Contains(t, response.Fields, "count")
+	})
+
+	t.Run("too much context", func(t *testing.T) {
+		entries := make([]string, models.MaxGenerateContext+1)
+		for i := range entries {
+			entries[i] = `"c"`
+		}
+		recorder := post(strings.NewReader(`{"input":"Write a CLI","context":[` + strings.Join(entries, ",") + `]}`))
+		require.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"context"`)
+	})
+}
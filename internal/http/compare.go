@@ -0,0 +1,279 @@
+package http
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jonwraymond/prompt-alchemy/internal/storage"
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+)
+
+// maxDiffCells bounds the LCS table of a content diff. Content too long to
+// diff within it is reported as one deletion and one insertion.
+const maxDiffCells = 4_000_000
+
+// Diff operations
+const (
+	diffEqual  = "equal"
+	diffInsert = "insert"
+	diffDelete = "delete"
+)
+
+// DiffOp is a run of lines or words that both prompts share (equal), only
+// b has (insert) or only a has (delete)
+type DiffOp struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// ContentDiff turns a's content into b's, line by line and word by word
+type ContentDiff struct {
+	Lines        []DiffOp `json:"lines"`
+	Words        []DiffOp `json:"words"`
+	LinesAdded   int      `json:"lines_added"`
+	LinesRemoved int      `json:"lines_removed"`
+	WordsAdded   int      `json:"words_added"`
+	WordsRemoved int      `json:"words_removed"`
+}
+
+// TagDiff splits the two prompts' tags by which of them has each
+type TagDiff struct {
+	Common []string `json:"common"`
+	OnlyA  []string `json:"only_a"`
+	OnlyB  []string `json:"only_b"`
+}
+
+// ValueComparison is one number of both prompts and how much b changes it
+type ValueComparison struct {
+	A     float64 `json:"a"`
+	B     float64 `json:"b"`
+	Delta float64 `json:"delta"`
+}
+
+// PromptComparison is a side by side comparison of two prompts. Token
+// counts from the model metadata are included when both prompts have it.
+// Similarity is the cosine similarity of their embeddings, omitted unless
+// both have an embedding of the same size.
+type PromptComparison struct {
+	A          *models.Prompt             `json:"a"`
+	B          *models.Prompt             `json:"b"`
+	Identical  bool                       `json:"identical"`
+	Content    ContentDiff                `json:"content"`
+	Tags       TagDiff                    `json:"tags"`
+	Scores     map[string]ValueComparison `json:"scores"`
+	Tokens     map[string]ValueComparison `json:"tokens"`
+	Similarity *float64                   `json:"similarity,omitempty"`
+}
+
+// handleComparePrompts compares the prompts ?a= and ?b=, e.g. an original
+// and its optimized version
+func (s *SimpleServer) handleComparePrompts(w http.ResponseWriter, r *http.Request) {
+	logger := s.requestLogger(r.Context())
+	query := r.URL.Query()
+	idA, errA := uuid.Parse(query.Get("a"))
+	idB, errB := uuid.Parse(query.Get("b"))
+	if errA != nil || errB != nil {
+		s.writeError(w, http.StatusBadRequest, "Query parameters 'a' and 'b' must be prompt IDs")
+		return
+	}
+
+	loaded := make([]*models.Prompt, 2)
+	feedback := make([]*models.FeedbackSummary, 2)
+	embeddings := make([][]float32, 2)
+	for i, id := range []uuid.UUID{idA, idB} {
+		prompt, err := s.store.GetPromptByID(r.Context(), id)
+		if errors.Is(err, storage.ErrPromptNotFound) {
+			s.writeError(w, http.StatusNotFound, "Prompt not found: "+id.String())
+			return
+		}
+		if err == nil {
+			err = s.store.LoadPromptDetails(r.Context(), prompt)
+		}
+		if err == nil {
+			feedback[i], err = s.store.GetPromptFeedbackSummary(r.Context(), id)
+		}
+		if err == nil {
+			embeddings[i], err = s.store.GetPromptEmbedding(r.Context(), id)
+		}
+		if err != nil {
+			logger.WithError(err).WithField("prompt_id", id).Error("Failed to load prompt for comparison")
+			s.writeError(w, http.StatusInternalServerError, "Failed to compare prompts")
+			return
+		}
+		loaded[i] = prompt
+	}
+	a, b := loaded[0], loaded[1]
+
+	comparison := PromptComparison{
+		A:       a,
+		B:       b,
+		Content: diffContent(a.Content, b.Content),
+		Tags:    diffTags(a.Tags, b.Tags),
+		Scores: map[string]ValueComparison{
+			"relevance": compareValues(a.RelevanceScore, b.RelevanceScore),
+			"feedback":  compareValues(feedback[0].AverageOutcome, feedback[1].AverageOutcome),
+		},
+		Tokens: map[string]ValueComparison{
+			"actual": compareValues(float64(a.ActualTokens), float64(b.ActualTokens)),
+		},
+	}
+	if a.ModelMetadata != nil && b.ModelMetadata != nil {
+		comparison.Tokens["input"] = compareValues(float64(a.ModelMetadata.InputTokens), float64(b.ModelMetadata.InputTokens))
+		comparison.Tokens["output"] = compareValues(float64(a.ModelMetadata.OutputTokens), float64(b.ModelMetadata.OutputTokens))
+		comparison.Tokens["total"] = compareValues(float64(a.ModelMetadata.TotalTokens), float64(b.ModelMetadata.TotalTokens))
+	}
+	comparison.Identical = a.Content == b.Content && len(comparison.Tags.OnlyA) == 0 && len(comparison.Tags.OnlyB) == 0
+	if similarity, ok := embeddingSimilarity(embeddings[0], embeddings[1]); ok {
+		comparison.Similarity = &similarity
+	}
+
+	s.writeJSON(w, http.StatusOK, comparison)
+}
+
+func compareValues(a, b float64) ValueComparison {
+	return ValueComparison{A: a, B: b, Delta: b - a}
+}
+
+// embeddingSimilarity returns the cosine similarity of two embeddings, or
+// false when either is missing or they come from different-sized models
+func embeddingSimilarity(a, b []float32) (float64, bool) {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0, false
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0, false
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB)), true
+}
+
+// diffTags sorts the tags of a and b by which prompt has them
+func diffTags(a, b []string) TagDiff {
+	inA := make(map[string]bool, len(a))
+	for _, tag := range a {
+		inA[tag] = true
+	}
+	inB := make(map[string]bool, len(b))
+	for _, tag := range b {
+		inB[tag] = true
+	}
+
+	diff := TagDiff{Common: []string{}, OnlyA: []string{}, OnlyB: []string{}}
+	for tag := range inA {
+		if inB[tag] {
+			diff.Common = append(diff.Common, tag)
+		} else {
+			diff.OnlyA = append(diff.OnlyA, tag)
+		}
+	}
+	for tag := range inB {
+		if !inA[tag] {
+			diff.OnlyB = append(diff.OnlyB, tag)
+		}
+	}
+	sort.Strings(diff.Common)
+	sort.Strings(diff.OnlyA)
+	sort.Strings(diff.OnlyB)
+	return diff
+}
+
+// diffContent diffs two prompts' content by line and by word
+func diffContent(a, b string) ContentDiff {
+	var diff ContentDiff
+	diff.Lines, diff.LinesAdded, diff.LinesRemoved = diffTokens(strings.Split(a, "\n"), strings.Split(b, "\n"), "\n")
+	diff.Words, diff.WordsAdded, diff.WordsRemoved = diffTokens(strings.Fields(a), strings.Fields(b), " ")
+	return diff
+}
+
+// diffTokens diffs two token sequences by their longest common subsequence,
+// merging runs of the same operation joined by sep. It also returns the
+// number of tokens inserted and deleted.
+func diffTokens(a, b []string, sep string) (ops []DiffOp, added, removed int) {
+	ops = []DiffOp{}
+	var run []string
+	runOp := ""
+	emit := func(op, token string) {
+		if op != runOp && len(run) > 0 {
+			ops = append(ops, DiffOp{Op: runOp, Text: strings.Join(run, sep)})
+			run = run[:0]
+		}
+		runOp = op
+		run = append(run, token)
+		switch op {
+		case diffInsert:
+			added++
+		case diffDelete:
+			removed++
+		}
+	}
+
+	// Only the differing middle needs the LCS table
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	for _, token := range a[:prefix] {
+		emit(diffEqual, token)
+	}
+
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if (len(midA)+1)*(len(midB)+1) > maxDiffCells {
+		for _, token := range midA {
+			emit(diffDelete, token)
+		}
+		for _, token := range midB {
+			emit(diffInsert, token)
+		}
+	} else {
+		// lcs[i][j] is the LCS length of midA[i:] and midB[j:]
+		lcs := make([][]int, len(midA)+1)
+		for i := range lcs {
+			lcs[i] = make([]int, len(midB)+1)
+		}
+		for i := len(midA) - 1; i >= 0; i-- {
+			for j := len(midB) - 1; j >= 0; j-- {
+				if midA[i] == midB[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else {
+					lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+				}
+			}
+		}
+		i, j := 0, 0
+		for i < len(midA) || j < len(midB) {
+			switch {
+			case i < len(midA) && j < len(midB) && midA[i] == midB[j]:
+				emit(diffEqual, midA[i])
+				i++
+				j++
+			case i < len(midA) && (j == len(midB) || lcs[i+1][j] >= lcs[i][j+1]):
+				emit(diffDelete, midA[i])
+				i++
+			default:
+				emit(diffInsert, midB[j])
+				j++
+			}
+		}
+	}
+
+	for _, token := range a[len(a)-suffix:] {
+		emit(diffEqual, token)
+	}
+	if len(run) > 0 {
+		ops = append(ops, DiffOp{Op: runOp, Text: strings.Join(run, sep)})
+	}
+	return ops, added, removed
+}
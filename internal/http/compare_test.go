@@ -0,0 +1,111 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleComparePrompts(t *testing.T) {
+	server, store := newTestServer(t)
+
+	compare := func(a, b string) (int, PromptComparison) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/prompts/compare?a="+a+"&b="+b, nil)
+		recorder := httptest.NewRecorder()
+		server.Router().ServeHTTP(recorder, req)
+		var response PromptComparison
+		if recorder.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		}
+		return recorder.Code, response
+	}
+
+	ctx := context.Background()
+	save := func(content string, tags []string, embedding []float32) string {
+		p := &models.Prompt{Content: content, Tags: tags, Phase: models.PhaseSolutio, Provider: "openai", Embedding: embedding}
+		if embedding != nil {
+			p.EmbeddingProvider, p.EmbeddingModel = "embed", "embed-model"
+		}
+		require.NoError(t, store.SavePrompt(ctx, p))
+		return p.ID.String()
+	}
+
+	original := save("Write a parser.\nHandle quoted fields.", []string{"csv", "parser"}, []float32{1, 0, 0})
+	optimized := save("Write a streaming parser.\nHandle quoted fields.\nReport line numbers.", []string{"parser", "streaming"}, []float32{0.6, 0.8, 0})
+	unembedded := save("Write a parser.", nil, nil)
+
+	// Content is unique per prompt, so a prompt compared with itself stands
+	// in for an identical copy
+	t.Run("identical prompts", func(t *testing.T) {
+		status, response := compare(original, original)
+		require.Equal(t, http.StatusOK, status)
+		assert.True(t, response.Identical)
+		assert.Equal(t, []DiffOp{{Op: diffEqual, Text: "Write a parser.\nHandle quoted fields."}}, response.Content.Lines)
+		assert.Zero(t, response.Content.WordsAdded+response.Content.WordsRemoved)
+		assert.Equal(t, []string{"csv", "parser"}, response.Tags.Common)
+		require.NotNil(t, response.Similarity)
+		assert.InDelta(t, 1.0, *response.Similarity, 1e-6)
+	})
+
+	t.Run("content and tag differences", func(t *testing.T) {
+		status, response := compare(original, optimized)
+		require.Equal(t, http.StatusOK, status)
+		assert.False(t, response.Identical)
+		assert.Equal(t, original, response.A.ID.String())
+		assert.Equal(t, optimized, response.B.ID.String())
+
+		assert.Equal(t, []DiffOp{
+			{Op: diffDelete, Text: "Write a parser."},
+			{Op: diffInsert, Text: "Write a streaming parser."},
+			{Op: diffEqual, Text: "Handle quoted fields."},
+			{Op: diffInsert, Text: "Report line numbers."},
+		}, response.Content.Lines)
+		assert.Equal(t, 2, response.Content.LinesAdded)
+		assert.Equal(t, 1, response.Content.LinesRemoved)
+		assert.Equal(t, 4, response.Content.WordsAdded, "streaming, Report, line, numbers.")
+		assert.Equal(t, 0, response.Content.WordsRemoved)
+
+		assert.Equal(t, TagDiff{Common: []string{"parser"}, OnlyA: []string{"csv"}, OnlyB: []string{"streaming"}}, response.Tags)
+		require.NotNil(t, response.Similarity)
+		assert.InDelta(t, 0.6, *response.Similarity, 1e-6)
+		assert.Contains(t, response.Scores, "relevance")
+		assert.Contains(t, response.Tokens, "actual")
+	})
+
+	t.Run("similarity is omitted without both embeddings", func(t *testing.T) {
+		status, response := compare(original, unembedded)
+		require.Equal(t, http.StatusOK, status)
+		assert.Nil(t, response.Similarity)
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		status, _ := compare(original, "not-a-uuid")
+		assert.Equal(t, http.StatusBadRequest, status)
+		status, _ = compare(original, uuid.NewString())
+		assert.Equal(t, http.StatusNotFound, status)
+	})
+}
+
+func TestDiffTokens(t *testing.T) {
+	ops, added, removed := diffTokens([]string{"a", "b", "c", "d"}, []string{"a", "x", "c", "d", "e"}, " ")
+	assert.Equal(t, []DiffOp{
+		{Op: diffEqual, Text: "a"},
+		{Op: diffDelete, Text: "b"},
+		{Op: diffInsert, Text: "x"},
+		{Op: diffEqual, Text: "c d"},
+		{Op: diffInsert, Text: "e"},
+	}, ops)
+	assert.Equal(t, 2, added)
+	assert.Equal(t, 1, removed)
+
+	ops, added, removed = diffTokens(nil, nil, " ")
+	assert.Empty(t, ops)
+	assert.Zero(t, added+removed)
+}
@@ -0,0 +1,104 @@
+package http
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ConcurrencyLimiter bounds the number of requests a handler serves at once.
+// Requests beyond the limit wait up to queueTimeout for a free slot and are
+// then rejected with 429 Too Many Requests and a Retry-After header. It is
+// shared by every route it wraps, so all generation endpoints draw from the
+// same pool.
+type ConcurrencyLimiter struct {
+	slots        chan struct{}
+	queueTimeout time.Duration
+	logger       *logrus.Logger
+}
+
+// NewConcurrencyLimiter creates a limiter allowing maxInFlight concurrent
+// requests. A maxInFlight of zero or less disables limiting.
+func NewConcurrencyLimiter(maxInFlight int, queueTimeout time.Duration, logger *logrus.Logger) *ConcurrencyLimiter {
+	l := &ConcurrencyLimiter{
+		queueTimeout: queueTimeout,
+		logger:       logger,
+	}
+	if maxInFlight > 0 {
+		l.slots = make(chan struct{}, maxInFlight)
+	}
+	return l
+}
+
+// InFlight returns the number of requests currently holding a slot
+func (l *ConcurrencyLimiter) InFlight() int {
+	return len(l.slots)
+}
+
+// Middleware wraps next with the concurrency limit
+func (l *ConcurrencyLimiter) Middleware(next http.Handler) http.Handler {
+	if l.slots == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !l.acquire(r) {
+			if r.Context().Err() != nil {
+				return
+			}
+
+			l.logger.WithFields(logrus.Fields{
+				"remote_addr":  r.RemoteAddr,
+				"path":         r.URL.Path,
+				"max_inflight": cap(l.slots),
+			}).Warn("Concurrency limit reached, rejecting request")
+
+			w.Header().Set("Retry-After", l.retryAfter())
+			writeErrorResponse(w, errorResponse(w, &APIError{
+				Status:  http.StatusTooManyRequests,
+				Code:    ErrCodeRateLimited,
+				Message: "Server is busy, too many concurrent generations",
+			}))
+			return
+		}
+		defer func() { <-l.slots }()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (l *ConcurrencyLimiter) acquire(r *http.Request) bool {
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	default:
+	}
+
+	if l.queueTimeout <= 0 {
+		return false
+	}
+
+	timer := time.NewTimer(l.queueTimeout)
+	defer timer.Stop()
+
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-r.Context().Done():
+		return false
+	}
+}
+
+// retryAfter suggests waiting at least as long as a request may queue
+func (l *ConcurrencyLimiter) retryAfter() string {
+	seconds := int(math.Ceil(l.queueTimeout.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	return strconv.Itoa(seconds)
+}
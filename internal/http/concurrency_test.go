@@ -0,0 +1,92 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConcurrencyLimiter(t *testing.T) {
+	tests := []struct {
+		name         string
+		queueTimeout time.Duration
+		releaseAfter time.Duration
+		wantStatus   int
+		wantRetry    string
+	}{
+		{
+			name:       "rejects immediately without queue",
+			wantStatus: http.StatusTooManyRequests,
+			wantRetry:  "1",
+		},
+		{
+			name:         "rejects after queue timeout",
+			queueTimeout: 20 * time.Millisecond,
+			wantStatus:   http.StatusTooManyRequests,
+			wantRetry:    "1",
+		},
+		{
+			name:         "queued request runs when a slot frees",
+			queueTimeout: time.Second,
+			releaseAfter: 20 * time.Millisecond,
+			wantStatus:   http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := NewConcurrencyLimiter(1, tt.queueTimeout, logrus.New())
+
+			started := make(chan struct{})
+			release := make(chan struct{})
+			handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/slow" {
+					close(started)
+					<-release
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/slow", nil))
+			}()
+			<-started
+			assert.Equal(t, 1, limiter.InFlight())
+
+			if tt.releaseAfter > 0 {
+				time.AfterFunc(tt.releaseAfter, func() { close(release) })
+			}
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/fast", nil))
+
+			assert.Equal(t, tt.wantStatus, recorder.Code)
+			assert.Equal(t, tt.wantRetry, recorder.Header().Get("Retry-After"))
+
+			if tt.releaseAfter == 0 {
+				close(release)
+			}
+			wg.Wait()
+			assert.Equal(t, 0, limiter.InFlight())
+		})
+	}
+}
+
+func TestConcurrencyLimiterDisabled(t *testing.T) {
+	limiter := NewConcurrencyLimiter(0, 0, logrus.New())
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	handler := limiter.Middleware(next)
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+}
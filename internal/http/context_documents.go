@@ -0,0 +1,53 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/jonwraymond/prompt-alchemy/pkg/providers"
+	"github.com/sirupsen/logrus"
+)
+
+// ContextDocumentRequest is a reference document to ingest. Source is
+// free-form, such as the URL or file the content came from.
+type ContextDocumentRequest struct {
+	Title   string `json:"title,omitempty"`
+	Source  string `json:"source,omitempty"`
+	Content string `json:"content"`
+}
+
+// handleIngestContextDocument chunks a document, embeds the chunks and
+// stores them, so generate requests can name it in context_document_ids
+func (s *SimpleServer) handleIngestContextDocument(w http.ResponseWriter, r *http.Request) {
+	logger := s.requestLogger(r.Context())
+
+	var req ContextDocumentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+	if strings.TrimSpace(req.Content) == "" {
+		s.writeValidationError(w, "Invalid context document", map[string]string{"content": "content is required"})
+		return
+	}
+
+	embed := providers.NewQueryEmbedder(s.registry)
+	if embed == nil {
+		s.writeErrorCode(w, http.StatusServiceUnavailable, ErrCodeProviderUnavailable, "No embedding provider is available to embed the document")
+		return
+	}
+
+	doc, err := s.store.IngestContextDocument(r.Context(), req.Title, req.Source, req.Content, embed)
+	if err != nil {
+		logger.WithError(err).Error("Failed to ingest context document")
+		s.writeAPIError(w, apiErrorFrom(err, "Failed to ingest context document"))
+		return
+	}
+
+	logger.WithFields(logrus.Fields{
+		"document_id": doc.ID,
+		"chunks":      doc.Chunks,
+	}).Info("Ingested context document")
+	s.writeJSON(w, http.StatusCreated, doc)
+}
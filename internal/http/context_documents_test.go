@@ -0,0 +1,56 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleIngestContextDocument(t *testing.T) {
+	ingest := func(server *SimpleServer, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/context/documents", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		server.Router().ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	server, store := newTestServer(t)
+	embedder := &checkedProvider{pingProvider: pingProvider{name: "embedder", available: true}}
+	require.NoError(t, server.registry.Register(embedder.name, embedder))
+
+	t.Run("document is chunked and stored", func(t *testing.T) {
+		recorder := ingest(server, `{"title":"Runbook","source":"runbook.md","content":"Rate limits are enforced per API key."}`)
+		require.Equal(t, http.StatusCreated, recorder.Code, recorder.Body.String())
+
+		var doc models.ContextDocument
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &doc))
+		assert.Equal(t, "Runbook", doc.Title)
+		assert.Equal(t, 1, doc.Chunks)
+		assert.Equal(t, 3, doc.Dimensions)
+		assert.Equal(t, 1, embedder.embeds)
+
+		stored, err := store.GetContextDocument(context.Background(), doc.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "runbook.md", stored.Source)
+	})
+
+	t.Run("content is required", func(t *testing.T) {
+		recorder := ingest(server, `{"title":"Empty","content":"  "}`)
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "content")
+	})
+
+	t.Run("no embedding provider", func(t *testing.T) {
+		unembedded, _ := newTestServer(t)
+		recorder := ingest(unembedded, `{"content":"Rate limits are enforced per API key."}`)
+		assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	})
+}
@@ -0,0 +1,67 @@
+package http
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/go-chi/cors"
+	"github.com/jonwraymond/prompt-alchemy/internal/observability/tracing"
+	"github.com/sirupsen/logrus"
+)
+
+// corsOrigins returns the origins CORS may allow. Browsers reject a
+// wildcard origin on credentialed requests, and allowing credentials from
+// any origin would be unsafe anyway, so with credentials enabled the
+// wildcard is dropped with a warning.
+func corsOrigins(origins []string, allowCredentials bool, logger *logrus.Logger) []string {
+	if !allowCredentials || !slices.Contains(origins, "*") {
+		return origins
+	}
+	logger.Warn("CORS origins include \"*\" but credentials are allowed; ignoring the wildcard, list the allowed origins explicitly")
+	return slices.DeleteFunc(slices.Clone(origins), func(origin string) bool { return origin == "*" })
+}
+
+// newCORSHandler returns a CORS handler allowing origins. An empty list
+// allows no cross-origin requests, where the CORS package would allow all.
+func newCORSHandler(origins []string, allowCredentials bool) func(http.Handler) http.Handler {
+	options := cors.Options{
+		AllowedOrigins:   origins,
+		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Cache-Control", "Content-Type", "Idempotency-Key", "X-API-Key", "X-CSRF-Token"},
+		ExposedHeaders:   []string{"Link", tracing.TraceIDHeader},
+		AllowCredentials: allowCredentials,
+		MaxAge:           300,
+	}
+	if len(origins) == 0 {
+		options.AllowOriginFunc = func(*http.Request, string) bool { return false }
+	}
+	return cors.Handler(options)
+}
+
+// corsMiddleware applies CORSOrigins to reads and CORSWriteOrigins, when
+// set, to generation and other writes. Preflight requests are matched by
+// the method they ask for.
+func (s *SimpleServer) corsMiddleware() func(http.Handler) http.Handler {
+	readOrigins := corsOrigins(s.config.CORSOrigins, s.config.CORSAllowCredentials, s.logger)
+	writeOrigins := readOrigins
+	if len(s.config.CORSWriteOrigins) > 0 {
+		writeOrigins = corsOrigins(s.config.CORSWriteOrigins, s.config.CORSAllowCredentials, s.logger)
+	}
+	readCORS := newCORSHandler(readOrigins, s.config.CORSAllowCredentials)
+	writeCORS := newCORSHandler(writeOrigins, s.config.CORSAllowCredentials)
+
+	return func(next http.Handler) http.Handler {
+		read, write := readCORS(next), writeCORS(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			method := r.Method
+			if requested := r.Header.Get("Access-Control-Request-Method"); r.Method == http.MethodOptions && requested != "" {
+				method = requested
+			}
+			if isReadMethod(method) {
+				read.ServeHTTP(w, r)
+			} else {
+				write.ServeHTTP(w, r)
+			}
+		})
+	}
+}
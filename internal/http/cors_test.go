@@ -0,0 +1,110 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCORSOrigins(t *testing.T) {
+	newServer := func(t *testing.T, settings map[string]interface{}) (*SimpleServer, *test.Hook) {
+		t.Cleanup(viper.Reset)
+		for key, value := range settings {
+			viper.Set(key, value)
+		}
+		logger, hook := test.NewNullLogger()
+		server, _ := newTestServerWithLogger(t, logger)
+		return server, hook
+	}
+	warnings := func(hook *test.Hook) []string {
+		var messages []string
+		for _, entry := range hook.AllEntries() {
+			if entry.Level == logrus.WarnLevel {
+				messages = append(messages, entry.Message)
+			}
+		}
+		return messages
+	}
+	send := func(server *SimpleServer, method, path, origin, preflightMethod string) http.Header {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Origin", origin)
+		if preflightMethod != "" {
+			req.Header.Set("Access-Control-Request-Method", preflightMethod)
+		}
+		recorder := httptest.NewRecorder()
+		server.Router().ServeHTTP(recorder, req)
+		return recorder.Header()
+	}
+
+	t.Run("configured origins with a stricter write allowlist", func(t *testing.T) {
+		server, hook := newServer(t, map[string]interface{}{
+			"http.cors_origins":           []string{"https://app.example.com", "https://dashboard.example.com"},
+			"http.cors_write_origins":     []string{"https://app.example.com"},
+			"http.cors_allow_credentials": true,
+		})
+		assert.Empty(t, warnings(hook))
+
+		tests := []struct {
+			name            string
+			method          string
+			path            string
+			origin          string
+			preflightMethod string
+			wantOrigin      string
+		}{
+			{name: "read from app", method: http.MethodGet, path: "/health", origin: "https://app.example.com", wantOrigin: "https://app.example.com"},
+			{name: "read from dashboard", method: http.MethodGet, path: "/health", origin: "https://dashboard.example.com", wantOrigin: "https://dashboard.example.com"},
+			{name: "read from unknown origin", method: http.MethodGet, path: "/health", origin: "https://evil.example.com"},
+			{name: "generate preflight from app", method: http.MethodOptions, path: "/api/v1/generate", origin: "https://app.example.com", preflightMethod: http.MethodPost, wantOrigin: "https://app.example.com"},
+			{name: "generate preflight from dashboard", method: http.MethodOptions, path: "/api/v1/generate", origin: "https://dashboard.example.com", preflightMethod: http.MethodPost},
+			{name: "delete preflight from dashboard", method: http.MethodOptions, path: "/api/v1/prompts/some-id", origin: "https://dashboard.example.com", preflightMethod: http.MethodDelete},
+			{name: "read preflight from dashboard", method: http.MethodOptions, path: "/api/v1/prompts/", origin: "https://dashboard.example.com", preflightMethod: http.MethodGet, wantOrigin: "https://dashboard.example.com"},
+			{name: "write from dashboard", method: http.MethodPost, path: "/api/v1/generate", origin: "https://dashboard.example.com"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				header := send(server, tt.method, tt.path, tt.origin, tt.preflightMethod)
+				if tt.wantOrigin == "" {
+					assert.Empty(t, header.Values("Access-Control-Allow-Origin"))
+					return
+				}
+				assert.Equal(t, tt.wantOrigin, header.Get("Access-Control-Allow-Origin"))
+				assert.Equal(t, "true", header.Get("Access-Control-Allow-Credentials"))
+			})
+		}
+	})
+
+	t.Run("wildcard without credentials", func(t *testing.T) {
+		server, _ := newServer(t, nil)
+		header := send(server, http.MethodGet, "/health", "https://anywhere.example.com", "")
+		assert.Equal(t, "*", header.Get("Access-Control-Allow-Origin"))
+		assert.Empty(t, header.Get("Access-Control-Allow-Credentials"))
+	})
+
+	t.Run("wildcard with credentials is ignored", func(t *testing.T) {
+		server, hook := newServer(t, map[string]interface{}{
+			"http.cors_origins":           []string{"*", "https://app.example.com"},
+			"http.cors_allow_credentials": true,
+		})
+		if assert.Len(t, warnings(hook), 1) {
+			assert.Contains(t, warnings(hook)[0], "credentials")
+		}
+
+		assert.Empty(t, send(server, http.MethodGet, "/health", "https://anywhere.example.com", "").Values("Access-Control-Allow-Origin"))
+		assert.Equal(t, "https://app.example.com", send(server, http.MethodGet, "/health", "https://app.example.com", "").Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("only a wildcard with credentials allows no origins", func(t *testing.T) {
+		server, _ := newServer(t, map[string]interface{}{
+			"http.cors_origins":           []string{"*"},
+			"http.cors_allow_credentials": true,
+		})
+		header := send(server, http.MethodOptions, "/api/v1/generate", "https://anywhere.example.com", http.MethodPost)
+		assert.Empty(t, header.Values("Access-Control-Allow-Origin"))
+	})
+}
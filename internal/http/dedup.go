@@ -0,0 +1,62 @@
+package http
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jonwraymond/prompt-alchemy/internal/storage"
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/spf13/viper"
+)
+
+// savePrompts saves generated prompts, continuing past any that fail. With
+// generation.dedup on, prompts repeating a stored one are skipped and
+// returned instead: same content always, and with
+// generation.dedup_similarity above zero, embeddings at least that similar.
+// Prompts derived from a skipped one are relinked to the prompt it repeats.
+func (s *SimpleServer) savePrompts(ctx context.Context, prompts []*models.Prompt) []storage.Duplicate {
+	logger := s.requestLogger(ctx)
+	dedup := viper.GetBool("generation.dedup")
+	minSimilarity := viper.GetFloat64("generation.dedup_similarity")
+
+	var skipped []storage.Duplicate
+	replacedBy := make(map[uuid.UUID]uuid.UUID)
+	for _, prompt := range prompts {
+		if prompt.ParentID != nil {
+			if existing, ok := replacedBy[*prompt.ParentID]; ok {
+				prompt.ParentID = &existing
+			}
+		}
+		if dedup {
+			duplicate, err := s.store.FindDuplicate(ctx, prompt, minSimilarity)
+			if err != nil {
+				logger.WithError(err).WithField("prompt_id", prompt.ID).Warn("Failed to check for a duplicate prompt, saving it")
+			} else if duplicate != nil {
+				logger.WithField("prompt_id", prompt.ID).WithField("duplicate_of", duplicate.DuplicateOf).Debug("Skipping duplicate prompt")
+				skipped = append(skipped, *duplicate)
+				replacedBy[prompt.ID] = duplicate.DuplicateOf
+				continue
+			}
+		}
+		if err := s.store.SavePrompt(ctx, prompt); err != nil {
+			logger.WithError(err).WithField("prompt_id", prompt.ID).Error("Failed to save prompt")
+		}
+	}
+	return skipped
+}
+
+// unsavedPrompts returns the prompts that aren't stored yet
+func (s *SimpleServer) unsavedPrompts(ctx context.Context, prompts []*models.Prompt) []*models.Prompt {
+	var unsaved []*models.Prompt
+	for _, prompt := range prompts {
+		_, err := s.store.GetPromptByID(ctx, prompt.ID)
+		if errors.Is(err, storage.ErrPromptNotFound) {
+			unsaved = append(unsaved, prompt)
+		} else if err != nil {
+			s.requestLogger(ctx).WithError(err).WithField("prompt_id", prompt.ID).Warn("Failed to check whether a cached prompt is saved, saving it")
+			unsaved = append(unsaved, prompt)
+		}
+	}
+	return unsaved
+}
@@ -0,0 +1,80 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jonwraymond/prompt-alchemy/internal/storage"
+	"github.com/jonwraymond/prompt-alchemy/pkg/providers"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// numberingProvider numbers each generation, so no two are the same
+type numberingProvider struct {
+	pingProvider
+	calls atomic.Int32
+}
+
+func (p *numberingProvider) Generate(ctx context.Context, req providers.GenerateRequest) (*providers.GenerateResponse, error) {
+	return &providers.GenerateResponse{Content: fmt.Sprintf("generated %d", p.calls.Add(1)), Model: "numbering-model"}, nil
+}
+
+func TestHandleGeneratePromptsDedup(t *testing.T) {
+	defer viper.Reset()
+	viper.Set("generation.dedup", true)
+
+	server, store := newTestServer(t)
+	require.NoError(t, server.registry.Register("counting", &countingProvider{pingProvider: pingProvider{name: "counting", available: true}}))
+	require.NoError(t, server.registry.Register("numbering", &numberingProvider{pingProvider: pingProvider{name: "numbering", available: true}}))
+
+	generate := func(provider string) GenerateResponse {
+		body := `{"input":"write a haiku","phases":["prima-materia"],"count":2,"providers":{"prima-materia":"` + provider + `"}}`
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/prompts/generate", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		server.Router().ServeHTTP(recorder, req)
+		require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+
+		var response GenerateResponse
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		require.Len(t, response.Prompts, 2)
+		return response
+	}
+	ctx := context.Background()
+
+	t.Run("exact duplicates are not saved", func(t *testing.T) {
+		response := generate("counting")
+		first, second := response.Prompts[0], response.Prompts[1]
+
+		require.Len(t, response.Metadata.Deduplicated, 1)
+		duplicate := response.Metadata.Deduplicated[0]
+		assert.Equal(t, second.ID, duplicate.PromptID)
+		assert.Equal(t, first.ID, duplicate.DuplicateOf)
+		assert.Equal(t, storage.DuplicateExact, duplicate.Match)
+
+		_, err := store.GetPromptByID(ctx, first.ID)
+		assert.NoError(t, err)
+		_, err = store.GetPromptByID(ctx, second.ID)
+		assert.Error(t, err)
+
+		repeat := generate("counting")
+		assert.Len(t, repeat.Metadata.Deduplicated, 2, "both match the prompt saved before")
+	})
+
+	t.Run("distinct content is saved", func(t *testing.T) {
+		response := generate("numbering")
+		assert.Empty(t, response.Metadata.Deduplicated)
+		for _, prompt := range response.Prompts {
+			_, err := store.GetPromptByID(ctx, prompt.ID)
+			assert.NoError(t, err)
+		}
+	})
+}
@@ -0,0 +1,179 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/jonwraymond/prompt-alchemy/internal/engine"
+	"github.com/jonwraymond/prompt-alchemy/internal/log"
+	"github.com/jonwraymond/prompt-alchemy/internal/storage"
+	"github.com/jonwraymond/prompt-alchemy/pkg/providers"
+)
+
+// Error codes of the error envelope. Unlike the messages they are stable, so
+// clients should branch on them.
+const (
+	ErrCodeInvalidInput         = "invalid_input"
+	ErrCodeUnauthorized         = "unauthorized"
+	ErrCodeForbidden            = "forbidden"
+	ErrCodeNotFound             = "not_found"
+	ErrCodeConflict             = "conflict"
+	ErrCodePayloadTooLarge      = "payload_too_large"
+	ErrCodeUnsupportedMediaType = "unsupported_media_type"
+	ErrCodeUnprocessable        = "unprocessable"
+	ErrCodeContextTooLong       = "context_too_long"
+	ErrCodeRateLimited          = "rate_limited"
+	ErrCodeProviderUnavailable  = "provider_unavailable"
+	ErrCodeTimeout              = "timeout"
+	ErrCodeUnavailable          = "unavailable"
+	ErrCodeInternal             = "internal_error"
+)
+
+// APIError is an error answered with its HTTP status, code, message and
+// optional details
+type APIError struct {
+	Status  int
+	Code    string
+	Message string
+	Details map[string]interface{}
+}
+
+func (e *APIError) Error() string { return e.Message }
+
+// ErrorResponse is the body of every error response. Error repeats Message
+// for clients written before codes were added; Fields lists the invalid
+// request fields of a validation error.
+type ErrorResponse struct {
+	Code      string                 `json:"code"`
+	Message   string                 `json:"message"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+	Fields    map[string]string      `json:"fields,omitempty"`
+	Error     string                 `json:"error"`
+	Status    int                    `json:"status"`
+	RequestID string                 `json:"request_id,omitempty"`
+	Timestamp time.Time              `json:"timestamp"`
+}
+
+// errorCodeForStatus is the code of an error answered with status when
+// nothing more specific is known
+func errorCodeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return ErrCodeInvalidInput
+	case http.StatusUnauthorized:
+		return ErrCodeUnauthorized
+	case http.StatusForbidden:
+		return ErrCodeForbidden
+	case http.StatusNotFound:
+		return ErrCodeNotFound
+	case http.StatusConflict:
+		return ErrCodeConflict
+	case http.StatusRequestEntityTooLarge:
+		return ErrCodePayloadTooLarge
+	case http.StatusUnsupportedMediaType:
+		return ErrCodeUnsupportedMediaType
+	case http.StatusUnprocessableEntity:
+		return ErrCodeUnprocessable
+	case http.StatusTooManyRequests:
+		return ErrCodeRateLimited
+	case http.StatusServiceUnavailable:
+		return ErrCodeUnavailable
+	case http.StatusGatewayTimeout:
+		return ErrCodeTimeout
+	default:
+		return ErrCodeInternal
+	}
+}
+
+// apiErrorFrom maps an error from the engine, storage or providers to the
+// status and code it is answered with, and message. Errors it doesn't know
+// are internal errors.
+func apiErrorFrom(err error, message string) *APIError {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	var overflow *providers.ContextTooLongError
+	if errors.As(err, &overflow) {
+		return &APIError{
+			Status:  http.StatusRequestEntityTooLarge,
+			Code:    ErrCodeContextTooLong,
+			Message: message,
+			Details: map[string]interface{}{"context_overflow": contextOverflowDetails(overflow)},
+		}
+	}
+	var timedOut *engine.PhaseTimeoutError
+	if errors.As(err, &timedOut) {
+		return &APIError{
+			Status:  http.StatusGatewayTimeout,
+			Code:    ErrCodeTimeout,
+			Message: message,
+			Details: map[string]interface{}{"phase_timeouts": timedOut.Timeouts},
+		}
+	}
+	if errors.Is(err, storage.ErrPromptNotFound) || errors.Is(err, storage.ErrVersionNotFound) ||
+		errors.Is(err, storage.ErrContextDocumentNotFound) {
+		return &APIError{Status: http.StatusNotFound, Code: ErrCodeNotFound, Message: message}
+	}
+
+	switch providers.ErrorClass(err) {
+	case providers.ErrorClassRateLimited:
+		return &APIError{Status: http.StatusTooManyRequests, Code: ErrCodeRateLimited, Message: message}
+	case providers.ErrorClassNotConfigured, providers.ErrorClassCircuitOpen:
+		return &APIError{Status: http.StatusServiceUnavailable, Code: ErrCodeProviderUnavailable, Message: message}
+	case providers.ErrorClassTimeout:
+		return &APIError{Status: http.StatusGatewayTimeout, Code: ErrCodeTimeout, Message: message}
+	}
+	return &APIError{Status: http.StatusInternalServerError, Code: ErrCodeInternal, Message: message}
+}
+
+// errorResponse builds the envelope of apiErr. Errors of a request with an
+// ID carry it, so a client reporting one can be matched with the server's
+// logs.
+func errorResponse(w http.ResponseWriter, apiErr *APIError) ErrorResponse {
+	return ErrorResponse{
+		Code:      apiErr.Code,
+		Message:   apiErr.Message,
+		Details:   apiErr.Details,
+		Error:     apiErr.Message,
+		Status:    apiErr.Status,
+		RequestID: w.Header().Get("X-Request-ID"),
+		Timestamp: time.Now(),
+	}
+}
+
+// writeErrorResponse writes response as JSON with its status, for
+// middleware without a server
+func writeErrorResponse(w http.ResponseWriter, response ErrorResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(response.Status)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.GetLogger().WithError(err).Error("Failed to encode error response")
+	}
+}
+
+// writeAPIError writes apiErr in the error envelope
+func (s *SimpleServer) writeAPIError(w http.ResponseWriter, apiErr *APIError) {
+	s.writeJSON(w, apiErr.Status, errorResponse(w, apiErr))
+}
+
+// writeError writes an error with the code for its status
+func (s *SimpleServer) writeError(w http.ResponseWriter, status int, message string) {
+	s.writeErrorCode(w, status, errorCodeForStatus(status), message)
+}
+
+// writeErrorCode writes an error with a code more specific than its status's
+func (s *SimpleServer) writeErrorCode(w http.ResponseWriter, status int, code, message string) {
+	s.writeAPIError(w, &APIError{Status: status, Code: code, Message: message})
+}
+
+// writeValidationError writes a 400 invalid_input error with a fields map of
+// what is wrong with each invalid request field
+func (s *SimpleServer) writeValidationError(w http.ResponseWriter, message string, fields map[string]string) {
+	response := errorResponse(w, &APIError{Status: http.StatusBadRequest, Code: ErrCodeInvalidInput, Message: message})
+	response.Fields = fields
+	s.writeJSON(w, http.StatusBadRequest, response)
+}
@@ -0,0 +1,103 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jonwraymond/prompt-alchemy/pkg/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingProvider fails every generation with err
+type failingProvider struct {
+	pingProvider
+	err error
+}
+
+func (p *failingProvider) Generate(ctx context.Context, req providers.GenerateRequest) (*providers.GenerateResponse, error) {
+	return nil, p.err
+}
+
+func TestErrorResponses(t *testing.T) {
+	server, _ := newTestServer(t)
+	for name, err := range map[string]error{
+		"limited":  fmt.Errorf("limited: %w", providers.ErrRateLimited),
+		"unset":    providers.ErrNotConfigured,
+		"small":    &providers.ContextTooLongError{Provider: "small", InputTokens: 9000, ContextWindow: 8000},
+		"breaking": errors.New("unexpected failure"),
+	} {
+		require.NoError(t, server.registry.Register(name, &failingProvider{pingProvider{name: name, available: true}, err}))
+	}
+
+	generate := func(provider string) string {
+		return fmt.Sprintf(`{"input":"Write a parser","phases":["prima-materia"],"count":1,"providers":{"prima-materia":%q}}`, provider)
+	}
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		body        string
+		status      int
+		code        string
+	}{
+		{"malformed JSON", http.MethodPost, "/api/v1/prompts/generate", "application/json", "{", http.StatusBadRequest, ErrCodeInvalidInput},
+		{"invalid field", http.MethodPost, "/api/v1/prompts/generate", "application/json", `{"input":""}`, http.StatusBadRequest, ErrCodeInvalidInput},
+		{"unknown prompt", http.MethodGet, "/api/v1/prompts/" + uuid.NewString(), "", "", http.StatusNotFound, ErrCodeNotFound},
+		{"not JSON", http.MethodPost, "/api/v1/prompts/generate", "text/plain", "input", http.StatusUnsupportedMediaType, ErrCodeUnsupportedMediaType},
+		{"maintenance without auth", http.MethodPost, "/api/v1/admin/maintenance", "application/json", `{"cleanup_old":true}`, http.StatusForbidden, ErrCodeForbidden},
+		{"provider rate limited", http.MethodPost, "/api/v1/prompts/generate?save=false", "application/json", generate("limited"), http.StatusTooManyRequests, ErrCodeRateLimited},
+		{"provider not configured", http.MethodPost, "/api/v1/prompts/generate?save=false", "application/json", generate("unset"), http.StatusServiceUnavailable, ErrCodeProviderUnavailable},
+		{"context too long", http.MethodPost, "/api/v1/prompts/generate?save=false", "application/json", generate("small"), http.StatusRequestEntityTooLarge, ErrCodeContextTooLong},
+		{"provider failure", http.MethodPost, "/api/v1/prompts/generate?save=false", "application/json", generate("breaking"), http.StatusInternalServerError, ErrCodeInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			recorder := httptest.NewRecorder()
+			server.Router().ServeHTTP(recorder, req)
+
+			require.Equal(t, tt.status, recorder.Code, recorder.Body.String())
+			var response ErrorResponse
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+			assert.Equal(t, tt.code, response.Code)
+			assert.Equal(t, tt.status, response.Status)
+			assert.NotEmpty(t, response.Message)
+			assert.Equal(t, response.Message, response.Error, "error repeats the message")
+			assert.NotEmpty(t, response.RequestID)
+		})
+	}
+
+	t.Run("context overflow details", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/prompts/generate?save=false", strings.NewReader(generate("small")))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		server.Router().ServeHTTP(recorder, req)
+
+		var response ErrorResponse
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		overflow, ok := response.Details["context_overflow"].(map[string]interface{})
+		require.True(t, ok, "details carry the overflow: %v", response.Details)
+		assert.Equal(t, float64(1000), overflow["overflow_tokens"])
+	})
+}
+
+func TestErrorCodeForStatus(t *testing.T) {
+	assert.Equal(t, ErrCodeInvalidInput, errorCodeForStatus(http.StatusBadRequest))
+	assert.Equal(t, ErrCodeConflict, errorCodeForStatus(http.StatusConflict))
+	assert.Equal(t, ErrCodeRateLimited, errorCodeForStatus(http.StatusTooManyRequests))
+	assert.Equal(t, ErrCodeInternal, errorCodeForStatus(http.StatusBadGateway))
+}
@@ -0,0 +1,158 @@
+package http
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jonwraymond/prompt-alchemy/internal/storage"
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/sirupsen/logrus"
+)
+
+// Export formats accepted by GET /api/v1/prompts/export
+const (
+	exportFormatJSONL = "jsonl"
+	exportFormatCSV   = "csv"
+)
+
+// invalidSinceMessage is the error response for a malformed since filter
+const invalidSinceMessage = "Invalid date format for 'since' parameter (use YYYY-MM-DD)"
+
+// exportCSVHeader names the prompt fields flattened into each CSV row
+var exportCSVHeader = []string{"id", "phase", "provider", "model", "content", "tags", "created_at", "score"}
+
+// parsePromptFilters reads the q, phase, provider, tags and since filters
+// shared by prompt search and export. It fails only on a malformed since
+// date.
+func parsePromptFilters(values url.Values) (storage.SearchCriteria, error) {
+	criteria := storage.SearchCriteria{
+		Query:    values.Get("q"),
+		Phase:    values.Get("phase"),
+		Provider: values.Get("provider"),
+	}
+	if tags := values.Get("tags"); tags != "" {
+		for _, tag := range strings.Split(tags, ",") {
+			criteria.Tags = append(criteria.Tags, strings.TrimSpace(tag))
+		}
+	}
+	if since := values.Get("since"); since != "" {
+		parsed, err := time.Parse("2006-01-02", since)
+		if err != nil {
+			return storage.SearchCriteria{}, fmt.Errorf("invalid since date %q: %w", since, err)
+		}
+		criteria.Since = &parsed
+	}
+	return criteria, nil
+}
+
+// handleExportPrompts streams every prompt matching the search filters as
+// JSONL, one full prompt per line, or as CSV. Rows are written as they are
+// read, so the export never holds more than a page of prompts in memory.
+// Once streaming has started, errors can only be logged: the status line
+// has already been sent.
+func (s *SimpleServer) handleExportPrompts(w http.ResponseWriter, r *http.Request) {
+	logger := s.requestLogger(r.Context())
+	query := r.URL.Query()
+	format := strings.ToLower(query.Get("format"))
+	if format == "" {
+		format = exportFormatJSONL
+	}
+	if format != exportFormatJSONL && format != exportFormatCSV {
+		s.writeError(w, http.StatusBadRequest, "Invalid 'format' parameter (use jsonl or csv)")
+		return
+	}
+
+	criteria, err := parsePromptFilters(query)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, invalidSinceMessage)
+		return
+	}
+	if limitStr := query.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 1 {
+			s.writeError(w, http.StatusBadRequest, "Invalid 'limit' parameter (must be a positive integer)")
+			return
+		}
+		criteria.Limit = limit
+	}
+
+	filename := fmt.Sprintf("prompts-%s.%s", time.Now().UTC().Format("20060102-150405"), format)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	var write func(*models.Prompt) error
+	var flush func() error
+	switch format {
+	case exportFormatCSV:
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		writer := csv.NewWriter(w)
+		if err := writer.Write(exportCSVHeader); err != nil {
+			logger.WithError(err).Error("Failed to write export header")
+			return
+		}
+		write = func(p *models.Prompt) error {
+			tags, err := json.Marshal(p.Tags)
+			if err != nil {
+				return err
+			}
+			return writer.Write([]string{
+				p.ID.String(),
+				string(p.Phase),
+				p.Provider,
+				p.Model,
+				p.Content,
+				string(tags),
+				p.CreatedAt.UTC().Format(time.RFC3339),
+				strconv.FormatFloat(p.RelevanceScore, 'f', -1, 64),
+			})
+		}
+		flush = func() error {
+			writer.Flush()
+			return writer.Error()
+		}
+	default:
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		encoder := json.NewEncoder(w)
+		write = func(p *models.Prompt) error {
+			if err := s.store.LoadPromptDetails(r.Context(), p); err != nil {
+				return err
+			}
+			return encoder.Encode(p)
+		}
+		flush = func() error { return nil }
+	}
+
+	flusher, _ := w.(http.Flusher)
+	exported := 0
+	err = s.store.ExportPrompts(r.Context(), criteria, func(p *models.Prompt) error {
+		if err := write(p); err != nil {
+			return err
+		}
+		exported++
+		// Send rows on as they are written rather than buffering the response
+		if flusher != nil && exported%100 == 0 {
+			if err := flush(); err != nil {
+				return err
+			}
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		logger.WithError(err).WithField("exported", exported).Error("Prompt export failed")
+		return
+	}
+
+	logger.WithFields(logrus.Fields{
+		"format":   format,
+		"exported": exported,
+	}).Info("Prompts exported via HTTP API")
+}
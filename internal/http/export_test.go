@@ -0,0 +1,89 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleExportPrompts(t *testing.T) {
+	server, store := newTestServer(t)
+
+	ctx := context.Background()
+	tricky := &models.Prompt{
+		Content:        "Summarize, then list:\n- risks\n- \"open\" questions",
+		Phase:          models.PhaseCoagulatio,
+		Provider:       "anthropic",
+		Model:          "claude-3-5-sonnet",
+		Tags:           []string{"summary", "risk, review"},
+		RelevanceScore: 0.75,
+	}
+	require.NoError(t, store.SavePrompt(ctx, tricky))
+	for i := 0; i < 150; i++ {
+		require.NoError(t, store.SavePrompt(ctx, &models.Prompt{Content: fmt.Sprintf("bulk %d", i), Phase: models.PhaseSolutio, Provider: "openai"}))
+	}
+
+	export := func(query string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		server.Router().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/prompts/export"+query, nil))
+		return recorder
+	}
+
+	t.Run("jsonl streams one prompt per line", func(t *testing.T) {
+		recorder := export("?format=jsonl")
+		require.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "application/x-ndjson", recorder.Header().Get("Content-Type"))
+		assert.Contains(t, recorder.Header().Get("Content-Disposition"), `attachment; filename="prompts-`)
+		assert.True(t, recorder.Flushed, "rows are flushed as they are written")
+
+		lines := 0
+		scanner := bufio.NewScanner(recorder.Body)
+		for scanner.Scan() {
+			var p models.Prompt
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &p))
+			assert.NotEmpty(t, p.ID)
+			lines++
+		}
+		assert.Equal(t, 151, lines)
+	})
+
+	t.Run("csv escapes commas, quotes and newlines", func(t *testing.T) {
+		recorder := export("?format=csv&phase=coagulatio")
+		require.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "text/csv; charset=utf-8", recorder.Header().Get("Content-Type"))
+		assert.True(t, strings.HasSuffix(recorder.Header().Get("Content-Disposition"), `.csv"`))
+
+		rows, err := csv.NewReader(recorder.Body).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, rows, 2)
+		assert.Equal(t, []string{"id", "phase", "provider", "model", "content", "tags", "created_at", "score"}, rows[0])
+		row := rows[1]
+		assert.Equal(t, tricky.ID.String(), row[0])
+		assert.Equal(t, "coagulatio", row[1])
+		assert.Equal(t, tricky.Content, row[4])
+		assert.Equal(t, `["summary","risk, review"]`, row[5])
+		assert.Equal(t, "0.75", row[7])
+	})
+
+	t.Run("search filters and limit", func(t *testing.T) {
+		rows, err := csv.NewReader(export("?format=csv&provider=openai&limit=5").Body).ReadAll()
+		require.NoError(t, err)
+		assert.Len(t, rows, 6)
+	})
+
+	t.Run("invalid parameters", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, export("?format=excel").Code)
+		assert.Equal(t, http.StatusBadRequest, export("?since=yesterday").Code)
+		assert.Equal(t, http.StatusBadRequest, export("?limit=0").Code)
+	})
+}
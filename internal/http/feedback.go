@@ -0,0 +1,99 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jonwraymond/prompt-alchemy/internal/storage"
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/sirupsen/logrus"
+)
+
+// FeedbackRequest reports an outcome for a prompt
+type FeedbackRequest struct {
+	Signal  models.FeedbackSignal `json:"signal"`
+	Score   *float64              `json:"score,omitempty"` // optional 0-1 strength of the signal
+	Comment string                `json:"comment,omitempty"`
+}
+
+// FeedbackResponse is the saved feedback and the prompt's updated summary
+type FeedbackResponse struct {
+	Feedback *models.PromptFeedback  `json:"feedback"`
+	Summary  *models.FeedbackSummary `json:"summary"`
+}
+
+// handlePromptFeedback saves an outcome reported for a prompt and feeds it
+// to the learning engine, which adjusts the prompt's relevance for future
+// ranking and selection
+func (s *SimpleServer) handlePromptFeedback(w http.ResponseWriter, r *http.Request) {
+	logger := s.requestLogger(r.Context())
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid prompt ID")
+		return
+	}
+
+	var req FeedbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+	if !req.Signal.IsValid() {
+		signals := make([]string, len(models.FeedbackSignals))
+		for i, signal := range models.FeedbackSignals {
+			signals[i] = string(signal)
+		}
+		s.writeError(w, http.StatusBadRequest, "signal must be one of "+strings.Join(signals, ", "))
+		return
+	}
+	if req.Score != nil && (*req.Score < 0 || *req.Score > 1) {
+		s.writeError(w, http.StatusBadRequest, "score must be between 0 and 1")
+		return
+	}
+
+	if _, err := s.store.GetPromptByID(r.Context(), id); err != nil {
+		if errors.Is(err, storage.ErrPromptNotFound) {
+			s.writeError(w, http.StatusNotFound, "Prompt not found")
+		} else {
+			logger.WithError(err).Error("Failed to get prompt")
+			s.writeError(w, http.StatusInternalServerError, "Failed to save feedback")
+		}
+		return
+	}
+
+	feedback := &models.PromptFeedback{
+		PromptID: id,
+		Signal:   req.Signal,
+		Score:    req.Score,
+		Comment:  req.Comment,
+	}
+	if err := s.store.SavePromptFeedback(r.Context(), feedback); err != nil {
+		logger.WithError(err).WithField("prompt_id", id).Error("Failed to save feedback")
+		s.writeError(w, http.StatusInternalServerError, "Failed to save feedback")
+		return
+	}
+
+	// The feedback is kept even if learning from it fails
+	if s.learner != nil {
+		if err := s.learner.RecordFeedback(r.Context(), feedback); err != nil {
+			logger.WithError(err).WithField("prompt_id", id).Warn("Failed to learn from feedback")
+		}
+	}
+
+	summary, err := s.store.GetPromptFeedbackSummary(r.Context(), id)
+	if err != nil {
+		logger.WithError(err).WithField("prompt_id", id).Error("Failed to get feedback summary")
+		s.writeError(w, http.StatusInternalServerError, "Failed to get feedback summary")
+		return
+	}
+
+	logger.WithFields(logrus.Fields{
+		"prompt_id": id,
+		"signal":    feedback.Signal,
+	}).Info("Prompt feedback recorded via HTTP API")
+	s.writeJSON(w, http.StatusCreated, FeedbackResponse{Feedback: feedback, Summary: summary})
+}
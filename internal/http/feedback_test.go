@@ -0,0 +1,71 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jonwraymond/prompt-alchemy/internal/learning"
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandlePromptFeedback(t *testing.T) {
+	server, store := newTestServer(t)
+	server.learner = learning.NewLearningEngine(store, server.registry, server.logger)
+
+	ctx := context.Background()
+	prompt := &models.Prompt{Content: "summarize an incident", Phase: models.PhaseSolutio, Provider: "openai", Model: "gpt-4", RelevanceScore: 0.5}
+	require.NoError(t, store.SavePrompt(ctx, prompt))
+
+	send := func(id uuid.UUID, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/prompts/"+id.String()+"/feedback", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		server.Router().ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	t.Run("feedback is saved and learned from", func(t *testing.T) {
+		recorder := send(prompt.ID, `{"signal":"thumbs_up"}`)
+		require.Equal(t, http.StatusCreated, recorder.Code, recorder.Body.String())
+		recorder = send(prompt.ID, `{"signal":"conversion","score":0.5,"comment":"half the signups"}`)
+		require.Equal(t, http.StatusCreated, recorder.Code, recorder.Body.String())
+
+		var response FeedbackResponse
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		assert.Equal(t, models.FeedbackConversion, response.Feedback.Signal)
+		assert.Equal(t, "half the signups", response.Feedback.Comment)
+		assert.Equal(t, 2, response.Summary.Total)
+		assert.InDelta(t, 0.75, response.Summary.AverageOutcome, 1e-9)
+
+		stored, err := store.GetPromptByID(ctx, prompt.ID)
+		require.NoError(t, err)
+		assert.Greater(t, stored.RelevanceScore, 0.5, "positive feedback raises relevance")
+	})
+
+	t.Run("prompt detail includes the aggregate", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		server.Router().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/prompts/"+prompt.ID.String(), nil))
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		var detail PromptDetailResponse
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &detail))
+		require.NotNil(t, detail.Feedback)
+		assert.Equal(t, 2, detail.Feedback.Total)
+		assert.Equal(t, map[models.FeedbackSignal]int{models.FeedbackThumbsUp: 1, models.FeedbackConversion: 1}, detail.Feedback.Signals)
+		assert.NotNil(t, detail.Feedback.LastFeedbackAt)
+	})
+
+	t.Run("invalid feedback", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, send(prompt.ID, `{"signal":"meh"}`).Code)
+		assert.Equal(t, http.StatusBadRequest, send(prompt.ID, `{"signal":"thumbs_up","score":1.5}`).Code)
+		assert.Equal(t, http.StatusBadRequest, send(prompt.ID, `{"signal":`).Code)
+		assert.Equal(t, http.StatusNotFound, send(uuid.New(), `{"signal":"thumbs_up"}`).Code)
+	})
+}
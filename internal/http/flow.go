@@ -0,0 +1,244 @@
+package http
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+)
+
+// Node and flow states reported to the web UI
+const (
+	flowReady      = "ready"
+	flowProcessing = "processing"
+	flowComplete   = "complete"
+	flowFailed     = "failed"
+)
+
+// DefaultFlowTTL is how long a generation's flow state is kept after its
+// last update
+const DefaultFlowTTL = 10 * time.Minute
+
+// flowSubscriberBuffer is how many updates a slow subscriber may fall behind
+// before updates to it are dropped
+const flowSubscriberBuffer = 16
+
+// NodeState is the progress of one phase of a generation
+type NodeState struct {
+	Phase       models.Phase `json:"phase"`
+	Status      string       `json:"status"`
+	Progress    int          `json:"progress"` // 0-100
+	Prompts     int          `json:"prompts"`
+	StartedAt   *time.Time   `json:"started_at,omitempty"`
+	CompletedAt *time.Time   `json:"completed_at,omitempty"`
+}
+
+// FlowState is the progress of a generation through its phases
+type FlowState struct {
+	SessionID string       `json:"session_id"`
+	Status    string       `json:"status"`
+	Phase     models.Phase `json:"phase,omitempty"` // phase running now, or the last one run
+	Progress  int          `json:"progress"`        // share of phases complete, 0-100
+	Nodes     []NodeState  `json:"nodes"`
+	Error     string       `json:"error,omitempty"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+// Node returns the state of the given phase, which is ready if the
+// generation doesn't run it
+func (f FlowState) Node(phase models.Phase) NodeState {
+	for _, node := range f.Nodes {
+		if node.Phase == phase {
+			return node
+		}
+	}
+	return NodeState{Phase: phase, Status: flowReady}
+}
+
+// FlowTracker records which phase each active generation is in, so the web
+// UI's status endpoints and flow event stream can show live progress.
+// Sessions are kept in memory and forgotten ttl after their last update.
+type FlowTracker struct {
+	ttl time.Duration
+
+	mu          sync.Mutex
+	flows       map[string]*FlowState
+	subscribers map[chan FlowState]struct{}
+}
+
+// NewFlowTracker creates a tracker that keeps each session for ttl after its
+// last update
+func NewFlowTracker(ttl time.Duration) *FlowTracker {
+	if ttl <= 0 {
+		ttl = DefaultFlowTTL
+	}
+	return &FlowTracker{
+		ttl:         ttl,
+		flows:       make(map[string]*FlowState),
+		subscribers: make(map[chan FlowState]struct{}),
+	}
+}
+
+// Start begins tracking a generation that will run phases in order
+func (t *FlowTracker) Start(sessionID string, phases []models.Phase) {
+	flow := &FlowState{
+		SessionID: sessionID,
+		Status:    flowProcessing,
+		Nodes:     make([]NodeState, len(phases)),
+	}
+	for i, phase := range phases {
+		flow.Nodes[i] = NodeState{Phase: phase, Status: flowReady}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.flows[sessionID] = flow
+	t.publish(flow)
+}
+
+// PhaseStarted marks a phase of the session as processing
+func (t *FlowTracker) PhaseStarted(sessionID string, phase models.Phase) {
+	t.update(sessionID, func(flow *FlowState) {
+		now := time.Now()
+		flow.Phase = phase
+		node := flow.node(phase)
+		node.Status = flowProcessing
+		node.StartedAt = &now
+	})
+}
+
+// PhaseCompleted marks a phase of the session as complete with the number of
+// prompts it produced
+func (t *FlowTracker) PhaseCompleted(sessionID string, phase models.Phase, prompts int) {
+	t.update(sessionID, func(flow *FlowState) {
+		now := time.Now()
+		node := flow.node(phase)
+		node.Status = flowComplete
+		node.Progress = 100
+		node.Prompts = prompts
+		node.CompletedAt = &now
+
+		complete := 0
+		for _, n := range flow.Nodes {
+			if n.Status == flowComplete {
+				complete++
+			}
+		}
+		flow.Progress = complete * 100 / len(flow.Nodes)
+	})
+}
+
+// Finish ends the session's generation, marking it failed if err is set.
+// A phase still processing when the generation fails is marked failed too.
+func (t *FlowTracker) Finish(sessionID string, err error) {
+	t.update(sessionID, func(flow *FlowState) {
+		if err == nil {
+			flow.Status = flowComplete
+			flow.Progress = 100
+			return
+		}
+		flow.Status = flowFailed
+		flow.Error = err.Error()
+		for i := range flow.Nodes {
+			if flow.Nodes[i].Status == flowProcessing {
+				flow.Nodes[i].Status = flowFailed
+			}
+		}
+	})
+}
+
+// Get returns a copy of the session's flow state. With an empty sessionID it
+// returns the most recently updated session, so the UI can follow a
+// generation it didn't start.
+func (t *FlowTracker) Get(sessionID string) (FlowState, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.prune()
+
+	flow, ok := t.flows[sessionID]
+	if sessionID == "" {
+		for _, candidate := range t.flows {
+			if flow == nil || candidate.UpdatedAt.After(flow.UpdatedAt) {
+				flow, ok = candidate, true
+			}
+		}
+	}
+	if !ok {
+		return FlowState{}, false
+	}
+	return flow.clone(), true
+}
+
+// Subscribe returns a channel receiving a copy of every flow state change
+// and a function that unsubscribes and closes it. Updates are dropped for a
+// subscriber that falls behind rather than stalling generation.
+func (t *FlowTracker) Subscribe() (<-chan FlowState, func()) {
+	ch := make(chan FlowState, flowSubscriberBuffer)
+
+	t.mu.Lock()
+	t.subscribers[ch] = struct{}{}
+	t.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			t.mu.Lock()
+			delete(t.subscribers, ch)
+			t.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// update applies fn to a tracked session and publishes the result
+func (t *FlowTracker) update(sessionID string, fn func(*FlowState)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	flow, ok := t.flows[sessionID]
+	if !ok {
+		return
+	}
+	fn(flow)
+	t.publish(flow)
+}
+
+// publish stamps the flow and sends a copy to every subscriber. t.mu must
+// be held.
+func (t *FlowTracker) publish(flow *FlowState) {
+	flow.UpdatedAt = time.Now()
+	for ch := range t.subscribers {
+		select {
+		case ch <- flow.clone():
+		default:
+		}
+	}
+	t.prune()
+}
+
+// prune forgets sessions not updated within the TTL. t.mu must be held.
+func (t *FlowTracker) prune() {
+	cutoff := time.Now().Add(-t.ttl)
+	for id, flow := range t.flows {
+		if flow.UpdatedAt.Before(cutoff) {
+			delete(t.flows, id)
+		}
+	}
+}
+
+// node returns the phase's node, adding it if the flow wasn't started with it
+func (f *FlowState) node(phase models.Phase) *NodeState {
+	for i := range f.Nodes {
+		if f.Nodes[i].Phase == phase {
+			return &f.Nodes[i]
+		}
+	}
+	f.Nodes = append(f.Nodes, NodeState{Phase: phase, Status: flowReady})
+	return &f.Nodes[len(f.Nodes)-1]
+}
+
+func (f *FlowState) clone() FlowState {
+	snapshot := *f
+	snapshot.Nodes = append([]NodeState(nil), f.Nodes...)
+	return snapshot
+}
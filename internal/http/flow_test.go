@@ -0,0 +1,75 @@
+package http
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlowTracker(t *testing.T) {
+	tracker := NewFlowTracker(time.Minute)
+	updates, unsubscribe := tracker.Subscribe()
+	defer unsubscribe()
+
+	phases := []models.Phase{models.PhasePrimaMaterial, models.PhaseSolutio}
+	tracker.Start("s1", phases)
+	tracker.PhaseStarted("s1", models.PhasePrimaMaterial)
+
+	flow, ok := tracker.Get("s1")
+	require.True(t, ok)
+	assert.Equal(t, flowProcessing, flow.Status)
+	assert.Equal(t, models.PhasePrimaMaterial, flow.Phase)
+	assert.Equal(t, flowProcessing, flow.Node(models.PhasePrimaMaterial).Status)
+	assert.Equal(t, flowReady, flow.Node(models.PhaseSolutio).Status)
+	assert.Equal(t, flowReady, flow.Node(models.PhaseCoagulatio).Status, "phases not run are ready")
+
+	tracker.PhaseCompleted("s1", models.PhasePrimaMaterial, 3)
+	flow, _ = tracker.Get("s1")
+	assert.Equal(t, 50, flow.Progress)
+	node := flow.Node(models.PhasePrimaMaterial)
+	assert.Equal(t, flowComplete, node.Status)
+	assert.Equal(t, 100, node.Progress)
+	assert.Equal(t, 3, node.Prompts)
+
+	tracker.PhaseStarted("s1", models.PhaseSolutio)
+	tracker.Finish("s1", errors.New("provider unavailable"))
+	flow, _ = tracker.Get("s1")
+	assert.Equal(t, flowFailed, flow.Status)
+	assert.Equal(t, flowFailed, flow.Node(models.PhaseSolutio).Status)
+	assert.Equal(t, "provider unavailable", flow.Error)
+
+	// Every change was published, in order
+	var statuses []string
+	for len(updates) > 0 {
+		statuses = append(statuses, (<-updates).Status)
+	}
+	assert.Equal(t, []string{flowProcessing, flowProcessing, flowProcessing, flowProcessing, flowFailed}, statuses)
+
+	t.Run("latest session without an ID", func(t *testing.T) {
+		tracker.Start("s2", phases)
+		tracker.PhaseCompleted("s2", models.PhasePrimaMaterial, 1)
+		tracker.PhaseCompleted("s2", models.PhaseSolutio, 1)
+		tracker.Finish("s2", nil)
+
+		flow, ok := tracker.Get("")
+		require.True(t, ok)
+		assert.Equal(t, "s2", flow.SessionID)
+		assert.Equal(t, flowComplete, flow.Status)
+		assert.Equal(t, 100, flow.Progress)
+
+		_, ok = tracker.Get("unknown")
+		assert.False(t, ok)
+	})
+
+	t.Run("sessions expire", func(t *testing.T) {
+		short := NewFlowTracker(10 * time.Millisecond)
+		short.Start("s3", phases)
+		time.Sleep(20 * time.Millisecond)
+		_, ok := short.Get("s3")
+		assert.False(t, ok)
+	})
+}
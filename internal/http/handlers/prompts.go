@@ -8,6 +8,7 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/jonwraymond/prompt-alchemy/internal/httputil"
 	log "github.com/jonwraymond/prompt-alchemy/internal/log"
 	"github.com/jonwraymond/prompt-alchemy/internal/selection"
 	"github.com/jonwraymond/prompt-alchemy/pkg/models"
@@ -27,6 +28,12 @@ func SelectPromptHandler(w http.ResponseWriter, r *http.Request, registry *provi
 	logger.WithField("request_id", reqID).Info("Starting prompt selection request")
 	start := time.Now()
 
+	if !httputil.HasJSONBody(r) {
+		logger.WithField("content_type", r.Header.Get("Content-Type")).Warn("Unsupported content type")
+		http.Error(w, "Content-Type must be application/json", http.StatusUnsupportedMediaType)
+		return
+	}
+
 	var req struct {
 		Prompts  []string                    `json:"prompts"`
 		Criteria selection.SelectionCriteria `json:"criteria"`
@@ -0,0 +1,96 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/jonwraymond/prompt-alchemy/pkg/providers"
+)
+
+// Overall states of a deep health check
+const (
+	healthHealthy   = "healthy"
+	healthDegraded  = "degraded"  // storage is up but a provider can't be reached
+	healthUnhealthy = "unhealthy" // storage is down
+)
+
+// storagePingTimeout bounds the storage check so a wedged database fails the
+// probe instead of hanging it
+const storagePingTimeout = 2 * time.Second
+
+// StorageHealth is the outcome of pinging storage
+type StorageHealth struct {
+	Status    string `json:"status"` // "up" or "down"
+	LatencyMS int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// DeepHealthResponse reports each dependency a ready server needs
+type DeepHealthResponse struct {
+	Status    string                   `json:"status"`
+	Timestamp time.Time                `json:"timestamp"`
+	Version   string                   `json:"version"`
+	Storage   StorageHealth            `json:"storage"`
+	Providers map[string]LatencyResult `json:"providers"`
+}
+
+// handleLiveness answers as long as the process serves requests, without
+// touching any dependency, so a slow database never gets the pod restarted
+func (s *SimpleServer) handleLiveness(w http.ResponseWriter, r *http.Request) {
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status":    healthHealthy,
+		"timestamp": time.Now(),
+	})
+}
+
+// handleReadiness pings storage and every registered provider. It answers
+// 503 when storage is down; unreachable providers only mark the server
+// degraded, since generation can fall back to the others. Provider results
+// come from the latency probe's cache, so frequent probes don't each call
+// the provider APIs.
+func (s *SimpleServer) handleReadiness(w http.ResponseWriter, r *http.Request) {
+	response := DeepHealthResponse{
+		Status:    healthHealthy,
+		Timestamp: time.Now(),
+		Version:   "1.0.0",
+		Storage:   s.checkStorage(r.Context()),
+		Providers: make(map[string]LatencyResult),
+	}
+
+	names := s.registry.ListProviders()
+	list := make([]providers.Provider, 0, len(names))
+	for _, name := range names {
+		if provider, err := s.registry.Get(name); err == nil {
+			list = append(list, provider)
+		}
+	}
+	for i, result := range s.latencyProbe.CheckAll(r.Context(), list) {
+		response.Providers[list[i].Name()] = result
+		if result.Status != connectionConnected {
+			response.Status = healthDegraded
+		}
+	}
+
+	status := http.StatusOK
+	if response.Storage.Status != "up" {
+		response.Status = healthUnhealthy
+		status = http.StatusServiceUnavailable
+	}
+	s.writeJSON(w, status, response)
+}
+
+func (s *SimpleServer) checkStorage(ctx context.Context) StorageHealth {
+	ctx, cancel := context.WithTimeout(ctx, storagePingTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := s.store.Ping(ctx)
+	result := StorageHealth{Status: "up", LatencyMS: time.Since(start).Milliseconds()}
+	if err != nil {
+		s.requestLogger(ctx).WithError(err).Warn("Storage health check failed")
+		result.Status = "down"
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,80 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jonwraymond/prompt-alchemy/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthChecks(t *testing.T) {
+	reachable := func(ctx context.Context) (time.Duration, error) { return time.Millisecond, nil }
+	unreachable := func(ctx context.Context) (time.Duration, error) { return 0, errors.New("connection refused") }
+
+	newServer := func(t *testing.T, pings map[string]func(ctx context.Context) (time.Duration, error)) (*SimpleServer, *storage.Storage) {
+		server, store := newTestServer(t)
+		for name, ping := range pings {
+			require.NoError(t, server.registry.Register(name, &pingProvider{name: name, available: true, ping: ping}))
+		}
+		return server, store
+	}
+	get := func(server *SimpleServer, path string) (int, DeepHealthResponse) {
+		recorder := httptest.NewRecorder()
+		server.Router().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		var response DeepHealthResponse
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		return recorder.Code, response
+	}
+
+	t.Run("healthy", func(t *testing.T) {
+		server, _ := newServer(t, map[string]func(ctx context.Context) (time.Duration, error){"openai": reachable})
+
+		for _, path := range []string{"/readyz", "/health?deep=true"} {
+			status, response := get(server, path)
+			assert.Equal(t, http.StatusOK, status, path)
+			assert.Equal(t, healthHealthy, response.Status, path)
+			assert.Equal(t, "up", response.Storage.Status, path)
+			require.Contains(t, response.Providers, "openai", path)
+			assert.Equal(t, connectionConnected, response.Providers["openai"].Status, path)
+		}
+
+		status, response := get(server, "/health")
+		assert.Equal(t, http.StatusOK, status)
+		assert.Empty(t, response.Providers, "the shallow check skips dependencies")
+	})
+
+	t.Run("storage down", func(t *testing.T) {
+		server, store := newServer(t, map[string]func(ctx context.Context) (time.Duration, error){"openai": reachable})
+		require.NoError(t, store.Close())
+
+		status, response := get(server, "/readyz")
+		assert.Equal(t, http.StatusServiceUnavailable, status)
+		assert.Equal(t, healthUnhealthy, response.Status)
+		assert.Equal(t, "down", response.Storage.Status)
+		assert.NotEmpty(t, response.Storage.Error)
+
+		status, _ = get(server, "/healthz")
+		assert.Equal(t, http.StatusOK, status, "liveness ignores storage")
+	})
+
+	t.Run("degraded providers", func(t *testing.T) {
+		server, _ := newServer(t, map[string]func(ctx context.Context) (time.Duration, error){
+			"openai":    reachable,
+			"anthropic": unreachable,
+		})
+
+		status, response := get(server, "/readyz")
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, healthDegraded, response.Status)
+		assert.Equal(t, connectionConnected, response.Providers["openai"].Status)
+		assert.Equal(t, connectionError, response.Providers["anthropic"].Status)
+		assert.Equal(t, "connection refused", response.Providers["anthropic"].Error)
+	})
+}
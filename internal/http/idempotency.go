@@ -0,0 +1,118 @@
+package http
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jonwraymond/prompt-alchemy/internal/storage"
+	"github.com/spf13/viper"
+)
+
+const (
+	// IdempotencyKeyHeader carries a client-chosen key that makes retries
+	// of a generate request return the first response
+	IdempotencyKeyHeader = "Idempotency-Key"
+
+	// DefaultIdempotencyTTL is how long a key's response is kept when
+	// http.idempotency_ttl is unset
+	DefaultIdempotencyTTL = 24 * time.Hour
+
+	maxIdempotencyKeyLength = 255
+)
+
+// idempotencyTTL reads http.idempotency_ttl, falling back to the default
+func idempotencyTTL() time.Duration {
+	if ttl := viper.GetDuration("http.idempotency_ttl"); ttl > 0 {
+		return ttl
+	}
+	return DefaultIdempotencyTTL
+}
+
+// idempotencyRequestHash fingerprints a generate request, including the
+// query parameters that change its behavior, so a key reused for a
+// different request can be told apart from a retry
+func idempotencyRequestHash(req GenerateRequest, query string) (string, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(append(append(body, '?'), query...))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// reserveIdempotencyKey claims key for this request. It reports true if it
+// has already answered the request: with the saved response of an earlier
+// request with the key, or with an error if that request is still running,
+// was a different one, or the key could not be checked.
+func (s *SimpleServer) reserveIdempotencyKey(w http.ResponseWriter, r *http.Request, key, requestHash string) bool {
+	logger := s.requestLogger(r.Context())
+	saved, err := s.store.ReserveIdempotencyKey(r.Context(), key, requestHash, idempotencyTTL())
+	switch {
+	case errors.Is(err, storage.ErrIdempotencyKeyInFlight):
+		w.Header().Set("Retry-After", "1")
+		s.writeError(w, http.StatusConflict, "A request with this Idempotency-Key is still in progress")
+	case errors.Is(err, storage.ErrIdempotencyKeyReused):
+		s.writeError(w, http.StatusUnprocessableEntity, "Idempotency-Key was already used for a different request")
+	case err != nil:
+		logger.WithError(err).Error("Failed to reserve idempotency key")
+		s.writeError(w, http.StatusInternalServerError, "Failed to check Idempotency-Key")
+	case saved != nil:
+		logger.WithField("idempotency_key", key).Info("Replaying saved generate response")
+		w.Header().Set("Idempotent-Replayed", "true")
+		if wantsEventStream(r) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Header().Set("Cache-Control", "no-cache")
+			w.WriteHeader(http.StatusOK)
+			s.writeEvent(w, "done", json.RawMessage(saved))
+			return true
+		}
+		if format, _ := generateResponseFormat(r); format == responseFormatMarkdown {
+			var response GenerateResponse
+			if err := json.Unmarshal(saved, &response); err == nil {
+				s.writeMarkdown(w, response)
+				return true
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(saved)
+	default:
+		return false
+	}
+	return true
+}
+
+// completeIdempotencyKey saves the response to the request holding key, so
+// retries get it. If it can't be saved the key is released instead.
+func (s *SimpleServer) completeIdempotencyKey(ctx context.Context, key string, response GenerateResponse) {
+	ctx = context.WithoutCancel(ctx)
+	body, err := json.Marshal(response)
+	if err == nil {
+		err = s.store.CompleteIdempotencyKey(ctx, key, append(body, '\n'))
+	}
+	if err != nil {
+		s.requestLogger(ctx).WithError(err).WithField("idempotency_key", key).Error("Failed to save idempotent response")
+		s.releaseIdempotencyKey(ctx, key)
+	}
+}
+
+// releaseIdempotencyKey frees key after its request failed, so it can be retried
+func (s *SimpleServer) releaseIdempotencyKey(ctx context.Context, key string) {
+	if err := s.store.ReleaseIdempotencyKey(context.WithoutCancel(ctx), key); err != nil {
+		s.requestLogger(ctx).WithError(err).WithField("idempotency_key", key).Error("Failed to release idempotency key")
+	}
+}
+
+// validateIdempotencyKey checks a key sent in the Idempotency-Key header
+func validateIdempotencyKey(key string) error {
+	if len(key) > maxIdempotencyKeyLength {
+		return fmt.Errorf("%s must be at most %d characters", IdempotencyKeyHeader, maxIdempotencyKeyLength)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jonwraymond/prompt-alchemy/pkg/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingProvider counts the generations it is asked for
+type countingProvider struct {
+	pingProvider
+	calls atomic.Int32
+}
+
+func (p *countingProvider) Generate(ctx context.Context, req providers.GenerateRequest) (*providers.GenerateResponse, error) {
+	p.calls.Add(1)
+	return &providers.GenerateResponse{Content: "generated", Model: "counting-model"}, nil
+}
+
+func TestHandleGeneratePromptsIdempotency(t *testing.T) {
+	server, store := newTestServer(t)
+	provider := &countingProvider{pingProvider: pingProvider{name: "counting", available: true}}
+	require.NoError(t, server.registry.Register("counting", provider))
+
+	generate := func(key, input string) *httptest.ResponseRecorder {
+		body := `{"input":"` + input + `","phases":["prima-materia"],"count":1,"providers":{"prima-materia":"counting"}}`
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/prompts/generate?save=false", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		recorder := httptest.NewRecorder()
+		server.Router().ServeHTTP(recorder, req)
+		return recorder
+	}
+	sessionID := func(recorder *httptest.ResponseRecorder) string {
+		require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+		var response GenerateResponse
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		return response.SessionID.String()
+	}
+
+	t.Run("retry replays the first response", func(t *testing.T) {
+		provider.calls.Store(0)
+		first := generate("retry-key", "write a haiku")
+		second := generate("retry-key", "write a haiku")
+
+		assert.Equal(t, sessionID(first), sessionID(second))
+		assert.JSONEq(t, first.Body.String(), second.Body.String())
+		assert.Equal(t, "true", second.Header().Get("Idempotent-Replayed"))
+		assert.Equal(t, int32(1), provider.calls.Load(), "provider called only for the first request")
+
+		assert.NotEqual(t, sessionID(first), sessionID(generate("", "write a haiku")), "requests without a key always generate")
+	})
+
+	t.Run("duplicate while the first is in flight", func(t *testing.T) {
+		provider.calls.Store(0)
+		hash, err := idempotencyRequestHash(GenerateRequest{
+			Input:     "write a limerick",
+			Phases:    []string{"prima-materia"},
+			Count:     1,
+			Providers: map[string]string{"prima-materia": "counting"},
+		}, "save=false")
+		require.NoError(t, err)
+		saved, err := store.ReserveIdempotencyKey(context.Background(), "busy-key", hash, time.Hour)
+		require.NoError(t, err)
+		require.Nil(t, saved)
+
+		recorder := generate("busy-key", "write a limerick")
+		assert.Equal(t, http.StatusConflict, recorder.Code)
+		assert.Equal(t, int32(0), provider.calls.Load())
+	})
+
+	t.Run("key reused for a different request", func(t *testing.T) {
+		sessionID(generate("reused-key", "write a sonnet"))
+		assert.Equal(t, http.StatusUnprocessableEntity, generate("reused-key", "write an ode").Code)
+	})
+
+	t.Run("key too long", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, generate(strings.Repeat("k", maxIdempotencyKeyLength+1), "write a haiku").Code)
+	})
+}
@@ -0,0 +1,180 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// importBatchSize is how many prompts are saved per transaction
+	importBatchSize = 100
+	// maxImportLineBytes bounds a single JSONL line
+	maxImportLineBytes = 16 << 20
+)
+
+// ImportPromptsResponse reports the outcome of a JSONL prompt import
+type ImportPromptsResponse struct {
+	Imported int               `json:"imported"` // prompts saved, or that would be in a dry run
+	Failed   int               `json:"failed"`
+	DryRun   bool              `json:"dry_run"`
+	Upsert   bool              `json:"upsert"`
+	Errors   []ImportLineError `json:"errors"`
+}
+
+// ImportLineError is the reason one line of an import was skipped
+type ImportLineError struct {
+	Line  int    `json:"line"` // 1-based line number in the request body
+	Error string `json:"error"`
+}
+
+// importLine is a validated prompt waiting to be saved with its batch
+type importLine struct {
+	number int
+	prompt *models.Prompt
+}
+
+// handleImportPrompts reads one prompt per line of the request body and
+// saves them in batches. Lines that don't parse or validate are reported
+// and skipped; the rest are still imported. ?upsert=true keeps the prompts'
+// IDs, replacing existing prompts, instead of assigning new ones, and
+// ?dry_run=true validates every line without saving.
+func (s *SimpleServer) handleImportPrompts(w http.ResponseWriter, r *http.Request) {
+	logger := s.requestLogger(r.Context())
+	query := r.URL.Query()
+	upsert, err := parseOptionalBool(query.Get("upsert"))
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid 'upsert' parameter (must be true or false)")
+		return
+	}
+	dryRun, err := parseOptionalBool(query.Get("dry_run"))
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid 'dry_run' parameter (must be true or false)")
+		return
+	}
+
+	response := ImportPromptsResponse{DryRun: dryRun, Upsert: upsert, Errors: []ImportLineError{}}
+	fail := func(line int, err error) {
+		response.Failed++
+		response.Errors = append(response.Errors, ImportLineError{Line: line, Error: err.Error()})
+	}
+
+	var batch []importLine
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		prompts := make([]*models.Prompt, len(batch))
+		for i, line := range batch {
+			prompts[i] = line.prompt
+		}
+		errs, err := s.store.ImportPrompts(r.Context(), prompts, upsert)
+		if err != nil {
+			return err
+		}
+		for i, err := range errs {
+			if err != nil {
+				fail(batch[i].number, err)
+			} else {
+				response.Imported++
+			}
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxImportLineBytes)
+	number := 0
+	for scanner.Scan() {
+		number++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		prompt, err := parseImportLine(line)
+		if err != nil {
+			fail(number, err)
+			continue
+		}
+		if dryRun {
+			response.Imported++
+			continue
+		}
+
+		batch = append(batch, importLine{number: number, prompt: prompt})
+		if len(batch) >= importBatchSize {
+			if err := flush(); err != nil {
+				logger.WithError(err).WithField("line", number).Error("Prompt import failed")
+				s.writeError(w, http.StatusInternalServerError, "Failed to import prompts")
+				return
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		message := "Failed to read request body"
+		if errors.Is(err, bufio.ErrTooLong) {
+			message = "Line " + strconv.Itoa(number+1) + " is longer than the 16 MiB limit"
+		}
+		s.writeError(w, http.StatusBadRequest, message)
+		return
+	}
+	if err := flush(); err != nil {
+		logger.WithError(err).Error("Prompt import failed")
+		s.writeError(w, http.StatusInternalServerError, "Failed to import prompts")
+		return
+	}
+
+	logger.WithFields(logrus.Fields{
+		"imported": response.Imported,
+		"failed":   response.Failed,
+		"dry_run":  dryRun,
+		"upsert":   upsert,
+	}).Info("Prompts imported via HTTP API")
+	s.writeJSON(w, http.StatusOK, response)
+}
+
+// parseImportLine decodes and validates one JSONL prompt, filling in the
+// same defaults as POST /api/v1/prompts
+func parseImportLine(line []byte) (*models.Prompt, error) {
+	var prompt models.Prompt
+	if err := json.Unmarshal(line, &prompt); err != nil {
+		return nil, errors.New("invalid JSON: " + err.Error())
+	}
+	if strings.TrimSpace(prompt.Content) == "" {
+		return nil, errors.New("content is required")
+	}
+	if err := validatePromptSettings(&prompt); err != nil {
+		return nil, err
+	}
+
+	if prompt.Phase == "" {
+		prompt.Phase = models.PhasePrimaMaterial
+	}
+	if prompt.Provider == "" {
+		prompt.Provider = "unknown"
+	}
+	if prompt.Model == "" {
+		prompt.Model = "unknown"
+	}
+	if prompt.Tags == nil {
+		prompt.Tags = []string{}
+	}
+	return &prompt, nil
+}
+
+// parseOptionalBool parses a boolean query parameter, false when empty
+func parseOptionalBool(value string) (bool, error) {
+	if value == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
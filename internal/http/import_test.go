@@ -0,0 +1,133 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleImportPrompts(t *testing.T) {
+	server, store := newTestServer(t)
+
+	ctx := context.Background()
+	existing := &models.Prompt{Content: "original", Phase: models.PhaseSolutio, Provider: "openai", Model: "gpt-4"}
+	require.NoError(t, store.SavePrompt(ctx, existing))
+
+	importLines := func(query string, lines ...string) (*httptest.ResponseRecorder, ImportPromptsResponse) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/prompts/import"+query, strings.NewReader(strings.Join(lines, "\n")))
+		req.Header.Set("Content-Type", "application/x-ndjson")
+		recorder := httptest.NewRecorder()
+		server.Router().ServeHTTP(recorder, req)
+
+		var response ImportPromptsResponse
+		if recorder.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		}
+		return recorder, response
+	}
+	count := func() int {
+		n, err := store.GetPromptsCount(ctx)
+		require.NoError(t, err)
+		return n
+	}
+
+	t.Run("bad lines are reported and skipped", func(t *testing.T) {
+		before := count()
+		recorder, response := importLines("",
+			`{"content": "first", "phase": "solutio", "tags": ["imported"]}`,
+			`{"content": "broken"`,
+			``,
+			`{"phase": "solutio"}`,
+			`{"content": "too hot", "temperature": 3}`,
+			`{"content": "last"}`,
+		)
+		require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+		assert.Equal(t, 2, response.Imported)
+		assert.Equal(t, 3, response.Failed)
+		require.Len(t, response.Errors, 3)
+		assert.Equal(t, 2, response.Errors[0].Line)
+		assert.Contains(t, response.Errors[0].Error, "invalid JSON")
+		assert.Equal(t, 4, response.Errors[1].Line, "blank lines still count")
+		assert.Equal(t, "content is required", response.Errors[1].Error)
+		assert.Equal(t, 5, response.Errors[2].Line)
+		assert.Equal(t, before+2, count())
+	})
+
+	t.Run("dry run validates without saving", func(t *testing.T) {
+		before := count()
+		recorder, response := importLines("?dry_run=true",
+			`{"content": "one"}`,
+			`not json`,
+			`{"content": "two"}`,
+		)
+		require.Equal(t, http.StatusOK, recorder.Code)
+		assert.True(t, response.DryRun)
+		assert.Equal(t, 2, response.Imported)
+		assert.Equal(t, 1, response.Failed)
+		assert.Equal(t, before, count())
+	})
+
+	withID := func(content string) string {
+		return `{"id": "` + existing.ID.String() + `", "content": "` + content + `", "phase": "solutio"}`
+	}
+
+	t.Run("insert assigns new IDs", func(t *testing.T) {
+		before := count()
+		recorder, response := importLines("", withID("copied"))
+		require.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, 1, response.Imported)
+		assert.Equal(t, before+1, count())
+
+		original, err := store.GetPromptByID(ctx, existing.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "original", original.Content)
+	})
+
+	t.Run("upsert keeps IDs", func(t *testing.T) {
+		fresh := uuid.New()
+		before := count()
+		recorder, response := importLines("?upsert=true", withID("replaced"), `{"id": "`+fresh.String()+`", "content": "new"}`)
+		require.Equal(t, http.StatusOK, recorder.Code)
+		assert.True(t, response.Upsert)
+		assert.Equal(t, 2, response.Imported)
+		assert.Equal(t, before+1, count(), "only the unknown ID is added")
+
+		replaced, err := store.GetPromptByID(ctx, existing.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "replaced", replaced.Content)
+		versions, err := store.GetPromptVersions(ctx, existing.ID)
+		require.NoError(t, err)
+		assert.Len(t, versions, 1, "the replaced prompt is versioned")
+
+		_, err = store.GetPromptByID(ctx, fresh)
+		assert.NoError(t, err)
+	})
+
+	t.Run("upsert skips content stored under another ID", func(t *testing.T) {
+		before := count()
+		recorder, response := importLines("?upsert=true", withID("copied"))
+		require.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, 0, response.Imported)
+		assert.Equal(t, 1, response.Failed)
+		require.Len(t, response.Errors, 1)
+		assert.Contains(t, response.Errors[0].Error, "content is already stored")
+		assert.Equal(t, before, count())
+
+		kept, err := store.GetPromptByID(ctx, existing.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "replaced", kept.Content)
+	})
+
+	t.Run("invalid flags are rejected", func(t *testing.T) {
+		recorder, _ := importLines("?upsert=maybe", `{"content": "x"}`)
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+}
@@ -0,0 +1,122 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jonwraymond/prompt-alchemy/pkg/providers"
+)
+
+// Connection states reported for a provider. Timeout and error are kept
+// apart from disconnected so the UI can tell a slow or failing API from one
+// that is simply not configured.
+const (
+	connectionConnected    = "connected"
+	connectionDisconnected = "disconnected"
+	connectionTimeout      = "timeout"
+	connectionError        = "error"
+)
+
+// LatencyResult is the outcome of pinging a single provider
+type LatencyResult struct {
+	Status    string    `json:"status"`
+	Latency   string    `json:"latency"`
+	LatencyMS int64     `json:"latency_ms"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// LatencyProbe pings providers and caches the results for ttl, so frequent
+// status polls from the web UI don't turn into a stream of provider API calls.
+type LatencyProbe struct {
+	ttl     time.Duration
+	timeout time.Duration
+
+	mu      sync.Mutex
+	results map[string]LatencyResult
+}
+
+// NewLatencyProbe creates a probe that gives each ping up to timeout and
+// reuses results for ttl
+func NewLatencyProbe(ttl, timeout time.Duration) *LatencyProbe {
+	return &LatencyProbe{
+		ttl:     ttl,
+		timeout: timeout,
+		results: make(map[string]LatencyResult),
+	}
+}
+
+// Check returns the provider's cached latency, pinging it if the cached
+// result is missing or stale
+func (p *LatencyProbe) Check(ctx context.Context, provider providers.Provider) LatencyResult {
+	name := provider.Name()
+
+	p.mu.Lock()
+	cached, ok := p.results[name]
+	p.mu.Unlock()
+	if ok && time.Since(cached.CheckedAt) < p.ttl {
+		return cached
+	}
+
+	result := p.ping(ctx, provider)
+	if ctx.Err() != nil {
+		// The caller went away mid-ping; don't cache a bogus failure
+		return result
+	}
+
+	p.mu.Lock()
+	p.results[name] = result
+	p.mu.Unlock()
+	return result
+}
+
+// CheckAll pings the given providers concurrently
+func (p *LatencyProbe) CheckAll(ctx context.Context, list []providers.Provider) []LatencyResult {
+	results := make([]LatencyResult, len(list))
+	var wg sync.WaitGroup
+	for i, provider := range list {
+		wg.Add(1)
+		go func(idx int, provider providers.Provider) {
+			defer wg.Done()
+			results[idx] = p.Check(ctx, provider)
+		}(i, provider)
+	}
+	wg.Wait()
+	return results
+}
+
+func (p *LatencyProbe) ping(ctx context.Context, provider providers.Provider) LatencyResult {
+	result := LatencyResult{CheckedAt: time.Now()}
+	if !provider.IsAvailable() {
+		result.Status = connectionDisconnected
+		result.Latency = "N/A"
+		return result
+	}
+
+	pingCtx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	elapsed, err := provider.Ping(pingCtx)
+	result.LatencyMS = elapsed.Milliseconds()
+	result.Latency = fmt.Sprintf("%dms", result.LatencyMS)
+
+	switch {
+	case err == nil:
+		result.Status = connectionConnected
+	case errors.Is(err, providers.ErrNotConfigured):
+		result.Status = connectionDisconnected
+		result.Latency = "N/A"
+		result.LatencyMS = 0
+	case errors.Is(err, context.DeadlineExceeded) || errors.Is(pingCtx.Err(), context.DeadlineExceeded):
+		result.Status = connectionTimeout
+		result.Latency = "timeout"
+		result.Error = err.Error()
+	default:
+		result.Status = connectionError
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,110 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jonwraymond/prompt-alchemy/pkg/providers"
+	"github.com/stretchr/testify/assert"
+)
+
+// pingProvider is a Provider whose Ping behaviour is scripted by the test
+type pingProvider struct {
+	name      string
+	available bool
+	pings     int32
+	ping      func(ctx context.Context) (time.Duration, error)
+}
+
+func (p *pingProvider) Generate(ctx context.Context, req providers.GenerateRequest) (*providers.GenerateResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (p *pingProvider) GetEmbedding(ctx context.Context, text string, registry providers.RegistryInterface) ([]float32, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (p *pingProvider) Name() string             { return p.name }
+func (p *pingProvider) IsAvailable() bool        { return p.available }
+func (p *pingProvider) SupportsEmbeddings() bool { return false }
+func (p *pingProvider) SupportsStreaming() bool  { return false }
+
+func (p *pingProvider) Ping(ctx context.Context) (time.Duration, error) {
+	atomic.AddInt32(&p.pings, 1)
+	return p.ping(ctx)
+}
+
+func TestLatencyProbe(t *testing.T) {
+	tests := []struct {
+		name       string
+		available  bool
+		ping       func(ctx context.Context) (time.Duration, error)
+		wantStatus string
+		wantMS     int64
+	}{
+		{
+			name:       "connected",
+			available:  true,
+			ping:       func(ctx context.Context) (time.Duration, error) { return 42 * time.Millisecond, nil },
+			wantStatus: connectionConnected,
+			wantMS:     42,
+		},
+		{
+			name:      "timeout",
+			available: true,
+			ping: func(ctx context.Context) (time.Duration, error) {
+				<-ctx.Done()
+				return 20 * time.Millisecond, ctx.Err()
+			},
+			wantStatus: connectionTimeout,
+			wantMS:     20,
+		},
+		{
+			name:      "error",
+			available: true,
+			ping: func(ctx context.Context) (time.Duration, error) {
+				return 5 * time.Millisecond, errors.New("authentication failed: status code 401")
+			},
+			wantStatus: connectionError,
+			wantMS:     5,
+		},
+		{
+			name:       "disconnected",
+			available:  false,
+			wantStatus: connectionDisconnected,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &pingProvider{name: tt.name, available: tt.available, ping: tt.ping}
+			probe := NewLatencyProbe(time.Minute, 20*time.Millisecond)
+
+			result := probe.Check(context.Background(), provider)
+			assert.Equal(t, tt.wantStatus, result.Status)
+			assert.Equal(t, tt.wantMS, result.LatencyMS)
+		})
+	}
+}
+
+func TestLatencyProbeCachesResults(t *testing.T) {
+	provider := &pingProvider{
+		name:      "cached",
+		available: true,
+		ping:      func(ctx context.Context) (time.Duration, error) { return time.Millisecond, nil },
+	}
+	probe := NewLatencyProbe(time.Minute, time.Second)
+
+	for i := 0; i < 3; i++ {
+		probe.CheckAll(context.Background(), []providers.Provider{provider})
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&provider.pings))
+
+	expired := NewLatencyProbe(0, time.Second)
+	expired.Check(context.Background(), provider)
+	expired.Check(context.Background(), provider)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&provider.pings))
+}
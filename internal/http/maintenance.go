@@ -0,0 +1,137 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jonwraymond/prompt-alchemy/internal/storage"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// Maintenance defaults used when the maintenance.* settings are unset
+const (
+	defaultMaintenanceDecayRate         = 0.01
+	defaultMaintenanceMaxPrompts        = 10000
+	defaultMaintenanceMinRelevanceScore = 0.1
+	defaultMaintenanceMinAge            = 7 * 24 * time.Hour
+)
+
+// MaintenanceRequest selects the lifecycle maintenance to run. Unset limits
+// come from the maintenance.* settings.
+type MaintenanceRequest struct {
+	UpdateRelevance   bool     `json:"update_relevance"`
+	CleanupOld        bool     `json:"cleanup_old"`
+	DryRun            bool     `json:"dry_run"`
+	DecayRate         *float64 `json:"decay_rate,omitempty"`
+	MaxPrompts        *int     `json:"max_prompts,omitempty"`
+	MinRelevanceScore *float64 `json:"min_relevance_score,omitempty"`
+}
+
+// MaintenanceResponse counts the prompts maintenance changed. On a dry run
+// the counts and IDs are what would have changed.
+type MaintenanceResponse struct {
+	DryRun           bool        `json:"dry_run"`
+	RelevanceUpdated int         `json:"relevance_updated"`
+	Deleted          int         `json:"deleted"`
+	PromptIDs        []uuid.UUID `json:"prompt_ids,omitempty"`
+}
+
+// maintenanceFloat returns the float setting key, or fallback when unset
+func maintenanceFloat(key string, fallback float64) float64 {
+	if viper.IsSet(key) {
+		return viper.GetFloat64(key)
+	}
+	return fallback
+}
+
+// handleMaintenance decays relevance scores and removes old, low-relevance
+// prompts beyond the configured cap. Since it deletes prompts it is only
+// served with API key authentication enabled, like the configuration reload.
+func (s *SimpleServer) handleMaintenance(w http.ResponseWriter, r *http.Request) {
+	logger := s.requestLogger(r.Context())
+
+	if !s.config.EnableAuth {
+		s.writeError(w, http.StatusForbidden, "Maintenance requires API key authentication (http.enable_auth)")
+		return
+	}
+
+	var req MaintenanceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid JSON in request body")
+		return
+	}
+
+	decayRate := maintenanceFloat("maintenance.decay_rate", defaultMaintenanceDecayRate)
+	if req.DecayRate != nil {
+		decayRate = *req.DecayRate
+	}
+	minRelevance := maintenanceFloat("maintenance.min_relevance_score", defaultMaintenanceMinRelevanceScore)
+	if req.MinRelevanceScore != nil {
+		minRelevance = *req.MinRelevanceScore
+	}
+	maxPrompts := defaultMaintenanceMaxPrompts
+	if viper.IsSet("maintenance.max_prompts") {
+		maxPrompts = viper.GetInt("maintenance.max_prompts")
+	}
+	if req.MaxPrompts != nil {
+		maxPrompts = *req.MaxPrompts
+	}
+	minAge := defaultMaintenanceMinAge
+	if viper.IsSet("maintenance.min_age") {
+		minAge = viper.GetDuration("maintenance.min_age")
+	}
+
+	fields := map[string]string{}
+	if !req.UpdateRelevance && !req.CleanupOld {
+		fields["update_relevance"] = "at least one of update_relevance and cleanup_old is required"
+	}
+	if decayRate < 0 || decayRate >= 1 {
+		fields["decay_rate"] = "decay_rate must be at least 0 and below 1"
+	}
+	if maxPrompts < 0 {
+		fields["max_prompts"] = "max_prompts must not be negative"
+	}
+	if minRelevance < 0 || minRelevance > 1 {
+		fields["min_relevance_score"] = "min_relevance_score must be between 0 and 1"
+	}
+	if len(fields) > 0 {
+		s.writeValidationError(w, "Invalid maintenance request", fields)
+		return
+	}
+
+	response := MaintenanceResponse{DryRun: req.DryRun}
+	if req.UpdateRelevance {
+		updated, err := s.store.UpdateRelevanceScores(r.Context(), decayRate, req.DryRun)
+		if err != nil {
+			logger.WithError(err).Error("Failed to decay relevance scores")
+			s.writeError(w, http.StatusInternalServerError, "Failed to decay relevance scores")
+			return
+		}
+		response.RelevanceUpdated = updated
+	}
+	if req.CleanupOld {
+		result, err := s.store.CleanupOldPrompts(r.Context(), storage.CleanupOptions{
+			MaxPrompts:        maxPrompts,
+			MinRelevanceScore: minRelevance,
+			MinAge:            minAge,
+			DryRun:            req.DryRun,
+		})
+		if err != nil {
+			logger.WithError(err).Error("Failed to clean up old prompts")
+			s.writeError(w, http.StatusInternalServerError, "Failed to clean up old prompts")
+			return
+		}
+		response.Deleted = result.Deleted
+		response.PromptIDs = result.PromptIDs
+	}
+
+	logger.WithFields(logrus.Fields{
+		"dry_run":           req.DryRun,
+		"relevance_updated": response.RelevanceUpdated,
+		"deleted":           response.Deleted,
+	}).Info("Lifecycle maintenance completed via HTTP API")
+	s.writeJSON(w, http.StatusOK, response)
+}
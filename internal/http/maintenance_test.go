@@ -0,0 +1,100 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jonwraymond/prompt-alchemy/internal/storage"
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleMaintenance(t *testing.T) {
+	defer viper.Reset()
+	viper.Set("http.enable_auth", true)
+	viper.Set("http.api_keys", []string{"write-key"})
+	server, store := newTestServer(t)
+
+	ctx := context.Background()
+	monthAgo := time.Now().AddDate(0, -1, 0)
+	stale := &models.Prompt{Content: "Stale and unused", Phase: models.PhasePrimaMaterial, RelevanceScore: 0.05, CreatedAt: monthAgo}
+	valued := &models.Prompt{Content: "Old but relevant", Phase: models.PhasePrimaMaterial, RelevanceScore: 0.9, CreatedAt: monthAgo}
+	recent := &models.Prompt{Content: "New and unscored", Phase: models.PhasePrimaMaterial, RelevanceScore: 0.05}
+	for _, prompt := range []*models.Prompt{stale, valued, recent} {
+		require.NoError(t, store.SavePrompt(ctx, prompt))
+	}
+
+	maintain := func(body string) (*httptest.ResponseRecorder, MaintenanceResponse) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/maintenance", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-API-Key", "write-key")
+		recorder := httptest.NewRecorder()
+		server.Router().ServeHTTP(recorder, req)
+		var response MaintenanceResponse
+		if recorder.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		}
+		return recorder, response
+	}
+
+	t.Run("dry run", func(t *testing.T) {
+		recorder, response := maintain(`{"update_relevance":true,"cleanup_old":true,"dry_run":true}`)
+		require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+		assert.True(t, response.DryRun)
+		assert.Equal(t, 0, response.RelevanceUpdated, "every prompt was just saved")
+		assert.Equal(t, 1, response.Deleted)
+		assert.Equal(t, stale.ID, response.PromptIDs[0])
+
+		_, err := store.GetPrompt(ctx, stale.ID.String())
+		assert.NoError(t, err, "a dry run deletes nothing")
+	})
+
+	t.Run("max prompts", func(t *testing.T) {
+		recorder, response := maintain(`{"cleanup_old":true,"dry_run":true,"max_prompts":1,"min_relevance_score":0}`)
+		require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+		assert.Equal(t, 1, response.Deleted, "the recent prompt is over the cap but too new")
+		assert.Equal(t, stale.ID, response.PromptIDs[0])
+	})
+
+	t.Run("cleanup", func(t *testing.T) {
+		recorder, response := maintain(`{"cleanup_old":true}`)
+		require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+		assert.False(t, response.DryRun)
+		assert.Equal(t, 1, response.Deleted)
+
+		_, err := store.GetPrompt(ctx, stale.ID.String())
+		assert.ErrorIs(t, err, storage.ErrPromptNotFound)
+		for _, kept := range []*models.Prompt{valued, recent} {
+			_, err := store.GetPrompt(ctx, kept.ID.String())
+			assert.NoError(t, err)
+		}
+	})
+
+	t.Run("invalid request", func(t *testing.T) {
+		recorder, _ := maintain(`{"dry_run":true}`)
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "update_relevance")
+
+		recorder, _ = maintain(`{"update_relevance":true,"decay_rate":1.5}`)
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "decay_rate")
+	})
+
+	t.Run("refused without authentication", func(t *testing.T) {
+		viper.Set("http.enable_auth", false)
+		unauthenticated, _ := newTestServer(t)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/maintenance", strings.NewReader(`{"cleanup_old":true}`))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		unauthenticated.Router().ServeHTTP(recorder, req)
+		assert.Equal(t, http.StatusForbidden, recorder.Code, recorder.Body.String())
+		assert.Contains(t, recorder.Body.String(), ErrCodeForbidden)
+	})
+}
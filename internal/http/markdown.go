@@ -0,0 +1,80 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+)
+
+// Response formats of the generate endpoint
+const (
+	responseFormatJSON     = "json"
+	responseFormatMarkdown = "markdown"
+)
+
+// generateResponseFormat reads the format a generate response is wanted
+// in: ?format= when given, otherwise markdown when the Accept header asks
+// for text/markdown, and JSON by default
+func generateResponseFormat(r *http.Request) (string, error) {
+	switch format := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("format"))); format {
+	case "":
+		if strings.Contains(r.Header.Get("Accept"), "text/markdown") {
+			return responseFormatMarkdown, nil
+		}
+		return responseFormatJSON, nil
+	case responseFormatJSON:
+		return responseFormatJSON, nil
+	case responseFormatMarkdown, "md":
+		return responseFormatMarkdown, nil
+	default:
+		return "", fmt.Errorf("unknown format %q (use json or markdown)", format)
+	}
+}
+
+// topPrompts picks one prompt per phase, in phase order, for a markdown
+// response: the selected prompt in its phase, otherwise the phase's best
+// ranked prompt, or its first when none was ranked
+func topPrompts(response GenerateResponse) []models.Prompt {
+	scores := make(map[uuid.UUID]float64, len(response.Rankings))
+	for _, ranking := range response.Rankings {
+		if ranking.Prompt != nil {
+			scores[ranking.Prompt.ID] = ranking.Score
+		}
+	}
+
+	var phases []models.Phase
+	top := make(map[models.Phase]models.Prompt)
+	for _, p := range response.Prompts {
+		current, seen := top[p.Phase]
+		if !seen {
+			phases = append(phases, p.Phase)
+			top[p.Phase] = p
+			continue
+		}
+		if score, ok := scores[p.ID]; ok && score > scores[current.ID] {
+			top[p.Phase] = p
+		}
+	}
+	if selected := response.Selected; selected != nil {
+		if _, ok := top[selected.Phase]; !ok {
+			phases = append(phases, selected.Phase)
+		}
+		top[selected.Phase] = *selected
+	}
+
+	prompts := make([]models.Prompt, len(phases))
+	for i, phase := range phases {
+		prompts[i] = top[phase]
+	}
+	return prompts
+}
+
+// writeMarkdown writes the top prompts of a generate response as markdown
+func (s *SimpleServer) writeMarkdown(w http.ResponseWriter, response GenerateResponse) {
+	w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(models.RenderMarkdown(topPrompts(response))))
+}
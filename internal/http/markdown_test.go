@@ -0,0 +1,68 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateResponseFormat(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		query  string
+		accept string
+		want   string
+	}{
+		{"default", "", "", responseFormatJSON},
+		{"query", "?format=markdown", "", responseFormatMarkdown},
+		{"short query", "?format=md", "", responseFormatMarkdown},
+		{"accept header", "", "text/markdown", responseFormatMarkdown},
+		{"query wins over accept", "?format=json", "text/markdown", responseFormatJSON},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/prompts/generate"+tc.query, nil)
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			format, err := generateResponseFormat(req)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, format)
+		})
+	}
+
+	_, err := generateResponseFormat(httptest.NewRequest(http.MethodPost, "/api/v1/prompts/generate?format=yaml", nil))
+	assert.ErrorContains(t, err, "unknown format")
+}
+
+func TestTopPrompts(t *testing.T) {
+	prompt := func(phase models.Phase, content string) models.Prompt {
+		return models.Prompt{ID: uuid.New(), Phase: phase, Content: content}
+	}
+	first := prompt(models.PhasePrimaMaterial, "first")
+	ranked := prompt(models.PhasePrimaMaterial, "ranked")
+	solutio := prompt(models.PhaseSolutio, "solutio")
+	coagulatio := prompt(models.PhaseCoagulatio, "coagulatio")
+	selected := prompt(models.PhaseCoagulatio, "selected")
+
+	top := topPrompts(GenerateResponse{
+		Prompts: []models.Prompt{first, ranked, solutio, coagulatio, selected},
+		Rankings: []models.PromptRanking{
+			{Prompt: &first, Score: 0.4},
+			{Prompt: &ranked, Score: 0.9},
+			{Prompt: &coagulatio, Score: 0.95},
+		},
+		Selected: &selected,
+	})
+
+	contents := make([]string, len(top))
+	for i, p := range top {
+		contents[i] = p.Content
+	}
+	assert.Equal(t, []string{"ranked", "solutio", "selected"}, contents,
+		"the best ranked prompt of each phase, the selected one replacing its phase's")
+}
@@ -0,0 +1,38 @@
+package http
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/jonwraymond/prompt-alchemy/internal/observability/metrics"
+)
+
+// SetMetrics records generate requests in m and serves it at /metrics. Pass
+// m to the engine's SetGenerationObserver too to record each generation.
+func (s *SimpleServer) SetMetrics(m *metrics.Metrics) {
+	s.metrics = m
+}
+
+// handleMetrics serves the Prometheus metrics set with SetMetrics
+func (s *SimpleServer) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if s.metrics == nil {
+		s.writeError(w, http.StatusNotFound, "Metrics are not enabled")
+		return
+	}
+	s.metrics.Handler().ServeHTTP(w, r)
+}
+
+// recordGenerateRequest counts a generate request by the engine's outcome
+func (s *SimpleServer) recordGenerateRequest(ctx context.Context, err error) {
+	if s.metrics == nil {
+		return
+	}
+	outcome := "success"
+	switch {
+	case err != nil && ctx.Err() != nil:
+		outcome = "canceled"
+	case err != nil:
+		outcome = "error"
+	}
+	s.metrics.RecordGenerateRequest(outcome)
+}
@@ -8,6 +8,9 @@ import (
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 	"github.com/google/uuid"
+	"github.com/jonwraymond/prompt-alchemy/internal/httputil"
+	"github.com/jonwraymond/prompt-alchemy/internal/log"
+	"github.com/jonwraymond/prompt-alchemy/internal/observability/tracing"
 	"github.com/sirupsen/logrus"
 )
 
@@ -20,6 +23,8 @@ type MiddlewareConfig struct {
 	EnableRateLimit bool
 	RequestsPerMin  int
 	Burst           int
+	RateLimiter     RateLimiter // replaces the in-memory limiter when set
+	MaxBodyBytes    int64       // DefaultMaxBodyBytes when zero
 }
 
 // SetupMiddleware configures and returns common middleware stack
@@ -27,7 +32,10 @@ func SetupMiddleware(logger *logrus.Logger, config MiddlewareConfig) []func(http
 	var middlewares []func(http.Handler) http.Handler
 
 	// Request ID middleware (always first)
-	middlewares = append(middlewares, middleware.RequestID)
+	middlewares = append(middlewares, middleware.RequestID, PropagateRequestID())
+
+	// Tracing middleware, so later middleware and handlers log the trace ID
+	middlewares = append(middlewares, tracing.Middleware)
 
 	// Real IP middleware
 	middlewares = append(middlewares, middleware.RealIP)
@@ -46,8 +54,8 @@ func SetupMiddleware(logger *logrus.Logger, config MiddlewareConfig) []func(http
 		corsMiddleware := cors.Handler(cors.Options{
 			AllowedOrigins:   config.CORSOrigins,
 			AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-			AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-Request-ID"},
-			ExposedHeaders:   []string{"Link", "X-Request-ID"},
+			AllowedHeaders:   []string{"Accept", "Authorization", "Cache-Control", "Content-Type", "Idempotency-Key", "X-CSRF-Token", "X-Request-ID"},
+			ExposedHeaders:   []string{"Link", "X-Request-ID", tracing.TraceIDHeader},
 			AllowCredentials: true,
 			MaxAge:           300,
 		})
@@ -59,9 +67,22 @@ func SetupMiddleware(logger *logrus.Logger, config MiddlewareConfig) []func(http
 		middlewares = append(middlewares, APIKeyAuth(config.APIKeys, logger))
 	}
 
+	// Request body size limit
+	middlewares = append(middlewares, LimitBody(config.MaxBodyBytes, func(w http.ResponseWriter, status int, message string) {
+		if status == http.StatusRequestEntityTooLarge {
+			httputil.RequestEntityTooLarge(w, message)
+			return
+		}
+		httputil.BadRequest(w, message)
+	}))
+
 	// Rate limiting middleware
 	if config.EnableRateLimit {
-		middlewares = append(middlewares, RateLimit(config.RequestsPerMin, config.Burst, logger))
+		if config.RateLimiter != nil {
+			middlewares = append(middlewares, RateLimitWith(config.RateLimiter, logger))
+		} else {
+			middlewares = append(middlewares, RateLimit(config.RequestsPerMin, config.Burst, logger))
+		}
 	}
 
 	return middlewares
@@ -87,7 +108,7 @@ func RequestLogger(logger *logrus.Logger) func(next http.Handler) http.Handler {
 
 			// Log request
 			duration := time.Since(start)
-			logger.WithFields(logrus.Fields{
+			log.WithContext(r.Context(), logger).WithFields(logrus.Fields{
 				"request_id":  reqID,
 				"method":      r.Method,
 				"path":        r.URL.Path,
@@ -157,22 +178,24 @@ func APIKeyAuth(validKeys []string, logger *logrus.Logger) func(next http.Handle
 
 // RateLimit provides simple in-memory rate limiting middleware
 func RateLimit(requestsPerMin int, burst int, logger *logrus.Logger) func(next http.Handler) http.Handler {
-	// Simple in-memory rate limiter using a map
-	// For production, use Redis or similar distributed storage
-	clients := make(map[string]*ClientLimiter)
+	return RateLimitWith(NewMemoryRateLimiter(requestsPerMin, burst), logger)
+}
 
+// RateLimitWith rejects requests the limiter refuses with 429 Too Many
+// Requests. If the limiter fails the request is let through, so an outage of
+// a shared backend doesn't take the API down with it.
+func RateLimitWith(limiter RateLimiter, logger *logrus.Logger) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			clientIP := r.RemoteAddr
+			clientIP := rateLimitKey(r)
 
-			// Get or create client limiter
-			limiter, exists := clients[clientIP]
-			if !exists {
-				limiter = NewClientLimiter(requestsPerMin, burst)
-				clients[clientIP] = limiter
+			allowed, err := limiter.Allow(r.Context(), clientIP)
+			if err != nil {
+				logger.WithError(err).WithField("remote_addr", clientIP).Warn("Rate limiter unavailable, allowing request")
+				allowed = true
 			}
 
-			if !limiter.Allow() {
+			if !allowed {
 				logger.WithFields(logrus.Fields{
 					"remote_addr": clientIP,
 					"path":        r.URL.Path,
@@ -248,6 +271,21 @@ func SecurityHeaders() func(next http.Handler) http.Handler {
 	}
 }
 
+// RequireJSON rejects request bodies that are not declared as JSON with
+// 415 Unsupported Media Type, instead of letting the handler fail to decode
+// them with a vague 400
+func RequireJSON() func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !httputil.HasJSONBody(r) {
+				httputil.UnsupportedMediaType(w, "Content-Type must be application/json")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 // RequestID adds request ID if not present
 func RequestID() func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -258,7 +296,24 @@ func RequestID() func(next http.Handler) http.Handler {
 				r.Header.Set("X-Request-ID", reqID)
 			}
 			w.Header().Set("X-Request-ID", reqID)
-			next.ServeHTTP(w, r)
+			next.ServeHTTP(w, r.WithContext(log.WithRequestID(r.Context(), reqID)))
+		})
+	}
+}
+
+// PropagateRequestID carries the ID assigned by chi's RequestID middleware
+// into the context the engine, providers and storage log with, and echoes
+// it in the X-Request-ID response header
+func PropagateRequestID() func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			reqID := middleware.GetReqID(r.Context())
+			if reqID == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Set("X-Request-ID", reqID)
+			next.ServeHTTP(w, r.WithContext(log.WithRequestID(r.Context(), reqID)))
 		})
 	}
 }
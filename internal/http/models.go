@@ -0,0 +1,134 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jonwraymond/prompt-alchemy/pkg/providers"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultModelsCacheTTL is how long a provider's live model list is reused
+const DefaultModelsCacheTTL = 10 * time.Minute
+
+// modelsListTimeout bounds a single live model listing
+const modelsListTimeout = 10 * time.Second
+
+// Sources of a model list. A live list was just fetched from the provider's
+// API, a cached one earlier; a static list is the built-in one, returned
+// when the provider can't list its models.
+const (
+	modelsSourceLive   = "live"
+	modelsSourceCache  = "cache"
+	modelsSourceStatic = "static"
+)
+
+// ModelsResponse lists a provider's models. Error says why the live listing
+// failed when the static list was returned instead.
+type ModelsResponse struct {
+	Provider    string    `json:"provider"`
+	Models      []string  `json:"models"`
+	Source      string    `json:"source"`
+	Error       string    `json:"error,omitempty"`
+	RetrievedAt time.Time `json:"retrieved_at"`
+}
+
+// cachedModels is a provider's model list and when it was fetched
+type cachedModels struct {
+	models    []string
+	fetchedAt time.Time
+}
+
+// ModelCatalog lists providers' models from their APIs and caches each list
+// for ttl, so the models endpoint doesn't query a provider on every call.
+// Failed listings are not cached.
+type ModelCatalog struct {
+	ttl time.Duration
+
+	mu    sync.Mutex
+	lists map[string]cachedModels
+}
+
+// NewModelCatalog creates a catalog that reuses each list for ttl
+func NewModelCatalog(ttl time.Duration) *ModelCatalog {
+	if ttl <= 0 {
+		ttl = DefaultModelsCacheTTL
+	}
+	return &ModelCatalog{
+		ttl:   ttl,
+		lists: make(map[string]cachedModels),
+	}
+}
+
+// List returns the provider's models, from the cache when its list is
+// fresh, and reports whether it was
+func (c *ModelCatalog) List(ctx context.Context, provider providers.Provider) ([]string, bool, error) {
+	name := provider.Name()
+
+	c.mu.Lock()
+	cached, ok := c.lists[name]
+	c.mu.Unlock()
+	if ok && time.Since(cached.fetchedAt) < c.ttl {
+		return cached.models, true, nil
+	}
+
+	lister, ok := providers.AsModelLister(provider)
+	if !ok {
+		return nil, false, fmt.Errorf("%s cannot list its models", name)
+	}
+	listCtx, cancel := context.WithTimeout(ctx, modelsListTimeout)
+	defer cancel()
+	models, err := lister.ListModels(listCtx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	c.mu.Lock()
+	c.lists[name] = cachedModels{models: models, fetchedAt: time.Now()}
+	c.mu.Unlock()
+	return models, false, nil
+}
+
+// handleListModels lists the models of the provider named by the provider
+// query parameter, queried live from its API and cached for
+// http.models_cache_ttl. When the provider can't list its models or the
+// listing fails, the built-in list is returned instead.
+func (s *SimpleServer) handleListModels(w http.ResponseWriter, r *http.Request) {
+	logger := s.requestLogger(r.Context())
+
+	name := r.URL.Query().Get("provider")
+	if name == "" {
+		s.writeValidationError(w, "Invalid models request", map[string]string{"provider": "provider is required"})
+		return
+	}
+	provider, err := s.registry.Get(name)
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, fmt.Sprintf("Provider %s not found", name))
+		return
+	}
+
+	response := ModelsResponse{Provider: name, Source: modelsSourceLive, RetrievedAt: time.Now()}
+	models, cached, err := s.modelCatalog.List(r.Context(), provider)
+	switch {
+	case err != nil:
+		logger.WithError(err).WithField("provider", name).Warn("Failed to list models live, returning the built-in list")
+		response.Models = s.getProviderModels(name)
+		response.Source = modelsSourceStatic
+		response.Error = err.Error()
+	case cached:
+		response.Models = models
+		response.Source = modelsSourceCache
+	default:
+		response.Models = models
+	}
+
+	logger.WithFields(logrus.Fields{
+		"provider": name,
+		"source":   response.Source,
+		"models":   len(response.Models),
+	}).Info("Models requested via HTTP API")
+	s.writeJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,85 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jonwraymond/prompt-alchemy/pkg/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// listingProvider lists models, or fails with err, counting its listings
+type listingProvider struct {
+	pingProvider
+	models   []string
+	err      error
+	listings int
+}
+
+func (p *listingProvider) ListModels(ctx context.Context) ([]string, error) {
+	p.listings++
+	return p.models, p.err
+}
+
+func TestHandleListModels(t *testing.T) {
+	server, _ := newTestServer(t)
+	live := &listingProvider{pingProvider: pingProvider{name: "live", available: true}, models: []string{"model-a", "model-b"}}
+	failing := &listingProvider{pingProvider: pingProvider{name: providers.ProviderOpenAI, available: true}, err: errors.New("connection refused")}
+	require.NoError(t, server.registry.Register(live.name, live))
+	require.NoError(t, server.registry.Register(failing.name, failing))
+	require.NoError(t, server.registry.Register(providers.ProviderPerplexity, &pingProvider{name: providers.ProviderPerplexity, available: true}))
+
+	listModels := func(t *testing.T, query string) (int, ModelsResponse) {
+		t.Helper()
+		recorder := httptest.NewRecorder()
+		server.Router().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/models"+query, nil))
+		var response ModelsResponse
+		if recorder.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		}
+		return recorder.Code, response
+	}
+
+	t.Run("lists live models and caches them", func(t *testing.T) {
+		status, response := listModels(t, "?provider=live")
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, []string{"model-a", "model-b"}, response.Models)
+		assert.Equal(t, modelsSourceLive, response.Source)
+
+		status, response = listModels(t, "?provider=live")
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, []string{"model-a", "model-b"}, response.Models)
+		assert.Equal(t, modelsSourceCache, response.Source)
+		assert.Equal(t, 1, live.listings, "the second request is served from the cache")
+	})
+
+	t.Run("falls back to the static list on error", func(t *testing.T) {
+		status, response := listModels(t, "?provider="+providers.ProviderOpenAI)
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, server.getProviderModels(providers.ProviderOpenAI), response.Models)
+		assert.Equal(t, modelsSourceStatic, response.Source)
+		assert.Contains(t, response.Error, "connection refused")
+
+		listModels(t, "?provider="+providers.ProviderOpenAI)
+		assert.Equal(t, 2, failing.listings, "failed listings are not cached")
+	})
+
+	t.Run("falls back to the static list without a models API", func(t *testing.T) {
+		status, response := listModels(t, "?provider="+providers.ProviderPerplexity)
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, providers.PerplexityModels(), response.Models)
+		assert.Equal(t, modelsSourceStatic, response.Source)
+	})
+
+	t.Run("requires a registered provider", func(t *testing.T) {
+		status, _ := listModels(t, "")
+		assert.Equal(t, http.StatusBadRequest, status)
+		status, _ = listModels(t, "?provider=unknown")
+		assert.Equal(t, http.StatusNotFound, status)
+	})
+}
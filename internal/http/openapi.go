@@ -0,0 +1,456 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jonwraymond/prompt-alchemy/internal/storage"
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+)
+
+// openAPIVersion is the version of the API the document describes
+const openAPIVersion = "1.0.0"
+
+// openAPIParam is a query parameter of an operation
+type openAPIParam struct {
+	Name        string
+	Type        string // string, integer, number or boolean
+	Description string
+	Required    bool
+}
+
+// openAPIOperation documents a route of /api/v1. Request and Response are
+// the Go types the handler decodes and encodes, from which the schemas are
+// derived so they can't drift from the handlers; a nil Response is a
+// free-form object. Path parameters are taken from Path.
+type openAPIOperation struct {
+	Method      string
+	Path        string
+	Tag         string
+	Summary     string
+	Query       []openAPIParam
+	Request     reflect.Type
+	Status      int
+	Response    reflect.Type
+	ContentType string // of the response, application/json when empty
+}
+
+// Query parameters shared by several operations
+var (
+	promptFilterParams = []openAPIParam{
+		{Name: "q", Type: "string", Description: "Text to search for"},
+		{Name: "phase", Type: "string", Description: "Only prompts of this phase"},
+		{Name: "provider", Type: "string", Description: "Only prompts of this provider"},
+		{Name: "tags", Type: "string", Description: "Comma-separated tags the prompts must have"},
+		{Name: "since", Type: "string", Description: "Only prompts created on or after this date (YYYY-MM-DD)"},
+	}
+	limitParam  = openAPIParam{Name: "limit", Type: "integer", Description: "Maximum number of results"}
+	dryRunParam = openAPIParam{Name: "dry_run", Type: "boolean", Description: "Report what would change without changing it"}
+)
+
+// openAPIOperations lists every /api/v1 route. Adding a route without an
+// entry here fails the OpenAPI tests.
+var openAPIOperations = []openAPIOperation{
+	{Method: http.MethodGet, Path: "/health", Tag: "System", Summary: "Check the server's health",
+		Query: []openAPIParam{{Name: "deep", Type: "boolean", Description: "Also check storage and every provider"}}},
+	{Method: http.MethodGet, Path: "/status", Tag: "System", Summary: "Report the server's status"},
+	{Method: http.MethodGet, Path: "/info", Tag: "System", Summary: "Describe the server and its capabilities"},
+	{Method: http.MethodGet, Path: "/openapi.json", Tag: "System", Summary: "This OpenAPI document"},
+	{Method: http.MethodGet, Path: "/docs", Tag: "System", Summary: "Swagger UI for this API", ContentType: "text/html"},
+
+	{Method: http.MethodPost, Path: "/generate", Tag: "Generation", Summary: "Generate prompts (alias of /prompts/generate)",
+		Request: reflect.TypeFor[GenerateRequest](), Response: reflect.TypeFor[GenerateResponse]()},
+	{Method: http.MethodPost, Path: "/prompts/generate", Tag: "Generation", Summary: "Generate prompts through the alchemical phases",
+		Query: []openAPIParam{
+			{Name: "save", Type: "boolean", Description: "Save the generated prompts (default true)"},
+			{Name: "stream", Type: "boolean", Description: "Stream phase progress as Server-Sent Events"},
+			{Name: "dry_run", Type: "boolean", Description: "Validate and resolve the request without generating"},
+			{Name: "explain", Type: "boolean", Description: "Include each ranking's score breakdown"},
+		},
+		Request: reflect.TypeFor[GenerateRequest](), Response: reflect.TypeFor[GenerateResponse]()},
+	{Method: http.MethodPost, Path: "/prompts/batch", Tag: "Generation", Summary: "Generate prompts for several inputs",
+		Request: reflect.TypeFor[BatchGenerateRequest](), Response: reflect.TypeFor[BatchGenerateResponse]()},
+	{Method: http.MethodPost, Path: "/prompts/optimize", Tag: "Generation", Summary: "Iteratively optimize a prompt",
+		Request: reflect.TypeFor[OptimizeRequest](), Response: reflect.TypeFor[OptimizeResponse]()},
+
+	{Method: http.MethodGet, Path: "/prompts", Tag: "Prompts", Summary: "List saved prompts, newest first",
+		Query: []openAPIParam{
+			limitParam,
+			{Name: "cursor", Type: "string", Description: "Cursor of the next page, from next_cursor"},
+			{Name: "phase", Type: "string", Description: "Only prompts of this phase"},
+			{Name: "provider", Type: "string", Description: "Only prompts of this provider"},
+		},
+		Response: reflect.TypeFor[ListPromptsResponse]()},
+	{Method: http.MethodPost, Path: "/prompts", Tag: "Prompts", Summary: "Save a prompt",
+		Request: reflect.TypeFor[models.Prompt](), Status: http.StatusCreated, Response: reflect.TypeFor[models.Prompt]()},
+	{Method: http.MethodGet, Path: "/prompts/search", Tag: "Prompts", Summary: "Search saved prompts",
+		Query: append(append([]openAPIParam{}, promptFilterParams...),
+			limitParam,
+			openAPIParam{Name: "semantic", Type: "boolean", Description: "Search by embedding similarity"},
+			openAPIParam{Name: "similarity", Type: "number", Description: "Minimum similarity of semantic results, 0 to 1"},
+		),
+		Response: reflect.TypeFor[SearchPromptsResponse]()},
+	{Method: http.MethodPost, Path: "/prompts/search-by-vector", Tag: "Prompts", Summary: "Search saved prompts by embedding",
+		Request: reflect.TypeFor[SearchByVectorRequest](), Response: reflect.TypeFor[SearchPromptsResponse]()},
+	{Method: http.MethodGet, Path: "/prompts/compare", Tag: "Prompts", Summary: "Compare two prompts",
+		Query: []openAPIParam{
+			{Name: "a", Type: "string", Description: "ID of the first prompt", Required: true},
+			{Name: "b", Type: "string", Description: "ID of the second prompt", Required: true},
+		},
+		Response: reflect.TypeFor[PromptComparison]()},
+	{Method: http.MethodGet, Path: "/prompts/export", Tag: "Prompts", Summary: "Export prompts as JSONL or CSV",
+		Query: append(append([]openAPIParam{}, promptFilterParams...),
+			limitParam,
+			openAPIParam{Name: "format", Type: "string", Description: "jsonl (default) or csv"},
+		),
+		ContentType: "application/x-ndjson"},
+	{Method: http.MethodPost, Path: "/prompts/import", Tag: "Prompts", Summary: "Import prompts from JSONL",
+		Query: []openAPIParam{
+			dryRunParam,
+			{Name: "upsert", Type: "boolean", Description: "Replace prompts that already exist"},
+		},
+		Response: reflect.TypeFor[ImportPromptsResponse]()},
+	{Method: http.MethodGet, Path: "/prompts/{id}", Tag: "Prompts", Summary: "Get a prompt",
+		Query: []openAPIParam{
+			{Name: "include_embedding", Type: "boolean", Description: "Include the prompt's embedding"},
+			{Name: "include_metrics", Type: "boolean", Description: "Include the prompt's metrics"},
+		},
+		Response: reflect.TypeFor[PromptDetailResponse]()},
+	{Method: http.MethodPut, Path: "/prompts/{id}", Tag: "Prompts", Summary: "Update a prompt, keeping its previous version",
+		Request: reflect.TypeFor[models.Prompt](), Response: reflect.TypeFor[models.Prompt]()},
+	{Method: http.MethodDelete, Path: "/prompts/{id}", Tag: "Prompts", Summary: "Delete a prompt", Status: http.StatusNoContent},
+	{Method: http.MethodGet, Path: "/prompts/{id}/versions", Tag: "Prompts", Summary: "List a prompt's versions",
+		Response: reflect.TypeFor[PromptVersionsResponse]()},
+	{Method: http.MethodPost, Path: "/prompts/{id}/feedback", Tag: "Prompts", Summary: "Record feedback on a prompt",
+		Request: reflect.TypeFor[FeedbackRequest](), Response: reflect.TypeFor[FeedbackResponse]()},
+	{Method: http.MethodPost, Path: "/prompts/{id}/versions/{version}/restore", Tag: "Prompts", Summary: "Restore a prompt's version",
+		Response: reflect.TypeFor[models.Prompt]()},
+	{Method: http.MethodPost, Path: "/prompts/{id}/regenerate", Tag: "Prompts", Summary: "Regenerate a prompt from its stored parameters",
+		Request: reflect.TypeFor[RegenerateRequest](), Status: http.StatusCreated, Response: reflect.TypeFor[RegenerateResponse]()},
+
+	{Method: http.MethodGet, Path: "/sessions/{id}", Tag: "Sessions", Summary: "Get the prompts of a generation session",
+		Response: reflect.TypeFor[SessionResponse]()},
+	{Method: http.MethodGet, Path: "/sessions/{id}/lineage", Tag: "Sessions", Summary: "Get the lineage of a generation session",
+		Response: reflect.TypeFor[SessionLineageResponse]()},
+	{Method: http.MethodPost, Path: "/relationships/discover", Tag: "Sessions", Summary: "Discover prompts related to a prompt",
+		Request: reflect.TypeFor[DiscoverRelationshipsRequest](), Response: reflect.TypeFor[DiscoverRelationshipsResponse]()},
+	{Method: http.MethodPost, Path: "/context/documents", Tag: "Generation", Summary: "Ingest a context document for retrieval",
+		Request: reflect.TypeFor[ContextDocumentRequest](), Status: http.StatusCreated, Response: reflect.TypeFor[models.ContextDocument]()},
+
+	{Method: http.MethodGet, Path: "/stats", Tag: "Catalog", Summary: "Database statistics",
+		Query: []openAPIParam{
+			{Name: "include_relationships", Type: "boolean", Description: "Include relationship statistics"},
+			{Name: "include_enhancements", Type: "boolean", Description: "Include enhancement statistics"},
+			{Name: "include_usage", Type: "boolean", Description: "Include usage statistics"},
+		},
+		Response: reflect.TypeFor[storage.Statistics]()},
+	{Method: http.MethodGet, Path: "/tags", Tag: "Catalog", Summary: "List tags with their prompt counts",
+		Query: []openAPIParam{{Name: "min_count", Type: "integer", Description: "Only tags on at least this many prompts"}}},
+	{Method: http.MethodGet, Path: "/personas", Tag: "Catalog", Summary: "List the personas"},
+	{Method: http.MethodGet, Path: "/providers", Tag: "Providers", Summary: "List the providers",
+		Response: reflect.TypeFor[ProvidersResponse]()},
+	{Method: http.MethodPost, Path: "/providers/{name}/test", Tag: "Providers", Summary: "Test a provider with a live generation and embedding",
+		Response: reflect.TypeFor[ProviderTestResponse]()},
+	{Method: http.MethodGet, Path: "/models", Tag: "Providers", Summary: "List a provider's models, queried live",
+		Query:    []openAPIParam{{Name: "provider", Type: "string", Description: "The provider to list", Required: true}},
+		Response: reflect.TypeFor[ModelsResponse]()},
+
+	{Method: http.MethodPost, Path: "/admin/reindex", Tag: "Admin", Summary: "Start rebuilding the vector index",
+		Status: http.StatusAccepted, Response: reflect.TypeFor[storage.ReindexStatus]()},
+	{Method: http.MethodGet, Path: "/admin/reindex/status", Tag: "Admin", Summary: "Progress of the reindex",
+		Response: reflect.TypeFor[storage.ReindexStatus]()},
+	{Method: http.MethodPost, Path: "/admin/backfill-embeddings", Tag: "Admin", Summary: "Start embedding prompts without embeddings",
+		Query: []openAPIParam{
+			dryRunParam,
+			{Name: "batch_size", Type: "integer", Description: "Prompts embedded per batch"},
+		},
+		Status: http.StatusAccepted, Response: reflect.TypeFor[storage.BackfillStatus]()},
+	{Method: http.MethodGet, Path: "/admin/backfill-embeddings/status", Tag: "Admin", Summary: "Progress of the embedding backfill",
+		Response: reflect.TypeFor[storage.BackfillStatus]()},
+	{Method: http.MethodPost, Path: "/admin/reload", Tag: "Admin", Summary: "Reload the provider configuration",
+		Response: reflect.TypeFor[ReloadResponse]()},
+	{Method: http.MethodPost, Path: "/admin/maintenance", Tag: "Admin", Summary: "Decay relevance scores and clean up old prompts",
+		Request: reflect.TypeFor[MaintenanceRequest](), Response: reflect.TypeFor[MaintenanceResponse]()},
+	{Method: http.MethodPost, Path: "/admin/backup", Tag: "Admin", Summary: "Back up the database to storage.backup_dir, or stream the backup when it is unset",
+		Status: http.StatusCreated, Response: reflect.TypeFor[BackupResponse]()},
+	{Method: http.MethodPost, Path: "/admin/restore", Tag: "Admin", Summary: "Replace the database with a backup from storage.backup_dir",
+		Request: reflect.TypeFor[RestoreRequest](), Response: reflect.TypeFor[RestoreResponse]()},
+}
+
+// openAPIPathParam matches the parameters of a route path
+var openAPIPathParam = regexp.MustCompile(`\{([a-z_]+)\}`)
+
+// openAPISchemas derives JSON schemas from Go types. Named structs become
+// components referenced by name, other types are inlined.
+type openAPISchemas struct {
+	components map[string]interface{}
+	names      map[reflect.Type]string
+}
+
+// schema returns the schema of t, adding the components it refers to
+func (g *openAPISchemas) schema(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t {
+	case reflect.TypeFor[time.Time]():
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case reflect.TypeFor[uuid.UUID]():
+		return map[string]interface{}{"type": "string", "format": "uuid"}
+	case reflect.TypeFor[time.Duration]():
+		return map[string]interface{}{"type": "integer", "description": "Duration in nanoseconds"}
+	case reflect.TypeFor[json.RawMessage]():
+		return map[string]interface{}{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + g.component(t)}
+	default:
+		// interface{} and anything else JSON can't describe more closely
+		return map[string]interface{}{}
+	}
+}
+
+// component adds the named struct t to the components, once, and returns
+// its name. A name already taken by a type of another package is prefixed
+// with the package's name.
+func (g *openAPISchemas) component(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+	name := t.Name()
+	if _, taken := g.components[name]; taken {
+		pkg := path.Base(t.PkgPath())
+		name = strings.ToUpper(pkg[:1]) + pkg[1:] + name
+	}
+	g.names[t] = name
+	g.components[name] = map[string]interface{}{} // placeholder for recursive types
+	g.components[name] = g.object(t)
+	return name
+}
+
+// object returns the schema of struct t's JSON fields. Embedded structs
+// without a JSON name contribute their fields, as encoding/json does.
+func (g *openAPISchemas) object(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	var addFields func(t reflect.Type)
+	addFields = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			tag := field.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			name, opts, _ := strings.Cut(tag, ",")
+			fieldType := field.Type
+			for fieldType.Kind() == reflect.Pointer {
+				fieldType = fieldType.Elem()
+			}
+			if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct {
+				addFields(fieldType)
+				continue
+			}
+			if !field.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			if strings.Contains(opts, "string") {
+				properties[name] = map[string]interface{}{"type": "string"}
+				continue
+			}
+			properties[name] = g.schema(field.Type)
+		}
+	}
+	addFields(t)
+	return map[string]interface{}{"type": "object", "properties": properties}
+}
+
+// BuildOpenAPISpec returns the OpenAPI 3 document of /api/v1
+func BuildOpenAPISpec() map[string]interface{} {
+	schemas := &openAPISchemas{components: map[string]interface{}{}, names: map[reflect.Type]string{}}
+	errorSchema := schemas.schema(reflect.TypeFor[ErrorResponse]())
+	errorResponse := map[string]interface{}{
+		"description": "Error",
+		"content":     map[string]interface{}{"application/json": map[string]interface{}{"schema": errorSchema}},
+	}
+
+	paths := map[string]interface{}{}
+	for _, op := range openAPIOperations {
+		var parameters []interface{}
+		for _, match := range openAPIPathParam.FindAllStringSubmatch(op.Path, -1) {
+			parameters = append(parameters, map[string]interface{}{
+				"name": match[1], "in": "path", "required": true,
+				"schema": map[string]interface{}{"type": "string"},
+			})
+		}
+		for _, param := range op.Query {
+			parameters = append(parameters, map[string]interface{}{
+				"name": param.Name, "in": "query", "required": param.Required,
+				"description": param.Description,
+				"schema":      map[string]interface{}{"type": param.Type},
+			})
+		}
+
+		status := op.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		success := map[string]interface{}{"description": http.StatusText(status)}
+		if status != http.StatusNoContent {
+			contentType := op.ContentType
+			if contentType == "" {
+				contentType = "application/json"
+			}
+			schema := map[string]interface{}{"type": "object"}
+			if op.Response != nil {
+				schema = schemas.schema(op.Response)
+			} else if contentType != "application/json" {
+				schema = map[string]interface{}{"type": "string"}
+			}
+			success["content"] = map[string]interface{}{contentType: map[string]interface{}{"schema": schema}}
+		}
+
+		operation := map[string]interface{}{
+			"tags":        []string{op.Tag},
+			"summary":     op.Summary,
+			"operationId": openAPIOperationID(op),
+			"responses": map[string]interface{}{
+				fmt.Sprint(status): success,
+				"default":          errorResponse,
+			},
+		}
+		if len(parameters) > 0 {
+			operation["parameters"] = parameters
+		}
+		if op.Request != nil {
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": schemas.schema(op.Request)},
+				},
+			}
+		}
+
+		fullPath := "/api/v1" + op.Path
+		item, ok := paths[fullPath].(map[string]interface{})
+		if !ok {
+			item = map[string]interface{}{}
+			paths[fullPath] = item
+		}
+		item[strings.ToLower(op.Method)] = operation
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "Prompt Alchemy API",
+			"description": "Generate, rank, store and search prompts through the alchemical phases.",
+			"version":     openAPIVersion,
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": schemas.components,
+			// Keys are only checked with http.enable_auth on
+			"securitySchemes": map[string]interface{}{
+				"apiKey":     map[string]interface{}{"type": "apiKey", "in": "header", "name": "X-API-Key"},
+				"bearerAuth": map[string]interface{}{"type": "http", "scheme": "bearer"},
+			},
+		},
+		"security": []interface{}{
+			map[string]interface{}{"apiKey": []string{}},
+			map[string]interface{}{"bearerAuth": []string{}},
+		},
+	}
+}
+
+// openAPIOperationID names an operation after its method and path, such as
+// getPromptsIdVersions
+func openAPIOperationID(op openAPIOperation) string {
+	var id strings.Builder
+	id.WriteString(strings.ToLower(op.Method))
+	for _, part := range strings.FieldsFunc(op.Path, func(r rune) bool {
+		return r == '/' || r == '{' || r == '}' || r == '-' || r == '_' || r == '.'
+	}) {
+		id.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return id.String()
+}
+
+var (
+	openAPISpecOnce sync.Once
+	openAPISpecJSON []byte
+)
+
+// handleOpenAPISpec serves the OpenAPI document, built on first use
+func (s *SimpleServer) handleOpenAPISpec(w http.ResponseWriter, r *http.Request) {
+	openAPISpecOnce.Do(func() {
+		spec, err := json.Marshal(BuildOpenAPISpec())
+		if err != nil {
+			s.logger.WithError(err).Error("Failed to encode OpenAPI document")
+			return
+		}
+		openAPISpecJSON = spec
+	})
+	if openAPISpecJSON == nil {
+		s.writeError(w, http.StatusInternalServerError, "Failed to build the OpenAPI document")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(openAPISpecJSON)
+}
+
+// swaggerUIPage renders /api/v1/openapi.json with Swagger UI from a CDN
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Prompt Alchemy API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js" crossorigin></script>
+  <script>
+    window.onload = () => {
+      window.ui = SwaggerUIBundle({url: "/api/v1/openapi.json", dom_id: "#swagger-ui"});
+    };
+  </script>
+</body>
+</html>
+`
+
+// handleAPIDocs serves Swagger UI for the OpenAPI document
+func (s *SimpleServer) handleAPIDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write([]byte(swaggerUIPage))
+}
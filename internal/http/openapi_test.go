@@ -0,0 +1,116 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// collectRefs appends every $ref in the JSON value v to refs
+func collectRefs(v interface{}, refs *[]string) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if ref, ok := value.(string); ok && key == "$ref" {
+				*refs = append(*refs, ref)
+				continue
+			}
+			collectRefs(value, refs)
+		}
+	case []interface{}:
+		for _, value := range v {
+			collectRefs(value, refs)
+		}
+	}
+}
+
+func TestOpenAPISpec(t *testing.T) {
+	server, _ := newTestServer(t)
+
+	recorder := httptest.NewRecorder()
+	server.Router().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/openapi.json", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+
+	var spec struct {
+		OpenAPI    string                            `json:"openapi"`
+		Info       map[string]interface{}            `json:"info"`
+		Paths      map[string]map[string]interface{} `json:"paths"`
+		Components struct {
+			Schemas map[string]interface{} `json:"schemas"`
+		} `json:"components"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &spec))
+	assert.True(t, strings.HasPrefix(spec.OpenAPI, "3."), spec.OpenAPI)
+	assert.NotEmpty(t, spec.Info["title"])
+	assert.NotEmpty(t, spec.Info["version"])
+
+	t.Run("documents the generate route", func(t *testing.T) {
+		generate, ok := spec.Paths["/api/v1/prompts/generate"]["post"].(map[string]interface{})
+		require.True(t, ok, "POST /api/v1/prompts/generate is documented")
+		var refs []string
+		collectRefs(generate, &refs)
+		assert.Contains(t, refs, "#/components/schemas/GenerateRequest")
+		assert.Contains(t, refs, "#/components/schemas/GenerateResponse")
+		assert.Contains(t, refs, "#/components/schemas/ErrorResponse")
+
+		request := spec.Components.Schemas["GenerateRequest"].(map[string]interface{})
+		properties := request["properties"].(map[string]interface{})
+		for _, field := range []string{"input", "phases", "count", "providers", "phase_selection"} {
+			assert.Contains(t, properties, field, "schema fields follow the JSON tags")
+		}
+	})
+
+	t.Run("every reference resolves", func(t *testing.T) {
+		var refs []string
+		collectRefs(spec.Paths, &refs)
+		collectRefs(spec.Components.Schemas, &refs)
+		require.NotEmpty(t, refs)
+		for _, ref := range refs {
+			name, ok := strings.CutPrefix(ref, "#/components/schemas/")
+			require.True(t, ok, ref)
+			assert.Contains(t, spec.Components.Schemas, name)
+		}
+	})
+
+	t.Run("every operation has responses", func(t *testing.T) {
+		for path, item := range spec.Paths {
+			for method, op := range item {
+				operation := op.(map[string]interface{})
+				assert.NotEmpty(t, operation["responses"], "%s %s", method, path)
+				assert.NotEmpty(t, operation["operationId"], "%s %s", method, path)
+			}
+		}
+	})
+
+	t.Run("documents every route", func(t *testing.T) {
+		routes := map[string]bool{}
+		require.NoError(t, chi.Walk(server.Router(), func(method, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+			if strings.HasPrefix(route, "/api/v1/") {
+				routes[strings.ToLower(method)+" "+strings.TrimSuffix(route, "/")] = true
+			}
+			return nil
+		}))
+		documented := map[string]bool{}
+		for path, item := range spec.Paths {
+			for method := range item {
+				documented[method+" "+path] = true
+			}
+		}
+		assert.Equal(t, routes, documented)
+	})
+
+	t.Run("serves Swagger UI", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		server.Router().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/docs", nil))
+		require.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Header().Get("Content-Type"), "text/html")
+		assert.Contains(t, recorder.Body.String(), "/api/v1/openapi.json")
+	})
+}
@@ -0,0 +1,210 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/jonwraymond/prompt-alchemy/internal/optimizer"
+	"github.com/jonwraymond/prompt-alchemy/internal/storage"
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/jonwraymond/prompt-alchemy/pkg/providers"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// Defaults and bounds of an optimize request, matching the MCP optimize_prompt tool
+const (
+	defaultOptimizeIterations  = 5
+	maxOptimizeIterations      = 20
+	defaultOptimizeTargetScore = 8.5
+)
+
+// OptimizeRequest asks for a prompt to be iteratively improved with a judge
+// scoring each version
+type OptimizeRequest struct {
+	Prompt        string   `json:"prompt"`
+	Task          string   `json:"task,omitempty"`
+	Persona       string   `json:"persona,omitempty"`
+	TargetModel   string   `json:"target_model,omitempty"`
+	JudgeProvider string   `json:"judge_provider,omitempty"`
+	MaxIterations int      `json:"max_iterations,omitempty"`
+	TargetScore   *float64 `json:"target_score,omitempty"`
+}
+
+// OptimizeIteration is one improved version of the prompt and its score
+type OptimizeIteration struct {
+	Iteration    int      `json:"iteration"`
+	Prompt       string   `json:"prompt"`
+	Score        float64  `json:"score"`
+	Reasoning    string   `json:"reasoning"`
+	Improvements []string `json:"improvements,omitempty"`
+}
+
+// OptimizeResponse is the best prompt found and how each iteration scored.
+// Scores are out of 10.
+type OptimizeResponse struct {
+	OriginalPrompt  string              `json:"original_prompt"`
+	OptimizedPrompt string              `json:"optimized_prompt"`
+	OriginalScore   float64             `json:"original_score"`
+	FinalScore      float64             `json:"final_score"`
+	Improvement     float64             `json:"improvement"`
+	TargetScore     float64             `json:"target_score"`
+	TargetReached   bool                `json:"target_reached"`
+	ConvergedAt     int                 `json:"converged_at"` // iteration that met target_score, -1 if none did
+	Iterations      []OptimizeIteration `json:"iterations"`
+	Provider        string              `json:"provider"`
+	JudgeProvider   string              `json:"judge_provider"`
+	Duration        string              `json:"duration"`
+}
+
+// handleOptimizePrompt runs the meta-prompt optimizer on a prompt: the
+// provider rewrites it from the judge's feedback until a version meets the
+// target score or the iterations run out. Iterations are returned either way.
+func (s *SimpleServer) handleOptimizePrompt(w http.ResponseWriter, r *http.Request) {
+	logger := s.requestLogger(r.Context())
+	var req OptimizeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+	if req.Prompt == "" {
+		s.writeError(w, http.StatusBadRequest, "prompt is required")
+		return
+	}
+	if req.MaxIterations == 0 {
+		req.MaxIterations = defaultOptimizeIterations
+	}
+	if req.MaxIterations < 1 || req.MaxIterations > maxOptimizeIterations {
+		s.writeError(w, http.StatusBadRequest, fmt.Sprintf("max_iterations must be between 1 and %d", maxOptimizeIterations))
+		return
+	}
+	targetScore := defaultOptimizeTargetScore
+	if req.TargetScore != nil {
+		targetScore = *req.TargetScore
+	}
+	if targetScore < 0 || targetScore > 10 {
+		s.writeError(w, http.StatusBadRequest, "target_score must be between 0 and 10")
+		return
+	}
+	if req.Persona == "" {
+		req.Persona = string(models.PersonaCode)
+	}
+	if _, err := models.GetPersona(models.PersonaType(req.Persona)); err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	providerName, provider, err := s.optimizeProvider()
+	if err != nil {
+		s.writeErrorCode(w, http.StatusServiceUnavailable, ErrCodeProviderUnavailable, err.Error())
+		return
+	}
+
+	// An explicit judge must exist; the configured one falls back to the provider
+	judgeName, judgeProvider := providerName, provider
+	if req.JudgeProvider != "" {
+		judge, err := s.registry.Get(req.JudgeProvider)
+		if err != nil || !judge.IsAvailable() {
+			s.writeErrorCode(w, http.StatusBadRequest, ErrCodeProviderUnavailable, fmt.Sprintf("judge provider %q is not available", req.JudgeProvider))
+			return
+		}
+		judgeName, judgeProvider = req.JudgeProvider, judge
+	} else if configured := viper.GetString("optimize.judge_provider"); configured != "" {
+		if judge, err := s.registry.Get(configured); err == nil {
+			judgeName, judgeProvider = configured, judge
+		}
+	}
+
+	// Without storage there is no optimization history to learn from
+	var store storage.StorageInterface
+	if s.store != nil {
+		store = s.store
+	}
+	metaOptimizer := optimizer.NewMetaPromptOptimizer(provider, judgeProvider, store, s.registry)
+
+	modelFamily := models.ModelFamilyGeneric
+	if req.TargetModel != "" {
+		modelFamily = models.DetectModelFamily(req.TargetModel)
+	}
+	request := &optimizer.OptimizationRequest{
+		OriginalPrompt:  req.Prompt,
+		TaskDescription: req.Task,
+		Examples:        []optimizer.OptimizationExample{},
+		Constraints:     []string{"Maintain clarity", "Preserve intent", "Improve effectiveness"},
+		ModelFamily:     modelFamily,
+		PersonaType:     models.PersonaType(req.Persona),
+		MaxIterations:   req.MaxIterations,
+		TargetScore:     targetScore,
+		OptimizationGoals: map[string]float64{
+			"factual_accuracy": 0.3,
+			"code_quality":     0.3,
+			"helpfulness":      0.2,
+			"conciseness":      0.2,
+		},
+	}
+
+	result, err := metaOptimizer.OptimizePrompt(r.Context(), request)
+	if err != nil {
+		logger.WithError(err).Error("Prompt optimization failed")
+		s.writeAPIError(w, apiErrorFrom(err, fmt.Sprintf("Optimization failed: %v", err)))
+		return
+	}
+
+	iterations := make([]OptimizeIteration, len(result.Iterations))
+	for i, iter := range result.Iterations {
+		iterations[i] = OptimizeIteration{
+			Iteration:    iter.Iteration,
+			Prompt:       iter.Prompt,
+			Score:        optimizer.ScoreOutOfTen(iter.Score),
+			Reasoning:    iter.ChangeReasoning,
+			Improvements: iter.Improvements,
+		}
+	}
+	originalScore := optimizer.ScoreOutOfTen(result.OriginalScore)
+	finalScore := optimizer.ScoreOutOfTen(result.FinalScore)
+
+	logger.WithFields(logrus.Fields{
+		"provider":       providerName,
+		"judge_provider": judgeName,
+		"iterations":     len(iterations),
+		"final_score":    finalScore,
+		"converged_at":   result.ConvergedAt,
+	}).Info("Prompt optimization finished")
+
+	s.writeJSON(w, http.StatusOK, OptimizeResponse{
+		OriginalPrompt:  req.Prompt,
+		OptimizedPrompt: result.OptimizedPrompt,
+		OriginalScore:   originalScore,
+		FinalScore:      finalScore,
+		Improvement:     finalScore - originalScore,
+		TargetScore:     targetScore,
+		TargetReached:   result.ConvergedAt >= 0,
+		ConvergedAt:     result.ConvergedAt,
+		Iterations:      iterations,
+		Provider:        providerName,
+		JudgeProvider:   judgeName,
+		Duration:        result.TotalTime.Round(time.Millisecond).String(),
+	})
+}
+
+// optimizeProvider returns the provider that rewrites prompts:
+// generation.default_provider, or the first available one by name
+func (s *SimpleServer) optimizeProvider() (string, providers.Provider, error) {
+	name := viper.GetString("generation.default_provider")
+	if name == "" {
+		available := s.registry.ListAvailable()
+		if len(available) == 0 {
+			return "", nil, fmt.Errorf("no providers available")
+		}
+		sort.Strings(available)
+		name = available[0]
+	}
+	provider, err := s.registry.Get(name)
+	if err != nil || !provider.IsAvailable() {
+		return "", nil, fmt.Errorf("provider %q is not available", name)
+	}
+	return name, provider, nil
+}
@@ -0,0 +1,98 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jonwraymond/prompt-alchemy/pkg/providers"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rewritingProvider answers test prompts and numbers each rewrite it is asked for
+type rewritingProvider struct {
+	pingProvider
+	rewrites int32
+}
+
+func (p *rewritingProvider) Generate(ctx context.Context, req providers.GenerateRequest) (*providers.GenerateResponse, error) {
+	if strings.Contains(req.Prompt, "IMPROVED PROMPT:") {
+		n := atomic.AddInt32(&p.rewrites, 1)
+		return &providers.GenerateResponse{Content: fmt.Sprintf("REASONING: more specific\n\nIMPROVED PROMPT:\nversion %d", n)}, nil
+	}
+	return &providers.GenerateResponse{Content: "a test response"}, nil
+}
+
+// scoringJudge returns its scores, on a 0-1 scale, in order
+type scoringJudge struct {
+	pingProvider
+	scores []float64
+	calls  int32
+}
+
+func (j *scoringJudge) Generate(ctx context.Context, req providers.GenerateRequest) (*providers.GenerateResponse, error) {
+	score := j.scores[atomic.AddInt32(&j.calls, 1)-1]
+	return &providers.GenerateResponse{Content: fmt.Sprintf(`{"overall_score": %v, "reasoning": "ok", "improvements": ["add detail"]}`, score)}, nil
+}
+
+func TestHandleOptimizePrompt(t *testing.T) {
+	viper.Set("generation.default_provider", "writer")
+	defer viper.Reset()
+	server, _ := newTestServer(t)
+	require.NoError(t, server.registry.Register("writer", &rewritingProvider{pingProvider: pingProvider{name: "writer", available: true}}))
+	judge := &scoringJudge{pingProvider: pingProvider{name: "judge", available: true}, scores: []float64{0.5, 0.7, 0.6}}
+	require.NoError(t, server.registry.Register("judge", judge))
+	require.NoError(t, server.registry.Register("offline", &pingProvider{name: "offline"}))
+
+	send := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/prompts/optimize", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		server.Router().ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	t.Run("iterations are returned when the target is not reached", func(t *testing.T) {
+		recorder := send(`{"prompt":"write a parser","task":"parse CSV","judge_provider":"judge","max_iterations":2,"target_score":9}`)
+		require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+
+		var response OptimizeResponse
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		assert.Equal(t, "writer", response.Provider)
+		assert.Equal(t, "judge", response.JudgeProvider)
+		assert.False(t, response.TargetReached)
+		assert.Equal(t, -1, response.ConvergedAt)
+
+		// Scores are reported out of 10 and the best version wins
+		require.Len(t, response.Iterations, 2)
+		assert.Equal(t, "version 1", response.Iterations[0].Prompt)
+		assert.InDelta(t, 7.0, response.Iterations[0].Score, 1e-9)
+		assert.Equal(t, "more specific", response.Iterations[0].Reasoning)
+		assert.Equal(t, []string{"add detail"}, response.Iterations[0].Improvements)
+		assert.InDelta(t, 6.0, response.Iterations[1].Score, 1e-9)
+		assert.Equal(t, "version 1", response.OptimizedPrompt)
+		assert.InDelta(t, 5.0, response.OriginalScore, 1e-9)
+		assert.InDelta(t, 7.0, response.FinalScore, 1e-9)
+		assert.InDelta(t, 2.0, response.Improvement, 1e-9)
+		assert.Equal(t, int32(3), atomic.LoadInt32(&judge.calls))
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, send(`{"task":"parse CSV"}`).Code)
+		assert.Equal(t, http.StatusBadRequest, send(`{"prompt":"write a parser","max_iterations":50}`).Code)
+		assert.Equal(t, http.StatusBadRequest, send(`{"prompt":"write a parser","target_score":11}`).Code)
+		assert.Equal(t, http.StatusBadRequest, send(`{"prompt":"write a parser","persona":"poet"}`).Code)
+
+		recorder := send(`{"prompt":"write a parser","judge_provider":"missing"}`)
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `judge provider \"missing\" is not available`)
+		assert.Equal(t, http.StatusBadRequest, send(`{"prompt":"write a parser","judge_provider":"offline"}`).Code)
+	})
+}
@@ -0,0 +1,38 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+)
+
+// PersonaSummary describes a persona a generate request can name
+type PersonaSummary struct {
+	Name             string                  `json:"name"`
+	DisplayName      string                  `json:"display_name"`
+	Description      string                  `json:"description"`
+	DefaultReasoning models.ReasoningPattern `json:"default_reasoning"`
+	ScoringCriteria  string                  `json:"scoring_criteria,omitempty"`
+	Custom           bool                    `json:"custom"`
+}
+
+// handleListPersonas lists the built-in personas followed by the custom ones
+// loaded from personas.custom and personas.dir
+func (s *SimpleServer) handleListPersonas(w http.ResponseWriter, r *http.Request) {
+	list := models.ListPersonas()
+	personas := make([]PersonaSummary, 0, len(list))
+	for _, persona := range list {
+		personas = append(personas, PersonaSummary{
+			Name:             string(persona.Type),
+			DisplayName:      persona.Name,
+			Description:      persona.Description,
+			DefaultReasoning: persona.DefaultReasoning,
+			ScoringCriteria:  persona.ScoringCriteria,
+			Custom:           persona.Custom,
+		})
+	}
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"personas": personas,
+		"total":    len(personas),
+	})
+}
@@ -0,0 +1,93 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jonwraymond/prompt-alchemy/internal/personas"
+	"github.com/jonwraymond/prompt-alchemy/internal/selection"
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/jonwraymond/prompt-alchemy/pkg/providers"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// systemPromptProvider records the system prompts it is sent
+type systemPromptProvider struct {
+	pingProvider
+	mu            sync.Mutex
+	systemPrompts []string
+}
+
+func (p *systemPromptProvider) Generate(ctx context.Context, req providers.GenerateRequest) (*providers.GenerateResponse, error) {
+	p.mu.Lock()
+	p.systemPrompts = append(p.systemPrompts, req.SystemPrompt)
+	p.mu.Unlock()
+	return &providers.GenerateResponse{Content: "generated", Model: "recording-model"}, nil
+}
+
+func TestCustomPersonas(t *testing.T) {
+	t.Cleanup(func() {
+		viper.Reset()
+		_ = models.SetCustomPersonas(nil)
+	})
+	viper.Set("personas.custom", map[string]interface{}{
+		"reviewer": map[string]interface{}{
+			"display_name":  "Code Reviewer",
+			"description":   "Reviews pull requests",
+			"system_prompt": "Point out missing tests.",
+		},
+	})
+	_, err := personas.Load(selection.BuiltinWeightPresets())
+	require.NoError(t, err)
+
+	server, _ := newTestServer(t)
+	provider := &systemPromptProvider{pingProvider: pingProvider{name: "recording", available: true}}
+	require.NoError(t, server.registry.Register("recording", provider))
+
+	t.Run("listed", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		server.Router().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/personas", nil))
+		require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+
+		var response struct {
+			Personas []PersonaSummary `json:"personas"`
+			Total    int              `json:"total"`
+		}
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		assert.Equal(t, 5, response.Total)
+		last := response.Personas[len(response.Personas)-1]
+		assert.Equal(t, PersonaSummary{
+			Name:             "reviewer",
+			DisplayName:      "Code Reviewer",
+			Description:      "Reviews pull requests",
+			DefaultReasoning: models.ReasoningCoT,
+			Custom:           true,
+		}, last)
+	})
+
+	t.Run("used in a generate request", func(t *testing.T) {
+		body := `{"input":"review my change","phases":["prima-materia"],"count":1,"persona":"reviewer","providers":{"prima-materia":"recording"}}`
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/prompts/generate?save=false", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		server.Router().ServeHTTP(recorder, req)
+		require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+
+		var response GenerateResponse
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		require.Len(t, response.Prompts, 1)
+		assert.Equal(t, "reviewer", response.Prompts[0].PersonaUsed)
+
+		provider.mu.Lock()
+		defer provider.mu.Unlock()
+		require.Len(t, provider.systemPrompts, 1)
+		assert.True(t, strings.HasSuffix(provider.systemPrompts[0], "\n\nPoint out missing tests."), provider.systemPrompts[0])
+	})
+}
@@ -0,0 +1,151 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jonwraymond/prompt-alchemy/pkg/providers"
+)
+
+// providerTestTimeout bounds each call of a provider test
+const providerTestTimeout = 30 * time.Second
+
+// providerTestPrompt is the generation a provider test asks for, kept tiny
+// so testing costs next to nothing
+const providerTestPrompt = "Reply with the single word OK."
+
+// providerTestSampleLength caps the sample output returned by a test
+const providerTestSampleLength = 200
+
+// ProviderCheck is the outcome of one live call made by a provider test
+type ProviderCheck struct {
+	Success    bool   `json:"success"`
+	LatencyMS  int64  `json:"latency_ms"`
+	Output     string `json:"output,omitempty"`
+	Dimensions int    `json:"dimensions,omitempty"`
+	Error      string `json:"error,omitempty"`
+	ErrorClass string `json:"error_class,omitempty"`
+}
+
+// ProviderTestResponse reports whether a provider works with its current
+// configuration. Embedding is omitted for providers without embeddings.
+type ProviderTestResponse struct {
+	Provider   string         `json:"provider"`
+	Success    bool           `json:"success"`
+	Model      string         `json:"model,omitempty"`
+	Generation ProviderCheck  `json:"generation"`
+	Embedding  *ProviderCheck `json:"embedding,omitempty"`
+	TestedAt   time.Time      `json:"tested_at"`
+}
+
+// handleTestProvider makes a minimal live generation with the named
+// provider, and an embedding when it supports them, so credentials can be
+// checked after configuring them. Failed calls are reported in the response
+// rather than as an error status; only an unknown provider is an error.
+func (s *SimpleServer) handleTestProvider(w http.ResponseWriter, r *http.Request) {
+	logger := s.requestLogger(r.Context())
+
+	name := chi.URLParam(r, "name")
+	provider, err := s.registry.Get(name)
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, fmt.Sprintf("Provider %s not found", name))
+		return
+	}
+
+	response := ProviderTestResponse{
+		Provider: name,
+		Model:    providers.ConfiguredModel(name),
+		TestedAt: time.Now(),
+	}
+	if !provider.IsAvailable() {
+		response.Generation = failedCheck(0, fmt.Errorf("%s: %w", name, providers.ErrNotConfigured))
+		s.writeJSON(w, http.StatusOK, response)
+		return
+	}
+
+	response.Generation = testGeneration(r.Context(), provider, &response.Model)
+	if provider.SupportsEmbeddings() {
+		check := testEmbedding(r.Context(), provider, s.registry)
+		response.Embedding = &check
+	}
+	response.Success = response.Generation.Success && (response.Embedding == nil || response.Embedding.Success)
+
+	entry := logger.WithField("provider", name)
+	if response.Success {
+		entry.Info("Provider test passed")
+	} else {
+		entry.Warn("Provider test failed")
+	}
+	s.writeJSON(w, http.StatusOK, response)
+}
+
+// testGeneration asks provider for a tiny generation, setting model to the
+// one that served it when the provider reports it
+func testGeneration(ctx context.Context, provider providers.Provider, model *string) ProviderCheck {
+	ctx, cancel := context.WithTimeout(ctx, providerTestTimeout)
+	defer cancel()
+
+	start := time.Now()
+	resp, err := provider.Generate(ctx, providers.GenerateRequest{
+		Prompt:      providerTestPrompt,
+		Temperature: 0,
+		MaxTokens:   16,
+	})
+	elapsed := time.Since(start)
+	if err != nil {
+		return failedCheck(elapsed, err)
+	}
+	if resp.Model != "" {
+		*model = resp.Model
+	}
+	return ProviderCheck{
+		Success:   true,
+		LatencyMS: elapsed.Milliseconds(),
+		Output:    sampleOutput(resp.Content),
+	}
+}
+
+// testEmbedding embeds a short text with provider. The text is unique to the
+// test, so the embedding cache can't answer for the provider.
+func testEmbedding(ctx context.Context, provider providers.Provider, registry providers.RegistryInterface) ProviderCheck {
+	ctx, cancel := context.WithTimeout(ctx, providerTestTimeout)
+	defer cancel()
+
+	text := fmt.Sprintf("Prompt Alchemy provider test %d", time.Now().UnixNano())
+	start := time.Now()
+	embedding, err := provider.GetEmbedding(ctx, text, registry)
+	elapsed := time.Since(start)
+	switch {
+	case err != nil:
+		return failedCheck(elapsed, err)
+	case len(embedding) == 0:
+		return failedCheck(elapsed, errors.New("provider returned an empty embedding"))
+	}
+	return ProviderCheck{
+		Success:    true,
+		LatencyMS:  elapsed.Milliseconds(),
+		Dimensions: len(embedding),
+	}
+}
+
+// sampleOutput returns content cut to providerTestSampleLength characters
+func sampleOutput(content string) string {
+	runes := []rune(strings.TrimSpace(content))
+	if len(runes) <= providerTestSampleLength {
+		return string(runes)
+	}
+	return string(runes[:providerTestSampleLength]) + "..."
+}
+
+func failedCheck(elapsed time.Duration, err error) ProviderCheck {
+	return ProviderCheck{
+		LatencyMS:  elapsed.Milliseconds(),
+		Error:      err.Error(),
+		ErrorClass: providers.ErrorClass(err),
+	}
+}
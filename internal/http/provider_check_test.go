@@ -0,0 +1,109 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jonwraymond/prompt-alchemy/pkg/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// checkedProvider answers provider tests with scripted errors: nil errors
+// succeed with a fixed generation and embedding
+type checkedProvider struct {
+	pingProvider
+	generateErr error
+	embedErr    error
+	embeds      int
+}
+
+func (p *checkedProvider) Generate(ctx context.Context, req providers.GenerateRequest) (*providers.GenerateResponse, error) {
+	if p.generateErr != nil {
+		return nil, p.generateErr
+	}
+	return &providers.GenerateResponse{Content: "OK", Model: p.name + "-model"}, nil
+}
+
+func (p *checkedProvider) GetEmbedding(ctx context.Context, text string, registry providers.RegistryInterface) ([]float32, error) {
+	p.embeds++
+	if p.embedErr != nil {
+		return nil, p.embedErr
+	}
+	return []float32{0.1, 0.2, 0.3}, nil
+}
+
+func (p *checkedProvider) SupportsEmbeddings() bool { return true }
+
+func TestHandleTestProvider(t *testing.T) {
+	server, _ := newTestServer(t)
+
+	unauthorized := errors.New("openai API call failed: status code 401: Incorrect API key provided")
+	working := &checkedProvider{pingProvider: pingProvider{name: "working", available: true}}
+	rejected := &checkedProvider{pingProvider: pingProvider{name: "rejected", available: true}, generateErr: unauthorized, embedErr: unauthorized}
+	unconfigured := &checkedProvider{pingProvider: pingProvider{name: "unconfigured"}}
+	for _, provider := range []*checkedProvider{working, rejected, unconfigured} {
+		require.NoError(t, server.registry.Register(provider.name, provider))
+	}
+
+	test := func(name string) (*httptest.ResponseRecorder, ProviderTestResponse) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/providers/"+name+"/test", nil)
+		recorder := httptest.NewRecorder()
+		server.Router().ServeHTTP(recorder, req)
+		var response ProviderTestResponse
+		if recorder.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		}
+		return recorder, response
+	}
+
+	t.Run("success", func(t *testing.T) {
+		recorder, response := test("working")
+		require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+		assert.True(t, response.Success)
+		assert.Equal(t, "working-model", response.Model)
+		assert.True(t, response.Generation.Success)
+		assert.Equal(t, "OK", response.Generation.Output)
+		assert.Empty(t, response.Generation.Error)
+		require.NotNil(t, response.Embedding)
+		assert.True(t, response.Embedding.Success)
+		assert.Equal(t, 3, response.Embedding.Dimensions)
+	})
+
+	t.Run("auth failure", func(t *testing.T) {
+		recorder, response := test("rejected")
+		require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+		assert.False(t, response.Success)
+		assert.False(t, response.Generation.Success)
+		assert.Contains(t, response.Generation.Error, "Incorrect API key")
+		assert.Equal(t, providers.ErrorClassUnauthorized, response.Generation.ErrorClass)
+		require.NotNil(t, response.Embedding)
+		assert.False(t, response.Embedding.Success)
+		assert.Equal(t, providers.ErrorClassUnauthorized, response.Embedding.ErrorClass)
+	})
+
+	t.Run("not configured", func(t *testing.T) {
+		recorder, response := test("unconfigured")
+		require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+		assert.False(t, response.Success)
+		assert.Equal(t, providers.ErrorClassNotConfigured, response.Generation.ErrorClass)
+		assert.Nil(t, response.Embedding)
+		assert.Zero(t, unconfigured.embeds)
+	})
+
+	t.Run("unknown provider", func(t *testing.T) {
+		recorder, _ := test("missing")
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+}
+
+func TestSampleOutput(t *testing.T) {
+	assert.Equal(t, "OK", sampleOutput("  OK\n"))
+	long := sampleOutput(strings.Repeat("é", providerTestSampleLength+10))
+	assert.Len(t, []rune(long), providerTestSampleLength+3)
+}
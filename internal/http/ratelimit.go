@@ -0,0 +1,105 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Rate limit backends, selected by http.rate_limit.backend
+const (
+	RateLimitBackendMemory  = "memory"
+	RateLimitBackendStorage = "storage"
+)
+
+// RateLimiter decides whether a client may make another request
+type RateLimiter interface {
+	Allow(ctx context.Context, clientKey string) (bool, error)
+}
+
+// NewRateLimiter returns the limiter for backend. The storage backend counts
+// requests with counter, which every instance of the API must share.
+func NewRateLimiter(backend string, counter RateLimitCounter, requestsPerMin, burst int) (RateLimiter, error) {
+	switch backend {
+	case "", RateLimitBackendMemory:
+		return NewMemoryRateLimiter(requestsPerMin, burst), nil
+	case RateLimitBackendStorage:
+		if counter == nil {
+			return nil, fmt.Errorf("rate limit backend %q requires storage", backend)
+		}
+		return NewStoreRateLimiter(counter, requestsPerMin), nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit backend %q (want %s or %s)", backend, RateLimitBackendMemory, RateLimitBackendStorage)
+	}
+}
+
+// MemoryRateLimiter keeps a token bucket per client in process memory. Each
+// instance of a horizontally scaled deployment enforces its own limit.
+type MemoryRateLimiter struct {
+	mu             sync.Mutex
+	clients        map[string]*ClientLimiter
+	requestsPerMin int
+	burst          int
+}
+
+// NewMemoryRateLimiter creates an in-memory limiter
+func NewMemoryRateLimiter(requestsPerMin, burst int) *MemoryRateLimiter {
+	return &MemoryRateLimiter{
+		clients:        make(map[string]*ClientLimiter),
+		requestsPerMin: requestsPerMin,
+		burst:          burst,
+	}
+}
+
+// Allow takes a token from the client's bucket
+func (m *MemoryRateLimiter) Allow(ctx context.Context, clientKey string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	limiter, exists := m.clients[clientKey]
+	if !exists {
+		limiter = NewClientLimiter(m.requestsPerMin, m.burst)
+		m.clients[clientKey] = limiter
+	}
+	return limiter.Allow(), nil
+}
+
+// RateLimitCounter counts requests per client in fixed windows, in storage
+// shared by every instance. storage.Storage implements it.
+type RateLimitCounter interface {
+	IncrementRateLimit(ctx context.Context, clientKey string, window time.Time) (int, error)
+}
+
+// StoreRateLimiter allows requestsPerMin requests per client in each
+// one-minute window, counted in shared storage so the limit holds across
+// every instance rather than per instance
+type StoreRateLimiter struct {
+	counter        RateLimitCounter
+	requestsPerMin int
+}
+
+// NewStoreRateLimiter creates a limiter backed by counter
+func NewStoreRateLimiter(counter RateLimitCounter, requestsPerMin int) *StoreRateLimiter {
+	return &StoreRateLimiter{counter: counter, requestsPerMin: requestsPerMin}
+}
+
+// Allow counts the request against the client's current window
+func (l *StoreRateLimiter) Allow(ctx context.Context, clientKey string) (bool, error) {
+	count, err := l.counter.IncrementRateLimit(ctx, clientKey, time.Now().Truncate(time.Minute))
+	if err != nil {
+		return false, err
+	}
+	return count <= l.requestsPerMin, nil
+}
+
+// rateLimitKey identifies the client of r by IP, without the port, so every
+// connection from a client shares its limit
+func rateLimitKey(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
@@ -0,0 +1,78 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCounter stands in for shared storage; two limiters using the same
+// counter behave like two API instances sharing a database
+type fakeCounter struct {
+	mu     sync.Mutex
+	counts map[string]int
+	err    error
+}
+
+func (c *fakeCounter) IncrementRateLimit(ctx context.Context, clientKey string, window time.Time) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return 0, c.err
+	}
+	key := clientKey + "@" + window.String()
+	c.counts[key]++
+	return c.counts[key], nil
+}
+
+func TestStoreRateLimiterIsShared(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	counter := &fakeCounter{counts: make(map[string]int)}
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	instanceA := RateLimitWith(NewStoreRateLimiter(counter, 3), logger)(ok)
+	instanceB := RateLimitWith(NewStoreRateLimiter(counter, 3), logger)(ok)
+
+	send := func(h http.Handler, remoteAddr string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/prompts", nil)
+		req.RemoteAddr = remoteAddr
+		recorder := httptest.NewRecorder()
+		h.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	assert.Equal(t, http.StatusOK, send(instanceA, "10.0.0.1:5000"))
+	assert.Equal(t, http.StatusOK, send(instanceB, "10.0.0.1:5001"))
+	assert.Equal(t, http.StatusOK, send(instanceA, "10.0.0.1:5002"))
+	assert.Equal(t, http.StatusTooManyRequests, send(instanceB, "10.0.0.1:5003"), "limit applies across instances and ports")
+	assert.Equal(t, http.StatusOK, send(instanceB, "10.0.0.2:5000"))
+
+	// A failing backend lets requests through
+	counter.err = errors.New("database is locked")
+	assert.Equal(t, http.StatusOK, send(instanceA, "10.0.0.1:5004"))
+}
+
+func TestNewRateLimiter(t *testing.T) {
+	limiter, err := NewRateLimiter("", nil, 60, 100)
+	require.NoError(t, err)
+	assert.IsType(t, &MemoryRateLimiter{}, limiter)
+
+	limiter, err = NewRateLimiter(RateLimitBackendStorage, &fakeCounter{}, 60, 100)
+	require.NoError(t, err)
+	assert.IsType(t, &StoreRateLimiter{}, limiter)
+
+	_, err = NewRateLimiter(RateLimitBackendStorage, nil, 60, 100)
+	assert.Error(t, err)
+
+	_, err = NewRateLimiter("redis", nil, 60, 100)
+	assert.Error(t, err)
+}
@@ -0,0 +1,201 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jonwraymond/prompt-alchemy/internal/helpers"
+	"github.com/jonwraymond/prompt-alchemy/internal/storage"
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/sirupsen/logrus"
+)
+
+// RegenerateRequest overrides parameters of the source prompt's generation.
+// Unset fields keep the source prompt's values.
+type RegenerateRequest struct {
+	Input       string   `json:"input,omitempty"`
+	Phase       string   `json:"phase,omitempty"`
+	Provider    string   `json:"provider,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+	MaxTokens   int      `json:"max_tokens,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Persona     string   `json:"persona,omitempty"`
+	TargetModel string   `json:"target_model,omitempty"`
+}
+
+// RegenerateResponse is the regenerated prompt and its derived_from link to
+// the source prompt
+type RegenerateResponse struct {
+	Prompt       *models.Prompt             `json:"prompt"`
+	Relationship *models.PromptRelationship `json:"relationship"`
+}
+
+// handleRegeneratePrompt generates a new prompt with the source prompt's
+// input, phase, provider, temperature, max tokens, tags, persona and target
+// model, each overridable in the body, and saves it derived from the
+// source. Prompts stored without their generation input are regenerated
+// from their content.
+func (s *SimpleServer) handleRegeneratePrompt(w http.ResponseWriter, r *http.Request) {
+	logger := s.requestLogger(r.Context())
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid prompt ID")
+		return
+	}
+
+	// The body is optional; without one the generation is repeated as is
+	var req RegenerateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		s.writeError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	source, err := s.store.GetPromptByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, storage.ErrPromptNotFound) {
+			s.writeError(w, http.StatusNotFound, "Prompt not found")
+		} else {
+			logger.WithError(err).Error("Failed to get prompt")
+			s.writeError(w, http.StatusInternalServerError, "Failed to get prompt")
+		}
+		return
+	}
+
+	input := firstNonEmpty(req.Input, source.OriginalInput, source.Content)
+	phase := models.Phase(firstNonEmpty(req.Phase, string(source.Phase), string(models.PhasePrimaMaterial)))
+	provider := firstNonEmpty(req.Provider, source.Provider, helpers.PhaseProvider(phase))
+	temperature := source.Temperature
+	if req.Temperature != nil {
+		temperature = *req.Temperature
+	}
+	maxTokens := source.MaxTokens
+	if req.MaxTokens > 0 {
+		maxTokens = req.MaxTokens
+	}
+	if maxTokens <= 0 {
+		maxTokens = 2000
+	}
+	tags := source.Tags
+	if req.Tags != nil {
+		tags = req.Tags
+	}
+	if tags == nil {
+		tags = []string{}
+	}
+	persona := firstNonEmpty(req.Persona, source.PersonaUsed, string(models.PersonaCode))
+	targetModel := firstNonEmpty(req.TargetModel, source.TargetModelFamily)
+
+	var fields models.FieldErrors
+	if err := models.ValidateGenerateRequest(models.GenerateRequest{
+		Input:       input,
+		Phases:      []string{string(phase)},
+		Count:       1,
+		Temperature: temperature,
+		MaxTokens:   maxTokens,
+		Persona:     persona,
+	}, s.engine.KnownPhase); errors.As(err, &fields) {
+		// One phase is regenerated, not a list
+		if message, ok := fields["phases"]; ok {
+			delete(fields, "phases")
+			fields["phase"] = message
+		}
+		s.writeValidationError(w, err.Error(), fields)
+		return
+	}
+	if generator, err := s.registry.Get(provider); err != nil || !generator.IsAvailable() {
+		s.writeErrorCode(w, http.StatusBadRequest, ErrCodeProviderUnavailable, fmt.Sprintf("provider %q is not available", provider))
+		return
+	}
+
+	sessionID := uuid.New()
+	result, err := s.engine.Generate(r.Context(), models.GenerateOptions{
+		Request: models.PromptRequest{
+			Input:       input,
+			Phases:      []models.Phase{phase},
+			Count:       1,
+			Providers:   map[models.Phase]string{phase: provider},
+			Temperature: temperature,
+			MaxTokens:   maxTokens,
+			Tags:        tags,
+			SessionID:   sessionID,
+		},
+		PhaseConfigs:   []models.PhaseConfig{{Phase: phase, Provider: provider}},
+		IncludeContext: true,
+		Persona:        persona,
+		TargetModel:    targetModel,
+		// A regeneration asks for a new take, not the stored one again
+		BypassSemanticCache: true,
+	})
+	if err != nil {
+		logger.WithError(err).WithField("prompt_id", id).Error("Failed to regenerate prompt")
+		s.writeAPIError(w, apiErrorFrom(err, "Failed to regenerate prompt"))
+		return
+	}
+	if len(result.Prompts) == 0 {
+		s.writeError(w, http.StatusUnprocessableEntity, "Regeneration produced no prompt")
+		return
+	}
+
+	prompt := result.Prompts[0]
+	prompt.ParentID = &source.ID
+	prompt.SessionID = sessionID
+	prompt.SourceType = "derived"
+	prompt.EnhancementMethod = "regenerated"
+
+	// Prompts are unique by content, so a regeneration repeating a stored
+	// prompt word for word can't be saved as a new one
+	duplicate, err := s.store.FindDuplicate(r.Context(), &prompt, 0)
+	if err != nil {
+		logger.WithError(err).WithField("prompt_id", id).Error("Failed to check regenerated prompt for duplicates")
+		s.writeError(w, http.StatusInternalServerError, "Failed to save regenerated prompt")
+		return
+	}
+	if duplicate != nil {
+		s.writeAPIError(w, &APIError{
+			Status:  http.StatusConflict,
+			Code:    ErrCodeConflict,
+			Message: "Regeneration reproduced a stored prompt, retry or change the overrides",
+			Details: map[string]interface{}{"duplicate_of": duplicate.DuplicateOf},
+		})
+		return
+	}
+	if err := s.store.SavePrompt(r.Context(), &prompt); err != nil {
+		logger.WithError(err).WithField("prompt_id", id).Error("Failed to save regenerated prompt")
+		s.writeError(w, http.StatusInternalServerError, "Failed to save regenerated prompt")
+		return
+	}
+
+	// Saving a prompt with a parent records its derived_from relationship
+	relationships, err := s.store.GetRelationships(r.Context(), prompt.ID, models.RelationshipDerivedFrom)
+	if err != nil || len(relationships) == 0 {
+		logger.WithError(err).WithField("prompt_id", prompt.ID).Error("Failed to load regenerated prompt's relationship")
+		s.writeError(w, http.StatusInternalServerError, "Failed to link regenerated prompt")
+		return
+	}
+
+	logger.WithFields(logrus.Fields{
+		"source_id": source.ID,
+		"prompt_id": prompt.ID,
+		"phase":     phase,
+		"provider":  prompt.Provider,
+	}).Info("Prompt regenerated via HTTP API")
+	s.writeJSON(w, http.StatusCreated, RegenerateResponse{
+		Prompt:       &prompt,
+		Relationship: relationships[0],
+	})
+}
+
+// firstNonEmpty returns the first of values that isn't empty
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
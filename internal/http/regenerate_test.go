@@ -0,0 +1,171 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/jonwraymond/prompt-alchemy/pkg/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingProvider keeps the requests it was sent and numbers its
+// generations with its name, so no two are the same unless content is set
+type recordingProvider struct {
+	pingProvider
+	mu       sync.Mutex
+	requests []providers.GenerateRequest
+	content  string // if set, every generation returns it
+}
+
+func (p *recordingProvider) Generate(ctx context.Context, req providers.GenerateRequest) (*providers.GenerateResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.requests = append(p.requests, req)
+	content := p.content
+	if content == "" {
+		content = fmt.Sprintf("regenerated by %s %d", p.name, len(p.requests))
+	}
+	return &providers.GenerateResponse{Content: content, Model: "recording-model"}, nil
+}
+
+func (p *recordingProvider) last() providers.GenerateRequest {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.requests[len(p.requests)-1]
+}
+
+func TestHandleRegeneratePrompt(t *testing.T) {
+	server, store := newTestServer(t)
+	original := &recordingProvider{pingProvider: pingProvider{name: "original", available: true}}
+	other := &recordingProvider{pingProvider: pingProvider{name: "other", available: true}}
+	require.NoError(t, server.registry.Register("original", original))
+	require.NoError(t, server.registry.Register("other", other))
+
+	ctx := context.Background()
+	source := &models.Prompt{
+		Content:       "An earlier haiku prompt",
+		OriginalInput: "write a haiku about rain",
+		Phase:         models.PhaseSolutio,
+		Provider:      "original",
+		Model:         "recording-model",
+		Temperature:   0.3,
+		MaxTokens:     512,
+		Tags:          []string{"poetry", "rain"},
+	}
+	require.NoError(t, store.SavePrompt(ctx, source))
+
+	regenerate := func(id, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/prompts/"+id+"/regenerate", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		server.Router().ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	t.Run("inherits the source prompt's parameters", func(t *testing.T) {
+		recorder := regenerate(source.ID.String(), "")
+		require.Equal(t, http.StatusCreated, recorder.Code, recorder.Body.String())
+		var response RegenerateResponse
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+
+		prompt := response.Prompt
+		require.NotNil(t, prompt)
+		assert.NotEqual(t, source.ID, prompt.ID)
+		assert.Equal(t, source.OriginalInput, prompt.OriginalInput)
+		assert.Equal(t, models.PhaseSolutio, prompt.Phase)
+		assert.Equal(t, "original", prompt.Provider)
+		assert.InDelta(t, 0.3, prompt.Temperature, 1e-9)
+		assert.Equal(t, 512, prompt.MaxTokens)
+		assert.ElementsMatch(t, source.Tags, prompt.Tags)
+		require.NotNil(t, prompt.ParentID)
+		assert.Equal(t, source.ID, *prompt.ParentID)
+
+		sent := original.last()
+		assert.InDelta(t, 0.3, sent.Temperature, 1e-9)
+		assert.Equal(t, 512, sent.MaxTokens)
+		assert.Contains(t, sent.Prompt, source.OriginalInput)
+		assert.Empty(t, other.requests)
+
+		relationship := response.Relationship
+		require.NotNil(t, relationship)
+		assert.Equal(t, prompt.ID, relationship.SourcePromptID)
+		assert.Equal(t, source.ID, relationship.TargetPromptID)
+		assert.Equal(t, models.RelationshipDerivedFrom, relationship.Type)
+
+		saved, err := store.GetPromptByID(ctx, prompt.ID)
+		require.NoError(t, err)
+		assert.Equal(t, prompt.Content, saved.Content)
+		relationships, err := store.GetRelationships(ctx, prompt.ID, models.RelationshipDerivedFrom)
+		require.NoError(t, err)
+		require.Len(t, relationships, 1)
+		assert.Equal(t, relationship.ID, relationships[0].ID)
+	})
+
+	t.Run("applies overrides", func(t *testing.T) {
+		body := `{"input":"write a limerick","provider":"other","temperature":0.9,"max_tokens":128,"tags":["limerick"]}`
+		recorder := regenerate(source.ID.String(), body)
+		require.Equal(t, http.StatusCreated, recorder.Code, recorder.Body.String())
+		var response RegenerateResponse
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+
+		prompt := response.Prompt
+		assert.Equal(t, "write a limerick", prompt.OriginalInput)
+		assert.Equal(t, models.PhaseSolutio, prompt.Phase, "the phase is still inherited")
+		assert.Equal(t, "other", prompt.Provider)
+		assert.InDelta(t, 0.9, prompt.Temperature, 1e-9)
+		assert.Equal(t, 128, prompt.MaxTokens)
+		assert.Equal(t, []string{"limerick"}, prompt.Tags)
+
+		sent := other.last()
+		assert.InDelta(t, 0.9, sent.Temperature, 1e-9)
+		assert.Equal(t, 128, sent.MaxTokens)
+		assert.Equal(t, source.ID, response.Relationship.TargetPromptID)
+	})
+
+	t.Run("repeating a stored prompt conflicts", func(t *testing.T) {
+		original.mu.Lock()
+		original.content = "the same take every time"
+		original.mu.Unlock()
+		defer func() {
+			original.mu.Lock()
+			original.content = ""
+			original.mu.Unlock()
+		}()
+
+		recorder := regenerate(source.ID.String(), "")
+		require.Equal(t, http.StatusCreated, recorder.Code, recorder.Body.String())
+		var first RegenerateResponse
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &first))
+
+		recorder = regenerate(source.ID.String(), "")
+		require.Equal(t, http.StatusConflict, recorder.Code, recorder.Body.String())
+		var response ErrorResponse
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		assert.Equal(t, ErrCodeConflict, response.Code)
+		assert.Equal(t, first.Prompt.ID.String(), response.Details["duplicate_of"])
+	})
+
+	t.Run("rejects invalid overrides", func(t *testing.T) {
+		recorder := regenerate(source.ID.String(), `{"phase":"nigredo"}`)
+		assert.Equal(t, http.StatusBadRequest, recorder.Code, recorder.Body.String())
+		assert.Contains(t, recorder.Body.String(), `"phase"`)
+
+		recorder = regenerate(source.ID.String(), `{"provider":"missing"}`)
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), ErrCodeProviderUnavailable)
+	})
+
+	t.Run("unknown prompts", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, regenerate(uuid.New().String(), "").Code)
+		assert.Equal(t, http.StatusBadRequest, regenerate("not-a-uuid", "").Code)
+	})
+}
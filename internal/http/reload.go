@@ -0,0 +1,46 @@
+package http
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/jonwraymond/prompt-alchemy/pkg/providers"
+)
+
+// ReloadResponse reports what a configuration reload changed
+type ReloadResponse struct {
+	Providers providers.RegistryChanges `json:"providers"`
+	Available []string                  `json:"available"`
+}
+
+// SetReloader enables POST /api/v1/admin/reload, which reloads the
+// configuration with reloader
+func (s *SimpleServer) SetReloader(reloader *providers.Reloader) {
+	s.reloader = reloader
+}
+
+// handleReloadConfig re-reads the configuration file and swaps in the
+// providers it configures. Since it can change API keys it is only served
+// with API key authentication enabled, which already requires a write key.
+// Server settings such as the port and API keys keep their startup values.
+func (s *SimpleServer) handleReloadConfig(w http.ResponseWriter, r *http.Request) {
+	if !s.config.EnableAuth {
+		s.writeError(w, http.StatusForbidden, "Configuration reload requires API key authentication (http.enable_auth)")
+		return
+	}
+	if s.reloader == nil {
+		s.writeError(w, http.StatusServiceUnavailable, "Configuration reload is not available")
+		return
+	}
+
+	changes, err := s.reloader.Reload()
+	if err != nil {
+		s.requestLogger(r.Context()).WithError(err).Error("Failed to reload configuration")
+		s.writeError(w, http.StatusInternalServerError, "Failed to reload configuration: "+err.Error())
+		return
+	}
+
+	available := s.registry.ListAvailable()
+	sort.Strings(available)
+	s.writeJSON(w, http.StatusOK, ReloadResponse{Providers: changes, Available: available})
+}
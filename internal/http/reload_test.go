@@ -0,0 +1,76 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jonwraymond/prompt-alchemy/pkg/providers"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleReloadConfig(t *testing.T) {
+	defer viper.Reset()
+	viper.Set("http.enable_auth", true)
+	viper.Set("http.api_keys", []string{"write-key"})
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte("providers:\n  openai:\n    api_key: sk-1\n"), 0o600))
+	viper.SetConfigFile(configFile)
+	require.NoError(t, viper.ReadInConfig())
+
+	register := func(registry *providers.Registry) error {
+		for _, name := range []string{providers.ProviderOpenAI, providers.ProviderAnthropic} {
+			if viper.GetString("providers."+name+".api_key") != "" {
+				if err := registry.Register(name, &pingProvider{name: name, available: true}); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	server, _ := newTestServer(t)
+	registry := server.registry
+	require.NoError(t, register(registry))
+
+	reload := func(key string) (int, ReloadResponse) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/reload", nil)
+		req.Header.Set("X-API-Key", key)
+		recorder := httptest.NewRecorder()
+		server.Router().ServeHTTP(recorder, req)
+		var response ReloadResponse
+		if recorder.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		}
+		return recorder.Code, response
+	}
+
+	status, _ := reload("write-key")
+	assert.Equal(t, http.StatusServiceUnavailable, status, "no reloader configured")
+	server.SetReloader(providers.NewReloader(registry, register))
+
+	status, _ = reload("wrong-key")
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	require.NoError(t, os.WriteFile(configFile, []byte("providers:\n  openai:\n    api_key: sk-1\n  anthropic:\n    api_key: sk-ant-1\n"), 0o600))
+	status, response := reload("write-key")
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, []string{providers.ProviderAnthropic}, response.Providers.Added)
+	assert.Empty(t, response.Providers.Changed, "openai's settings are unchanged")
+	assert.Equal(t, []string{providers.ProviderAnthropic, providers.ProviderOpenAI}, response.Available)
+	_, err := registry.Get(providers.ProviderAnthropic)
+	assert.NoError(t, err, "the added provider is available without a restart")
+
+	t.Run("refused without authentication", func(t *testing.T) {
+		viper.Set("http.enable_auth", false)
+		unauthenticated, _ := newTestServer(t)
+		unauthenticated.SetReloader(providers.NewReloader(unauthenticated.registry, register))
+		recorder := httptest.NewRecorder()
+		unauthenticated.Router().ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/api/v1/admin/reload", nil))
+		assert.Equal(t, http.StatusForbidden, recorder.Code)
+	})
+}
@@ -0,0 +1,100 @@
+package http
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jonwraymond/prompt-alchemy/internal/httputil"
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+)
+
+// SessionPhase is how many prompts a session saved for one phase
+type SessionPhase struct {
+	Phase models.Phase `json:"phase"`
+	Count int          `json:"count"`
+}
+
+// SessionSummary aggregates the prompts of a session. The time span runs
+// from the first prompt saved to the last.
+type SessionSummary struct {
+	PromptCount int            `json:"prompt_count"`
+	Phases      []SessionPhase `json:"phases"`
+	TotalTokens int            `json:"total_tokens"`
+	Providers   []string       `json:"providers"`
+	StartedAt   time.Time      `json:"started_at"`
+	EndedAt     time.Time      `json:"ended_at"`
+	Duration    string         `json:"duration"`
+}
+
+// SessionResponse lists the prompts of a generation session grouped by phase
+type SessionResponse struct {
+	SessionID uuid.UUID      `json:"session_id"`
+	Prompts   interface{}    `json:"prompts"` // []*models.Prompt, possibly projected with ?fields=
+	Summary   SessionSummary `json:"summary"`
+}
+
+// handleGetSession returns every prompt saved for a generation session in
+// cascade order, with totals over the session
+func (s *SimpleServer) handleGetSession(w http.ResponseWriter, r *http.Request) {
+	logger := s.requestLogger(r.Context())
+	sessionID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid session ID")
+		return
+	}
+
+	prompts, err := s.store.GetPromptsBySession(r.Context(), sessionID)
+	if err != nil {
+		logger.WithError(err).WithField("session_id", sessionID).Error("Failed to get session prompts")
+		s.writeError(w, http.StatusInternalServerError, "Failed to get session")
+		return
+	}
+	if len(prompts) == 0 {
+		s.writeError(w, http.StatusNotFound, "Session not found")
+		return
+	}
+
+	projected, err := httputil.ParseFields(r).Project(prompts)
+	if err != nil {
+		logger.WithError(err).Error("Failed to project prompt fields")
+		s.writeError(w, http.StatusInternalServerError, "Failed to get session")
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, SessionResponse{
+		SessionID: sessionID,
+		Prompts:   projected,
+		Summary:   summarizeSession(prompts),
+	})
+}
+
+// summarizeSession totals prompts, which GetPromptsBySession has grouped by
+// phase
+func summarizeSession(prompts []*models.Prompt) SessionSummary {
+	summary := SessionSummary{PromptCount: len(prompts), Phases: []SessionPhase{}, Providers: []string{}}
+	providers := make(map[string]bool)
+	for i, p := range prompts {
+		if n := len(summary.Phases); n > 0 && summary.Phases[n-1].Phase == p.Phase {
+			summary.Phases[n-1].Count++
+		} else {
+			summary.Phases = append(summary.Phases, SessionPhase{Phase: p.Phase, Count: 1})
+		}
+		summary.TotalTokens += p.ActualTokens
+		if p.Provider != "" && !providers[p.Provider] {
+			providers[p.Provider] = true
+			summary.Providers = append(summary.Providers, p.Provider)
+		}
+		if i == 0 || p.CreatedAt.Before(summary.StartedAt) {
+			summary.StartedAt = p.CreatedAt
+		}
+		if p.CreatedAt.After(summary.EndedAt) {
+			summary.EndedAt = p.CreatedAt
+		}
+	}
+	sort.Strings(summary.Providers)
+	summary.Duration = summary.EndedAt.Sub(summary.StartedAt).String()
+	return summary
+}
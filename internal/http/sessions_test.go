@@ -0,0 +1,93 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleGetSession(t *testing.T) {
+	server, store := newTestServer(t)
+
+	ctx := context.Background()
+	sessionID := uuid.New()
+	save := func(content string, phase models.Phase, provider string, tokens int) *models.Prompt {
+		p := &models.Prompt{Content: content, Phase: phase, Provider: provider, ActualTokens: tokens, SessionID: sessionID}
+		require.NoError(t, store.SavePrompt(ctx, p))
+		return p
+	}
+	// Two variants through three phases, the later phases saved first
+	c1 := save("Write a precise CSV parser", models.PhaseCoagulatio, "openai", 30)
+	s1 := save("Write a friendly CSV parser", models.PhaseSolutio, "anthropic", 20)
+	p1 := save("Write a CSV parser", models.PhasePrimaMaterial, "openai", 10)
+	p2 := save("Write a TSV parser", models.PhasePrimaMaterial, "openai", 10)
+	s2 := save("Write a friendly TSV parser", models.PhaseSolutio, "anthropic", 20)
+	c2 := save("Write a precise TSV parser", models.PhaseCoagulatio, "openai", 30)
+	require.NoError(t, store.SavePrompt(ctx, &models.Prompt{Content: "Another session", Phase: models.PhasePrimaMaterial, SessionID: uuid.New()}))
+
+	get := func(path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		server.Router().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		return recorder
+	}
+
+	t.Run("prompts grouped by phase", func(t *testing.T) {
+		recorder := get("/api/v1/sessions/" + sessionID.String())
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		var response struct {
+			SessionID uuid.UUID       `json:"session_id"`
+			Prompts   []models.Prompt `json:"prompts"`
+			Summary   SessionSummary  `json:"summary"`
+		}
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		assert.Equal(t, sessionID, response.SessionID)
+
+		ids := make([]uuid.UUID, len(response.Prompts))
+		for i, p := range response.Prompts {
+			ids[i] = p.ID
+		}
+		assert.Equal(t, []uuid.UUID{p1.ID, p2.ID, s1.ID, s2.ID, c1.ID, c2.ID}, ids)
+
+		assert.Equal(t, 6, response.Summary.PromptCount)
+		assert.Equal(t, []SessionPhase{
+			{Phase: models.PhasePrimaMaterial, Count: 2},
+			{Phase: models.PhaseSolutio, Count: 2},
+			{Phase: models.PhaseCoagulatio, Count: 2},
+		}, response.Summary.Phases)
+		assert.Equal(t, 120, response.Summary.TotalTokens)
+		assert.Equal(t, []string{"anthropic", "openai"}, response.Summary.Providers)
+		assert.False(t, response.Summary.StartedAt.After(response.Summary.EndedAt))
+	})
+
+	t.Run("output retrieve honors session_id", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/output/retrieve", bytes.NewBufferString(`{"session_id":"`+sessionID.String()+`","limit":3}`))
+		req.Header.Set("Content-Type", "application/json")
+		server.Router().ServeHTTP(recorder, req)
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		var response struct {
+			Data []struct {
+				ID string `json:"id"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		require.Len(t, response.Data, 3)
+		assert.Equal(t, []string{p1.ID.String(), p2.ID.String(), s1.ID.String()},
+			[]string{response.Data[0].ID, response.Data[1].ID, response.Data[2].ID})
+	})
+
+	t.Run("unknown and invalid sessions", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, get("/api/v1/sessions/"+uuid.NewString()).Code)
+		assert.Equal(t, http.StatusBadRequest, get("/api/v1/sessions/not-a-uuid").Code)
+	})
+}
@@ -3,17 +3,25 @@ package http
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
-	"github.com/go-chi/cors"
 	"github.com/google/uuid"
 	"github.com/jonwraymond/prompt-alchemy/internal/engine"
+	"github.com/jonwraymond/prompt-alchemy/internal/helpers"
+	"github.com/jonwraymond/prompt-alchemy/internal/httputil"
 	"github.com/jonwraymond/prompt-alchemy/internal/learning"
+	"github.com/jonwraymond/prompt-alchemy/internal/log"
+	"github.com/jonwraymond/prompt-alchemy/internal/observability/metrics"
+	"github.com/jonwraymond/prompt-alchemy/internal/observability/tracing"
 	"github.com/jonwraymond/prompt-alchemy/internal/ranking"
 	"github.com/jonwraymond/prompt-alchemy/internal/selection"
 	"github.com/jonwraymond/prompt-alchemy/internal/storage"
@@ -26,25 +34,69 @@ import (
 
 // API request/response models for generate endpoint
 type GenerateRequest struct {
-	Input               string            `json:"input" binding:"required"`
-	Phases              []string          `json:"phases,omitempty"`
-	Count               int               `json:"count,omitempty"`
-	Providers           map[string]string `json:"providers,omitempty"`
-	Temperature         float64           `json:"temperature,omitempty"`
-	MaxTokens           int               `json:"max_tokens,omitempty"`
-	Tags                []string          `json:"tags,omitempty"`
-	Context             []string          `json:"context,omitempty"`
-	Persona             string            `json:"persona,omitempty"`
-	TargetModel         string            `json:"target_model,omitempty"`
-	UseParallel         bool              `json:"use_parallel,omitempty"`
-	Save                bool              `json:"save,omitempty"`
-	UseOptimization     bool              `json:"use_optimization,omitempty"`
-	SimilarityThreshold float64           `json:"similarity_threshold,omitempty"`
-	HistoricalWeight    float64           `json:"historical_weight,omitempty"`
-	EnableJudging       bool              `json:"enable_judging,omitempty"`
-	JudgeProvider       string            `json:"judge_provider,omitempty"`
-	ScoringCriteria     string            `json:"scoring_criteria,omitempty"`
-	TargetUseCase       string            `json:"target_use_case,omitempty"`
+	Input               string             `json:"input" binding:"required"`
+	Phases              []string           `json:"phases,omitempty"`
+	Count               int                `json:"count,omitempty"`
+	Providers           map[string]string  `json:"providers,omitempty"`
+	Temperature         float64            `json:"temperature,omitempty"`
+	MaxTokens           int                `json:"max_tokens,omitempty"`
+	Tags                []string           `json:"tags,omitempty"`
+	Context             []string           `json:"context,omitempty"`
+	Persona             string             `json:"persona,omitempty"`
+	TargetModel         string             `json:"target_model,omitempty"`
+	UseParallel         bool               `json:"use_parallel,omitempty"`
+	Save                bool               `json:"save,omitempty"`
+	SavePhases          []string           `json:"save_phases,omitempty"`
+	UseOptimization     bool               `json:"use_optimization,omitempty"`
+	SimilarityThreshold float64            `json:"similarity_threshold,omitempty"`
+	HistoricalWeight    float64            `json:"historical_weight,omitempty"`
+	EnableJudging       bool               `json:"enable_judging,omitempty"`
+	JudgeProvider       string             `json:"judge_provider,omitempty"`
+	ScoringCriteria     string             `json:"scoring_criteria,omitempty"`
+	MinConfidence       float64            `json:"min_confidence,omitempty"`
+	FallbackToRanker    *bool              `json:"fallback_to_ranker,omitempty"`
+	JudgeProviders      []string           `json:"judge_providers,omitempty"`
+	JudgeWeights        map[string]float64 `json:"judge_weights,omitempty"`
+	TargetUseCase       string             `json:"target_use_case,omitempty"`
+
+	// AutoSummarizeContext summarizes context beyond
+	// generation.context_token_budget before it reaches the providers
+	AutoSummarizeContext bool `json:"auto_summarize_context,omitempty"`
+
+	// Variables fill the {{name}} placeholders of Input; AllowMissing
+	// leaves placeholders without a value in place instead of failing
+	Variables    map[string]string `json:"variables,omitempty"`
+	AllowMissing bool              `json:"allow_missing,omitempty"`
+
+	// SystemPrompts replaces the system prompt of the phases it names
+	SystemPrompts map[string]string `json:"system_prompts,omitempty"`
+
+	// TruncateOnOverflow cuts a phase input that overflows the provider's
+	// context window to fit instead of failing with 413
+	TruncateOnOverflow bool `json:"truncate_on_overflow,omitempty"`
+
+	// Seed asks providers that support it for deterministic sampling
+	Seed *int `json:"seed,omitempty"`
+
+	// DryRun returns the resolved plan and its projected usage without
+	// calling any provider; ?dry_run=true does the same
+	DryRun bool `json:"dry_run,omitempty"`
+
+	// PhaseSelection is all, best or cascade, generation.phase_selection
+	// when unset
+	PhaseSelection string `json:"phase_selection,omitempty"`
+
+	// Explain adds each ranking's score breakdown to the response;
+	// ?explain=true does the same
+	Explain bool `json:"explain,omitempty"`
+
+	// ContextDocumentIDs names ingested context documents whose chunks most
+	// relevant to Input are added to Context
+	ContextDocumentIDs []uuid.UUID `json:"context_document_ids,omitempty"`
+
+	// BypassCache generates even when generation.semantic_cache holds a
+	// generation for a similar input; Cache-Control: no-cache does the same
+	BypassCache bool `json:"bypass_cache,omitempty"`
 }
 
 type GenerateResponse struct {
@@ -66,13 +118,76 @@ type GenerateMetadata struct {
 	Timestamp        time.Time              `json:"timestamp"`
 	OptimizationUsed bool                   `json:"optimization_used,omitempty"`
 	JudgingUsed      bool                   `json:"judging_used,omitempty"`
+	JudgeConfidence  float64                `json:"judge_confidence,omitempty"`
+	LowConfidence    bool                   `json:"low_confidence,omitempty"`
+	SelectionSource  string                 `json:"selection_source,omitempty"`
+
+	// Set when several judges evaluated the prompts
+	Judges        []string `json:"judges,omitempty"`
+	ConsensusRate float64  `json:"consensus_rate,omitempty"`
+
+	// Token usage and cost summed over every generated prompt. CostEstimated
+	// is set when some provider didn't report usage or a model has no
+	// pricing, so the totals undercount.
+	TotalInputTokens  int     `json:"total_input_tokens"`
+	TotalOutputTokens int     `json:"total_output_tokens"`
+	EstimatedCostUSD  float64 `json:"estimated_cost_usd"`
+	CostEstimated     bool    `json:"cost_estimated,omitempty"`
+
+	// Set when auto_summarize_context shrank an over-budget context
+	ContextSummarized  bool `json:"context_summarized,omitempty"`
+	ContextTokensSaved int  `json:"context_tokens_saved,omitempty"`
+
+	// Phases run at a lower or higher temperature than requested because
+	// their provider doesn't accept it
+	TemperatureAdjustments []models.TemperatureAdjustment `json:"temperature_adjustments,omitempty"`
+
+	// Phases skipped because they ran past generation.phase_timeout
+	PhaseTimeouts []models.PhaseTimeout `json:"phase_timeouts,omitempty"`
+
+	// Prompts left out because moderation scored them at or above
+	// moderation.threshold with the drop action
+	ModerationDropped int `json:"moderation_dropped,omitempty"`
+
+	// Prompts left unsaved because generation.dedup found them stored already
+	Deduplicated []storage.Duplicate `json:"deduplicated,omitempty"`
+
+	// Chunks of the requested context documents added to the context
+	RetrievedContext []models.ContextChunk `json:"retrieved_context,omitempty"`
+
+	// Set when the prompts come from generation.semantic_cache, generated
+	// earlier for an input CacheSimilarity similar
+	Cached          bool    `json:"cached,omitempty"`
+	CacheSimilarity float64 `json:"cache_similarity,omitempty"`
+
+	// Set on dry runs, where the token and cost totals are projections
+	DryRun   bool                       `json:"dry_run,omitempty"`
+	Estimate *models.GenerationEstimate `json:"estimate,omitempty"`
+}
+
+// GeneratePhaseEvent is the payload of a "phase" event sent while streaming
+// a generation; the final "done" event carries a full GenerateResponse
+type GeneratePhaseEvent struct {
+	Phase     string          `json:"phase"`
+	Prompts   []models.Prompt `json:"prompts"`
+	SessionID uuid.UUID       `json:"session_id"`
+}
+
+// GenerateTokenEvent is the payload of a "token" event, a piece of the
+// final phase's text as its provider produces it. Index is the variant the
+// text belongs to.
+type GenerateTokenEvent struct {
+	Phase string `json:"phase"`
+	Index int    `json:"index"`
+	Text  string `json:"text"`
 }
 
 type GenerateRequestSummary struct {
-	Phases      []string `json:"phases"`
-	Count       int      `json:"count"`
-	Persona     string   `json:"persona,omitempty"`
-	TargetModel string   `json:"target_model,omitempty"`
+	Phases         []string `json:"phases"`
+	Count          int      `json:"count"`
+	Persona        string   `json:"persona,omitempty"`
+	TargetModel    string   `json:"target_model,omitempty"`
+	PhaseSelection string   `json:"phase_selection,omitempty"`
 }
 
 // AI Selection API models
@@ -118,6 +233,23 @@ type SearchPromptsResponse struct {
 	Metadata     SearchMetadata  `json:"metadata"`
 }
 
+// ListPromptsResponse is the prompt listing response. It follows the
+// SearchPromptsResponse shape and adds cursor pagination.
+type ListPromptsResponse struct {
+	Prompts    interface{}    `json:"prompts"` // []models.Prompt, possibly projected with ?fields=
+	TotalFound int            `json:"total_found"`
+	SearchType string         `json:"search_type"`
+	Total      int            `json:"total"`
+	NextCursor *string        `json:"next_cursor"`
+	Metadata   SearchMetadata `json:"metadata"`
+}
+
+// Prompt listing limits
+const (
+	defaultListLimit = 20
+	maxListLimit     = 100
+)
+
 type SearchMetadata struct {
 	Phase         string     `json:"phase,omitempty"`
 	Provider      string     `json:"provider,omitempty"`
@@ -129,6 +261,28 @@ type SearchMetadata struct {
 	SearchedAt    time.Time  `json:"searched_at"`
 }
 
+// SessionLineageResponse lists the cascade chains of a generation session
+type SessionLineageResponse struct {
+	SessionID uuid.UUID   `json:"session_id"`
+	Chains    interface{} `json:"chains"` // [][]*models.Prompt, possibly projected with ?fields=
+	Count     int         `json:"count"`
+}
+
+// DiscoverRelationshipsRequest is the body of POST /api/v1/relationships/discover
+type DiscoverRelationshipsRequest struct {
+	PromptID  string   `json:"prompt_id"`
+	Threshold *float64 `json:"threshold,omitempty"` // minimum similarity, defaults to relationships.similarity_threshold
+	MaxLinks  int      `json:"max_links,omitempty"`
+}
+
+// DiscoverRelationshipsResponse lists the similar_to links created for a prompt
+type DiscoverRelationshipsResponse struct {
+	PromptID      uuid.UUID                    `json:"prompt_id"`
+	Relationships []*models.PromptRelationship `json:"relationships"`
+	Count         int                          `json:"count"`
+	Threshold     float64                      `json:"threshold"`
+}
+
 // Provider API models
 type ProviderInfo struct {
 	Name               string   `json:"name"`
@@ -157,7 +311,16 @@ type Config struct {
 	EnableCORS      bool
 	CORSOrigins     []string
 	EnableAuth      bool
-	APIKeys         []string
+	APIKeys         []string // keys with write scope
+	ReadOnlyAPIKeys []string // keys limited to GET, HEAD and OPTIONS requests
+
+	// CORSWriteOrigins, when set, replaces CORSOrigins for generation and
+	// other writes so they can be limited to fewer origins than reads
+	CORSWriteOrigins     []string
+	CORSAllowCredentials bool
+
+	// MaxBodyBytes bounds request bodies, DefaultMaxBodyBytes when zero
+	MaxBodyBytes int64
 }
 
 // SimpleServer is a basic HTTP server for now
@@ -171,6 +334,33 @@ type SimpleServer struct {
 	summarizer *summarization.Summarizer
 	logger     *logrus.Logger
 	config     *Config
+
+	// generationLimiter bounds in-flight generations across all generate routes
+	generationLimiter *ConcurrencyLimiter
+
+	// generations keeps database restores and generations apart
+	generations generationGate
+
+	// latencyProbe caches provider round-trip measurements between UI polls
+	latencyProbe *LatencyProbe
+
+	// weightPresets maps scoring_criteria to judge weights
+	weightPresets selection.WeightPresets
+
+	// flows tracks the phase progress of running generations for the web UI
+	flows *FlowTracker
+
+	// modelCatalog caches the live model lists of providers
+	modelCatalog *ModelCatalog
+
+	// thinking fans thinking updates out to the web UI's thinking streams
+	thinking *ThinkingHub
+
+	// reloader, when set, serves POST /api/v1/admin/reload
+	reloader *providers.Reloader
+
+	// metrics, when set, records generations and serves /metrics
+	metrics *metrics.Metrics
 }
 
 // NewSimpleServer creates a new simple HTTP server instance
@@ -200,8 +390,21 @@ func NewSimpleServer(
 		IdleTimeout:     300 * time.Second, // Increased for connection reuse
 		ShutdownTimeout: 15 * time.Second,
 		EnableCORS:      true,
-		CORSOrigins:     []string{"*"},
-		EnableAuth:      false,
+		CORSOrigins:     viper.GetStringSlice("http.cors_origins"),
+		EnableAuth:      viper.GetBool("http.enable_auth"),
+		APIKeys:         viper.GetStringSlice("http.api_keys"),
+		ReadOnlyAPIKeys: viper.GetStringSlice("http.read_only_api_keys"),
+
+		CORSWriteOrigins:     viper.GetStringSlice("http.cors_write_origins"),
+		CORSAllowCredentials: viper.GetBool("http.cors_allow_credentials"),
+
+		MaxBodyBytes: viper.GetInt64("http.max_body_bytes"),
+	}
+	if len(config.CORSOrigins) == 0 {
+		config.CORSOrigins = []string{"*"}
+	}
+	if config.EnableAuth && len(config.APIKeys)+len(config.ReadOnlyAPIKeys) == 0 {
+		logger.Warn("API key authentication is enabled but no keys are configured; all requests will be rejected")
 	}
 
 	s := &SimpleServer{
@@ -213,7 +416,24 @@ func NewSimpleServer(
 		summarizer: summarization.NewSummarizer(logger),
 		logger:     logger,
 		config:     config,
+
+		generationLimiter: NewConcurrencyLimiter(
+			viper.GetInt("generation.max_concurrent"),
+			viper.GetDuration("generation.queue_timeout"),
+			logger,
+		),
+		latencyProbe: NewLatencyProbe(10*time.Second, 3*time.Second),
+		flows:        NewFlowTracker(viper.GetDuration("http.flow_ttl")),
+		modelCatalog: NewModelCatalog(viper.GetDuration("http.models_cache_ttl")),
+		thinking:     NewThinkingHub(),
+	}
+
+	presets, err := selection.LoadWeightPresets()
+	if err != nil {
+		logger.WithError(err).Error("Invalid judge weight presets, using built-in presets")
+		presets = selection.BuiltinWeightPresets()
 	}
+	s.weightPresets = presets
 
 	logger.Info("=== CALLING SETUP ROUTER ===")
 	s.setupRouter()
@@ -228,6 +448,8 @@ func (s *SimpleServer) setupRouter() {
 
 	// Basic middleware
 	r.Use(middleware.RequestID)
+	r.Use(PropagateRequestID())
+	r.Use(tracing.Middleware)
 	r.Use(middleware.RealIP)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
@@ -235,47 +457,85 @@ func (s *SimpleServer) setupRouter() {
 
 	// CORS
 	if s.config.EnableCORS {
-		r.Use(cors.Handler(cors.Options{
-			AllowedOrigins:   s.config.CORSOrigins,
-			AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-			AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-			ExposedHeaders:   []string{"Link"},
-			AllowCredentials: true,
-			MaxAge:           300,
-		}))
+		r.Use(s.corsMiddleware())
 	}
 
-	// Health check
+	// API key authentication
+	if s.config.EnableAuth {
+		r.Use(s.requireAPIKey)
+	}
+
+	// Oversized bodies are refused before any handler decodes them
+	r.Use(LimitBody(s.config.MaxBodyBytes, s.writeError))
+
+	// Health checks: /healthz for liveness, /readyz for readiness
 	r.Get("/health", s.handleHealth)
+	r.Get("/healthz", s.handleLiveness)
+	r.Get("/readyz", s.handleReadiness)
 	r.Get("/version", s.handleVersion)
+	r.Get("/metrics", s.handleMetrics)
 
 	// API routes
 	r.Route("/api/v1", func(r chi.Router) {
+		r.Use(s.requireJSON)
 		r.Get("/health", s.handleHealth) // Add health endpoint under API
 		r.Get("/status", s.handleStatus)
 		r.Get("/info", s.handleInfo)
-		r.Post("/generate", s.handleGeneratePrompts) // Add generate directly under API
+		r.Get("/openapi.json", s.handleOpenAPISpec)
+		r.Get("/docs", s.handleAPIDocs)
+		r.With(s.generations.Middleware, s.generationLimiter.Middleware).Post("/generate", s.handleGeneratePrompts) // Add generate directly under API
 
 		// Prompt CRUD endpoints
 		r.Route("/prompts", func(r chi.Router) {
 			s.logger.Info("=== REGISTERING PROMPTS ROUTES ===")
-			// r.Get("/", s.handleListPrompts)
+			r.Get("/", s.handleListPrompts)
 			r.Post("/", s.handleCreatePrompt)
-			r.Post("/generate", s.handleGeneratePrompts)
+			r.With(s.generations.Middleware, s.generationLimiter.Middleware).Post("/generate", s.handleGeneratePrompts)
 			s.logger.Info("=== REGISTERED /generate ROUTE ===")
+			r.With(s.generations.Middleware, s.generationLimiter.Middleware).Post("/batch", s.handleBatchGenerate)
+			r.With(s.generations.Middleware, s.generationLimiter.Middleware).Post("/optimize", s.handleOptimizePrompt)
 			// r.Post("/select", s.handleAISelectPrompt)
-			// r.Get("/search", s.handleSearchPrompts)
-			// r.Get("/{id}", s.handleGetPrompt)
-			// r.Put("/{id}", s.handleUpdatePrompt)
-			// r.Delete("/{id}", s.handleDeletePrompt)
+			r.Get("/search", s.handleSearchPrompts)
+			r.Post("/search-by-vector", s.handleSearchByVector)
+			r.Get("/compare", s.handleComparePrompts)
+			r.Get("/export", s.handleExportPrompts)
+			r.Post("/import", s.handleImportPrompts)
+			r.Get("/{id}", s.handleGetPrompt)
+			r.Put("/{id}", s.handleUpdatePrompt)
+			r.Delete("/{id}", s.handleDeletePrompt)
+			r.Get("/{id}/versions", s.handleListPromptVersions)
+			r.Post("/{id}/feedback", s.handlePromptFeedback)
+			r.Post("/{id}/versions/{version}/restore", s.handleRestorePromptVersion)
+			r.With(s.generations.Middleware, s.generationLimiter.Middleware).Post("/{id}/regenerate", s.handleRegeneratePrompt)
 		})
 
+		r.Get("/sessions/{id}", s.handleGetSession)
+		r.Get("/sessions/{id}/lineage", s.handleSessionLineage)
+		r.Post("/relationships/discover", s.handleDiscoverRelationships)
+		r.Post("/context/documents", s.handleIngestContextDocument)
+		r.Get("/stats", s.handleStats)
+		r.Get("/tags", s.handleListTags)
+		r.Get("/personas", s.handleListPersonas)
+
+		// Admin endpoints
+		r.Post("/admin/reindex", s.handleStartReindex)
+		r.Get("/admin/reindex/status", s.handleReindexStatus)
+		r.Post("/admin/backfill-embeddings", s.handleStartBackfill)
+		r.Get("/admin/backfill-embeddings/status", s.handleBackfillStatus)
+		r.Post("/admin/reload", s.handleReloadConfig)
+		r.Post("/admin/maintenance", s.handleMaintenance)
+		r.Post("/admin/backup", s.handleBackup)
+		r.Post("/admin/restore", s.handleRestore)
+
 		// TODO: Add more endpoints
 		r.Get("/providers", s.handleListProviders)
+		r.With(s.generationLimiter.Middleware).Post("/providers/{name}/test", s.handleTestProvider)
+		r.Get("/models", s.handleListModels)
 	})
 
 	// HTMX API endpoints for the web UI
 	r.Route("/api", func(r chi.Router) {
+		r.Use(s.requireJSON)
 		r.Get("/flow-status", s.handleFlowStatus)
 		r.Get("/system-status", s.handleSystemStatus)
 		r.Get("/nodes-status", s.handleNodesStatus)
@@ -311,8 +571,8 @@ func (s *SimpleServer) setupRouter() {
 
 	// AI Thinking Process endpoint
 	r.Get("/api/thinking-stream", s.handleThinkingStream)
-	r.Post("/api/thinking-update", s.handleThinkingUpdate)
-	r.Post("/api/summarize", s.handleSummarize)
+	r.With(s.requireJSON).Post("/api/thinking-update", s.handleThinkingUpdate)
+	r.With(s.requireJSON).Post("/api/summarize", s.handleSummarize)
 
 	s.router = r
 }
@@ -356,7 +616,14 @@ func (s *SimpleServer) Start(ctx context.Context) error {
 }
 
 // Basic handlers
+
+// handleHealth answers without checking dependencies unless ?deep=true, which
+// runs the readiness checks
 func (s *SimpleServer) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if deep, _ := strconv.ParseBool(r.URL.Query().Get("deep")); deep {
+		s.handleReadiness(w, r)
+		return
+	}
 	response := map[string]interface{}{
 		"status":    "healthy",
 		"timestamp": time.Now(),
@@ -379,6 +646,7 @@ func (s *SimpleServer) handleStatus(w http.ResponseWriter, r *http.Request) {
 		"protocol":      "http",
 		"learning_mode": s.learner != nil,
 		"uptime":        time.Since(time.Now()).String(),
+		"providers":     s.registry.Health(),
 	}
 	s.writeJSON(w, http.StatusOK, response)
 }
@@ -389,51 +657,95 @@ func (s *SimpleServer) handleInfo(w http.ResponseWriter, r *http.Request) {
 		"version":     "1.0.0",
 		"description": "HTTP API for Prompt Alchemy prompt generation and management",
 		"endpoints": map[string]string{
-			"health":  "/health",
-			"version": "/version",
-			"status":  "/api/v1/status",
-			"info":    "/api/v1/info",
+			"health":   "/health",
+			"healthz":  "/healthz",
+			"readyz":   "/readyz",
+			"version":  "/version",
+			"status":   "/api/v1/status",
+			"info":     "/api/v1/info",
+			"prompts":  "/api/v1/prompts",
+			"session":  "/api/v1/sessions/{id}",
+			"lineage":  "/api/v1/sessions/{id}/lineage",
+			"tags":     "/api/v1/tags",
+			"personas": "/api/v1/personas",
 		},
 	}
 	s.writeJSON(w, http.StatusOK, response)
 }
 
 // CRUD handlers for prompts
-// func (s *SimpleServer) handleListPrompts(w http.ResponseWriter, r *http.Request) {
-// 	// Parse pagination parameters
-// 	limitStr := r.URL.Query().Get("limit")
-// 	offsetStr := r.URL.Query().Get("offset")
 
-// 	limit := 20 // default
-// 	offset := 0 // default
+// handleListPrompts pages through saved prompts, newest first. Paging is
+// keyed on created_at + id rather than an offset so deep pages stay cheap;
+// clients pass the returned next_cursor back as ?cursor= to continue.
+func (s *SimpleServer) handleListPrompts(w http.ResponseWriter, r *http.Request) {
+	logger := s.requestLogger(r.Context())
+	query := r.URL.Query()
+
+	limit := defaultListLimit
+	if limitStr := query.Get("limit"); limitStr != "" {
+		parsedLimit, err := strconv.Atoi(limitStr)
+		if err != nil || parsedLimit < 1 {
+			s.writeError(w, http.StatusBadRequest, "Invalid 'limit' parameter (must be a positive integer)")
+			return
+		}
+		limit = min(parsedLimit, maxListLimit)
+	}
+
+	opts := storage.ListPromptsOptions{
+		Limit:    limit,
+		Phase:    query.Get("phase"),
+		Provider: query.Get("provider"),
+	}
+	if cursorStr := query.Get("cursor"); cursorStr != "" {
+		cursor, err := storage.DecodeCursor(cursorStr)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, "Invalid 'cursor' parameter")
+			return
+		}
+		opts.After = &cursor
+	}
 
-// 	if limitStr != "" {
-// 		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 && parsedLimit <= 100 {
-// 			limit = parsedLimit
-// 		}
-// 	}
+	total, err := s.store.GetPromptsCount(r.Context())
+	if err != nil {
+		logger.WithError(err).Error("Failed to count prompts")
+		s.writeError(w, http.StatusInternalServerError, "Failed to list prompts")
+		return
+	}
 
-// 	if offsetStr != "" {
-// 		if parsedOffset, err := strconv.Atoi(offsetStr); err == nil && parsedOffset >= 0 {
-// 			offset = parsedOffset
-// 		}
-// 	}
+	prompts, next, err := s.store.ListPromptsPage(r.Context(), opts)
+	if err != nil {
+		logger.WithError(err).Error("Failed to list prompts")
+		s.writeError(w, http.StatusInternalServerError, "Failed to list prompts")
+		return
+	}
 
-// 	prompts, err := s.store.ListPrompts(limit, offset)
-// 	if err != nil {
-// 		s.logger.WithError(err).Error("Failed to list prompts")
-// 		s.writeError(w, http.StatusInternalServerError, "Failed to list prompts")
-// 		return
-// 	}
+	projected, err := httputil.ParseFields(r).Project(prompts)
+	if err != nil {
+		logger.WithError(err).Error("Failed to project prompt fields")
+		s.writeError(w, http.StatusInternalServerError, "Failed to list prompts")
+		return
+	}
 
-// 	response := map[string]interface{}{
-// 		"prompts": prompts,
-// 		"limit":   limit,
-// 		"offset":  offset,
-// 		"count":   len(prompts),
-// 	}
-// 	s.writeJSON(w, http.StatusOK, response)
-// }
+	response := ListPromptsResponse{
+		Prompts:    projected,
+		TotalFound: len(prompts),
+		SearchType: "list",
+		Total:      total,
+		Metadata: SearchMetadata{
+			Phase:      opts.Phase,
+			Provider:   opts.Provider,
+			Limit:      limit,
+			SearchedAt: time.Now(),
+		},
+	}
+	if next != nil {
+		encoded := next.Encode()
+		response.NextCursor = &encoded
+	}
+
+	s.writeJSON(w, http.StatusOK, response)
+}
 
 func (s *SimpleServer) handleCreatePrompt(w http.ResponseWriter, r *http.Request) {
 	var prompt models.Prompt
@@ -463,7 +775,7 @@ func (s *SimpleServer) handleCreatePrompt(w http.ResponseWriter, r *http.Request
 	}
 
 	if err := s.store.SavePrompt(r.Context(), &prompt); err != nil {
-		s.logger.WithError(err).Error("Failed to save prompt")
+		s.requestLogger(r.Context()).WithError(err).Error("Failed to save prompt")
 		s.writeError(w, http.StatusInternalServerError, "Failed to save prompt")
 		return
 	}
@@ -471,117 +783,442 @@ func (s *SimpleServer) handleCreatePrompt(w http.ResponseWriter, r *http.Request
 	s.writeJSON(w, http.StatusCreated, prompt)
 }
 
-// func (s *SimpleServer) handleGetPrompt(w http.ResponseWriter, r *http.Request) {
-// 	idStr := chi.URLParam(r, "id")
-// 	id, err := uuid.Parse(idStr)
-// 	if err != nil {
-// 		s.writeError(w, http.StatusBadRequest, "Invalid prompt ID")
-// 		return
-// 	}
+// handleSessionLineage returns how the prompts of a generation session evolved
+// through the cascade phases. Each chain starts at a first-phase prompt and
+// ends at the final prompt derived from it.
+func (s *SimpleServer) handleSessionLineage(w http.ResponseWriter, r *http.Request) {
+	logger := s.requestLogger(r.Context())
+	sessionID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid session ID")
+		return
+	}
 
-// 	prompt, err := s.store.GetPrompt(id)
-// 	if err != nil {
-// 		if err.Error() == "prompt not found" {
-// 			s.writeError(w, http.StatusNotFound, "Prompt not found")
-// 		} else {
-// 			s.logger.WithError(err).Error("Failed to get prompt")
-// 			s.writeError(w, http.StatusInternalServerError, "Failed to get prompt")
-// 		}
-// 		return
-// 	}
+	chains, err := s.store.GetSessionLineage(r.Context(), sessionID)
+	if err != nil {
+		logger.WithError(err).WithField("session_id", sessionID).Error("Failed to get session lineage")
+		s.writeError(w, http.StatusInternalServerError, "Failed to get session lineage")
+		return
+	}
+	if len(chains) == 0 {
+		s.writeError(w, http.StatusNotFound, "Session not found")
+		return
+	}
 
-// 	s.writeJSON(w, http.StatusOK, prompt)
-// }
+	projected, err := httputil.ParseFields(r).Project(chains)
+	if err != nil {
+		logger.WithError(err).Error("Failed to project prompt fields")
+		s.writeError(w, http.StatusInternalServerError, "Failed to get session lineage")
+		return
+	}
 
-// func (s *SimpleServer) handleUpdatePrompt(w http.ResponseWriter, r *http.Request) {
-// 	idStr := chi.URLParam(r, "id")
-// 	id, err := uuid.Parse(idStr)
-// 	if err != nil {
-// 		s.writeError(w, http.StatusBadRequest, "Invalid prompt ID")
-// 		return
-// 	}
+	s.writeJSON(w, http.StatusOK, SessionLineageResponse{
+		SessionID: sessionID,
+		Chains:    projected,
+		Count:     len(chains),
+	})
+}
 
-// 	// Check if prompt exists
-// 	existingPrompt, err := s.store.GetPrompt(id)
-// 	if err != nil {
-// 		if err.Error() == "prompt not found" {
-// 			s.writeError(w, http.StatusNotFound, "Prompt not found")
-// 		} else {
-// 			s.logger.WithError(err).Error("Failed to get prompt")
-// 			s.writeError(w, http.StatusInternalServerError, "Failed to get prompt")
-// 		}
-// 		return
-// 	}
+// handleStats returns database statistics for dashboards. The
+// include_relationships, include_enhancements and include_usage flags add
+// those sections.
+func (s *SimpleServer) handleStats(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	stats, err := s.store.Statistics(r.Context(), storage.StatisticsOptions{
+		IncludeRelationships: query.Get("include_relationships") == "true",
+		IncludeEnhancements:  query.Get("include_enhancements") == "true",
+		IncludeUsage:         query.Get("include_usage") == "true",
+	})
+	if err != nil {
+		s.requestLogger(r.Context()).WithError(err).Error("Failed to compute database statistics")
+		s.writeError(w, http.StatusInternalServerError, "Failed to compute database statistics")
+		return
+	}
+	s.writeJSON(w, http.StatusOK, stats)
+}
 
-// 	var updatedPrompt models.Prompt
-// 	if err := json.NewDecoder(r.Body).Decode(&updatedPrompt); err != nil {
-// 		s.writeError(w, http.StatusBadRequest, "Invalid JSON payload")
-// 		return
-// 	}
+// handleListTags lists every tag in use with its prompt count and average
+// relevance score, most used first. ?min_count= drops rarely used tags.
+func (s *SimpleServer) handleListTags(w http.ResponseWriter, r *http.Request) {
+	minCount := 1
+	if minCountStr := r.URL.Query().Get("min_count"); minCountStr != "" {
+		parsed, err := strconv.Atoi(minCountStr)
+		if err != nil || parsed < 1 {
+			s.writeError(w, http.StatusBadRequest, "Invalid 'min_count' parameter (must be a positive integer)")
+			return
+		}
+		minCount = parsed
+	}
 
-// 	// Preserve important fields
-// 	updatedPrompt.ID = existingPrompt.ID
-// 	updatedPrompt.CreatedAt = existingPrompt.CreatedAt
-// 	updatedPrompt.UpdatedAt = time.Now()
-// 	updatedPrompt.SessionID = existingPrompt.SessionID
+	tags, err := s.store.TagStats(r.Context(), minCount)
+	if err != nil {
+		s.requestLogger(r.Context()).WithError(err).Error("Failed to compute tag statistics")
+		s.writeError(w, http.StatusInternalServerError, "Failed to compute tag statistics")
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"tags":  tags,
+		"total": len(tags),
+	})
+}
 
-// 	if err := s.store.UpdatePrompt(&updatedPrompt); err != nil {
-// 		s.logger.WithError(err).Error("Failed to update prompt")
-// 		s.writeError(w, http.StatusInternalServerError, "Failed to update prompt")
-// 		return
-// 	}
+// Relationship discovery defaults
+const (
+	defaultRelationshipThreshold = 0.8
+	defaultMaxRelationshipLinks  = 10
+	maxRelationshipLinks         = 100
+)
 
-// 	s.writeJSON(w, http.StatusOK, updatedPrompt)
-// }
+// handleDiscoverRelationships links a prompt to the prompts most similar to
+// it by embedding, recording each as similar_to with the similarity as its
+// strength
+func (s *SimpleServer) handleDiscoverRelationships(w http.ResponseWriter, r *http.Request) {
+	var req DiscoverRelationshipsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+	promptID, err := uuid.Parse(req.PromptID)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid prompt ID")
+		return
+	}
 
-// func (s *SimpleServer) handleDeletePrompt(w http.ResponseWriter, r *http.Request) {
-// 	idStr := chi.URLParam(r, "id")
-// 	id, err := uuid.Parse(idStr)
-// 	if err != nil {
-// 		s.writeError(w, http.StatusBadRequest, "Invalid prompt ID")
-// 		return
-// 	}
+	threshold := viper.GetFloat64("relationships.similarity_threshold")
+	if threshold <= 0 {
+		threshold = defaultRelationshipThreshold
+	}
+	if req.Threshold != nil {
+		if *req.Threshold < 0 || *req.Threshold > 1 {
+			s.writeError(w, http.StatusBadRequest, "threshold must be between 0 and 1")
+			return
+		}
+		threshold = *req.Threshold
+	}
+	maxLinks := req.MaxLinks
+	if maxLinks == 0 {
+		maxLinks = defaultMaxRelationshipLinks
+	}
+	if maxLinks < 0 || maxLinks > maxRelationshipLinks {
+		s.writeError(w, http.StatusBadRequest, fmt.Sprintf("max_links must be between 1 and %d", maxRelationshipLinks))
+		return
+	}
 
-// 	// Check if prompt exists
-// 	if _, err := s.store.GetPrompt(id); err != nil {
-// 		if err.Error() == "prompt not found" {
-// 			s.writeError(w, http.StatusNotFound, "Prompt not found")
-// 		} else {
-// 			s.logger.WithError(err).Error("Failed to get prompt")
-// 			s.writeError(w, http.StatusInternalServerError, "Failed to get prompt")
-// 		}
-// 		return
-// 	}
+	links, err := s.store.DiscoverSimilarRelationships(r.Context(), promptID, threshold, maxLinks)
+	switch {
+	case errors.Is(err, storage.ErrPromptNotFound):
+		s.writeError(w, http.StatusNotFound, "Prompt not found")
+		return
+	case errors.Is(err, storage.ErrPromptNotEmbedded):
+		s.writeError(w, http.StatusUnprocessableEntity, "Prompt has no embedding; rebuild the search index first")
+		return
+	case err != nil:
+		s.requestLogger(r.Context()).WithError(err).WithField("prompt_id", promptID).Error("Failed to discover relationships")
+		s.writeError(w, http.StatusInternalServerError, "Failed to discover relationships")
+		return
+	}
 
-// 	if err := s.store.DeletePrompt(id); err != nil {
-// 		s.logger.WithError(err).Error("Failed to delete prompt")
-// 		s.writeError(w, http.StatusInternalServerError, "Failed to delete prompt")
-// 		return
-// 	}
+	s.writeJSON(w, http.StatusOK, DiscoverRelationshipsResponse{
+		PromptID:      promptID,
+		Relationships: links,
+		Count:         len(links),
+		Threshold:     threshold,
+	})
+}
 
-// 	w.WriteHeader(http.StatusNoContent)
-// }
+// handleStartReindex rebuilds the search index in the background, e.g. after
+// changing embedding models. Progress is reported by handleReindexStatus.
+func (s *SimpleServer) handleStartReindex(w http.ResponseWriter, r *http.Request) {
+	logger := s.requestLogger(r.Context())
+	err := s.store.StartReindex(r.Context(), providers.NewQueryEmbedder(s.registry))
+	switch {
+	case errors.Is(err, storage.ErrReindexRunning), errors.Is(err, storage.ErrBackfillRunning):
+		s.writeError(w, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		logger.WithError(err).Warn("Failed to start search index rebuild")
+		s.writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+
+	logger.Info("Started search index rebuild")
+	s.writeJSON(w, http.StatusAccepted, s.store.ReindexStatus())
+}
+
+// handleReindexStatus reports the progress of the current or last rebuild
+func (s *SimpleServer) handleReindexStatus(w http.ResponseWriter, r *http.Request) {
+	s.writeJSON(w, http.StatusOK, s.store.ReindexStatus())
+}
+
+// PromptDetailResponse is a prompt with its embedding, which models.Prompt
+// leaves out of its JSON, and a summary of the feedback reported for it
+type PromptDetailResponse struct {
+	*models.Prompt
+	Embedding []float32               `json:"embedding,omitempty"`
+	Feedback  *models.FeedbackSummary `json:"feedback"`
+}
+
+// handleGetPrompt returns a prompt with its model metadata, context and
+// feedback summary. The embedding and performance metrics are included only
+// when requested with include_embedding and include_metrics, since
+// embeddings are large.
+func (s *SimpleServer) handleGetPrompt(w http.ResponseWriter, r *http.Request) {
+	logger := s.requestLogger(r.Context())
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid prompt ID")
+		return
+	}
+
+	prompt, err := s.store.GetPromptByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, storage.ErrPromptNotFound) {
+			s.writeError(w, http.StatusNotFound, "Prompt not found")
+		} else {
+			logger.WithError(err).Error("Failed to get prompt")
+			s.writeError(w, http.StatusInternalServerError, "Failed to get prompt")
+		}
+		return
+	}
+	if err := s.store.LoadPromptDetails(r.Context(), prompt); err != nil {
+		logger.WithError(err).WithField("prompt_id", id).Error("Failed to get prompt details")
+		s.writeError(w, http.StatusInternalServerError, "Failed to get prompt")
+		return
+	}
+
+	response := PromptDetailResponse{Prompt: prompt}
+	if response.Feedback, err = s.store.GetPromptFeedbackSummary(r.Context(), id); err != nil {
+		logger.WithError(err).WithField("prompt_id", id).Error("Failed to get prompt feedback")
+		s.writeError(w, http.StatusInternalServerError, "Failed to get prompt")
+		return
+	}
+	if includeEmbedding, _ := strconv.ParseBool(r.URL.Query().Get("include_embedding")); includeEmbedding {
+		if response.Embedding, err = s.store.GetPromptEmbedding(r.Context(), id); err != nil {
+			logger.WithError(err).WithField("prompt_id", id).Error("Failed to get prompt embedding")
+			s.writeError(w, http.StatusInternalServerError, "Failed to get prompt")
+			return
+		}
+	}
+	if includeMetrics, _ := strconv.ParseBool(r.URL.Query().Get("include_metrics")); includeMetrics {
+		if prompt.Metrics, err = s.store.GetPromptMetrics(r.Context(), prompt); err != nil {
+			logger.WithError(err).WithField("prompt_id", id).Error("Failed to get prompt metrics")
+			s.writeError(w, http.StatusInternalServerError, "Failed to get prompt")
+			return
+		}
+	}
+
+	s.writeJSON(w, http.StatusOK, response)
+}
+
+// maxPromptTemperature is the highest temperature any provider accepts
+const maxPromptTemperature = 2.0
+
+// validatePromptSettings checks a prompt's generation settings, including
+// max_tokens against its model's output limit when the model is known
+func validatePromptSettings(p *models.Prompt) error {
+	if p.Temperature < 0 || p.Temperature > maxPromptTemperature {
+		return fmt.Errorf("temperature must be between 0 and %.0f, got %g", maxPromptTemperature, p.Temperature)
+	}
+	if p.MaxTokens < 0 {
+		return fmt.Errorf("max_tokens must not be negative, got %d", p.MaxTokens)
+	}
+	if limits, ok := providers.LookupModelLimits(p.Provider, p.Model); ok && p.MaxTokens > limits.MaxOutputTokens {
+		return fmt.Errorf("max_tokens %d exceeds the %d output token limit of %s/%s", p.MaxTokens, limits.MaxOutputTokens, p.Provider, p.Model)
+	}
+	return nil
+}
+
+// handleUpdatePrompt replaces a prompt, keeping its ID, creation time and
+// session
+func (s *SimpleServer) handleUpdatePrompt(w http.ResponseWriter, r *http.Request) {
+	logger := s.requestLogger(r.Context())
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid prompt ID")
+		return
+	}
+
+	// Check if prompt exists
+	existingPrompt, err := s.store.GetPromptByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, storage.ErrPromptNotFound) {
+			s.writeError(w, http.StatusNotFound, "Prompt not found")
+		} else {
+			logger.WithError(err).Error("Failed to get prompt")
+			s.writeError(w, http.StatusInternalServerError, "Failed to get prompt")
+		}
+		return
+	}
+
+	var updatedPrompt models.Prompt
+	if err := json.NewDecoder(r.Body).Decode(&updatedPrompt); err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+	if strings.TrimSpace(updatedPrompt.Content) == "" {
+		s.writeError(w, http.StatusBadRequest, "Content is required")
+		return
+	}
+	if err := validatePromptSettings(&updatedPrompt); err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Preserve important fields
+	updatedPrompt.ID = existingPrompt.ID
+	updatedPrompt.CreatedAt = existingPrompt.CreatedAt
+	updatedPrompt.SessionID = existingPrompt.SessionID
+
+	if err := s.store.UpdatePrompt(r.Context(), &updatedPrompt); err != nil {
+		if errors.Is(err, storage.ErrContentTooLarge) {
+			s.writeError(w, http.StatusRequestEntityTooLarge, err.Error())
+			return
+		}
+		logger.WithError(err).Error("Failed to update prompt")
+		s.writeError(w, http.StatusInternalServerError, "Failed to update prompt")
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, updatedPrompt)
+}
+
+// handleDeletePrompt deletes a prompt along with the relationships and
+// history that reference it
+func (s *SimpleServer) handleDeletePrompt(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid prompt ID")
+		return
+	}
+
+	if err := s.store.DeletePrompt(r.Context(), id.String()); err != nil {
+		if errors.Is(err, storage.ErrPromptNotFound) {
+			s.writeError(w, http.StatusNotFound, "Prompt not found")
+			return
+		}
+		s.requestLogger(r.Context()).WithError(err).Error("Failed to delete prompt")
+		s.writeError(w, http.StatusInternalServerError, "Failed to delete prompt")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PromptVersionsResponse lists the earlier versions of a prompt, oldest first
+type PromptVersionsResponse struct {
+	PromptID uuid.UUID              `json:"prompt_id"`
+	Versions []models.PromptVersion `json:"versions"`
+	Count    int                    `json:"count"`
+}
+
+func (s *SimpleServer) handleListPromptVersions(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid prompt ID")
+		return
+	}
+
+	versions, err := s.store.GetPromptVersions(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, storage.ErrPromptNotFound) {
+			s.writeError(w, http.StatusNotFound, "Prompt not found")
+			return
+		}
+		s.requestLogger(r.Context()).WithError(err).WithField("prompt_id", id).Error("Failed to get prompt versions")
+		s.writeError(w, http.StatusInternalServerError, "Failed to get prompt versions")
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, PromptVersionsResponse{
+		PromptID: id,
+		Versions: versions,
+		Count:    len(versions),
+	})
+}
+
+// handleRestorePromptVersion makes an earlier version current. The replaced
+// content is kept as a new version.
+func (s *SimpleServer) handleRestorePromptVersion(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid prompt ID")
+		return
+	}
+	version, err := strconv.Atoi(chi.URLParam(r, "version"))
+	if err != nil || version < 1 {
+		s.writeError(w, http.StatusBadRequest, "Invalid version")
+		return
+	}
+
+	prompt, err := s.store.RestorePromptVersion(r.Context(), id, version)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrPromptNotFound):
+			s.writeError(w, http.StatusNotFound, "Prompt not found")
+		case errors.Is(err, storage.ErrVersionNotFound):
+			s.writeError(w, http.StatusNotFound, "Version not found")
+		default:
+			s.requestLogger(r.Context()).WithError(err).WithField("prompt_id", id).Error("Failed to restore prompt version")
+			s.writeError(w, http.StatusInternalServerError, "Failed to restore prompt version")
+		}
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, prompt)
+}
 
 func (s *SimpleServer) handleGeneratePrompts(w http.ResponseWriter, r *http.Request) {
-	s.logger.Info("=== GENERATE ENDPOINT CALLED ===")
+	logger := s.requestLogger(r.Context())
+	logger.Info("=== GENERATE ENDPOINT CALLED ===")
 
 	var req GenerateRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		s.writeError(w, http.StatusBadRequest, "Invalid JSON payload")
 		return
 	}
+	format, err := generateResponseFormat(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// A retry with the same Idempotency-Key gets the first response back
+	// instead of generating (and paying for) the prompts again
+	idempotencyKey := r.Header.Get(IdempotencyKeyHeader)
+	var requestHash string
+	if idempotencyKey != "" {
+		if err := validateIdempotencyKey(idempotencyKey); err != nil {
+			s.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		hash, err := idempotencyRequestHash(req, r.URL.RawQuery)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, "Invalid JSON payload")
+			return
+		}
+		requestHash = hash
+	}
 
 	// DEBUG: Log request details
-	s.logger.WithFields(logrus.Fields{
+	logger.WithFields(logrus.Fields{
 		"providers_nil":   req.Providers == nil,
 		"providers_len":   len(req.Providers),
 		"providers_value": req.Providers,
 		"input":           req.Input,
 	}).Info("DEBUG: Received generate request")
 
-	// Validate required fields
-	if req.Input == "" {
-		s.writeError(w, http.StatusBadRequest, "Input is required")
+	var fields models.FieldErrors
+	if err := models.ValidateGenerateRequest(models.GenerateRequest{
+		Input:          req.Input,
+		Phases:         req.Phases,
+		Count:          req.Count,
+		Temperature:    req.Temperature,
+		MaxTokens:      req.MaxTokens,
+		Context:        req.Context,
+		Persona:        req.Persona,
+		PhaseSelection: req.PhaseSelection,
+	}, s.engine.KnownPhase); errors.As(err, &fields) {
+		s.writeValidationError(w, err.Error(), fields)
 		return
 	}
 
@@ -592,44 +1229,8 @@ func (s *SimpleServer) handleGeneratePrompts(w http.ResponseWriter, r *http.Requ
 	if req.Temperature == 0 {
 		req.Temperature = 0.7
 	}
-
-	// Provider-specific temperature validation
-	// Determine the primary provider for temperature validation
-	primaryProvider := ""
-	if len(req.Providers) > 0 {
-		// If specific providers are set, use the first one for validation
-		for _, provider := range req.Providers {
-			primaryProvider = provider
-			break
-		}
-	}
-
-	// Validate and adjust temperature based on provider constraints
-	originalTemp := req.Temperature
-	adjustedTemp := false
-
-	switch primaryProvider {
-	case "anthropic":
-		if req.Temperature > 1.0 {
-			req.Temperature = 1.0
-			adjustedTemp = true
-		}
-	case "openai", "google", "ollama", "openrouter", "grok", "":
-		// These providers support 0-2 range, no adjustment needed for typical values
-		if req.Temperature > 2.0 {
-			req.Temperature = 2.0
-			adjustedTemp = true
-		}
-	}
-
-	// Log temperature adjustment for debugging
-	if adjustedTemp {
-		s.logger.WithFields(logrus.Fields{
-			"original_temperature": originalTemp,
-			"adjusted_temperature": req.Temperature,
-			"provider":             primaryProvider,
-		}).Warn("Temperature automatically adjusted for provider compatibility")
-	}
+	// The engine clamps the temperature to each phase's provider's range
+	explicitMaxTokens := req.MaxTokens > 0
 	if req.MaxTokens <= 0 {
 		req.MaxTokens = 2000
 	}
@@ -645,10 +1246,34 @@ func (s *SimpleServer) handleGeneratePrompts(w http.ResponseWriter, r *http.Requ
 	if req.Context == nil {
 		req.Context = []string{}
 	}
+	if req.PhaseSelection == "" {
+		req.PhaseSelection = viper.GetString("generation.phase_selection")
+	}
+	if req.PhaseSelection == "" {
+		req.PhaseSelection = models.PhaseSelectionAll
+	}
+	if req.MinConfidence == 0 {
+		req.MinConfidence = viper.GetFloat64("generation.min_judge_confidence")
+	}
+	if req.MinConfidence < 0 || req.MinConfidence > 1 {
+		s.writeError(w, http.StatusBadRequest, "min_confidence must be between 0 and 1")
+		return
+	}
+	fallbackToRanker := viper.GetBool("generation.judge_fallback_to_ranker")
+	if req.FallbackToRanker != nil {
+		fallbackToRanker = *req.FallbackToRanker
+	}
 	// Save defaults to true (recommended)
 	if !r.URL.Query().Has("save") {
 		req.Save = true
 	}
+	if req.SavePhases == nil {
+		req.SavePhases = viper.GetStringSlice("generation.save_phases")
+	}
+	if err := models.ValidateSavePhases(req.SavePhases); err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
 	// Parallel processing defaults to true (recommended)
 	if !r.URL.Query().Has("use_parallel") {
@@ -684,7 +1309,7 @@ func (s *SimpleServer) handleGeneratePrompts(w http.ResponseWriter, r *http.Requ
 	}
 
 	// Log provider request details for debugging
-	s.logger.WithFields(logrus.Fields{
+	logger.WithFields(logrus.Fields{
 		"req_providers": req.Providers,
 		"providers_nil": req.Providers == nil,
 		"providers_len": len(req.Providers),
@@ -692,21 +1317,19 @@ func (s *SimpleServer) handleGeneratePrompts(w http.ResponseWriter, r *http.Requ
 
 	// If no providers were specified in request, read from viper configuration
 	if len(req.Providers) == 0 {
-		s.logger.Info("No providers specified in request, reading from viper configuration")
+		logger.Info("No providers specified in request, reading from viper configuration")
 		// Read directly from viper with logging
 		for i, phase := range phases {
-			viperKey := "phases." + string(phase) + ".provider"
-			provider := viper.GetString(viperKey)
-			s.logger.WithFields(logrus.Fields{
-				"phase":     phase,
-				"viper_key": viperKey,
-				"provider":  provider,
+			provider := helpers.PhaseProvider(phase)
+			logger.WithFields(logrus.Fields{
+				"phase":    phase,
+				"provider": provider,
 			}).Info("Reading phase provider from viper")
 
 			// Fallback to openai if viper returns empty (more reliable than ollama)
 			if provider == "" {
 				provider = "openai"
-				s.logger.WithField("phase", phase).Info("Using fallback provider: openai")
+				logger.WithField("phase", phase).Info("Using fallback provider: openai")
 			}
 
 			phaseConfigs[i] = models.PhaseConfig{
@@ -718,18 +1341,16 @@ func (s *SimpleServer) handleGeneratePrompts(w http.ResponseWriter, r *http.Requ
 		// For each phase, if no provider specified, read from viper
 		for i, config := range phaseConfigs {
 			if config.Provider == "" {
-				viperKey := "phases." + string(config.Phase) + ".provider"
-				provider := viper.GetString(viperKey)
-				s.logger.WithFields(logrus.Fields{
-					"phase":     config.Phase,
-					"viper_key": viperKey,
-					"provider":  provider,
+				provider := helpers.PhaseProvider(config.Phase)
+				logger.WithFields(logrus.Fields{
+					"phase":    config.Phase,
+					"provider": provider,
 				}).Info("Reading missing phase provider from viper")
 
 				// Fallback to openai if viper returns empty (more reliable than ollama)
 				if provider == "" {
 					provider = "openai"
-					s.logger.WithField("phase", config.Phase).Info("Using fallback provider: openai")
+					logger.WithField("phase", config.Phase).Info("Using fallback provider: openai")
 				}
 
 				phaseConfigs[i].Provider = provider
@@ -737,54 +1358,193 @@ func (s *SimpleServer) handleGeneratePrompts(w http.ResponseWriter, r *http.Requ
 		}
 	}
 
-	// Build provider map for PromptRequest
-	providerMap := make(map[models.Phase]string)
-	for _, config := range phaseConfigs {
-		if config.Provider != "" {
-			providerMap[config.Phase] = config.Provider
+	// Check max_tokens against the output limit of every phase's model. An
+	// explicit request the model cannot honor is a client error; the default
+	// is simply lowered to fit.
+	for _, config := range phaseConfigs {
+		model := providers.ConfiguredModel(config.Provider)
+		limits, ok := providers.LookupModelLimits(config.Provider, model)
+		if !ok || req.MaxTokens <= limits.MaxOutputTokens {
+			continue
+		}
+		if explicitMaxTokens {
+			s.writeError(w, http.StatusBadRequest, fmt.Sprintf(
+				"max_tokens %d exceeds the %d output tokens supported by %s model %s (phase %s)",
+				req.MaxTokens, limits.MaxOutputTokens, config.Provider, model, config.Phase))
+			return
+		}
+		logger.WithFields(logrus.Fields{
+			"original_max_tokens": req.MaxTokens,
+			"adjusted_max_tokens": limits.MaxOutputTokens,
+			"provider":            config.Provider,
+			"model":               model,
+		}).Warn("Default max_tokens lowered to the model's output limit")
+		req.MaxTokens = limits.MaxOutputTokens
+	}
+
+	// Build provider map for PromptRequest
+	providerMap := make(map[models.Phase]string)
+	for _, config := range phaseConfigs {
+		if config.Provider != "" {
+			providerMap[config.Phase] = config.Provider
+		}
+	}
+
+	// Create session ID
+	sessionID := uuid.New()
+
+	// Create PromptRequest
+	promptRequest := models.PromptRequest{
+		Input:       req.Input,
+		Phases:      phases,
+		Count:       req.Count,
+		Providers:   providerMap,
+		Temperature: req.Temperature,
+		MaxTokens:   req.MaxTokens,
+		Tags:        req.Tags,
+		Context:     req.Context,
+		SessionID:   sessionID,
+
+		Variables:             req.Variables,
+		AllowMissingVariables: req.AllowMissing,
+		Seed:                  req.Seed,
+		ContextDocumentIDs:    req.ContextDocumentIDs,
+	}
+	if _, err := promptRequest.ResolveInput(); err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	systemPrompts, err := models.SystemPromptOverrides(req.SystemPrompts)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Create GenerateOptions for engine
+	generateOpts := models.GenerateOptions{
+		Request:              promptRequest,
+		PhaseConfigs:         convertToProviderPhaseConfigs(phaseConfigs),
+		UseParallel:          req.UseParallel,
+		IncludeContext:       true,
+		Persona:              req.Persona,
+		TargetModel:          req.TargetModel,
+		AutoSummarizeContext: req.AutoSummarizeContext,
+		SystemPrompts:        systemPrompts,
+		TruncateOnOverflow:   req.TruncateOnOverflow,
+		PhaseSelection:       req.PhaseSelection,
+		BypassSemanticCache:  req.BypassCache || noCache(r),
+	}
+
+	if dryRun, err := strconv.ParseBool(r.URL.Query().Get("dry_run")); err == nil {
+		req.DryRun = dryRun
+	}
+	if explain, err := strconv.ParseBool(r.URL.Query().Get("explain")); err == nil {
+		req.Explain = explain
+	}
+	if req.DryRun {
+		s.writeGenerateEstimate(w, req, generateOpts, phaseConfigs)
+		return
+	}
+
+	idempotencyCompleted := false
+	if idempotencyKey != "" {
+		if s.reserveIdempotencyKey(w, r, idempotencyKey, requestHash) {
+			return
+		}
+		defer func() {
+			if !idempotencyCompleted {
+				s.releaseIdempotencyKey(r.Context(), idempotencyKey)
+			}
+		}()
+	}
+
+	// In streaming mode each finished phase is pushed to the client as an
+	// event, and a client disconnect cancels the remaining provider calls.
+	// Either way the request ID and trace are kept so every layer's logs and
+	// spans can be correlated.
+	ctx := tracing.WithSpanFrom(log.WithRequestID(context.Background(), middleware.GetReqID(r.Context())), r.Context())
+	streaming := wantsEventStream(r)
+	if streaming {
+		if _, ok := w.(http.Flusher); !ok {
+			s.writeError(w, http.StatusInternalServerError, "Streaming is not supported by this connection")
+			return
+		}
+		ctx = r.Context()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+
+		generateOpts.OnPhaseComplete = func(phase models.Phase, prompts []models.Prompt) {
+			event := GeneratePhaseEvent{
+				Phase:     string(phase),
+				Prompts:   make([]models.Prompt, len(prompts)),
+				SessionID: sessionID,
+			}
+			copy(event.Prompts, prompts)
+			for i := range event.Prompts {
+				// Cached prompts keep the session they were generated in
+				if event.Prompts[i].SessionID == uuid.Nil {
+					event.Prompts[i].SessionID = sessionID
+				}
+			}
+			s.writeEvent(w, "phase", event)
+		}
+
+		// With parallel generation the variants stream concurrently
+		var tokenMu sync.Mutex
+		generateOpts.OnToken = func(phase models.Phase, index int, delta string) {
+			tokenMu.Lock()
+			defer tokenMu.Unlock()
+			s.writeEvent(w, "token", GenerateTokenEvent{Phase: string(phase), Index: index, Text: delta})
 		}
 	}
 
-	// Create session ID
-	sessionID := uuid.New()
-
-	// Create PromptRequest
-	promptRequest := models.PromptRequest{
-		Input:       req.Input,
-		Phases:      phases,
-		Count:       req.Count,
-		Providers:   providerMap,
-		Temperature: req.Temperature,
-		MaxTokens:   req.MaxTokens,
-		Tags:        req.Tags,
-		Context:     req.Context,
-		SessionID:   sessionID,
+	// Report each phase to the web UI's flow status endpoints as it runs
+	flowID := sessionID.String()
+	s.flows.Start(flowID, phases)
+	generateOpts.OnPhaseStart = func(phase models.Phase) {
+		s.flows.PhaseStarted(flowID, phase)
 	}
-
-	// Create GenerateOptions for engine
-	generateOpts := models.GenerateOptions{
-		Request:        promptRequest,
-		PhaseConfigs:   convertToProviderPhaseConfigs(phaseConfigs),
-		UseParallel:    req.UseParallel,
-		IncludeContext: true,
-		Persona:        req.Persona,
-		TargetModel:    req.TargetModel,
+	onPhaseComplete := generateOpts.OnPhaseComplete
+	generateOpts.OnPhaseComplete = func(phase models.Phase, prompts []models.Prompt) {
+		s.flows.PhaseCompleted(flowID, phase, len(prompts))
+		if onPhaseComplete != nil {
+			onPhaseComplete(phase, prompts)
+		}
 	}
 
 	// Time the generation
 	startTime := time.Now()
 
 	// Generate prompts using the engine
-	ctx := context.Background()
 	result, err := s.engine.Generate(ctx, generateOpts)
+	s.flows.Finish(flowID, err)
+	s.recordGenerateRequest(ctx, err)
 	if err != nil {
-		s.logger.WithError(err).Error("Failed to generate prompts")
-		s.writeError(w, http.StatusInternalServerError, fmt.Sprintf("Generation failed: %v", err))
+		if streaming && ctx.Err() != nil {
+			logger.WithField("session_id", sessionID).Info("Client disconnected, generation cancelled")
+			return
+		}
+		logger.WithError(err).Error("Failed to generate prompts")
+		apiErr := apiErrorFrom(err, fmt.Sprintf("Generation failed: %v", err))
+		if streaming {
+			s.writeEvent(w, "error", errorResponse(w, apiErr))
+			return
+		}
+		s.writeAPIError(w, apiErr)
 		return
 	}
 
 	generationTime := time.Since(startTime)
 
+	// A cached generation answers with the session it was generated in, if
+	// the request that generated it had one
+	if result.Cached && result.SessionID != uuid.Nil {
+		sessionID = result.SessionID
+	}
+
 	// Assign session ID to all generated prompts
 	for i := range result.Prompts {
 		result.Prompts[i].SessionID = sessionID
@@ -792,7 +1552,7 @@ func (s *SimpleServer) handleGeneratePrompts(w http.ResponseWriter, r *http.Requ
 
 	// Apply historical optimization if enabled
 	if req.UseOptimization && len(result.Prompts) > 0 {
-		s.logger.Info("Applying historical optimization...")
+		logger.Info("Applying historical optimization...")
 
 		// Apply vector-based similarity search for optimization
 		for i := range result.Prompts {
@@ -811,7 +1571,7 @@ func (s *SimpleServer) handleGeneratePrompts(w http.ResponseWriter, r *http.Requ
 			}
 		}
 
-		s.logger.WithFields(logrus.Fields{
+		logger.WithFields(logrus.Fields{
 			"similarity_threshold": req.SimilarityThreshold,
 			"historical_weight":    req.HistoricalWeight,
 		}).Info("Historical optimization applied")
@@ -819,10 +1579,10 @@ func (s *SimpleServer) handleGeneratePrompts(w http.ResponseWriter, r *http.Requ
 
 	// Rank prompts if ranker is available
 	if s.ranker != nil {
-		s.logger.Info("Ranking prompts...")
-		rankings, err := s.ranker.RankPrompts(ctx, result.Prompts, promptRequest.Input)
+		logger.Info("Ranking prompts...")
+		rankings, err := s.ranker.RankPromptsWithOptions(ctx, result.Prompts, promptRequest.Input, ranking.RankOptions{Explain: req.Explain})
 		if err != nil {
-			s.logger.WithError(err).Warn("Failed to rank prompts, continuing without rankings")
+			logger.WithError(err).Warn("Failed to rank prompts, continuing without rankings")
 		} else {
 			result.Rankings = rankings
 
@@ -833,9 +1593,17 @@ func (s *SimpleServer) handleGeneratePrompts(w http.ResponseWriter, r *http.Requ
 		}
 	}
 
+	selectionSource := ""
+	if result.Selected != nil {
+		selectionSource = "ranker"
+	}
+	var judgeConfidence, consensusRate float64
+	var judges []string
+	lowConfidence := false
+
 	// Use AI selector for judging if enabled
 	if req.EnableJudging && len(result.Prompts) > 0 {
-		s.logger.Info("Using AI selector for prompt evaluation...")
+		logger.Info("Using AI selector for prompt evaluation...")
 		aiSelector := selection.NewAISelector(s.registry)
 
 		// Build evaluation criteria
@@ -846,20 +1614,25 @@ func (s *SimpleServer) handleGeneratePrompts(w http.ResponseWriter, r *http.Requ
 
 		scoringCriteria := req.ScoringCriteria
 		if scoringCriteria == "" {
-			scoringCriteria = "comprehensive"
+			scoringCriteria = selection.DefaultWeightPreset
+			if persona, ok := models.CustomPersona(models.PersonaType(req.Persona)); ok && persona.ScoringCriteria != "" {
+				scoringCriteria = persona.ScoringCriteria
+			}
 		}
 
 		// Set weights based on scoring criteria
-		var weights selection.EvaluationWeights
-		switch scoringCriteria {
-		case "clarity":
-			weights = selection.EvaluationWeights{Relevance: 0.2, Clarity: 0.5, Completeness: 0.2, Conciseness: 0.1, Toxicity: 0.0}
-		case "creativity":
-			weights = selection.EvaluationWeights{Relevance: 0.3, Clarity: 0.2, Completeness: 0.3, Conciseness: 0.1, Toxicity: 0.1}
-		case "effectiveness":
-			weights = selection.EvaluationWeights{Relevance: 0.4, Clarity: 0.3, Completeness: 0.2, Conciseness: 0.1, Toxicity: 0.0}
-		default: // comprehensive
-			weights = selection.EvaluationWeights{Relevance: 0.3, Clarity: 0.25, Completeness: 0.25, Conciseness: 0.15, Toxicity: 0.05}
+		weights, ok := s.weightPresets.Get(scoringCriteria)
+		if !ok {
+			logger.WithField("scoring_criteria", scoringCriteria).Warnf("Unknown scoring criteria, using %s weights", selection.DefaultWeightPreset)
+		}
+
+		judgeProviders := req.JudgeProviders
+		if judgeProviders == nil {
+			judgeProviders = viper.GetStringSlice("generation.judge_providers")
+		}
+		judgeWeights := req.JudgeWeights
+		if judgeWeights == nil {
+			judgeWeights = judgeWeightsConfig()
 		}
 
 		criteria := selection.SelectionCriteria{
@@ -872,12 +1645,14 @@ func (s *SimpleServer) handleGeneratePrompts(w http.ResponseWriter, r *http.Requ
 			EvaluationModel:    "claude-3-5-sonnet-latest",
 			EvaluationProvider: judgeProvider,
 			Weights:            weights,
+			JudgeProviders:     judgeProviders,
+			JudgeWeights:       judgeWeights,
 		}
 
 		// Perform AI evaluation
 		selectionResult, err := aiSelector.Select(ctx, result.Prompts, criteria)
 		if err != nil {
-			s.logger.WithError(err).Warn("Failed to evaluate prompts with AI selector, continuing without evaluation")
+			logger.WithError(err).Warn("Failed to evaluate prompts with AI selector, continuing without evaluation")
 		} else {
 			// Update prompts with evaluation scores and reasoning
 			for i := range result.Prompts {
@@ -891,15 +1666,29 @@ func (s *SimpleServer) handleGeneratePrompts(w http.ResponseWriter, r *http.Requ
 				}
 			}
 
-			// Update selected prompt with AI evaluation
-			if selectionResult.SelectedPrompt != nil {
+			judgeConfidence = selectionResult.Confidence
+			if len(selectionResult.Judges) > 1 {
+				judges = selectionResult.Judges
+				consensusRate = selectionResult.ConsensusRate
+			}
+			lowConfidence = req.MinConfidence > 0 && judgeConfidence < req.MinConfidence
+
+			// Update selected prompt with AI evaluation, unless the judge is too
+			// unsure and we have the ranker's choice to fall back on
+			if lowConfidence && fallbackToRanker && result.Selected != nil {
+				logger.WithFields(logrus.Fields{
+					"confidence_score": judgeConfidence,
+					"min_confidence":   req.MinConfidence,
+				}).Warn("Judge confidence below threshold, keeping ranker selection")
+			} else if selectionResult.SelectedPrompt != nil {
 				result.Selected = selectionResult.SelectedPrompt
 				// Ensure the selected prompt has the evaluation data
 				result.Selected.Score = selectionResult.Confidence
 				result.Selected.Reasoning = selectionResult.Reasoning
+				selectionSource = "judge"
 			}
 
-			s.logger.WithFields(logrus.Fields{
+			logger.WithFields(logrus.Fields{
 				"selected_prompt_id": selectionResult.SelectedPrompt.ID,
 				"confidence_score":   selectionResult.Confidence,
 				"processing_time_ms": selectionResult.ProcessingTime,
@@ -907,15 +1696,15 @@ func (s *SimpleServer) handleGeneratePrompts(w http.ResponseWriter, r *http.Requ
 		}
 	}
 
-	// Save prompts if requested
+	// Save prompts if requested, limited to the phases asked for. Cached
+	// prompts may have been saved by the request that generated them.
+	var deduplicated []storage.Duplicate
 	if req.Save {
-		for i := range result.Prompts {
-			prompt := &result.Prompts[i]
-			if err := s.store.SavePrompt(ctx, prompt); err != nil {
-				s.logger.WithError(err).WithField("prompt_id", prompt.ID).Error("Failed to save prompt")
-				// Continue with other prompts even if one fails
-			}
+		toSave := models.PromptsToSave(result.Prompts, result.Selected, req.SavePhases)
+		if result.Cached {
+			toSave = s.unsavedPrompts(ctx, toSave)
 		}
+		deduplicated = s.savePrompts(ctx, toSave)
 	}
 
 	// Build providers used map
@@ -926,32 +1715,61 @@ func (s *SimpleServer) handleGeneratePrompts(w http.ResponseWriter, r *http.Requ
 		}
 	}
 
-	// Create response
+	// Create response. With best or cascade selection only the best prompt
+	// of each phase is returned, but every one generated was paid for.
+	generated := result.Prompts
+	if result.Candidates != nil {
+		generated = result.Candidates
+	}
+	usage := models.SummarizeUsage(generated)
+	if result.Cached {
+		// Nothing was generated, so nothing was paid for
+		usage = models.UsageSummary{}
+	}
 	response := GenerateResponse{
 		Prompts:   result.Prompts,
 		Rankings:  result.Rankings,
 		Selected:  result.Selected,
 		SessionID: sessionID,
 		Metadata: GenerateMetadata{
-			TotalGenerated:   len(result.Prompts),
-			PhasesTiming:     map[string]int{"total": int(generationTime.Milliseconds())},
-			ProvidersUsed:    providersUsed,
-			GeneratedAt:      time.Now(),
-			Duration:         generationTime.String(),
-			PhaseCount:       len(req.Phases),
-			Timestamp:        time.Now(),
-			OptimizationUsed: req.UseOptimization,
-			JudgingUsed:      req.EnableJudging,
+			TotalGenerated:         len(generated),
+			PhasesTiming:           map[string]int{"total": int(generationTime.Milliseconds())},
+			ProvidersUsed:          providersUsed,
+			GeneratedAt:            time.Now(),
+			Duration:               generationTime.String(),
+			PhaseCount:             len(req.Phases),
+			Timestamp:              time.Now(),
+			OptimizationUsed:       req.UseOptimization,
+			JudgingUsed:            req.EnableJudging,
+			JudgeConfidence:        judgeConfidence,
+			LowConfidence:          lowConfidence,
+			SelectionSource:        selectionSource,
+			Judges:                 judges,
+			ConsensusRate:          consensusRate,
+			TotalInputTokens:       usage.InputTokens,
+			TotalOutputTokens:      usage.OutputTokens,
+			EstimatedCostUSD:       usage.CostUSD,
+			CostEstimated:          usage.Estimated,
+			ContextSummarized:      result.ContextSummarized,
+			ContextTokensSaved:     result.ContextTokensSaved,
+			TemperatureAdjustments: result.TemperatureAdjustments,
+			PhaseTimeouts:          result.PhaseTimeouts,
+			ModerationDropped:      result.ModerationDropped,
+			Deduplicated:           deduplicated,
+			RetrievedContext:       result.RetrievedContext,
+			Cached:                 result.Cached,
+			CacheSimilarity:        result.CacheSimilarity,
 			RequestOptions: GenerateRequestSummary{
-				Phases:      req.Phases,
-				Count:       req.Count,
-				Persona:     req.Persona,
-				TargetModel: req.TargetModel,
+				Phases:         req.Phases,
+				Count:          req.Count,
+				Persona:        req.Persona,
+				TargetModel:    req.TargetModel,
+				PhaseSelection: req.PhaseSelection,
 			},
 		},
 	}
 
-	s.logger.WithFields(logrus.Fields{
+	logger.WithFields(logrus.Fields{
 		"session_id":        sessionID,
 		"prompts_generated": len(result.Prompts),
 		"generation_time":   generationTime,
@@ -959,9 +1777,103 @@ func (s *SimpleServer) handleGeneratePrompts(w http.ResponseWriter, r *http.Requ
 		"persona":           req.Persona,
 	}).Info("Prompt generation completed successfully")
 
+	// Saved before the response is written so a retry can't slip in between
+	if idempotencyKey != "" {
+		s.completeIdempotencyKey(r.Context(), idempotencyKey, response)
+		idempotencyCompleted = true
+	}
+
+	if streaming {
+		s.writeEvent(w, "done", response)
+		return
+	}
+	if format == responseFormatMarkdown {
+		s.writeMarkdown(w, response)
+		return
+	}
 	s.writeJSON(w, http.StatusOK, response)
 }
 
+// writeGenerateEstimate answers a dry run with the plan the engine would
+// follow and its projected token usage and cost
+func (s *SimpleServer) writeGenerateEstimate(w http.ResponseWriter, req GenerateRequest, opts models.GenerateOptions, phaseConfigs []models.PhaseConfig) {
+	estimate, err := s.engine.Estimate(opts)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	providersUsed := make(map[string]string)
+	for _, phase := range estimate.Phases {
+		providersUsed[string(phase.Phase)] = phase.Provider
+	}
+	now := time.Now()
+	s.writeJSON(w, http.StatusOK, GenerateResponse{
+		Prompts: []models.Prompt{},
+		Metadata: GenerateMetadata{
+			ProvidersUsed:     providersUsed,
+			GeneratedAt:       now,
+			Duration:          "0s",
+			PhaseCount:        len(phaseConfigs),
+			Timestamp:         now,
+			TotalInputTokens:  estimate.InputTokens,
+			TotalOutputTokens: estimate.OutputTokens,
+			EstimatedCostUSD:  estimate.CostUSD,
+			CostEstimated:     true,
+			DryRun:            true,
+			Estimate:          estimate,
+			RequestOptions: GenerateRequestSummary{
+				Phases:      req.Phases,
+				Count:       req.Count,
+				Persona:     req.Persona,
+				TargetModel: req.TargetModel,
+			},
+		},
+	})
+}
+
+// judgeWeightsConfig returns generation.judge_weights, the default weight
+// of each judge provider
+func judgeWeightsConfig() map[string]float64 {
+	weights := make(map[string]float64)
+	for judge := range viper.GetStringMap("generation.judge_weights") {
+		weights[judge] = viper.GetFloat64("generation.judge_weights." + judge)
+	}
+	return weights
+}
+
+// wantsEventStream reports whether the client asked for Server-Sent Events,
+// either with ?stream=true or an Accept: text/event-stream header
+func wantsEventStream(r *http.Request) bool {
+	if stream, err := strconv.ParseBool(r.URL.Query().Get("stream")); err == nil {
+		return stream
+	}
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+// noCache reports whether the client sent Cache-Control: no-cache, asking
+// for a fresh generation rather than a cached one
+func noCache(r *http.Request) bool {
+	for _, directive := range strings.Split(r.Header.Get("Cache-Control"), ",") {
+		if strings.EqualFold(strings.TrimSpace(directive), "no-cache") {
+			return true
+		}
+	}
+	return false
+}
+
+// writeEvent sends a named Server-Sent Event with a JSON payload and flushes
+// it. The event stream headers must already have been written.
+func (s *SimpleServer) writeEvent(w http.ResponseWriter, event string, data interface{}) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		s.logger.WithError(err).WithField("event", event).Error("Failed to encode event")
+		return
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+	w.(http.Flusher).Flush()
+}
+
 // Helper functions
 func (s *SimpleServer) writeJSON(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
@@ -971,13 +1883,37 @@ func (s *SimpleServer) writeJSON(w http.ResponseWriter, status int, data interfa
 	}
 }
 
-func (s *SimpleServer) writeError(w http.ResponseWriter, status int, message string) {
-	response := map[string]interface{}{
-		"error":     message,
-		"status":    status,
-		"timestamp": time.Now(),
+// requireJSON rejects request bodies that are not declared as JSON or JSON
+// Lines with 415, in the same error shape as the rest of the server's
+// responses
+func (s *SimpleServer) requireJSON(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !httputil.HasJSONBody(r) && !httputil.IsJSONLinesContentType(r.Header.Get("Content-Type")) {
+			s.writeError(w, http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// contextOverflowDetails describes which phase and provider overflowed the
+// context window, and by roughly how many tokens
+func contextOverflowDetails(overflow *providers.ContextTooLongError) map[string]interface{} {
+	return map[string]interface{}{
+		"phase":           overflow.Phase,
+		"provider":        overflow.Provider,
+		"model":           overflow.Model,
+		"context_window":  overflow.ContextWindow,
+		"input_tokens":    overflow.InputTokens,
+		"overflow_tokens": overflow.OverflowTokens(),
 	}
-	s.writeJSON(w, status, response)
+}
+
+// requestLogger returns the server's logger tagged with the request ID and
+// trace ID ctx carries, the same fields the engine, providers and storage
+// log under
+func (s *SimpleServer) requestLogger(ctx context.Context) *logrus.Entry {
+	return log.WithContext(ctx, s.logger)
 }
 
 // func (s *SimpleServer) handleAISelectPrompt(w http.ResponseWriter, r *http.Request) {
@@ -1132,116 +2068,120 @@ func (s *SimpleServer) writeError(w http.ResponseWriter, status int, message str
 // 	s.writeJSON(w, http.StatusOK, response)
 // }
 
-// func (s *SimpleServer) handleSearchPrompts(w http.ResponseWriter, r *http.Request) {
-// 	// Parse query parameters
-// 	query := r.URL.Query().Get("q")
-// 	semantic := r.URL.Query().Get("semantic") == "true"
-// 	phase := r.URL.Query().Get("phase")
-// 	provider := r.URL.Query().Get("provider")
-// 	tagsStr := r.URL.Query().Get("tags")
-// 	since := r.URL.Query().Get("since")
-
-// 	// Parse limit parameter
-// 	limit := 10 // default
-// 	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
-// 		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 && parsedLimit <= 100 {
-// 			limit = parsedLimit
-// 		}
-// 	}
+// Similarity threshold for semantic search when none is given
+const defaultMinSimilarity = 0.5
 
-// 	// Parse similarity parameter for semantic search
-// 	similarity := 0.5 // default
-// 	if similarityStr := r.URL.Query().Get("similarity"); similarityStr != "" {
-// 		if parsedSimilarity, err := strconv.ParseFloat(similarityStr, 64); err == nil && parsedSimilarity >= 0 && parsedSimilarity <= 1 {
-// 			similarity = parsedSimilarity
-// 		}
-// 	}
+// parseMinSimilarity reads the similarity query parameter, clamped to [0, 1]
+func parseMinSimilarity(value string) (float64, error) {
+	if value == "" {
+		return defaultMinSimilarity, nil
+	}
+	similarity, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, err
+	}
+	return max(0, min(similarity, 1)), nil
+}
 
-// 	// Parse tags
-// 	var tagList []string
-// 	if tagsStr != "" {
-// 		tagList = strings.Split(tagsStr, ",")
-// 		for i, tag := range tagList {
-// 			tagList[i] = strings.TrimSpace(tag)
-// 		}
-// 	}
+func (s *SimpleServer) handleSearchPrompts(w http.ResponseWriter, r *http.Request) {
+	logger := s.requestLogger(r.Context())
+	// Parse query parameters
+	filters, err := parsePromptFilters(r.URL.Query())
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, invalidSinceMessage)
+		return
+	}
+	query, phase, provider := filters.Query, filters.Phase, filters.Provider
+	tagList, sinceTime := filters.Tags, filters.Since
+	semantic := r.URL.Query().Get("semantic") == "true"
 
-// 	// Parse since date
-// 	var sinceTime *time.Time
-// 	if since != "" {
-// 		if parsed, err := time.Parse("2006-01-02", since); err == nil {
-// 			sinceTime = &parsed
-// 		} else {
-// 			s.writeError(w, http.StatusBadRequest, "Invalid date format for 'since' parameter (use YYYY-MM-DD)")
-// 			return
-// 		}
-// 	}
+	// Parse limit parameter
+	limit := 10 // default
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 && parsedLimit <= 100 {
+			limit = parsedLimit
+		}
+	}
 
-// 	var prompts []models.Prompt
-// 	var similarities []float64
-// 	var err error
-// 	searchType := "text"
-
-// 	if semantic && query != "" {
-// 		// Semantic search
-// 		searchType = "semantic"
-// 		criteria := storage.SemanticSearchCriteria{
-// 			Query:         query,
-// 			Limit:         limit,
-// 			MinSimilarity: similarity,
-// 			Phase:         phase,
-// 			Provider:      provider,
-// 			Tags:          tagList,
-// 			Since:         sinceTime,
-// 		}
-// 		prompts, similarities, err = s.store.SearchPromptsSemanticFast(criteria)
-// 	} else {
-// 		// Text-based search (metadata filtering only)
-// 		criteria := storage.SearchCriteria{
-// 			Phase:    phase,
-// 			Provider: provider,
-// 			Tags:     tagList,
-// 			Since:    sinceTime,
-// 			Limit:    limit,
-// 		}
-// 		prompts, err = s.store.SearchPrompts(criteria)
-// 	}
+	// Parse similarity parameter for semantic search
+	similarity, err := parseMinSimilarity(r.URL.Query().Get("similarity"))
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid 'similarity' parameter (must be a number between 0 and 1)")
+		return
+	}
 
-// 	if err != nil {
-// 		s.writeError(w, http.StatusInternalServerError, fmt.Sprintf("Search failed: %v", err))
-// 		return
-// 	}
+	var prompts []models.Prompt
+	var similarities []float64
+	searchType := storage.SearchTypeText
+
+	if semantic && query != "" {
+		// Semantic search needs a provider to embed the query
+		embed := providers.NewQueryEmbedder(s.registry)
+		if embed == nil {
+			s.writeErrorCode(w, http.StatusBadRequest, ErrCodeProviderUnavailable, "Semantic search requires an embedding-capable provider, but none is registered")
+			return
+		}
 
-// 	// Create response
-// 	response := SearchPromptsResponse{
-// 		Prompts:      prompts,
-// 		TotalFound:   len(prompts),
-// 		SearchType:   searchType,
-// 		Query:        query,
-// 		Similarities: similarities,
-// 		Metadata: SearchMetadata{
-// 			Phase:         phase,
-// 			Provider:      provider,
-// 			Tags:          tagList,
-// 			Since:         sinceTime,
-// 			Limit:         limit,
-// 			Semantic:      semantic,
-// 			MinSimilarity: similarity,
-// 			SearchedAt:    time.Now(),
-// 		},
-// 	}
+		searchType = storage.SearchTypeSemantic
+		criteria := storage.SemanticSearchCriteria{
+			Query:         query,
+			Limit:         limit,
+			MinSimilarity: similarity,
+			Phase:         phase,
+			Provider:      provider,
+			Tags:          tagList,
+			Since:         sinceTime,
+		}
+		prompts, similarities, err = s.store.SearchPromptsSemanticFast(r.Context(), criteria, embed)
+	} else {
+		// Text-based search (metadata filtering only)
+		criteria := storage.SearchCriteria{
+			Query:    query,
+			Phase:    phase,
+			Provider: provider,
+			Tags:     tagList,
+			Since:    sinceTime,
+			Limit:    limit,
+		}
+		prompts, err = s.store.SearchPromptsByCriteria(r.Context(), criteria)
+	}
 
-// 	s.logger.WithFields(logrus.Fields{
-// 		"query":       query,
-// 		"search_type": searchType,
-// 		"results":     len(prompts),
-// 		"semantic":    semantic,
-// 		"phase":       phase,
-// 		"provider":    provider,
-// 	}).Info("Prompt search completed via HTTP API")
+	if err != nil {
+		logger.WithError(err).WithField("search_type", searchType).Error("Prompt search failed")
+		s.writeAPIError(w, apiErrorFrom(err, fmt.Sprintf("Search failed: %v", err)))
+		return
+	}
 
-// 	s.writeJSON(w, http.StatusOK, response)
-// }
+	// Create response
+	response := SearchPromptsResponse{
+		Prompts:      prompts,
+		TotalFound:   len(prompts),
+		SearchType:   searchType,
+		Query:        query,
+		Similarities: similarities,
+		Metadata: SearchMetadata{
+			Phase:         phase,
+			Provider:      provider,
+			Tags:          tagList,
+			Since:         sinceTime,
+			Limit:         limit,
+			Semantic:      semantic,
+			MinSimilarity: similarity,
+			SearchedAt:    time.Now(),
+		},
+	}
+
+	logger.WithFields(logrus.Fields{
+		"query":       query,
+		"search_type": searchType,
+		"results":     len(prompts),
+		"semantic":    semantic,
+		"phase":       phase,
+		"provider":    provider,
+	}).Info("Prompt search completed via HTTP API")
+
+	s.writeJSON(w, http.StatusOK, response)
+}
 
 func convertToProviderPhaseConfigs(configs []models.PhaseConfig) []models.PhaseConfig {
 	// No conversion needed since the engine now expects models.PhaseConfig
@@ -1315,7 +2255,7 @@ func (s *SimpleServer) handleListProviders(w http.ResponseWriter, r *http.Reques
 		RetrievedAt:        time.Now(),
 	}
 
-	s.logger.WithFields(logrus.Fields{
+	s.requestLogger(r.Context()).WithFields(logrus.Fields{
 		"total_providers":     len(allProviders),
 		"available_providers": len(availableProviders),
 		"embedding_providers": len(embeddingProviders),
@@ -1336,20 +2276,62 @@ func (s *SimpleServer) getProviderModels(providerName string) []string {
 		return []string{"llama3.2", "qwen2.5", "mistral", "phi3", "gemma2"}
 	case providers.ProviderOpenRouter:
 		return []string{"auto", "anthropic/claude-3.5-sonnet", "openai/o4-mini", "google/gemini-pro-1.5"}
+	case providers.ProviderMistral:
+		return []string{"mistral-large-latest", "mistral-medium-latest", "mistral-small-latest", "codestral-latest", "ministral-8b-latest", "open-mistral-nemo"}
+	case providers.ProviderDeepSeek:
+		return []string{providers.DefaultDeepSeekModel, providers.DeepSeekReasonerModel}
+	case providers.ProviderCohere:
+		return []string{providers.DefaultCohereModel, "command-r-plus-08-2024", "command-r-08-2024", "command-r7b-12-2024"}
+	case providers.ProviderPerplexity:
+		return providers.PerplexityModels()
+	case providers.ProviderAzure:
+		return providers.AzureDeployments()
+	case providers.ProviderBedrock:
+		return providers.BedrockModels()
 	default:
+		if custom := providers.CustomProviderModels(providerName); custom != nil {
+			return custom
+		}
 		return []string{}
 	}
 }
 
 // HTMX API handlers for the web UI
 
+// flowPhases are the phases shown as nodes on the web UI's board
+var flowPhases = []models.Phase{models.PhasePrimaMaterial, models.PhaseSolutio, models.PhaseCoagulatio}
+
+// flowState returns the progress of the generation named by the session_id
+// query parameter, or of the most recent generation if none is named. It is
+// ready when no such generation is being tracked.
+func (s *SimpleServer) flowState(r *http.Request) FlowState {
+	flow, ok := s.flows.Get(r.URL.Query().Get("session_id"))
+	if !ok {
+		return FlowState{Status: flowReady, Nodes: []NodeState{}}
+	}
+	return flow
+}
+
 func (s *SimpleServer) handleFlowStatus(w http.ResponseWriter, r *http.Request) {
+	flow := s.flowState(r)
+	phase := string(flow.Phase)
+	if phase == "" {
+		phase = flow.Status
+	}
+	totalSteps := len(flow.Nodes)
+	if totalSteps == 0 {
+		totalSteps = len(flowPhases)
+	}
+
 	response := map[string]interface{}{
-		"flow_id":     "alchemy-flow-1",
-		"status":      "active",
-		"phase":       "ready",
-		"progress":    0,
-		"total_steps": 3,
+		"flow_id":     flow.SessionID,
+		"session_id":  flow.SessionID,
+		"status":      flow.Status,
+		"phase":       phase,
+		"progress":    flow.Progress,
+		"total_steps": totalSteps,
+		"nodes":       flow.Nodes,
+		"error":       flow.Error,
 		"timestamp":   time.Now().Format(time.RFC3339),
 	}
 	s.writeJSON(w, http.StatusOK, response)
@@ -1470,56 +2452,75 @@ func (s *SimpleServer) handleSystemStatus(w http.ResponseWriter, r *http.Request
 		"cpu_usage":          "12%",
 		"active_connections": 1,
 		"last_check":         time.Now().Format(time.RFC3339),
+		"circuit_breakers":   s.breakerStates(configuredProviders),
 	}
 	s.writeJSON(w, http.StatusOK, response)
 }
 
 func (s *SimpleServer) handleNodesStatus(w http.ResponseWriter, r *http.Request) {
+	flow := s.flowState(r)
+	names := map[models.Phase]string{
+		models.PhasePrimaMaterial: "Prima Materia",
+		models.PhaseSolutio:       "Solutio",
+		models.PhaseCoagulatio:    "Coagulatio",
+	}
+
+	nodes := make([]map[string]interface{}, 0, len(flowPhases))
+	for _, phase := range flowPhases {
+		node := flow.Node(phase)
+		nodes = append(nodes, map[string]interface{}{
+			"id":       string(phase),
+			"name":     names[phase],
+			"status":   node.Status,
+			"phase":    string(phase),
+			"active":   node.Status == flowProcessing,
+			"progress": node.Progress,
+			"prompts":  node.Prompts,
+		})
+	}
+
 	response := map[string]interface{}{
-		"nodes": []map[string]interface{}{
-			{
-				"id":     "prima-materia",
-				"name":   "Prima Materia",
-				"status": "ready",
-				"phase":  "prima-materia",
-				"active": false,
-			},
-			{
-				"id":     "solutio",
-				"name":   "Solutio",
-				"status": "ready",
-				"phase":  "solutio",
-				"active": false,
-			},
-			{
-				"id":     "coagulatio",
-				"name":   "Coagulatio",
-				"status": "ready",
-				"phase":  "coagulatio",
-				"active": false,
-			},
-		},
-		"timestamp": time.Now().Format(time.RFC3339),
+		"session_id": flow.SessionID,
+		"nodes":      nodes,
+		"timestamp":  time.Now().Format(time.RFC3339),
 	}
 	s.writeJSON(w, http.StatusOK, response)
 }
 
 func (s *SimpleServer) handleConnectionStatus(w http.ResponseWriter, r *http.Request) {
 	availableProviders := s.registry.ListAvailable()
-	connections := make([]map[string]interface{}, 0)
+	sort.Strings(availableProviders)
 
-	for _, provider := range availableProviders {
-		connections = append(connections, map[string]interface{}{
-			"provider": provider,
-			"status":   "connected",
-			"latency":  "45ms",
-		})
+	toCheck := make([]providers.Provider, 0, len(availableProviders))
+	for _, name := range availableProviders {
+		if provider, err := s.registry.Get(name); err == nil {
+			toCheck = append(toCheck, provider)
+		}
+	}
+
+	connections := make([]map[string]interface{}, 0, len(toCheck))
+	healthy := 0
+	for i, result := range s.latencyProbe.CheckAll(r.Context(), toCheck) {
+		if result.Status == connectionConnected {
+			healthy++
+		}
+		connection := map[string]interface{}{
+			"provider":   toCheck[i].Name(),
+			"status":     result.Status,
+			"latency":    result.Latency,
+			"latency_ms": result.LatencyMS,
+			"checked_at": result.CheckedAt.Format(time.RFC3339),
+		}
+		if result.Error != "" {
+			connection["error"] = result.Error
+		}
+		connections = append(connections, connection)
 	}
 
 	response := map[string]interface{}{
 		"connections": connections,
 		"total":       len(connections),
-		"healthy":     len(connections),
+		"healthy":     healthy,
 		"timestamp":   time.Now().Format(time.RFC3339),
 	}
 	s.writeJSON(w, http.StatusOK, response)
@@ -1686,77 +2687,108 @@ func (s *SimpleServer) handleActivityFeed(w http.ResponseWriter, r *http.Request
 	s.writeJSON(w, http.StatusOK, response)
 }
 
+// boardSessionID identifies the web UI session whose board layout is
+// persisted, from the session_id query parameter or X-Session-ID header
+func boardSessionID(r *http.Request) string {
+	if id := r.URL.Query().Get("session_id"); id != "" {
+		return id
+	}
+	return r.Header.Get("X-Session-ID")
+}
+
 func (s *SimpleServer) handleBoardState(w http.ResponseWriter, r *http.Request) {
 	// Return the board configuration data expected by hex-flow-board.js
-	boardState := map[string]interface{}{
-		"nodes": []map[string]interface{}{
-			{
-				"id":     "input",
-				"type":   "input",
-				"label":  "Input",
-				"x":      150,
-				"y":      350,
-				"status": "ready",
-				"active": false,
-				"phase":  "input",
-				"icon":   "fa-upload",
-			},
-			{
-				"id":     "prima",
-				"type":   "phase",
-				"label":  "Prima Materia",
-				"x":      350,
-				"y":      200,
-				"status": "inactive",
-				"active": false,
-				"phase":  "prima-materia",
-				"icon":   "fa-atom",
-			},
-			{
-				"id":     "solutio",
-				"type":   "phase",
-				"label":  "Solutio",
-				"x":      550,
-				"y":      350,
-				"status": "inactive",
-				"active": false,
-				"phase":  "solutio",
-				"icon":   "fa-water",
-			},
-			{
-				"id":     "coagulatio",
-				"type":   "phase",
-				"label":  "Coagulatio",
-				"x":      750,
-				"y":      200,
-				"status": "inactive",
-				"active": false,
-				"phase":  "coagulatio",
-				"icon":   "fa-gem",
-			},
-			{
-				"id":     "output",
-				"type":   "output",
-				"label":  "Output",
-				"x":      850,
-				"y":      350,
-				"status": "waiting",
-				"active": false,
-				"phase":  "output",
-				"icon":   "fa-download",
-			},
-			{
-				"id":     "hub",
-				"type":   "hub",
-				"label":  "Central Hub",
-				"x":      500,
-				"y":      500,
-				"status": "active",
-				"active": true,
-				"phase":  "hub",
-				"icon":   "fa-hub",
-			},
+	nodes := []map[string]interface{}{
+		{
+			"id":     "input",
+			"type":   "input",
+			"label":  "Input",
+			"x":      150,
+			"y":      350,
+			"status": "ready",
+			"active": false,
+			"phase":  "input",
+			"icon":   "fa-upload",
+		},
+		{
+			"id":     "prima",
+			"type":   "phase",
+			"label":  "Prima Materia",
+			"x":      350,
+			"y":      200,
+			"status": "inactive",
+			"active": false,
+			"phase":  "prima-materia",
+			"icon":   "fa-atom",
+		},
+		{
+			"id":     "solutio",
+			"type":   "phase",
+			"label":  "Solutio",
+			"x":      550,
+			"y":      350,
+			"status": "inactive",
+			"active": false,
+			"phase":  "solutio",
+			"icon":   "fa-water",
+		},
+		{
+			"id":     "coagulatio",
+			"type":   "phase",
+			"label":  "Coagulatio",
+			"x":      750,
+			"y":      200,
+			"status": "inactive",
+			"active": false,
+			"phase":  "coagulatio",
+			"icon":   "fa-gem",
+		},
+		{
+			"id":     "output",
+			"type":   "output",
+			"label":  "Output",
+			"x":      850,
+			"y":      350,
+			"status": "waiting",
+			"active": false,
+			"phase":  "output",
+			"icon":   "fa-download",
+		},
+		{
+			"id":     "hub",
+			"type":   "hub",
+			"label":  "Central Hub",
+			"x":      500,
+			"y":      500,
+			"status": "active",
+			"active": true,
+			"phase":  "hub",
+			"icon":   "fa-hub",
 		},
+	}
+	viewport := models.Viewport{Zoom: 1.0}
+
+	// Restore the session's saved layout over the defaults
+	restored := false
+	if sessionID := boardSessionID(r); sessionID != "" {
+		saved, err := s.store.GetBoardState(r.Context(), sessionID)
+		if err != nil {
+			s.requestLogger(r.Context()).WithError(err).WithField("session_id", sessionID).Warn("Failed to load board state, using default layout")
+		} else if saved != nil {
+			restored = true
+			viewport = saved.Viewport
+			for _, node := range nodes {
+				if pos, ok := saved.NodePosition(node["id"].(string)); ok {
+					node["x"], node["y"] = pos.X, pos.Y
+				}
+			}
+		}
+	}
+
+	boardState := map[string]interface{}{
+		"nodes":    nodes,
+		"viewport": viewport,
+		"restored": restored,
 		"connections": []map[string]interface{}{
 			{"from": "input", "to": "prima", "id": "input-prima", "status": "ready"},
 			{"from": "prima", "to": "hub", "id": "prima-hub", "status": "inactive"},
@@ -1850,7 +2882,7 @@ func (s *SimpleServer) handleActivatePhase(w http.ResponseWriter, r *http.Reques
 		"timestamp": time.Now().Format(time.RFC3339),
 	}
 
-	s.logger.WithFields(logrus.Fields{
+	s.requestLogger(r.Context()).WithFields(logrus.Fields{
 		"phase_id": activateReq.PhaseID,
 		"input":    activateReq.Input,
 	}).Info("Phase activation requested via HTMX API")
@@ -1934,7 +2966,7 @@ func (s *SimpleServer) handleNodeActivate(w http.ResponseWriter, r *http.Request
 		},
 	}
 
-	s.logger.WithFields(logrus.Fields{
+	s.requestLogger(r.Context()).WithFields(logrus.Fields{
 		"node_id":  req.NodeID,
 		"provider": req.Provider,
 		"input":    req.Input != "",
@@ -1957,43 +2989,41 @@ func (s *SimpleServer) handleConnectionDetails(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	// Check actual provider status
-	var status string
-	if provider.IsAvailable() {
-		status = "connected"
-	} else {
-		status = "disconnected"
-	}
-
-	providerName := provider.Name()
-	// Note: Real latency testing would require actual network calls
-	latency := "N/A"
+	result := s.latencyProbe.Check(r.Context(), provider)
 
 	response := map[string]interface{}{
 		"connection_id":      connectionID,
-		"status":             status,
-		"provider":           providerName,
-		"latency":            latency,
+		"status":             result.Status,
+		"provider":           provider.Name(),
+		"latency":            result.Latency,
+		"latency_ms":         result.LatencyMS,
+		"checked_at":         result.CheckedAt.Format(time.RFC3339),
 		"available":          provider.IsAvailable(),
 		"supports_embedding": provider.SupportsEmbeddings(),
 		"supports_streaming": provider.SupportsStreaming(),
 		"timestamp":          time.Now().Format(time.RFC3339),
 	}
+	if result.Error != "" {
+		response["error"] = result.Error
+	}
 
 	s.writeJSON(w, http.StatusOK, response)
 }
 
 func (s *SimpleServer) handleViewportUpdate(w http.ResponseWriter, r *http.Request) {
+	logger := s.requestLogger(r.Context())
 	var req struct {
-		X      float64 `json:"x"`
-		Y      float64 `json:"y"`
-		Zoom   float64 `json:"zoom"`
-		Width  int     `json:"width"`
-		Height int     `json:"height"`
+		SessionID string                `json:"session_id"`
+		X         float64               `json:"x"`
+		Y         float64               `json:"y"`
+		Zoom      float64               `json:"zoom"`
+		Width     int                   `json:"width"`
+		Height    int                   `json:"height"`
+		Nodes     []models.NodePosition `json:"nodes,omitempty"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		s.logger.WithError(err).Error("Failed to decode viewport update request")
+		logger.WithError(err).Error("Failed to decode viewport update request")
 		s.writeError(w, http.StatusBadRequest, "Invalid JSON payload: "+err.Error())
 		return
 	}
@@ -2002,17 +3032,33 @@ func (s *SimpleServer) handleViewportUpdate(w http.ResponseWriter, r *http.Reque
 	if req.Zoom <= 0 {
 		req.Zoom = 1.0 // Default zoom level
 	}
+	viewport := models.Viewport{X: req.X, Y: req.Y, Zoom: req.Zoom, Width: req.Width, Height: req.Height}
+
+	// Persist the layout for the session so it survives a page reload. Node
+	// positions are optional; without them the previously saved ones are kept.
+	sessionID := req.SessionID
+	if sessionID == "" {
+		sessionID = boardSessionID(r)
+	}
+	persisted := false
+	if sessionID != "" {
+		state := &models.BoardState{SessionID: sessionID, Viewport: viewport, Nodes: req.Nodes}
+		if state.Nodes == nil {
+			if saved, err := s.store.GetBoardState(r.Context(), sessionID); err == nil && saved != nil {
+				state.Nodes = saved.Nodes
+			}
+		}
+		if err := s.store.SaveBoardState(r.Context(), state); err != nil {
+			logger.WithError(err).WithField("session_id", sessionID).Error("Failed to save board state")
+		} else {
+			persisted = true
+		}
+	}
 
-	// Store viewport state (in production, this would be persisted)
 	response := map[string]interface{}{
-		"success": true,
-		"viewport": map[string]interface{}{
-			"x":      req.X,
-			"y":      req.Y,
-			"zoom":   req.Zoom,
-			"width":  req.Width,
-			"height": req.Height,
-		},
+		"success":   true,
+		"viewport":  viewport,
+		"persisted": persisted,
 		"message":   "Viewport updated successfully",
 		"timestamp": time.Now().Format(time.RFC3339),
 	}
@@ -2020,6 +3066,9 @@ func (s *SimpleServer) handleViewportUpdate(w http.ResponseWriter, r *http.Reque
 	s.writeJSON(w, http.StatusOK, response)
 }
 
+// handleFlowEvents streams a flow_update event whenever a generation
+// changes phase, limited to one generation with the session_id query
+// parameter, plus periodic heartbeats
 func (s *SimpleServer) handleFlowEvents(w http.ResponseWriter, r *http.Request) {
 	// Set headers for Server-Sent Events
 	w.Header().Set("Content-Type", "text/event-stream")
@@ -2027,11 +3076,20 @@ func (s *SimpleServer) handleFlowEvents(w http.ResponseWriter, r *http.Request)
 	w.Header().Set("Connection", "keep-alive")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 
+	updates, unsubscribe := s.flows.Subscribe()
+	defer unsubscribe()
+	sessionID := r.URL.Query().Get("session_id")
+
 	// Send initial connection event
 	fmt.Fprintf(w, "data: %s\n\n", `{"type":"connected","message":"Flow events stream connected","timestamp":"`+time.Now().Format(time.RFC3339)+`"}`)
 	w.(http.Flusher).Flush()
 
-	// Simulate periodic events
+	send := func(event map[string]interface{}) {
+		eventJSON, _ := json.Marshal(event)
+		fmt.Fprintf(w, "data: %s\n\n", string(eventJSON))
+		w.(http.Flusher).Flush()
+	}
+
 	ticker := time.NewTicker(5 * time.Second)
 	defer ticker.Stop()
 
@@ -2042,18 +3100,23 @@ func (s *SimpleServer) handleFlowEvents(w http.ResponseWriter, r *http.Request)
 		select {
 		case <-ctx.Done():
 			return
+		case flow := <-updates:
+			if sessionID != "" && flow.SessionID != sessionID {
+				continue
+			}
+			send(map[string]interface{}{
+				"type":      "flow_update",
+				"flow":      flow,
+				"timestamp": time.Now().Format(time.RFC3339),
+			})
 		case <-ticker.C:
 			eventCount++
-			event := map[string]interface{}{
+			send(map[string]interface{}{
 				"type":      "heartbeat",
 				"count":     eventCount,
 				"message":   "System healthy",
 				"timestamp": time.Now().Format(time.RFC3339),
-			}
-
-			eventJSON, _ := json.Marshal(event)
-			fmt.Fprintf(w, "data: %s\n\n", string(eventJSON))
-			w.(http.Flusher).Flush()
+			})
 		}
 	}
 }
@@ -2080,11 +3143,13 @@ func (s *SimpleServer) handleNodeInput(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *SimpleServer) handlePhasePrima(w http.ResponseWriter, r *http.Request) {
+	node := s.flowState(r).Node(models.PhasePrimaMaterial)
 	response := map[string]interface{}{
 		"phase_id":        "prima-materia",
 		"name":            "Prima Materia",
 		"description":     "The first alchemical phase - extracts raw essence and structures initial ideas",
-		"status":          "ready",
+		"status":          node.Status,
+		"progress":        node.Progress,
 		"order":           1,
 		"provider":        "openai",
 		"model":           "gpt-4o",
@@ -2099,11 +3164,13 @@ func (s *SimpleServer) handlePhasePrima(w http.ResponseWriter, r *http.Request)
 }
 
 func (s *SimpleServer) handlePhaseSolutio(w http.ResponseWriter, r *http.Request) {
+	node := s.flowState(r).Node(models.PhaseSolutio)
 	response := map[string]interface{}{
 		"phase_id":        "solutio",
 		"name":            "Solutio",
 		"description":     "The second alchemical phase - dissolves structured ideas into natural, flowing language",
-		"status":          "ready",
+		"status":          node.Status,
+		"progress":        node.Progress,
 		"order":           2,
 		"provider":        "anthropic",
 		"model":           "claude-3-5-sonnet-20241022",
@@ -2118,11 +3185,13 @@ func (s *SimpleServer) handlePhaseSolutio(w http.ResponseWriter, r *http.Request
 }
 
 func (s *SimpleServer) handlePhaseCoagulatio(w http.ResponseWriter, r *http.Request) {
+	node := s.flowState(r).Node(models.PhaseCoagulatio)
 	response := map[string]interface{}{
 		"phase_id":        "coagulatio",
 		"name":            "Coagulatio",
 		"description":     "The third alchemical phase - crystallizes flowing language into precise, production-ready prompts",
-		"status":          "ready",
+		"status":          node.Status,
+		"progress":        node.Progress,
 		"order":           3,
 		"provider":        "google",
 		"model":           "gemini-2.5-flash",
@@ -2168,6 +3237,7 @@ func (s *SimpleServer) handleCoreStatus(w http.ResponseWriter, r *http.Request)
 }
 
 func (s *SimpleServer) handleOutputRetrieve(w http.ResponseWriter, r *http.Request) {
+	logger := s.requestLogger(r.Context())
 	var req struct {
 		SessionID   string `json:"session_id,omitempty"`
 		OutputType  string `json:"output_type,omitempty"`
@@ -2199,15 +3269,24 @@ func (s *SimpleServer) handleOutputRetrieve(w http.ResponseWriter, r *http.Reque
 
 	// Retrieve prompts from storage
 	if req.SessionID != "" {
-		// TODO: Implement session-based prompt retrieval
-		// For now, fall back to recent prompts
-		prompts, err = s.store.GetRecentPrompts(ctx, req.Limit)
+		sessionID, parseErr := uuid.Parse(req.SessionID)
+		if parseErr != nil {
+			s.writeError(w, http.StatusBadRequest, "Invalid session ID")
+			return
+		}
+		var sessionPrompts []*models.Prompt
+		sessionPrompts, err = s.store.GetPromptsBySession(ctx, sessionID)
+		for i, p := range sessionPrompts {
+			if i >= req.Offset && len(prompts) < req.Limit {
+				prompts = append(prompts, *p)
+			}
+		}
 	} else {
 		prompts, err = s.store.ListPrompts(ctx, req.Limit, req.Offset)
 	}
 
 	if err != nil {
-		s.logger.WithError(err).Error("Failed to retrieve prompts from storage")
+		logger.WithError(err).Error("Failed to retrieve prompts from storage")
 		s.writeError(w, http.StatusInternalServerError, "Failed to retrieve prompts")
 		return
 	}
@@ -2238,7 +3317,7 @@ func (s *SimpleServer) handleOutputRetrieve(w http.ResponseWriter, r *http.Reque
 		// Get total count for metadata
 		totalCount, err := s.store.GetPromptsCount(ctx)
 		if err != nil {
-			s.logger.WithError(err).Warn("Failed to get total prompts count")
+			logger.WithError(err).Warn("Failed to get total prompts count")
 			totalCount = len(promptData)
 		}
 
@@ -2386,6 +3465,14 @@ func (s *SimpleServer) getProviderStatus(providerName string) (status, icon, cla
 		return "initializing", "⚠", "provider-initializing"
 	}
 
+	// A tripped circuit breaker overrides availability
+	switch s.registry.Health()[providerName].State {
+	case providers.BreakerOpen:
+		return "down", "✗", "provider-down"
+	case providers.BreakerHalfOpen:
+		return "recovering", "⚠", "provider-initializing"
+	}
+
 	// Test actual provider availability
 	if provider.IsAvailable() {
 		return "up", "✓", "provider-up"
@@ -2394,6 +3481,18 @@ func (s *SimpleServer) getProviderStatus(providerName string) (status, icon, cla
 	}
 }
 
+// breakerStates returns the circuit breaker state of each named provider
+func (s *SimpleServer) breakerStates(names []string) map[string]providers.BreakerState {
+	health := s.registry.Health()
+	states := make(map[string]providers.BreakerState, len(names))
+	for _, name := range names {
+		if h, ok := health[name]; ok {
+			states[name] = h.State
+		}
+	}
+	return states
+}
+
 // getProviderDotClass maps provider status classes to dot classes for collapsed view
 func (s *SimpleServer) getProviderDotClass(statusClass string) string {
 	switch statusClass {
@@ -2410,7 +3509,7 @@ func (s *SimpleServer) getProviderDotClass(statusClass string) string {
 
 // getConfiguredProviders returns only providers that are actually configured
 func (s *SimpleServer) getConfiguredProviders() []string {
-	allProviders := []string{"openai", "anthropic", "google", "ollama", "openrouter", "grok"}
+	allProviders := []string{"openai", "anthropic", "google", "ollama", "openrouter", "grok", "mistral", "deepseek", "cohere", "perplexity", "azure", "bedrock"}
 	configuredProviders := make([]string, 0)
 
 	for _, provider := range allProviders {
@@ -2467,6 +3566,9 @@ func (s *SimpleServer) calculateSystemStatus(providers []string) (string, string
 
 // Add new handler methods at the end of the file
 
+// handleThinkingStream streams the thinking updates posted for the
+// session_id query parameter, or for every session without one, plus
+// periodic heartbeats. The subscription ends when the client disconnects.
 func (s *SimpleServer) handleThinkingStream(w http.ResponseWriter, r *http.Request) {
 	// Set headers for Server-Sent Events
 	w.Header().Set("Content-Type", "text/event-stream")
@@ -2474,6 +3576,15 @@ func (s *SimpleServer) handleThinkingStream(w http.ResponseWriter, r *http.Reque
 	w.Header().Set("Connection", "keep-alive")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 
+	updates, unsubscribe := s.thinking.Subscribe(r.URL.Query().Get("session_id"))
+	defer unsubscribe()
+
+	send := func(event interface{}) {
+		eventJSON, _ := json.Marshal(event)
+		fmt.Fprintf(w, "data: %s\n\n", string(eventJSON))
+		w.(http.Flusher).Flush()
+	}
+
 	// Send initial connection event
 	fmt.Fprintf(w, "data: %s\n\n", `{"type":"connected","message":"AI thinking stream connected","timestamp":"`+time.Now().Format(time.RFC3339)+`"}`)
 	w.(http.Flusher).Flush()
@@ -2487,19 +3598,27 @@ func (s *SimpleServer) handleThinkingStream(w http.ResponseWriter, r *http.Reque
 		select {
 		case <-ctx.Done():
 			return
+		case update := <-updates:
+			send(update)
 		case <-ticker.C:
 			// Send heartbeat
-			event := map[string]interface{}{
+			send(map[string]interface{}{
 				"type":      "heartbeat",
 				"timestamp": time.Now().Format(time.RFC3339),
-			}
-			eventJSON, _ := json.Marshal(event)
-			fmt.Fprintf(w, "data: %s\n\n", string(eventJSON))
-			w.(http.Flusher).Flush()
+			})
 		}
 	}
 }
 
+// ThinkingUpdateResponse echoes a posted thinking update with the number of
+// streams it was delivered to
+type ThinkingUpdateResponse struct {
+	ThinkingUpdate
+	Delivered int `json:"delivered"`
+}
+
+// handleThinkingUpdate publishes a thinking update to the thinking streams of
+// its session
 func (s *SimpleServer) handleThinkingUpdate(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		Phase     string `json:"phase"`
@@ -2514,18 +3633,18 @@ func (s *SimpleServer) handleThinkingUpdate(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	// Broadcast thinking update (in a real implementation, this would broadcast to specific session)
-	response := map[string]interface{}{
-		"type":       "thinking",
-		"phase":      req.Phase,
-		"stage":      req.Stage,
-		"message":    req.Message,
-		"progress":   req.Progress,
-		"session_id": req.SessionID,
-		"timestamp":  time.Now().Format(time.RFC3339),
+	update := ThinkingUpdate{
+		Type:      "thinking",
+		Phase:     req.Phase,
+		Stage:     req.Stage,
+		Message:   req.Message,
+		Progress:  req.Progress,
+		SessionID: req.SessionID,
+		Timestamp: time.Now().Format(time.RFC3339),
 	}
+	delivered := s.thinking.Publish(update)
 
-	s.writeJSON(w, http.StatusOK, response)
+	s.writeJSON(w, http.StatusOK, ThinkingUpdateResponse{ThinkingUpdate: update, Delivered: delivered})
 }
 
 // handleSummarize provides AI-powered text summarization
@@ -2554,7 +3673,7 @@ func (s *SimpleServer) handleSummarize(w http.ResponseWriter, r *http.Request) {
 	// Perform summarization
 	summary, err := s.summarizer.Summarize(r.Context(), req)
 	if err != nil {
-		s.logger.WithError(err).Error("Summarization failed")
+		s.requestLogger(r.Context()).WithError(err).Error("Summarization failed")
 		s.writeError(w, http.StatusInternalServerError, "Summarization failed")
 		return
 	}
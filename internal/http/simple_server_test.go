@@ -1,17 +1,54 @@
 package http
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync/atomic"
 	"testing"
 
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
 	"github.com/jonwraymond/prompt-alchemy/internal/engine"
 	"github.com/jonwraymond/prompt-alchemy/internal/learning"
+	"github.com/jonwraymond/prompt-alchemy/internal/log"
 	"github.com/jonwraymond/prompt-alchemy/internal/ranking"
 	"github.com/jonwraymond/prompt-alchemy/internal/storage"
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
 	"github.com/jonwraymond/prompt-alchemy/pkg/providers"
 	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
+// newTestServer returns a server over storage in a temp dir, with an empty
+// registry and an engine using it. Tests register providers in
+// server.registry and set the ranker or learner they need.
+func newTestServer(t *testing.T) (*SimpleServer, *storage.Storage) {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	return newTestServerWithLogger(t, logger)
+}
+
+// newTestServerWithLogger is newTestServer logging to logger, for tests
+// that check what the server logs
+func newTestServerWithLogger(t *testing.T, logger *logrus.Logger) (*SimpleServer, *storage.Storage) {
+	t.Helper()
+	store, err := storage.NewStorage(t.TempDir(), logger)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = store.Close() })
+
+	registry := providers.NewRegistry()
+	return NewSimpleServer(store, registry, engine.NewEngine(registry, logger), nil, &learning.LearningEngine{}, logger), store
+}
+
 func TestNewSimpleServer(t *testing.T) {
 	// Test that NewSimpleServer can be created without panic
 	logger := logrus.New()
@@ -33,3 +70,758 @@ func TestNewSimpleServer(t *testing.T) {
 	assert.NotNil(t, server)
 	assert.NotNil(t, server.Router())
 }
+
+var promptIDPattern = regexp.MustCompile(`Prompt ID: ([0-9a-f-]{36})`)
+
+func TestHandleGeneratePromptsJudgeConfidence(t *testing.T) {
+	defer viper.Reset()
+	server, store := newTestServer(t)
+	server.ranker = ranking.NewRanker(store, server.registry, server.logger)
+
+	generated := 0
+	require.NoError(t, server.registry.Register("numbering", &providers.MockProvider{
+		NameFunc: func() string { return "numbering" },
+		GenerateFunc: func(ctx context.Context, req providers.GenerateRequest) (*providers.GenerateResponse, error) {
+			generated++
+			return &providers.GenerateResponse{Content: fmt.Sprintf("generated %d", generated)}, nil
+		},
+	}))
+	// The judge scores the last prompt it is shown highest
+	var confidence float64
+	var favored string
+	require.NoError(t, server.registry.Register("judge", &providers.MockProvider{
+		NameFunc: func() string { return "judge" },
+		GenerateFunc: func(ctx context.Context, req providers.GenerateRequest) (*providers.GenerateResponse, error) {
+			matches := promptIDPattern.FindAllStringSubmatch(req.Prompt, -1)
+			scores := make([]string, 0, len(matches))
+			for i, match := range matches {
+				score := 0.2
+				if i == len(matches)-1 {
+					score, favored = 0.9, match[1]
+				}
+				scores = append(scores, fmt.Sprintf(`{"promptId":%q,"score":%v,"reasoning":"judged","confidence":%v}`, match[1], score, confidence))
+			}
+			return &providers.GenerateResponse{Content: "[" + strings.Join(scores, ",") + "]"}, nil
+		},
+	}))
+
+	generate := func(extra string) GenerateResponse {
+		body := `{"input":"write a haiku","phases":["prima-materia"],"count":2,"providers":{"prima-materia":"numbering"},` +
+			`"enable_judging":true,"judge_provider":"judge"` + extra + `}`
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/prompts/generate", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		server.Router().ServeHTTP(recorder, req)
+		require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+
+		var response GenerateResponse
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		require.Len(t, response.Prompts, 2)
+		require.NotEmpty(t, response.Rankings)
+		require.NotNil(t, response.Selected)
+		// The judge must favor a prompt the ranker didn't pick for the two
+		// selections to be told apart
+		require.NotEqual(t, response.Rankings[0].Prompt.ID.String(), favored)
+		return response
+	}
+
+	t.Run("confidence below the minimum is flagged", func(t *testing.T) {
+		confidence = 0.4
+		response := generate(`,"min_confidence":0.6,"fallback_to_ranker":false`)
+		assert.True(t, response.Metadata.LowConfidence)
+		assert.InDelta(t, 0.4, response.Metadata.JudgeConfidence, 1e-9)
+		assert.Equal(t, "judge", response.Metadata.SelectionSource, "without fallback the judge still selects")
+		assert.Equal(t, favored, response.Selected.ID.String())
+	})
+
+	t.Run("fallback keeps the ranker's selection", func(t *testing.T) {
+		confidence = 0.4
+		response := generate(`,"min_confidence":0.6,"fallback_to_ranker":true`)
+		assert.True(t, response.Metadata.LowConfidence)
+		assert.Equal(t, "ranker", response.Metadata.SelectionSource)
+		assert.Equal(t, response.Rankings[0].Prompt.ID, response.Selected.ID)
+	})
+
+	t.Run("confidence above the minimum keeps the judge's selection", func(t *testing.T) {
+		confidence = 0.8
+		response := generate(`,"min_confidence":0.6,"fallback_to_ranker":true`)
+		assert.False(t, response.Metadata.LowConfidence)
+		assert.InDelta(t, 0.8, response.Metadata.JudgeConfidence, 1e-9)
+		assert.Equal(t, "judge", response.Metadata.SelectionSource)
+		assert.Equal(t, favored, response.Selected.ID.String())
+	})
+
+	t.Run("minimum comes from config", func(t *testing.T) {
+		viper.Set("generation.min_judge_confidence", 0.9)
+		viper.Set("generation.judge_fallback_to_ranker", true)
+		confidence = 0.8
+		response := generate("")
+		assert.True(t, response.Metadata.LowConfidence)
+		assert.Equal(t, "ranker", response.Metadata.SelectionSource)
+		assert.Equal(t, response.Rankings[0].Prompt.ID, response.Selected.ID)
+	})
+
+	t.Run("minimum outside 0..1 is rejected", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/prompts/generate", strings.NewReader(`{"input":"write a haiku","min_confidence":1.5}`))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		server.Router().ServeHTTP(recorder, req)
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+}
+
+func TestHandleListPrompts(t *testing.T) {
+	server, store := newTestServer(t)
+
+	list := func(query string) (int, map[string]interface{}) {
+		recorder := httptest.NewRecorder()
+		server.Router().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/prompts/"+query, nil))
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+		return recorder.Code, body
+	}
+
+	// Empty database returns an empty array and a null cursor
+	status, body := list("")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, []interface{}{}, body["prompts"])
+	assert.Nil(t, body["next_cursor"])
+	assert.EqualValues(t, 0, body["total"])
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, store.SavePrompt(context.Background(), &models.Prompt{
+			Content:  fmt.Sprintf("prompt %d", i),
+			Phase:    models.PhasePrimaMaterial,
+			Provider: "openai",
+		}))
+	}
+
+	status, body = list("?limit=2&fields=content")
+	assert.Equal(t, http.StatusOK, status)
+	assert.EqualValues(t, 3, body["total"])
+	require.Len(t, body["prompts"], 2)
+	assert.ElementsMatch(t, []string{"id", "content"}, keys(body["prompts"].([]interface{})[0]))
+	require.NotNil(t, body["next_cursor"])
+
+	status, body = list("?limit=2&cursor=" + body["next_cursor"].(string))
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, body["prompts"], 1)
+	assert.Nil(t, body["next_cursor"])
+
+	status, _ = list("?cursor=garbage")
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status, _ = list("?limit=0")
+	assert.Equal(t, http.StatusBadRequest, status)
+}
+
+func TestHandleSearchPrompts(t *testing.T) {
+	server, store := newTestServer(t)
+
+	search := func(query string) (int, map[string]interface{}) {
+		recorder := httptest.NewRecorder()
+		server.Router().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/prompts/search"+query, nil))
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+		return recorder.Code, body
+	}
+
+	ctx := context.Background()
+	require.NoError(t, store.SavePrompt(ctx, &models.Prompt{Content: "sort a list", Phase: models.PhaseCoagulatio, Provider: "openai", Tags: []string{"code"}}))
+	require.NoError(t, store.SavePrompt(ctx, &models.Prompt{Content: "sort the results", Phase: models.PhaseSolutio, Provider: "openai"}))
+
+	status, body := search("?q=sort&phase=coagulatio&tags=code")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "text", body["search_type"])
+	require.Len(t, body["prompts"], 1)
+	assert.Equal(t, "sort a list", body["prompts"].([]interface{})[0].(map[string]interface{})["content"])
+
+	status, body = search("?q=sort&since=15-01-2025")
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Contains(t, body["error"], "YYYY-MM-DD")
+
+	status, _ = search("?q=sort&similarity=high")
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	// No embedding provider is registered
+	status, body = search("?q=sort&semantic=true")
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Contains(t, body["error"], "embedding-capable provider")
+}
+
+func TestHandleUpdateAndDeletePrompt(t *testing.T) {
+	server, store := newTestServer(t)
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		recorder := httptest.NewRecorder()
+		server.Router().ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	ctx := context.Background()
+	parent := &models.Prompt{Content: "draft a release note", Phase: models.PhasePrimaMaterial, Provider: "openai", SessionID: uuid.New()}
+	require.NoError(t, store.SavePrompt(ctx, parent))
+	child := &models.Prompt{Content: "draft a concise release note", Phase: models.PhaseSolutio, Provider: "openai", SessionID: parent.SessionID, ParentID: &parent.ID}
+	require.NoError(t, store.SavePrompt(ctx, child))
+
+	t.Run("update preserves identity", func(t *testing.T) {
+		recorder := send(http.MethodPut, "/api/v1/prompts/"+parent.ID.String(), `{"content":"draft release notes","temperature":0.4,"max_tokens":500,"session_id":"`+uuid.NewString()+`"}`)
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		updated, err := store.GetPromptByID(ctx, parent.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "draft release notes", updated.Content)
+		assert.Equal(t, parent.SessionID, updated.SessionID)
+		assert.Equal(t, parent.CreatedAt.Unix(), updated.CreatedAt.Unix())
+	})
+
+	t.Run("update validates settings", func(t *testing.T) {
+		recorder := send(http.MethodPut, "/api/v1/prompts/"+parent.ID.String(), `{"content":"x","temperature":2.5}`)
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		recorder = send(http.MethodPut, "/api/v1/prompts/"+parent.ID.String(), `{"content":"x","max_tokens":-1}`)
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		recorder = send(http.MethodPut, "/api/v1/prompts/"+parent.ID.String(), `{"temperature":0.5}`)
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+
+	t.Run("bad and unknown IDs", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, send(http.MethodPut, "/api/v1/prompts/not-a-uuid", `{"content":"x"}`).Code)
+		assert.Equal(t, http.StatusBadRequest, send(http.MethodDelete, "/api/v1/prompts/not-a-uuid", "").Code)
+		assert.Equal(t, http.StatusNotFound, send(http.MethodPut, "/api/v1/prompts/"+uuid.NewString(), `{"content":"x"}`).Code)
+		assert.Equal(t, http.StatusNotFound, send(http.MethodDelete, "/api/v1/prompts/"+uuid.NewString(), "").Code)
+	})
+
+	t.Run("delete removes lineage links", func(t *testing.T) {
+		chains, err := store.GetSessionLineage(ctx, parent.SessionID)
+		require.NoError(t, err)
+		require.Len(t, chains, 1)
+
+		assert.Equal(t, http.StatusNoContent, send(http.MethodDelete, "/api/v1/prompts/"+parent.ID.String(), "").Code)
+
+		_, err = store.GetPromptByID(ctx, parent.ID)
+		assert.ErrorIs(t, err, storage.ErrPromptNotFound)
+		chains, err = store.GetSessionLineage(ctx, parent.SessionID)
+		require.NoError(t, err)
+		require.Len(t, chains, 1)
+		assert.Equal(t, child.ID, chains[0][0].ID, "child no longer derives from the deleted prompt")
+
+		assert.Equal(t, http.StatusNotFound, send(http.MethodDelete, "/api/v1/prompts/"+parent.ID.String(), "").Code)
+	})
+}
+
+func TestHandleGetPrompt(t *testing.T) {
+	server, store := newTestServer(t)
+
+	ctx := context.Background()
+	prompt := &models.Prompt{
+		Content:       "draft a release note",
+		Phase:         models.PhasePrimaMaterial,
+		Provider:      "openai",
+		SessionID:     uuid.New(),
+		Embedding:     []float32{0.1, 0.2, 0.3},
+		ModelMetadata: &models.ModelMetadata{GenerationProvider: "openai", ProcessingTime: 420, TotalTokens: 90},
+		Context:       []models.PromptContext{{ContextType: "file", Content: "CHANGELOG.md"}},
+	}
+	require.NoError(t, store.SavePrompt(ctx, prompt))
+	require.NoError(t, store.SaveInteraction(ctx, &models.UserInteraction{PromptID: prompt.ID, SessionID: prompt.SessionID, Action: "chosen", Score: 0.8}))
+	require.NoError(t, store.SaveInteraction(ctx, &models.UserInteraction{PromptID: prompt.ID, SessionID: prompt.SessionID, Action: "skipped"}))
+
+	get := func(query string) (*httptest.ResponseRecorder, map[string]any) {
+		recorder := httptest.NewRecorder()
+		server.Router().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/prompts/"+prompt.ID.String()+query, nil))
+		var body map[string]any
+		if recorder.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+		}
+		return recorder, body
+	}
+
+	t.Run("embedding omitted by default", func(t *testing.T) {
+		recorder, body := get("")
+		require.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, prompt.Content, body["content"])
+		assert.NotContains(t, body, "embedding")
+		assert.NotContains(t, body, "metrics")
+		require.Contains(t, body, "model_metadata")
+		assert.EqualValues(t, 420, body["model_metadata"].(map[string]any)["processing_time"])
+		assert.Len(t, body["context"], 1)
+	})
+
+	t.Run("embedding and metrics on request", func(t *testing.T) {
+		recorder, body := get("?include_embedding=true&include_metrics=true")
+		require.Equal(t, http.StatusOK, recorder.Code)
+		assert.Len(t, body["embedding"], 3)
+		require.Contains(t, body, "metrics")
+		metrics := body["metrics"].(map[string]any)
+		assert.InDelta(t, 0.5, metrics["conversion_rate"], 1e-9)
+		assert.EqualValues(t, 90, metrics["token_usage"])
+	})
+
+	t.Run("bad and unknown IDs", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		server.Router().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/prompts/not-a-uuid", nil))
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+		recorder = httptest.NewRecorder()
+		server.Router().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/prompts/"+uuid.NewString(), nil))
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+}
+
+func TestHandleDiscoverRelationships(t *testing.T) {
+	server, store := newTestServer(t)
+
+	discover := func(body string) (int, DiscoverRelationshipsResponse) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/relationships/discover", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		server.Router().ServeHTTP(recorder, req)
+		var response DiscoverRelationshipsResponse
+		if recorder.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		}
+		return recorder.Code, response
+	}
+
+	ctx := context.Background()
+	seeded := map[string]*models.Prompt{}
+	for name, embedding := range map[string][]float32{
+		"source": {1, 0, 0},
+		"close":  {0.9, 0.3, 0},
+		"nearby": {0.8, 0.45, 0},
+		"loose":  {0.6, 0.8, 0},
+		"far":    {0, 1, 0},
+	} {
+		p := &models.Prompt{Content: name, Phase: models.PhaseSolutio, Provider: "openai", Embedding: embedding}
+		p.EmbeddingProvider, p.EmbeddingModel = "embed", "embed-model"
+		require.NoError(t, store.SavePrompt(ctx, p))
+		seeded[name] = p
+	}
+	source := seeded["source"].ID.String()
+
+	t.Run("links only prompts above the threshold", func(t *testing.T) {
+		status, response := discover(`{"prompt_id":"` + source + `","threshold":0.85,"max_links":1}`)
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, 1, response.Count)
+		assert.Equal(t, seeded["close"].ID, response.Relationships[0].TargetPromptID, "the most similar prompt is linked first")
+		assert.Equal(t, models.RelationshipSimilarTo, response.Relationships[0].Type)
+		assert.InDelta(t, 0.949, response.Relationships[0].Strength, 0.001)
+
+		status, response = discover(`{"prompt_id":"` + source + `","threshold":0.85}`)
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, 1, response.Count, "close is already linked, loose and far are below the threshold")
+		assert.Equal(t, seeded["nearby"].ID, response.Relationships[0].TargetPromptID)
+		assert.InDelta(t, 0.872, response.Relationships[0].Strength, 0.001)
+	})
+
+	t.Run("existing links are not duplicated in either direction", func(t *testing.T) {
+		status, response := discover(`{"prompt_id":"` + source + `","threshold":0.85}`)
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, 0, response.Count)
+
+		status, response = discover(`{"prompt_id":"` + seeded["close"].ID.String() + `","threshold":0.85}`)
+		require.Equal(t, http.StatusOK, status)
+		for _, rel := range response.Relationships {
+			assert.NotEqual(t, seeded["close"].ID, rel.TargetPromptID, "no self links")
+			assert.NotEqual(t, seeded["source"].ID, rel.TargetPromptID, "source already links to close")
+		}
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		status, _ := discover(`{"prompt_id":"not-a-uuid"}`)
+		assert.Equal(t, http.StatusBadRequest, status)
+		status, _ = discover(`{"prompt_id":"` + source + `","threshold":1.5}`)
+		assert.Equal(t, http.StatusBadRequest, status)
+		status, _ = discover(`{"prompt_id":"` + source + `","max_links":500}`)
+		assert.Equal(t, http.StatusBadRequest, status)
+		status, _ = discover(`{"prompt_id":"` + uuid.NewString() + `"}`)
+		assert.Equal(t, http.StatusNotFound, status)
+
+		unembedded := &models.Prompt{Content: "no embedding", Phase: models.PhaseSolutio, Provider: "openai"}
+		require.NoError(t, store.SavePrompt(ctx, unembedded))
+		status, _ = discover(`{"prompt_id":"` + unembedded.ID.String() + `"}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+	})
+}
+
+func TestHandleStats(t *testing.T) {
+	server, store := newTestServer(t)
+
+	ctx := context.Background()
+	require.NoError(t, store.SavePrompt(ctx, &models.Prompt{Content: "sort a list", Phase: models.PhaseSolutio, Provider: "openai",
+		Embedding: []float32{1, 0}, EmbeddingProvider: "openai", EmbeddingModel: "text-embedding-3-small"}))
+	require.NoError(t, store.SavePrompt(ctx, &models.Prompt{Content: "sort the results", Phase: models.PhaseSolutio, Provider: "openai"}))
+	require.NoError(t, store.SavePrompt(ctx, &models.Prompt{Content: "summarize the results", Phase: models.PhaseCoagulatio, Provider: "anthropic"}))
+
+	stats := func(query string) map[string]interface{} {
+		recorder := httptest.NewRecorder()
+		server.Router().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/stats"+query, nil))
+		require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+		return body
+	}
+
+	body := stats("")
+	assert.Equal(t, 3.0, body["total_prompts"])
+	assert.Equal(t, map[string]interface{}{"solutio": 2.0, "coagulatio": 1.0}, body["by_phase"])
+	assert.Equal(t, 33.3, body["embeddings"].(map[string]interface{})["coverage_percent"])
+	assert.NotContains(t, body, "relationships")
+	assert.NotContains(t, body, "usage")
+
+	body = stats("?include_relationships=true&include_enhancements=true&include_usage=true")
+	assert.Contains(t, body, "relationships")
+	assert.Contains(t, body, "enhancements")
+	assert.Contains(t, body, "usage")
+}
+
+func TestHandleListTags(t *testing.T) {
+	server, store := newTestServer(t)
+
+	ctx := context.Background()
+	for _, p := range []*models.Prompt{
+		{Content: "parse csv", Tags: []string{"csv", "parser"}, RelevanceScore: 0.8},
+		{Content: "parse json", Tags: []string{"json", "parser"}, RelevanceScore: 0.4},
+		{Content: "validate json", Tags: []string{"json", "parser"}, RelevanceScore: 0.3},
+	} {
+		p.Phase, p.Provider = models.PhaseSolutio, "openai"
+		require.NoError(t, store.SavePrompt(ctx, p))
+	}
+
+	listTags := func(query string) (int, []storage.TagStat) {
+		recorder := httptest.NewRecorder()
+		server.Router().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/tags"+query, nil))
+		var body struct {
+			Tags  []storage.TagStat `json:"tags"`
+			Total int               `json:"total"`
+		}
+		if recorder.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+			assert.Equal(t, len(body.Tags), body.Total)
+		}
+		return recorder.Code, body.Tags
+	}
+
+	status, tags := listTags("")
+	require.Equal(t, http.StatusOK, status)
+	require.Len(t, tags, 3)
+	assert.Equal(t, "parser", tags[0].Tag)
+	assert.Equal(t, 3, tags[0].PromptCount)
+	assert.InDelta(t, 0.5, tags[0].AverageScore, 1e-9)
+	assert.Equal(t, "json", tags[1].Tag)
+	assert.Equal(t, 2, tags[1].PromptCount)
+	assert.InDelta(t, 0.35, tags[1].AverageScore, 1e-9)
+	assert.Equal(t, storage.TagStat{Tag: "csv", PromptCount: 1, AverageScore: 0.8}, tags[2])
+
+	status, tags = listTags("?min_count=3")
+	require.Equal(t, http.StatusOK, status)
+	require.Len(t, tags, 1)
+	assert.Equal(t, "parser", tags[0].Tag)
+
+	for _, query := range []string{"?min_count=0", "?min_count=many"} {
+		status, _ = listTags(query)
+		assert.Equal(t, http.StatusBadRequest, status, query)
+	}
+}
+
+func TestHandlePromptVersions(t *testing.T) {
+	server, store := newTestServer(t)
+
+	ctx := context.Background()
+	prompt := &models.Prompt{Content: "v1", Phase: models.PhaseSolutio, Provider: "openai"}
+	require.NoError(t, store.SavePrompt(ctx, prompt))
+	for _, content := range []string{"v2", "v3"} {
+		prompt.Content = content
+		require.NoError(t, store.UpdatePrompt(ctx, prompt))
+	}
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		server.Router().ServeHTTP(recorder, httptest.NewRequest(method, path, nil))
+		return recorder
+	}
+	base := "/api/v1/prompts/" + prompt.ID.String()
+
+	recorder := serve(http.MethodGet, base+"/versions")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var listed PromptVersionsResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &listed))
+	require.Equal(t, 2, listed.Count)
+	assert.Equal(t, "v1", listed.Versions[0].Content)
+	assert.Equal(t, "v2", listed.Versions[1].Content)
+
+	recorder = serve(http.MethodPost, base+"/versions/1/restore")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var restored models.Prompt
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &restored))
+	assert.Equal(t, "v1", restored.Content)
+
+	recorder = serve(http.MethodGet, base+"/versions")
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &listed))
+	require.Equal(t, 3, listed.Count)
+	assert.Equal(t, "v3", listed.Versions[2].Content)
+
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodPost, base+"/versions/9/restore").Code)
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodPost, base+"/versions/latest/restore").Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/api/v1/prompts/"+uuid.NewString()+"/versions").Code)
+}
+
+func TestParseMinSimilarity(t *testing.T) {
+	for value, want := range map[string]float64{
+		"":    defaultMinSimilarity,
+		"0.7": 0.7,
+		"1.5": 1,
+		"-2":  0,
+	} {
+		got, err := parseMinSimilarity(value)
+		require.NoError(t, err, value)
+		assert.Equal(t, want, got, value)
+	}
+
+	_, err := parseMinSimilarity("high")
+	assert.Error(t, err)
+}
+
+func TestRequireJSON(t *testing.T) {
+	server, _ := newTestServer(t)
+
+	post := func(path, contentType, body string) int {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		recorder := httptest.NewRecorder()
+		server.Router().ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	assert.Equal(t, http.StatusUnsupportedMediaType, post("/api/v1/prompts/generate", "application/x-www-form-urlencoded", "input=test"))
+	assert.Equal(t, http.StatusUnsupportedMediaType, post("/api/zoom", "text/plain", `{"action":"in"}`))
+	assert.Equal(t, http.StatusUnsupportedMediaType, post("/api/summarize", "", `{"text":"hello"}`))
+
+	// JSON bodies still reach the handler
+	assert.Equal(t, http.StatusOK, post("/api/zoom", "application/json", `{"action":"in"}`))
+	assert.Equal(t, http.StatusBadRequest, post("/api/activate-phase", "application/json; charset=utf-8", `{}`))
+}
+
+func TestPropagateRequestID(t *testing.T) {
+	var seen string
+	handler := middleware.RequestID(PropagateRequestID()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = log.RequestIDFromContext(r.Context())
+	})))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/health", nil)
+	req.Header.Set("X-Request-Id", "trace-me")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	assert.Equal(t, "trace-me", seen)
+	assert.Equal(t, "trace-me", recorder.Header().Get("X-Request-ID"))
+}
+
+func TestRequestIDInLogs(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	logger.SetLevel(logrus.InfoLevel)
+	server, _ := newTestServerWithLogger(t, logger)
+	require.NoError(t, server.registry.Register("counting", &countingProvider{pingProvider: pingProvider{name: "counting", available: true}}))
+
+	send := func(requestID, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/prompts/generate", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Request-Id", requestID)
+		recorder := httptest.NewRecorder()
+		server.Router().ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	t.Run("handler and engine log lines carry the request ID", func(t *testing.T) {
+		hook.Reset()
+		recorder := send("req-generate", `{"input":"Write a CLI","phases":["prima-materia"],"count":1,"providers":{"prima-materia":"counting"}}`)
+		require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+
+		requestIDs := map[string]interface{}{}
+		for _, entry := range hook.AllEntries() {
+			switch entry.Message {
+			case "=== GENERATE ENDPOINT CALLED ===", "Processing phase":
+				requestIDs[entry.Message] = entry.Data["request_id"]
+			}
+		}
+		assert.Equal(t, map[string]interface{}{
+			"=== GENERATE ENDPOINT CALLED ===": "req-generate", // handler
+			"Processing phase":                 "req-generate", // engine
+		}, requestIDs)
+	})
+
+	t.Run("error responses carry the request ID", func(t *testing.T) {
+		recorder := send("req-invalid", `{"input":`)
+		require.Equal(t, http.StatusBadRequest, recorder.Code)
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		assert.Equal(t, "req-invalid", response["request_id"])
+	})
+}
+
+func keys(v interface{}) []string {
+	var out []string
+	for k := range v.(map[string]interface{}) {
+		out = append(out, k)
+	}
+	return out
+}
+
+func TestHandleGeneratePromptsVariables(t *testing.T) {
+	server, _ := newTestServer(t)
+	provider := &countingProvider{pingProvider: pingProvider{name: "counting", available: true}}
+	require.NoError(t, server.registry.Register("counting", provider))
+
+	generate := func(fields string) *httptest.ResponseRecorder {
+		body := `{"phases":["prima-materia"],"save_phases":["prima-materia"],"count":1,"providers":{"prima-materia":"counting"},` + fields + `}`
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/prompts/generate", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		server.Router().ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	t.Run("resolved input is stored with the template", func(t *testing.T) {
+		recorder := generate(`"input":"Write a {{language}} CLI with {{framework}}","variables":{"language":"Go","framework":"cobra"}`)
+		require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+		var response GenerateResponse
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		require.Len(t, response.Prompts, 1)
+		assert.Equal(t, "Write a Go CLI with cobra", response.Prompts[0].OriginalInput)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/prompts/"+response.Prompts[0].ID.String(), nil)
+		detail := httptest.NewRecorder()
+		server.Router().ServeHTTP(detail, req)
+		require.Equal(t, http.StatusOK, detail.Code, detail.Body.String())
+		var stored models.Prompt
+		require.NoError(t, json.Unmarshal(detail.Body.Bytes(), &stored))
+		assert.Equal(t, "Write a Go CLI with cobra", stored.OriginalInput)
+		assert.Equal(t, "Write a {{language}} CLI with {{framework}}", stored.InputTemplate)
+		assert.Equal(t, map[string]string{"language": "Go", "framework": "cobra"}, stored.InputVariables)
+	})
+
+	t.Run("missing variable is a bad request", func(t *testing.T) {
+		provider.calls.Store(0)
+		recorder := generate(`"input":"Write a {{language}} CLI with {{framework}}","variables":{"language":"Go"}`)
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "unresolved template variables: framework")
+		assert.Zero(t, provider.calls.Load())
+	})
+
+	t.Run("missing variable allowed", func(t *testing.T) {
+		recorder := generate(`"input":"Write a {{language}} CLI with {{framework}}","variables":{"language":"Go"},"allow_missing":true`)
+		require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+		var response GenerateResponse
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		assert.Equal(t, "Write a Go CLI with {{framework}}", response.Prompts[0].OriginalInput)
+	})
+}
+
+func TestHandleGeneratePromptsSemanticCache(t *testing.T) {
+	t.Cleanup(viper.Reset)
+	viper.Set(engine.SemanticCacheEnabledKey, true)
+
+	server, store := newTestServer(t)
+	var generations atomic.Int32
+	require.NoError(t, server.registry.Register("mock", &providers.MockProvider{
+		GenerateFunc: func(ctx context.Context, req providers.GenerateRequest) (*providers.GenerateResponse, error) {
+			generations.Add(1)
+			return &providers.GenerateResponse{Content: "generated", Model: "mock-model", InputTokens: 40, OutputTokens: 60, TokensUsed: 100}, nil
+		},
+		GetEmbeddingFunc: func(ctx context.Context, text string, registry providers.RegistryInterface) ([]float32, error) {
+			return []float32{0.1, 0.2, 0.3}, nil
+		},
+		SupportsEmbeddingsFunc: func() bool { return true },
+	}))
+
+	generate := func(query string) GenerateResponse {
+		body := `{"input":"Design a rate limiter","phases":["prima-materia"],"save_phases":["prima-materia"],"count":1,"providers":{"prima-materia":"mock"}}`
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/prompts/generate"+query, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		server.Router().ServeHTTP(recorder, req)
+		require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+		var response GenerateResponse
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		require.Len(t, response.Prompts, 1)
+		return response
+	}
+
+	first := generate("?save=false")
+	assert.False(t, first.Metadata.Cached)
+	assert.Equal(t, 100, first.Metadata.TotalInputTokens+first.Metadata.TotalOutputTokens)
+	_, err := store.GetPromptByID(context.Background(), first.Prompts[0].ID)
+	require.ErrorIs(t, err, storage.ErrPromptNotFound)
+
+	// The hit answers with the first generation, saved now that it's asked for
+	cached := generate("")
+	assert.Equal(t, int32(1), generations.Load())
+	assert.True(t, cached.Metadata.Cached)
+	assert.Equal(t, first.SessionID, cached.SessionID)
+	assert.Equal(t, first.Prompts[0].ID, cached.Prompts[0].ID)
+	assert.Equal(t, first.SessionID, cached.Prompts[0].SessionID)
+	assert.Zero(t, cached.Metadata.TotalInputTokens)
+	assert.Zero(t, cached.Metadata.TotalOutputTokens)
+	assert.Zero(t, cached.Metadata.EstimatedCostUSD)
+
+	stored, err := store.GetPromptByID(context.Background(), first.Prompts[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, first.SessionID, stored.SessionID)
+}
+
+func TestHandleGeneratePromptsDryRun(t *testing.T) {
+	t.Cleanup(viper.Reset)
+	viper.Set("phases.prima-materia.provider", "anthropic")
+	viper.Set("phases.solutio.provider", "openai")
+	viper.Set("providers.openai.model", "gpt-4o-mini")
+
+	server, _ := newTestServer(t)
+	openai := &countingProvider{pingProvider: pingProvider{name: "openai", available: true}}
+	anthropic := &countingProvider{pingProvider: pingProvider{name: "anthropic", available: true}}
+	require.NoError(t, server.registry.Register("openai", openai))
+	require.NoError(t, server.registry.Register("anthropic", anthropic))
+
+	body := `{"input":"Write a {{language}} CLI","variables":{"language":"Go"},"count":2,"max_tokens":500}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/prompts/generate?dry_run=true", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	server.Router().ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+
+	var response GenerateResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.Zero(t, openai.calls.Load())
+	assert.Zero(t, anthropic.calls.Load())
+	assert.Empty(t, response.Prompts)
+
+	// Coagulatio has no configured provider and falls back to openai
+	meta := response.Metadata
+	assert.True(t, meta.DryRun)
+	assert.Equal(t, map[string]string{"prima-materia": "anthropic", "solutio": "openai", "coagulatio": "openai"}, meta.ProvidersUsed)
+	require.NotNil(t, meta.Estimate)
+	require.Len(t, meta.Estimate.Phases, 3)
+
+	var cost float64
+	for _, phase := range meta.Estimate.Phases {
+		assert.Equal(t, meta.ProvidersUsed[string(phase.Phase)], phase.Provider)
+		assert.Equal(t, 2, phase.Calls)
+		assert.Equal(t, 1000, phase.OutputTokens)
+		assert.Positive(t, phase.InputTokens)
+		require.True(t, phase.Priced, phase.Model)
+		pricing, _ := providers.LookupPricing(phase.Provider, phase.Model)
+		assert.InDelta(t, pricing.Cost(phase.InputTokens, phase.OutputTokens), phase.CostUSD, 1e-9)
+		cost += phase.CostUSD
+	}
+	assert.Equal(t, "gpt-4o-mini", meta.Estimate.Phases[1].Model)
+	assert.Equal(t, providers.DefaultModel("anthropic"), meta.Estimate.Phases[0].Model)
+	// Later phases are fed the previous phase's full output
+	assert.Greater(t, meta.Estimate.Phases[1].InputTokens, 1000)
+	assert.Equal(t, 3000, meta.TotalOutputTokens)
+	assert.InDelta(t, cost, meta.EstimatedCostUSD, 1e-9)
+}
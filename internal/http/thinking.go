@@ -0,0 +1,94 @@
+package http
+
+import "sync"
+
+// thinkingSubscriberBuffer is how many updates a slow thinking stream may
+// fall behind before updates to it are dropped
+const thinkingSubscriberBuffer = 16
+
+// ThinkingUpdate is a step of the AI's thinking, posted to
+// /api/thinking-update and streamed to the session's /api/thinking-stream
+type ThinkingUpdate struct {
+	Type      string `json:"type"`
+	Phase     string `json:"phase"`
+	Stage     string `json:"stage"`
+	Message   string `json:"message"`
+	Progress  int    `json:"progress"`
+	SessionID string `json:"session_id"`
+	Timestamp string `json:"timestamp"`
+}
+
+// ThinkingHub fans thinking updates out to the streams subscribed to their
+// session. Streams subscribed without a session receive every update.
+type ThinkingHub struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan ThinkingUpdate]struct{}
+}
+
+// NewThinkingHub creates a hub without subscribers
+func NewThinkingHub() *ThinkingHub {
+	return &ThinkingHub{subscribers: make(map[string]map[chan ThinkingUpdate]struct{})}
+}
+
+// Subscribe returns a channel receiving the updates of sessionID, or of
+// every session when it is empty, and a function that unsubscribes and
+// closes it
+func (h *ThinkingHub) Subscribe(sessionID string) (<-chan ThinkingUpdate, func()) {
+	ch := make(chan ThinkingUpdate, thinkingSubscriberBuffer)
+
+	h.mu.Lock()
+	if h.subscribers[sessionID] == nil {
+		h.subscribers[sessionID] = make(map[chan ThinkingUpdate]struct{})
+	}
+	h.subscribers[sessionID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subscribers[sessionID], ch)
+			if len(h.subscribers[sessionID]) == 0 {
+				delete(h.subscribers, sessionID)
+			}
+			h.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// Publish sends update to the subscribers of its session and to those of
+// every session, and returns how many received it. Updates are dropped for a
+// subscriber that falls behind rather than stalling the publisher.
+func (h *ThinkingHub) Publish(update ThinkingUpdate) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delivered := 0
+	send := func(subscribers map[chan ThinkingUpdate]struct{}) {
+		for ch := range subscribers {
+			select {
+			case ch <- update:
+				delivered++
+			default:
+			}
+		}
+	}
+	send(h.subscribers[update.SessionID])
+	if update.SessionID != "" {
+		send(h.subscribers[""])
+	}
+	return delivered
+}
+
+// Subscribers returns the number of streams subscribed to any session
+func (h *ThinkingHub) Subscribers() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	n := 0
+	for _, subscribers := range h.subscribers {
+		n += len(subscribers)
+	}
+	return n
+}
@@ -0,0 +1,93 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThinkingHub(t *testing.T) {
+	hub := NewThinkingHub()
+	session, unsubscribeSession := hub.Subscribe("s1")
+	all, unsubscribeAll := hub.Subscribe("")
+	other, unsubscribeOther := hub.Subscribe("s2")
+	assert.Equal(t, 3, hub.Subscribers())
+
+	assert.Equal(t, 2, hub.Publish(ThinkingUpdate{SessionID: "s1", Message: "hello"}))
+	assert.Equal(t, "hello", (<-session).Message)
+	assert.Equal(t, "hello", (<-all).Message)
+	assert.Empty(t, other, "other sessions don't receive the update")
+
+	unsubscribeSession()
+	unsubscribeAll()
+	unsubscribeOther()
+	unsubscribeOther()
+	assert.Equal(t, 0, hub.Subscribers())
+	assert.Empty(t, hub.subscribers, "sessions without subscribers are forgotten")
+	assert.Equal(t, 0, hub.Publish(ThinkingUpdate{SessionID: "s1"}))
+
+	_, ok := <-session
+	assert.False(t, ok, "unsubscribing closes the channel")
+}
+
+func TestThinkingStream(t *testing.T) {
+	server, _ := newTestServer(t)
+	ts := httptest.NewServer(server.Router())
+	t.Cleanup(ts.Close)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/api/thinking-stream?session_id=s1", nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+
+	events := bufio.NewScanner(resp.Body)
+	next := func() map[string]interface{} {
+		t.Helper()
+		for events.Scan() {
+			if data, ok := strings.CutPrefix(events.Text(), "data: "); ok {
+				var event map[string]interface{}
+				require.NoError(t, json.Unmarshal([]byte(data), &event))
+				return event
+			}
+		}
+		t.Fatalf("stream ended: %v", events.Err())
+		return nil
+	}
+	// The stream is subscribed once it has connected
+	require.Equal(t, "connected", next()["type"])
+
+	post := func(body string) ThinkingUpdateResponse {
+		t.Helper()
+		resp, err := http.Post(ts.URL+"/api/thinking-update", "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		defer func() { _ = resp.Body.Close() }()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var response ThinkingUpdateResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+		return response
+	}
+
+	assert.Equal(t, 0, post(`{"session_id":"s2","message":"elsewhere"}`).Delivered)
+	assert.Equal(t, 1, post(`{"session_id":"s1","phase":"solutio","message":"refining","progress":40}`).Delivered)
+
+	event := next()
+	assert.Equal(t, "thinking", event["type"])
+	assert.Equal(t, "solutio", event["phase"])
+	assert.Equal(t, "refining", event["message"])
+	assert.Equal(t, float64(40), event["progress"])
+
+	// Disconnecting unsubscribes the stream
+	cancel()
+	assert.Eventually(t, func() bool { return server.thinking.Subscribers() == 0 }, 2*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,91 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jonwraymond/prompt-alchemy/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+// maxVectorSearchLimit bounds the results of a vector search, like ?limit=
+// of the text search
+const maxVectorSearchLimit = 100
+
+// SearchByVectorRequest searches prompts by a precomputed embedding, which
+// must have the dimension of the stored embeddings
+type SearchByVectorRequest struct {
+	Vector        []float32 `json:"vector"`
+	Limit         int       `json:"limit,omitempty"`
+	MinSimilarity *float64  `json:"min_similarity,omitempty"`
+}
+
+// handleSearchByVector ranks stored prompts by cosine similarity to the
+// request's vector, without calling an embedding provider
+func (s *SimpleServer) handleSearchByVector(w http.ResponseWriter, r *http.Request) {
+	logger := s.requestLogger(r.Context())
+
+	var req SearchByVectorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid JSON in request body")
+		return
+	}
+
+	fields := map[string]string{}
+	if len(req.Vector) == 0 {
+		fields["vector"] = "vector is required"
+	}
+	if req.Limit < 0 || req.Limit > maxVectorSearchLimit {
+		fields["limit"] = fmt.Sprintf("limit must be between 1 and %d", maxVectorSearchLimit)
+	}
+	minSimilarity := defaultMinSimilarity
+	if req.MinSimilarity != nil {
+		minSimilarity = *req.MinSimilarity
+		if minSimilarity < 0 || minSimilarity > 1 {
+			fields["min_similarity"] = "min_similarity must be between 0 and 1"
+		}
+	}
+	if len(fields) > 0 {
+		s.writeValidationError(w, "Invalid vector search request", fields)
+		return
+	}
+	limit := req.Limit
+	if limit == 0 {
+		limit = 10
+	}
+
+	criteria := storage.SemanticSearchCriteria{Limit: limit, MinSimilarity: minSimilarity}
+	prompts, similarities, err := s.store.SearchPromptsByVector(r.Context(), req.Vector, criteria)
+	if errors.Is(err, storage.ErrEmbeddingDimensionMismatch) {
+		s.writeValidationError(w, "Vector dimension does not match the stored embeddings", map[string]string{
+			"vector": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		logger.WithError(err).Error("Vector search failed")
+		s.writeAPIError(w, apiErrorFrom(err, fmt.Sprintf("Search failed: %v", err)))
+		return
+	}
+
+	logger.WithFields(logrus.Fields{
+		"dimensions": len(req.Vector),
+		"results":    len(prompts),
+	}).Info("Vector search completed via HTTP API")
+
+	s.writeJSON(w, http.StatusOK, SearchPromptsResponse{
+		Prompts:      prompts,
+		TotalFound:   len(prompts),
+		SearchType:   storage.SearchTypeVector,
+		Similarities: similarities,
+		Metadata: SearchMetadata{
+			Limit:         limit,
+			Semantic:      true,
+			MinSimilarity: minSimilarity,
+			SearchedAt:    time.Now(),
+		},
+	})
+}
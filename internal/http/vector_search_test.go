@@ -0,0 +1,91 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jonwraymond/prompt-alchemy/internal/storage"
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleSearchByVector(t *testing.T) {
+	// No provider is registered, so nothing can embed a query
+	server, store := newTestServer(t)
+
+	search := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/prompts/search-by-vector", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		server.Router().ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	ctx := context.Background()
+	for _, seeded := range []struct {
+		content   string
+		embedding []float32
+	}{
+		{"Parse a CSV file", []float32{1, 0, 0}},
+		{"Stream a CSV file", []float32{0.8, 0.6, 0}},
+		{"Render a chart", []float32{0, 0, 1}},
+	} {
+		require.NoError(t, store.SavePrompt(ctx, &models.Prompt{
+			Content:           seeded.content,
+			Phase:             models.PhaseCoagulatio,
+			Provider:          "openai",
+			Embedding:         seeded.embedding,
+			EmbeddingProvider: "embed",
+			EmbeddingModel:    "embed-model",
+		}))
+	}
+
+	t.Run("most similar first", func(t *testing.T) {
+		recorder := search(`{"vector":[1,0.1,0],"min_similarity":0.5}`)
+		require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+
+		var response SearchPromptsResponse
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		assert.Equal(t, storage.SearchTypeVector, response.SearchType)
+		require.Len(t, response.Prompts, 2, "the chart prompt is below min_similarity")
+		assert.Equal(t, "Parse a CSV file", response.Prompts[0].Content)
+		assert.Equal(t, "Stream a CSV file", response.Prompts[1].Content)
+		require.Len(t, response.Similarities, 2)
+		assert.Greater(t, response.Similarities[0], response.Similarities[1])
+	})
+
+	t.Run("limit", func(t *testing.T) {
+		recorder := search(`{"vector":[1,0.1,0],"limit":1,"min_similarity":0}`)
+		require.Equal(t, http.StatusOK, recorder.Code)
+		var response SearchPromptsResponse
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		require.Len(t, response.Prompts, 1)
+		assert.Equal(t, "Parse a CSV file", response.Prompts[0].Content)
+	})
+
+	t.Run("dimension mismatch", func(t *testing.T) {
+		recorder := search(`{"vector":[1,0]}`)
+		require.Equal(t, http.StatusBadRequest, recorder.Code)
+		var response struct {
+			Fields map[string]string `json:"fields"`
+		}
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		assert.Contains(t, response.Fields["vector"], "expected 3, got 2")
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		for _, body := range []string{
+			`{}`,
+			`{"vector":[1,0,0],"limit":1000}`,
+			`{"vector":[1,0,0],"min_similarity":2}`,
+			`not json`,
+		} {
+			assert.Equal(t, http.StatusBadRequest, search(body).Code, body)
+		}
+	})
+}
@@ -0,0 +1,50 @@
+package httputil
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// IsJSONContentType reports whether a Content-Type header value names JSON,
+// either application/json or a structured +json type such as
+// application/merge-patch+json. Parameters like charset are ignored.
+func IsJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" ||
+		(strings.HasPrefix(mediaType, "application/") && strings.HasSuffix(mediaType, "+json"))
+}
+
+// IsJSONLinesContentType reports whether a Content-Type header value names
+// newline-delimited JSON: application/x-ndjson, application/jsonl or
+// application/jsonlines
+func IsJSONLinesContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch mediaType {
+	case "application/x-ndjson", "application/jsonl", "application/jsonlines":
+		return true
+	default:
+		return false
+	}
+}
+
+// HasJSONBody reports whether a request body, if any, is declared as JSON.
+// Methods without a body and requests with an empty body always pass, so
+// the handler can report a missing payload itself.
+func HasJSONBody(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+	default:
+		return true
+	}
+	if r.ContentLength == 0 {
+		return true
+	}
+	return IsJSONContentType(r.Header.Get("Content-Type"))
+}
@@ -0,0 +1,73 @@
+package httputil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsJSONContentType(t *testing.T) {
+	tests := []struct {
+		contentType string
+		expected    bool
+	}{
+		{"application/json", true},
+		{"application/json; charset=utf-8", true},
+		{"Application/JSON", true},
+		{"application/merge-patch+json", true},
+		{"", false},
+		{"text/plain", false},
+		{"application/x-www-form-urlencoded", false},
+		{"multipart/form-data; boundary=xyz", false},
+		{"text/json+xml", false},
+		{"application/json;;", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.contentType, func(t *testing.T) {
+			assert.Equal(t, tt.expected, IsJSONContentType(tt.contentType))
+		})
+	}
+}
+
+func TestIsJSONLinesContentType(t *testing.T) {
+	for contentType, expected := range map[string]bool{
+		"application/x-ndjson":             true,
+		"application/jsonl; charset=utf-8": true,
+		"application/jsonlines":            true,
+		"application/json":                 false,
+		"text/plain":                       false,
+		"application/x-ndjson;;":           false,
+	} {
+		assert.Equal(t, expected, IsJSONLinesContentType(contentType), contentType)
+	}
+}
+
+func TestHasJSONBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		body        string
+		contentType string
+		expected    bool
+	}{
+		{"json post", http.MethodPost, `{"input":"x"}`, "application/json", true},
+		{"form post", http.MethodPost, "input=x", "application/x-www-form-urlencoded", false},
+		{"missing content type", http.MethodPut, `{"input":"x"}`, "", false},
+		{"empty body", http.MethodPost, "", "", true},
+		{"get ignores content type", http.MethodGet, "", "text/plain", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			assert.Equal(t, tt.expected, HasJSONBody(req))
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package httputil
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// FieldsParam is the query parameter clients use to request a sparse fieldset
+const FieldsParam = "fields"
+
+// FieldSet is the set of top-level JSON fields a client asked for. A nil
+// FieldSet selects every field. The id field is always kept so projected
+// items can still be referenced.
+type FieldSet map[string]bool
+
+// ParseFields reads the comma-separated fields query parameter, e.g.
+// ?fields=id,content,phase. It returns nil when the parameter is absent.
+func ParseFields(r *http.Request) FieldSet {
+	raw := r.URL.Query().Get(FieldsParam)
+	if strings.TrimSpace(raw) == "" {
+		return nil
+	}
+
+	fields := FieldSet{"id": true}
+	for _, name := range strings.Split(raw, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			fields[name] = true
+		}
+	}
+	return fields
+}
+
+// Project reduces v to the selected fields. Objects are filtered by key and
+// arrays are filtered element by element; other values are returned as is.
+// The result marshals to the same JSON as v minus the unselected fields.
+func (f FieldSet) Project(v interface{}) (interface{}, error) {
+	if f == nil {
+		return v, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal value for projection: %w", err)
+	}
+	return f.projectRaw(data)
+}
+
+func (f FieldSet) projectRaw(data json.RawMessage) (json.RawMessage, error) {
+	trimmed := strings.TrimSpace(string(data))
+	switch {
+	case strings.HasPrefix(trimmed, "{"):
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(data, &object); err != nil {
+			return nil, fmt.Errorf("failed to decode object for projection: %w", err)
+		}
+		for key := range object {
+			if !f[key] {
+				delete(object, key)
+			}
+		}
+		return json.Marshal(object)
+
+	case strings.HasPrefix(trimmed, "["):
+		var items []json.RawMessage
+		if err := json.Unmarshal(data, &items); err != nil {
+			return nil, fmt.Errorf("failed to decode array for projection: %w", err)
+		}
+		for i, item := range items {
+			projected, err := f.projectRaw(item)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = projected
+		}
+		return json.Marshal(items)
+
+	default:
+		return data, nil
+	}
+}
@@ -0,0 +1,92 @@
+package httputil
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFields(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  FieldSet
+	}{
+		{name: "absent", query: "", want: nil},
+		{name: "blank", query: "?fields=%20", want: nil},
+		{name: "id always included", query: "?fields=content", want: FieldSet{"id": true, "content": true}},
+		{name: "whitespace and empty entries", query: "?fields=content,%20phase,,", want: FieldSet{"id": true, "content": true, "phase": true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/api/v1/prompts"+tt.query, nil)
+			assert.Equal(t, tt.want, ParseFields(r))
+		})
+	}
+}
+
+func TestFieldSetProject(t *testing.T) {
+	type item struct {
+		ID      string   `json:"id"`
+		Content string   `json:"content"`
+		Tags    []string `json:"tags"`
+		Score   float64  `json:"score"`
+	}
+	items := []item{
+		{ID: "a", Content: "first", Tags: []string{"x"}, Score: 0.5},
+		{ID: "b", Content: "second", Score: 1},
+	}
+
+	tests := []struct {
+		name   string
+		fields FieldSet
+		value  interface{}
+		want   string
+	}{
+		{
+			name:   "nil field set keeps everything",
+			fields: nil,
+			value:  items[0],
+			want:   `{"id":"a","content":"first","tags":["x"],"score":0.5}`,
+		},
+		{
+			name:   "object",
+			fields: FieldSet{"id": true, "content": true},
+			value:  items[0],
+			want:   `{"id":"a","content":"first"}`,
+		},
+		{
+			name:   "array of objects",
+			fields: FieldSet{"id": true, "score": true},
+			value:  items,
+			want:   `[{"id":"a","score":0.5},{"id":"b","score":1}]`,
+		},
+		{
+			name:   "nested arrays",
+			fields: FieldSet{"id": true},
+			value:  [][]item{items},
+			want:   `[[{"id":"a"},{"id":"b"}]]`,
+		},
+		{
+			name:   "unknown fields are ignored",
+			fields: FieldSet{"id": true, "missing": true},
+			value:  items[1],
+			want:   `{"id":"b"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			projected, err := tt.fields.Project(tt.value)
+			require.NoError(t, err)
+
+			data, err := json.Marshal(projected)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(data))
+		})
+	}
+}
@@ -19,9 +19,10 @@ type Response struct {
 
 // ErrorInfo represents error details in API responses
 type ErrorInfo struct {
-	Code    string `json:"code"`
-	Message string `json:"message"`
-	Details string `json:"details,omitempty"`
+	Code    string            `json:"code"`
+	Message string            `json:"message"`
+	Details string            `json:"details,omitempty"`
+	Fields  map[string]string `json:"fields,omitempty"` // invalid request fields, see ValidationFailed
 }
 
 // PaginatedResponse represents a paginated API response
@@ -119,6 +120,27 @@ func BadRequest(w http.ResponseWriter, message string) {
 	WriteError(w, http.StatusBadRequest, "BAD_REQUEST", message)
 }
 
+// ValidationFailed writes a 400 Bad Request error listing what is wrong with
+// each invalid request field
+func ValidationFailed(w http.ResponseWriter, message string, fields map[string]string) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusBadRequest)
+
+	response := Response{
+		Success: false,
+		Error: &ErrorInfo{
+			Code:    "VALIDATION_FAILED",
+			Message: message,
+			Fields:  fields,
+		},
+		Timestamp: time.Now(),
+	}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.GetLogger().WithError(err).Error("Failed to encode JSON error response")
+	}
+}
+
 // Unauthorized writes a 401 Unauthorized error
 func Unauthorized(w http.ResponseWriter, message string) {
 	WriteError(w, http.StatusUnauthorized, "UNAUTHORIZED", message)
@@ -134,6 +156,16 @@ func NotFound(w http.ResponseWriter, message string) {
 	WriteError(w, http.StatusNotFound, "NOT_FOUND", message)
 }
 
+// RequestEntityTooLarge writes a 413 Request Entity Too Large error
+func RequestEntityTooLarge(w http.ResponseWriter, message string) {
+	WriteError(w, http.StatusRequestEntityTooLarge, "REQUEST_TOO_LARGE", message)
+}
+
+// UnsupportedMediaType writes a 415 Unsupported Media Type error
+func UnsupportedMediaType(w http.ResponseWriter, message string) {
+	WriteError(w, http.StatusUnsupportedMediaType, "UNSUPPORTED_MEDIA_TYPE", message)
+}
+
 // InternalServerError writes a 500 Internal Server Error
 func InternalServerError(w http.ResponseWriter, message string) {
 	WriteError(w, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", message)
@@ -0,0 +1,122 @@
+package httputil
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/jonwraymond/prompt-alchemy/internal/log"
+)
+
+// streamFlushInterval is the number of array elements written between flushes
+const streamFlushInterval = 50
+
+// ArrayStreamer writes a standard API response whose data field is a JSON
+// array encoded one element at a time. Elements are written straight to the
+// client as they are produced instead of being buffered into a single slice,
+// which keeps memory flat and lowers time-to-first-byte for large listings.
+//
+// The envelope matches PaginatedResponse, so clients can decode a streamed
+// response exactly like a buffered one.
+type ArrayStreamer struct {
+	w       io.Writer
+	enc     *json.Encoder
+	flusher http.Flusher
+	count   int
+	err     error
+}
+
+// NewArrayStreamer writes the response headers and the opening of the
+// envelope. Callers must finish the response with Close or Abort.
+func NewArrayStreamer(w http.ResponseWriter, status int) *ArrayStreamer {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+
+	s := &ArrayStreamer{w: w, enc: json.NewEncoder(w)}
+	if f, ok := w.(http.Flusher); ok {
+		s.flusher = f
+	}
+
+	success, _ := json.Marshal(status >= 200 && status < 300)
+	s.write(`{"success":` + string(success) + `,"data":[`)
+	return s
+}
+
+// Encode appends a single element to the data array
+func (s *ArrayStreamer) Encode(v interface{}) error {
+	if s.err != nil {
+		return s.err
+	}
+	if s.count > 0 {
+		s.write(",")
+	}
+	if s.err == nil {
+		s.err = s.enc.Encode(v)
+	}
+	if s.err != nil {
+		return s.err
+	}
+
+	s.count++
+	if s.flusher != nil && s.count%streamFlushInterval == 0 {
+		s.flusher.Flush()
+	}
+	return nil
+}
+
+// Count returns the number of elements written so far
+func (s *ArrayStreamer) Count() int {
+	return s.count
+}
+
+// Close terminates the data array and writes the trailing envelope fields
+func (s *ArrayStreamer) Close(pagination *PaginationInfo) error {
+	s.write("]")
+	if pagination != nil {
+		s.writeField("pagination", pagination)
+	}
+	s.writeField("timestamp", time.Now())
+	s.write("}\n")
+	return s.finish()
+}
+
+// Abort terminates the data array early and reports the error in the
+// envelope. The status line has already been sent at this point, so the
+// error field is the only way to tell the client the listing is incomplete.
+func (s *ArrayStreamer) Abort(code, message string) error {
+	s.write("]")
+	s.writeField("error", &ErrorInfo{Code: code, Message: message})
+	s.writeField("timestamp", time.Now())
+	s.write("}\n")
+	return s.finish()
+}
+
+func (s *ArrayStreamer) writeField(name string, v interface{}) {
+	if s.err != nil {
+		return
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		s.err = err
+		return
+	}
+	s.write(`,"` + name + `":` + string(data))
+}
+
+func (s *ArrayStreamer) write(str string) {
+	if s.err != nil {
+		return
+	}
+	_, s.err = io.WriteString(s.w, str)
+}
+
+func (s *ArrayStreamer) finish() error {
+	if s.flusher != nil {
+		s.flusher.Flush()
+	}
+	if s.err != nil {
+		log.GetLogger().WithError(s.err).Error("Failed to stream JSON response")
+	}
+	return s.err
+}
@@ -0,0 +1,86 @@
+package httputil
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArrayStreamer(t *testing.T) {
+	tests := []struct {
+		name       string
+		items      []map[string]int
+		pagination *PaginationInfo
+	}{
+		{
+			name:  "empty array",
+			items: nil,
+		},
+		{
+			name:       "single item with pagination",
+			items:      []map[string]int{{"n": 1}},
+			pagination: CalculatePagination(1, 20, 1),
+		},
+		{
+			name: "items across flush interval",
+			items: func() []map[string]int {
+				items := make([]map[string]int, streamFlushInterval*2+3)
+				for i := range items {
+					items[i] = map[string]int{"n": i}
+				}
+				return items
+			}(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+
+			stream := NewArrayStreamer(recorder, http.StatusOK)
+			for _, item := range tt.items {
+				require.NoError(t, stream.Encode(item))
+			}
+			require.NoError(t, stream.Close(tt.pagination))
+
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			assert.Equal(t, "application/json; charset=utf-8", recorder.Header().Get("Content-Type"))
+			assert.Equal(t, len(tt.items), stream.Count())
+
+			var response struct {
+				PaginatedResponse
+				Data []map[string]int `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+
+			assert.True(t, response.Success)
+			assert.NotZero(t, response.Timestamp)
+			assert.Nil(t, response.Error)
+			assert.Len(t, response.Data, len(tt.items))
+			for i, item := range tt.items {
+				assert.Equal(t, item, response.Data[i])
+			}
+			assert.Equal(t, tt.pagination, response.Pagination)
+		})
+	}
+}
+
+func TestArrayStreamerAbort(t *testing.T) {
+	recorder := httptest.NewRecorder()
+
+	stream := NewArrayStreamer(recorder, http.StatusOK)
+	require.NoError(t, stream.Encode(map[string]int{"n": 1}))
+	require.NoError(t, stream.Abort("INTERNAL_SERVER_ERROR", "listing interrupted"))
+
+	var response PaginatedResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+
+	require.NotNil(t, response.Error)
+	assert.Equal(t, "INTERNAL_SERVER_ERROR", response.Error.Code)
+	assert.Equal(t, "listing interrupted", response.Error.Message)
+	assert.Len(t, response.Data, 1)
+}
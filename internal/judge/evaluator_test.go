@@ -4,6 +4,7 @@ import (
 	"context"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/jonwraymond/prompt-alchemy/pkg/models"
 	"github.com/jonwraymond/prompt-alchemy/pkg/providers"
@@ -89,6 +90,10 @@ func (m *MockJudgeProvider) SupportsStreaming() bool {
 	return false
 }
 
+func (m *MockJudgeProvider) Ping(ctx context.Context) (time.Duration, error) {
+	return 0, nil
+}
+
 func TestNewLLMJudge(t *testing.T) {
 	provider := NewMockJudgeProvider()
 	judge := NewLLMJudge(provider, testModelName)
@@ -116,6 +116,51 @@ func (le *LearningEngine) RecordUsage(ctx context.Context, usage models.UsageAna
 	return nil
 }
 
+// RecordFeedback learns from an outcome a user reported for a prompt. The
+// prompt's success rate and satisfaction move toward the feedback's outcome,
+// and so does its stored relevance score, which orders the historical
+// prompts used for future generations.
+func (le *LearningEngine) RecordFeedback(ctx context.Context, feedback *models.PromptFeedback) error {
+	outcome := feedback.Outcome()
+	le.logger.WithFields(logrus.Fields{
+		"prompt_id": feedback.PromptID,
+		"signal":    feedback.Signal,
+		"outcome":   outcome,
+	}).Debug("Recording feedback for learning")
+
+	le.updateFeedbackMetrics(feedback.PromptID, outcome)
+
+	prompt, err := le.storage.GetPromptByID(ctx, feedback.PromptID)
+	if err != nil {
+		return fmt.Errorf("failed to get prompt for feedback: %w", err)
+	}
+	relevance := prompt.RelevanceScore + le.learningRate*(outcome-prompt.RelevanceScore)
+	if err := le.storage.UpdatePromptRelevanceScore(ctx, prompt.ID, relevance); err != nil {
+		return fmt.Errorf("failed to update relevance score from feedback: %w", err)
+	}
+	return nil
+}
+
+// updateFeedbackMetrics moves a prompt's success rate and satisfaction
+// toward a reported 0-1 outcome
+func (le *LearningEngine) updateFeedbackMetrics(promptID uuid.UUID, outcome float64) {
+	le.metrics.mutex.Lock()
+	defer le.metrics.mutex.Unlock()
+
+	metrics, exists := le.metrics.promptMetrics[promptID]
+	if !exists {
+		metrics = &PromptMetrics{
+			PromptID: promptID,
+		}
+		le.metrics.promptMetrics[promptID] = metrics
+	}
+
+	alpha := le.learningRate
+	metrics.SuccessRate = alpha*outcome + (1-alpha)*metrics.SuccessRate
+	metrics.UserSatisfaction = alpha*outcome + (1-alpha)*metrics.UserSatisfaction
+	metrics.LastAccessed = time.Now()
+}
+
 // updatePromptMetrics updates real-time metrics for a prompt
 func (le *LearningEngine) updatePromptMetrics(usage models.UsageAnalytics) {
 	le.metrics.mutex.Lock()
@@ -1,6 +1,7 @@
 package learning
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -70,6 +71,37 @@ func TestRecordUsage(t *testing.T) {
 	assert.Greater(t, metrics.UserSatisfaction, 0.0)
 }
 
+func TestRecordFeedback(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	store, err := storage.NewStorage(t.TempDir(), logger)
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	engine := NewLearningEngine(store, providers.NewRegistry(), logger)
+	ctx := context.Background()
+
+	prompt := &models.Prompt{Content: "rated", Phase: models.PhaseSolutio, Provider: "openai", Model: "gpt-4", RelevanceScore: 0.5}
+	require.NoError(t, store.SavePrompt(ctx, prompt))
+
+	require.NoError(t, engine.RecordFeedback(ctx, &models.PromptFeedback{PromptID: prompt.ID, Signal: models.FeedbackThumbsUp}))
+	metrics := engine.metrics.promptMetrics[prompt.ID]
+	require.NotNil(t, metrics)
+	assert.InDelta(t, 0.1, metrics.SuccessRate, 1e-9)
+	assert.InDelta(t, 0.1, metrics.UserSatisfaction, 1e-9)
+
+	stored, err := store.GetPromptByID(ctx, prompt.ID)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.55, stored.RelevanceScore, 1e-9, "relevance moves toward the positive outcome")
+
+	require.NoError(t, engine.RecordFeedback(ctx, &models.PromptFeedback{PromptID: prompt.ID, Signal: models.FeedbackThumbsDown}))
+	stored, err = store.GetPromptByID(ctx, prompt.ID)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.495, stored.RelevanceScore, 1e-9, "and back down after a thumbs down")
+
+	assert.Error(t, engine.RecordFeedback(ctx, &models.PromptFeedback{PromptID: uuid.New(), Signal: models.FeedbackThumbsUp}))
+}
+
 func TestDetectPattern(t *testing.T) {
 	store := &storage.Storage{}
 	registry := providers.NewRegistry()
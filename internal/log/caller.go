@@ -0,0 +1,38 @@
+package log
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SetIncludeCaller makes logger tag each entry with the file:line that
+// logged it, as log.include_caller asks. Call it after setting the
+// formatter; text, JSON and sanitizing formatters are supported.
+func SetIncludeCaller(logger *logrus.Logger, include bool) {
+	logger.SetReportCaller(include)
+	if !include {
+		return
+	}
+	setCallerPrettyfier(logger.Formatter)
+}
+
+func setCallerPrettyfier(formatter logrus.Formatter) {
+	switch f := formatter.(type) {
+	case *logrus.TextFormatter:
+		f.CallerPrettyfier = callerFileLine
+	case *logrus.JSONFormatter:
+		f.CallerPrettyfier = callerFileLine
+	case *SanitizingFormatter:
+		setCallerPrettyfier(f.underlying)
+	}
+}
+
+// callerFileLine reports a caller as its package directory, file and line,
+// e.g. engine/engine.go:212, without the function
+func callerFileLine(frame *runtime.Frame) (function string, file string) {
+	dir := filepath.Base(filepath.Dir(frame.File))
+	return "", fmt.Sprintf("%s/%s:%d", dir, filepath.Base(frame.File), frame.Line)
+}
@@ -2,10 +2,12 @@ package log
 
 import (
 	"bytes"
+	"context"
 	"strings"
 	"testing"
 
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func TestLoggerSanitization(t *testing.T) {
@@ -85,3 +87,59 @@ func TestGetLogger(t *testing.T) {
 		t.Error("GetLogger() returned nil")
 	}
 }
+
+func TestWithContext(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&buf)
+
+	WithContext(context.Background(), logger).Info("no request")
+	if strings.Contains(buf.String(), "request_id") {
+		t.Errorf("Expected no request_id without one in the context, got: %s", buf.String())
+	}
+
+	buf.Reset()
+	ctx := WithRequestID(context.Background(), "req-123")
+	if got := RequestIDFromContext(ctx); got != "req-123" {
+		t.Errorf("RequestIDFromContext() = %q, want %q", got, "req-123")
+	}
+	WithContext(ctx, logger).Info("with request")
+	if !strings.Contains(buf.String(), "request_id=req-123") {
+		t.Errorf("Expected output to contain the request ID, got: %s", buf.String())
+	}
+	if strings.Contains(buf.String(), "trace_id") {
+		t.Errorf("Expected no trace_id without a span in the context, got: %s", buf.String())
+	}
+
+	buf.Reset()
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	traced := trace.ContextWithSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID}))
+	WithContext(traced, logger).Info("with trace")
+	if !strings.Contains(buf.String(), "trace_id=4bf92f3577b34da6a3ce929d0e0e4736") {
+		t.Errorf("Expected output to contain the trace ID, got: %s", buf.String())
+	}
+}
+
+func TestSetIncludeCaller(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&buf)
+	logger.SetFormatter(&SanitizingFormatter{underlying: &logrus.TextFormatter{DisableTimestamp: true}})
+
+	SetIncludeCaller(logger, true)
+	WithContext(WithRequestID(context.Background(), "req-123"), logger).Info("with caller")
+	if !strings.Contains(buf.String(), `file="log/log_test.go:`) {
+		t.Errorf("Expected output to contain the caller's file and line, got: %s", buf.String())
+	}
+	if strings.Contains(buf.String(), "func=") {
+		t.Errorf("Expected no function name, got: %s", buf.String())
+	}
+
+	buf.Reset()
+	SetIncludeCaller(logger, false)
+	logger.Info("without caller")
+	if strings.Contains(buf.String(), "file=") {
+		t.Errorf("Expected no caller, got: %s", buf.String())
+	}
+}
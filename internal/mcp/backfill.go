@@ -0,0 +1,59 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jonwraymond/prompt-alchemy/internal/storage"
+	"github.com/jonwraymond/prompt-alchemy/pkg/providers"
+)
+
+// handleBackfillEmbeddings starts embedding the prompts saved without an
+// embedding, or reports the progress of the backfill already running
+func (s *Server) handleBackfillEmbeddings(ctx context.Context, id interface{}, args interface{}) {
+	argsMap, _ := args.(map[string]interface{})
+
+	if dryRun, _ := argsMap["dry_run"].(bool); dryRun {
+		missing, err := s.storage.CountPromptsWithoutEmbeddings(ctx)
+		if err != nil {
+			s.sendToolError(id, fmt.Sprintf("Failed to count prompts without embeddings: %v", err))
+			return
+		}
+		s.sendToolResult(id, ToolResult{
+			Content:  []Content{{Type: "text", Text: fmt.Sprintf("%d prompts are missing embeddings", missing)}},
+			Metadata: map[string]interface{}{"dry_run": true, "missing": missing},
+		})
+		return
+	}
+
+	opts := storage.LoadBackfillOptions()
+	opts.Embed = providers.NewQueryEmbedder(s.registry)
+	opts.Provider = providers.QueryEmbedderName(s.registry)
+	if batchSize, ok := argsMap["batch_size"].(float64); ok {
+		if batchSize < 1 {
+			s.sendToolError(id, "batch_size must be a positive integer")
+			return
+		}
+		opts.BatchSize = int(batchSize)
+	}
+
+	err := s.storage.StartBackfill(ctx, opts)
+	switch {
+	case errors.Is(err, storage.ErrBackfillRunning):
+		// Calling again while it runs is how clients poll for progress
+	case err != nil:
+		s.sendToolError(id, fmt.Sprintf("Failed to start embedding backfill: %v", err))
+		return
+	}
+
+	status := s.storage.BackfillStatus()
+	s.sendToolResult(id, ToolResult{
+		Content: []Content{{
+			Type: "text",
+			Text: fmt.Sprintf("Embedding backfill %s: %d of %d prompts embedded, %d skipped, %d failed",
+				status.State, status.Embedded, status.Total, status.Skipped, status.Failed),
+		}},
+		Metadata: map[string]interface{}{"backfill": status},
+	})
+}
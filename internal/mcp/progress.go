@@ -0,0 +1,133 @@
+package mcp
+
+import (
+	"fmt"
+	"sync"
+)
+
+// ProgressNotification is an MCP notifications/progress message
+type ProgressNotification struct {
+	JSONRPC string                     `json:"jsonrpc"`
+	Method  string                     `json:"method"`
+	Params  ProgressNotificationParams `json:"params"`
+}
+
+// ProgressNotificationParams reports progress as a percentage of Total
+type ProgressNotificationParams struct {
+	ProgressToken interface{} `json:"progressToken"`
+	Progress      float64     `json:"progress"`
+	Total         float64     `json:"total,omitempty"`
+	Message       string      `json:"message,omitempty"`
+}
+
+const progressTotal = 100
+
+// ProgressTracker sends progress notifications for in-flight tool calls.
+// MCP requires progress to increase with every notification, so reports
+// that don't move a token forward are dropped.
+type ProgressTracker struct {
+	mu     sync.Mutex
+	send   func(v interface{}) error
+	tokens map[interface{}]float64
+}
+
+// NewProgressTracker creates a progress tracker that writes notifications with send
+func NewProgressTracker(send func(v interface{}) error) *ProgressTracker {
+	return &ProgressTracker{
+		send:   send,
+		tokens: make(map[interface{}]float64),
+	}
+}
+
+// newProgressTracker creates a tracker that writes to the server's output
+// alongside its responses
+func (s *Server) newProgressTracker() *ProgressTracker {
+	return NewProgressTracker(s.writeMessage)
+}
+
+// Start begins progress tracking for a token
+func (pt *ProgressTracker) Start(token interface{}, title string) error {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+
+	pt.tokens[token] = 0
+
+	return pt.sendProgress(token, 0, title)
+}
+
+// Update reports the token's progress as a percentage
+func (pt *ProgressTracker) Update(token interface{}, message string, percentage float64) error {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+
+	last, ok := pt.tokens[token]
+	if !ok {
+		return fmt.Errorf("unknown progress token")
+	}
+	if percentage <= last {
+		return nil
+	}
+
+	pt.tokens[token] = percentage
+	return pt.sendProgress(token, percentage, message)
+}
+
+// End completes progress tracking for a token, reporting 100% unless an
+// earlier update already did
+func (pt *ProgressTracker) End(token interface{}, message string) error {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+
+	last, ok := pt.tokens[token]
+	if !ok {
+		return fmt.Errorf("unknown progress token")
+	}
+
+	delete(pt.tokens, token)
+	if last >= progressTotal {
+		return nil
+	}
+
+	return pt.sendProgress(token, progressTotal, message)
+}
+
+// sendProgress sends a progress notification
+func (pt *ProgressTracker) sendProgress(token interface{}, progress float64, message string) error {
+	notification := ProgressNotification{
+		JSONRPC: "2.0",
+		Method:  "notifications/progress",
+		Params: ProgressNotificationParams{
+			ProgressToken: token,
+			Progress:      progress,
+			Total:         progressTotal,
+			Message:       message,
+		},
+	}
+
+	return pt.send(notification)
+}
+
+// WithProgress wraps a function with progress tracking
+func (s *Server) WithProgress(token interface{}, title string, fn func(*ProgressTracker) error) error {
+	tracker := s.newProgressTracker()
+
+	// Start progress
+	if err := tracker.Start(token, title); err != nil {
+		s.logger.WithError(err).Warn("Failed to start progress")
+	}
+
+	// Execute function with progress tracking
+	err := fn(tracker)
+
+	// End progress
+	endMsg := "Completed"
+	if err != nil {
+		endMsg = fmt.Sprintf("Failed: %v", err)
+	}
+
+	if endErr := tracker.End(token, endMsg); endErr != nil {
+		s.logger.WithError(endErr).Warn("Failed to end progress")
+	}
+
+	return err
+}
@@ -0,0 +1,60 @@
+// Package mcp implements the Prompt Alchemy Model Context Protocol server,
+// which exposes prompt generation, search and optimization as tools over
+// newline-delimited JSON-RPC 2.0.
+package mcp
+
+// Request is a JSON-RPC 2.0 request from an MCP client
+type Request struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      interface{} `json:"id"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+// Response is a JSON-RPC 2.0 response to an MCP client
+type Response struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      interface{} `json:"id"`
+	Result  interface{} `json:"result,omitempty"`
+	Error   *Error      `json:"error,omitempty"`
+}
+
+// Error is a JSON-RPC 2.0 error object
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    string `json:"data,omitempty"`
+}
+
+// Tool describes a tool returned by tools/list
+type Tool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	InputSchema map[string]interface{} `json:"inputSchema"`
+}
+
+// ToolResult is the result of a tools/call request
+type ToolResult struct {
+	Content  []Content   `json:"content"`
+	IsError  bool        `json:"isError,omitempty"`
+	Metadata interface{} `json:"_meta,omitempty"`
+}
+
+// Content is a single content block in a tool result
+type Content struct {
+	Type     string      `json:"type"`
+	Text     string      `json:"text,omitempty"`
+	MimeType string      `json:"mimeType,omitempty"`
+	Data     interface{} `json:"data,omitempty"`
+}
+
+// JSON-RPC 2.0 error codes
+const (
+	codeParseError     = -32700
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+
+	// codeRequestCancelled answers a call interrupted by shutdown, as in
+	// the Language Server Protocol
+	codeRequestCancelled = -32800
+)
@@ -0,0 +1,357 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/jonwraymond/prompt-alchemy/internal/engine"
+	"github.com/jonwraymond/prompt-alchemy/internal/learning"
+	"github.com/jonwraymond/prompt-alchemy/internal/ranking"
+	"github.com/jonwraymond/prompt-alchemy/internal/storage"
+	"github.com/jonwraymond/prompt-alchemy/pkg/providers"
+	"github.com/sirupsen/logrus"
+)
+
+// Server serves Prompt Alchemy's tools over MCP. It is shared by every
+// binary that offers an MCP mode, so all of them expose the same tools and
+// accept the same phase names.
+type Server struct {
+	storage  *storage.Storage
+	registry *providers.Registry
+	engine   *engine.Engine
+	ranker   *ranking.Ranker
+	learner  *learning.LearningEngine
+	logger   *logrus.Logger
+	reader   *bufio.Reader
+	writer   *bufio.Writer
+	encoder  *json.Encoder
+
+	// writeMu serializes responses and progress notifications, which batch
+	// workers send concurrently, so messages never interleave on the wire
+	writeMu sync.Mutex
+}
+
+// NewServer creates an MCP server backed by the given components
+func NewServer(
+	store *storage.Storage,
+	registry *providers.Registry,
+	eng *engine.Engine,
+	ranker *ranking.Ranker,
+	learner *learning.LearningEngine,
+	logger *logrus.Logger,
+) *Server {
+	return &Server{
+		storage:  store,
+		registry: registry,
+		engine:   eng,
+		ranker:   ranker,
+		learner:  learner,
+		logger:   logger,
+	}
+}
+
+// Serve reads newline-delimited JSON-RPC requests from in and writes
+// responses to out until in is closed or ctx is cancelled. Tool calls run
+// under ctx, so cancelling it also interrupts the call in flight, which is
+// answered before Serve returns.
+func (s *Server) Serve(ctx context.Context, in io.Reader, out io.Writer) error {
+	s.reader = bufio.NewReader(in)
+	s.writer = bufio.NewWriter(out)
+	s.encoder = json.NewEncoder(s.writer)
+
+	s.logger.Info("MCP server listening for requests")
+
+	// Create a channel to signal when ReadString returns
+	lineChan := make(chan string)
+	errChan := make(chan error)
+
+	// The reader stays blocked on in after a shutdown, but must not also
+	// block on handing over a line nobody will read
+	go func() {
+		for {
+			line, err := s.reader.ReadString('\n')
+			if err != nil {
+				select {
+				case errChan <- err:
+				case <-ctx.Done():
+				}
+				return
+			}
+			select {
+			case lineChan <- line:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.logger.Info("MCP server shutting down due to context cancellation")
+			return nil
+		case line := <-lineChan:
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+
+			var req Request
+			if err := json.Unmarshal([]byte(line), &req); err != nil {
+				s.logger.WithError(err).Error("Failed to parse MCP request")
+				s.sendError(nil, codeParseError, "Parse error", "")
+				continue
+			}
+			s.handleRequest(ctx, &req)
+		case err := <-errChan:
+			if errors.Is(err, io.EOF) {
+				s.logger.Info("MCP server stdin closed, shutting down")
+				return nil
+			}
+			s.logger.WithError(err).Error("Failed to read from stdin")
+			return err
+		}
+	}
+}
+
+func (s *Server) handleRequest(ctx context.Context, req *Request) {
+	s.logger.WithFields(logrus.Fields{
+		"method": req.Method,
+		"id":     req.ID,
+	}).Debug("Handling MCP request")
+
+	switch req.Method {
+	case "initialize":
+		s.handleInitialize(req)
+	case "tools/list":
+		s.handleToolsList(req)
+	case "tools/call":
+		s.handleToolCall(ctx, req)
+	default:
+		s.sendError(req.ID, codeMethodNotFound, "Method not found", "")
+	}
+}
+
+func (s *Server) handleInitialize(req *Request) {
+	result := map[string]interface{}{
+		"protocolVersion": "2024-11-05",
+		"serverInfo": map[string]interface{}{
+			"name":    "prompt-alchemy",
+			"version": "1.0.0",
+		},
+		"capabilities": map[string]interface{}{
+			"tools": map[string]interface{}{},
+		},
+	}
+
+	s.sendResult(req.ID, result)
+}
+
+func (s *Server) handleToolCall(ctx context.Context, req *Request) {
+	if ctx.Err() != nil {
+		s.sendCancelled(req.ID)
+		return
+	}
+
+	// Parse tool call params
+	params, ok := req.Params.(map[string]interface{})
+	if !ok {
+		s.sendError(req.ID, codeInvalidParams, "Invalid params", "")
+		return
+	}
+
+	toolName, ok := params["name"].(string)
+	if !ok {
+		s.sendError(req.ID, codeInvalidParams, "Missing tool name", "")
+		return
+	}
+
+	arguments := params["arguments"]
+
+	// Route to appropriate tool handler
+	switch toolName {
+	case "generate_prompts":
+		s.handleGeneratePrompts(ctx, req.ID, arguments)
+	case "search_prompts":
+		s.handleSearchPrompts(ctx, req.ID, arguments)
+	case "get_prompt":
+		s.handleGetPrompt(ctx, req.ID, arguments)
+	case "list_providers":
+		s.handleListProviders(req.ID)
+	case "optimize_prompt":
+		s.handleOptimizePrompt(ctx, req.ID, arguments)
+	case "batch_generate":
+		s.handleBatchGenerate(ctx, req.ID, arguments)
+	case "backfill_embeddings":
+		s.handleBackfillEmbeddings(ctx, req.ID, arguments)
+	default:
+		s.sendError(req.ID, codeInvalidParams, "Unknown tool", toolName)
+	}
+}
+
+func (s *Server) sendResult(id interface{}, result interface{}) {
+	resp := Response{
+		JSONRPC: "2.0",
+		ID:      id,
+		Result:  result,
+	}
+	s.sendResponse(resp)
+}
+
+func (s *Server) sendError(id interface{}, code int, message string, data string) {
+	resp := Response{
+		JSONRPC: "2.0",
+		ID:      id,
+		Error: &Error{
+			Code:    code,
+			Message: message,
+			Data:    data,
+		},
+	}
+	s.sendResponse(resp)
+}
+
+// sendCancelled answers a call that shutdown interrupted before it had
+// anything to return
+func (s *Server) sendCancelled(id interface{}) {
+	s.sendError(id, codeRequestCancelled, "Request cancelled", "server shutting down")
+}
+
+func (s *Server) sendToolResult(id interface{}, result ToolResult) {
+	s.sendResult(id, result)
+}
+
+func (s *Server) sendToolError(id interface{}, message string) {
+	result := ToolResult{
+		Content: []Content{
+			{
+				Type: "text",
+				Text: fmt.Sprintf("Error: %s", message),
+			},
+		},
+		IsError: true,
+	}
+	s.sendResult(id, result)
+}
+
+func (s *Server) sendResponse(resp Response) {
+	if err := s.writeMessage(resp); err != nil {
+		s.logger.WithError(err).Error("Failed to write response")
+	}
+}
+
+// writeMessage encodes v as a single line of output and flushes it
+func (s *Server) writeMessage(v interface{}) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	if err := s.encoder.Encode(v); err != nil {
+		return err
+	}
+	return s.writer.Flush()
+}
+
+// Output formats accepted by a tool's output_format argument. Markdown is
+// only offered by tools that return prompts.
+const (
+	outputFormatText     = "text"
+	outputFormatJSON     = "json"
+	outputFormatMarkdown = "markdown"
+)
+
+// outputFormatProperty is the input schema of the output_format argument,
+// offering markdown when the tool renders it
+func outputFormatProperty(markdown bool) map[string]interface{} {
+	description := "Result format: 'text' for a readable summary, 'json' for a single application/json block holding the result's _meta"
+	formats := []string{outputFormatText, outputFormatJSON}
+	if markdown {
+		description += ", 'markdown' for a text/markdown document of the prompts by phase"
+		formats = append(formats, outputFormatMarkdown)
+	}
+	return map[string]interface{}{
+		"type":        "string",
+		"description": description,
+		"default":     outputFormatText,
+		"enum":        formats,
+	}
+}
+
+// outputFormat reads a tool's output_format argument, which defaults to
+// text. "console" is accepted as a synonym of text, as in the CLI, and
+// markdown only when the tool renders it.
+func outputFormat(args map[string]interface{}, markdown bool) (string, error) {
+	format, _ := args["output_format"].(string)
+	switch normalized := strings.ToLower(strings.TrimSpace(format)); {
+	case normalized == "" || normalized == outputFormatText || normalized == "console":
+		return outputFormatText, nil
+	case normalized == outputFormatJSON:
+		return outputFormatJSON, nil
+	case markdown && normalized == outputFormatMarkdown:
+		return outputFormatMarkdown, nil
+	case markdown:
+		return "", fmt.Errorf("unknown output_format %q (use text, json or markdown)", format)
+	default:
+		return "", fmt.Errorf("unknown output_format %q (use text or json)", format)
+	}
+}
+
+// templateVariables reads a tool's variables argument, an object of
+// placeholder names to string values
+func templateVariables(args map[string]interface{}) (map[string]string, error) {
+	raw, ok := args["variables"]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+	object, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("variables must be an object of names to string values")
+	}
+	variables := make(map[string]string, len(object))
+	for name, value := range object {
+		text, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("variable %q must be a string", name)
+		}
+		variables[name] = text
+	}
+	return variables, nil
+}
+
+// sendFormattedToolResult sends result as is in text format. In JSON format
+// its content is replaced by one block holding its metadata as JSON, so
+// clients can parse it without scraping text.
+func (s *Server) sendFormattedToolResult(id interface{}, format string, result ToolResult) {
+	if format == outputFormatJSON {
+		data, err := json.Marshal(result.Metadata)
+		if err != nil {
+			s.logger.WithError(err).Error("Failed to encode tool result")
+			s.sendToolError(id, fmt.Sprintf("Failed to encode result: %v", err))
+			return
+		}
+		result.Content = []Content{{Type: "text", MimeType: "application/json", Text: string(data)}}
+	}
+	s.sendToolResult(id, result)
+}
+
+func formatPrompts(prompts []map[string]interface{}) string {
+	var result strings.Builder
+	for i, p := range prompts {
+		result.WriteString(fmt.Sprintf("%d. [%s] %s\n", i+1, p["phase"], p["id"]))
+	}
+	return result.String()
+}
+
+func contains(slice []string, item string) bool {
+	for _, s := range slice {
+		if s == item {
+			return true
+		}
+	}
+	return false
+}
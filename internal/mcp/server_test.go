@@ -0,0 +1,534 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jonwraymond/prompt-alchemy/internal/engine"
+	"github.com/jonwraymond/prompt-alchemy/internal/storage"
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/jonwraymond/prompt-alchemy/pkg/providers"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serve runs the server over the given request lines and decodes each
+// response line
+func serve(t *testing.T, requests ...string) []Response {
+	t.Helper()
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	registry := providers.NewRegistry()
+	server := NewServer(nil, registry, engine.NewEngine(registry, logger), nil, nil, logger)
+
+	var responses []Response
+	for _, line := range serveLines(t, server, requests...) {
+		var resp Response
+		require.NoError(t, json.Unmarshal(line, &resp))
+		responses = append(responses, resp)
+	}
+	return responses
+}
+
+// serveLines runs server over the given request lines and returns every
+// output line, notifications included
+func serveLines(t *testing.T, server *Server, requests ...string) []json.RawMessage {
+	t.Helper()
+
+	var out bytes.Buffer
+	in := strings.NewReader(strings.Join(requests, "\n") + "\n")
+	require.NoError(t, server.Serve(context.Background(), in, &out))
+
+	var lines []json.RawMessage
+	decoder := json.NewDecoder(&out)
+	for decoder.More() {
+		var line json.RawMessage
+		require.NoError(t, decoder.Decode(&line))
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// stubProvider answers every generation with a fixed prompt
+type stubProvider struct{}
+
+func (stubProvider) Name() string             { return "stub" }
+func (stubProvider) IsAvailable() bool        { return true }
+func (stubProvider) SupportsEmbeddings() bool { return false }
+func (stubProvider) SupportsStreaming() bool  { return false }
+
+func (stubProvider) Generate(ctx context.Context, req providers.GenerateRequest) (*providers.GenerateResponse, error) {
+	return &providers.GenerateResponse{Content: "Refined prompt", TokensUsed: 10, Model: "stub-model"}, nil
+}
+
+func (stubProvider) GetEmbedding(ctx context.Context, text string, registry providers.RegistryInterface) ([]float32, error) {
+	return nil, errors.New("embeddings not supported")
+}
+
+func (stubProvider) Ping(ctx context.Context) (time.Duration, error) { return 0, nil }
+
+func TestServer_ToolsList(t *testing.T) {
+	responses := serve(t, `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)
+	require.Len(t, responses, 1)
+
+	data, err := json.Marshal(responses[0].Result)
+	require.NoError(t, err)
+	var result struct {
+		Tools []Tool `json:"tools"`
+	}
+	require.NoError(t, json.Unmarshal(data, &result))
+
+	names := make([]string, len(result.Tools))
+	for i, tool := range result.Tools {
+		names[i] = tool.Name
+		assert.NotEmpty(t, tool.Description, tool.Name)
+		assert.Equal(t, "object", tool.InputSchema["type"], tool.Name)
+	}
+
+	// Clients depend on these names; changing the set is a breaking change
+	assert.Equal(t, []string{
+		"generate_prompts",
+		"search_prompts",
+		"get_prompt",
+		"list_providers",
+		"optimize_prompt",
+		"batch_generate",
+		"backfill_embeddings",
+	}, names)
+}
+
+func TestServer_Protocol(t *testing.T) {
+	responses := serve(t,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize"}`,
+		`not json`,
+		`{"jsonrpc":"2.0","id":2,"method":"resources/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"no_such_tool"}}`,
+	)
+	require.Len(t, responses, 4)
+
+	assert.Equal(t, "2.0", responses[0].JSONRPC)
+	assert.EqualValues(t, 1, responses[0].ID)
+	assert.Nil(t, responses[0].Error)
+	assert.Equal(t, "2024-11-05", responses[0].Result.(map[string]interface{})["protocolVersion"])
+
+	require.NotNil(t, responses[1].Error)
+	assert.Equal(t, codeParseError, responses[1].Error.Code)
+
+	require.NotNil(t, responses[2].Error)
+	assert.Equal(t, codeMethodNotFound, responses[2].Error.Code)
+
+	require.NotNil(t, responses[3].Error)
+	assert.Equal(t, codeInvalidParams, responses[3].Error.Code)
+	assert.Equal(t, "no_such_tool", responses[3].Error.Data)
+}
+
+func TestServer_GenerateRejectsUnknownPhases(t *testing.T) {
+	responses := serve(t, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"generate_prompts","arguments":{"input":"x","phases":"brainstorm"}}}`)
+	require.Len(t, responses, 1)
+
+	result := responses[0].Result.(map[string]interface{})
+	assert.Equal(t, true, result["isError"])
+	assert.Contains(t, result["content"].([]interface{})[0].(map[string]interface{})["text"], `phases: unknown phases "brainstorm"`)
+}
+
+func TestServer_GenerateSendsProgressNotifications(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	registry := providers.NewRegistry()
+	require.NoError(t, registry.Register("stub", stubProvider{}))
+	server := NewServer(nil, registry, engine.NewEngine(registry, logger), nil, nil, logger)
+
+	for _, strategy := range []string{"best", "cascade", "all"} {
+		t.Run(strategy, func(t *testing.T) {
+			lines := serveLines(t, server, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"generate_prompts","arguments":{"input":"sort a list","count":1,"phase_selection":"`+strategy+`","progressToken":"gen-1"}}}`)
+			require.NotEmpty(t, lines)
+
+			var notifications []ProgressNotification
+			for _, line := range lines[:len(lines)-1] {
+				var n ProgressNotification
+				require.NoError(t, json.Unmarshal(line, &n))
+				notifications = append(notifications, n)
+			}
+
+			// A start notification, then one per phase; the response comes last
+			require.Len(t, notifications, 4)
+			last := -1.0
+			for _, n := range notifications {
+				assert.Equal(t, "notifications/progress", n.Method)
+				assert.Equal(t, "gen-1", n.Params.ProgressToken)
+				assert.Greater(t, n.Params.Progress, last)
+				last = n.Params.Progress
+			}
+			assert.Equal(t, "Completed coagulatio phase", notifications[3].Params.Message)
+			assert.EqualValues(t, 100, last)
+
+			var resp Response
+			require.NoError(t, json.Unmarshal(lines[len(lines)-1], &resp))
+			assert.EqualValues(t, 1, resp.ID)
+			assert.Nil(t, resp.Error)
+		})
+	}
+}
+
+// blockingProvider blocks every generation until its context is done
+type blockingProvider struct {
+	stubProvider
+	started chan struct{}
+}
+
+func (p blockingProvider) Generate(ctx context.Context, req providers.GenerateRequest) (*providers.GenerateResponse, error) {
+	select {
+	case p.started <- struct{}{}:
+	default:
+	}
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+// serveInBackground runs server over a pipe until ctx is cancelled, returning
+// the request writer and a channel that yields Serve's error
+func serveInBackground(ctx context.Context, server *Server, out io.Writer) (*io.PipeWriter, <-chan error) {
+	in, writer := io.Pipe()
+	done := make(chan error, 1)
+	go func() { done <- server.Serve(ctx, in, out) }()
+	return writer, done
+}
+
+func TestServer_ShutdownWhileIdle(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	server := NewServer(nil, providers.NewRegistry(), nil, nil, nil, logger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var out bytes.Buffer
+	requests, done := serveInBackground(ctx, server, &out)
+	defer func() { _ = requests.Close() }()
+
+	// stdin stays open, as it does under an MCP client
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Serve did not return after the context was cancelled")
+	}
+}
+
+func TestServer_ShutdownInterruptsToolCall(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	registry := providers.NewRegistry()
+	provider := blockingProvider{started: make(chan struct{}, 1)}
+	require.NoError(t, registry.Register("stub", provider))
+	server := NewServer(nil, registry, engine.NewEngine(registry, logger), nil, nil, logger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var out bytes.Buffer
+	requests, done := serveInBackground(ctx, server, &out)
+	defer func() { _ = requests.Close() }()
+
+	go func() {
+		_, _ = io.WriteString(requests, `{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{"name":"generate_prompts","arguments":{"input":"sort a list","count":1,"phase_selection":"all"}}}`+"\n")
+	}()
+	select {
+	case <-provider.started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the tool call never reached the provider")
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Serve did not return after the context was cancelled")
+	}
+
+	// The interrupted call is still answered
+	var resp Response
+	require.NoError(t, json.Unmarshal(out.Bytes(), &resp))
+	assert.EqualValues(t, 7, resp.ID)
+	require.NotNil(t, resp.Error)
+	assert.Equal(t, codeRequestCancelled, resp.Error.Code)
+}
+
+// embedProvider embeds every query along the first axis, so a prompt's
+// similarity is set by its stored embedding
+type embedProvider struct {
+	stubProvider
+}
+
+func (embedProvider) Name() string             { return "embed" }
+func (embedProvider) SupportsEmbeddings() bool { return true }
+
+func (embedProvider) GetEmbedding(ctx context.Context, text string, registry providers.RegistryInterface) ([]float32, error) {
+	return []float32{1, 0, 0}, nil
+}
+
+func TestServer_SearchPromptsSemantic(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	store, err := storage.NewStorage(t.TempDir(), logger)
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	registry := providers.NewRegistry()
+	require.NoError(t, registry.Register("embed", embedProvider{}))
+	server := NewServer(store, registry, nil, nil, nil, logger)
+
+	ctx := context.Background()
+	for _, p := range []*models.Prompt{
+		{Content: "far", Phase: models.PhaseSolutio, Embedding: []float32{0, 1, 0}},
+		{Content: "close", Phase: models.PhaseSolutio, Embedding: []float32{0.9, 0.3, 0}},
+		{Content: "exact", Phase: models.PhaseCoagulatio, Embedding: []float32{1, 0, 0}},
+		{Content: "related", Phase: models.PhaseSolutio, Embedding: []float32{0.6, 0.8, 0}},
+	} {
+		p.Provider, p.EmbeddingProvider, p.EmbeddingModel = "openai", "embed", "embed-model"
+		require.NoError(t, store.SavePrompt(ctx, p))
+	}
+
+	search := func(arguments string) map[string]interface{} {
+		lines := serveLines(t, server, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"search_prompts","arguments":`+arguments+`}}`)
+		require.Len(t, lines, 1)
+		var resp Response
+		require.NoError(t, json.Unmarshal(lines[0], &resp))
+		require.Nil(t, resp.Error)
+		return resp.Result.(map[string]interface{})
+	}
+	contents := func(result map[string]interface{}) ([]string, []float64) {
+		meta := result["_meta"].(map[string]interface{})
+		var names []string
+		var scores []float64
+		for _, p := range meta["prompts"].([]interface{}) {
+			prompt := p.(map[string]interface{})
+			names = append(names, prompt["content"].(string))
+			scores = append(scores, prompt["similarity"].(float64))
+		}
+		return names, scores
+	}
+
+	t.Run("ordered by similarity within the limit", func(t *testing.T) {
+		result := search(`{"query":"sort a list","semantic":true,"similarity":0.1,"limit":3}`)
+		require.Nil(t, result["isError"])
+		names, scores := contents(result)
+		assert.Equal(t, []string{"exact", "close", "related"}, names)
+		require.Len(t, scores, 3)
+		for i := 1; i < len(scores); i++ {
+			assert.GreaterOrEqual(t, scores[i-1], scores[i])
+		}
+		assert.InDelta(t, 1.0, scores[0], 0.001)
+		assert.Equal(t, "semantic", result["_meta"].(map[string]interface{})["search_type"])
+	})
+
+	t.Run("filters and threshold", func(t *testing.T) {
+		names, _ := contents(search(`{"query":"sort a list","semantic":true,"phase":"solutio"}`))
+		assert.Equal(t, []string{"close", "related"}, names, "the default threshold drops far")
+	})
+
+	t.Run("no embedding provider", func(t *testing.T) {
+		responses := serve(t, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"search_prompts","arguments":{"query":"x","semantic":true}}}`)
+		require.Len(t, responses, 1)
+		result := responses[0].Result.(map[string]interface{})
+		assert.Equal(t, true, result["isError"])
+		assert.Contains(t, result["content"].([]interface{})[0].(map[string]interface{})["text"], "embedding-capable provider")
+	})
+}
+
+func TestServer_SearchPromptsPaging(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	store, err := storage.NewStorage(t.TempDir(), logger)
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	server := NewServer(store, providers.NewRegistry(), nil, nil, nil, logger)
+
+	// Prompts share timestamps so paging has to order by id as well;
+	// since drops the first day's three
+	ctx := context.Background()
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	wanted := map[string]bool{}
+	for i := 0; i < 10; i++ {
+		p := &models.Prompt{Content: fmt.Sprintf("prompt %d", i), Phase: models.PhaseSolutio, Provider: "openai", CreatedAt: base.AddDate(0, 0, i/3)}
+		if i%4 == 0 {
+			p.Provider = "anthropic"
+		}
+		require.NoError(t, store.SavePrompt(ctx, p))
+		if p.Provider == "openai" && i >= 3 {
+			wanted[p.Content] = true
+		}
+	}
+
+	search := func(arguments string) map[string]interface{} {
+		lines := serveLines(t, server, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"search_prompts","arguments":`+arguments+`}}`)
+		require.Len(t, lines, 1)
+		var resp Response
+		require.NoError(t, json.Unmarshal(lines[0], &resp))
+		require.Nil(t, resp.Error)
+		return resp.Result.(map[string]interface{})
+	}
+
+	seen := map[string]bool{}
+	cursor := ""
+	for page := 0; page < 10; page++ {
+		arguments := `{"query":"prompt","provider":"openai","since":"2024-01-02","limit":2`
+		if cursor != "" {
+			arguments += `,"cursor":"` + cursor + `"`
+		}
+		result := search(arguments + `}`)
+		require.Nil(t, result["isError"], result)
+		meta := result["_meta"].(map[string]interface{})
+		for _, p := range meta["prompts"].([]interface{}) {
+			content := p.(map[string]interface{})["content"].(string)
+			assert.False(t, seen[content], "%s returned twice", content)
+			seen[content] = true
+		}
+		if meta["has_more"] != true {
+			assert.Nil(t, meta["next_cursor"])
+			break
+		}
+		cursor = meta["next_cursor"].(string)
+	}
+	assert.Equal(t, wanted, seen)
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		result := search(`{"query":"prompt","cursor":"not-a-cursor"}`)
+		assert.Equal(t, true, result["isError"])
+	})
+}
+
+func TestServer_JSONOutputFormat(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	registry := providers.NewRegistry()
+	require.NoError(t, registry.Register("stub", stubProvider{}))
+	server := NewServer(nil, registry, engine.NewEngine(registry, logger), nil, nil, logger)
+
+	call := func(arguments string) ToolResult {
+		lines := serveLines(t, server, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"generate_prompts","arguments":`+arguments+`}}`)
+		require.Len(t, lines, 1)
+		var resp struct {
+			Result ToolResult `json:"result"`
+		}
+		require.NoError(t, json.Unmarshal(lines[0], &resp))
+		return resp.Result
+	}
+
+	t.Run("json block round-trips", func(t *testing.T) {
+		result := call(`{"input":"sort a list","count":1,"phase_selection":"all","output_format":"json"}`)
+		require.False(t, result.IsError)
+		require.Len(t, result.Content, 1)
+		assert.Equal(t, "application/json", result.Content[0].MimeType)
+
+		var decoded struct {
+			Prompts []struct {
+				ID    string  `json:"id"`
+				Phase string  `json:"phase"`
+				Score float64 `json:"score"`
+			} `json:"prompts"`
+			Count int `json:"count"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(result.Content[0].Text), &decoded))
+		require.Equal(t, 3, decoded.Count)
+		require.Len(t, decoded.Prompts, 3)
+		for _, p := range decoded.Prompts {
+			_, err := uuid.Parse(p.ID)
+			assert.NoError(t, err)
+			assert.NotEmpty(t, p.Phase)
+		}
+		assert.NotNil(t, result.Metadata, "_meta is kept in json mode")
+	})
+
+	t.Run("text by default", func(t *testing.T) {
+		result := call(`{"input":"sort a list","count":1,"phase_selection":"all"}`)
+		require.Len(t, result.Content, 1)
+		assert.Empty(t, result.Content[0].MimeType)
+		assert.Contains(t, result.Content[0].Text, "Generated 3 prompts total")
+	})
+
+	t.Run("markdown document", func(t *testing.T) {
+		result := call(`{"input":"sort a list","count":1,"phase_selection":"all","output_format":"markdown"}`)
+		require.False(t, result.IsError)
+		require.Len(t, result.Content, 1)
+		assert.Equal(t, "text/markdown", result.Content[0].MimeType)
+		assert.True(t, strings.HasPrefix(result.Content[0].Text, "# Generated Prompts\n"))
+		assert.Contains(t, result.Content[0].Text, "## Prima Materia")
+		assert.Contains(t, result.Content[0].Text, "*Provider: stub")
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		result := call(`{"input":"sort a list","output_format":"yaml"}`)
+		assert.True(t, result.IsError)
+		assert.Contains(t, result.Content[0].Text, "unknown output_format")
+	})
+}
+
+// recordingProvider records the prompts it is asked to generate from
+type recordingProvider struct {
+	stubProvider
+	prompts *[]string
+}
+
+func (p recordingProvider) Generate(ctx context.Context, req providers.GenerateRequest) (*providers.GenerateResponse, error) {
+	*p.prompts = append(*p.prompts, req.Prompt)
+	return p.stubProvider.Generate(ctx, req)
+}
+
+func TestServer_GenerateVariables(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	var prompts []string
+	registry := providers.NewRegistry()
+	require.NoError(t, registry.Register("stub", recordingProvider{prompts: &prompts}))
+	server := NewServer(nil, registry, engine.NewEngine(registry, logger), nil, nil, logger)
+
+	call := func(arguments string) ToolResult {
+		prompts = nil
+		lines := serveLines(t, server, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"generate_prompts","arguments":`+arguments+`}}`)
+		require.Len(t, lines, 1)
+		var resp struct {
+			Result ToolResult `json:"result"`
+		}
+		require.NoError(t, json.Unmarshal(lines[0], &resp))
+		return resp.Result
+	}
+
+	t.Run("substituted before generation", func(t *testing.T) {
+		result := call(`{"input":"sort a {{language}} slice, not \\{{language}}","variables":{"language":"Go"},"count":1,"phases":"prima-materia"}`)
+		require.False(t, result.IsError, result.Content[0].Text)
+		require.Len(t, prompts, 1)
+		assert.Contains(t, prompts[0], "sort a Go slice, not {{language}}")
+	})
+
+	t.Run("missing variable", func(t *testing.T) {
+		result := call(`{"input":"sort a {{language}} {{collection}}","variables":{"language":"Go"},"count":1}`)
+		assert.True(t, result.IsError)
+		assert.Contains(t, result.Content[0].Text, "unresolved template variables: collection")
+		assert.Empty(t, prompts)
+	})
+
+	t.Run("missing variable allowed", func(t *testing.T) {
+		result := call(`{"input":"sort a {{language}} {{collection}}","variables":{"language":"Go"},"allow_missing":true,"count":1,"phases":"prima-materia"}`)
+		require.False(t, result.IsError)
+		require.Len(t, prompts, 1)
+		assert.Contains(t, prompts[0], "sort a Go {{collection}}")
+	})
+
+	t.Run("non-string value", func(t *testing.T) {
+		result := call(`{"input":"sort a {{language}} slice","variables":{"language":3}}`)
+		assert.True(t, result.IsError)
+		assert.Contains(t, result.Content[0].Text, `variable "language" must be a string`)
+	})
+}
@@ -0,0 +1,1281 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jonwraymond/prompt-alchemy/internal/engine"
+	"github.com/jonwraymond/prompt-alchemy/internal/helpers"
+	"github.com/jonwraymond/prompt-alchemy/internal/optimizer"
+	"github.com/jonwraymond/prompt-alchemy/internal/storage"
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/jonwraymond/prompt-alchemy/pkg/providers"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// batchInput is a single entry of the batch_generate tool's inputs
+type batchInput struct {
+	ID      string
+	Input   string
+	Phases  string
+	Count   int
+	Persona string
+}
+
+// toolDefinitions returns every tool the server offers, in tools/list order
+func toolDefinitions() []Tool {
+	return []Tool{
+		{
+			Name:        "generate_prompts",
+			Description: "Generate refined AI prompts through a systematic three-phase alchemical process. Use this when you need to create new prompts from raw ideas or improve existing concepts. The tool transforms vague ideas into precise, effective prompts optimized for AI models. Supports different strategies: 'best' selects top prompts from each phase, 'cascade' progressively refines through phases, 'all' returns everything. Ideal for creating prompts for coding, writing, analysis, or any AI task.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"input": map[string]interface{}{
+						"type":        "string",
+						"description": "Input text or idea",
+					},
+					"phases": map[string]interface{}{
+						"type":        "string",
+						"description": "Comma-separated phases",
+						"default":     "prima-materia,solutio,coagulatio",
+					},
+					"count": map[string]interface{}{
+						"type":        "integer",
+						"description": "Number of variants per phase",
+						"default":     3,
+					},
+					"persona": map[string]interface{}{
+						"type":        "string",
+						"description": "AI persona (code, writing, analysis, generic)",
+						"default":     "code",
+					},
+					"temperature": map[string]interface{}{
+						"type":        "number",
+						"description": "Temperature for generation (0.0-1.0)",
+						"default":     0.7,
+					},
+					"max_tokens": map[string]interface{}{
+						"type":        "integer",
+						"description": "Maximum tokens in response",
+						"default":     2000,
+					},
+					"seed": map[string]interface{}{
+						"type":        "integer",
+						"description": "Seed for deterministic sampling, forwarded to providers that support it",
+					},
+					"optimize": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Apply optimization after generation",
+						"default":     false,
+					},
+					"phase_selection": map[string]interface{}{
+						"type":        "string",
+						"description": "Selection strategy: 'best' (best from each phase), 'cascade' (use best as input to next), 'all' (return all)",
+						"default":     "best",
+						"enum":        []string{"best", "cascade", "all"},
+					},
+					"variables": map[string]interface{}{
+						"type":                 "object",
+						"description":          "Values for {{name}} placeholders in the input; write \\{{name}} for a literal placeholder",
+						"additionalProperties": map[string]interface{}{"type": "string"},
+					},
+					"allow_missing": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Leave placeholders without a value in place instead of failing",
+						"default":     false,
+					},
+					"output_format": outputFormatProperty(true),
+				},
+				"required": []string{"input"},
+			},
+		},
+		{
+			Name:        "search_prompts",
+			Description: "Search through your stored prompt library to find previously generated or optimized prompts. Use this to avoid regenerating similar prompts and to learn from past successful patterns. Text search matches the query as a substring; semantic search ranks prompts by embedding similarity and returns each prompt's similarity score, most similar first. Both can be narrowed by phase, provider, tags and creation date. Returns prompts with metadata including phases used and tags, and has_more when more results match; text search results, newest first, can be paged by passing back next_cursor. Useful for finding inspiration or reusing effective prompts for similar tasks.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"query": map[string]interface{}{
+						"type":        "string",
+						"description": "Search query; required for semantic search",
+					},
+					"limit": map[string]interface{}{
+						"type":        "integer",
+						"description": "Max results (1-100)",
+						"default":     10,
+					},
+					"semantic": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Rank by embedding similarity; requires an embedding-capable provider",
+						"default":     false,
+					},
+					"similarity": map[string]interface{}{
+						"type":        "number",
+						"description": "Minimum cosine similarity for semantic results (0-1)",
+						"default":     0.5,
+					},
+					"phase": map[string]interface{}{
+						"type":        "string",
+						"description": "Only prompts from this phase",
+					},
+					"provider": map[string]interface{}{
+						"type":        "string",
+						"description": "Only prompts from this provider",
+					},
+					"tags": map[string]interface{}{
+						"type":        "string",
+						"description": "Comma-separated tags; prompts with any of them match",
+					},
+					"since": map[string]interface{}{
+						"type":        "string",
+						"description": "Only prompts created on or after this date (YYYY-MM-DD)",
+					},
+					"cursor": map[string]interface{}{
+						"type":        "string",
+						"description": "next_cursor from the previous page of a text search, to fetch the next page with the same filters",
+					},
+					"output_format": outputFormatProperty(false),
+				},
+			},
+		},
+		{
+			Name:        "get_prompt",
+			Description: "Retrieve the complete details of a specific prompt using its unique ID. Use this when you have a prompt ID from search results or previous generations and need to access its full content, metadata, and generation history. Returns comprehensive information including the prompt text, phase details, provider used, generation parameters, and performance metrics. Essential for examining successful prompts in detail or sharing specific prompts.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"id": map[string]interface{}{
+						"type":        "string",
+						"description": "Prompt ID (UUID)",
+					},
+				},
+				"required": []string{"id"},
+			},
+		},
+		{
+			Name:        "list_providers",
+			Description: "List all configured and available AI providers (OpenAI, Anthropic, Google, Grok, OpenRouter, Ollama). Use this to check which providers are properly configured with valid API keys, their supported models, and current status. Helps in troubleshooting connection issues and choosing the best provider for specific tasks. Shows provider capabilities, rate limits, and whether they support embeddings. Essential for understanding your available AI resources before generating prompts.",
+			InputSchema: map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{},
+			},
+		},
+		{
+			Name:        "optimize_prompt",
+			Description: "Optimize an existing prompt using advanced AI-powered meta-prompting techniques. Use this to iteratively improve prompt quality through multiple refinement cycles until reaching a target quality score. The AI judge evaluates prompts based on clarity, specificity, and effectiveness for the intended task. Supports targeting specific models and personas. Ideal for critical prompts that need maximum effectiveness. Can transform mediocre prompts into highly effective ones through systematic improvement.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"prompt": map[string]interface{}{
+						"type":        "string",
+						"description": "Prompt to optimize",
+					},
+					"task": map[string]interface{}{
+						"type":        "string",
+						"description": "Task description for the prompt",
+						"default":     "",
+					},
+					"persona": map[string]interface{}{
+						"type":        "string",
+						"description": "AI persona (code, writing, analysis, generic)",
+						"default":     "code",
+					},
+					"target_model": map[string]interface{}{
+						"type":        "string",
+						"description": "Target model for optimization",
+						"default":     "",
+					},
+					"max_iterations": map[string]interface{}{
+						"type":        "integer",
+						"description": "Maximum optimization iterations",
+						"default":     5,
+					},
+					"target_score": map[string]interface{}{
+						"type":        "number",
+						"description": "Target quality score (1-10)",
+						"default":     8.5,
+					},
+					"accept_first_passing": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Stop and return the first prompt that meets target_score, including the original, to save time and tokens",
+						"default":     false,
+					},
+				},
+				"required": []string{"prompt"},
+			},
+		},
+		{
+			Name:        "batch_generate",
+			Description: "Generate multiple prompts efficiently in parallel batch processing mode. Use this when you need to create prompts for multiple related tasks or variations of a concept. Processes inputs concurrently using worker pools for optimal performance. Each input can have its own configuration (phases, count, persona). Returns organized results with success/error tracking. Perfect for generating prompt sets for testing, creating variations for A/B testing, or processing lists of ideas. Supports progress tracking for long operations.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"inputs": map[string]interface{}{
+						"type":        "array",
+						"description": "Array of prompt inputs",
+						"items": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"id": map[string]interface{}{
+									"type":        "string",
+									"description": "Unique ID for this input",
+								},
+								"input": map[string]interface{}{
+									"type":        "string",
+									"description": "Input text or idea",
+								},
+								"phases": map[string]interface{}{
+									"type":        "string",
+									"description": "Comma-separated phases",
+									"default":     "prima-materia,solutio,coagulatio",
+								},
+								"count": map[string]interface{}{
+									"type":        "integer",
+									"description": "Number of variants",
+									"default":     3,
+								},
+								"persona": map[string]interface{}{
+									"type":        "string",
+									"description": "AI persona",
+									"default":     "code",
+								},
+							},
+							"required": []string{"input"},
+						},
+					},
+					"workers": map[string]interface{}{
+						"type":        "integer",
+						"description": "Number of concurrent workers",
+						"default":     3,
+					},
+				},
+				"required": []string{"inputs"},
+			},
+		},
+		{
+			Name:        "backfill_embeddings",
+			Description: "Generate embeddings for saved prompts that are missing them, so semantic search and similarity features cover every prompt. Runs in the background in rate-limited batches; call it again to see progress. Only missing embeddings are generated, so it is safe to re-run and resumes an interrupted backfill. Use dry_run to just count the prompts that would be embedded.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"dry_run": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Only count the prompts missing embeddings",
+						"default":     false,
+					},
+					"batch_size": map[string]interface{}{
+						"type":        "integer",
+						"description": "Prompts embedded per batch (default embeddings.backfill_batch_size)",
+					},
+				},
+			},
+		},
+	}
+}
+
+func (s *Server) handleToolsList(req *Request) {
+	result := map[string]interface{}{
+		"tools": toolDefinitions(),
+	}
+
+	s.sendResult(req.ID, result)
+}
+
+func (s *Server) handleGeneratePrompts(ctx context.Context, id interface{}, args interface{}) {
+	// Parse arguments
+	argsMap, ok := args.(map[string]interface{})
+	if !ok {
+		s.sendToolError(id, "Invalid arguments")
+		return
+	}
+
+	input, _ := argsMap["input"].(string)
+
+	// Parse optional parameters
+	phases := "prima-materia,solutio,coagulatio"
+	if p, ok := argsMap["phases"].(string); ok {
+		phases = p
+	}
+
+	count := 3
+	if c, ok := argsMap["count"].(float64); ok {
+		count = int(c)
+	}
+
+	persona := "code"
+	if p, ok := argsMap["persona"].(string); ok {
+		persona = p
+	}
+
+	temperature := 0.7
+	if t, ok := argsMap["temperature"].(float64); ok {
+		temperature = t
+	}
+
+	maxTokens := 2000
+	if mt, ok := argsMap["max_tokens"].(float64); ok {
+		maxTokens = int(mt)
+	}
+
+	optimize := false
+	if o, ok := argsMap["optimize"].(bool); ok {
+		optimize = o
+	}
+
+	phaseSelection := "best"
+	if ps, ok := argsMap["phase_selection"].(string); ok {
+		phaseSelection = ps
+	}
+
+	// Legacy phase names (idea, human, precision) map to their alchemical names
+	modelPhases := helpers.ParsePhases(phases)
+	if len(modelPhases) == 0 {
+		s.sendToolError(id, fmt.Sprintf("No valid phases in %q; use prima-materia, solutio and/or coagulatio", phases))
+		return
+	}
+	phaseNames := make([]string, len(modelPhases))
+	for i, phase := range modelPhases {
+		phaseNames[i] = string(phase)
+	}
+	var knownPhase func(models.Phase) bool
+	if s.engine != nil {
+		knownPhase = s.engine.KnownPhase
+	}
+	if err := models.ValidateGenerateRequest(models.GenerateRequest{
+		Input:          input,
+		Phases:         phaseNames,
+		Count:          count,
+		Temperature:    temperature,
+		MaxTokens:      maxTokens,
+		Persona:        persona,
+		PhaseSelection: phaseSelection,
+	}, knownPhase); err != nil {
+		s.sendToolError(id, err.Error())
+		return
+	}
+
+	format, err := outputFormat(argsMap, true)
+	if err != nil {
+		s.sendToolError(id, err.Error())
+		return
+	}
+
+	// Fill in template variables up front, so cascaded phase outputs and
+	// historical insights are never treated as templates
+	variables, err := templateVariables(argsMap)
+	if err != nil {
+		s.sendToolError(id, err.Error())
+		return
+	}
+	if len(variables) > 0 {
+		allowMissing, _ := argsMap["allow_missing"].(bool)
+		input, err = models.SubstituteVariables(input, variables, allowMissing)
+		if err != nil {
+			s.sendToolError(id, err.Error())
+			return
+		}
+	}
+
+	// Extract progress token if provided
+	var progressToken interface{}
+	if pt, ok := argsMap["progressToken"]; ok {
+		progressToken = pt
+	}
+
+	// Enhanced logging for debugging
+	s.logger.WithFields(logrus.Fields{
+		"input":           input,
+		"phases":          phases,
+		"count":           count,
+		"persona":         persona,
+		"temperature":     temperature,
+		"optimize":        optimize,
+		"phase_selection": phaseSelection,
+	}).Info("MCP: Starting prompt generation")
+
+	s.logger.WithField("phases", modelPhases).Debug("Parsed phases from request")
+
+	// Apply self-learning enhancement if available
+	enhancedInput := input
+	if s.storage != nil && len(modelPhases) > 0 {
+		// Get embedding provider
+		available := s.registry.ListAvailable()
+		var embedder providers.Provider
+		for _, providerName := range available {
+			p, err := s.registry.Get(providerName)
+			if err == nil && p.SupportsEmbeddings() {
+				embedder = p
+				break
+			}
+		}
+
+		if embedder != nil {
+			enhancer := engine.NewHistoryEnhancer(s.storage, embedder)
+			enhancedContext, err := enhancer.EnhanceWithHistory(ctx, input, modelPhases[0])
+			if err == nil && enhancedContext != nil {
+				// Format enhanced input with insights
+				var insights []string
+				if len(enhancedContext.SimilarPrompts) > 0 {
+					insights = append(insights, fmt.Sprintf("Found %d similar historical prompts", len(enhancedContext.SimilarPrompts)))
+				}
+				if len(enhancedContext.ExtractedPatterns) > 0 {
+					insights = append(insights, "Patterns: "+strings.Join(enhancedContext.ExtractedPatterns[:min(3, len(enhancedContext.ExtractedPatterns))], ", "))
+				}
+				if enhancedContext.HistoricalInsights != "" {
+					insights = append(insights, enhancedContext.HistoricalInsights)
+				}
+
+				if len(insights) > 0 {
+					enhancedInput = fmt.Sprintf("%s\n\n[Enhanced with historical insights: %s]", input, strings.Join(insights, "; "))
+					s.logger.WithField("enhanced", true).Info("MCP: Input enhanced with historical data")
+				}
+			}
+		}
+	}
+
+	// Create request
+	promptReq := models.PromptRequest{
+		Input:       enhancedInput,
+		Phases:      modelPhases,
+		Count:       count,
+		Temperature: temperature,
+		MaxTokens:   maxTokens,
+		SessionID:   uuid.New(),
+	}
+	if seed, ok := argsMap["seed"].(float64); ok {
+		value := int(seed)
+		promptReq.Seed = &value
+	}
+
+	// Build phase configs - for MCP, use openai as default provider for all phases
+	// This is a simplification for the MCP server
+	phaseConfigs := make([]models.PhaseConfig, len(modelPhases))
+	defaultProvider := "openai"
+
+	// Log available providers
+	available := s.registry.ListAvailable()
+	s.logger.WithField("available_providers", available).Debug("Available providers in registry")
+
+	// Check if openai is available, otherwise use first available provider
+	if !contains(available, defaultProvider) && len(available) > 0 {
+		defaultProvider = available[0]
+		s.logger.WithField("fallback_provider", defaultProvider).Info("OpenAI not available, using fallback provider")
+	}
+
+	for i, phase := range modelPhases {
+		phaseConfigs[i] = models.PhaseConfig{
+			Phase:    phase,
+			Provider: defaultProvider,
+		}
+		s.logger.WithFields(logrus.Fields{
+			"index":    i,
+			"phase":    string(phase),
+			"provider": defaultProvider,
+		}).Debug("Phase provider configuration")
+	}
+
+	// Generate prompts
+	opts := models.GenerateOptions{
+		Request:        promptReq,
+		PhaseConfigs:   phaseConfigs,
+		UseParallel:    false,
+		IncludeContext: true,
+		Persona:        persona,
+		Optimize:       optimize,
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"phases":       modelPhases,
+		"phaseConfigs": phaseConfigs,
+	}).Debug("Calling engine.Generate")
+
+	// Apply phase selection strategy
+	var finalPrompts []models.Prompt
+	var allPrompts []models.Prompt
+
+	// Report progress after each phase when the client sent a token
+	var tracker *ProgressTracker
+	if progressToken != nil {
+		tracker = s.newProgressTracker()
+		title := "Generating all prompts"
+		switch phaseSelection {
+		case "best":
+			title = "Generating prompts"
+		case "cascade":
+			title = "Cascade prompt generation"
+		}
+		if err := tracker.Start(progressToken, title); err != nil {
+			s.logger.WithError(err).Warn("Failed to start progress")
+		}
+	}
+	completedPhases := 0
+	reportPhase := func(phase models.Phase) {
+		completedPhases++
+		if tracker == nil {
+			return
+		}
+		percentage := float64(completedPhases) / float64(len(modelPhases)) * 100
+		if err := tracker.Update(progressToken, fmt.Sprintf("Completed %s phase", phase), percentage); err != nil {
+			s.logger.WithError(err).Warn("Failed to send progress")
+		}
+	}
+
+	generateFunc := func() error {
+		opts.PhaseSelection = phaseSelection
+		opts.OnPhaseComplete = func(phase models.Phase, _ []models.Prompt) {
+			reportPhase(phase)
+		}
+
+		result, err := s.engine.Generate(ctx, opts)
+		if err != nil {
+			return err
+		}
+
+		s.logger.WithField("count", len(result.Prompts)).Info("MCP: Generated prompts")
+		finalPrompts = result.Prompts
+		allPrompts = result.Prompts
+		if len(result.Candidates) > 0 {
+			allPrompts = result.Candidates
+		}
+		return nil
+	}
+
+	// Execute generation with error handling. Shutdown interrupts it; the
+	// phases finished by then are still returned.
+	err = generateFunc()
+	interrupted := ctx.Err() != nil
+	if interrupted {
+		s.logger.WithField("phases_completed", completedPhases).Warn("MCP: Generation interrupted by shutdown")
+	}
+	if tracker != nil {
+		endMsg := fmt.Sprintf("Generated %d prompts", len(finalPrompts))
+		if err != nil {
+			endMsg = fmt.Sprintf("Failed: %v", err)
+		}
+		if endErr := tracker.End(progressToken, endMsg); endErr != nil {
+			s.logger.WithError(endErr).Warn("Failed to end progress")
+		}
+	}
+	if interrupted && len(finalPrompts) == 0 {
+		s.sendCancelled(id)
+		return
+	}
+	if err != nil {
+		s.sendToolError(id, fmt.Sprintf("Generation failed: %v", err))
+		return
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"total_generated": len(allPrompts),
+		"final_prompts":   len(finalPrompts),
+		"strategy":        phaseSelection,
+	}).Info("MCP: Generation complete")
+
+	// Format response
+	prompts := make([]map[string]interface{}, len(finalPrompts))
+	for i, p := range finalPrompts {
+		prompts[i] = map[string]interface{}{
+			"id":       p.ID.String(),
+			"content":  p.Content,
+			"phase":    string(p.Phase),
+			"provider": p.Provider,
+			"model":    p.Model,
+			"score":    p.RelevanceScore,
+		}
+	}
+
+	content := Content{
+		Type: "text",
+		Text: fmt.Sprintf("Generated %d prompts total, selected %d final prompts using '%s' strategy:\n\n%s",
+			len(allPrompts), len(finalPrompts), phaseSelection, formatPrompts(prompts)),
+	}
+	metadata := map[string]interface{}{
+		"prompts":         prompts,
+		"count":           len(prompts),
+		"total_generated": len(allPrompts),
+		"strategy":        phaseSelection,
+		"optimized":       optimize,
+	}
+	if interrupted {
+		content.Text = fmt.Sprintf("Interrupted by shutdown after %d of %d phases. %s", completedPhases, len(modelPhases), content.Text)
+		metadata["interrupted"] = true
+	}
+	if format == outputFormatMarkdown {
+		// The interruption stays in _meta, the document holds only prompts
+		content = Content{Type: "text", MimeType: "text/markdown", Text: models.RenderMarkdown(finalPrompts)}
+	}
+
+	toolResult := ToolResult{
+		Content:  []Content{content},
+		Metadata: metadata,
+	}
+
+	s.sendFormattedToolResult(id, format, toolResult)
+}
+
+func (s *Server) handleSearchPrompts(ctx context.Context, id interface{}, args interface{}) {
+	// Parse arguments
+	argsMap, ok := args.(map[string]interface{})
+	if !ok {
+		s.sendToolError(id, "Invalid arguments")
+		return
+	}
+
+	query, _ := argsMap["query"].(string)
+	phase, _ := argsMap["phase"].(string)
+	provider, _ := argsMap["provider"].(string)
+
+	limit := 10
+	if l, ok := argsMap["limit"].(float64); ok {
+		limit = int(l)
+	}
+	if limit < 1 || limit > 100 {
+		s.sendToolError(id, fmt.Sprintf("limit must be between 1 and 100, got %d", limit))
+		return
+	}
+
+	semantic, _ := argsMap["semantic"].(bool)
+	similarity := 0.5
+	if v, ok := argsMap["similarity"].(float64); ok {
+		similarity = v
+	}
+	if similarity < 0 || similarity > 1 {
+		s.sendToolError(id, fmt.Sprintf("similarity must be between 0 and 1, got %g", similarity))
+		return
+	}
+
+	var tags []string
+	switch v := argsMap["tags"].(type) {
+	case string:
+		tags = helpers.ParseTags(v)
+	case []interface{}:
+		for _, tag := range v {
+			if str, ok := tag.(string); ok && strings.TrimSpace(str) != "" {
+				tags = append(tags, strings.TrimSpace(str))
+			}
+		}
+	}
+
+	format, err := outputFormat(argsMap, false)
+	if err != nil {
+		s.sendToolError(id, err.Error())
+		return
+	}
+
+	var since *time.Time
+	if v, ok := argsMap["since"].(string); ok && v != "" {
+		parsed, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			s.sendToolError(id, fmt.Sprintf("Invalid since date %q (use YYYY-MM-DD)", v))
+			return
+		}
+		since = &parsed
+	}
+
+	var after *storage.PromptCursor
+	if v, ok := argsMap["cursor"].(string); ok && v != "" {
+		if semantic {
+			s.sendToolError(id, "cursor is only supported for text search")
+			return
+		}
+		cursor, err := storage.DecodeCursor(v)
+		if err != nil {
+			s.sendToolError(id, fmt.Sprintf("Invalid cursor %q", v))
+			return
+		}
+		after = &cursor
+	}
+
+	var prompts []models.Prompt
+	var similarities []float64
+	var next *storage.PromptCursor
+	hasMore := false
+	searchType := storage.SearchTypeText
+	if semantic {
+		if query == "" {
+			s.sendToolError(id, "Query is required for semantic search")
+			return
+		}
+		// Semantic search needs a provider to embed the query
+		embed := providers.NewQueryEmbedder(s.registry)
+		if embed == nil {
+			s.sendToolError(id, "Semantic search requires an embedding-capable provider, but none is registered")
+			return
+		}
+
+		searchType = storage.SearchTypeSemantic
+		// One extra result tells whether more prompts are similar enough
+		prompts, similarities, err = s.storage.SearchPromptsSemanticFast(ctx, storage.SemanticSearchCriteria{
+			Query:         query,
+			Limit:         limit + 1,
+			MinSimilarity: similarity,
+			Phase:         phase,
+			Provider:      provider,
+			Tags:          tags,
+			Since:         since,
+		}, embed)
+		if len(prompts) > limit {
+			prompts, similarities, hasMore = prompts[:limit], similarities[:limit], true
+		}
+	} else {
+		prompts, next, err = s.storage.SearchPromptsPage(ctx, storage.SearchCriteria{
+			Query:    query,
+			Phase:    phase,
+			Provider: provider,
+			Tags:     tags,
+			Since:    since,
+			Limit:    limit,
+			After:    after,
+		})
+		hasMore = next != nil
+	}
+	if err != nil {
+		s.logger.WithError(err).WithField("search_type", searchType).Error("Prompt search failed")
+		s.sendToolError(id, fmt.Sprintf("Search failed: %v", err))
+		return
+	}
+
+	// Format response; semantic results are most similar first
+	results := make([]map[string]interface{}, len(prompts))
+	for i, p := range prompts {
+		results[i] = map[string]interface{}{
+			"id":       p.ID.String(),
+			"content":  p.Content,
+			"phase":    string(p.Phase),
+			"provider": p.Provider,
+			"input":    p.OriginalInput,
+			"tags":     p.Tags,
+			"score":    p.RelevanceScore,
+		}
+		if i < len(similarities) {
+			results[i]["similarity"] = similarities[i]
+		}
+	}
+
+	text := fmt.Sprintf("Found %d prompts matching '%s'", len(results), query)
+	if semantic {
+		text = fmt.Sprintf("Found %d prompts similar to '%s'", len(results), query)
+	}
+	if hasMore {
+		text += " (more results available)"
+	}
+
+	metadata := map[string]interface{}{
+		"prompts":     results,
+		"count":       len(results),
+		"query":       query,
+		"search_type": searchType,
+		"has_more":    hasMore,
+		"filters": map[string]interface{}{
+			"phase":    phase,
+			"provider": provider,
+			"tags":     tags,
+			"since":    since,
+			"limit":    limit,
+		},
+	}
+	if semantic {
+		metadata["similarities"] = similarities
+		metadata["min_similarity"] = similarity
+	}
+	if next != nil {
+		metadata["next_cursor"] = next.Encode()
+	}
+
+	s.sendFormattedToolResult(id, format, ToolResult{
+		Content:  []Content{{Type: "text", Text: text}},
+		Metadata: metadata,
+	})
+}
+
+func (s *Server) handleGetPrompt(ctx context.Context, id interface{}, args interface{}) {
+	// Parse arguments
+	argsMap, ok := args.(map[string]interface{})
+	if !ok {
+		s.sendToolError(id, "Invalid arguments")
+		return
+	}
+
+	promptID, ok := argsMap["id"].(string)
+	if !ok || promptID == "" {
+		s.sendToolError(id, "Prompt ID is required")
+		return
+	}
+
+	// Parse UUID
+	uuid, err := uuid.Parse(promptID)
+	if err != nil {
+		s.sendToolError(id, "Invalid prompt ID format")
+		return
+	}
+
+	// Get prompt
+	prompt, err := s.storage.GetPromptByID(ctx, uuid)
+	if err != nil {
+		s.sendToolError(id, fmt.Sprintf("Failed to get prompt: %v", err))
+		return
+	}
+
+	// Format response
+	content := Content{
+		Type: "text",
+		Text: fmt.Sprintf("Prompt %s:\n\n%s", prompt.ID, prompt.Content),
+	}
+
+	toolResult := ToolResult{
+		Content: []Content{content},
+		Metadata: map[string]interface{}{
+			"prompt": map[string]interface{}{
+				"id":          prompt.ID.String(),
+				"content":     prompt.Content,
+				"phase":       string(prompt.Phase),
+				"provider":    prompt.Provider,
+				"model":       prompt.Model,
+				"temperature": prompt.Temperature,
+				"max_tokens":  prompt.MaxTokens,
+				"tags":        prompt.Tags,
+				"created_at":  prompt.CreatedAt,
+				"input":       prompt.OriginalInput,
+			},
+		},
+	}
+
+	s.sendToolResult(id, toolResult)
+}
+
+func (s *Server) handleListProviders(id interface{}) {
+	available := s.registry.ListAvailable()
+	embeddingCapable := s.registry.ListEmbeddingCapableProviders()
+
+	// Create provider list
+	providers := make([]map[string]interface{}, 0)
+	for _, name := range available {
+		provider := map[string]interface{}{
+			"name":                name,
+			"available":           true,
+			"supports_embeddings": contains(embeddingCapable, name),
+		}
+		providers = append(providers, provider)
+	}
+
+	content := Content{
+		Type: "text",
+		Text: fmt.Sprintf("Available providers: %d", len(providers)),
+	}
+
+	toolResult := ToolResult{
+		Content: []Content{content},
+		Metadata: map[string]interface{}{
+			"providers": providers,
+			"count":     len(providers),
+		},
+	}
+
+	s.sendToolResult(id, toolResult)
+}
+
+func (s *Server) handleOptimizePrompt(ctx context.Context, id interface{}, args interface{}) {
+	// Parse arguments
+	argsMap, ok := args.(map[string]interface{})
+	if !ok {
+		s.sendToolError(id, "Invalid arguments")
+		return
+	}
+
+	prompt, ok := argsMap["prompt"].(string)
+	if !ok || prompt == "" {
+		s.sendToolError(id, "Prompt is required")
+		return
+	}
+
+	// Parse optional parameters
+	task := ""
+	if t, ok := argsMap["task"].(string); ok {
+		task = t
+	}
+
+	persona := "code"
+	if p, ok := argsMap["persona"].(string); ok {
+		persona = p
+	}
+
+	targetModel := ""
+	if tm, ok := argsMap["target_model"].(string); ok {
+		targetModel = tm
+	}
+
+	maxIterations := 5
+	if mi, ok := argsMap["max_iterations"].(float64); ok {
+		maxIterations = int(mi)
+	}
+
+	targetScore := 8.5
+	if ts, ok := argsMap["target_score"].(float64); ok {
+		targetScore = ts
+	}
+
+	acceptFirstPassing, _ := argsMap["accept_first_passing"].(bool)
+
+	// Get providers
+	available := s.registry.ListAvailable()
+	if len(available) == 0 {
+		s.sendToolError(id, "No providers available")
+		return
+	}
+
+	providerName := viper.GetString("generation.default_provider")
+	if providerName == "" {
+		providerName = available[0]
+	}
+
+	provider, err := s.registry.Get(providerName)
+	if err != nil {
+		s.sendToolError(id, fmt.Sprintf("Provider '%s' not available: %v", providerName, err))
+		return
+	}
+
+	judgeProviderName := viper.GetString("optimize.judge_provider")
+	if judgeProviderName == "" {
+		judgeProviderName = providerName
+	}
+
+	judgeProvider, err := s.registry.Get(judgeProviderName)
+	if err != nil {
+		judgeProvider = provider
+	}
+
+	// Create optimizer
+	metaOptimizer := optimizer.NewMetaPromptOptimizer(provider, judgeProvider, s.storage, s.registry)
+
+	// Detect model family
+	modelFamily := models.ModelFamilyGeneric
+	if targetModel != "" {
+		modelFamily = models.DetectModelFamily(targetModel)
+	}
+
+	// Create optimization request
+	request := &optimizer.OptimizationRequest{
+		OriginalPrompt:     prompt,
+		TaskDescription:    task,
+		Examples:           []optimizer.OptimizationExample{},
+		Constraints:        []string{"Maintain clarity", "Preserve intent", "Improve effectiveness"},
+		ModelFamily:        modelFamily,
+		PersonaType:        models.PersonaType(persona),
+		MaxIterations:      maxIterations,
+		TargetScore:        targetScore,
+		AcceptFirstPassing: acceptFirstPassing,
+		OptimizationGoals: map[string]float64{
+			"factual_accuracy": 0.3,
+			"code_quality":     0.3,
+			"helpfulness":      0.2,
+			"conciseness":      0.2,
+		},
+	}
+
+	// Run optimization
+	result, err := metaOptimizer.OptimizePrompt(ctx, request)
+	if err != nil && ctx.Err() != nil {
+		s.sendCancelled(id)
+		return
+	}
+	if err != nil {
+		s.sendToolError(id, fmt.Sprintf("Optimization failed: %v", err))
+		return
+	}
+
+	// Format response
+	iterations := make([]map[string]interface{}, len(result.Iterations))
+	for i, iter := range result.Iterations {
+		iterations[i] = map[string]interface{}{
+			"iteration": iter.Iteration,
+			"prompt":    iter.Prompt,
+			"score":     optimizer.ScoreOutOfTen(iter.Score),
+			"reasoning": iter.ChangeReasoning,
+		}
+	}
+
+	// Report scores out of 10 whatever scale the judge used
+	finalScore := optimizer.ScoreOutOfTen(result.FinalScore)
+	originalScore := optimizer.ScoreOutOfTen(result.OriginalScore)
+	improvement := finalScore - originalScore
+
+	content := Content{
+		Type: "text",
+		Text: fmt.Sprintf("Optimization complete!\n\nOriginal prompt:\n%s\n\nOptimized prompt:\n%s\n\nFinal score: %.1f/10\nImprovement: %.1f\nIterations: %d",
+			prompt,
+			result.OptimizedPrompt,
+			finalScore,
+			improvement,
+			len(result.Iterations)),
+	}
+
+	toolResult := ToolResult{
+		Content: []Content{content},
+		Metadata: map[string]interface{}{
+			"original_prompt":  prompt,
+			"optimized_prompt": result.OptimizedPrompt,
+			"original_score":   originalScore,
+			"final_score":      finalScore,
+			"improvement":      improvement,
+			"iterations":       iterations,
+			"total_iterations": len(result.Iterations),
+			"converged_at":     result.ConvergedAt,
+		},
+	}
+
+	s.sendToolResult(id, toolResult)
+}
+
+func (s *Server) handleBatchGenerate(ctx context.Context, id interface{}, args interface{}) {
+	// Parse arguments
+	argsMap, ok := args.(map[string]interface{})
+	if !ok {
+		s.sendToolError(id, "Invalid arguments")
+		return
+	}
+
+	inputsRaw, ok := argsMap["inputs"].([]interface{})
+	if !ok || len(inputsRaw) == 0 {
+		s.sendToolError(id, "Inputs array is required")
+		return
+	}
+
+	workers := 3
+	if w, ok := argsMap["workers"].(float64); ok {
+		workers = int(w)
+	}
+
+	// Extract progress token if provided
+	var progressToken interface{}
+	if pt, ok := argsMap["progressToken"]; ok {
+		progressToken = pt
+	}
+
+	// Parse batch inputs
+	var batchInputs []batchInput
+	for _, inputRaw := range inputsRaw {
+		input, ok := inputRaw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		batchInput := batchInput{
+			Input:   "",
+			Phases:  "prima-materia,solutio,coagulatio",
+			Count:   3,
+			Persona: "code",
+		}
+
+		if id, ok := input["id"].(string); ok {
+			batchInput.ID = id
+		}
+		if text, ok := input["input"].(string); ok {
+			batchInput.Input = text
+		}
+		if phases, ok := input["phases"].(string); ok {
+			batchInput.Phases = phases
+		}
+		if count, ok := input["count"].(float64); ok {
+			batchInput.Count = int(count)
+		}
+		if persona, ok := input["persona"].(string); ok {
+			batchInput.Persona = persona
+		}
+
+		if batchInput.Input != "" {
+			batchInputs = append(batchInputs, batchInput)
+		}
+	}
+
+	if len(batchInputs) == 0 {
+		s.sendToolError(id, "No valid inputs provided")
+		return
+	}
+
+	// Process batch with workers
+	results := make([]map[string]interface{}, 0)
+	resultsChan := make(chan map[string]interface{}, len(batchInputs))
+	errorsChan := make(chan error, len(batchInputs))
+
+	// Initialize progress tracking
+	var tracker *ProgressTracker
+	if progressToken != nil {
+		tracker = s.newProgressTracker()
+		tracker.Start(progressToken, fmt.Sprintf("Processing %d prompts", len(batchInputs)))
+	}
+
+	// Track completed items for progress
+	completedCount := 0
+	completedMutex := &sync.Mutex{}
+
+	// Create worker pool
+	workChan := make(chan batchInput, len(batchInputs))
+	var wg sync.WaitGroup
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for input := range workChan {
+				// Shutdown skips the inputs not yet started
+				if err := ctx.Err(); err != nil {
+					errorsChan <- fmt.Errorf("input %s: %w", input.ID, err)
+					continue
+				}
+
+				// Process single input
+				modelPhases := helpers.ParsePhases(input.Phases)
+				if len(modelPhases) == 0 {
+					errorsChan <- fmt.Errorf("input %s: no valid phases in %q", input.ID, input.Phases)
+					continue
+				}
+				if err := s.engine.ValidatePhases(modelPhases); err != nil {
+					errorsChan <- fmt.Errorf("input %s: %w", input.ID, err)
+					continue
+				}
+
+				req := models.PromptRequest{
+					Input:       input.Input,
+					Phases:      modelPhases,
+					Count:       input.Count,
+					Temperature: 0.7,
+					MaxTokens:   2000,
+				}
+
+				// Build phase configs
+				phaseConfigs := make([]models.PhaseConfig, len(modelPhases))
+				defaultProvider := "openai"
+				available := s.registry.ListAvailable()
+				if !contains(available, defaultProvider) && len(available) > 0 {
+					defaultProvider = available[0]
+				}
+
+				for j, phase := range modelPhases {
+					phaseConfigs[j] = models.PhaseConfig{
+						Phase:    phase,
+						Provider: defaultProvider,
+					}
+				}
+
+				opts := models.GenerateOptions{
+					Request:        req,
+					PhaseConfigs:   phaseConfigs,
+					UseParallel:    false,
+					IncludeContext: true,
+					Persona:        input.Persona,
+				}
+
+				result, err := s.engine.Generate(ctx, opts)
+				if err != nil {
+					s.logger.WithError(err).WithField("input_id", input.ID).Error("Batch generation failed for input")
+					errorsChan <- fmt.Errorf("input %s: %v", input.ID, err)
+					continue
+				}
+
+				// Format result
+				prompts := make([]map[string]interface{}, len(result.Prompts))
+				for k, p := range result.Prompts {
+					prompts[k] = map[string]interface{}{
+						"id":       p.ID.String(),
+						"content":  p.Content,
+						"phase":    string(p.Phase),
+						"provider": p.Provider,
+						"model":    p.Model,
+					}
+				}
+
+				resultsChan <- map[string]interface{}{
+					"id":      input.ID,
+					"input":   input.Input,
+					"prompts": prompts,
+					"count":   len(prompts),
+				}
+
+				// Update progress
+				if tracker != nil {
+					completedMutex.Lock()
+					completedCount++
+					percentage := float64(completedCount) / float64(len(batchInputs)) * 100
+					tracker.Update(progressToken, fmt.Sprintf("Completed %d/%d prompts", completedCount, len(batchInputs)), percentage)
+					completedMutex.Unlock()
+				}
+			}
+		}()
+	}
+
+	// Send work to workers
+	for _, input := range batchInputs {
+		workChan <- input
+	}
+	close(workChan)
+
+	// Wait for workers to complete
+	wg.Wait()
+	close(resultsChan)
+	close(errorsChan)
+
+	// Collect results and errors in goroutines
+	var resultsDone sync.WaitGroup
+	resultsDone.Add(2)
+
+	go func() {
+		defer resultsDone.Done()
+		for result := range resultsChan {
+			results = append(results, result)
+		}
+	}()
+
+	var errors []string
+	go func() {
+		defer resultsDone.Done()
+		for err := range errorsChan {
+			errors = append(errors, err.Error())
+		}
+	}()
+
+	resultsDone.Wait()
+
+	interrupted := ctx.Err() != nil
+	if interrupted && len(results) == 0 {
+		s.sendCancelled(id)
+		return
+	}
+
+	// Complete progress tracking
+	if tracker != nil {
+		tracker.End(progressToken, fmt.Sprintf("Processed %d prompts with %d errors", len(results), len(errors)))
+	}
+
+	heading := "Batch generation complete!"
+	metadata := map[string]interface{}{
+		"results":      results,
+		"total_inputs": len(batchInputs),
+		"successful":   len(results),
+		"errors":       errors,
+	}
+	if interrupted {
+		heading = "Batch generation interrupted by shutdown."
+		metadata["interrupted"] = true
+	}
+	content := Content{
+		Type: "text",
+		Text: fmt.Sprintf("%s\n\nProcessed: %d inputs\nSuccessful: %d\nErrors: %d",
+			heading,
+			len(batchInputs),
+			len(results),
+			len(errors)),
+	}
+
+	toolResult := ToolResult{
+		Content:  []Content{content},
+		Metadata: metadata,
+	}
+
+	s.sendToolResult(id, toolResult)
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
 )
 
 // Config contains configuration for metrics collection
@@ -32,16 +33,22 @@ type Metrics struct {
 	HTTPResponseSize    *prometheus.HistogramVec
 
 	// Business metrics
+	GenerateRequests    *prometheus.CounterVec
 	PromptsGenerated    *prometheus.CounterVec
 	GenerationDuration  *prometheus.HistogramVec
 	TokensUsed          *prometheus.CounterVec
+	GenerationCost      *prometheus.CounterVec
 	PhaseProcessingTime *prometheus.HistogramVec
 	ProviderRequests    *prometheus.CounterVec
 	ProviderErrors      *prometheus.CounterVec
+	ProviderRateLimit   *prometheus.GaugeVec
+	GenerationsInFlight prometheus.Gauge
+	EmbeddingCache      *prometheus.CounterVec
 
 	// System metrics
 	ActiveConnections prometheus.Gauge
 	StorageOperations *prometheus.CounterVec
+	StorageRetries    *prometheus.CounterVec
 	CacheHitRate      *prometheus.GaugeVec
 
 	// Learning metrics
@@ -110,6 +117,16 @@ func NewMetrics(config Config, logger *logrus.Logger) (*Metrics, error) {
 		),
 
 		// Business metrics
+		GenerateRequests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: config.Namespace,
+				Subsystem: config.Subsystem,
+				Name:      "generate_requests_total",
+				Help:      "Generate requests served, by outcome (success, error or canceled)",
+			},
+			[]string{"outcome"},
+		),
+
 		PromptsGenerated: prometheus.NewCounterVec(
 			prometheus.CounterOpts{
 				Namespace: config.Namespace,
@@ -141,6 +158,16 @@ func NewMetrics(config Config, logger *logrus.Logger) (*Metrics, error) {
 			[]string{"provider", "model"},
 		),
 
+		GenerationCost: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: config.Namespace,
+				Subsystem: config.Subsystem,
+				Name:      "generation_cost_usd_total",
+				Help:      "Estimated cost in USD of generations, from reported usage and known prices",
+			},
+			[]string{"provider", "model"},
+		),
+
 		PhaseProcessingTime: prometheus.NewHistogramVec(
 			prometheus.HistogramOpts{
 				Namespace: config.Namespace,
@@ -172,6 +199,35 @@ func NewMetrics(config Config, logger *logrus.Logger) (*Metrics, error) {
 			[]string{"provider", "error_type"},
 		),
 
+		ProviderRateLimit: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: config.Namespace,
+				Subsystem: config.Subsystem,
+				Name:      "provider_rate_limit_utilization",
+				Help:      "Requests to a rate-limited provider in the last minute as a share of its RPM",
+			},
+			[]string{"provider"},
+		),
+
+		GenerationsInFlight: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: config.Namespace,
+				Subsystem: config.Subsystem,
+				Name:      "generations_in_flight",
+				Help:      "Provider calls the engine's parallel path is running, bounded by generation.max_concurrency",
+			},
+		),
+
+		EmbeddingCache: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: config.Namespace,
+				Subsystem: config.Subsystem,
+				Name:      "embedding_cache_lookups_total",
+				Help:      "Lookups in the embedding cache, by result (hit or miss)",
+			},
+			[]string{"result"},
+		),
+
 		// System metrics
 		ActiveConnections: prometheus.NewGauge(
 			prometheus.GaugeOpts{
@@ -192,6 +248,16 @@ func NewMetrics(config Config, logger *logrus.Logger) (*Metrics, error) {
 			[]string{"operation", "table"},
 		),
 
+		StorageRetries: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: config.Namespace,
+				Subsystem: config.Subsystem,
+				Name:      "storage_retries_total",
+				Help:      "Storage writes retried after a transient error such as a locked database",
+			},
+			[]string{"operation"},
+		),
+
 		CacheHitRate: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
 				Namespace: config.Namespace,
@@ -254,14 +320,20 @@ func (m *Metrics) registerMetrics() error {
 		m.HTTPRequestDuration,
 		m.HTTPRequestSize,
 		m.HTTPResponseSize,
+		m.GenerateRequests,
 		m.PromptsGenerated,
 		m.GenerationDuration,
 		m.TokensUsed,
+		m.GenerationCost,
 		m.PhaseProcessingTime,
 		m.ProviderRequests,
 		m.ProviderErrors,
+		m.ProviderRateLimit,
+		m.GenerationsInFlight,
+		m.EmbeddingCache,
 		m.ActiveConnections,
 		m.StorageOperations,
+		m.StorageRetries,
 		m.CacheHitRate,
 		m.ModelTrainingEvents,
 		m.RankingAccuracy,
@@ -342,8 +414,18 @@ func (m *Metrics) Middleware() func(http.Handler) http.Handler {
 
 // Business metric recording methods
 
-// RecordPromptGeneration records metrics for prompt generation
-func (m *Metrics) RecordPromptGeneration(ctx context.Context, phase, provider, persona string, duration time.Duration, tokens int) {
+// RecordGenerateRequest counts a generate request by outcome: success,
+// error or canceled
+func (m *Metrics) RecordGenerateRequest(outcome string) {
+	if !m.config.Enabled {
+		return
+	}
+	m.GenerateRequests.WithLabelValues(outcome).Inc()
+}
+
+// RecordPromptGeneration records metrics for prompt generation: the prompt,
+// its duration, the tokens it used and its estimated cost in USD
+func (m *Metrics) RecordPromptGeneration(ctx context.Context, phase, provider, model, persona string, duration time.Duration, tokens int, cost float64) {
 	if !m.config.Enabled {
 		return
 	}
@@ -352,8 +434,14 @@ func (m *Metrics) RecordPromptGeneration(ctx context.Context, phase, provider, p
 	m.GenerationDuration.WithLabelValues(phase, provider).Observe(duration.Seconds())
 	m.PhaseProcessingTime.WithLabelValues(phase).Observe(duration.Seconds())
 
+	if model == "" {
+		model = "unknown"
+	}
 	if tokens > 0 {
-		m.TokensUsed.WithLabelValues(provider, "unknown").Add(float64(tokens))
+		m.TokensUsed.WithLabelValues(provider, model).Add(float64(tokens))
+	}
+	if cost > 0 {
+		m.GenerationCost.WithLabelValues(provider, model).Add(cost)
 	}
 }
 
@@ -373,6 +461,36 @@ func (m *Metrics) RecordProviderError(provider, errorType string) {
 	m.ProviderErrors.WithLabelValues(provider, errorType).Inc()
 }
 
+// SetProviderRateLimitUtilization sets how much of a provider's rate limit
+// budget is in use
+func (m *Metrics) SetProviderRateLimitUtilization(provider string, utilization float64) {
+	if !m.config.Enabled {
+		return
+	}
+	m.ProviderRateLimit.WithLabelValues(provider).Set(utilization)
+}
+
+// SetGenerationsInFlight sets the number of parallel provider calls the
+// engine is running
+func (m *Metrics) SetGenerationsInFlight(inFlight int) {
+	if !m.config.Enabled {
+		return
+	}
+	m.GenerationsInFlight.Set(float64(inFlight))
+}
+
+// RecordEmbeddingCacheLookup counts a lookup in the embedding cache
+func (m *Metrics) RecordEmbeddingCacheLookup(hit bool) {
+	if !m.config.Enabled {
+		return
+	}
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	m.EmbeddingCache.WithLabelValues(result).Inc()
+}
+
 // RecordStorageOperation records metrics for storage operations
 func (m *Metrics) RecordStorageOperation(operation, table string) {
 	if !m.config.Enabled {
@@ -381,6 +499,14 @@ func (m *Metrics) RecordStorageOperation(operation, table string) {
 	m.StorageOperations.WithLabelValues(operation, table).Inc()
 }
 
+// RecordStorageRetry counts a storage write retried after a transient error
+func (m *Metrics) RecordStorageRetry(operation string) {
+	if !m.config.Enabled {
+		return
+	}
+	m.StorageRetries.WithLabelValues(operation).Inc()
+}
+
 // RecordCacheHitRate records cache hit rate metrics
 func (m *Metrics) RecordCacheHitRate(cacheType string, hitRate float64) {
 	if !m.config.Enabled {
@@ -455,3 +581,22 @@ func DefaultConfig() Config {
 		Subsystem: "api",
 	}
 }
+
+// LoadConfig reads the metrics.* settings, falling back to DefaultConfig for
+// those unset
+func LoadConfig() Config {
+	config := DefaultConfig()
+	if viper.IsSet("metrics.enabled") {
+		config.Enabled = viper.GetBool("metrics.enabled")
+	}
+	if path := viper.GetString("metrics.path"); path != "" {
+		config.Path = path
+	}
+	if namespace := viper.GetString("metrics.namespace"); namespace != "" {
+		config.Namespace = namespace
+	}
+	if subsystem := viper.GetString("metrics.subsystem"); subsystem != "" {
+		config.Subsystem = subsystem
+	}
+	return config
+}
@@ -0,0 +1,149 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName names the tracer every span is started from
+const instrumentationName = "github.com/jonwraymond/prompt-alchemy"
+
+// TraceIDHeader carries the request's trace ID on every traced response
+const TraceIDHeader = "X-Trace-ID"
+
+// Config contains configuration for trace export
+type Config struct {
+	Enabled      bool    `yaml:"enabled" mapstructure:"enabled"`
+	OTLPEndpoint string  `yaml:"otlp_endpoint" mapstructure:"otlp_endpoint"` // host:port of an OTLP/HTTP collector
+	Insecure     bool    `yaml:"insecure" mapstructure:"insecure"`           // plain HTTP instead of TLS
+	ServiceName  string  `yaml:"service_name" mapstructure:"service_name"`
+	SampleRatio  float64 `yaml:"sample_ratio" mapstructure:"sample_ratio"` // fraction of new traces kept, 0 means all
+}
+
+// Tracing owns the tracer provider that exports spans over OTLP
+type Tracing struct {
+	config   Config
+	provider *sdktrace.TracerProvider
+	logger   *logrus.Logger
+}
+
+// NewTracing installs a global tracer provider exporting to the configured
+// OTLP endpoint. When tracing is disabled spans are no-ops.
+func NewTracing(ctx context.Context, config Config, logger *logrus.Logger) (*Tracing, error) {
+	if !config.Enabled {
+		logger.Info("Tracing disabled")
+		return &Tracing{config: config, logger: logger}, nil
+	}
+
+	var opts []otlptracehttp.Option
+	if config.OTLPEndpoint != "" {
+		opts = append(opts, otlptracehttp.WithEndpoint(config.OTLPEndpoint))
+	}
+	if config.Insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+
+	if config.ServiceName == "" {
+		config.ServiceName = DefaultConfig().ServiceName
+	}
+	ratio := config.SampleRatio
+	if ratio <= 0 || ratio > 1 {
+		ratio = 1
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", config.ServiceName))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	logger.WithFields(logrus.Fields{
+		"endpoint":     config.OTLPEndpoint,
+		"service_name": config.ServiceName,
+		"sample_ratio": ratio,
+	}).Info("Tracing enabled")
+
+	return &Tracing{config: config, provider: provider, logger: logger}, nil
+}
+
+// Shutdown flushes buffered spans and stops the exporter
+func (t *Tracing) Shutdown(ctx context.Context) error {
+	if t.provider == nil {
+		return nil
+	}
+	return t.provider.Shutdown(ctx)
+}
+
+// Start starts a span as a child of any span in ctx
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End ends span, marking it failed when err is set
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// TraceID returns the ID of the trace ctx belongs to, or "" if none
+func TraceID(ctx context.Context) string {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.HasTraceID() {
+		return ""
+	}
+	return spanContext.TraceID().String()
+}
+
+// WithSpanFrom returns a copy of ctx carrying the span in from, for work
+// detached from the request context that should still join its trace
+func WithSpanFrom(ctx, from context.Context) context.Context {
+	return trace.ContextWithSpan(ctx, trace.SpanFromContext(from))
+}
+
+// Middleware starts a server span for each request, continuing any trace
+// propagated by the caller, and returns its trace ID in TraceIDHeader
+func Middleware(next http.Handler) http.Handler {
+	withTraceID := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if traceID := TraceID(r.Context()); traceID != "" {
+			w.Header().Set(TraceIDHeader, traceID)
+		}
+		next.ServeHTTP(w, r)
+	})
+	return otelhttp.NewHandler(withTraceID, "http.server",
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return "HTTP " + r.Method
+		}),
+	)
+}
+
+// DefaultConfig returns default tracing configuration
+func DefaultConfig() Config {
+	return Config{
+		Enabled:      false,
+		OTLPEndpoint: "localhost:4318",
+		Insecure:     true,
+		ServiceName:  "prompt-alchemy",
+		SampleRatio:  1,
+	}
+}
@@ -32,6 +32,9 @@ type OptimizationRequest struct {
 	MaxIterations     int                   `json:"max_iterations"`
 	TargetScore       float64               `json:"target_score"`
 	OptimizationGoals map[string]float64    `json:"optimization_goals"`
+	// AcceptFirstPassing returns the first prompt that meets TargetScore,
+	// including the original, instead of the best one seen
+	AcceptFirstPassing bool `json:"accept_first_passing"`
 }
 
 // OptimizationExample provides training data for the optimizer
@@ -49,7 +52,7 @@ type OptimizationResult struct {
 	Improvement     float64                 `json:"improvement"`
 	Iterations      []OptimizationIteration `json:"iterations"`
 	TotalTime       time.Duration           `json:"total_time"`
-	ConvergedAt     int                     `json:"converged_at"`
+	ConvergedAt     int                     `json:"converged_at"` // iteration that met TargetScore, 0 for an accepted original, -1 if none did
 }
 
 // OptimizationIteration represents one iteration of optimization
@@ -63,6 +66,15 @@ type OptimizationIteration struct {
 	ProcessingTime  time.Duration           `json:"processing_time"`
 }
 
+// ScoreOutOfTen returns a judge score on the 0-10 scale. Judges that score
+// from 0 to 1 are scaled up.
+func ScoreOutOfTen(score float64) float64 {
+	if score <= 1.0 {
+		return score * 10.0
+	}
+	return score
+}
+
 // NewMetaPromptOptimizer creates a new meta-prompt optimizer
 func NewMetaPromptOptimizer(provider providers.Provider, judgeProvider providers.Provider, storage storage.StorageInterface, registry providers.RegistryInterface) *MetaPromptOptimizer {
 	return &MetaPromptOptimizer{
@@ -115,8 +127,15 @@ func (o *MetaPromptOptimizer) OptimizePrompt(ctx context.Context, request *Optim
 	bestScore := originalScore
 	bestPrompt := currentPrompt
 
+	maxIterations := request.MaxIterations
+	if request.AcceptFirstPassing && originalScore >= request.TargetScore {
+		logger.Infof("Original prompt meets target score of %.2f, accepting it", request.TargetScore)
+		result.ConvergedAt = 0
+		maxIterations = 0
+	}
+
 	// Iterative optimization
-	for i := 0; i < request.MaxIterations; i++ {
+	for i := 0; i < maxIterations; i++ {
 		logger.Infof("Starting optimization iteration %d", i+1)
 		iterStart := time.Now()
 
@@ -157,6 +176,10 @@ func (o *MetaPromptOptimizer) OptimizePrompt(ctx context.Context, request *Optim
 		if score >= request.TargetScore {
 			logger.Infof("Target score of %.2f reached, stopping optimization", request.TargetScore)
 			result.ConvergedAt = i + 1
+			if request.AcceptFirstPassing {
+				bestScore = score
+				bestPrompt = improvedPrompt
+			}
 			break
 		}
 
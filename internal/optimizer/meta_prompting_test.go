@@ -6,8 +6,10 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/jonwraymond/prompt-alchemy/internal/storage"
 	"github.com/jonwraymond/prompt-alchemy/pkg/models"
 	"github.com/jonwraymond/prompt-alchemy/pkg/providers"
 
@@ -77,6 +79,10 @@ func (m *MockOptimizerProvider) SupportsStreaming() bool {
 	return false
 }
 
+func (m *MockOptimizerProvider) Ping(ctx context.Context) (time.Duration, error) {
+	return 0, nil
+}
+
 func (m *MockOptimizerProvider) SetResponse(prompt, response string) {
 	m.responses[prompt] = response
 }
@@ -159,6 +165,10 @@ func (m *MockJudgeProvider) SupportsStreaming() bool {
 	return false
 }
 
+func (m *MockJudgeProvider) Ping(ctx context.Context) (time.Duration, error) {
+	return 0, nil
+}
+
 func (m *MockJudgeProvider) SetScore(prompt string, score float64) {
 	m.scores[prompt] = score
 }
@@ -201,6 +211,12 @@ func (m *MockStorage) SearchSimilarHighQualityPrompts(ctx context.Context, embed
 func (m *MockStorage) SaveInteraction(ctx context.Context, interaction *models.UserInteraction) error {
 	return nil
 }
+func (m *MockStorage) StreamPrompts(ctx context.Context, limit, offset int, fn func(*models.Prompt) error) error {
+	return nil
+}
+func (m *MockStorage) FindDuplicate(ctx context.Context, p *models.Prompt, minSimilarity float64) (*storage.Duplicate, error) {
+	return nil, nil
+}
 func (m *MockStorage) SetEmbeddingConfig(provider, model string, dims int) {
 	m.embeddingProvider = provider
 	m.embeddingModel = model
@@ -337,6 +353,39 @@ func TestOptimizePromptTargetScoreReached(t *testing.T) {
 	assert.Less(t, result.ConvergedAt, 5)    // Before max iterations
 }
 
+func TestOptimizePromptAcceptFirstPassing(t *testing.T) {
+	optimizer, _, _ := createTestOptimizer()
+
+	newRequest := func(accept bool) *OptimizationRequest {
+		return &OptimizationRequest{
+			OriginalPrompt:     fibonacciPrompt,
+			TaskDescription:    fibonacciTaskDescription,
+			ModelFamily:        models.ModelFamilyGPT,
+			PersonaType:        models.PersonaCode,
+			MaxIterations:      3,
+			TargetScore:        1.0, // The original prompt already passes
+			AcceptFirstPassing: accept,
+			OptimizationGoals: map[string]float64{
+				"code_quality": 1.0,
+			},
+		}
+	}
+
+	ctx := context.Background()
+	result, err := optimizer.OptimizePrompt(ctx, newRequest(true))
+	require.NoError(t, err)
+	assert.Empty(t, result.Iterations)
+	assert.Equal(t, 0, result.ConvergedAt)
+	assert.Equal(t, fibonacciPrompt, result.OptimizedPrompt)
+	assert.Equal(t, result.OriginalScore, result.FinalScore)
+
+	// Without the flag at least one iteration always runs
+	result, err = optimizer.OptimizePrompt(ctx, newRequest(false))
+	require.NoError(t, err)
+	assert.Len(t, result.Iterations, 1)
+	assert.Equal(t, 1, result.ConvergedAt)
+}
+
 func TestOptimizePromptMaxIterationsReached(t *testing.T) {
 	optimizer, _, _ := createTestOptimizer()
 
@@ -0,0 +1,116 @@
+package personas
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jonwraymond/prompt-alchemy/internal/selection"
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/spf13/viper"
+)
+
+// fileExtensions are the persona file formats read from personas.dir
+var fileExtensions = map[string]bool{".yaml": true, ".yml": true, ".json": true}
+
+// definition is a custom persona as written in configuration
+type definition struct {
+	Name            string `mapstructure:"name"` // defaults to the config key or file name
+	DisplayName     string `mapstructure:"display_name"`
+	Description     string `mapstructure:"description"`
+	SystemPrompt    string `mapstructure:"system_prompt"`
+	ScoringCriteria string `mapstructure:"scoring_criteria"`
+}
+
+// Load reads the personas defined under personas.custom, keyed by name, and
+// in the files of personas.dir, one persona per file, and registers them
+// with models.SetCustomPersonas. A persona's scoring_criteria must name one
+// of presets. It returns the registered personas.
+func Load(presets selection.WeightPresets) ([]*models.Persona, error) {
+	var configured map[string]definition
+	if err := viper.UnmarshalKey("personas.custom", &configured); err != nil {
+		return nil, fmt.Errorf("invalid personas.custom: %w", err)
+	}
+	definitions := make([]definition, 0, len(configured))
+	for name, def := range configured {
+		if def.Name == "" {
+			def.Name = name
+		}
+		definitions = append(definitions, def)
+	}
+	sort.Slice(definitions, func(i, j int) bool { return definitions[i].Name < definitions[j].Name })
+
+	if dir := viper.GetString("personas.dir"); dir != "" {
+		fromFiles, err := loadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		definitions = append(definitions, fromFiles...)
+	}
+
+	list := make([]*models.Persona, 0, len(definitions))
+	for _, def := range definitions {
+		if def.ScoringCriteria != "" {
+			if _, ok := presets[def.ScoringCriteria]; !ok {
+				return nil, fmt.Errorf("custom persona %q: unknown scoring_criteria %q", def.Name, def.ScoringCriteria)
+			}
+		}
+		list = append(list, def.persona())
+	}
+	if err := models.SetCustomPersonas(list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// loadDir reads one persona from each YAML or JSON file in dir. A missing
+// directory defines no personas.
+func loadDir(dir string) ([]definition, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read personas.dir: %w", err)
+	}
+
+	var definitions []definition
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || !fileExtensions[ext] {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		file := viper.New()
+		file.SetConfigFile(path)
+		if err := file.ReadInConfig(); err != nil {
+			return nil, fmt.Errorf("failed to read persona file %s: %w", path, err)
+		}
+		var def definition
+		if err := file.Unmarshal(&def); err != nil {
+			return nil, fmt.Errorf("invalid persona file %s: %w", path, err)
+		}
+		if def.Name == "" {
+			def.Name = strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		}
+		definitions = append(definitions, def)
+	}
+	return definitions, nil
+}
+
+func (d definition) persona() *models.Persona {
+	name := d.DisplayName
+	if name == "" {
+		name = d.Name
+	}
+	return &models.Persona{
+		Type:             models.PersonaType(d.Name),
+		Name:             name,
+		Description:      d.Description,
+		DefaultReasoning: models.ReasoningCoT,
+		SystemPrompt:     strings.TrimSpace(d.SystemPrompt),
+		ScoringCriteria:  d.ScoringCriteria,
+	}
+}
@@ -0,0 +1,64 @@
+package personas
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jonwraymond/prompt-alchemy/internal/selection"
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	t.Cleanup(func() {
+		viper.Reset()
+		_ = models.SetCustomPersonas(nil)
+	})
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "reviewer.yaml"), []byte(`
+description: Reviews pull requests
+system_prompt: Focus on correctness and missing tests.
+scoring_criteria: clarity
+`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a persona"), 0o644))
+
+	viper.Set("personas.dir", dir)
+	viper.Set("personas.custom", map[string]interface{}{
+		"support": map[string]interface{}{
+			"display_name":  "Customer Support",
+			"description":   "Answers customer questions",
+			"system_prompt": "Be patient and concrete.",
+		},
+	})
+
+	loaded, err := Load(selection.BuiltinWeightPresets())
+	require.NoError(t, err)
+	require.Len(t, loaded, 2)
+
+	support, err := models.GetPersona("support")
+	require.NoError(t, err)
+	assert.Equal(t, "Customer Support", support.Name)
+	assert.Equal(t, "Be patient and concrete.", support.SystemPrompt)
+
+	reviewer, err := models.GetPersona("reviewer")
+	require.NoError(t, err)
+	assert.Equal(t, "reviewer", reviewer.Name, "the name doubles as the display name")
+	assert.Equal(t, "clarity", reviewer.ScoringCriteria)
+
+	t.Run("unknown scoring criteria", func(t *testing.T) {
+		_, err := Load(selection.WeightPresets{})
+		assert.ErrorContains(t, err, `unknown scoring_criteria "clarity"`)
+	})
+
+	t.Run("defined in config and a file", func(t *testing.T) {
+		viper.Set("personas.custom", map[string]interface{}{
+			"reviewer": map[string]interface{}{"description": "Again", "system_prompt": "Again."},
+		})
+		_, err := Load(selection.BuiltinWeightPresets())
+		assert.ErrorContains(t, err, "defined more than once")
+	})
+}
@@ -0,0 +1,135 @@
+package phases
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"text/template"
+
+	"github.com/jonwraymond/prompt-alchemy/internal/templates"
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/spf13/viper"
+)
+
+// BuiltinPhases are the alchemical phases every engine can run
+var BuiltinPhases = []models.Phase{models.PhasePrimaMaterial, models.PhaseSolutio, models.PhaseCoagulatio}
+
+// customPhaseName restricts custom phase names to what can be used as a
+// config key and in URLs
+var customPhaseName = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+
+// IsBuiltin reports whether phase is one of the alchemical phases
+func IsBuiltin(phase models.Phase) bool {
+	for _, builtin := range BuiltinPhases {
+		if phase == builtin {
+			return true
+		}
+	}
+	return false
+}
+
+// CustomPhase is a phase defined under phases.custom. Its template is a Go
+// text/template executed with templates.PhaseContext, so it can use
+// {{.Prompt}}, {{.Persona}}, {{.TargetModel}} and {{.Context}}.
+type CustomPhase struct {
+	Name         models.Phase `mapstructure:"-"`
+	Template     string       `mapstructure:"template"`
+	SystemPrompt string       `mapstructure:"system_prompt"`
+	Provider     string       `mapstructure:"provider"`    // used when a request names no provider for the phase
+	Temperature  float64      `mapstructure:"temperature"` // overrides the request's temperature when set
+
+	content *template.Template
+	system  *template.Template
+}
+
+// LoadCustomPhases reads and validates the phases defined under
+// phases.custom, keyed by phase name
+func LoadCustomPhases() (map[models.Phase]*CustomPhase, error) {
+	var configured map[string]*CustomPhase
+	if err := viper.UnmarshalKey("phases.custom", &configured); err != nil {
+		return nil, fmt.Errorf("invalid phases.custom: %w", err)
+	}
+
+	names := make([]string, 0, len(configured))
+	for name := range configured {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	custom := make(map[models.Phase]*CustomPhase, len(configured))
+	for _, name := range names {
+		phase := configured[name]
+		if phase == nil {
+			phase = &CustomPhase{}
+		}
+		phase.Name = models.Phase(name)
+		if err := phase.init(); err != nil {
+			return nil, fmt.Errorf("custom phase %q: %w", name, err)
+		}
+		custom[phase.Name] = phase
+	}
+	return custom, nil
+}
+
+// init validates the phase and parses its templates
+func (c *CustomPhase) init() error {
+	if !customPhaseName.MatchString(string(c.Name)) {
+		return fmt.Errorf("name must be lowercase letters, digits, '-' or '_'")
+	}
+	if IsBuiltin(c.Name) {
+		return fmt.Errorf("name is taken by a built-in phase")
+	}
+	if c.Template == "" {
+		return fmt.Errorf("template is required")
+	}
+	if c.Temperature < 0 || c.Temperature > 2 {
+		return fmt.Errorf("temperature must be between 0 and 2, got %g", c.Temperature)
+	}
+
+	var err error
+	if c.content, err = template.New(string(c.Name)).Parse(c.Template); err != nil {
+		return fmt.Errorf("invalid template: %w", err)
+	}
+	if c.SystemPrompt != "" {
+		if c.system, err = template.New(string(c.Name) + "_system").Parse(c.SystemPrompt); err != nil {
+			return fmt.Errorf("invalid system_prompt: %w", err)
+		}
+	}
+	return nil
+}
+
+func (c *CustomPhase) GetTemplate() string {
+	return string(c.Name)
+}
+
+func (c *CustomPhase) BuildSystemPrompt(opts models.GenerateOptions) string {
+	if c.system == nil {
+		return ""
+	}
+	systemPrompt, err := templates.ExecuteTemplate(c.system, c.context("", opts))
+	if err != nil {
+		return c.SystemPrompt
+	}
+	return systemPrompt
+}
+
+func (c *CustomPhase) PreparePromptContent(input string, opts models.GenerateOptions) string {
+	content, err := templates.ExecuteTemplate(c.content, c.context(input, opts))
+	if err != nil {
+		// Fallback to the bare input if template execution fails
+		return input
+	}
+	return content
+}
+
+// context builds the data the phase's templates are executed with
+func (c *CustomPhase) context(input string, opts models.GenerateOptions) *templates.PhaseContext {
+	return &templates.PhaseContext{
+		Input:       opts.Request.Input,
+		Prompt:      input,
+		Context:     opts.Request.Context,
+		Persona:     opts.Persona,
+		TargetModel: opts.TargetModel,
+		Phase:       string(c.Name),
+	}
+}
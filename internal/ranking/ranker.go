@@ -34,6 +34,9 @@ type Ranker struct {
 	embedModel    string
 	embedProvider string
 
+	// second-pass relevance scoring, see RerankWithProvider
+	scorer RelevanceScorer
+
 	// for hot-reload
 	weightsMutex sync.RWMutex
 	watcher      *fsnotify.Watcher
@@ -62,6 +65,22 @@ const (
 	MaxPreferredTokenLen = 2000
 )
 
+// Names of the factors in an explained ranking's breakdown
+const (
+	FactorTemperature = "temperature"
+	FactorToken       = "token"
+	FactorSemantic    = "semantic"
+	FactorLength      = "length"
+	FactorRerank      = "rerank"
+)
+
+// RankOptions adjusts how RankPromptsWithOptions ranks prompts
+type RankOptions struct {
+	// Explain fills each ranking's Breakdown with the factors its score
+	// is made of
+	Explain bool
+}
+
 // NewRanker creates a new ranker instance
 // registry is required so we can obtain an embedding-capable provider for
 // semantic similarity calculations.
@@ -73,6 +92,8 @@ func NewRanker(storage storage.StorageInterface, registry providers.RegistryInte
 	viper.SetDefault(WeightLengthKey, DefaultWeightLength)
 	viper.SetDefault(EmbeddingModelKey, "text-embedding-3-small")
 	viper.SetDefault(EmbeddingProviderKey, "openai")
+	viper.SetDefault(RerankTopKKey, DefaultRerankTopK)
+	viper.SetDefault(RerankWeightKey, DefaultRerankWeight)
 
 	weights := loadWeights()
 	normalizedWeights := normalizeWeights(weights)
@@ -225,13 +246,23 @@ func (r *Ranker) Close() error {
 	return nil
 }
 
-// RankPrompts ranks prompts based on multiple factors
+// RankPrompts ranks prompts based on multiple factors. With
+// ranking.rerank.enabled, the top ranked prompts are then reranked by
+// RerankWithProvider.
 func (r *Ranker) RankPrompts(ctx context.Context, prompts []models.Prompt, originalInput string) ([]models.PromptRanking, error) {
+	return r.RankPromptsWithOptions(ctx, prompts, originalInput, RankOptions{})
+}
+
+// RankPromptsWithOptions ranks prompts like RankPrompts, adjusted by opts
+func (r *Ranker) RankPromptsWithOptions(ctx context.Context, prompts []models.Prompt, originalInput string, opts RankOptions) ([]models.PromptRanking, error) {
 	r.logger.Infof("Ranking %d prompts", len(prompts))
 	rankings := make([]models.PromptRanking, 0, len(prompts))
 
 	for i := range prompts {
 		ranking := r.calculateRanking(ctx, &prompts[i], originalInput)
+		if opts.Explain {
+			ranking.Breakdown = r.breakdown(ranking)
+		}
 		rankings = append(rankings, ranking)
 	}
 
@@ -240,6 +271,10 @@ func (r *Ranker) RankPrompts(ctx context.Context, prompts []models.Prompt, origi
 		return rankings[i].Score > rankings[j].Score
 	})
 
+	if viper.GetBool(RerankEnabledKey) {
+		rankings = r.RerankWithProvider(ctx, rankings, originalInput)
+	}
+
 	r.logger.Info("Finished ranking prompts")
 	return rankings, nil
 }
@@ -288,6 +323,23 @@ func (r *Ranker) calculateRanking(ctx context.Context, prompt *models.Prompt, or
 	}
 }
 
+// breakdown lists the weighted factors of a heuristic ranking's score
+func (r *Ranker) breakdown(ranking models.PromptRanking) []models.ScoreFactor {
+	r.weightsMutex.RLock()
+	defer r.weightsMutex.RUnlock()
+
+	factors := []models.ScoreFactor{
+		{Name: FactorTemperature, Score: ranking.TemperatureScore, Weight: r.tempWeight},
+		{Name: FactorToken, Score: ranking.TokenScore, Weight: r.tokenWeight},
+		{Name: FactorSemantic, Score: ranking.SemanticScore, Weight: r.semanticWeight},
+		{Name: FactorLength, Score: ranking.LengthScore, Weight: r.lengthWeight},
+	}
+	for i := range factors {
+		factors[i].Contribution = factors[i].Score * factors[i].Weight
+	}
+	return factors
+}
+
 // calculateLengthRatio returns a [0,1] similarity based on text lengths.
 func (r *Ranker) calculateLengthRatio(text1, text2 string) float64 {
 	len1 := float64(len(text1))
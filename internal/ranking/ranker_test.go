@@ -2,13 +2,16 @@ package ranking
 
 import (
 	"context"
+	"strings"
 	"testing"
 
 	"github.com/google/uuid"
 	"github.com/jonwraymond/prompt-alchemy/pkg/models"
 	"github.com/jonwraymond/prompt-alchemy/pkg/providers"
 	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCalculateSemanticSimilarity(t *testing.T) {
@@ -90,3 +93,80 @@ func TestRankPrompts(t *testing.T) {
 	assert.True(t, rankings[0].SemanticScore > rankings[1].SemanticScore) // "similar" ranks higher
 	assert.Equal(t, "similar", rankings[0].Prompt.Content)                // Verify sorting
 }
+
+func TestRankPromptsExplain(t *testing.T) {
+	defer viper.Reset()
+	mockProv := new(providers.MockProvider)
+	mockProv.SupportsEmbeddingsFunc = func() bool { return true }
+	mockProv.GetEmbeddingFunc = func(ctx context.Context, text string, registry providers.RegistryInterface) ([]float32, error) {
+		if text == "original" {
+			return []float32{1, 0}, nil
+		}
+		return []float32{float32(len(text)), 1}, nil
+	}
+	registry := providers.NewRegistry()
+	registry.Register("openai", mockProv)
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	r := NewRanker(nil, registry, logger)
+	r.embedProvider = "openai"
+
+	prompts := []models.Prompt{
+		{ID: uuid.New(), Content: "short", Temperature: 0.2},
+		{ID: uuid.New(), Content: strings.Repeat("a moderately long prompt ", 10), Temperature: 0.7},
+		{ID: uuid.New(), Content: strings.Repeat("x", 3000), Temperature: 1.1},
+	}
+	assertSums := func(t *testing.T, rankings []models.PromptRanking, factors int) {
+		t.Helper()
+		for _, ranking := range rankings {
+			require.Len(t, ranking.Breakdown, factors)
+			sum, weights := 0.0, 0.0
+			for _, factor := range ranking.Breakdown {
+				assert.InDelta(t, factor.Score*factor.Weight, factor.Contribution, 1e-9, factor.Name)
+				sum += factor.Contribution
+				weights += factor.Weight
+			}
+			assert.InDelta(t, ranking.Score, sum, 1e-9)
+			assert.InDelta(t, 1.0, weights, 1e-9)
+		}
+	}
+
+	t.Run("lean by default", func(t *testing.T) {
+		rankings, err := r.RankPrompts(context.Background(), prompts, "original")
+		require.NoError(t, err)
+		for _, ranking := range rankings {
+			assert.Nil(t, ranking.Breakdown)
+		}
+	})
+
+	t.Run("breakdown sums to the score", func(t *testing.T) {
+		rankings, err := r.RankPromptsWithOptions(context.Background(), prompts, "original", RankOptions{Explain: true})
+		require.NoError(t, err)
+		require.Len(t, rankings, 3)
+		assertSums(t, rankings, 4)
+
+		names := make([]string, 0, 4)
+		for _, factor := range rankings[0].Breakdown {
+			names = append(names, factor.Name)
+		}
+		assert.Equal(t, []string{FactorTemperature, FactorToken, FactorSemantic, FactorLength}, names)
+	})
+
+	t.Run("reranking adds its share", func(t *testing.T) {
+		viper.Set(RerankEnabledKey, true)
+		viper.Set(RerankTopKKey, 2)
+		viper.Set(RerankWeightKey, 0.4)
+		r.SetRelevanceScorer(fixedScorer{prompts[0].Content: 0.3, prompts[1].Content: 0.9, prompts[2].Content: 0.6})
+
+		rankings, err := r.RankPromptsWithOptions(context.Background(), prompts, "original", RankOptions{Explain: true})
+		require.NoError(t, err)
+		assertSums(t, rankings[:2], 5)
+		assertSums(t, rankings[2:], 4)
+
+		rerank := rankings[0].Breakdown[4]
+		assert.Equal(t, FactorRerank, rerank.Name)
+		assert.InDelta(t, 0.4, rerank.Weight, 1e-9)
+		assert.Equal(t, rankings[0].RerankScore, rerank.Score)
+	})
+}
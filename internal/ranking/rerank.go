@@ -0,0 +1,225 @@
+package ranking
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/jonwraymond/prompt-alchemy/pkg/providers"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// Configuration keys for the rerank stage
+const (
+	RerankEnabledKey  = "ranking.rerank.enabled"
+	RerankTopKKey     = "ranking.rerank.top_k"
+	RerankWeightKey   = "ranking.rerank.weight"
+	RerankProviderKey = "ranking.rerank.provider"
+
+	// Default rerank values
+	DefaultRerankTopK   = 5
+	DefaultRerankWeight = 0.5
+)
+
+// RelevanceScorer rates how well a prompt serves the original input, from 0
+// (irrelevant) to 1 (ideal)
+type RelevanceScorer interface {
+	ScoreRelevance(ctx context.Context, input, prompt string) (float64, error)
+}
+
+// batchRelevanceScorer is implemented by scorers that rate many prompts in
+// one request, such as a provider's rerank endpoint
+type batchRelevanceScorer interface {
+	ScoreRelevanceBatch(ctx context.Context, input string, prompts []string) ([]float64, error)
+}
+
+// SetRelevanceScorer sets the scorer used by RerankWithProvider. Without
+// one, a provider from the registry is asked to score each prompt.
+func (r *Ranker) SetRelevanceScorer(scorer RelevanceScorer) {
+	r.scorer = scorer
+}
+
+// RerankWithProvider re-scores the top ranked prompts by comparing each
+// against the original input, blending that relevance with the original
+// score by ranking.rerank.weight, and re-sorts them. Rankings below
+// ranking.rerank.top_k keep their scores and positions. Without a scorer or
+// an available provider the rankings are returned unchanged, as are prompts
+// whose scoring fails. A provider with a rerank endpoint scores them all in
+// one request.
+func (r *Ranker) RerankWithProvider(ctx context.Context, rankings []models.PromptRanking, originalInput string) []models.PromptRanking {
+	topK := viper.GetInt(RerankTopKKey)
+	if topK <= 0 {
+		topK = DefaultRerankTopK
+	}
+	topK = min(topK, len(rankings))
+	if topK < 2 {
+		return rankings
+	}
+	weight := max(0, min(viper.GetFloat64(RerankWeightKey), 1))
+
+	scorer := r.relevanceScorer()
+	if scorer == nil {
+		r.logger.Warn("No provider available for reranking, keeping heuristic ranking")
+		return rankings
+	}
+
+	blend := func(i int, relevance float64) {
+		rankings[i].RerankScore = relevance
+		rankings[i].Score = (1-weight)*rankings[i].Score + weight*relevance
+		if rankings[i].Breakdown != nil {
+			rankings[i].Breakdown = blendBreakdown(rankings[i].Breakdown, relevance, weight)
+		}
+	}
+	if batch, ok := scorer.(batchRelevanceScorer); ok {
+		prompts := make([]string, topK)
+		for i := range prompts {
+			prompts[i] = rankings[i].Prompt.Content
+		}
+		scores, err := batch.ScoreRelevanceBatch(ctx, originalInput, prompts)
+		if err != nil {
+			r.logger.WithError(err).Warn("Failed to rerank prompts, keeping heuristic ranking")
+			return rankings
+		}
+		for i, relevance := range scores {
+			blend(i, relevance)
+		}
+	} else {
+		for i := range rankings[:topK] {
+			relevance, err := scorer.ScoreRelevance(ctx, originalInput, rankings[i].Prompt.Content)
+			if err != nil {
+				r.logger.WithError(err).WithField("prompt_id", rankings[i].Prompt.ID).Warn("Failed to rerank prompt, keeping its score")
+				continue
+			}
+			blend(i, relevance)
+		}
+	}
+
+	sort.SliceStable(rankings[:topK], func(i, j int) bool {
+		return rankings[i].Score > rankings[j].Score
+	})
+
+	r.logger.WithFields(logrus.Fields{
+		"top_k":  topK,
+		"weight": weight,
+	}).Debug("Reranked top prompts")
+	return rankings
+}
+
+// blendBreakdown scales an explained ranking's factors by the share the
+// rerank stage leaves them and adds the relevance as a factor, so they
+// still sum to the blended score
+func blendBreakdown(factors []models.ScoreFactor, relevance, weight float64) []models.ScoreFactor {
+	for i := range factors {
+		factors[i].Weight *= 1 - weight
+		factors[i].Contribution *= 1 - weight
+	}
+	return append(factors, models.ScoreFactor{
+		Name:         FactorRerank,
+		Score:        relevance,
+		Weight:       weight,
+		Contribution: relevance * weight,
+	})
+}
+
+// relevanceScorer returns the configured scorer, or one backed by
+// ranking.rerank.provider, generation.default_provider or the first
+// available provider, in that order. Providers with a rerank endpoint use
+// it. It returns nil if none is available.
+func (r *Ranker) relevanceScorer() RelevanceScorer {
+	if r.scorer != nil {
+		return r.scorer
+	}
+	if r.registry == nil {
+		return nil
+	}
+
+	candidates := []string{viper.GetString(RerankProviderKey), viper.GetString("generation.default_provider")}
+	available := r.registry.ListAvailable()
+	sort.Strings(available)
+	candidates = append(candidates, available...)
+	for _, name := range candidates {
+		if name == "" {
+			continue
+		}
+		if provider, err := r.registry.Get(name); err == nil && provider.IsAvailable() {
+			if reranker, ok := providers.AsReranker(provider); ok {
+				return &rerankerScorer{reranker: reranker}
+			}
+			return &providerScorer{provider: provider}
+		}
+	}
+	return nil
+}
+
+// providerScorer asks an LLM to rate a prompt's relevance out of 10
+type providerScorer struct {
+	provider providers.Provider
+}
+
+const rerankPrompt = `Rate how well the prompt below serves the user's request, from 0 (irrelevant) to 10 (ideal).
+Answer with the number only.
+
+Request:
+%s
+
+Prompt:
+%s`
+
+var scorePattern = regexp.MustCompile(`\d+(\.\d+)?`)
+
+func (s *providerScorer) ScoreRelevance(ctx context.Context, input, prompt string) (float64, error) {
+	response, err := s.provider.Generate(ctx, providers.GenerateRequest{
+		Prompt:      fmt.Sprintf(rerankPrompt, input, prompt),
+		Temperature: 0.0, // Deterministic scoring
+		MaxTokens:   10,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get relevance score from %s: %w", s.provider.Name(), err)
+	}
+	return parseRelevanceScore(response.Content)
+}
+
+// rerankerScorer scores prompts with a provider's rerank endpoint
+type rerankerScorer struct {
+	reranker providers.Reranker
+}
+
+func (s *rerankerScorer) ScoreRelevance(ctx context.Context, input, prompt string) (float64, error) {
+	scores, err := s.ScoreRelevanceBatch(ctx, input, []string{prompt})
+	if err != nil {
+		return 0, err
+	}
+	return scores[0], nil
+}
+
+func (s *rerankerScorer) ScoreRelevanceBatch(ctx context.Context, input string, prompts []string) ([]float64, error) {
+	scores, err := s.reranker.Rerank(ctx, input, prompts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rerank with %s: %w", s.reranker.Name(), err)
+	}
+	if len(scores) != len(prompts) {
+		return nil, fmt.Errorf("%s returned %d scores for %d prompts", s.reranker.Name(), len(scores), len(prompts))
+	}
+	for i := range scores {
+		scores[i] = max(0, min(scores[i], 1))
+	}
+	return scores, nil
+}
+
+// parseRelevanceScore reads the first number in an LLM's answer as a score
+// out of 10 and scales it to [0, 1]
+func parseRelevanceScore(content string) (float64, error) {
+	match := scorePattern.FindString(content)
+	if match == "" {
+		return 0, fmt.Errorf("no score in response %q", content)
+	}
+	score, err := strconv.ParseFloat(match, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid score %q: %w", match, err)
+	}
+	return max(0, min(score, 10)) / 10, nil
+}
@@ -0,0 +1,147 @@
+package ranking
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/jonwraymond/prompt-alchemy/pkg/providers"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fixedScorer scores each prompt by its content, failing for unknown ones
+type fixedScorer map[string]float64
+
+func (s fixedScorer) ScoreRelevance(ctx context.Context, input, prompt string) (float64, error) {
+	score, ok := s[prompt]
+	if !ok {
+		return 0, errors.New("unscored prompt")
+	}
+	return score, nil
+}
+
+func TestRerankWithProvider(t *testing.T) {
+	defer viper.Reset()
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	rankings := func() []models.PromptRanking {
+		var out []models.PromptRanking
+		for _, r := range []struct {
+			content string
+			score   float64
+		}{{"a", 0.9}, {"b", 0.8}, {"c", 0.7}, {"d", 0.6}, {"e", 0.1}} {
+			out = append(out, models.PromptRanking{Prompt: &models.Prompt{Content: r.content}, Score: r.score})
+		}
+		return out
+	}
+	contents := func(rankings []models.PromptRanking) []string {
+		var out []string
+		for _, r := range rankings {
+			out = append(out, r.Prompt.Content)
+		}
+		return out
+	}
+
+	r := &Ranker{logger: logger}
+	r.SetRelevanceScorer(fixedScorer{"a": 0.1, "b": 0.5, "c": 1.0, "d": 0.0, "e": 1.0})
+	viper.Set(RerankTopKKey, 3)
+	viper.Set(RerankWeightKey, 0.5)
+
+	t.Run("blends and reorders only the top k", func(t *testing.T) {
+		reranked := r.RerankWithProvider(context.Background(), rankings(), "input")
+
+		assert.Equal(t, []string{"c", "b", "a", "d", "e"}, contents(reranked))
+		assert.InDelta(t, 0.85, reranked[0].Score, 1e-9)
+		assert.InDelta(t, 0.65, reranked[1].Score, 1e-9)
+		assert.InDelta(t, 0.5, reranked[2].Score, 1e-9)
+		assert.Equal(t, 1.0, reranked[0].RerankScore)
+		assert.Equal(t, 0.6, reranked[3].Score, "below top k is left alone")
+		assert.Zero(t, reranked[4].RerankScore, "below top k is not scored")
+	})
+
+	t.Run("failed scores keep the original score", func(t *testing.T) {
+		r := &Ranker{logger: logger}
+		r.SetRelevanceScorer(fixedScorer{"a": 0.0})
+		reranked := r.RerankWithProvider(context.Background(), rankings(), "input")
+
+		assert.Equal(t, []string{"b", "c", "a", "d", "e"}, contents(reranked))
+		assert.Equal(t, 0.8, reranked[0].Score)
+	})
+
+	t.Run("weight zero keeps the heuristic ranking", func(t *testing.T) {
+		viper.Set(RerankWeightKey, 0.0)
+		defer viper.Set(RerankWeightKey, 0.5)
+
+		reranked := r.RerankWithProvider(context.Background(), rankings(), "input")
+		assert.Equal(t, []string{"a", "b", "c", "d", "e"}, contents(reranked))
+	})
+
+	t.Run("skipped without a provider", func(t *testing.T) {
+		r := &Ranker{logger: logger, registry: providers.NewRegistry()}
+		reranked := r.RerankWithProvider(context.Background(), rankings(), "input")
+		assert.Equal(t, rankings(), reranked)
+	})
+}
+
+// fakeReranker scores documents by content in one call, counting calls
+type fakeReranker struct {
+	providers.Provider
+	scores map[string]float64
+	calls  int
+}
+
+func (p *fakeReranker) Name() string      { return "reranker" }
+func (p *fakeReranker) IsAvailable() bool { return true }
+
+func (p *fakeReranker) Rerank(ctx context.Context, query string, docs []string) ([]float64, error) {
+	p.calls++
+	scores := make([]float64, len(docs))
+	for i, doc := range docs {
+		scores[i] = p.scores[doc]
+	}
+	return scores, nil
+}
+
+func TestRerankWithReranker(t *testing.T) {
+	defer viper.Reset()
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	viper.Set(RerankTopKKey, 3)
+	viper.Set(RerankWeightKey, 1.0)
+	viper.Set(RerankProviderKey, "reranker")
+
+	reranker := &fakeReranker{scores: map[string]float64{"a": 0.2, "b": 0.9, "c": 0.5, "d": 1.0}}
+	registry := providers.NewRegistry()
+	require.NoError(t, registry.Register("reranker", reranker))
+	r := &Ranker{logger: logger, registry: registry}
+
+	var rankings []models.PromptRanking
+	for _, content := range []string{"a", "b", "c", "d"} {
+		rankings = append(rankings, models.PromptRanking{Prompt: &models.Prompt{Content: content}, Score: 0.5})
+	}
+	reranked := r.RerankWithProvider(context.Background(), rankings, "input")
+
+	assert.Equal(t, 1, reranker.calls, "the top k are scored in one request")
+	var contents []string
+	for _, ranking := range reranked {
+		contents = append(contents, ranking.Prompt.Content)
+	}
+	assert.Equal(t, []string{"b", "c", "a", "d"}, contents)
+	assert.Equal(t, 0.9, reranked[0].RerankScore)
+	assert.Zero(t, reranked[3].RerankScore, "below top k is not scored")
+}
+
+func TestParseRelevanceScore(t *testing.T) {
+	for content, want := range map[string]float64{"8": 0.8, "Score: 7.5/10": 0.75, "12": 1, "0": 0} {
+		score, err := parseRelevanceScore(content)
+		require.NoError(t, err, content)
+		assert.InDelta(t, want, score, 1e-9, content)
+	}
+	_, err := parseRelevanceScore("not sure")
+	assert.Error(t, err)
+}
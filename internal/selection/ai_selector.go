@@ -2,11 +2,15 @@ package selection
 
 import (
 	"bytes"
+	"cmp"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -37,6 +41,12 @@ type SelectionCriteria struct {
 	EvaluationModel    string
 	EvaluationProvider string
 	Weights            EvaluationWeights
+
+	// JudgeProviders, when it names more than one available provider, has
+	// each of them evaluate the prompts and combines their scores. Judges
+	// are weighted by JudgeWeights, defaulting to 1.
+	JudgeProviders []string
+	JudgeWeights   map[string]float64
 }
 
 // EvaluationWeights defines weights for different evaluation factors
@@ -62,6 +72,12 @@ type AISelectionResult struct {
 	Confidence     float64
 	Scores         []EvaluationScore
 	ProcessingTime int64
+
+	// Judges lists the providers whose evaluations were combined, and
+	// ConsensusRate the weighted share of them whose top choice was the
+	// selected prompt. A single judge always agrees with itself.
+	Judges        []string
+	ConsensusRate float64
 }
 
 // EvaluationScore holds the detailed scores for a prompt
@@ -74,7 +90,9 @@ type EvaluationScore struct {
 	ErrorMessage string             `json:"error_message,omitempty"`
 }
 
-// Select uses an LLM to select the best prompt from a list
+// Select uses an LLM to select the best prompt from a list. With more than
+// one available judge in JudgeProviders, every judge evaluates the prompts
+// and the selection is made on their weighted scores.
 func (s *AISelector) Select(ctx context.Context, prompts []models.Prompt, criteria SelectionCriteria) (*AISelectionResult, error) {
 	startTime := time.Now()
 	logger := log.GetLogger()
@@ -84,11 +102,6 @@ func (s *AISelector) Select(ctx context.Context, prompts []models.Prompt, criter
 		return nil, fmt.Errorf("no prompts provided for selection")
 	}
 
-	provider, err := s.registry.Get(criteria.EvaluationProvider)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get evaluation provider: %w", err)
-	}
-
 	systemPrompt, err := s.buildSelectionPrompt(criteria)
 	if err != nil {
 		return nil, fmt.Errorf("failed to build selection prompt: %w", err)
@@ -103,6 +116,90 @@ func (s *AISelector) Select(ctx context.Context, prompts []models.Prompt, criter
 		Temperature:  0.2,  // Low temperature for deterministic scoring
 	}
 
+	judges := s.availableJudges(criteria.JudgeProviders)
+	if len(judges) < 2 {
+		judge := criteria.EvaluationProvider
+		if len(judges) == 1 {
+			judge = judges[0]
+		}
+		scores, err := s.evaluate(ctx, judge, req)
+		if err != nil {
+			return nil, err
+		}
+		return s.result(prompts, scores, []judgeScores{{judge, 1, scores}}, startTime)
+	}
+
+	logger.WithField("judges", judges).Info("Evaluating prompts with multiple judges")
+	results := make([]judgeScores, len(judges))
+	errs := make([]error, len(judges))
+	var wg sync.WaitGroup
+	for i, judge := range judges {
+		wg.Add(1)
+		go func(i int, judge string) {
+			defer wg.Done()
+			results[i].provider = judge
+			results[i].weight = judgeWeight(criteria.JudgeWeights, judge)
+			results[i].scores, errs[i] = s.evaluate(ctx, judge, req)
+		}(i, judge)
+	}
+	wg.Wait()
+
+	// A judge that fails is left out rather than failing the selection
+	succeeded := results[:0]
+	for i, result := range results {
+		if errs[i] != nil {
+			logger.WithError(errs[i]).WithField("judge", result.provider).Warn("Judge evaluation failed, continuing without it")
+			continue
+		}
+		succeeded = append(succeeded, result)
+	}
+	if len(succeeded) == 0 {
+		return nil, fmt.Errorf("all judges failed: %w", errors.Join(errs...))
+	}
+
+	return s.result(prompts, combineScores(succeeded), succeeded, startTime)
+}
+
+// judgeScores is one judge's evaluation of the prompts
+type judgeScores struct {
+	provider string
+	weight   float64
+	scores   []EvaluationScore
+}
+
+// availableJudges returns the registered, available providers among names,
+// without duplicates
+func (s *AISelector) availableJudges(names []string) []string {
+	var judges []string
+	for _, name := range names {
+		if slices.Contains(judges, name) {
+			continue
+		}
+		provider, err := s.registry.Get(name)
+		if err != nil || !provider.IsAvailable() {
+			log.GetLogger().WithField("judge", name).Warn("Judge provider is not available, skipping it")
+			continue
+		}
+		judges = append(judges, name)
+	}
+	return judges
+}
+
+// judgeWeight returns a judge's weight, 1 unless configured otherwise
+func judgeWeight(weights map[string]float64, judge string) float64 {
+	if weight, ok := weights[judge]; ok && weight > 0 {
+		return weight
+	}
+	return 1
+}
+
+// evaluate has one judge score the prompts
+func (s *AISelector) evaluate(ctx context.Context, judge string, req providers.GenerateRequest) ([]EvaluationScore, error) {
+	provider, err := s.registry.Get(judge)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get evaluation provider: %w", err)
+	}
+
 	resp, err := provider.Generate(ctx, req)
 	if err != nil {
 		return nil, fmt.Errorf("AI selection generation failed: %w", err)
@@ -116,9 +213,78 @@ func (s *AISelector) Select(ctx context.Context, prompts []models.Prompt, criter
 	if len(scores) == 0 {
 		return nil, fmt.Errorf("AI selection returned no scores")
 	}
+	return scores, nil
+}
 
+// combineScores averages the judges' scores for each prompt, weighted by
+// judge. A prompt's average only counts the judges that scored it.
+func combineScores(judges []judgeScores) []EvaluationScore {
+	type total struct {
+		score      EvaluationScore
+		weight     float64
+		subWeights map[string]float64
+		reasoning  []string
+	}
+	var order []uuid.UUID
+	totals := map[uuid.UUID]*total{}
+	for _, judge := range judges {
+		for _, score := range judge.scores {
+			t, ok := totals[score.PromptID]
+			if !ok {
+				t = &total{score: EvaluationScore{PromptID: score.PromptID}, subWeights: map[string]float64{}}
+				totals[score.PromptID] = t
+				order = append(order, score.PromptID)
+			}
+			t.weight += judge.weight
+			t.score.Score += score.Score * judge.weight
+			t.score.Confidence += score.Confidence * judge.weight
+			for name, sub := range score.SubScores {
+				if t.score.SubScores == nil {
+					t.score.SubScores = map[string]float64{}
+				}
+				t.score.SubScores[name] += sub * judge.weight
+				t.subWeights[name] += judge.weight
+			}
+			if score.Reasoning != "" {
+				t.reasoning = append(t.reasoning, judge.provider+": "+score.Reasoning)
+			}
+		}
+	}
+
+	combined := make([]EvaluationScore, 0, len(order))
+	for _, id := range order {
+		t := totals[id]
+		t.score.Score /= t.weight
+		t.score.Confidence /= t.weight
+		for name := range t.score.SubScores {
+			t.score.SubScores[name] /= t.subWeights[name]
+		}
+		t.score.Reasoning = strings.Join(t.reasoning, "\n")
+		combined = append(combined, t.score)
+	}
+	return combined
+}
+
+// consensusRate returns the weighted share of judges whose highest scored
+// prompt is selected
+func consensusRate(judges []judgeScores, selected uuid.UUID) float64 {
+	var agreeing, total float64
+	for _, judge := range judges {
+		total += judge.weight
+		best := slices.MaxFunc(judge.scores, func(a, b EvaluationScore) int {
+			return cmp.Compare(a.Score, b.Score)
+		})
+		if best.PromptID == selected {
+			agreeing += judge.weight
+		}
+	}
+	return agreeing / total
+}
+
+// result selects the prompt with the highest of scores
+func (s *AISelector) result(prompts []models.Prompt, scores []EvaluationScore, judges []judgeScores, startTime time.Time) (*AISelectionResult, error) {
 	// Find the best prompt based on the highest score
-	sort.Slice(scores, func(i, j int) bool {
+	sort.SliceStable(scores, func(i, j int) bool {
 		return scores[i].Score > scores[j].Score
 	})
 
@@ -137,12 +303,19 @@ func (s *AISelector) Select(ctx context.Context, prompts []models.Prompt, criter
 
 	processingTime := time.Since(startTime).Milliseconds()
 
+	names := make([]string, len(judges))
+	for i, judge := range judges {
+		names[i] = judge.provider
+	}
+
 	return &AISelectionResult{
 		SelectedPrompt: selectedPrompt,
 		Reasoning:      bestScore.Reasoning,
 		Confidence:     bestScore.Confidence,
 		Scores:         scores,
 		ProcessingTime: processingTime,
+		Judges:         names,
+		ConsensusRate:  consensusRate(judges, bestScore.PromptID),
 	}, nil
 }
 
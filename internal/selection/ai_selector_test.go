@@ -2,7 +2,10 @@ package selection
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jonwraymond/prompt-alchemy/pkg/models"
@@ -11,9 +14,42 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+// mockJudge is a provider whose evaluations come from GenerateFunc
+type mockJudge struct {
+	GenerateFunc func(ctx context.Context, req providers.GenerateRequest) (*providers.GenerateResponse, error)
+	unavailable  bool
+}
+
+func (m *mockJudge) Generate(ctx context.Context, req providers.GenerateRequest) (*providers.GenerateResponse, error) {
+	return m.GenerateFunc(ctx, req)
+}
+
+func (m *mockJudge) GetEmbedding(ctx context.Context, text string, registry providers.RegistryInterface) ([]float32, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockJudge) Name() string                                    { return "mock" }
+func (m *mockJudge) IsAvailable() bool                               { return !m.unavailable }
+func (m *mockJudge) SupportsEmbeddings() bool                        { return false }
+func (m *mockJudge) SupportsStreaming() bool                         { return false }
+func (m *mockJudge) Ping(ctx context.Context) (time.Duration, error) { return 0, nil }
+
+// judgeReturning returns a judge scoring each prompt with the given score
+func judgeReturning(t *testing.T, prompts []models.Prompt, scores ...float64) *mockJudge {
+	evaluations := make([]EvaluationScore, len(prompts))
+	for i, p := range prompts {
+		evaluations[i] = EvaluationScore{PromptID: p.ID, Score: scores[i], Confidence: scores[i], Reasoning: "scored"}
+	}
+	content, err := json.Marshal(evaluations)
+	require.NoError(t, err)
+	return &mockJudge{GenerateFunc: func(ctx context.Context, req providers.GenerateRequest) (*providers.GenerateResponse, error) {
+		return &providers.GenerateResponse{Content: string(content)}, nil
+	}}
+}
+
 func TestAISelector_Select(t *testing.T) {
 	registry := providers.NewRegistry()
-	mockProv := new(providers.MockProvider)
+	mockProv := new(mockJudge)
 	_ = registry.Register("mock", mockProv)
 
 	selector := NewAISelector(registry)
@@ -42,6 +78,88 @@ func TestAISelector_Select(t *testing.T) {
 
 }
 
+func TestAISelector_SelectConsensus(t *testing.T) {
+	prompts := []models.Prompt{
+		{ID: uuid.New(), Content: "Prompt 1"},
+		{ID: uuid.New(), Content: "Prompt 2"},
+	}
+	selectWith := func(t *testing.T, judges map[string]*mockJudge, criteria SelectionCriteria) *AISelectionResult {
+		registry := providers.NewRegistry()
+		for name, judge := range judges {
+			require.NoError(t, registry.Register(name, judge))
+		}
+		criteria.TaskDescription = "Test task"
+		result, err := NewAISelector(registry).Select(context.Background(), prompts, criteria)
+		require.NoError(t, err)
+		return result
+	}
+
+	t.Run("judges agree", func(t *testing.T) {
+		result := selectWith(t, map[string]*mockJudge{
+			"first":  judgeReturning(t, prompts, 0.9, 0.5),
+			"second": judgeReturning(t, prompts, 0.7, 0.3),
+		}, SelectionCriteria{JudgeProviders: []string{"first", "second"}})
+
+		assert.Equal(t, prompts[0].ID, result.SelectedPrompt.ID)
+		assert.Equal(t, []string{"first", "second"}, result.Judges)
+		assert.Equal(t, 1.0, result.ConsensusRate)
+		require.Len(t, result.Scores, 2)
+		assert.InDelta(t, 0.8, result.Scores[0].Score, 1e-9)
+		assert.InDelta(t, 0.4, result.Scores[1].Score, 1e-9)
+		assert.InDelta(t, 0.8, result.Confidence, 1e-9)
+		assert.Equal(t, "first: scored\nsecond: scored", result.Reasoning)
+	})
+
+	t.Run("judges disagree", func(t *testing.T) {
+		judges := map[string]*mockJudge{
+			"first":  judgeReturning(t, prompts, 0.9, 0.6),
+			"second": judgeReturning(t, prompts, 0.2, 0.8),
+		}
+
+		// Equal weights: (0.9+0.2)/2 = 0.55 against (0.6+0.8)/2 = 0.7
+		result := selectWith(t, judges, SelectionCriteria{JudgeProviders: []string{"first", "second"}})
+		assert.Equal(t, prompts[1].ID, result.SelectedPrompt.ID)
+		assert.InDelta(t, 0.7, result.Scores[0].Score, 1e-9)
+		assert.Equal(t, 0.5, result.ConsensusRate)
+
+		// Weighted 3:1: (2.7+0.2)/4 = 0.725 against (1.8+0.8)/4 = 0.65
+		result = selectWith(t, judges, SelectionCriteria{
+			JudgeProviders: []string{"first", "second"},
+			JudgeWeights:   map[string]float64{"first": 3},
+		})
+		assert.Equal(t, prompts[0].ID, result.SelectedPrompt.ID)
+		assert.InDelta(t, 0.725, result.Scores[0].Score, 1e-9)
+		assert.Equal(t, 0.75, result.ConsensusRate)
+	})
+
+	t.Run("single available judge", func(t *testing.T) {
+		offline := judgeReturning(t, prompts, 0.1, 0.9)
+		offline.unavailable = true
+		result := selectWith(t, map[string]*mockJudge{
+			"first":   judgeReturning(t, prompts, 0.9, 0.5),
+			"offline": offline,
+		}, SelectionCriteria{JudgeProviders: []string{"first", "offline"}})
+
+		assert.Equal(t, prompts[0].ID, result.SelectedPrompt.ID)
+		assert.Equal(t, []string{"first"}, result.Judges)
+		assert.Equal(t, 1.0, result.ConsensusRate)
+		assert.Equal(t, "scored", result.Reasoning)
+	})
+
+	t.Run("failing judge is left out", func(t *testing.T) {
+		failing := &mockJudge{GenerateFunc: func(ctx context.Context, req providers.GenerateRequest) (*providers.GenerateResponse, error) {
+			return nil, errors.New("rate limited")
+		}}
+		result := selectWith(t, map[string]*mockJudge{
+			"first":   judgeReturning(t, prompts, 0.4, 0.5),
+			"failing": failing,
+		}, SelectionCriteria{JudgeProviders: []string{"first", "failing"}})
+
+		assert.Equal(t, prompts[1].ID, result.SelectedPrompt.ID)
+		assert.Equal(t, []string{"first"}, result.Judges)
+	})
+}
+
 // TestNormalizeWeights removed - normalizeWeights function doesn't exist
 
 // Add more tests for error cases, different personas, etc.
@@ -49,7 +167,7 @@ func TestAISelector_Select(t *testing.T) {
 // Benchmarks
 func BenchmarkSelect(b *testing.B) {
 	registry := providers.NewRegistry()
-	mockProv := new(providers.MockProvider)
+	mockProv := new(mockJudge)
 	_ = registry.Register("mock", mockProv)
 	selector := NewAISelector(registry)
 	prompts := []models.Prompt{{ID: uuid.New(), Content: "P1"}, {ID: uuid.New(), Content: "P2"}}
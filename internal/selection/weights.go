@@ -0,0 +1,105 @@
+package selection
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/spf13/viper"
+)
+
+// DefaultWeightPreset is used when a request names no scoring criteria or
+// one that isn't configured
+const DefaultWeightPreset = "comprehensive"
+
+// weightSumTolerance is how far a preset's weights may sum from 1.0
+const weightSumTolerance = 0.01
+
+// WeightPresets maps scoring criteria names to judge weights
+type WeightPresets map[string]EvaluationWeights
+
+// BuiltinWeightPresets returns the presets available without configuration
+func BuiltinWeightPresets() WeightPresets {
+	return WeightPresets{
+		"clarity":       {Relevance: 0.2, Clarity: 0.5, Completeness: 0.2, Conciseness: 0.1, Toxicity: 0.0},
+		"creativity":    {Relevance: 0.3, Clarity: 0.2, Completeness: 0.3, Conciseness: 0.1, Toxicity: 0.1},
+		"effectiveness": {Relevance: 0.4, Clarity: 0.3, Completeness: 0.2, Conciseness: 0.1, Toxicity: 0.0},
+		"comprehensive": {Relevance: 0.3, Clarity: 0.25, Completeness: 0.25, Conciseness: 0.15, Toxicity: 0.05},
+	}
+}
+
+// LoadWeightPresets returns the built-in presets merged with
+// generation.judge_weight_presets. Configured presets replace built-ins of
+// the same name or add new ones. Every preset is validated.
+func LoadWeightPresets() (WeightPresets, error) {
+	presets := BuiltinWeightPresets()
+
+	var configured map[string]EvaluationWeights
+	if err := viper.UnmarshalKey("generation.judge_weight_presets", &configured); err != nil {
+		return nil, fmt.Errorf("invalid generation.judge_weight_presets: %w", err)
+	}
+	for name, weights := range configured {
+		presets[name] = weights
+	}
+
+	if err := presets.Validate(); err != nil {
+		return nil, err
+	}
+	return presets, nil
+}
+
+// Validate checks every preset, reporting the first invalid one by name
+func (p WeightPresets) Validate() error {
+	for _, name := range p.Names() {
+		if err := p[name].Validate(); err != nil {
+			return fmt.Errorf("judge weight preset %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Names returns the preset names in sorted order
+func (p WeightPresets) Names() []string {
+	names := make([]string, 0, len(p))
+	for name := range p {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Get returns the named preset, falling back to DefaultWeightPreset. The
+// bool reports whether the name was found.
+func (p WeightPresets) Get(name string) (EvaluationWeights, bool) {
+	if weights, ok := p[name]; ok {
+		return weights, true
+	}
+	if weights, ok := p[DefaultWeightPreset]; ok {
+		return weights, false
+	}
+	return BuiltinWeightPresets()[DefaultWeightPreset], false
+}
+
+// Sum returns the total of all weights
+func (w EvaluationWeights) Sum() float64 {
+	return w.Relevance + w.Clarity + w.Completeness + w.Conciseness + w.Toxicity
+}
+
+// Validate checks that no weight is negative and that they sum to 1.0
+func (w EvaluationWeights) Validate() error {
+	for name, value := range map[string]float64{
+		"relevance":    w.Relevance,
+		"clarity":      w.Clarity,
+		"completeness": w.Completeness,
+		"conciseness":  w.Conciseness,
+		"toxicity":     w.Toxicity,
+	} {
+		if value < 0 {
+			return fmt.Errorf("%s weight must not be negative, got %g", name, value)
+		}
+	}
+	if sum := w.Sum(); math.Abs(sum-1.0) > weightSumTolerance {
+		return fmt.Errorf("weights must sum to 1.0, got %.3f", sum)
+	}
+	return nil
+}
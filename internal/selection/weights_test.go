@@ -0,0 +1,61 @@
+package selection
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuiltinWeightPresetsAreValid(t *testing.T) {
+	require.NoError(t, BuiltinWeightPresets().Validate())
+}
+
+func TestLoadWeightPresets(t *testing.T) {
+	defer viper.Reset()
+
+	t.Run("adds and overrides presets", func(t *testing.T) {
+		viper.Set("generation.judge_weight_presets", map[string]interface{}{
+			"legal":   map[string]interface{}{"relevance": 0.5, "completeness": 0.4, "toxicity": 0.1},
+			"clarity": map[string]interface{}{"clarity": 0.7, "relevance": 0.3},
+		})
+
+		presets, err := LoadWeightPresets()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"clarity", "comprehensive", "creativity", "effectiveness", "legal"}, presets.Names())
+		assert.Equal(t, EvaluationWeights{Relevance: 0.5, Completeness: 0.4, Toxicity: 0.1}, presets["legal"])
+		assert.Equal(t, 0.7, presets["clarity"].Clarity)
+	})
+
+	t.Run("rejects weights that don't sum to 1", func(t *testing.T) {
+		viper.Set("generation.judge_weight_presets", map[string]interface{}{
+			"lopsided": map[string]interface{}{"relevance": 0.9, "clarity": 0.9},
+		})
+
+		_, err := LoadWeightPresets()
+		assert.ErrorContains(t, err, `"lopsided"`)
+		assert.ErrorContains(t, err, "sum to 1.0")
+	})
+
+	t.Run("rejects negative weights", func(t *testing.T) {
+		viper.Set("generation.judge_weight_presets", map[string]interface{}{
+			"odd": map[string]interface{}{"relevance": 1.2, "toxicity": -0.2},
+		})
+
+		_, err := LoadWeightPresets()
+		assert.ErrorContains(t, err, "toxicity weight must not be negative")
+	})
+}
+
+func TestWeightPresetsGet(t *testing.T) {
+	presets := BuiltinWeightPresets()
+
+	weights, ok := presets.Get("creativity")
+	assert.True(t, ok)
+	assert.Equal(t, presets["creativity"], weights)
+
+	weights, ok = presets.Get("unknown")
+	assert.False(t, ok)
+	assert.Equal(t, presets[DefaultWeightPreset], weights)
+}
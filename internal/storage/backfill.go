@@ -0,0 +1,267 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jonwraymond/prompt-alchemy/internal/log"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// States reported in BackfillStatus
+const (
+	BackfillIdle      = "idle"
+	BackfillRunning   = "running"
+	BackfillCompleted = "completed"
+	BackfillFailed    = "failed"
+)
+
+// DefaultBackfillBatchSize is how many prompts a backfill embeds between
+// pauses when BackfillOptions leaves it unset
+const DefaultBackfillBatchSize = 20
+
+// ErrBackfillRunning is returned by StartBackfill while a backfill is in
+// progress
+var ErrBackfillRunning = errors.New("embedding backfill already in progress")
+
+// errAlreadyEmbedded skips a prompt that gained an embedding after the
+// backfill listed it
+var errAlreadyEmbedded = errors.New("prompt already embedded")
+
+// BackfillOptions configures an embedding backfill
+type BackfillOptions struct {
+	Embed      QueryEmbedder
+	Provider   string        // recorded as the prompts' embedding provider
+	Model      string        // recorded as the prompts' embedding model
+	BatchSize  int           // prompts embedded between pauses
+	BatchDelay time.Duration // pause between batches, to stay under provider rate limits
+}
+
+// LoadBackfillOptions reads embeddings.backfill_batch_size and
+// embeddings.backfill_batch_delay, recording generation.default_embedding_model
+// as the model. The caller supplies Embed and Provider.
+func LoadBackfillOptions() BackfillOptions {
+	return BackfillOptions{
+		Model:      viper.GetString("generation.default_embedding_model"),
+		BatchSize:  viper.GetInt("embeddings.backfill_batch_size"),
+		BatchDelay: viper.GetDuration("embeddings.backfill_batch_delay"),
+	}
+}
+
+// BackfillStatus reports the progress of the current or last embedding
+// backfill
+type BackfillStatus struct {
+	State      string     `json:"state"`
+	Total      int        `json:"total"`    // prompts missing an embedding when the backfill started
+	Embedded   int        `json:"embedded"` // prompts embedded so far
+	Skipped    int        `json:"skipped"`  // prompts embedded or deleted meanwhile
+	Failed     int        `json:"failed"`
+	BatchSize  int        `json:"batch_size"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// BackfillStatus returns the progress of the current or last backfill
+func (s *Storage) BackfillStatus() BackfillStatus {
+	s.jobs.mu.Lock()
+	defer s.jobs.mu.Unlock()
+
+	status := s.jobs.backfill
+	if status.State == "" {
+		status.State = BackfillIdle
+	}
+	return status
+}
+
+// CountPromptsWithoutEmbeddings counts the prompts a backfill would embed:
+// those missing from the vector collection of the current embedding model
+func (s *Storage) CountPromptsWithoutEmbeddings(ctx context.Context) (int, error) {
+	ids, err := s.promptIDsWithoutEmbeddings(ctx)
+	return len(ids), err
+}
+
+// StartBackfill embeds, in the background, every prompt missing an
+// embedding. Only missing embeddings are generated, so running it again
+// resumes an interrupted backfill and repeats no work. A prompt that fails
+// to embed is counted and skipped. It returns ErrBackfillRunning or
+// ErrReindexRunning while either job is in progress; poll BackfillStatus
+// for progress.
+func (s *Storage) StartBackfill(ctx context.Context, opts BackfillOptions) error {
+	if opts.Embed == nil {
+		return fmt.Errorf("no embedding provider available to backfill embeddings")
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBackfillBatchSize
+	}
+
+	// Like a rebuild, the backfill works on its own connection
+	job, err := s.withConn()
+	if err != nil {
+		return err
+	}
+
+	s.jobs.mu.Lock()
+	if err := s.jobs.running(); err != nil {
+		s.jobs.mu.Unlock()
+		job.closeConn()
+		return err
+	}
+	now := time.Now()
+	s.jobs.backfill = BackfillStatus{State: BackfillRunning, BatchSize: opts.BatchSize, StartedAt: &now}
+	s.jobs.mu.Unlock()
+
+	// The backfill outlives the request that started it, but keeps its
+	// request ID for logging
+	bgCtx := log.WithRequestID(context.Background(), log.RequestIDFromContext(ctx))
+	go func() {
+		defer job.closeConn()
+		err := job.backfillEmbeddings(bgCtx, opts)
+
+		s.updateBackfill(func(status *BackfillStatus) {
+			finished := time.Now()
+			status.FinishedAt = &finished
+			status.State = BackfillCompleted
+			if err != nil {
+				status.State = BackfillFailed
+				status.Error = err.Error()
+			}
+		})
+	}()
+	return nil
+}
+
+func (s *Storage) updateBackfill(fn func(*BackfillStatus)) {
+	s.jobs.update(func(j *jobs) { fn(&j.backfill) })
+}
+
+// backfillEmbeddings embeds the prompts missing an embedding, opts.BatchSize
+// at a time with opts.BatchDelay between batches
+func (s *Storage) backfillEmbeddings(ctx context.Context, opts BackfillOptions) error {
+	logger := s.loggerFor(ctx)
+
+	ids, err := s.promptIDsWithoutEmbeddings(ctx)
+	if err != nil {
+		return err
+	}
+	s.updateBackfill(func(status *BackfillStatus) { status.Total = len(ids) })
+	logger.WithField("total", len(ids)).Info("Backfilling prompt embeddings")
+
+	for start := 0; start < len(ids); start += opts.BatchSize {
+		if start > 0 && opts.BatchDelay > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(opts.BatchDelay):
+			}
+		}
+
+		for _, id := range ids[start:min(start+opts.BatchSize, len(ids))] {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			err := s.backfillPrompt(ctx, id, opts)
+			switch {
+			case errors.Is(err, errAlreadyEmbedded) || errors.Is(err, ErrPromptNotFound):
+				s.updateBackfill(func(status *BackfillStatus) { status.Skipped++ })
+			case err != nil:
+				logger.WithError(err).WithField("prompt_id", id).Warn("Failed to backfill prompt embedding")
+				s.updateBackfill(func(status *BackfillStatus) { status.Failed++ })
+			default:
+				s.updateBackfill(func(status *BackfillStatus) { status.Embedded++ })
+			}
+		}
+	}
+
+	status := s.BackfillStatus()
+	logger.WithFields(logrus.Fields{
+		"embedded": status.Embedded,
+		"skipped":  status.Skipped,
+		"failed":   status.Failed,
+	}).Info("Backfilled prompt embeddings")
+	if status.Failed > 0 && status.Embedded == 0 {
+		return fmt.Errorf("no prompts could be embedded")
+	}
+	return nil
+}
+
+// backfillPrompt embeds one prompt and records it in the vector collection
+// and its embedding columns
+func (s *Storage) backfillPrompt(ctx context.Context, id uuid.UUID, opts BackfillOptions) error {
+	p, err := s.GetPromptByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if existing, _ := s.GetPromptEmbedding(ctx, id); existing != nil {
+		return errAlreadyEmbedded
+	}
+
+	embedding, err := opts.Embed(ctx, p.Content)
+	if err != nil {
+		return err
+	}
+	if _, _, dims := s.embedding.get(); dims > 0 && len(embedding) != dims {
+		return fmt.Errorf("embedding dimension mismatch: expected %d, got %d", dims, len(embedding))
+	}
+	p.Embedding = embedding
+	p.EmbeddingProvider = opts.Provider
+	p.EmbeddingModel = opts.Model
+	if err := s.savePromptEmbedding(ctx, p); err != nil {
+		return err
+	}
+
+	stmt, _, err := s.db.Prepare(`
+		UPDATE prompts SET
+			embedding_provider = COALESCE(NULLIF(?, ''), embedding_provider),
+			embedding_model = COALESCE(NULLIF(?, ''), embedding_model)
+		WHERE id = ?`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare embedding update: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+	_ = stmt.BindText(1, opts.Provider)
+	_ = stmt.BindText(2, opts.Model)
+	_ = stmt.BindText(3, id.String())
+	stmt.Step()
+	if err := stmt.Err(); err != nil {
+		return fmt.Errorf("failed to record prompt embedding: %w", err)
+	}
+	return nil
+}
+
+// promptIDsWithoutEmbeddings lists, oldest first, the prompts missing from
+// the vector collection of the current embedding model
+func (s *Storage) promptIDsWithoutEmbeddings(ctx context.Context) ([]uuid.UUID, error) {
+	ctx, span := s.startSpan(ctx, "promptIDsWithoutEmbeddings")
+	defer span.End()
+
+	stmt, _, err := s.db.Prepare("SELECT id FROM prompts ORDER BY created_at, id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare prompt ID query: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	var ids []uuid.UUID
+	for stmt.Step() {
+		id, err := uuid.Parse(stmt.ColumnText(0))
+		if err != nil {
+			return nil, fmt.Errorf("invalid prompt ID %q: %w", stmt.ColumnText(0), err)
+		}
+		ids = append(ids, id)
+	}
+	if err := stmt.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list prompt IDs: %w", err)
+	}
+
+	missing := ids[:0]
+	for _, id := range ids {
+		if embedding, _ := s.GetPromptEmbedding(ctx, id); embedding == nil {
+			missing = append(missing, id)
+		}
+	}
+	return missing, nil
+}
@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartBackfill(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	store, err := NewStorage(t.TempDir(), logger)
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	ctx := context.Background()
+	embedded := &models.Prompt{
+		Content:           "already embedded",
+		Phase:             models.PhaseCoagulatio,
+		Embedding:         []float32{1, 0, 0},
+		EmbeddingProvider: "test",
+		EmbeddingModel:    "test-model",
+	}
+	require.NoError(t, store.SavePrompt(ctx, embedded))
+	for _, content := range []string{"sort a list", "parse a date", "unembeddable"} {
+		require.NoError(t, store.SavePrompt(ctx, &models.Prompt{Content: content, Phase: models.PhaseCoagulatio}))
+	}
+
+	missing, err := store.CountPromptsWithoutEmbeddings(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, missing, "dry runs count only prompts without embeddings")
+
+	var mu sync.Mutex
+	var embeddedTexts []string
+	failing := true
+	embed := func(ctx context.Context, text string) ([]float32, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		embeddedTexts = append(embeddedTexts, text)
+		if text == "unembeddable" && failing {
+			return nil, errors.New("provider rejected input")
+		}
+		return []float32{0, float32(len(text)), 1}, nil
+	}
+	backfill := func() BackfillStatus {
+		require.NoError(t, store.StartBackfill(ctx, BackfillOptions{Embed: embed, Provider: "test", Model: "test-model", BatchSize: 2}))
+		require.Eventually(t, func() bool {
+			return store.BackfillStatus().State != BackfillRunning
+		}, 5*time.Second, 10*time.Millisecond)
+		return store.BackfillStatus()
+	}
+
+	status := backfill()
+	assert.Equal(t, BackfillCompleted, status.State, status.Error)
+	assert.Equal(t, 3, status.Total)
+	assert.Equal(t, 2, status.Embedded)
+	assert.Equal(t, 1, status.Failed, "a failing prompt doesn't abort the batch")
+	assert.ElementsMatch(t, []string{"sort a list", "parse a date", "unembeddable"}, embeddedTexts)
+	assert.NotContains(t, embeddedTexts, "already embedded")
+
+	missing, err = store.CountPromptsWithoutEmbeddings(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, missing)
+
+	// Running again resumes with the prompt that failed
+	mu.Lock()
+	embeddedTexts, failing = nil, false
+	mu.Unlock()
+	status = backfill()
+	assert.Equal(t, BackfillCompleted, status.State, status.Error)
+	assert.Equal(t, 1, status.Total)
+	assert.Equal(t, 1, status.Embedded)
+	assert.Equal(t, []string{"unembeddable"}, embeddedTexts)
+
+	missing, err = store.CountPromptsWithoutEmbeddings(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, missing)
+
+	assert.Error(t, store.StartBackfill(ctx, BackfillOptions{}), "an embedder is required")
+}
+
+func TestBackfillExcludesReindex(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	store, err := NewStorage(t.TempDir(), logger)
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	ctx := context.Background()
+	require.NoError(t, store.SavePrompt(ctx, &models.Prompt{Content: "sort a list", Phase: models.PhaseCoagulatio}))
+
+	release := make(chan struct{})
+	embed := func(ctx context.Context, text string) ([]float32, error) {
+		<-release
+		return []float32{1, 0, 0}, nil
+	}
+
+	// Of concurrent starts, exactly one job claims the slot
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if i%2 == 0 {
+				errs <- store.StartBackfill(ctx, BackfillOptions{Embed: embed})
+			} else {
+				errs <- store.StartReindex(ctx, embed)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	started := 0
+	for err := range errs {
+		if err == nil {
+			started++
+			continue
+		}
+		assert.True(t, errors.Is(err, ErrBackfillRunning) || errors.Is(err, ErrReindexRunning), err)
+	}
+	assert.Equal(t, 1, started)
+
+	backfilling := store.BackfillStatus().State == BackfillRunning
+	assert.NotEqual(t, backfilling, store.ReindexStatus().State == ReindexRunning, "one job runs")
+	if backfilling {
+		assert.ErrorIs(t, store.StartReindex(ctx, embed), ErrBackfillRunning)
+	} else {
+		assert.ErrorIs(t, store.StartBackfill(ctx, BackfillOptions{Embed: embed}), ErrReindexRunning)
+	}
+
+	close(release)
+	require.Eventually(t, func() bool {
+		return store.BackfillStatus().State != BackfillRunning && store.ReindexStatus().State != ReindexRunning
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/ncruces/go-sqlite3"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrBackupExists is returned by Backup when its destination already exists
+var ErrBackupExists = errors.New("backup file already exists")
+
+// ErrInvalidBackup is returned by ValidateBackup and Restore for a file that
+// isn't an intact prompt-alchemy database
+var ErrInvalidBackup = errors.New("invalid backup")
+
+// Backup writes a consistent snapshot of the SQLite database to path with
+// SQLite's online backup API, so the database stays usable while it runs.
+// It refuses to overwrite an existing file. The vector index is not part of
+// the backup.
+func (s *Storage) Backup(ctx context.Context, path string) error {
+	ctx, span := s.startSpan(ctx, "Backup")
+	defer span.End()
+
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("%w: %s", ErrBackupExists, path)
+	}
+	if err := s.db.Backup("main", path); err != nil {
+		_ = os.Remove(path)
+		return fmt.Errorf("failed to back up database: %w", err)
+	}
+
+	s.loggerFor(ctx).WithField("path", path).Info("Backed up database")
+	return nil
+}
+
+// ValidateBackup checks that path is an SQLite database that passes an
+// integrity check and has a prompts table, and returns how many prompts it
+// holds
+func ValidateBackup(path string) (int, error) {
+	if _, err := os.Stat(path); err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+	db, err := sqlite3.OpenFlags(path, sqlite3.OPEN_READONLY)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+	defer func() { _ = db.Close() }()
+
+	stmt, _, err := db.Prepare("PRAGMA integrity_check")
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+	result := ""
+	if stmt.Step() {
+		result = stmt.ColumnText(0)
+	}
+	err = stmt.Err()
+	_ = stmt.Close()
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+	if result != "ok" {
+		return 0, fmt.Errorf("%w: integrity check failed: %s", ErrInvalidBackup, result)
+	}
+
+	stmt, _, err = db.Prepare("SELECT COUNT(*) FROM prompts")
+	if err != nil {
+		return 0, fmt.Errorf("%w: no prompts table: %v", ErrInvalidBackup, err)
+	}
+	defer func() { _ = stmt.Close() }()
+	count := 0
+	if stmt.Step() {
+		count = stmt.ColumnInt(0)
+	}
+	if err := stmt.Err(); err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+	return count, nil
+}
+
+// Restore replaces the contents of the database with the backup at path,
+// after ValidateBackup accepts it, and returns how many prompts it holds.
+// Tables added since the backup was taken are recreated. It returns
+// ErrReindexRunning or ErrBackfillRunning while either job is in progress.
+// The caller must keep other writes away until it returns.
+func (s *Storage) Restore(ctx context.Context, path string) (int, error) {
+	ctx, span := s.startSpan(ctx, "Restore")
+	defer span.End()
+
+	// Holding the job lock keeps a rebuild or backfill from starting
+	// mid-restore
+	s.jobs.mu.Lock()
+	defer s.jobs.mu.Unlock()
+	if err := s.jobs.running(); err != nil {
+		return 0, err
+	}
+	count, err := ValidateBackup(path)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := s.db.Restore("main", path); err != nil {
+		return 0, fmt.Errorf("failed to restore database: %w", err)
+	}
+	if err := s.db.Exec(ddl); err != nil {
+		return 0, fmt.Errorf("failed to update restored schema: %w", err)
+	}
+
+	s.loggerFor(ctx).WithFields(logrus.Fields{
+		"path":    path,
+		"prompts": count,
+	}).Warn("Restored database from backup")
+	return count, nil
+}
@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupAndRestore(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	store, err := NewStorage(t.TempDir(), logger)
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	ctx := context.Background()
+	kept := &models.Prompt{Content: "Saved before the backup", Phase: models.PhasePrimaMaterial}
+	require.NoError(t, store.SavePrompt(ctx, kept))
+
+	path := filepath.Join(t.TempDir(), "backup.db")
+	require.NoError(t, store.Backup(ctx, path))
+	count, err := ValidateBackup(path)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.ErrorIs(t, store.Backup(ctx, path), ErrBackupExists)
+
+	// Change the database after the snapshot
+	later := &models.Prompt{Content: "Saved after the backup", Phase: models.PhaseSolutio}
+	require.NoError(t, store.SavePrompt(ctx, later))
+	require.NoError(t, store.DeletePrompt(ctx, kept.ID.String()))
+
+	count, err = store.Restore(ctx, path)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	restored, err := store.GetPrompt(ctx, kept.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, kept.Content, restored.Content)
+	_, err = store.GetPrompt(ctx, later.ID.String())
+	assert.Error(t, err, "prompts saved after the backup are gone")
+	total, err := store.GetPromptsCount(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+
+	// The restored database stays writable
+	require.NoError(t, store.SavePrompt(ctx, &models.Prompt{Content: "Saved after the restore", Phase: models.PhaseCoagulatio}))
+}
+
+func TestRestore_InvalidBackup(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	store, err := NewStorage(t.TempDir(), logger)
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	ctx := context.Background()
+	prompt := &models.Prompt{Content: "Survives a failed restore", Phase: models.PhasePrimaMaterial}
+	require.NoError(t, store.SavePrompt(ctx, prompt))
+
+	garbage := filepath.Join(t.TempDir(), "garbage.db")
+	require.NoError(t, os.WriteFile(garbage, []byte("not a database"), 0o600))
+	for _, path := range []string{garbage, filepath.Join(t.TempDir(), "missing.db")} {
+		_, err := store.Restore(ctx, path)
+		assert.ErrorIs(t, err, ErrInvalidBackup, path)
+	}
+
+	_, err = store.GetPrompt(ctx, prompt.ID.String())
+	assert.NoError(t, err, "the database is untouched")
+}
+
+func TestRestore_RefusedDuringJobs(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	store, err := NewStorage(t.TempDir(), logger)
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	ctx := context.Background()
+	require.NoError(t, store.SavePrompt(ctx, &models.Prompt{Content: "Being reindexed", Phase: models.PhasePrimaMaterial}))
+	path := filepath.Join(t.TempDir(), "backup.db")
+	require.NoError(t, store.Backup(ctx, path))
+
+	release := make(chan struct{})
+	require.NoError(t, store.StartReindex(ctx, func(ctx context.Context, text string) ([]float32, error) {
+		<-release
+		return []float32{1, 0, 0}, nil
+	}))
+	_, err = store.Restore(ctx, path)
+	assert.ErrorIs(t, err, ErrReindexRunning)
+
+	close(release)
+	require.Eventually(t, func() bool {
+		return store.ReindexStatus().State != ReindexRunning
+	}, 5*time.Second, 10*time.Millisecond)
+	_, err = store.Restore(ctx, path)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/spf13/viper"
+)
+
+// DefaultBoardStateTTL is how long a session's board state is kept after its
+// last update when storage.board_state_ttl is unset
+const DefaultBoardStateTTL = 7 * 24 * time.Hour
+
+// boardStateTTL reads storage.board_state_ttl, falling back to the default
+func boardStateTTL() time.Duration {
+	if ttl := viper.GetDuration("storage.board_state_ttl"); ttl > 0 {
+		return ttl
+	}
+	return DefaultBoardStateTTL
+}
+
+// SaveBoardState stores the board layout for state.SessionID, replacing any
+// earlier one. States abandoned for longer than the TTL are pruned on save.
+func (s *Storage) SaveBoardState(ctx context.Context, state *models.BoardState) error {
+	if state.SessionID == "" {
+		return fmt.Errorf("board state requires a session ID")
+	}
+	state.UpdatedAt = time.Now()
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to encode board state: %w", err)
+	}
+
+	stmt, _, err := s.db.Prepare(`
+		INSERT INTO board_state (session_id, state, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT(session_id) DO UPDATE SET state = excluded.state, updated_at = excluded.updated_at`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare save board state statement: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	_ = stmt.BindText(1, state.SessionID)
+	_ = stmt.BindText(2, string(data))
+	_ = stmt.BindInt64(3, state.UpdatedAt.Unix())
+
+	stmt.Step()
+	if err := stmt.Err(); err != nil {
+		return fmt.Errorf("failed to execute save board state statement: %w", err)
+	}
+
+	if _, err := s.DeleteBoardStatesBefore(ctx, state.UpdatedAt.Add(-boardStateTTL())); err != nil {
+		s.loggerFor(ctx).WithError(err).Warn("Failed to clean up expired board states")
+	}
+	return nil
+}
+
+// GetBoardState returns the board layout saved for sessionID, or nil if
+// there is none or it has expired
+func (s *Storage) GetBoardState(ctx context.Context, sessionID string) (*models.BoardState, error) {
+	stmt, _, err := s.db.Prepare("SELECT state FROM board_state WHERE session_id = ? AND updated_at >= ?")
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare get board state query: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	_ = stmt.BindText(1, sessionID)
+	_ = stmt.BindInt64(2, time.Now().Add(-boardStateTTL()).Unix())
+
+	if !stmt.Step() {
+		if err := stmt.Err(); err != nil {
+			return nil, fmt.Errorf("failed to get board state: %w", err)
+		}
+		return nil, nil
+	}
+
+	var state models.BoardState
+	if err := json.Unmarshal([]byte(stmt.ColumnText(0)), &state); err != nil {
+		return nil, fmt.Errorf("failed to decode board state: %w", err)
+	}
+	return &state, nil
+}
+
+// DeleteBoardStatesBefore removes board states last updated before cutoff
+// and returns how many were deleted
+func (s *Storage) DeleteBoardStatesBefore(ctx context.Context, cutoff time.Time) (int, error) {
+	stmt, _, err := s.db.Prepare("DELETE FROM board_state WHERE updated_at < ?")
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare board state cleanup statement: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	_ = stmt.BindInt64(1, cutoff.Unix())
+
+	if !stmt.Step() {
+		if err := stmt.Err(); err != nil {
+			return 0, fmt.Errorf("failed to execute board state cleanup statement: %w", err)
+		}
+	}
+	deleted := int(s.db.Changes())
+	if deleted > 0 {
+		s.loggerFor(ctx).WithField("deleted", deleted).Debug("Cleaned up expired board states")
+	}
+	return deleted, nil
+}
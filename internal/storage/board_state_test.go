@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBoardState(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	store, err := NewStorage(t.TempDir(), logger)
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	ctx := context.Background()
+
+	state, err := store.GetBoardState(ctx, "session-1")
+	require.NoError(t, err)
+	assert.Nil(t, state)
+
+	require.NoError(t, store.SaveBoardState(ctx, &models.BoardState{
+		SessionID: "session-1",
+		Viewport:  models.Viewport{X: 10, Y: -20, Zoom: 1.5, Width: 1280, Height: 720},
+		Nodes:     []models.NodePosition{{ID: "prima", X: 300, Y: 220}},
+	}))
+	require.NoError(t, store.SaveBoardState(ctx, &models.BoardState{
+		SessionID: "session-1",
+		Viewport:  models.Viewport{X: 40, Y: 5, Zoom: 0.75},
+		Nodes:     []models.NodePosition{{ID: "prima", X: 320, Y: 240}},
+	}))
+
+	state, err = store.GetBoardState(ctx, "session-1")
+	require.NoError(t, err)
+	require.NotNil(t, state)
+	assert.Equal(t, models.Viewport{X: 40, Y: 5, Zoom: 0.75}, state.Viewport)
+	node, ok := state.NodePosition("prima")
+	require.True(t, ok)
+	assert.Equal(t, 320.0, node.X)
+
+	deleted, err := store.DeleteBoardStatesBefore(ctx, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+
+	state, err = store.GetBoardState(ctx, "session-1")
+	require.NoError(t, err)
+	assert.Nil(t, state)
+
+	assert.Error(t, store.SaveBoardState(ctx, &models.BoardState{}))
+}
@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// Modes for storage.oversize_content, applied to prompts whose content is
+// longer than storage.max_content_bytes
+const (
+	OversizeTruncate = "truncate"
+	OversizeReject   = "reject"
+)
+
+// TruncatedTag is added to prompts whose content was cut to fit
+// storage.max_content_bytes, so the truncation is visible after the fact
+const TruncatedTag = "content-truncated"
+
+// ErrContentTooLarge is returned by SavePrompt in reject mode
+var ErrContentTooLarge = errors.New("prompt content exceeds storage.max_content_bytes")
+
+// contentLimit guards against runaway generations being stored verbatim
+type contentLimit struct {
+	maxBytes int
+	mode     string
+}
+
+// loadContentLimit reads storage.max_content_bytes (0 disables the limit)
+// and storage.oversize_content, which defaults to truncate
+func loadContentLimit(logger *logrus.Logger) contentLimit {
+	limit := contentLimit{
+		maxBytes: viper.GetInt("storage.max_content_bytes"),
+		mode:     viper.GetString("storage.oversize_content"),
+	}
+	switch limit.mode {
+	case OversizeTruncate, OversizeReject:
+	case "":
+		limit.mode = OversizeTruncate
+	default:
+		logger.WithField("mode", limit.mode).Warn("Invalid storage.oversize_content, truncating oversized prompts")
+		limit.mode = OversizeTruncate
+	}
+	return limit
+}
+
+// apply enforces the limit on p, truncating its content on a UTF-8
+// boundary or rejecting it. It reports whether the content was truncated.
+func (l contentLimit) apply(p *models.Prompt) (bool, error) {
+	size := len(p.Content)
+	if l.maxBytes <= 0 || size <= l.maxBytes {
+		return false, nil
+	}
+	if l.mode == OversizeReject {
+		return false, fmt.Errorf("%w: %d bytes, limit is %d", ErrContentTooLarge, size, l.maxBytes)
+	}
+
+	cut := l.maxBytes
+	for cut > 0 && !utf8.RuneStart(p.Content[cut]) {
+		cut--
+	}
+	p.Content = p.Content[:cut]
+
+	for _, tag := range p.Tags {
+		if tag == TruncatedTag {
+			return true, nil
+		}
+	}
+	p.Tags = append(p.Tags, TruncatedTag)
+	return true, nil
+}
@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContentLimitApply(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		p := &models.Prompt{Content: "a long prompt"}
+		truncated, err := contentLimit{mode: OversizeTruncate}.apply(p)
+		require.NoError(t, err)
+		assert.False(t, truncated)
+		assert.Equal(t, "a long prompt", p.Content)
+	})
+
+	t.Run("truncates on a rune boundary", func(t *testing.T) {
+		p := &models.Prompt{Content: "héllo wörld", Tags: []string{"code"}}
+		truncated, err := contentLimit{maxBytes: 2, mode: OversizeTruncate}.apply(p)
+		require.NoError(t, err)
+		assert.True(t, truncated)
+		assert.Equal(t, "h", p.Content, "é is two bytes and must not be split")
+		assert.Equal(t, []string{"code", TruncatedTag}, p.Tags)
+
+		// Saving the same prompt again doesn't repeat the tag
+		p.Content = "héllo wörld"
+		_, err = contentLimit{maxBytes: 2, mode: OversizeTruncate}.apply(p)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"code", TruncatedTag}, p.Tags)
+	})
+
+	t.Run("rejects", func(t *testing.T) {
+		p := &models.Prompt{Content: "hello world"}
+		_, err := contentLimit{maxBytes: 5, mode: OversizeReject}.apply(p)
+		assert.ErrorIs(t, err, ErrContentTooLarge)
+		assert.Equal(t, "hello world", p.Content)
+	})
+}
+
+func TestSavePromptRejectsOversizedContent(t *testing.T) {
+	viper.Set("storage.max_content_bytes", 8)
+	viper.Set("storage.oversize_content", OversizeReject)
+	defer viper.Reset()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	store, err := NewStorage(t.TempDir(), logger)
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	ctx := context.Background()
+	assert.ErrorIs(t, store.SavePrompt(ctx, &models.Prompt{Content: "far too long for the limit"}), ErrContentTooLarge)
+	require.NoError(t, store.SavePrompt(ctx, &models.Prompt{Content: "fits"}))
+
+	count, err := store.GetPromptsCount(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
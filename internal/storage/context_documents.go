@@ -0,0 +1,399 @@
+package storage
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// Defaults for the context.chunk_size and context.chunk_overlap settings,
+// in characters
+const (
+	DefaultChunkSize    = 1000
+	DefaultChunkOverlap = 150
+)
+
+// ErrContextDocumentNotFound is returned when no context document has the
+// requested ID
+var ErrContextDocumentNotFound = errors.New("context document not found")
+
+// ChunkConfig sets how ChunkText splits documents: chunks of at most Size
+// characters, each starting up to Overlap characters before the previous
+// one ended, so text cut at a boundary is still seen whole by one chunk
+type ChunkConfig struct {
+	Size    int
+	Overlap int
+}
+
+// LoadChunkConfig reads context.chunk_size and context.chunk_overlap,
+// falling back to the defaults for those unset or not positive. The overlap
+// is kept below half the chunk size.
+func LoadChunkConfig() ChunkConfig {
+	cfg := ChunkConfig{
+		Size:    viper.GetInt("context.chunk_size"),
+		Overlap: viper.GetInt("context.chunk_overlap"),
+	}
+	if cfg.Size <= 0 {
+		cfg.Size = DefaultChunkSize
+	}
+	if !viper.IsSet("context.chunk_overlap") {
+		cfg.Overlap = DefaultChunkOverlap
+	}
+	cfg.Overlap = min(max(cfg.Overlap, 0), (cfg.Size-1)/2)
+	return cfg
+}
+
+// ChunkText splits text into chunks of at most cfg.Size characters. Chunks
+// end at the last paragraph break in the second half of their window, or
+// failing that the last sentence end, or the last space; a word longer than
+// the window is cut. Each chunk after the first starts at a word up to
+// cfg.Overlap characters back into the previous one.
+func ChunkText(text string, cfg ChunkConfig) []string {
+	runes := []rune(strings.TrimSpace(text))
+	size := max(cfg.Size, 1)
+	overlap := min(max(cfg.Overlap, 0), (size-1)/2)
+
+	var chunks []string
+	for start := 0; start < len(runes); {
+		end := len(runes)
+		if start+size < len(runes) {
+			end = chunkBoundary(runes, start, start+size)
+		}
+		if chunk := strings.TrimSpace(string(runes[start:end])); chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+		if end == len(runes) {
+			break
+		}
+
+		next := wordStart(runes, end-overlap, end)
+		if next <= start {
+			next = end
+		}
+		for next < len(runes) && unicode.IsSpace(runes[next]) {
+			next++
+		}
+		start = next
+	}
+	return chunks
+}
+
+// chunkBoundary returns where a chunk of runes from start should end, at or
+// before limit, preferring a paragraph break, then a sentence end, then a
+// space in the second half of the window
+func chunkBoundary(runes []rune, start, limit int) int {
+	lowest := start + (limit-start)/2 + 1
+	for end := limit; end >= lowest; end-- {
+		if end-2 >= start && runes[end-1] == '\n' && runes[end-2] == '\n' {
+			return end
+		}
+	}
+	for end := limit; end >= lowest; end-- {
+		if unicode.IsSpace(runes[end]) && strings.ContainsRune(".!?", runes[end-1]) {
+			return end
+		}
+	}
+	for end := limit; end >= lowest; end-- {
+		if unicode.IsSpace(runes[end]) {
+			return end
+		}
+	}
+	return limit
+}
+
+// wordStart returns the start of the first word at or after i, or limit
+// if there is none before it
+func wordStart(runes []rune, i, limit int) int {
+	if i <= 0 {
+		return 0
+	}
+	for i < limit && !unicode.IsSpace(runes[i-1]) {
+		i++
+	}
+	for i < limit && unicode.IsSpace(runes[i]) {
+		i++
+	}
+	return i
+}
+
+// IngestContextDocument splits content into chunks with LoadChunkConfig,
+// embeds each chunk and saves the document
+func (s *Storage) IngestContextDocument(ctx context.Context, title, source, content string, embed QueryEmbedder) (*models.ContextDocument, error) {
+	if embed == nil {
+		return nil, fmt.Errorf("no embedding provider configured")
+	}
+	texts := ChunkText(content, LoadChunkConfig())
+	if len(texts) == 0 {
+		return nil, fmt.Errorf("context document is empty")
+	}
+
+	chunks := make([]models.ContextChunk, len(texts))
+	for i, text := range texts {
+		embedding, err := embed(ctx, text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed chunk %d: %w", i, err)
+		}
+		if len(embedding) == 0 {
+			return nil, fmt.Errorf("embedding provider returned an empty embedding")
+		}
+		chunks[i] = models.ContextChunk{Index: i, Content: text, Embedding: embedding}
+	}
+
+	doc := &models.ContextDocument{
+		Title:      title,
+		Source:     source,
+		Characters: len([]rune(content)),
+	}
+	if err := s.SaveContextDocument(ctx, doc, chunks); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// SaveContextDocument saves doc and its chunks with their embeddings in one
+// transaction, giving each an ID. The chunks' embeddings must all have the
+// same length.
+func (s *Storage) SaveContextDocument(ctx context.Context, doc *models.ContextDocument, chunks []models.ContextChunk) (err error) {
+	ctx, span := s.startSpan(ctx, "SaveContextDocument")
+	defer span.End()
+
+	if len(chunks) == 0 {
+		return fmt.Errorf("context document has no chunks")
+	}
+	dimensions := len(chunks[0].Embedding)
+	for _, chunk := range chunks {
+		if len(chunk.Embedding) != dimensions {
+			return fmt.Errorf("%w: chunk %d has %d dimensions, expected %d",
+				ErrEmbeddingDimensionMismatch, chunk.Index, len(chunk.Embedding), dimensions)
+		}
+	}
+
+	if doc.ID == uuid.Nil {
+		doc.ID = uuid.New()
+	}
+	if doc.CreatedAt.IsZero() {
+		doc.CreatedAt = time.Now()
+	}
+	doc.Chunks = len(chunks)
+	doc.Dimensions = dimensions
+
+	tx, err := s.db.BeginImmediate()
+	if err != nil {
+		return fmt.Errorf("failed to begin save context document transaction: %w", err)
+	}
+	defer tx.End(&err)
+
+	if err := s.insertContextDocument(doc); err != nil {
+		return err
+	}
+	for i := range chunks {
+		chunks[i].DocumentID = doc.ID
+		chunks[i].DocumentTitle = doc.Title
+		if err := s.insertContextChunk(&chunks[i]); err != nil {
+			return err
+		}
+	}
+
+	s.loggerFor(ctx).WithFields(logrus.Fields{
+		"document_id": doc.ID,
+		"chunks":      doc.Chunks,
+		"dimensions":  doc.Dimensions,
+	}).Info("Saved context document")
+	return nil
+}
+
+func (s *Storage) insertContextDocument(doc *models.ContextDocument) error {
+	stmt, _, err := s.db.Prepare(`
+		INSERT INTO context_documents (id, title, source, characters, chunk_count, dimensions, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare save context document statement: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	_ = stmt.BindText(1, doc.ID.String())
+	_ = stmt.BindText(2, doc.Title)
+	_ = stmt.BindText(3, doc.Source)
+	_ = stmt.BindInt(4, doc.Characters)
+	_ = stmt.BindInt(5, doc.Chunks)
+	_ = stmt.BindInt(6, doc.Dimensions)
+	_ = stmt.BindInt64(7, doc.CreatedAt.Unix())
+
+	stmt.Step()
+	if err := stmt.Err(); err != nil {
+		return fmt.Errorf("failed to execute save context document statement: %w", err)
+	}
+	return nil
+}
+
+func (s *Storage) insertContextChunk(chunk *models.ContextChunk) error {
+	if chunk.ID == uuid.Nil {
+		chunk.ID = uuid.New()
+	}
+
+	stmt, _, err := s.db.Prepare(`
+		INSERT INTO context_chunks (id, document_id, chunk_index, content, embedding)
+		VALUES (?, ?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare save context chunk statement: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	_ = stmt.BindText(1, chunk.ID.String())
+	_ = stmt.BindText(2, chunk.DocumentID.String())
+	_ = stmt.BindInt(3, chunk.Index)
+	_ = stmt.BindText(4, chunk.Content)
+	_ = stmt.BindBlob(5, encodeEmbedding(chunk.Embedding))
+
+	stmt.Step()
+	if err := stmt.Err(); err != nil {
+		return fmt.Errorf("failed to execute save context chunk statement: %w", err)
+	}
+	return nil
+}
+
+// GetContextDocument returns the context document with the given ID, or
+// ErrContextDocumentNotFound
+func (s *Storage) GetContextDocument(ctx context.Context, id uuid.UUID) (*models.ContextDocument, error) {
+	stmt, _, err := s.db.Prepare(`
+		SELECT title, source, characters, chunk_count, dimensions, created_at
+		FROM context_documents WHERE id = ?`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare get context document query: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	_ = stmt.BindText(1, id.String())
+	if !stmt.Step() {
+		if err := stmt.Err(); err != nil {
+			return nil, fmt.Errorf("failed to get context document: %w", err)
+		}
+		return nil, fmt.Errorf("%w: %s", ErrContextDocumentNotFound, id)
+	}
+	return &models.ContextDocument{
+		ID:         id,
+		Title:      stmt.ColumnText(0),
+		Source:     stmt.ColumnText(1),
+		Characters: stmt.ColumnInt(2),
+		Chunks:     stmt.ColumnInt(3),
+		Dimensions: stmt.ColumnInt(4),
+		CreatedAt:  time.Unix(stmt.ColumnInt64(5), 0),
+	}, nil
+}
+
+// SearchContextChunks returns up to limit chunks of the given documents
+// most similar to embedding, most similar first, leaving out those below
+// minSimilarity. It returns ErrContextDocumentNotFound if a document doesn't
+// exist, and ErrEmbeddingDimensionMismatch if one was embedded with a
+// different number of dimensions.
+func (s *Storage) SearchContextChunks(ctx context.Context, documentIDs []uuid.UUID, embedding []float32, limit int, minSimilarity float64) ([]models.ContextChunk, error) {
+	ctx, span := s.startSpan(ctx, "SearchContextChunks")
+	defer span.End()
+
+	var chunks []models.ContextChunk
+	for _, id := range documentIDs {
+		doc, err := s.GetContextDocument(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if doc.Dimensions != len(embedding) {
+			return nil, fmt.Errorf("%w: document %s has %d dimensions, the query %d",
+				ErrEmbeddingDimensionMismatch, id, doc.Dimensions, len(embedding))
+		}
+		docChunks, err := s.getContextChunks(doc)
+		if err != nil {
+			return nil, err
+		}
+		for _, chunk := range docChunks {
+			chunk.Similarity = cosineSimilarity(embedding, chunk.Embedding)
+			if chunk.Similarity >= minSimilarity {
+				chunks = append(chunks, chunk)
+			}
+		}
+	}
+
+	sort.SliceStable(chunks, func(i, j int) bool { return chunks[i].Similarity > chunks[j].Similarity })
+	if limit > 0 && len(chunks) > limit {
+		chunks = chunks[:limit]
+	}
+	return chunks, nil
+}
+
+// getContextChunks returns the chunks of doc in document order
+func (s *Storage) getContextChunks(doc *models.ContextDocument) ([]models.ContextChunk, error) {
+	stmt, _, err := s.db.Prepare(`
+		SELECT id, chunk_index, content, embedding
+		FROM context_chunks WHERE document_id = ? ORDER BY chunk_index`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare get context chunks query: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	_ = stmt.BindText(1, doc.ID.String())
+	chunks := make([]models.ContextChunk, 0, doc.Chunks)
+	for stmt.Step() {
+		id, err := uuid.Parse(stmt.ColumnText(0))
+		if err != nil {
+			return nil, fmt.Errorf("invalid context chunk ID: %w", err)
+		}
+		chunks = append(chunks, models.ContextChunk{
+			ID:            id,
+			DocumentID:    doc.ID,
+			DocumentTitle: doc.Title,
+			Index:         stmt.ColumnInt(1),
+			Content:       stmt.ColumnText(2),
+			Embedding:     decodeEmbedding(stmt.ColumnBlob(3, nil)),
+		})
+	}
+	if err := stmt.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get context chunks: %w", err)
+	}
+	return chunks, nil
+}
+
+// encodeEmbedding packs an embedding as little-endian float32s
+func encodeEmbedding(embedding []float32) []byte {
+	buf := make([]byte, 4*len(embedding))
+	for i, v := range embedding {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(v))
+	}
+	return buf
+}
+
+// decodeEmbedding unpacks an embedding packed by encodeEmbedding
+func decodeEmbedding(buf []byte) []float32 {
+	embedding := make([]float32, len(buf)/4)
+	for i := range embedding {
+		embedding[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+	}
+	return embedding
+}
+
+// cosineSimilarity returns the cosine of the angle between a and b, 0 when
+// either is zero or their lengths differ
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
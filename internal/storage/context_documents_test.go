@@ -0,0 +1,189 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChunkText(t *testing.T) {
+	t.Run("empty text has no chunks", func(t *testing.T) {
+		assert.Empty(t, ChunkText("  \n\n ", ChunkConfig{Size: 10}))
+	})
+
+	t.Run("short text is one chunk", func(t *testing.T) {
+		assert.Equal(t, []string{"Hello world."}, ChunkText(" Hello world.\n", ChunkConfig{Size: 100, Overlap: 10}))
+	})
+
+	t.Run("chunks end at a paragraph break", func(t *testing.T) {
+		text := "First paragraph. Still first.\n\nSecond paragraph here."
+		chunks := ChunkText(text, ChunkConfig{Size: 40})
+		assert.Equal(t, []string{"First paragraph. Still first.", "Second paragraph here."}, chunks)
+	})
+
+	t.Run("chunks end at a sentence before a space", func(t *testing.T) {
+		text := "One two three. Four five six seven eight"
+		chunks := ChunkText(text, ChunkConfig{Size: 25})
+		assert.Equal(t, []string{"One two three.", "Four five six seven eight"}, chunks)
+	})
+
+	t.Run("chunks end at a space without a sentence end", func(t *testing.T) {
+		text := "alpha beta gamma delta epsilon"
+		chunks := ChunkText(text, ChunkConfig{Size: 12})
+		assert.Equal(t, []string{"alpha beta", "gamma delta", "epsilon"}, chunks)
+	})
+
+	t.Run("a word longer than the chunk size is cut", func(t *testing.T) {
+		chunks := ChunkText(strings.Repeat("x", 25), ChunkConfig{Size: 10})
+		assert.Equal(t, []string{strings.Repeat("x", 10), strings.Repeat("x", 10), strings.Repeat("x", 5)}, chunks)
+	})
+
+	t.Run("overlap starts at a word of the previous chunk", func(t *testing.T) {
+		text := "alpha beta gamma delta epsilon zeta"
+		chunks := ChunkText(text, ChunkConfig{Size: 17, Overlap: 6})
+		assert.Equal(t, []string{"alpha beta gamma", "gamma delta", "delta epsilon", "zeta"}, chunks)
+	})
+
+	t.Run("chunks never exceed the size and cover the text", func(t *testing.T) {
+		text := strings.Repeat("The quick brown fox jumps over the lazy dog. ", 40)
+		cfg := ChunkConfig{Size: 120, Overlap: 30}
+		chunks := ChunkText(text, cfg)
+		require.Greater(t, len(chunks), 1)
+		words := strings.Fields(text)
+		for _, chunk := range chunks {
+			assert.LessOrEqual(t, len([]rune(chunk)), cfg.Size)
+			assert.Contains(t, words, strings.Fields(chunk)[0], "chunk starts mid-word")
+		}
+		assert.True(t, strings.HasSuffix(chunks[len(chunks)-1], "lazy dog."))
+	})
+}
+
+// keywordEmbedder embeds text as counts of a few keywords, so similarity
+// follows which keywords a text mentions
+func keywordEmbedder(keywords ...string) QueryEmbedder {
+	return func(ctx context.Context, text string) ([]float32, error) {
+		embedding := make([]float32, len(keywords))
+		for i, keyword := range keywords {
+			embedding[i] = float32(strings.Count(strings.ToLower(text), keyword))
+		}
+		return embedding, nil
+	}
+}
+
+func TestIngestContextDocument(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	store, err := NewStorage(t.TempDir(), logger)
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	ctx := context.Background()
+	embed := keywordEmbedder("cat", "dog", "bird")
+	content := strings.Repeat("The cat sat on the mat. ", 60) + "\n\n" + strings.Repeat("A dog barked at the bird. ", 60)
+
+	doc, err := store.IngestContextDocument(ctx, "Pets", "pets.md", content, embed)
+	require.NoError(t, err)
+	assert.NotEqual(t, uuid.Nil, doc.ID)
+	assert.Equal(t, 3, doc.Dimensions)
+	assert.Equal(t, len([]rune(content)), doc.Characters)
+	assert.Equal(t, len(ChunkText(content, LoadChunkConfig())), doc.Chunks)
+	assert.Greater(t, doc.Chunks, 1)
+
+	stored, err := store.GetContextDocument(ctx, doc.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Pets", stored.Title)
+	assert.Equal(t, "pets.md", stored.Source)
+	assert.Equal(t, doc.Chunks, stored.Chunks)
+
+	t.Run("chunks are stored with their embeddings", func(t *testing.T) {
+		chunks, err := store.getContextChunks(stored)
+		require.NoError(t, err)
+		require.Len(t, chunks, doc.Chunks)
+		for i, chunk := range chunks {
+			assert.Equal(t, i, chunk.Index)
+			assert.Equal(t, "Pets", chunk.DocumentTitle)
+			want, err := embed(ctx, chunk.Content)
+			require.NoError(t, err)
+			assert.Equal(t, want, chunk.Embedding)
+		}
+	})
+
+	t.Run("failed embedding saves nothing", func(t *testing.T) {
+		failing := func(ctx context.Context, text string) ([]float32, error) {
+			return nil, errors.New("provider down")
+		}
+		_, err := store.IngestContextDocument(ctx, "Broken", "", "some text", failing)
+		assert.ErrorContains(t, err, "provider down")
+	})
+
+	t.Run("unknown document", func(t *testing.T) {
+		_, err := store.GetContextDocument(ctx, uuid.New())
+		assert.ErrorIs(t, err, ErrContextDocumentNotFound)
+	})
+}
+
+func TestSearchContextChunks(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	store, err := NewStorage(t.TempDir(), logger)
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	ctx := context.Background()
+	embed := keywordEmbedder("cat", "dog", "bird")
+	pets, err := store.IngestContextDocument(ctx, "Pets", "", "The cat purrs.", embed)
+	require.NoError(t, err)
+	more, err := store.IngestContextDocument(ctx, "More pets", "", "The dog barks.", embed)
+	require.NoError(t, err)
+	birds, err := store.IngestContextDocument(ctx, "Birds", "", "The bird sings and the cat watches.", embed)
+	require.NoError(t, err)
+	ids := []uuid.UUID{pets.ID, more.ID, birds.ID}
+
+	t.Run("most similar chunks first", func(t *testing.T) {
+		query, _ := embed(ctx, "cat")
+		chunks, err := store.SearchContextChunks(ctx, ids, query, 10, 0)
+		require.NoError(t, err)
+		require.Len(t, chunks, 3)
+		assert.Equal(t, "The cat purrs.", chunks[0].Content)
+		assert.Equal(t, pets.ID, chunks[0].DocumentID)
+		assert.InDelta(t, 1, chunks[0].Similarity, 1e-9)
+		assert.Equal(t, "The bird sings and the cat watches.", chunks[1].Content)
+		assert.Equal(t, 0.0, chunks[2].Similarity)
+	})
+
+	t.Run("limit and minimum similarity", func(t *testing.T) {
+		query, _ := embed(ctx, "cat")
+		chunks, err := store.SearchContextChunks(ctx, ids, query, 1, 0)
+		require.NoError(t, err)
+		require.Len(t, chunks, 1)
+		assert.Equal(t, "The cat purrs.", chunks[0].Content)
+
+		chunks, err = store.SearchContextChunks(ctx, ids, query, 10, 0.5)
+		require.NoError(t, err)
+		assert.Len(t, chunks, 2)
+	})
+
+	t.Run("only the requested documents are searched", func(t *testing.T) {
+		query, _ := embed(ctx, "cat")
+		chunks, err := store.SearchContextChunks(ctx, []uuid.UUID{more.ID}, query, 10, 0)
+		require.NoError(t, err)
+		require.Len(t, chunks, 1)
+		assert.Equal(t, more.ID, chunks[0].DocumentID)
+	})
+
+	t.Run("unknown document", func(t *testing.T) {
+		_, err := store.SearchContextChunks(ctx, []uuid.UUID{uuid.New()}, []float32{1, 0, 0}, 10, 0)
+		assert.ErrorIs(t, err, ErrContextDocumentNotFound)
+	})
+
+	t.Run("embedding dimension mismatch", func(t *testing.T) {
+		_, err := store.SearchContextChunks(ctx, ids, []float32{1, 0}, 10, 0)
+		assert.ErrorIs(t, err, ErrEmbeddingDimensionMismatch)
+	})
+}
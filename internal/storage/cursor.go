@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/ncruces/go-sqlite3"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrInvalidCursor is returned when a pagination cursor cannot be decoded
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// PromptCursor marks a position in the prompt list. Prompts are ordered by
+// created_at then id, both descending, so the pair is unique and stable even
+// when many prompts share a timestamp.
+type PromptCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// CursorFor returns the cursor positioned at p
+func CursorFor(p *models.Prompt) PromptCursor {
+	return PromptCursor{CreatedAt: p.CreatedAt, ID: p.ID}
+}
+
+// Encode returns the opaque string form of the cursor handed to clients
+func (c PromptCursor) Encode() string {
+	raw := strconv.FormatInt(c.CreatedAt.Unix(), 10) + ":" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeCursor parses a cursor produced by PromptCursor.Encode
+func DecodeCursor(s string) (PromptCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return PromptCursor{}, ErrInvalidCursor
+	}
+
+	ts, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return PromptCursor{}, ErrInvalidCursor
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return PromptCursor{}, ErrInvalidCursor
+	}
+	promptID, err := uuid.Parse(id)
+	if err != nil {
+		return PromptCursor{}, ErrInvalidCursor
+	}
+
+	return PromptCursor{CreatedAt: time.Unix(unix, 0), ID: promptID}, nil
+}
+
+// ListPromptsOptions filters and positions a cursor-paginated prompt listing
+type ListPromptsOptions struct {
+	Limit    int
+	After    *PromptCursor
+	Phase    string
+	Provider string
+}
+
+// ListPromptsPage returns up to opts.Limit prompts strictly after opts.After,
+// newest first. The returned cursor points at the last prompt of the page and
+// is nil when there are no more prompts. Unlike offset paging, the cost of a
+// page does not grow with its depth.
+func (s *Storage) ListPromptsPage(ctx context.Context, opts ListPromptsOptions) ([]models.Prompt, *PromptCursor, error) {
+	s.loggerFor(ctx).WithFields(logrus.Fields{
+		"limit":    opts.Limit,
+		"cursor":   opts.After != nil,
+		"phase":    opts.Phase,
+		"provider": opts.Provider,
+	}).Debug("Listing prompts page")
+
+	if s.db == nil {
+		return nil, nil, fmt.Errorf("database connection not initialized")
+	}
+
+	var where []string
+	var args []interface{}
+	if opts.After != nil {
+		filter, afterArgs := afterFilter(*opts.After)
+		where = append(where, filter)
+		args = append(args, afterArgs...)
+	}
+	if opts.Phase != "" {
+		where = append(where, "phase = ?")
+		args = append(args, opts.Phase)
+	}
+	if opts.Provider != "" {
+		where = append(where, "provider = ?")
+		args = append(args, opts.Provider)
+	}
+
+	clause := ""
+	if len(where) > 0 {
+		clause = " WHERE " + strings.Join(where, " AND ")
+	}
+	// Fetch one extra row to learn whether another page exists
+	query := strings.Replace(s.baseSelectQuery(), ";", clause+" ORDER BY created_at DESC, id DESC LIMIT ?;", 1)
+	args = append(args, opts.Limit+1)
+
+	stmt, _, err := s.db.Prepare(query)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to prepare list prompts page query: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	bindArgs(stmt, args)
+
+	prompts, err := s.scanPrompts(stmt)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to scan prompts page: %w", err)
+	}
+
+	var next *PromptCursor
+	if len(prompts) > opts.Limit {
+		prompts = prompts[:opts.Limit]
+		cursor := CursorFor(prompts[len(prompts)-1])
+		next = &cursor
+	}
+
+	result := make([]models.Prompt, len(prompts))
+	for i, p := range prompts {
+		result[i] = *p
+	}
+	return result, next, nil
+}
+
+// afterFilter returns the WHERE condition, and its arguments, selecting the
+// prompts that come after cursor in newest-first order
+func afterFilter(cursor PromptCursor) (string, []interface{}) {
+	unix := cursor.CreatedAt.Unix()
+	return "(created_at < ? OR (created_at = ? AND id < ?))", []interface{}{unix, unix, cursor.ID.String()}
+}
+
+// bindArgs binds positional query arguments in order
+func bindArgs(stmt *sqlite3.Stmt, args []interface{}) {
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case int64:
+			_ = stmt.BindInt64(i+1, v)
+		case int:
+			_ = stmt.BindInt(i+1, v)
+		case string:
+			_ = stmt.BindText(i+1, v)
+		}
+	}
+}
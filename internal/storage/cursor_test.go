@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPromptCursorRoundTrip(t *testing.T) {
+	cursor := PromptCursor{CreatedAt: time.Unix(1700000000, 0), ID: uuid.New()}
+
+	decoded, err := DecodeCursor(cursor.Encode())
+	require.NoError(t, err)
+	assert.True(t, cursor.CreatedAt.Equal(decoded.CreatedAt))
+	assert.Equal(t, cursor.ID, decoded.ID)
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	for _, input := range []string{"", "not base64!", "bm8tY29sb24", "YWJjOjEyMw"} {
+		_, err := DecodeCursor(input)
+		assert.ErrorIs(t, err, ErrInvalidCursor, input)
+	}
+}
+
+func TestListPromptsPage(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	store, err := NewStorage(t.TempDir(), logger)
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	ctx := context.Background()
+
+	// Empty database yields an empty page without a cursor
+	prompts, next, err := store.ListPromptsPage(ctx, ListPromptsOptions{Limit: 10})
+	require.NoError(t, err)
+	assert.Empty(t, prompts)
+	assert.Nil(t, next)
+
+	// Several prompts share a timestamp so paging must fall back to the id
+	base := time.Unix(1700000000, 0)
+	for i := 0; i < 5; i++ {
+		phase := models.PhasePrimaMaterial
+		if i%2 == 1 {
+			phase = models.PhaseSolutio
+		}
+		require.NoError(t, store.SavePrompt(ctx, &models.Prompt{
+			Content:   "prompt " + uuid.NewString(),
+			Phase:     phase,
+			Provider:  "openai",
+			CreatedAt: base.Add(time.Duration(i/2) * time.Second),
+		}))
+	}
+
+	var seen []uuid.UUID
+	var after *PromptCursor
+	for page := 0; page < 5; page++ {
+		prompts, next, err := store.ListPromptsPage(ctx, ListPromptsOptions{Limit: 2, After: after})
+		require.NoError(t, err)
+		for _, p := range prompts {
+			seen = append(seen, p.ID)
+		}
+		if next == nil {
+			break
+		}
+		after = next
+	}
+	require.Len(t, seen, 5)
+	assert.Len(t, uniqueIDs(seen), 5, "pages must not overlap")
+
+	// Filters apply together with the cursor
+	prompts, next, err = store.ListPromptsPage(ctx, ListPromptsOptions{Limit: 10, Phase: string(models.PhaseSolutio)})
+	require.NoError(t, err)
+	assert.Len(t, prompts, 2)
+	assert.Nil(t, next)
+	for _, p := range prompts {
+		assert.Equal(t, models.PhaseSolutio, p.Phase)
+	}
+}
+
+func TestSearchPromptsPage(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	store, err := NewStorage(t.TempDir(), logger)
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	ctx := context.Background()
+	base := time.Unix(1700000000, 0)
+	for i := 0; i < 12; i++ {
+		phase, provider := models.PhaseSolutio, "openai"
+		if i%3 == 0 {
+			phase = models.PhaseCoagulatio
+		}
+		if i%4 == 0 {
+			provider = "anthropic"
+		}
+		require.NoError(t, store.SavePrompt(ctx, &models.Prompt{
+			Content:   "paged prompt " + uuid.NewString(),
+			Phase:     phase,
+			Provider:  provider,
+			CreatedAt: base.Add(time.Duration(i/3) * time.Second),
+		}))
+	}
+
+	since := base.Add(time.Second)
+	criteria := SearchCriteria{Query: "paged", Phase: string(models.PhaseSolutio), Provider: "openai", Since: &since}
+	all := criteria
+	all.Limit = 100
+	want, err := store.SearchPromptsByCriteria(ctx, all)
+	require.NoError(t, err)
+	require.Len(t, want, 4)
+
+	var got []models.Prompt
+	criteria.Limit = 3
+	for page := 0; page < 10; page++ {
+		prompts, next, err := store.SearchPromptsPage(ctx, criteria)
+		require.NoError(t, err)
+		got = append(got, prompts...)
+		if next == nil {
+			break
+		}
+		criteria.After = next
+	}
+
+	// Paging returns the same prompts in the same order, without gaps or repeats
+	require.Len(t, got, len(want))
+	for i := range want {
+		assert.Equal(t, want[i].ID, got[i].ID)
+		assert.Equal(t, models.PhaseSolutio, got[i].Phase)
+		assert.Equal(t, "openai", got[i].Provider)
+		assert.False(t, got[i].CreatedAt.Before(since))
+	}
+}
+
+func uniqueIDs(ids []uuid.UUID) map[uuid.UUID]bool {
+	unique := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		unique[id] = true
+	}
+	return unique
+}
@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+)
+
+// How a prompt matched the stored prompt it duplicates
+const (
+	DuplicateExact    = "exact"    // same content
+	DuplicateSemantic = "semantic" // embedding at least as similar as asked
+)
+
+// Duplicate is a prompt left unsaved because it repeats a stored one
+type Duplicate struct {
+	PromptID    uuid.UUID `json:"prompt_id"`
+	DuplicateOf uuid.UUID `json:"duplicate_of"`
+	Match       string    `json:"match"`
+	Similarity  float64   `json:"similarity"` // 1 for exact matches
+}
+
+// FindDuplicate returns the stored prompt that p repeats, or nil when there
+// is none. Prompts with the same content match through the content_hash
+// index. When minSimilarity is above zero and p has an embedding from the
+// current embedding model, the most similar stored prompt at or above it
+// matches too.
+func (s *Storage) FindDuplicate(ctx context.Context, p *models.Prompt, minSimilarity float64) (*Duplicate, error) {
+	ctx, span := s.startSpan(ctx, "FindDuplicate")
+	defer span.End()
+
+	existing, err := s.promptIDByContent(p.Content, p.ID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != uuid.Nil {
+		return &Duplicate{PromptID: p.ID, DuplicateOf: existing, Match: DuplicateExact, Similarity: 1}, nil
+	}
+
+	if _, _, dims := s.embedding.get(); minSimilarity <= 0 || len(p.Embedding) == 0 || len(p.Embedding) != dims {
+		return nil, nil
+	}
+	collection := s.getOrCreateCollection()
+	count := collection.Count()
+	if count == 0 {
+		return nil, nil
+	}
+	// p itself may already be indexed, so look one past it
+	results, err := collection.QueryEmbedding(ctx, p.Embedding, min(2, count), nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query vector collection: %w", err)
+	}
+	for _, result := range results {
+		if result.ID == p.ID.String() {
+			continue
+		}
+		similarity := float64(result.Similarity)
+		if similarity < minSimilarity {
+			break // results are ordered by similarity
+		}
+		existing, err := uuid.Parse(result.ID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid prompt ID %q in vector result: %w", result.ID, err)
+		}
+		return &Duplicate{PromptID: p.ID, DuplicateOf: existing, Match: DuplicateSemantic, Similarity: similarity}, nil
+	}
+	return nil, nil
+}
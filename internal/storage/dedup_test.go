@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindDuplicate(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	store, err := NewStorage(t.TempDir(), logger)
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	ctx := context.Background()
+	stored := &models.Prompt{Content: "sort a list", Phase: models.PhaseCoagulatio, Embedding: []float32{1, 0, 0}}
+	require.NoError(t, store.SavePrompt(ctx, stored))
+
+	t.Run("same content", func(t *testing.T) {
+		duplicate, err := store.FindDuplicate(ctx, &models.Prompt{Content: "sort a list"}, 0)
+		require.NoError(t, err)
+		require.NotNil(t, duplicate)
+		assert.Equal(t, stored.ID, duplicate.DuplicateOf)
+		assert.Equal(t, DuplicateExact, duplicate.Match)
+		assert.Equal(t, 1.0, duplicate.Similarity)
+	})
+
+	t.Run("distinct content", func(t *testing.T) {
+		duplicate, err := store.FindDuplicate(ctx, &models.Prompt{Content: "parse a date", Embedding: []float32{0.99, 0.141, 0}}, 0)
+		require.NoError(t, err)
+		assert.Nil(t, duplicate, "the semantic check is off at zero similarity")
+	})
+
+	t.Run("a prompt is not its own duplicate", func(t *testing.T) {
+		duplicate, err := store.FindDuplicate(ctx, stored, 0.5)
+		require.NoError(t, err)
+		assert.Nil(t, duplicate)
+	})
+
+	t.Run("similar embedding", func(t *testing.T) {
+		duplicate, err := store.FindDuplicate(ctx, &models.Prompt{Content: "order a list", Embedding: []float32{0.99, 0.141, 0}}, 0.95)
+		require.NoError(t, err)
+		require.NotNil(t, duplicate)
+		assert.Equal(t, stored.ID, duplicate.DuplicateOf)
+		assert.Equal(t, DuplicateSemantic, duplicate.Match)
+		assert.GreaterOrEqual(t, duplicate.Similarity, 0.95)
+	})
+
+	t.Run("dissimilar embedding", func(t *testing.T) {
+		duplicate, err := store.FindDuplicate(ctx, &models.Prompt{Content: "parse a date", Embedding: []float32{0, 1, 0}}, 0.95)
+		require.NoError(t, err)
+		assert.Nil(t, duplicate)
+	})
+}
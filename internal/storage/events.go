@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/sirupsen/logrus"
+)
+
+// EventType identifies a prompt lifecycle mutation
+type EventType string
+
+const (
+	EventPromptCreated    EventType = "prompt.created"
+	EventPromptUpdated    EventType = "prompt.updated"
+	EventPromptDeleted    EventType = "prompt.deleted"
+	EventPromptsCleanedUp EventType = "prompts.cleanup"
+)
+
+// Event describes a single mutation of the prompt corpus. Prompt holds a
+// snapshot whenever the whole prompt was written; score-only updates and
+// deletes carry just PromptID, and cleanups report the number removed in Count.
+type Event struct {
+	ID        uuid.UUID      `json:"id"`
+	Type      EventType      `json:"type"`
+	PromptID  uuid.UUID      `json:"prompt_id,omitempty"`
+	Prompt    *models.Prompt `json:"prompt,omitempty"`
+	Count     int            `json:"count,omitempty"`
+	Timestamp time.Time      `json:"timestamp"`
+}
+
+// EventSubscriber receives storage events. HandleEvent is called on the
+// goroutine performing the mutation, so slow work must be done asynchronously.
+type EventSubscriber interface {
+	HandleEvent(ctx context.Context, event Event)
+}
+
+// EventSubscriberFunc adapts a plain function to EventSubscriber
+type EventSubscriberFunc func(ctx context.Context, event Event)
+
+// HandleEvent calls f(ctx, event)
+func (f EventSubscriberFunc) HandleEvent(ctx context.Context, event Event) {
+	f(ctx, event)
+}
+
+// EventBus fans storage events out to its subscribers. A nil bus discards
+// events, so a zero Storage stays usable in tests.
+type EventBus struct {
+	mu          sync.RWMutex
+	subscribers []EventSubscriber
+	logger      *logrus.Logger
+}
+
+// NewEventBus creates an event bus with no subscribers
+func NewEventBus(logger *logrus.Logger) *EventBus {
+	return &EventBus{logger: logger}
+}
+
+// Subscribe registers a subscriber for every subsequent event
+func (b *EventBus) Subscribe(sub EventSubscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers = append(b.subscribers, sub)
+}
+
+// Publish stamps the event and delivers it to every subscriber
+func (b *EventBus) Publish(ctx context.Context, event Event) {
+	if b == nil {
+		return
+	}
+	if event.ID == uuid.Nil {
+		event.ID = uuid.New()
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	b.mu.RLock()
+	subscribers := b.subscribers
+	b.mu.RUnlock()
+
+	b.logger.WithFields(logrus.Fields{
+		"event":       event.Type,
+		"prompt_id":   event.PromptID,
+		"subscribers": len(subscribers),
+	}).Debug("Publishing storage event")
+
+	for _, sub := range subscribers {
+		sub.HandleEvent(ctx, event)
+	}
+}
+
+// Close waits for subscribers that deliver asynchronously to finish
+func (b *EventBus) Close() {
+	if b == nil {
+		return
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, sub := range b.subscribers {
+		if closer, ok := sub.(interface{ Close() }); ok {
+			closer.Close()
+		}
+	}
+}
+
+// Events returns the bus that storage mutations are published on
+func (s *Storage) Events() *EventBus {
+	return s.events
+}
+
+// publishPrompt publishes a create or update event carrying a copy of p, so
+// subscribers never race with later changes to the caller's prompt
+func (s *Storage) publishPrompt(ctx context.Context, eventType EventType, p *models.Prompt) {
+	snapshot := *p
+	s.events.Publish(ctx, Event{Type: eventType, PromptID: p.ID, Prompt: &snapshot})
+}
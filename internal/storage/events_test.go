@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorageEvents(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	store, err := NewStorage(t.TempDir(), logger)
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	var events []Event
+	store.Events().Subscribe(EventSubscriberFunc(func(ctx context.Context, e Event) {
+		events = append(events, e)
+	}))
+
+	ctx := context.Background()
+	p := &models.Prompt{Content: "Write a haiku", Phase: models.PhasePrimaMaterial, SessionID: uuid.New()}
+	require.NoError(t, store.SavePrompt(ctx, p))
+	p.Content = "Write a limerick"
+	require.NoError(t, store.UpdatePrompt(ctx, p))
+	require.NoError(t, store.DeletePrompt(ctx, p.ID.String()))
+
+	require.Len(t, events, 3)
+	assert.Equal(t, EventPromptCreated, events[0].Type)
+	assert.Equal(t, "Write a haiku", events[0].Prompt.Content, "event keeps a snapshot of the saved prompt")
+	assert.Equal(t, EventPromptUpdated, events[1].Type)
+	assert.Equal(t, "Write a limerick", events[1].Prompt.Content)
+	assert.Equal(t, EventPromptDeleted, events[2].Type)
+	assert.Equal(t, p.ID, events[2].PromptID)
+	assert.Nil(t, events[2].Prompt)
+	for _, e := range events {
+		assert.NotEqual(t, uuid.Nil, e.ID)
+		assert.False(t, e.Timestamp.IsZero())
+	}
+}
+
+func TestWebhookSubscriber(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	var mu sync.Mutex
+	var received []Event
+	var signatures []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var e Event
+		if err := json.Unmarshal(body, &e); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		received = append(received, e)
+		signatures = append(signatures, r.Header.Get(WebhookSignatureHeader))
+		mu.Unlock()
+		assert.Equal(t, signWebhookBody("s3cret", body), r.Header.Get(WebhookSignatureHeader))
+		assert.Equal(t, string(e.Type), r.Header.Get(WebhookEventHeader))
+	}))
+	defer server.Close()
+
+	subscriber, err := NewWebhookSubscriber(WebhookConfig{
+		URL:    server.URL,
+		Events: []string{string(EventPromptDeleted)},
+		Secret: "s3cret",
+	}, logger)
+	require.NoError(t, err)
+
+	bus := NewEventBus(logger)
+	bus.Subscribe(subscriber)
+
+	deleted := uuid.New()
+	bus.Publish(context.Background(), Event{Type: EventPromptCreated, PromptID: uuid.New()})
+	bus.Publish(context.Background(), Event{Type: EventPromptDeleted, PromptID: deleted})
+	bus.Close()
+
+	require.Len(t, received, 1, "only subscribed event types are delivered")
+	assert.Equal(t, EventPromptDeleted, received[0].Type)
+	assert.Equal(t, deleted, received[0].PromptID)
+	assert.NotEmpty(t, signatures[0])
+}
+
+func TestWebhookSubscriberRequiresURL(t *testing.T) {
+	_, err := NewWebhookSubscriber(WebhookConfig{}, logrus.New())
+	assert.Error(t, err)
+}
@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/sirupsen/logrus"
+)
+
+// exportPageSize is how many prompts ExportPrompts reads at a time, a
+// variable so tests can page through a few prompts
+var exportPageSize = 500
+
+// ExportPrompts calls fn with each prompt matching criteria, newest first.
+// criteria.Limit caps the number of prompts when positive. Prompts are read
+// a page at a time and no statement is left open while fn runs, so an
+// export of any size holds one page in memory and doesn't block other
+// queries. An error from fn stops the export and is returned as is.
+func (s *Storage) ExportPrompts(ctx context.Context, criteria SearchCriteria, fn func(*models.Prompt) error) error {
+	ctx, span := s.startSpan(ctx, "ExportPrompts")
+	defer span.End()
+
+	filters, filterArgs := criteriaFilters(criteria)
+	var after *PromptCursor
+	exported := 0
+	for {
+		pageSize := exportPageSize
+		if criteria.Limit > 0 {
+			pageSize = min(pageSize, criteria.Limit-exported)
+		}
+		if pageSize <= 0 {
+			break
+		}
+
+		page, err := s.exportPage(filters, filterArgs, after, pageSize)
+		if err != nil {
+			return err
+		}
+
+		for _, p := range page {
+			if err := fn(p); err != nil {
+				return err
+			}
+		}
+		exported += len(page)
+		if len(page) < pageSize {
+			break
+		}
+		cursor := CursorFor(page[len(page)-1])
+		after = &cursor
+	}
+
+	s.loggerFor(ctx).WithFields(logrus.Fields{
+		"exported": exported,
+		"phase":    criteria.Phase,
+		"provider": criteria.Provider,
+	}).Debug("Exported prompts")
+	return nil
+}
+
+// exportPage reads up to limit prompts matching filters strictly after
+// after, newest first
+func (s *Storage) exportPage(filters []string, filterArgs []interface{}, after *PromptCursor, limit int) ([]*models.Prompt, error) {
+	where := append([]string{}, filters...)
+	args := append([]interface{}{}, filterArgs...)
+	if after != nil {
+		filter, afterArgs := afterFilter(*after)
+		where = append(where, filter)
+		args = append(args, afterArgs...)
+	}
+	args = append(args, limit)
+
+	clause := ""
+	if len(where) > 0 {
+		clause = " WHERE " + strings.Join(where, " AND ")
+	}
+	query := strings.Replace(s.baseSelectQuery(), ";", clause+" ORDER BY created_at DESC, id DESC LIMIT ?;", 1)
+
+	stmt, _, err := s.db.Prepare(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare export query: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+	bindArgs(stmt, args)
+
+	prompts, err := s.scanPrompts(stmt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan exported prompts: %w", err)
+	}
+	return prompts, nil
+}
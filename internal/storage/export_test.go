@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportPrompts(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	store, err := NewStorage(t.TempDir(), logger)
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	defer func(size int) { exportPageSize = size }(exportPageSize)
+	exportPageSize = 2
+
+	ctx := context.Background()
+	for i := 0; i < 7; i++ {
+		phase := models.PhaseSolutio
+		if i%2 == 1 {
+			phase = models.PhaseCoagulatio
+		}
+		require.NoError(t, store.SavePrompt(ctx, &models.Prompt{Content: fmt.Sprintf("prompt %d", i), Phase: phase, Provider: "openai"}))
+	}
+
+	export := func(criteria SearchCriteria) []string {
+		var contents []string
+		require.NoError(t, store.ExportPrompts(ctx, criteria, func(p *models.Prompt) error {
+			contents = append(contents, p.Content)
+			return nil
+		}))
+		return contents
+	}
+
+	t.Run("pages through every prompt once", func(t *testing.T) {
+		contents := export(SearchCriteria{})
+		assert.Len(t, contents, 7)
+		assert.ElementsMatch(t, []string{"prompt 0", "prompt 1", "prompt 2", "prompt 3", "prompt 4", "prompt 5", "prompt 6"}, contents)
+	})
+
+	t.Run("filters and limit", func(t *testing.T) {
+		assert.Len(t, export(SearchCriteria{Phase: string(models.PhaseSolutio)}), 4)
+		assert.Len(t, export(SearchCriteria{Limit: 3}), 3)
+		assert.Equal(t, []string{"prompt 5"}, export(SearchCriteria{Query: "prompt 5"}))
+	})
+
+	t.Run("callback errors stop the export", func(t *testing.T) {
+		stop := errors.New("client gone")
+		calls := 0
+		err := store.ExportPrompts(ctx, SearchCriteria{}, func(p *models.Prompt) error {
+			calls++
+			return stop
+		})
+		assert.ErrorIs(t, err, stop)
+		assert.Equal(t, 1, calls)
+	})
+}
@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/ncruces/go-sqlite3"
+)
+
+// SavePromptFeedback records an outcome reported for a prompt, filling in
+// its ID and creation time when unset
+func (s *Storage) SavePromptFeedback(ctx context.Context, feedback *models.PromptFeedback) error {
+	_, span := s.startSpan(ctx, "SavePromptFeedback")
+	defer span.End()
+
+	if !feedback.Signal.IsValid() {
+		return fmt.Errorf("unknown feedback signal %q", feedback.Signal)
+	}
+	if feedback.ID == uuid.Nil {
+		feedback.ID = uuid.New()
+	}
+	if feedback.CreatedAt.IsZero() {
+		feedback.CreatedAt = time.Now()
+	}
+
+	stmt, _, err := s.db.Prepare(`
+		INSERT INTO prompt_feedback (id, prompt_id, signal, score, outcome, comment, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare save feedback statement: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	_ = stmt.BindText(1, feedback.ID.String())
+	_ = stmt.BindText(2, feedback.PromptID.String())
+	_ = stmt.BindText(3, string(feedback.Signal))
+	if feedback.Score != nil {
+		_ = stmt.BindFloat(4, *feedback.Score)
+	} else {
+		_ = stmt.BindNull(4)
+	}
+	_ = stmt.BindFloat(5, feedback.Outcome())
+	_ = stmt.BindText(6, feedback.Comment)
+	_ = stmt.BindInt64(7, feedback.CreatedAt.Unix())
+
+	stmt.Step()
+	if err := stmt.Err(); err != nil {
+		return fmt.Errorf("failed to execute save feedback statement: %w", err)
+	}
+
+	s.loggerFor(ctx).WithField("prompt_id", feedback.PromptID).WithField("signal", feedback.Signal).Debug("Saved prompt feedback")
+	return nil
+}
+
+// GetPromptFeedbackSummary aggregates the feedback reported for a prompt.
+// A prompt without feedback gets an empty summary.
+func (s *Storage) GetPromptFeedbackSummary(ctx context.Context, promptID uuid.UUID) (*models.FeedbackSummary, error) {
+	stmt, _, err := s.db.Prepare(`
+		SELECT signal, COUNT(*), SUM(outcome), MAX(created_at)
+		FROM prompt_feedback WHERE prompt_id = ? GROUP BY signal`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare feedback summary query: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	_ = stmt.BindText(1, promptID.String())
+
+	summary := &models.FeedbackSummary{Signals: map[models.FeedbackSignal]int{}}
+	var outcomes float64
+	var last int64
+	for stmt.Step() {
+		count := stmt.ColumnInt(1)
+		summary.Signals[models.FeedbackSignal(stmt.ColumnText(0))] = count
+		summary.Total += count
+		outcomes += stmt.ColumnFloat(2)
+		if stmt.ColumnType(3) != sqlite3.NULL && stmt.ColumnInt64(3) > last {
+			last = stmt.ColumnInt64(3)
+		}
+	}
+	if err := stmt.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get feedback summary: %w", err)
+	}
+
+	if summary.Total > 0 {
+		summary.AverageOutcome = outcomes / float64(summary.Total)
+		lastAt := time.Unix(last, 0)
+		summary.LastFeedbackAt = &lastAt
+	}
+	return summary, nil
+}
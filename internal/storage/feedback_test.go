@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPromptFeedback(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	store, err := NewStorage(t.TempDir(), logger)
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	ctx := context.Background()
+	prompt := &models.Prompt{Content: "rated", Phase: models.PhaseSolutio, Provider: "openai", Model: "gpt-4"}
+	require.NoError(t, store.SavePrompt(ctx, prompt))
+
+	t.Run("no feedback", func(t *testing.T) {
+		summary, err := store.GetPromptFeedbackSummary(ctx, prompt.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 0, summary.Total)
+		assert.Empty(t, summary.Signals)
+		assert.Nil(t, summary.LastFeedbackAt)
+	})
+
+	t.Run("saved feedback is aggregated", func(t *testing.T) {
+		half := 0.5
+		last := time.Now().Add(time.Minute).Truncate(time.Second)
+		for _, feedback := range []*models.PromptFeedback{
+			{PromptID: prompt.ID, Signal: models.FeedbackThumbsUp},
+			{PromptID: prompt.ID, Signal: models.FeedbackThumbsUp, Comment: "great"},
+			{PromptID: prompt.ID, Signal: models.FeedbackThumbsDown},
+			{PromptID: prompt.ID, Signal: models.FeedbackConversion, Score: &half, CreatedAt: last},
+		} {
+			require.NoError(t, store.SavePromptFeedback(ctx, feedback))
+			assert.NotEqual(t, uuid.Nil, feedback.ID)
+		}
+
+		summary, err := store.GetPromptFeedbackSummary(ctx, prompt.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 4, summary.Total)
+		assert.Equal(t, map[models.FeedbackSignal]int{
+			models.FeedbackThumbsUp:   2,
+			models.FeedbackThumbsDown: 1,
+			models.FeedbackConversion: 1,
+		}, summary.Signals)
+		assert.InDelta(t, (1+1+0+0.5)/4.0, summary.AverageOutcome, 1e-9)
+		require.NotNil(t, summary.LastFeedbackAt)
+		assert.True(t, last.Equal(*summary.LastFeedbackAt))
+	})
+
+	t.Run("unknown signal is rejected", func(t *testing.T) {
+		assert.Error(t, store.SavePromptFeedback(ctx, &models.PromptFeedback{PromptID: prompt.ID, Signal: "meh"}))
+	})
+
+	t.Run("deleted with the prompt", func(t *testing.T) {
+		require.NoError(t, store.DeletePrompt(ctx, prompt.ID.String()))
+		summary, err := store.GetPromptFeedbackSummary(ctx, prompt.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 0, summary.Total)
+	})
+}
@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ncruces/go-sqlite3"
+)
+
+var (
+	// ErrIdempotencyKeyInFlight is returned when the first request sent with
+	// an idempotency key has not finished yet
+	ErrIdempotencyKeyInFlight = errors.New("a request with this idempotency key is in progress")
+	// ErrIdempotencyKeyReused is returned when an idempotency key is sent
+	// with a different request than the one it was first used for
+	ErrIdempotencyKeyReused = errors.New("idempotency key was already used for a different request")
+)
+
+// ReserveIdempotencyKey claims key for a request whose content hashes to
+// requestHash, for ttl. If the key was already used for the same request and
+// that request finished, its saved response is returned. A nil response
+// means the caller now holds the key and must either complete or release
+// it. Instances that share the database share the keys.
+func (s *Storage) ReserveIdempotencyKey(ctx context.Context, key, requestHash string, ttl time.Duration) ([]byte, error) {
+	now := time.Now()
+	if err := s.deleteIdempotencyKeysBefore(now); err != nil {
+		s.loggerFor(ctx).WithError(err).Warn("Failed to clean up expired idempotency keys")
+	}
+
+	reserved, err := s.insertIdempotencyKey(key, requestHash, now.Add(ttl))
+	if err != nil || reserved {
+		return nil, err
+	}
+
+	stmt, _, err := s.db.Prepare("SELECT request_hash, response FROM idempotency_keys WHERE idempotency_key = ?")
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare get idempotency key query: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	_ = stmt.BindText(1, key)
+	if !stmt.Step() {
+		if err := stmt.Err(); err != nil {
+			return nil, fmt.Errorf("failed to get idempotency key: %w", err)
+		}
+		// Released between the insert and the query
+		return nil, ErrIdempotencyKeyInFlight
+	}
+	if stmt.ColumnText(0) != requestHash {
+		return nil, ErrIdempotencyKeyReused
+	}
+	if stmt.ColumnType(1) == sqlite3.NULL {
+		return nil, ErrIdempotencyKeyInFlight
+	}
+	return []byte(stmt.ColumnText(1)), nil
+}
+
+// insertIdempotencyKey adds key unless it already exists, reporting whether it did
+func (s *Storage) insertIdempotencyKey(key, requestHash string, expiresAt time.Time) (bool, error) {
+	stmt, _, err := s.db.Prepare(`
+		INSERT INTO idempotency_keys (idempotency_key, request_hash, expires_at) VALUES (?, ?, ?)
+		ON CONFLICT(idempotency_key) DO NOTHING
+		RETURNING idempotency_key`)
+	if err != nil {
+		return false, fmt.Errorf("failed to prepare reserve idempotency key statement: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	_ = stmt.BindText(1, key)
+	_ = stmt.BindText(2, requestHash)
+	_ = stmt.BindInt64(3, expiresAt.Unix())
+
+	inserted := stmt.Step()
+	// Finish the statement so the insert is applied
+	stmt.Step()
+	if err := stmt.Err(); err != nil {
+		return false, fmt.Errorf("failed to execute reserve idempotency key statement: %w", err)
+	}
+	return inserted, nil
+}
+
+// CompleteIdempotencyKey saves the response to the request holding key, to be
+// returned for any retry until the key expires
+func (s *Storage) CompleteIdempotencyKey(ctx context.Context, key string, response []byte) error {
+	stmt, _, err := s.db.Prepare("UPDATE idempotency_keys SET response = ? WHERE idempotency_key = ?")
+	if err != nil {
+		return fmt.Errorf("failed to prepare complete idempotency key statement: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	_ = stmt.BindText(1, string(response))
+	_ = stmt.BindText(2, key)
+
+	stmt.Step()
+	if err := stmt.Err(); err != nil {
+		return fmt.Errorf("failed to execute complete idempotency key statement: %w", err)
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey gives up key without saving a response, so the
+// request can be retried with it, for example after the request failed
+func (s *Storage) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	stmt, _, err := s.db.Prepare("DELETE FROM idempotency_keys WHERE idempotency_key = ? AND response IS NULL")
+	if err != nil {
+		return fmt.Errorf("failed to prepare release idempotency key statement: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	_ = stmt.BindText(1, key)
+	stmt.Step()
+	if err := stmt.Err(); err != nil {
+		return fmt.Errorf("failed to execute release idempotency key statement: %w", err)
+	}
+	return nil
+}
+
+// deleteIdempotencyKeysBefore removes keys that expired by cutoff
+func (s *Storage) deleteIdempotencyKeysBefore(cutoff time.Time) error {
+	stmt, _, err := s.db.Prepare("DELETE FROM idempotency_keys WHERE expires_at <= ?")
+	if err != nil {
+		return fmt.Errorf("failed to prepare idempotency key cleanup statement: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	_ = stmt.BindInt64(1, cutoff.Unix())
+	stmt.Step()
+	return stmt.Err()
+}
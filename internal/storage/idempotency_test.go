@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotencyKeys(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	store, err := NewStorage(t.TempDir(), logger)
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	ctx := context.Background()
+
+	t.Run("first request reserves, retries get its response", func(t *testing.T) {
+		response, err := store.ReserveIdempotencyKey(ctx, "key-1", "hash-a", time.Hour)
+		require.NoError(t, err)
+		assert.Nil(t, response, "the key is reserved")
+
+		_, err = store.ReserveIdempotencyKey(ctx, "key-1", "hash-a", time.Hour)
+		assert.ErrorIs(t, err, ErrIdempotencyKeyInFlight)
+
+		require.NoError(t, store.CompleteIdempotencyKey(ctx, "key-1", []byte(`{"session_id":"s1"}`)))
+		response, err = store.ReserveIdempotencyKey(ctx, "key-1", "hash-a", time.Hour)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"session_id":"s1"}`, string(response))
+
+		require.NoError(t, store.ReleaseIdempotencyKey(ctx, "key-1"))
+		response, err = store.ReserveIdempotencyKey(ctx, "key-1", "hash-a", time.Hour)
+		require.NoError(t, err)
+		assert.NotNil(t, response, "completed keys are not released")
+	})
+
+	t.Run("a different request with the same key is refused", func(t *testing.T) {
+		_, err := store.ReserveIdempotencyKey(ctx, "key-1", "hash-b", time.Hour)
+		assert.ErrorIs(t, err, ErrIdempotencyKeyReused)
+	})
+
+	t.Run("released keys can be reserved again", func(t *testing.T) {
+		_, err := store.ReserveIdempotencyKey(ctx, "key-2", "hash-a", time.Hour)
+		require.NoError(t, err)
+		require.NoError(t, store.ReleaseIdempotencyKey(ctx, "key-2"))
+
+		response, err := store.ReserveIdempotencyKey(ctx, "key-2", "hash-a", time.Hour)
+		require.NoError(t, err)
+		assert.Nil(t, response)
+	})
+
+	t.Run("expired keys are forgotten", func(t *testing.T) {
+		_, err := store.ReserveIdempotencyKey(ctx, "key-3", "hash-a", 0)
+		require.NoError(t, err)
+		require.NoError(t, store.CompleteIdempotencyKey(ctx, "key-3", []byte(`{}`)))
+
+		response, err := store.ReserveIdempotencyKey(ctx, "key-3", "hash-b", time.Hour)
+		require.NoError(t, err)
+		assert.Nil(t, response, "the expired key is reserved anew, even for another request")
+	})
+}
@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrDuplicateContent is returned for an imported prompt whose content is
+// already stored under another ID
+var ErrDuplicateContent = errors.New("content is already stored")
+
+// ImportPrompts saves a batch of prompts in one transaction. Each prompt is
+// saved under its own savepoint, so one that fails is rolled back without
+// affecting the rest. With upsert, prompts keep their IDs and replace any
+// existing prompt, whose previous state is kept as a version; otherwise
+// every prompt is given a new ID. Prompts are unique by content, so one
+// repeating the content of a prompt stored under another ID is skipped
+// with ErrDuplicateContent, never overwriting that prompt. It returns one
+// error per prompt, nil for those saved, and an error of its own only if
+// the transaction fails.
+func (s *Storage) ImportPrompts(ctx context.Context, prompts []*models.Prompt, upsert bool) ([]error, error) {
+	ctx, span := s.startSpan(ctx, "ImportPrompts")
+	defer span.End()
+
+	tx, err := s.db.BeginImmediate()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin import transaction: %w", err)
+	}
+
+	errs := make([]error, len(prompts))
+	events := make([]EventType, len(prompts))
+	imported := 0
+	for i, p := range prompts {
+		if !upsert || p.ID == uuid.Nil {
+			p.ID = uuid.New()
+		}
+		events[i], errs[i] = s.importPrompt(ctx, p, upsert)
+		if errs[i] == nil {
+			imported++
+		}
+	}
+	tx.End(&err)
+	if err != nil {
+		return nil, err
+	}
+
+	// Publish only once the batch is committed
+	for i, p := range prompts {
+		if errs[i] == nil {
+			s.publishPrompt(ctx, events[i], p)
+		}
+	}
+
+	s.loggerFor(ctx).WithFields(logrus.Fields{
+		"imported": imported,
+		"failed":   len(prompts) - imported,
+		"upsert":   upsert,
+	}).Debug("Imported prompt batch")
+	return errs, nil
+}
+
+// importPrompt saves p under a savepoint that is rolled back if it fails
+func (s *Storage) importPrompt(ctx context.Context, p *models.Prompt, upsert bool) (eventType EventType, err error) {
+	savepoint := s.db.Savepoint()
+	defer savepoint.Release(&err)
+
+	existing, err := s.promptIDByContent(p.Content, p.ID)
+	if err != nil {
+		return "", err
+	}
+	if existing != uuid.Nil {
+		return "", fmt.Errorf("%w as prompt %s", ErrDuplicateContent, existing)
+	}
+	if upsert {
+		return s.updatePrompt(ctx, p)
+	}
+	return s.savePrompt(ctx, p)
+}
@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportPrompts(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	store, err := NewStorage(t.TempDir(), logger)
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	ctx := context.Background()
+	existing := &models.Prompt{Content: "original", Phase: models.PhaseSolutio, Provider: "openai"}
+	require.NoError(t, store.SavePrompt(ctx, existing))
+
+	t.Run("failed prompts are rolled back alone", func(t *testing.T) {
+		good := &models.Prompt{Content: "good", Phase: models.PhaseSolutio, Embedding: []float32{1, 0, 0}}
+		// Fails after its row is written: the embedding has the wrong size
+		bad := &models.Prompt{Content: "bad", Phase: models.PhaseSolutio, Embedding: []float32{1, 0}}
+		errs, err := store.ImportPrompts(ctx, []*models.Prompt{good, bad}, false)
+		require.NoError(t, err)
+		require.Len(t, errs, 2)
+		assert.NoError(t, errs[0])
+		assert.Error(t, errs[1])
+
+		_, err = store.GetPromptByID(ctx, good.ID)
+		assert.NoError(t, err)
+		_, err = store.GetPromptByID(ctx, bad.ID)
+		assert.ErrorIs(t, err, ErrPromptNotFound)
+	})
+
+	t.Run("insert assigns new IDs", func(t *testing.T) {
+		p := &models.Prompt{ID: existing.ID, Content: "copy", Phase: models.PhaseSolutio}
+		errs, err := store.ImportPrompts(ctx, []*models.Prompt{p}, false)
+		require.NoError(t, err)
+		require.NoError(t, errs[0])
+		assert.NotEqual(t, existing.ID, p.ID)
+
+		original, err := store.GetPromptByID(ctx, existing.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "original", original.Content)
+	})
+
+	t.Run("upsert keeps IDs and versions replaced prompts", func(t *testing.T) {
+		fresh := uuid.New()
+		errs, err := store.ImportPrompts(ctx, []*models.Prompt{
+			{ID: existing.ID, Content: "replaced", Phase: models.PhaseSolutio},
+			{ID: fresh, Content: "new", Phase: models.PhaseSolutio},
+		}, true)
+		require.NoError(t, err)
+		assert.Equal(t, []error{nil, nil}, errs)
+
+		replaced, err := store.GetPromptByID(ctx, existing.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "replaced", replaced.Content)
+		versions, err := store.GetPromptVersions(ctx, existing.ID)
+		require.NoError(t, err)
+		require.Len(t, versions, 1)
+		assert.Equal(t, "original", versions[0].Content)
+
+		_, err = store.GetPromptByID(ctx, fresh)
+		assert.NoError(t, err)
+	})
+
+	t.Run("content stored under another ID is skipped", func(t *testing.T) {
+		taken := &models.Prompt{Content: "taken", Phase: models.PhaseSolutio}
+		require.NoError(t, store.SavePrompt(ctx, taken))
+
+		for _, upsert := range []bool{false, true} {
+			errs, err := store.ImportPrompts(ctx, []*models.Prompt{
+				{ID: existing.ID, Content: "taken", Phase: models.PhaseSolutio},
+			}, upsert)
+			require.NoError(t, err)
+			require.ErrorIs(t, errs[0], ErrDuplicateContent, "upsert=%v", upsert)
+			assert.ErrorContains(t, errs[0], taken.ID.String())
+		}
+
+		kept, err := store.GetPromptByID(ctx, existing.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "replaced", kept.Content, "the prompt with the ID is left alone")
+	})
+}
@@ -0,0 +1,28 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+)
+
+// StorageInterface is the prompt storage the engine, ranker, learner and
+// optimizer depend on, so they can run against a test double
+type StorageInterface interface {
+	Close() error
+	SavePrompt(ctx context.Context, prompt *models.Prompt) error
+	GetPromptByID(ctx context.Context, id uuid.UUID) (*models.Prompt, error)
+	GetPromptsWithoutEmbeddings(ctx context.Context, limit int) ([]*models.Prompt, error)
+	UpdatePromptRelevanceScore(ctx context.Context, id uuid.UUID, score float64) error
+	SearchSimilarPrompts(ctx context.Context, embedding []float32, limit int) ([]*models.Prompt, error)
+	GetHighQualityHistoricalPrompts(ctx context.Context, limit int) ([]*models.Prompt, error)
+	SearchSimilarHighQualityPrompts(ctx context.Context, embedding []float32, minScore float64, limit int) ([]*models.Prompt, error)
+	SaveInteraction(ctx context.Context, interaction *models.UserInteraction) error
+	StreamPrompts(ctx context.Context, limit, offset int, fn func(*models.Prompt) error) error
+	FindDuplicate(ctx context.Context, p *models.Prompt, minSimilarity float64) (*Duplicate, error)
+	SetEmbeddingConfig(provider, model string, dims int)
+	GetEmbeddingConfig() (provider, model string, dims int)
+}
+
+var _ StorageInterface = (*Storage)(nil)
@@ -0,0 +1,31 @@
+package storage
+
+import "sync"
+
+// jobs tracks the background maintenance jobs a Storage runs off the
+// request path. One lock covers every job's state so a job can check for
+// conflicting work and claim its slot in one step.
+type jobs struct {
+	mu       sync.Mutex
+	reindex  ReindexStatus
+	backfill BackfillStatus
+}
+
+// update runs fn with the job state locked
+func (j *jobs) update(fn func(*jobs)) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	fn(j)
+}
+
+// running returns the error for the job in progress, if any. The caller
+// holds mu.
+func (j *jobs) running() error {
+	switch {
+	case j.reindex.State == ReindexRunning:
+		return ErrReindexRunning
+	case j.backfill.State == BackfillRunning:
+		return ErrBackfillRunning
+	}
+	return nil
+}
@@ -0,0 +1,334 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/sirupsen/logrus"
+)
+
+// relationshipNamespace derives stable relationship IDs so re-saving a prompt
+// does not duplicate its relationships
+var relationshipNamespace = uuid.MustParse("6f1c1a52-9a5e-4c0e-8d43-3f0c2b7b9e11")
+
+// SaveRelationship records a relationship between two prompts. Saving the
+// same source, target and type again is a no-op.
+func (s *Storage) SaveRelationship(ctx context.Context, rel *models.PromptRelationship) error {
+	if rel.ID == uuid.Nil {
+		key := rel.SourcePromptID.String() + ":" + rel.TargetPromptID.String() + ":" + rel.Type
+		rel.ID = uuid.NewSHA1(relationshipNamespace, []byte(key))
+	}
+	if rel.CreatedAt.IsZero() {
+		rel.CreatedAt = time.Now()
+	}
+	return s.withRetry(ctx, "save_relationship", func() error { return s.insertRelationship(rel) })
+}
+
+// insertRelationship writes rel unless its ID is already stored
+func (s *Storage) insertRelationship(rel *models.PromptRelationship) error {
+	stmt, _, err := s.db.Prepare(`
+		INSERT OR IGNORE INTO prompt_relationships (
+			id, source_prompt_id, target_prompt_id, relationship_type, strength, context, created_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare save relationship statement: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	_ = stmt.BindText(1, rel.ID.String())
+	_ = stmt.BindText(2, rel.SourcePromptID.String())
+	_ = stmt.BindText(3, rel.TargetPromptID.String())
+	_ = stmt.BindText(4, rel.Type)
+	_ = stmt.BindFloat(5, rel.Strength)
+	_ = stmt.BindText(6, rel.Context)
+	_ = stmt.BindInt64(7, rel.CreatedAt.Unix())
+
+	stmt.Step()
+	if err := stmt.Err(); err != nil {
+		return fmt.Errorf("failed to execute save relationship statement: %w", err)
+	}
+	return nil
+}
+
+// saveDerivedFrom links a prompt to its parent when it has one
+func (s *Storage) saveDerivedFrom(ctx context.Context, p *models.Prompt) error {
+	if p.ParentID == nil || *p.ParentID == uuid.Nil || *p.ParentID == p.ID {
+		return nil
+	}
+	return s.SaveRelationship(ctx, &models.PromptRelationship{
+		SourcePromptID: p.ID,
+		TargetPromptID: *p.ParentID,
+		Type:           models.RelationshipDerivedFrom,
+		Strength:       1.0,
+		Context:        fmt.Sprintf("phase=%s", p.Phase),
+	})
+}
+
+// GetRelationships returns the relationships of type relType from the
+// prompt id to others, oldest first. An empty relType returns every type.
+func (s *Storage) GetRelationships(ctx context.Context, id uuid.UUID, relType string) ([]*models.PromptRelationship, error) {
+	stmt, _, err := s.db.Prepare(`
+		SELECT id, source_prompt_id, target_prompt_id, relationship_type, strength, context, created_at
+		FROM prompt_relationships
+		WHERE source_prompt_id = ?1 AND (?2 = '' OR relationship_type = ?2)
+		ORDER BY created_at, rowid`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare relationships query: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	_ = stmt.BindText(1, id.String())
+	_ = stmt.BindText(2, relType)
+
+	var relationships []*models.PromptRelationship
+	for stmt.Step() {
+		rel := &models.PromptRelationship{
+			Type:      stmt.ColumnText(3),
+			Strength:  stmt.ColumnFloat(4),
+			Context:   stmt.ColumnText(5),
+			CreatedAt: time.Unix(stmt.ColumnInt64(6), 0),
+		}
+		rel.ID, _ = uuid.Parse(stmt.ColumnText(0))
+		rel.SourcePromptID, _ = uuid.Parse(stmt.ColumnText(1))
+		rel.TargetPromptID, _ = uuid.Parse(stmt.ColumnText(2))
+		relationships = append(relationships, rel)
+	}
+	if err := stmt.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read relationships: %w", err)
+	}
+	return relationships, nil
+}
+
+// GetPromptsBySession returns all prompts saved for a session grouped by
+// phase in cascade order: the built-in phases first, then custom phases in
+// the order they were saved. Within a phase prompts are oldest first.
+func (s *Storage) GetPromptsBySession(ctx context.Context, sessionID uuid.UUID) ([]*models.Prompt, error) {
+	query := strings.Replace(s.baseSelectQuery(), ";", `
+		WHERE session_id = ?1
+		ORDER BY
+			CASE phase WHEN ?2 THEN 0 WHEN ?3 THEN 1 WHEN ?4 THEN 2 ELSE 3 END,
+			(SELECT MIN(first.rowid) FROM prompts first WHERE first.session_id = ?1 AND first.phase = prompts.phase),
+			rowid;`, 1)
+	stmt, _, err := s.db.Prepare(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare session prompts query: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	_ = stmt.BindText(1, sessionID.String())
+	_ = stmt.BindText(2, string(models.PhasePrimaMaterial))
+	_ = stmt.BindText(3, string(models.PhaseSolutio))
+	_ = stmt.BindText(4, string(models.PhaseCoagulatio))
+
+	prompts, err := s.scanPrompts(stmt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan session prompts: %w", err)
+	}
+	return prompts, nil
+}
+
+// getDerivedFromParents maps each prompt in the session to the prompt it was
+// derived from, according to prompt_relationships
+func (s *Storage) getDerivedFromParents(ctx context.Context, sessionID uuid.UUID) (map[uuid.UUID]uuid.UUID, error) {
+	stmt, _, err := s.db.Prepare(`
+		SELECT r.source_prompt_id, r.target_prompt_id
+		FROM prompt_relationships r
+		JOIN prompts p ON p.id = r.source_prompt_id
+		WHERE p.session_id = ? AND r.relationship_type = ?`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare lineage relationships query: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	_ = stmt.BindText(1, sessionID.String())
+	_ = stmt.BindText(2, models.RelationshipDerivedFrom)
+
+	parents := make(map[uuid.UUID]uuid.UUID)
+	for stmt.Step() {
+		source, err := uuid.Parse(stmt.ColumnText(0))
+		if err != nil {
+			continue
+		}
+		target, err := uuid.Parse(stmt.ColumnText(1))
+		if err != nil {
+			continue
+		}
+		parents[source] = target
+	}
+	if err := stmt.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read lineage relationships: %w", err)
+	}
+	return parents, nil
+}
+
+// GetSessionLineage returns the cascade chains of a session. Each chain is
+// ordered from the first phase's prompt to the final prompt derived from it;
+// a prompt that fed several later prompts appears at the head of each of
+// their chains.
+func (s *Storage) GetSessionLineage(ctx context.Context, sessionID uuid.UUID) ([][]*models.Prompt, error) {
+	prompts, err := s.GetPromptsBySession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if len(prompts) == 0 {
+		return nil, nil
+	}
+
+	parents, err := s.getDerivedFromParents(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildLineageChains(prompts, parents), nil
+}
+
+// buildLineageChains assembles root-to-leaf chains from prompts and their
+// derived_from parents. Prompts without a relationship fall back to ParentID.
+// Input order is preserved among roots and among siblings.
+func buildLineageChains(prompts []*models.Prompt, parents map[uuid.UUID]uuid.UUID) [][]*models.Prompt {
+	byID := make(map[uuid.UUID]*models.Prompt, len(prompts))
+	for _, p := range prompts {
+		byID[p.ID] = p
+	}
+
+	children := make(map[uuid.UUID][]*models.Prompt)
+	var roots []*models.Prompt
+	for _, p := range prompts {
+		parentID, ok := parents[p.ID]
+		if !ok && p.ParentID != nil {
+			parentID, ok = *p.ParentID, true
+		}
+		if ok {
+			if _, inSession := byID[parentID]; inSession && parentID != p.ID {
+				children[parentID] = append(children[parentID], p)
+				continue
+			}
+		}
+		roots = append(roots, p)
+	}
+
+	var chains [][]*models.Prompt
+	visited := make(map[uuid.UUID]bool, len(prompts))
+	var walk func(p *models.Prompt, chain []*models.Prompt)
+	walk = func(p *models.Prompt, chain []*models.Prompt) {
+		if visited[p.ID] {
+			return
+		}
+		visited[p.ID] = true
+		defer delete(visited, p.ID)
+
+		chain = append(chain, p)
+		if len(children[p.ID]) == 0 {
+			chains = append(chains, append([]*models.Prompt(nil), chain...))
+			return
+		}
+		for _, child := range children[p.ID] {
+			walk(child, chain)
+		}
+	}
+	for _, root := range roots {
+		walk(root, nil)
+	}
+	return chains
+}
+
+// ErrPromptNotEmbedded is returned when a prompt has no embedding under the
+// current embedding model, e.g. before a reindex
+var ErrPromptNotEmbedded = errors.New("prompt has no embedding")
+
+// DiscoverSimilarRelationships links a prompt to the stored prompts whose
+// embeddings are at least threshold similar to its own, most similar first
+// and at most maxLinks of them. Each link is saved as similar_to with the
+// similarity as its strength; prompts already linked as similar_to in either
+// direction are skipped. It returns the links it created.
+func (s *Storage) DiscoverSimilarRelationships(ctx context.Context, id uuid.UUID, threshold float64, maxLinks int) ([]*models.PromptRelationship, error) {
+	ctx, span := s.startSpan(ctx, "DiscoverSimilarRelationships")
+	defer span.End()
+
+	if _, err := s.GetPromptByID(ctx, id); err != nil {
+		return nil, err
+	}
+	embedding, err := s.GetPromptEmbedding(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(embedding) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrPromptNotEmbedded, id)
+	}
+
+	linked, err := s.getSimilarPromptIDs(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// The prompt itself and those already linked can take up result slots
+	collection := s.getOrCreateCollection()
+	results, err := collection.QueryEmbedding(ctx, embedding, min(maxLinks+len(linked)+1, collection.Count()), nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query vector collection: %w", err)
+	}
+
+	created := []*models.PromptRelationship{}
+	for _, result := range results {
+		similarity := float64(result.Similarity)
+		if similarity < threshold || len(created) >= maxLinks {
+			break // results are ordered by similarity
+		}
+		targetID, err := uuid.Parse(result.ID)
+		if err != nil || targetID == id || linked[targetID] {
+			continue
+		}
+
+		rel := &models.PromptRelationship{
+			SourcePromptID: id,
+			TargetPromptID: targetID,
+			Type:           models.RelationshipSimilarTo,
+			Strength:       similarity,
+			Context:        "discovered by embedding similarity",
+		}
+		if err := s.SaveRelationship(ctx, rel); err != nil {
+			return created, err
+		}
+		created = append(created, rel)
+	}
+
+	s.loggerFor(ctx).WithFields(logrus.Fields{
+		"prompt_id": id,
+		"threshold": threshold,
+		"linked":    len(created),
+	}).Debug("Discovered similar prompts")
+
+	return created, nil
+}
+
+// getSimilarPromptIDs returns the prompts linked to id as similar_to, in
+// either direction
+func (s *Storage) getSimilarPromptIDs(ctx context.Context, id uuid.UUID) (map[uuid.UUID]bool, error) {
+	stmt, _, err := s.db.Prepare(`
+		SELECT CASE WHEN source_prompt_id = ?1 THEN target_prompt_id ELSE source_prompt_id END
+		FROM prompt_relationships
+		WHERE (source_prompt_id = ?1 OR target_prompt_id = ?1) AND relationship_type = ?2`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare similar relationships query: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	_ = stmt.BindText(1, id.String())
+	_ = stmt.BindText(2, models.RelationshipSimilarTo)
+
+	linked := make(map[uuid.UUID]bool)
+	for stmt.Step() {
+		if other, err := uuid.Parse(stmt.ColumnText(0)); err == nil {
+			linked[other] = true
+		}
+	}
+	if err := stmt.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read similar relationships: %w", err)
+	}
+	return linked, nil
+}
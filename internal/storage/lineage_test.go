@@ -0,0 +1,145 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildLineageChains(t *testing.T) {
+	newPrompt := func(phase models.Phase, parent *models.Prompt) *models.Prompt {
+		p := &models.Prompt{ID: uuid.New(), Phase: phase}
+		if parent != nil {
+			parentID := parent.ID
+			p.ParentID = &parentID
+		}
+		return p
+	}
+
+	ids := func(chains [][]*models.Prompt) [][]uuid.UUID {
+		out := make([][]uuid.UUID, len(chains))
+		for i, chain := range chains {
+			for _, p := range chain {
+				out[i] = append(out[i], p.ID)
+			}
+		}
+		return out
+	}
+
+	t.Run("parallel cascades", func(t *testing.T) {
+		a1 := newPrompt(models.PhasePrimaMaterial, nil)
+		b1 := newPrompt(models.PhasePrimaMaterial, nil)
+		a2 := newPrompt(models.PhaseSolutio, a1)
+		b2 := newPrompt(models.PhaseSolutio, b1)
+		a3 := newPrompt(models.PhaseCoagulatio, a2)
+		b3 := newPrompt(models.PhaseCoagulatio, b2)
+
+		chains := buildLineageChains([]*models.Prompt{a1, b1, a2, b2, a3, b3}, nil)
+
+		assert.Equal(t, [][]uuid.UUID{
+			{a1.ID, a2.ID, a3.ID},
+			{b1.ID, b2.ID, b3.ID},
+		}, ids(chains))
+	})
+
+	t.Run("relationships take precedence over parent id", func(t *testing.T) {
+		a := newPrompt(models.PhasePrimaMaterial, nil)
+		b := newPrompt(models.PhasePrimaMaterial, nil)
+		c := newPrompt(models.PhaseSolutio, a)
+
+		chains := buildLineageChains([]*models.Prompt{a, b, c}, map[uuid.UUID]uuid.UUID{c.ID: b.ID})
+
+		assert.Equal(t, [][]uuid.UUID{{a.ID}, {b.ID, c.ID}}, ids(chains))
+	})
+
+	t.Run("parent outside session starts a chain", func(t *testing.T) {
+		outside := newPrompt(models.PhasePrimaMaterial, nil)
+		p := newPrompt(models.PhaseSolutio, outside)
+
+		chains := buildLineageChains([]*models.Prompt{p}, nil)
+
+		assert.Equal(t, [][]uuid.UUID{{p.ID}}, ids(chains))
+	})
+
+	t.Run("self reference is ignored", func(t *testing.T) {
+		p := newPrompt(models.PhasePrimaMaterial, nil)
+		p.ParentID = &p.ID
+
+		chains := buildLineageChains([]*models.Prompt{p}, nil)
+
+		assert.Equal(t, [][]uuid.UUID{{p.ID}}, ids(chains))
+	})
+}
+
+func TestGetPromptsBySession(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	store, err := NewStorage(t.TempDir(), logger)
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	ctx := context.Background()
+	sessionID := uuid.New()
+	save := func(content string, phase models.Phase, session uuid.UUID) *models.Prompt {
+		p := &models.Prompt{Content: content, Phase: phase, Provider: "openai", SessionID: session}
+		require.NoError(t, store.SavePrompt(ctx, p))
+		return p
+	}
+
+	// Saved out of cascade order, interleaved with another session
+	polish := save("Polish the outline", "polish", sessionID)
+	solutio := save("Outline the talk for humans", models.PhaseSolutio, sessionID)
+	prima1 := save("Outline the talk", models.PhasePrimaMaterial, sessionID)
+	save("Unrelated prompt", models.PhasePrimaMaterial, uuid.New())
+	coagulatio := save("Outline the talk precisely", models.PhaseCoagulatio, sessionID)
+	prima2 := save("Sketch the talk", models.PhasePrimaMaterial, sessionID)
+
+	prompts, err := store.GetPromptsBySession(ctx, sessionID)
+	require.NoError(t, err)
+
+	ids := make([]uuid.UUID, len(prompts))
+	for i, p := range prompts {
+		ids[i] = p.ID
+		assert.Equal(t, sessionID, p.SessionID)
+	}
+	assert.Equal(t, []uuid.UUID{prima1.ID, prima2.ID, solutio.ID, coagulatio.ID, polish.ID}, ids)
+
+	prompts, err = store.GetPromptsBySession(ctx, uuid.New())
+	require.NoError(t, err)
+	assert.Empty(t, prompts)
+}
+
+func TestGetRelationships(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	store, err := NewStorage(t.TempDir(), logger)
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	ctx := context.Background()
+	parent := &models.Prompt{Content: "Outline the talk", Phase: models.PhasePrimaMaterial}
+	require.NoError(t, store.SavePrompt(ctx, parent))
+	child := &models.Prompt{Content: "Outline the talk for humans", Phase: models.PhaseSolutio, ParentID: &parent.ID}
+	require.NoError(t, store.SavePrompt(ctx, child))
+
+	relationships, err := store.GetRelationships(ctx, child.ID, models.RelationshipDerivedFrom)
+	require.NoError(t, err)
+	require.Len(t, relationships, 1)
+	assert.Equal(t, child.ID, relationships[0].SourcePromptID)
+	assert.Equal(t, parent.ID, relationships[0].TargetPromptID)
+	assert.Equal(t, models.RelationshipDerivedFrom, relationships[0].Type)
+	assert.Equal(t, "phase=solutio", relationships[0].Context)
+
+	all, err := store.GetRelationships(ctx, child.ID, "")
+	require.NoError(t, err)
+	assert.Len(t, all, 1)
+
+	none, err := store.GetRelationships(ctx, parent.ID, models.RelationshipDerivedFrom)
+	require.NoError(t, err)
+	assert.Empty(t, none, "relationships are read from their source")
+}
@@ -0,0 +1,178 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// CleanupOptions selects the prompts CleanupOldPrompts removes
+type CleanupOptions struct {
+	// MaxPrompts is how many prompts are kept; the least relevant beyond it
+	// are removed. Zero means no cap.
+	MaxPrompts int
+	// MinRelevanceScore removes prompts scoring below it
+	MinRelevanceScore float64
+	// MinAge keeps prompts created more recently than this, whatever their
+	// score
+	MinAge time.Duration
+	// DryRun reports what would be removed without deleting anything
+	DryRun bool
+}
+
+// CleanupResult is what CleanupOldPrompts removed, or would remove on a dry
+// run
+type CleanupResult struct {
+	Deleted   int         `json:"deleted"`
+	PromptIDs []uuid.UUID `json:"prompt_ids"`
+}
+
+// UpdateRelevanceScores decays the relevance of every prompt that hasn't been
+// used or updated for at least a day by decayRate per idle day, compounding,
+// and returns how many prompts were affected. A dry run counts them without
+// writing.
+func (s *Storage) UpdateRelevanceScores(ctx context.Context, decayRate float64, dryRun bool) (updated int, err error) {
+	ctx, span := s.startSpan(ctx, "UpdateRelevanceScores")
+	defer span.End()
+
+	if decayRate < 0 || decayRate >= 1 {
+		return 0, fmt.Errorf("decay rate must be in [0, 1), got %g", decayRate)
+	}
+	if decayRate == 0 {
+		return 0, nil
+	}
+
+	type decay struct {
+		id    string
+		score float64
+	}
+	now := time.Now()
+
+	stmt, _, err := s.db.Prepare(`
+		SELECT id, relevance_score, MAX(updated_at, COALESCE(last_used_at, 0))
+		FROM prompts
+		WHERE relevance_score > 0`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare relevance decay query: %w", err)
+	}
+	var decays []decay
+	for stmt.Step() {
+		idle := now.Sub(time.Unix(stmt.ColumnInt64(2), 0))
+		days := math.Floor(idle.Hours() / 24)
+		if days < 1 {
+			continue
+		}
+		decays = append(decays, decay{
+			id:    stmt.ColumnText(0),
+			score: stmt.ColumnFloat(1) * math.Pow(1-decayRate, days),
+		})
+	}
+	err = stmt.Err()
+	_ = stmt.Close()
+	if err != nil {
+		return 0, fmt.Errorf("failed to query prompts for relevance decay: %w", err)
+	}
+
+	if dryRun || len(decays) == 0 {
+		return len(decays), nil
+	}
+
+	tx, err := s.db.BeginImmediate()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin relevance decay transaction: %w", err)
+	}
+	defer tx.End(&err)
+
+	update, _, err := s.db.Prepare("UPDATE prompts SET relevance_score = ?, updated_at = ? WHERE id = ?")
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare relevance decay statement: %w", err)
+	}
+	defer func() { _ = update.Close() }()
+
+	for _, d := range decays {
+		_ = update.BindFloat(1, d.score)
+		_ = update.BindInt64(2, now.Unix())
+		_ = update.BindText(3, d.id)
+		if err := update.Exec(); err != nil {
+			return 0, fmt.Errorf("failed to decay relevance of prompt %s: %w", d.id, err)
+		}
+	}
+
+	s.loggerFor(ctx).WithFields(logrus.Fields{
+		"decay_rate": decayRate,
+		"updated":    len(decays),
+	}).Info("Decayed prompt relevance scores")
+	return len(decays), nil
+}
+
+// CleanupOldPrompts removes prompts older than opts.MinAge that score below
+// opts.MinRelevanceScore or rank beyond opts.MaxPrompts by relevance, least
+// relevant and oldest first. A single cleanup event is published for the
+// batch; a dry run only returns the prompts that would be removed.
+func (s *Storage) CleanupOldPrompts(ctx context.Context, opts CleanupOptions) (*CleanupResult, error) {
+	ctx, span := s.startSpan(ctx, "CleanupOldPrompts")
+	defer span.End()
+
+	stmt, _, err := s.db.Prepare(`
+		SELECT id, relevance_score, created_at
+		FROM prompts
+		ORDER BY relevance_score DESC, created_at DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare cleanup query: %w", err)
+	}
+	cutoff := time.Now().Add(-opts.MinAge).Unix()
+	result := &CleanupResult{PromptIDs: []uuid.UUID{}}
+	for rank := 0; stmt.Step(); rank++ {
+		if stmt.ColumnInt64(2) >= cutoff {
+			continue
+		}
+		overCap := opts.MaxPrompts > 0 && rank >= opts.MaxPrompts
+		if stmt.ColumnFloat(1) >= opts.MinRelevanceScore && !overCap {
+			continue
+		}
+		id, err := uuid.Parse(stmt.ColumnText(0))
+		if err != nil {
+			continue
+		}
+		result.PromptIDs = append(result.PromptIDs, id)
+	}
+	err = stmt.Err()
+	_ = stmt.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query prompts for cleanup: %w", err)
+	}
+
+	// Least relevant first, so an interrupted cleanup removes the right ones
+	for i, j := 0, len(result.PromptIDs)-1; i < j; i, j = i+1, j-1 {
+		result.PromptIDs[i], result.PromptIDs[j] = result.PromptIDs[j], result.PromptIDs[i]
+	}
+	if opts.DryRun {
+		result.Deleted = len(result.PromptIDs)
+		return result, nil
+	}
+
+	collection := s.getOrCreateCollection()
+	for _, id := range result.PromptIDs {
+		if err := s.deletePromptRows(id); err != nil {
+			return result, err
+		}
+		result.Deleted++
+		if collection != nil {
+			if err := collection.Delete(ctx, nil, nil, id.String()); err != nil {
+				s.loggerFor(ctx).WithError(err).WithField("prompt_id", id).Warn("Failed to delete prompt embedding")
+			}
+		}
+	}
+
+	s.loggerFor(ctx).WithFields(logrus.Fields{
+		"max_prompts":         opts.MaxPrompts,
+		"min_relevance_score": opts.MinRelevanceScore,
+		"deleted":             result.Deleted,
+	}).Info("Cleaned up old prompts")
+	s.events.Publish(ctx, Event{Type: EventPromptsCleanedUp, Count: result.Deleted})
+	return result, nil
+}
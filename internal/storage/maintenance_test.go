@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateRelevanceScores(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	store, err := NewStorage(t.TempDir(), logger)
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	ctx := context.Background()
+	idle := &models.Prompt{Content: "Idle for ten days", Phase: models.PhasePrimaMaterial, RelevanceScore: 0.8}
+	active := &models.Prompt{Content: "Updated just now", Phase: models.PhasePrimaMaterial, RelevanceScore: 0.8}
+	require.NoError(t, store.SavePrompt(ctx, idle))
+	require.NoError(t, store.SavePrompt(ctx, active))
+	tenDaysAgo := time.Now().AddDate(0, 0, -10).Unix()
+	require.NoError(t, store.db.Exec(fmt.Sprintf("UPDATE prompts SET updated_at = %d WHERE id = '%s'", tenDaysAgo, idle.ID)))
+
+	updated, err := store.UpdateRelevanceScores(ctx, 0.1, true)
+	require.NoError(t, err)
+	assert.Equal(t, 1, updated)
+	stored, err := store.GetPrompt(ctx, idle.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, 0.8, stored.RelevanceScore, "a dry run writes nothing")
+
+	updated, err = store.UpdateRelevanceScores(ctx, 0.1, false)
+	require.NoError(t, err)
+	assert.Equal(t, 1, updated)
+	stored, err = store.GetPrompt(ctx, idle.ID.String())
+	require.NoError(t, err)
+	assert.InDelta(t, 0.8*0.9*0.9*0.9*0.9*0.9*0.9*0.9*0.9*0.9*0.9, stored.RelevanceScore, 1e-9)
+	stored, err = store.GetPrompt(ctx, active.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, 0.8, stored.RelevanceScore)
+
+	updated, err = store.UpdateRelevanceScores(ctx, 0.1, false)
+	require.NoError(t, err)
+	assert.Zero(t, updated, "decayed prompts start a new idle day")
+
+	_, err = store.UpdateRelevanceScores(ctx, 1, false)
+	assert.Error(t, err)
+}
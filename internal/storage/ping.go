@@ -0,0 +1,33 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrStorageClosed is returned by Ping once the storage has been closed
+var ErrStorageClosed = errors.New("storage is closed")
+
+// Ping checks that the database answers a trivial query, for readiness
+// probes
+func (s *Storage) Ping(ctx context.Context) error {
+	_, span := s.startSpan(ctx, "Ping")
+	defer span.End()
+
+	if s.db == nil || s.closed.Load() {
+		return ErrStorageClosed
+	}
+	stmt, _, err := s.db.Prepare("SELECT 1")
+	if err != nil {
+		return fmt.Errorf("failed to prepare ping query: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+	if !stmt.Step() {
+		if err := stmt.Err(); err != nil {
+			return fmt.Errorf("failed to query database: %w", err)
+		}
+		return errors.New("database returned no rows")
+	}
+	return nil
+}
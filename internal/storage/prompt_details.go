@@ -0,0 +1,188 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+)
+
+// savePromptDetails stores the prompt's model metadata and context, which
+// have no columns in the prompts table. Prompts saved without either keep
+// the details stored earlier.
+func (s *Storage) savePromptDetails(p *models.Prompt) error {
+	if p.ModelMetadata == nil && len(p.Context) == 0 {
+		return nil
+	}
+
+	metadata, err := json.Marshal(p.ModelMetadata)
+	if err != nil {
+		return fmt.Errorf("failed to encode model metadata: %w", err)
+	}
+	promptContext, err := json.Marshal(p.Context)
+	if err != nil {
+		return fmt.Errorf("failed to encode prompt context: %w", err)
+	}
+
+	stmt, _, err := s.db.Prepare(`
+		INSERT INTO prompt_details (prompt_id, model_metadata, context) VALUES (?, ?, ?)
+		ON CONFLICT(prompt_id) DO UPDATE SET model_metadata = excluded.model_metadata, context = excluded.context`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare save prompt details statement: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	_ = stmt.BindText(1, p.ID.String())
+	_ = stmt.BindText(2, string(metadata))
+	_ = stmt.BindText(3, string(promptContext))
+
+	stmt.Step()
+	if err := stmt.Err(); err != nil {
+		return fmt.Errorf("failed to execute save prompt details statement: %w", err)
+	}
+	return nil
+}
+
+// savePromptInput stores the template and variables of a prompt generated
+// from a templated input
+func (s *Storage) savePromptInput(p *models.Prompt) error {
+	if p.InputTemplate == "" {
+		return nil
+	}
+
+	variables, err := json.Marshal(p.InputVariables)
+	if err != nil {
+		return fmt.Errorf("failed to encode input variables: %w", err)
+	}
+
+	stmt, _, err := s.db.Prepare(`
+		INSERT INTO prompt_inputs (prompt_id, template, variables) VALUES (?, ?, ?)
+		ON CONFLICT(prompt_id) DO UPDATE SET template = excluded.template, variables = excluded.variables`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare save prompt input statement: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	_ = stmt.BindText(1, p.ID.String())
+	_ = stmt.BindText(2, p.InputTemplate)
+	_ = stmt.BindText(3, string(variables))
+
+	stmt.Step()
+	if err := stmt.Err(); err != nil {
+		return fmt.Errorf("failed to execute save prompt input statement: %w", err)
+	}
+	return nil
+}
+
+// LoadPromptDetails fills in the model metadata, context and input template
+// stored for p. Prompts saved without them are left unchanged.
+func (s *Storage) LoadPromptDetails(ctx context.Context, p *models.Prompt) error {
+	if err := s.loadPromptInput(p); err != nil {
+		return err
+	}
+
+	stmt, _, err := s.db.Prepare("SELECT model_metadata, context FROM prompt_details WHERE prompt_id = ?")
+	if err != nil {
+		return fmt.Errorf("failed to prepare get prompt details query: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	_ = stmt.BindText(1, p.ID.String())
+
+	if !stmt.Step() {
+		if err := stmt.Err(); err != nil {
+			return fmt.Errorf("failed to get prompt details: %w", err)
+		}
+		return nil
+	}
+
+	if err := json.Unmarshal([]byte(stmt.ColumnText(0)), &p.ModelMetadata); err != nil {
+		return fmt.Errorf("failed to decode model metadata: %w", err)
+	}
+	if err := json.Unmarshal([]byte(stmt.ColumnText(1)), &p.Context); err != nil {
+		return fmt.Errorf("failed to decode prompt context: %w", err)
+	}
+	return nil
+}
+
+// loadPromptInput fills in the input template and variables stored for p
+func (s *Storage) loadPromptInput(p *models.Prompt) error {
+	stmt, _, err := s.db.Prepare("SELECT template, variables FROM prompt_inputs WHERE prompt_id = ?")
+	if err != nil {
+		return fmt.Errorf("failed to prepare get prompt input query: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	_ = stmt.BindText(1, p.ID.String())
+
+	if !stmt.Step() {
+		if err := stmt.Err(); err != nil {
+			return fmt.Errorf("failed to get prompt input: %w", err)
+		}
+		return nil
+	}
+
+	p.InputTemplate = stmt.ColumnText(0)
+	if err := json.Unmarshal([]byte(stmt.ColumnText(1)), &p.InputVariables); err != nil {
+		return fmt.Errorf("failed to decode input variables: %w", err)
+	}
+	return nil
+}
+
+// GetPromptEmbedding returns the prompt's embedding from the vector store,
+// or nil if it was never embedded with the current embedding model
+func (s *Storage) GetPromptEmbedding(ctx context.Context, id uuid.UUID) ([]float32, error) {
+	collection := s.vectors.GetCollection(s.collectionName(), nil)
+	if collection == nil {
+		return nil, nil
+	}
+	// GetByID only fails for a missing document
+	doc, err := collection.GetByID(ctx, id.String())
+	if err != nil {
+		return nil, nil
+	}
+	return doc.Embedding, nil
+}
+
+// GetPromptMetrics summarizes how a prompt has performed: how often it was
+// chosen when shown, its average interaction score, and its token usage and
+// latency
+func (s *Storage) GetPromptMetrics(ctx context.Context, p *models.Prompt) (*models.PromptMetrics, error) {
+	stmt, _, err := s.db.Prepare(`
+		SELECT COUNT(*), COALESCE(SUM(action = 'chosen'), 0), COALESCE(AVG(score), 0)
+		FROM user_interactions WHERE prompt_id = ?`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare prompt metrics query: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	_ = stmt.BindText(1, p.ID.String())
+
+	if !stmt.Step() {
+		if err := stmt.Err(); err != nil {
+			return nil, fmt.Errorf("failed to get prompt metrics: %w", err)
+		}
+	}
+	interactions, chosen := stmt.ColumnInt(0), stmt.ColumnInt(1)
+
+	metrics := &models.PromptMetrics{
+		PromptID:        p.ID,
+		EngagementScore: stmt.ColumnFloat(2),
+		TokenUsage:      p.ActualTokens,
+		UsageCount:      p.UsageCount,
+		CreatedAt:       p.CreatedAt,
+		UpdatedAt:       p.UpdatedAt,
+	}
+	if interactions > 0 {
+		metrics.ConversionRate = float64(chosen) / float64(interactions)
+	}
+	if p.ModelMetadata != nil {
+		metrics.ResponseTime = p.ModelMetadata.ProcessingTime
+		if p.ModelMetadata.TotalTokens > 0 {
+			metrics.TokenUsage = p.ModelMetadata.TotalTokens
+		}
+	}
+	return metrics, nil
+}
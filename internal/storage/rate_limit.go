@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// IncrementRateLimit counts a request from clientKey in the rate limit window
+// starting at window and returns the window's count so far. Instances that
+// share the database share the counts. Earlier windows are pruned whenever a
+// new one starts.
+func (s *Storage) IncrementRateLimit(ctx context.Context, clientKey string, window time.Time) (int, error) {
+	stmt, _, err := s.db.Prepare(`
+		INSERT INTO rate_limits (client_key, window_start, count) VALUES (?, ?, 1)
+		ON CONFLICT(client_key, window_start) DO UPDATE SET count = count + 1
+		RETURNING count`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare rate limit statement: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	_ = stmt.BindText(1, clientKey)
+	_ = stmt.BindInt64(2, window.Unix())
+
+	if !stmt.Step() {
+		if err := stmt.Err(); err != nil {
+			return 0, fmt.Errorf("failed to execute rate limit statement: %w", err)
+		}
+		return 0, fmt.Errorf("rate limit statement returned no count")
+	}
+	count := stmt.ColumnInt(0)
+	// Finish the statement before pruning on the same connection
+	stmt.Step()
+	if err := stmt.Err(); err != nil {
+		return 0, fmt.Errorf("failed to execute rate limit statement: %w", err)
+	}
+
+	if count == 1 {
+		if err := s.deleteRateLimitsBefore(window); err != nil {
+			s.loggerFor(ctx).WithError(err).Warn("Failed to clean up expired rate limit windows")
+		}
+	}
+	return count, nil
+}
+
+// deleteRateLimitsBefore removes rate limit windows that started before cutoff
+func (s *Storage) deleteRateLimitsBefore(cutoff time.Time) error {
+	stmt, _, err := s.db.Prepare("DELETE FROM rate_limits WHERE window_start < ?")
+	if err != nil {
+		return fmt.Errorf("failed to prepare rate limit cleanup statement: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	_ = stmt.BindInt64(1, cutoff.Unix())
+	stmt.Step()
+	return stmt.Err()
+}
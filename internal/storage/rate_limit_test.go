@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIncrementRateLimit(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	store, err := NewStorage(t.TempDir(), logger)
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	ctx := context.Background()
+	window := time.Now().Truncate(time.Minute)
+
+	for want := 1; want <= 3; want++ {
+		count, err := store.IncrementRateLimit(ctx, "10.0.0.1", window)
+		require.NoError(t, err)
+		assert.Equal(t, want, count)
+	}
+
+	count, err := store.IncrementRateLimit(ctx, "10.0.0.2", window)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count, "clients are counted separately")
+
+	// A new window starts from zero and prunes the old one
+	count, err = store.IncrementRateLimit(ctx, "10.0.0.1", window.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	stmt, _, err := store.db.Prepare("SELECT COUNT(*) FROM rate_limits WHERE window_start < ?")
+	require.NoError(t, err)
+	defer func() { _ = stmt.Close() }()
+	_ = stmt.BindInt64(1, window.Add(time.Minute).Unix())
+	require.True(t, stmt.Step())
+	assert.Equal(t, 0, stmt.ColumnInt(0))
+}
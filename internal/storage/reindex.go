@@ -0,0 +1,167 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jonwraymond/prompt-alchemy/internal/log"
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/sirupsen/logrus"
+)
+
+// States reported in ReindexStatus
+const (
+	ReindexIdle      = "idle"
+	ReindexRunning   = "running"
+	ReindexCompleted = "completed"
+	ReindexFailed    = "failed"
+)
+
+// ErrReindexRunning is returned by StartReindex while a rebuild is in progress
+var ErrReindexRunning = errors.New("search index rebuild already in progress")
+
+// ReindexStatus reports the progress of the current or last search index
+// rebuild
+type ReindexStatus struct {
+	State      string     `json:"state"`
+	Total      int        `json:"total"`
+	Indexed    int        `json:"indexed"`
+	Failed     int        `json:"failed"`
+	Collection string     `json:"collection,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// ReindexStatus returns the progress of the current or last rebuild
+func (s *Storage) ReindexStatus() ReindexStatus {
+	s.jobs.mu.Lock()
+	defer s.jobs.mu.Unlock()
+
+	status := s.jobs.reindex
+	if status.State == "" {
+		status.State = ReindexIdle
+	}
+	return status
+}
+
+// StartReindex rebuilds the search index in the background, re-embedding
+// every stored prompt with embed. It returns ErrReindexRunning if a rebuild
+// is already in progress, or ErrBackfillRunning during an embedding
+// backfill; poll ReindexStatus for progress.
+func (s *Storage) StartReindex(ctx context.Context, embed QueryEmbedder) error {
+	if embed == nil {
+		return fmt.Errorf("no embedding provider available to rebuild the search index")
+	}
+
+	// The rebuild reads on its own connection so it doesn't share the
+	// request path's
+	job, err := s.withConn()
+	if err != nil {
+		return err
+	}
+
+	s.jobs.mu.Lock()
+	if err := s.jobs.running(); err != nil {
+		s.jobs.mu.Unlock()
+		job.closeConn()
+		return err
+	}
+	now := time.Now()
+	s.jobs.reindex = ReindexStatus{State: ReindexRunning, StartedAt: &now}
+	s.jobs.mu.Unlock()
+
+	// The rebuild outlives the request that started it, but keeps its
+	// request ID for logging
+	bgCtx := log.WithRequestID(context.Background(), log.RequestIDFromContext(ctx))
+	go func() {
+		defer job.closeConn()
+		err := job.rebuildSearchIndex(bgCtx, embed)
+
+		s.updateReindex(func(status *ReindexStatus) {
+			finished := time.Now()
+			status.FinishedAt = &finished
+			status.State = ReindexCompleted
+			if err != nil {
+				status.State = ReindexFailed
+				status.Error = err.Error()
+			}
+		})
+	}()
+	return nil
+}
+
+func (s *Storage) updateReindex(fn func(*ReindexStatus)) {
+	s.jobs.update(func(j *jobs) { fn(&j.reindex) })
+}
+
+// rebuildSearchIndex re-embeds every stored prompt with embed and replaces
+// the vector collection for the current embedding configuration. The
+// collection's dimensions follow the new embeddings, so this is the way to
+// switch embedding models. Prompts that fail to embed are counted and
+// skipped. Semantic search sees a partial index until the rebuild finishes.
+func (s *Storage) rebuildSearchIndex(ctx context.Context, embed QueryEmbedder) error {
+	logger := s.loggerFor(ctx)
+
+	total, err := s.GetPromptsCount(ctx)
+	if err != nil {
+		return err
+	}
+	// Read every prompt up front so the embedding calls don't hold a
+	// statement open
+	prompts := make([]*models.Prompt, 0, total)
+	if err := s.StreamPrompts(ctx, total, 0, func(p *models.Prompt) error {
+		prompts = append(prompts, p)
+		return nil
+	}); err != nil {
+		return err
+	}
+	s.updateReindex(func(status *ReindexStatus) { status.Total = len(prompts) })
+	logger.WithField("total", len(prompts)).Info("Rebuilding search index")
+
+	reset := false
+	for _, p := range prompts {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		embedding, err := embed(ctx, p.Content)
+		if err == nil && !reset {
+			// The first embedding fixes the dimensions of the new collection
+			s.embedding.setDims(len(embedding))
+			name := s.collectionName()
+			if err := s.vectors.DeleteCollection(name); err != nil {
+				return fmt.Errorf("failed to clear collection %s: %w", name, err)
+			}
+			s.updateReindex(func(status *ReindexStatus) { status.Collection = name })
+			reset = true
+		}
+		if _, _, dims := s.embedding.get(); err == nil && len(embedding) != dims {
+			err = fmt.Errorf("embedding dimension mismatch: expected %d, got %d", dims, len(embedding))
+		}
+		if err == nil {
+			p.Embedding = embedding
+			err = s.getOrCreateCollection().AddDocument(ctx, s.embeddingDocument(p))
+		}
+
+		if err != nil {
+			logger.WithError(err).WithField("prompt_id", p.ID).Warn("Failed to reindex prompt")
+			s.updateReindex(func(status *ReindexStatus) { status.Failed++ })
+			continue
+		}
+		s.updateReindex(func(status *ReindexStatus) { status.Indexed++ })
+	}
+
+	status := s.ReindexStatus()
+	logger.WithFields(logrus.Fields{
+		"indexed":    status.Indexed,
+		"failed":     status.Failed,
+		"collection": status.Collection,
+	}).Info("Rebuilt search index")
+	if len(prompts) > 0 && status.Indexed == 0 {
+		return fmt.Errorf("no prompts could be embedded")
+	}
+	return nil
+}
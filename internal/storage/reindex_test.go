@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartReindex(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	store, err := NewStorage(t.TempDir(), logger)
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	ctx := context.Background()
+	for _, content := range []string{"sort a list", "parse a date", "unembeddable"} {
+		require.NoError(t, store.SavePrompt(ctx, &models.Prompt{Content: content, Phase: models.PhaseCoagulatio}))
+	}
+	assert.Equal(t, ReindexIdle, store.ReindexStatus().State)
+
+	release := make(chan struct{})
+	embed := func(ctx context.Context, text string) ([]float32, error) {
+		<-release
+		if strings.HasPrefix(text, "unembeddable") {
+			return nil, errors.New("provider rejected input")
+		}
+		return []float32{float32(len(text)), 1, 0}, nil
+	}
+
+	require.NoError(t, store.StartReindex(ctx, embed))
+	assert.ErrorIs(t, store.StartReindex(ctx, embed), ErrReindexRunning)
+	close(release)
+
+	require.Eventually(t, func() bool {
+		return store.ReindexStatus().State != ReindexRunning
+	}, 5*time.Second, 10*time.Millisecond)
+
+	status := store.ReindexStatus()
+	assert.Equal(t, ReindexCompleted, status.State)
+	assert.Equal(t, 3, status.Total)
+	assert.Equal(t, 2, status.Indexed)
+	assert.Equal(t, 1, status.Failed)
+	assert.NotNil(t, status.FinishedAt)
+
+	_, _, dims := store.GetEmbeddingConfig()
+	assert.Equal(t, 3, dims)
+	similar, err := store.SearchSimilarPrompts(ctx, []float32{11, 1, 0}, 1)
+	require.NoError(t, err)
+	require.Len(t, similar, 1)
+
+	assert.Error(t, store.StartReindex(ctx, nil))
+}
+
+func TestStartReindexWhileSaving(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	store, err := NewStorage(t.TempDir(), logger)
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	ctx := context.Background()
+	for i := 0; i < 20; i++ {
+		require.NoError(t, store.SavePrompt(ctx, &models.Prompt{Content: fmt.Sprintf("stored prompt %d", i), Phase: models.PhaseCoagulatio}))
+	}
+
+	embed := func(ctx context.Context, text string) ([]float32, error) {
+		return []float32{1, 0, 0}, nil
+	}
+	require.NoError(t, store.StartReindex(ctx, embed))
+
+	// Requests keep saving on the main connection while the rebuild reads
+	// on its own
+	for i := 0; i < 20; i++ {
+		require.NoError(t, store.SavePrompt(ctx, &models.Prompt{
+			Content:   fmt.Sprintf("new prompt %d", i),
+			Phase:     models.PhaseCoagulatio,
+			Embedding: []float32{0, 1, 0},
+		}))
+		_ = store.ReindexStatus()
+		_, _, _ = store.GetEmbeddingConfig()
+	}
+
+	require.Eventually(t, func() bool {
+		return store.ReindexStatus().State != ReindexRunning
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, ReindexCompleted, store.ReindexStatus().State)
+}
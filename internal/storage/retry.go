@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/ncruces/go-sqlite3"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// Defaults for the storage.retry.* settings and storage.busy_timeout
+const (
+	DefaultRetryAttempts       = 4
+	DefaultRetryInitialBackoff = 25 * time.Millisecond
+	DefaultRetryMaxBackoff     = time.Second
+	DefaultBusyTimeout         = 5 * time.Second
+)
+
+// retryPolicy retries writes that fail because another connection holds
+// the database lock. SQLite already waits up to storage.busy_timeout for
+// the lock; this covers the cases where it gives up or returns SQLITE_BUSY
+// without waiting.
+type retryPolicy struct {
+	attempts int
+	initial  time.Duration
+	max      time.Duration
+	observe  func(operation string) // see Storage.SetRetryObserver
+}
+
+// loadRetryPolicy reads storage.retry.max_attempts (1 disables retries),
+// storage.retry.initial_backoff and storage.retry.max_backoff, falling back
+// to the defaults for those unset or not positive
+func loadRetryPolicy() retryPolicy {
+	policy := retryPolicy{
+		attempts: viper.GetInt("storage.retry.max_attempts"),
+		initial:  viper.GetDuration("storage.retry.initial_backoff"),
+		max:      viper.GetDuration("storage.retry.max_backoff"),
+	}
+	if policy.attempts <= 0 {
+		policy.attempts = DefaultRetryAttempts
+	}
+	if policy.initial <= 0 {
+		policy.initial = DefaultRetryInitialBackoff
+	}
+	if policy.max <= 0 {
+		policy.max = DefaultRetryMaxBackoff
+	}
+	if policy.max < policy.initial {
+		policy.max = policy.initial
+	}
+	return policy
+}
+
+// loadBusyTimeout reads storage.busy_timeout, how long SQLite waits for a
+// lock held by another connection before failing with SQLITE_BUSY
+func loadBusyTimeout() time.Duration {
+	if !viper.IsSet("storage.busy_timeout") {
+		return DefaultBusyTimeout
+	}
+	return viper.GetDuration("storage.busy_timeout")
+}
+
+// isTransient reports whether err is a lock conflict that may succeed
+// when retried
+func isTransient(err error) bool {
+	return errors.Is(err, sqlite3.BUSY) || errors.Is(err, sqlite3.LOCKED)
+}
+
+// backoff returns the wait before retry n (1-based): the initial backoff
+// doubled for each earlier retry, capped at the maximum, with jitter so
+// competing writers don't retry in lockstep
+func (p retryPolicy) backoff(n int) time.Duration {
+	wait := p.initial
+	for i := 1; i < n && wait < p.max; i++ {
+		wait *= 2
+	}
+	wait = min(wait, p.max)
+	return wait/2 + rand.N(wait/2+1)
+}
+
+// SetRetryObserver sets a function told each time a write is retried after
+// a transient error, e.g. to export retries as a metric. Set it before
+// using the storage.
+func (s *Storage) SetRetryObserver(observe func(operation string)) {
+	s.retry.observe = observe
+}
+
+// withRetry runs write, running it again after a backoff while it fails
+// with a transient error, up to the configured attempts. write must be
+// safe to repeat, which holds for single statements since SQLite rolls a
+// failed statement back.
+func (s *Storage) withRetry(ctx context.Context, operation string, write func() error) error {
+	attempts := max(s.retry.attempts, 1)
+	for attempt := 1; ; attempt++ {
+		err := write()
+		if err == nil || !isTransient(err) || attempt >= attempts {
+			return err
+		}
+
+		wait := s.retry.backoff(attempt)
+		s.loggerFor(ctx).WithError(err).WithFields(logrus.Fields{
+			"operation": operation,
+			"attempt":   attempt,
+			"backoff":   wait,
+		}).Debug("Retrying storage write after transient error")
+		if s.retry.observe != nil {
+			s.retry.observe(operation)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/ncruces/go-sqlite3"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithRetry(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	store := &Storage{
+		logger: logger,
+		retry:  retryPolicy{attempts: 3, initial: time.Millisecond, max: 4 * time.Millisecond},
+	}
+	var retries atomic.Int32
+	store.SetRetryObserver(func(operation string) {
+		assert.Equal(t, "save_prompt", operation)
+		retries.Add(1)
+	})
+	ctx := context.Background()
+
+	t.Run("succeeds after a busy error", func(t *testing.T) {
+		retries.Store(0)
+		calls := 0
+		err := store.withRetry(ctx, "save_prompt", func() error {
+			calls++
+			if calls == 1 {
+				return sqlite3.BUSY
+			}
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, calls)
+		assert.Equal(t, int32(1), retries.Load())
+	})
+
+	t.Run("gives up after the configured attempts", func(t *testing.T) {
+		retries.Store(0)
+		calls := 0
+		err := store.withRetry(ctx, "save_prompt", func() error {
+			calls++
+			return sqlite3.LOCKED
+		})
+		assert.ErrorIs(t, err, sqlite3.LOCKED)
+		assert.Equal(t, 3, calls)
+		assert.Equal(t, int32(2), retries.Load())
+	})
+
+	t.Run("doesn't retry other errors", func(t *testing.T) {
+		retries.Store(0)
+		calls := 0
+		failure := errors.New("constraint failed")
+		err := store.withRetry(ctx, "save_prompt", func() error {
+			calls++
+			return failure
+		})
+		assert.ErrorIs(t, err, failure)
+		assert.Equal(t, 1, calls)
+		assert.Zero(t, retries.Load())
+	})
+
+	t.Run("stops when the context is done", func(t *testing.T) {
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		calls := 0
+		err := store.withRetry(canceled, "save_prompt", func() error {
+			calls++
+			return sqlite3.BUSY
+		})
+		assert.ErrorIs(t, err, sqlite3.BUSY)
+		assert.Equal(t, 1, calls)
+	})
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := retryPolicy{attempts: 5, initial: 10 * time.Millisecond, max: 40 * time.Millisecond}
+	for n, want := range map[int]time.Duration{1: 10 * time.Millisecond, 2: 20 * time.Millisecond, 3: 40 * time.Millisecond, 6: 40 * time.Millisecond} {
+		wait := policy.backoff(n)
+		assert.GreaterOrEqual(t, wait, want/2, n)
+		assert.LessOrEqual(t, wait, want, n)
+	}
+}
+
+func TestSavePrompt_RetriesBusyDatabase(t *testing.T) {
+	defer viper.Reset()
+	// Fail at once on a held lock so the save relies on retries
+	viper.Set("storage.busy_timeout", "0s")
+	viper.Set("storage.retry.max_attempts", 20)
+	viper.Set("storage.retry.initial_backoff", "10ms")
+	viper.Set("storage.retry.max_backoff", "20ms")
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	dir := t.TempDir()
+	store, err := NewStorage(dir, logger)
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+	var retries atomic.Int32
+	store.SetRetryObserver(func(operation string) { retries.Add(1) })
+
+	// Another connection holds the write lock for a while
+	other, err := sqlite3.Open(filepath.Join(dir, "prompts.db"))
+	require.NoError(t, err)
+	defer func() { _ = other.Close() }()
+	require.NoError(t, other.Exec("BEGIN EXCLUSIVE"))
+	released := make(chan error, 1)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		released <- other.Exec("COMMIT")
+	}()
+
+	ctx := context.Background()
+	prompt := &models.Prompt{Content: "Saved once the lock is released", Phase: models.PhasePrimaMaterial}
+	require.NoError(t, store.SavePrompt(ctx, prompt))
+	require.NoError(t, <-released)
+	assert.Positive(t, retries.Load())
+
+	saved, err := store.GetPromptByID(ctx, prompt.ID)
+	require.NoError(t, err)
+	assert.Equal(t, prompt.Content, saved.Content)
+}
@@ -46,6 +46,19 @@ CREATE TABLE IF NOT EXISTS user_interactions (
     FOREIGN KEY (prompt_id) REFERENCES prompts(id)
 );
 
+-- Table to store outcomes users report for prompts (thumbs up/down, used in
+-- production, conversion)
+CREATE TABLE IF NOT EXISTS prompt_feedback (
+    id TEXT PRIMARY KEY,
+    prompt_id TEXT NOT NULL,
+    signal TEXT NOT NULL,
+    score REAL, -- optional 0-1 strength given with the signal
+    outcome REAL NOT NULL, -- 0-1 success value the signal stands for
+    comment TEXT,
+    created_at DATETIME NOT NULL,
+    FOREIGN KEY (prompt_id) REFERENCES prompts(id)
+);
+
 -- Table to store prompt relationships for optimization
 CREATE TABLE IF NOT EXISTS prompt_relationships (
     id TEXT PRIMARY KEY,
@@ -59,6 +72,86 @@ CREATE TABLE IF NOT EXISTS prompt_relationships (
     FOREIGN KEY (target_prompt_id) REFERENCES prompts(id)
 );
 
+-- Table to store each prompt's model metadata and context
+CREATE TABLE IF NOT EXISTS prompt_details (
+    prompt_id TEXT PRIMARY KEY,
+    model_metadata TEXT, -- JSON-encoded models.ModelMetadata
+    context TEXT, -- JSON-encoded []models.PromptContext
+    FOREIGN KEY (prompt_id) REFERENCES prompts(id)
+);
+
+-- Table to store the template and variables of prompts generated from a
+-- templated input; prompts.original_input holds the resolved input
+CREATE TABLE IF NOT EXISTS prompt_inputs (
+    prompt_id TEXT PRIMARY KEY,
+    template TEXT NOT NULL,
+    variables TEXT, -- JSON-encoded map of variable names to values
+    FOREIGN KEY (prompt_id) REFERENCES prompts(id)
+);
+
+-- Table to store earlier versions of edited prompts, numbered from 1 per prompt
+CREATE TABLE IF NOT EXISTS prompt_versions (
+    prompt_id TEXT NOT NULL,
+    version INTEGER NOT NULL,
+    content TEXT NOT NULL,
+    tags TEXT, -- Stored as a JSON array
+    provider TEXT,
+    model TEXT,
+    temperature REAL,
+    max_tokens INTEGER,
+    created_at DATETIME NOT NULL, -- When this version was replaced
+    PRIMARY KEY (prompt_id, version),
+    FOREIGN KEY (prompt_id) REFERENCES prompts(id)
+);
+
+-- Table to store each web UI session's board layout (viewport and node positions)
+CREATE TABLE IF NOT EXISTS board_state (
+    session_id TEXT PRIMARY KEY,
+    state TEXT NOT NULL, -- JSON-encoded models.BoardState
+    updated_at DATETIME NOT NULL
+);
+
+-- Table to count requests per client and window, so API instances sharing
+-- this database enforce one rate limit
+CREATE TABLE IF NOT EXISTS rate_limits (
+    client_key TEXT NOT NULL,
+    window_start INTEGER NOT NULL, -- Unix seconds
+    count INTEGER NOT NULL,
+    PRIMARY KEY (client_key, window_start)
+);
+
+-- Table to remember the response to each Idempotency-Key sent to the
+-- generate endpoint, so a retried request is answered without generating
+-- again, by whichever instance receives it
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    idempotency_key TEXT PRIMARY KEY,
+    request_hash TEXT NOT NULL, -- SHA-256 of the request the key was first used for
+    response TEXT, -- JSON response body, NULL while the first request is in flight
+    expires_at INTEGER NOT NULL -- Unix seconds
+);
+
+-- Table to store reference documents ingested for retrieval-augmented
+-- generation; their text is kept in context_chunks
+CREATE TABLE IF NOT EXISTS context_documents (
+    id TEXT PRIMARY KEY,
+    title TEXT,
+    source TEXT,
+    characters INTEGER NOT NULL,
+    chunk_count INTEGER NOT NULL,
+    dimensions INTEGER NOT NULL, -- length of the chunks' embeddings
+    created_at DATETIME NOT NULL
+);
+
+-- Table to store the chunks of each context document with their embeddings
+CREATE TABLE IF NOT EXISTS context_chunks (
+    id TEXT PRIMARY KEY,
+    document_id TEXT NOT NULL,
+    chunk_index INTEGER NOT NULL, -- position within the document, from 0
+    content TEXT NOT NULL,
+    embedding BLOB NOT NULL, -- little-endian float32 vector
+    FOREIGN KEY (document_id) REFERENCES context_documents(id)
+);
+
 -- Indexes to speed up queries
 CREATE INDEX IF NOT EXISTS idx_prompts_phase ON prompts(phase);
 CREATE INDEX IF NOT EXISTS idx_prompts_provider ON prompts(provider);
@@ -68,5 +161,9 @@ CREATE INDEX IF NOT EXISTS idx_prompts_relevance_score ON prompts(relevance_scor
 CREATE INDEX IF NOT EXISTS idx_prompts_session_id ON prompts(session_id);
 CREATE INDEX IF NOT EXISTS idx_interactions_session_id ON user_interactions(session_id);
 CREATE INDEX IF NOT EXISTS idx_interactions_prompt_id ON user_interactions(prompt_id);
+CREATE INDEX IF NOT EXISTS idx_prompt_feedback_prompt_id ON prompt_feedback(prompt_id);
 CREATE INDEX IF NOT EXISTS idx_relationships_source ON prompt_relationships(source_prompt_id);
-CREATE INDEX IF NOT EXISTS idx_relationships_target ON prompt_relationships(target_prompt_id);
\ No newline at end of file
+CREATE INDEX IF NOT EXISTS idx_relationships_target ON prompt_relationships(target_prompt_id);
+CREATE INDEX IF NOT EXISTS idx_board_state_updated_at ON board_state(updated_at);
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
+CREATE INDEX IF NOT EXISTS idx_context_chunks_document_id ON context_chunks(document_id, chunk_index);
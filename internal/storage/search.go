@@ -0,0 +1,366 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/sirupsen/logrus"
+)
+
+// Search types reported in SearchOutcome and search responses
+const (
+	SearchTypeSemantic = "semantic"
+	SearchTypeText     = "text"
+	SearchTypeVector   = "vector"
+)
+
+// QueryEmbedder produces an embedding for a search query
+type QueryEmbedder func(ctx context.Context, text string) ([]float32, error)
+
+// SearchOutcome describes the results of a search and how they were produced
+type SearchOutcome struct {
+	Prompts        []*models.Prompt `json:"prompts"`
+	SearchType     string           `json:"search_type"`
+	Fallback       bool             `json:"search_fallback"`
+	FallbackReason string           `json:"fallback_reason,omitempty"`
+}
+
+// SearchWithFallback runs a semantic search when requested and falls back to
+// text search when the query cannot be embedded. A missing or failing
+// embedding provider degrades search quality instead of breaking it; callers
+// can tell the difference through SearchOutcome.Fallback.
+func (s *Storage) SearchWithFallback(ctx context.Context, query string, limit int, semantic bool, embed QueryEmbedder) (*SearchOutcome, error) {
+	if semantic && query != "" {
+		prompts, err := s.searchSemantic(ctx, query, limit, embed)
+		if err == nil {
+			return &SearchOutcome{Prompts: prompts, SearchType: SearchTypeSemantic}, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		s.loggerFor(ctx).WithError(err).WithFields(logrus.Fields{
+			"query": query,
+			"limit": limit,
+		}).Warn("Semantic search unavailable, falling back to text search")
+
+		prompts, textErr := s.searchText(ctx, query, limit)
+		if textErr != nil {
+			return nil, textErr
+		}
+		return &SearchOutcome{
+			Prompts:        prompts,
+			SearchType:     SearchTypeText,
+			Fallback:       true,
+			FallbackReason: err.Error(),
+		}, nil
+	}
+
+	prompts, err := s.searchText(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	return &SearchOutcome{Prompts: prompts, SearchType: SearchTypeText}, nil
+}
+
+func (s *Storage) searchSemantic(ctx context.Context, query string, limit int, embed QueryEmbedder) ([]*models.Prompt, error) {
+	if embed == nil {
+		return nil, fmt.Errorf("no embedding provider configured")
+	}
+
+	embedding, err := embed(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+	if len(embedding) == 0 {
+		return nil, fmt.Errorf("embedding provider returned an empty embedding")
+	}
+
+	return s.SearchSimilarPrompts(ctx, embedding, limit)
+}
+
+func (s *Storage) searchText(ctx context.Context, query string, limit int) ([]*models.Prompt, error) {
+	results, err := s.SearchPrompts(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("text search failed: %w", err)
+	}
+
+	prompts := make([]*models.Prompt, len(results))
+	for i := range results {
+		prompts[i] = &results[i]
+	}
+	return prompts, nil
+}
+
+// SearchCriteria filters prompts by metadata. Query, when set, matches the
+// content or original input as a substring. Zero values match everything.
+type SearchCriteria struct {
+	Query    string
+	Phase    string
+	Provider string
+	Tags     []string // a prompt matches if it has any of them
+	Since    *time.Time
+	Limit    int
+	After    *PromptCursor // only prompts after this position, for paging
+}
+
+// ErrEmbeddingDimensionMismatch is returned by SearchPromptsByVector for a
+// vector whose length differs from the stored embeddings'
+var ErrEmbeddingDimensionMismatch = errors.New("embedding dimension mismatch")
+
+// SemanticSearchCriteria ranks prompts by similarity to Query, keeping those
+// at or above MinSimilarity that also match the metadata filters
+type SemanticSearchCriteria struct {
+	Query         string
+	Limit         int
+	MinSimilarity float64
+	Phase         string
+	Provider      string
+	Tags          []string
+	Since         *time.Time
+}
+
+// SearchPromptsByCriteria returns the newest prompts matching criteria
+func (s *Storage) SearchPromptsByCriteria(ctx context.Context, criteria SearchCriteria) ([]models.Prompt, error) {
+	prompts, _, err := s.SearchPromptsPage(ctx, criteria)
+	return prompts, err
+}
+
+// SearchPromptsPage returns up to criteria.Limit of the newest prompts
+// matching criteria, strictly after criteria.After. Like ListPromptsPage,
+// the returned cursor points at the last prompt of the page and is nil when
+// no more prompts match.
+func (s *Storage) SearchPromptsPage(ctx context.Context, criteria SearchCriteria) ([]models.Prompt, *PromptCursor, error) {
+	s.loggerFor(ctx).WithFields(logrus.Fields{
+		"query":    criteria.Query,
+		"phase":    criteria.Phase,
+		"provider": criteria.Provider,
+		"tags":     criteria.Tags,
+		"limit":    criteria.Limit,
+		"cursor":   criteria.After != nil,
+	}).Debug("Searching prompts by criteria")
+
+	where, args := criteriaFilters(criteria)
+	clause := ""
+	if len(where) > 0 {
+		clause = " WHERE " + strings.Join(where, " AND ")
+	}
+	// Fetch one extra row to learn whether another page exists
+	query := strings.Replace(s.baseSelectQuery(), ";", clause+" ORDER BY created_at DESC, id DESC LIMIT ?;", 1)
+	args = append(args, criteria.Limit+1)
+
+	stmt, _, err := s.db.Prepare(query)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to prepare criteria search query: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+	bindArgs(stmt, args)
+
+	prompts, err := s.scanPrompts(stmt)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to scan criteria search results: %w", err)
+	}
+
+	var next *PromptCursor
+	if len(prompts) > criteria.Limit {
+		prompts = prompts[:criteria.Limit]
+		cursor := CursorFor(prompts[len(prompts)-1])
+		next = &cursor
+	}
+
+	result := make([]models.Prompt, len(prompts))
+	for i, p := range prompts {
+		result[i] = *p
+	}
+	return result, next, nil
+}
+
+// criteriaFilters returns the WHERE conditions and their arguments for the
+// metadata filters of criteria
+func criteriaFilters(criteria SearchCriteria) ([]string, []interface{}) {
+	var where []string
+	var args []interface{}
+	if criteria.Query != "" {
+		pattern := "%" + criteria.Query + "%"
+		where = append(where, "(content LIKE ? OR original_input LIKE ?)")
+		args = append(args, pattern, pattern)
+	}
+	if criteria.Phase != "" {
+		where = append(where, "phase = ?")
+		args = append(args, criteria.Phase)
+	}
+	if criteria.Provider != "" {
+		where = append(where, "provider = ?")
+		args = append(args, criteria.Provider)
+	}
+	if len(criteria.Tags) > 0 {
+		tagClauses := make([]string, len(criteria.Tags))
+		for i, tag := range criteria.Tags {
+			tagClauses[i] = "json_extract(tags, '$') LIKE ?"
+			args = append(args, fmt.Sprintf("%%%q%%", tag))
+		}
+		where = append(where, "("+strings.Join(tagClauses, " OR ")+")")
+	}
+	if criteria.Since != nil {
+		where = append(where, "created_at >= ?")
+		args = append(args, criteria.Since.Unix())
+	}
+	if criteria.After != nil {
+		filter, afterArgs := afterFilter(*criteria.After)
+		where = append(where, filter)
+		args = append(args, afterArgs...)
+	}
+	return where, args
+}
+
+// SearchPromptsSemanticFast embeds criteria.Query and searches the vector
+// collection, filtering phase and provider inside the index and tags and
+// date after hydration. It returns the prompts with their cosine
+// similarities, most similar first and aligned by index.
+func (s *Storage) SearchPromptsSemanticFast(ctx context.Context, criteria SemanticSearchCriteria, embed QueryEmbedder) ([]models.Prompt, []float64, error) {
+	if embed == nil {
+		return nil, nil, fmt.Errorf("no embedding provider configured")
+	}
+	embedding, err := embed(ctx, criteria.Query)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+	if len(embedding) == 0 {
+		return nil, nil, fmt.Errorf("embedding provider returned an empty embedding")
+	}
+	return s.searchByEmbedding(ctx, embedding, criteria)
+}
+
+// SearchPromptsByVector searches the vector collection like
+// SearchPromptsSemanticFast, but with a precomputed embedding instead of
+// embedding criteria.Query, which is ignored. An embedding whose length
+// differs from the stored embeddings' fails with
+// ErrEmbeddingDimensionMismatch.
+func (s *Storage) SearchPromptsByVector(ctx context.Context, embedding []float32, criteria SemanticSearchCriteria) ([]models.Prompt, []float64, error) {
+	ctx, span := s.startSpan(ctx, "SearchPromptsByVector")
+	defer span.End()
+
+	if len(embedding) == 0 {
+		return nil, nil, fmt.Errorf("%w: the vector is empty", ErrEmbeddingDimensionMismatch)
+	}
+	dims, err := s.storedEmbeddingDims(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if dims > 0 && len(embedding) != dims {
+		return nil, nil, fmt.Errorf("%w: expected %d, got %d", ErrEmbeddingDimensionMismatch, dims, len(embedding))
+	}
+	return s.searchByEmbedding(ctx, embedding, criteria)
+}
+
+// storedEmbeddingDims returns the length of the stored embeddings: the
+// configured or auto-detected one, or else that of the newest embedded
+// prompt. It is 0 when nothing is embedded.
+func (s *Storage) storedEmbeddingDims(ctx context.Context) (int, error) {
+	if _, _, dims := s.embedding.get(); dims > 0 {
+		return dims, nil
+	}
+	if s.getOrCreateCollection().Count() == 0 {
+		return 0, nil
+	}
+
+	stmt, _, err := s.db.Prepare("SELECT id FROM prompts ORDER BY created_at DESC, id")
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare prompt ID query: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	for stmt.Step() {
+		id, err := uuid.Parse(stmt.ColumnText(0))
+		if err != nil {
+			continue
+		}
+		if embedding, _ := s.GetPromptEmbedding(ctx, id); len(embedding) > 0 {
+			return len(embedding), nil
+		}
+	}
+	if err := stmt.Err(); err != nil {
+		return 0, fmt.Errorf("failed to list prompt IDs: %w", err)
+	}
+	return 0, nil
+}
+
+// searchByEmbedding ranks the vector collection against embedding,
+// applying the filters and threshold of criteria
+func (s *Storage) searchByEmbedding(ctx context.Context, embedding []float32, criteria SemanticSearchCriteria) ([]models.Prompt, []float64, error) {
+	collection := s.getOrCreateCollection()
+	count := collection.Count()
+	if count == 0 {
+		s.loggerFor(ctx).Debug("Vector collection is empty, no semantic results")
+		return []models.Prompt{}, []float64{}, nil
+	}
+
+	where := map[string]string{}
+	if criteria.Phase != "" {
+		where["phase"] = criteria.Phase
+	}
+	if criteria.Provider != "" {
+		where["provider"] = criteria.Provider
+	}
+
+	// Over-fetch to leave room for the tag and date filters
+	searchLimit := min(criteria.Limit*3, count)
+	results, err := collection.QueryEmbedding(ctx, embedding, searchLimit, where, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query vector collection: %w", err)
+	}
+
+	prompts := []models.Prompt{}
+	similarities := []float64{}
+	for _, result := range results {
+		similarity := float64(result.Similarity)
+		if similarity < criteria.MinSimilarity || len(prompts) >= criteria.Limit {
+			break // results are ordered by similarity
+		}
+
+		promptID, err := uuid.Parse(result.ID)
+		if err != nil {
+			s.loggerFor(ctx).WithError(err).Warn("Invalid prompt ID in vector result")
+			continue
+		}
+		prompt, err := s.GetPromptByID(ctx, promptID)
+		if err != nil {
+			s.loggerFor(ctx).WithError(err).Warn("Failed to retrieve prompt by ID")
+			continue
+		}
+		if criteria.Since != nil && prompt.CreatedAt.Before(*criteria.Since) {
+			continue
+		}
+		if len(criteria.Tags) > 0 && !hasAnyTag(prompt.Tags, criteria.Tags) {
+			continue
+		}
+
+		prompts = append(prompts, *prompt)
+		similarities = append(similarities, similarity)
+	}
+
+	s.loggerFor(ctx).WithFields(logrus.Fields{
+		"results_found":  len(prompts),
+		"min_similarity": criteria.MinSimilarity,
+		"search_limit":   searchLimit,
+	}).Debug("Completed semantic prompt search")
+
+	return prompts, similarities, nil
+}
+
+// hasAnyTag reports whether tags contains any of wanted
+func hasAnyTag(tags, wanted []string) bool {
+	for _, tag := range tags {
+		for _, w := range wanted {
+			if tag == w {
+				return true
+			}
+		}
+	}
+	return false
+}
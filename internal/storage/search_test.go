@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchWithFallback(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	store, err := NewStorage(t.TempDir(), logger)
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	ctx := context.Background()
+	require.NoError(t, store.SavePrompt(ctx, &models.Prompt{Content: "Design a rate limiter", Phase: models.PhaseSolutio}))
+	require.NoError(t, store.SavePrompt(ctx, &models.Prompt{Content: "Write a poem about autumn", Phase: models.PhaseSolutio}))
+
+	contents := func(prompts []*models.Prompt) []string {
+		var result []string
+		for _, prompt := range prompts {
+			result = append(result, prompt.Content)
+		}
+		return result
+	}
+
+	t.Run("failing embedder falls back to text search", func(t *testing.T) {
+		failing := func(ctx context.Context, text string) ([]float32, error) {
+			return nil, errors.New("embedding quota exceeded")
+		}
+		outcome, err := store.SearchWithFallback(ctx, "rate limiter", 10, true, failing)
+		require.NoError(t, err)
+		assert.True(t, outcome.Fallback)
+		assert.Equal(t, SearchTypeText, outcome.SearchType)
+		assert.Contains(t, outcome.FallbackReason, "embedding quota exceeded")
+		assert.Equal(t, []string{"Design a rate limiter"}, contents(outcome.Prompts))
+	})
+
+	t.Run("no embedding provider falls back to text search", func(t *testing.T) {
+		outcome, err := store.SearchWithFallback(ctx, "autumn", 10, true, nil)
+		require.NoError(t, err)
+		assert.True(t, outcome.Fallback)
+		assert.Equal(t, SearchTypeText, outcome.SearchType)
+		assert.Contains(t, outcome.FallbackReason, "no embedding provider")
+		assert.Equal(t, []string{"Write a poem about autumn"}, contents(outcome.Prompts))
+	})
+
+	t.Run("empty embedding falls back to text search", func(t *testing.T) {
+		empty := func(ctx context.Context, text string) ([]float32, error) { return nil, nil }
+		outcome, err := store.SearchWithFallback(ctx, "poem", 10, true, empty)
+		require.NoError(t, err)
+		assert.True(t, outcome.Fallback)
+		assert.Len(t, outcome.Prompts, 1)
+	})
+
+	t.Run("text search is not a fallback", func(t *testing.T) {
+		outcome, err := store.SearchWithFallback(ctx, "rate limiter", 10, false, nil)
+		require.NoError(t, err)
+		assert.False(t, outcome.Fallback)
+		assert.Empty(t, outcome.FallbackReason)
+		assert.Equal(t, SearchTypeText, outcome.SearchType)
+		assert.Len(t, outcome.Prompts, 1)
+	})
+
+	t.Run("canceled searches don't fall back", func(t *testing.T) {
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		failing := func(ctx context.Context, text string) ([]float32, error) { return nil, ctx.Err() }
+		_, err := store.SearchWithFallback(canceled, "rate limiter", 10, true, failing)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
@@ -0,0 +1,254 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// StatisticsOptions selects the optional sections of Statistics
+type StatisticsOptions struct {
+	IncludeRelationships bool
+	IncludeEnhancements  bool
+	IncludeUsage         bool
+}
+
+// Statistics summarizes the prompts in the database
+type Statistics struct {
+	TotalPrompts  int                     `json:"total_prompts"`
+	ByPhase       map[string]int          `json:"by_phase"`
+	ByProvider    map[string]int          `json:"by_provider"`
+	Embeddings    EmbeddingStatistics     `json:"embeddings"`
+	OldestPrompt  *time.Time              `json:"oldest_prompt,omitempty"`
+	NewestPrompt  *time.Time              `json:"newest_prompt,omitempty"`
+	Relationships *RelationshipStatistics `json:"relationships,omitempty"`
+	Enhancements  *EnhancementStatistics  `json:"enhancements,omitempty"`
+	Usage         *UsageStatistics        `json:"usage,omitempty"`
+}
+
+// EmbeddingStatistics reports how many prompts semantic search can find
+type EmbeddingStatistics struct {
+	Embedded        int            `json:"embedded"` // prompts in the current embedding model's index
+	Missing         int            `json:"missing"`
+	CoveragePercent float64        `json:"coverage_percent"`
+	ByModel         map[string]int `json:"by_model"` // prompts by the model they were embedded with
+	Model           string         `json:"model,omitempty"`
+}
+
+// RelationshipStatistics counts prompt relationships by type
+type RelationshipStatistics struct {
+	Total           int                `json:"total"`
+	ByType          map[string]int     `json:"by_type"`
+	AverageStrength map[string]float64 `json:"average_strength"`
+}
+
+// EnhancementStatistics counts prompts by how they were produced
+type EnhancementStatistics struct {
+	ByMethod     map[string]int `json:"by_method"`
+	BySourceType map[string]int `json:"by_source_type"`
+	HistoryRows  int            `json:"history_rows"` // enhancement_history rows, in databases that have the table
+}
+
+// UsageStatistics summarizes how prompts have been used
+type UsageStatistics struct {
+	TotalUsage            int            `json:"total_usage"`
+	UsedPrompts           int            `json:"used_prompts"`
+	AverageRelevanceScore float64        `json:"average_relevance_score"`
+	Interactions          map[string]int `json:"interactions"` // user interactions by action
+}
+
+// Statistics aggregates counts over the prompts table, with the sections
+// selected by opts
+func (s *Storage) Statistics(ctx context.Context, opts StatisticsOptions) (*Statistics, error) {
+	ctx, span := s.startSpan(ctx, "Statistics")
+	defer span.End()
+
+	stats := &Statistics{}
+	var err error
+	if stats.ByPhase, err = s.countBy(ctx, "SELECT COALESCE(phase, ''), COUNT(*) FROM prompts GROUP BY 1"); err != nil {
+		return nil, err
+	}
+	if stats.ByProvider, err = s.countBy(ctx, "SELECT COALESCE(provider, ''), COUNT(*) FROM prompts GROUP BY 1"); err != nil {
+		return nil, err
+	}
+	for _, count := range stats.ByPhase {
+		stats.TotalPrompts += count
+	}
+
+	if stats.TotalPrompts > 0 {
+		oldest, newest, err := s.promptDateRange(ctx)
+		if err != nil {
+			return nil, err
+		}
+		stats.OldestPrompt, stats.NewestPrompt = &oldest, &newest
+	}
+
+	if stats.Embeddings, err = s.embeddingStatistics(ctx, stats.TotalPrompts); err != nil {
+		return nil, err
+	}
+	if opts.IncludeRelationships {
+		if stats.Relationships, err = s.relationshipStatistics(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if opts.IncludeEnhancements {
+		if stats.Enhancements, err = s.enhancementStatistics(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if opts.IncludeUsage {
+		if stats.Usage, err = s.usageStatistics(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return stats, nil
+}
+
+func (s *Storage) embeddingStatistics(ctx context.Context, total int) (EmbeddingStatistics, error) {
+	byModel, err := s.countBy(ctx, "SELECT embedding_model, COUNT(*) FROM prompts WHERE COALESCE(embedding_model, '') != '' GROUP BY 1")
+	if err != nil {
+		return EmbeddingStatistics{}, err
+	}
+
+	embedded := 0
+	if collection := s.vectors.GetCollection(s.collectionName(), nil); collection != nil {
+		embedded = min(collection.Count(), total)
+	}
+	_, model, _ := s.embedding.get()
+	stats := EmbeddingStatistics{
+		Embedded: embedded,
+		Missing:  total - embedded,
+		ByModel:  byModel,
+		Model:    model,
+	}
+	if total > 0 {
+		stats.CoveragePercent = percent(embedded, total)
+	}
+	return stats, nil
+}
+
+func (s *Storage) relationshipStatistics(ctx context.Context) (*RelationshipStatistics, error) {
+	stmt, _, err := s.db.Prepare(`
+		SELECT relationship_type, COUNT(*), AVG(strength)
+		FROM prompt_relationships GROUP BY relationship_type`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare relationship statistics query: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	stats := &RelationshipStatistics{ByType: map[string]int{}, AverageStrength: map[string]float64{}}
+	for stmt.Step() {
+		relType, count := stmt.ColumnText(0), stmt.ColumnInt(1)
+		stats.ByType[relType] = count
+		stats.AverageStrength[relType] = math.Round(stmt.ColumnFloat(2)*1000) / 1000
+		stats.Total += count
+	}
+	if err := stmt.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read relationship statistics: %w", err)
+	}
+	return stats, nil
+}
+
+func (s *Storage) enhancementStatistics(ctx context.Context) (*EnhancementStatistics, error) {
+	stats := &EnhancementStatistics{}
+	var err error
+	if stats.ByMethod, err = s.countBy(ctx, "SELECT enhancement_method, COUNT(*) FROM prompts WHERE COALESCE(enhancement_method, '') != '' GROUP BY 1"); err != nil {
+		return nil, err
+	}
+	if stats.BySourceType, err = s.countBy(ctx, "SELECT source_type, COUNT(*) FROM prompts WHERE COALESCE(source_type, '') != '' GROUP BY 1"); err != nil {
+		return nil, err
+	}
+
+	// enhancement_history only exists in databases created from the older
+	// schema
+	hasHistory, err := s.tableExists("enhancement_history")
+	if err != nil {
+		return nil, err
+	}
+	if hasHistory {
+		if stats.HistoryRows, err = s.countRows(ctx, "SELECT COUNT(*) FROM enhancement_history"); err != nil {
+			return nil, err
+		}
+	}
+	return stats, nil
+}
+
+func (s *Storage) usageStatistics(ctx context.Context) (*UsageStatistics, error) {
+	stmt, _, err := s.db.Prepare(`
+		SELECT COALESCE(SUM(usage_count), 0), COALESCE(SUM(usage_count > 0), 0), COALESCE(AVG(relevance_score), 0)
+		FROM prompts`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare usage statistics query: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	stats := &UsageStatistics{}
+	if stmt.Step() {
+		stats.TotalUsage = stmt.ColumnInt(0)
+		stats.UsedPrompts = stmt.ColumnInt(1)
+		stats.AverageRelevanceScore = math.Round(stmt.ColumnFloat(2)*1000) / 1000
+	}
+	if err := stmt.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read usage statistics: %w", err)
+	}
+
+	if stats.Interactions, err = s.countBy(ctx, "SELECT action, COUNT(*) FROM user_interactions GROUP BY action"); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// promptDateRange returns when the oldest and newest prompts were created
+func (s *Storage) promptDateRange(ctx context.Context) (time.Time, time.Time, error) {
+	stmt, _, err := s.db.Prepare("SELECT MIN(created_at), MAX(created_at) FROM prompts")
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("failed to prepare prompt date range query: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	if !stmt.Step() {
+		if err := stmt.Err(); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("failed to read prompt date range: %w", err)
+		}
+		return time.Time{}, time.Time{}, nil
+	}
+	return time.Unix(stmt.ColumnInt64(0), 0), time.Unix(stmt.ColumnInt64(1), 0), nil
+}
+
+// countBy runs a query selecting a key and a count per row into a map
+func (s *Storage) countBy(ctx context.Context, query string) (map[string]int, error) {
+	stmt, _, err := s.db.Prepare(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare statistics query: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	counts := map[string]int{}
+	for stmt.Step() {
+		counts[stmt.ColumnText(0)] = stmt.ColumnInt(1)
+	}
+	if err := stmt.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read statistics: %w", err)
+	}
+	return counts, nil
+}
+
+// countRows runs a query selecting a single count
+func (s *Storage) countRows(ctx context.Context, query string) (int, error) {
+	stmt, _, err := s.db.Prepare(query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare count query: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	if !stmt.Step() {
+		return 0, stmt.Err()
+	}
+	return stmt.ColumnInt(0), nil
+}
+
+// percent returns part as a percentage of total, to one decimal place
+func percent(part, total int) float64 {
+	return math.Round(float64(part)/float64(total)*1000) / 10
+}
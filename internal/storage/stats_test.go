@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatistics(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	store, err := NewStorage(t.TempDir(), logger)
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	ctx := context.Background()
+	empty, err := store.Statistics(ctx, StatisticsOptions{IncludeRelationships: true, IncludeUsage: true})
+	require.NoError(t, err)
+	assert.Zero(t, empty.TotalPrompts)
+	assert.Zero(t, empty.Embeddings.CoveragePercent)
+	assert.Nil(t, empty.OldestPrompt)
+
+	sessionID := uuid.New()
+	embedded := func(p *models.Prompt) *models.Prompt {
+		p.Embedding, p.EmbeddingProvider, p.EmbeddingModel = []float32{1, 0, 0}, "openai", "text-embedding-3-small"
+		return p
+	}
+	parent := embedded(&models.Prompt{Content: "outline the talk", Phase: models.PhasePrimaMaterial, Provider: "openai", SessionID: sessionID, UsageCount: 3, RelevanceScore: 0.8})
+	require.NoError(t, store.SavePrompt(ctx, parent))
+	for _, p := range []*models.Prompt{
+		embedded(&models.Prompt{Content: "outline the talk briefly", Phase: models.PhaseSolutio, Provider: "openai", SessionID: sessionID, ParentID: &parent.ID, EnhancementMethod: "refine"}),
+		{Content: "draft the abstract", Phase: models.PhaseSolutio, Provider: "anthropic", SourceType: "manual", RelevanceScore: 0.4},
+		{Content: "polish the abstract", Phase: models.PhaseCoagulatio, Provider: "anthropic"},
+	} {
+		require.NoError(t, store.SavePrompt(ctx, p))
+	}
+	require.NoError(t, store.SaveInteraction(ctx, &models.UserInteraction{PromptID: parent.ID, SessionID: sessionID, Action: "chosen"}))
+
+	t.Run("counts and coverage", func(t *testing.T) {
+		stats, err := store.Statistics(ctx, StatisticsOptions{})
+		require.NoError(t, err)
+
+		assert.Equal(t, 4, stats.TotalPrompts)
+		assert.Equal(t, map[string]int{"prima-materia": 1, "solutio": 2, "coagulatio": 1}, stats.ByPhase)
+		assert.Equal(t, map[string]int{"openai": 2, "anthropic": 2}, stats.ByProvider)
+		assert.Equal(t, 2, stats.Embeddings.Embedded)
+		assert.Equal(t, 2, stats.Embeddings.Missing)
+		assert.Equal(t, 50.0, stats.Embeddings.CoveragePercent)
+		assert.Equal(t, map[string]int{"text-embedding-3-small": 2}, stats.Embeddings.ByModel)
+		require.NotNil(t, stats.OldestPrompt)
+		require.NotNil(t, stats.NewestPrompt)
+		assert.Nil(t, stats.Relationships, "optional sections are omitted unless requested")
+		assert.Nil(t, stats.Usage)
+	})
+
+	t.Run("optional sections", func(t *testing.T) {
+		stats, err := store.Statistics(ctx, StatisticsOptions{IncludeRelationships: true, IncludeEnhancements: true, IncludeUsage: true})
+		require.NoError(t, err)
+
+		require.NotNil(t, stats.Relationships)
+		assert.Equal(t, 1, stats.Relationships.Total)
+		assert.Equal(t, map[string]int{models.RelationshipDerivedFrom: 1}, stats.Relationships.ByType)
+		assert.Equal(t, 1.0, stats.Relationships.AverageStrength[models.RelationshipDerivedFrom])
+
+		require.NotNil(t, stats.Enhancements)
+		assert.Equal(t, map[string]int{"refine": 1}, stats.Enhancements.ByMethod)
+		assert.Equal(t, 1, stats.Enhancements.BySourceType["manual"])
+
+		require.NotNil(t, stats.Usage)
+		assert.Equal(t, 3, stats.Usage.TotalUsage)
+		assert.Equal(t, 1, stats.Usage.UsedPrompts)
+		assert.Equal(t, map[string]int{"chosen": 1}, stats.Usage.Interactions)
+	})
+}
+
+func TestTagStats(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	store, err := NewStorage(t.TempDir(), logger)
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	ctx := context.Background()
+	empty, err := store.TagStats(ctx, 1)
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+
+	for _, p := range []*models.Prompt{
+		{Content: "parse csv", Tags: []string{"csv", "parser"}, RelevanceScore: 0.9},
+		{Content: "parse json", Tags: []string{"json", "parser", "parser"}, RelevanceScore: 0.5},
+		{Content: "stream csv", Tags: []string{"csv", "streaming"}, RelevanceScore: 0.4},
+		{Content: "untagged"},
+	} {
+		p.Phase, p.Provider = models.PhaseSolutio, "openai"
+		require.NoError(t, store.SavePrompt(ctx, p))
+	}
+
+	stats, err := store.TagStats(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, stats, 4)
+	byTag := map[string]TagStat{}
+	for _, stat := range stats {
+		byTag[stat.Tag] = stat
+	}
+	assert.Equal(t, []string{"csv", "parser"}, []string{stats[0].Tag, stats[1].Tag}, "most used first, then by name")
+	assert.Equal(t, 2, byTag["csv"].PromptCount)
+	assert.InDelta(t, 0.65, byTag["csv"].AverageScore, 1e-9)
+	assert.Equal(t, 2, byTag["parser"].PromptCount, "a tag repeated within a prompt counts once")
+	assert.InDelta(t, 0.7, byTag["parser"].AverageScore, 1e-9)
+	assert.Equal(t, 1, byTag["json"].PromptCount)
+	assert.InDelta(t, 0.4, byTag["streaming"].AverageScore, 1e-9)
+
+	frequent, err := store.TagStats(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []TagStat{stats[0], stats[1]}, frequent)
+}
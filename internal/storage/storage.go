@@ -6,36 +6,100 @@ import (
 	_ "embed"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jonwraymond/prompt-alchemy/internal/log"
+	"github.com/jonwraymond/prompt-alchemy/internal/observability/tracing"
 	"github.com/jonwraymond/prompt-alchemy/pkg/models"
 	"github.com/ncruces/go-sqlite3"
 	_ "github.com/ncruces/go-sqlite3/embed"
 	"github.com/philippgille/chromem-go"
 	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 //go:embed schema.sql
 var ddl string
 
+// ErrPromptNotFound is returned when no prompt has the requested ID
+var ErrPromptNotFound = errors.New("prompt not found")
+
 // Storage provides a future-proof hybrid approach:
 // - SQLite (WASM) for structured data, metadata, and relationships
 // - chromem-go for vector operations and similarity search
 // This eliminates atomic operations issues while maintaining performance
 type Storage struct {
+	dsn     string        // database path, for jobs that open their own connection
 	db      *sqlite3.Conn // SQLite for structured data (no vector extension)
 	vectors *chromem.DB   // chromem-go for vector operations
 	logger  *logrus.Logger
+	events  *EventBus // lifecycle events for prompt mutations
+
+	contentLimit contentLimit     // storage.max_content_bytes guard applied on save
+	jobs         *jobs            // background maintenance jobs, one of each at a time
+	embedding    *embeddingConfig // current embedding config, shared with background jobs
+	retry        retryPolicy      // storage.retry.* backoff for writes that hit a locked database
+	closed       *atomic.Bool     // set by Close; Ping reports closed storage as down
+}
+
+// embeddingConfig tracks the embedding provider, model and dimensions the
+// vector collection is keyed on. Saves and background jobs read and
+// auto-detect it concurrently.
+type embeddingConfig struct {
+	mu       sync.RWMutex
+	provider string
+	model    string
+	dims     int
+}
+
+func (c *embeddingConfig) get() (provider, model string, dims int) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.provider, c.model, c.dims
+}
+
+func (c *embeddingConfig) set(provider, model string, dims int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.provider, c.model, c.dims = provider, model, dims
+}
+
+func (c *embeddingConfig) setDims(dims int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.dims = dims
+}
+
+// detect fills in whichever of provider, model and dims aren't set yet and
+// returns the values it filled in
+func (c *embeddingConfig) detect(provider, model string, dims int) logrus.Fields {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	// New fields for tracking current embedding config
-	currentEmbeddingModel    string
-	currentEmbeddingProvider string
-	currentEmbeddingDims     int
+	detected := logrus.Fields{}
+	if c.provider == "" && provider != "" {
+		c.provider = provider
+		detected["provider"] = provider
+	}
+	if c.model == "" && model != "" {
+		c.model = model
+		detected["model"] = model
+	}
+	if c.dims == 0 && dims > 0 {
+		c.dims = dims
+		detected["dims"] = dims
+	}
+	return detected
 }
 
 // NewStorage creates a new Storage instance with hybrid architecture
@@ -48,16 +112,16 @@ func NewStorage(dsn string, logger *logrus.Logger) (*Storage, error) {
 	logger.WithField("dsn", dsn).Info("Initializing future-proof hybrid storage")
 
 	// Initialize SQLite (WASM) for structured data - no vector extensions needed
-	db, err := sqlite3.Open(dsn)
+	db, err := openConn(dsn)
 	if err != nil {
 		logger.WithError(err).Error("Failed to open SQLite database")
-		return nil, fmt.Errorf("failed to open SQLite database: %w", err)
+		return nil, err
 	}
 
-	// Enable WAL mode for better concurrency
-	if err := db.Exec("PRAGMA journal_mode=WAL"); err != nil {
+	// Wait for locks held by other connections instead of failing at once
+	if err := db.BusyTimeout(loadBusyTimeout()); err != nil {
 		_ = db.Close()
-		return nil, fmt.Errorf("failed to set WAL mode: %w", err)
+		return nil, fmt.Errorf("failed to set busy timeout: %w", err)
 	}
 
 	// Create tables (no vector-specific tables needed)
@@ -78,15 +142,85 @@ func NewStorage(dsn string, logger *logrus.Logger) (*Storage, error) {
 
 	logger.Info("Successfully initialized hybrid storage: SQLite (WASM) + chromem-go")
 
+	events := NewEventBus(logger)
+	var webhooks []WebhookConfig
+	if err := viper.UnmarshalKey("events.webhooks", &webhooks); err != nil {
+		logger.WithError(err).Warn("Invalid events.webhooks configuration, webhooks disabled")
+	}
+	for _, config := range webhooks {
+		subscriber, err := NewWebhookSubscriber(config, logger)
+		if err != nil {
+			logger.WithError(err).Warn("Skipping invalid webhook")
+			continue
+		}
+		events.Subscribe(subscriber)
+		logger.WithField("url", config.URL).Info("Registered prompt event webhook")
+	}
+
 	return &Storage{
-		db:      db,
-		vectors: vectors,
-		logger:  logger,
+		dsn:          dsn,
+		db:           db,
+		vectors:      vectors,
+		logger:       logger,
+		events:       events,
+		contentLimit: loadContentLimit(logger),
+		jobs:         &jobs{},
+		embedding:    &embeddingConfig{},
+		retry:        loadRetryPolicy(),
+		closed:       &atomic.Bool{},
 	}, nil
 }
 
+// openConn opens a SQLite connection to dsn in WAL mode, so background jobs
+// can read on their own connection while requests write on the main one
+func openConn(dsn string) (*sqlite3.Conn, error) {
+	db, err := sqlite3.Open(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SQLite database: %w", err)
+	}
+
+	// Enable WAL mode for better concurrency
+	if err := db.Exec("PRAGMA journal_mode=WAL"); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to set WAL mode: %w", err)
+	}
+	// Wait out another connection's write lock rather than fail at once
+	if err := db.BusyTimeout(5 * time.Second); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to set busy timeout: %w", err)
+	}
+	return db, nil
+}
+
+// withConn returns a view of s on its own database connection, sharing
+// everything else, for a background job to use off the request path. Close
+// it with closeConn when the job is done.
+func (s *Storage) withConn() (*Storage, error) {
+	db, err := openConn(s.dsn)
+	if err != nil {
+		return nil, err
+	}
+	view := *s
+	view.db = db
+	return &view, nil
+}
+
+// closeConn closes the connection of a view returned by withConn
+func (s *Storage) closeConn() {
+	if err := s.db.Close(); err != nil {
+		s.logger.WithError(err).Warn("Failed to close background job connection")
+	}
+}
+
 // Close closes all database connections
 func (s *Storage) Close() error {
+	if s.closed.Swap(true) {
+		return nil // already closed
+	}
+
+	// Let pending webhook deliveries finish before the process exits
+	s.events.Close()
+
 	if err := s.db.Close(); err != nil {
 		s.logger.WithError(err).Error("Failed to close SQLite connection")
 		return err
@@ -99,11 +233,19 @@ func (s *Storage) Close() error {
 	return nil
 }
 
+// loggerFor returns the storage logger tagged with ctx's request ID
+func (s *Storage) loggerFor(ctx context.Context) *logrus.Entry {
+	return log.WithContext(ctx, s.logger)
+}
+
+// startSpan starts a span for a storage operation as a child of ctx's span
+func (s *Storage) startSpan(ctx context.Context, operation string) (context.Context, trace.Span) {
+	return tracing.Start(ctx, "storage."+operation, attribute.String("db.system", "sqlite"))
+}
+
 // SetEmbeddingConfig updates the current embedding configuration
 func (s *Storage) SetEmbeddingConfig(provider, model string, dims int) {
-	s.currentEmbeddingProvider = provider
-	s.currentEmbeddingModel = model
-	s.currentEmbeddingDims = dims
+	s.embedding.set(provider, model, dims)
 
 	s.logger.WithFields(logrus.Fields{
 		"provider": provider,
@@ -114,13 +256,27 @@ func (s *Storage) SetEmbeddingConfig(provider, model string, dims int) {
 
 // GetEmbeddingConfig returns the current embedding configuration
 func (s *Storage) GetEmbeddingConfig() (provider, model string, dims int) {
-	return s.currentEmbeddingProvider, s.currentEmbeddingModel, s.currentEmbeddingDims
+	return s.embedding.get()
 }
 
 // SavePrompt saves a prompt using the hybrid approach:
 // - Structured data goes to SQLite
 // - Embedding goes to chromem-go for efficient vector search
 func (s *Storage) SavePrompt(ctx context.Context, p *models.Prompt) error {
+	ctx, span := s.startSpan(ctx, "SavePrompt")
+	defer span.End()
+
+	eventType, err := s.savePrompt(ctx, p)
+	if err != nil {
+		return err
+	}
+	s.publishPrompt(ctx, eventType, p)
+	return nil
+}
+
+// savePrompt does the work of SavePrompt without publishing its event, so
+// callers running it in a transaction can publish once it commits
+func (s *Storage) savePrompt(ctx context.Context, p *models.Prompt) (EventType, error) {
 	p.UpdatedAt = time.Now()
 	if p.CreatedAt.IsZero() {
 		p.CreatedAt = p.UpdatedAt
@@ -129,32 +285,81 @@ func (s *Storage) SavePrompt(ctx context.Context, p *models.Prompt) error {
 		p.ID = uuid.New()
 	}
 
+	size := len(p.Content)
+	truncated, err := s.contentLimit.apply(p)
+	if err != nil {
+		s.loggerFor(ctx).WithError(err).WithField("prompt_id", p.ID).Warn("Rejected oversized prompt")
+		return "", err
+	}
+	if truncated {
+		s.loggerFor(ctx).WithFields(logrus.Fields{
+			"prompt_id":      p.ID,
+			"original_bytes": size,
+			"stored_bytes":   len(p.Content),
+		}).Warn("Truncated oversized prompt content")
+	}
+
+	// SavePrompt upserts, so look first to tell creates from updates
+	eventType := EventPromptCreated
+	if exists, err := s.promptExists(p.ID); err != nil {
+		s.loggerFor(ctx).WithError(err).WithField("prompt_id", p.ID).Warn("Failed to check for existing prompt")
+	} else if exists {
+		eventType = EventPromptUpdated
+	}
+
 	// Save structured data to SQLite
-	if err := s.savePromptMetadata(ctx, p); err != nil {
-		return fmt.Errorf("failed to save prompt metadata: %w", err)
+	if err := s.withRetry(ctx, "save_prompt", func() error { return s.savePromptMetadata(ctx, p) }); err != nil {
+		return "", fmt.Errorf("failed to save prompt metadata: %w", err)
+	}
+
+	if err := s.withRetry(ctx, "save_prompt", func() error { return s.savePromptDetails(p) }); err != nil {
+		return "", fmt.Errorf("failed to save prompt details: %w", err)
+	}
+
+	if err := s.withRetry(ctx, "save_prompt", func() error { return s.savePromptInput(p) }); err != nil {
+		return "", fmt.Errorf("failed to save prompt input template: %w", err)
+	}
+
+	// Record cascade lineage so sessions can be traced across phases
+	if err := s.saveDerivedFrom(ctx, p); err != nil {
+		s.loggerFor(ctx).WithError(err).WithField("prompt_id", p.ID).Warn("Failed to save prompt lineage")
 	}
 
 	// Save embedding to chromem-go if available
 	if len(p.Embedding) > 0 {
 		// Auto-detect dimensions if not set
-		if s.currentEmbeddingDims == 0 {
-			s.currentEmbeddingDims = len(p.Embedding)
-			s.logger.WithField("dims", s.currentEmbeddingDims).Info("Auto-detected embedding dimensions")
+		if detected := s.embedding.detect("", "", len(p.Embedding)); len(detected) > 0 {
+			s.loggerFor(ctx).WithFields(detected).Info("Auto-detected embedding dimensions")
 		}
 
 		// Verify dimensions match
-		if len(p.Embedding) != s.currentEmbeddingDims {
-			return fmt.Errorf("embedding dimension mismatch: expected %d, got %d",
-				s.currentEmbeddingDims, len(p.Embedding))
+		if _, _, dims := s.embedding.get(); len(p.Embedding) != dims {
+			return "", fmt.Errorf("embedding dimension mismatch: expected %d, got %d",
+				dims, len(p.Embedding))
 		}
 
 		if err := s.savePromptEmbedding(ctx, p); err != nil {
-			s.logger.WithError(err).Warn("Failed to save embedding, continuing without vector search capability")
+			s.loggerFor(ctx).WithError(err).Warn("Failed to save embedding, continuing without vector search capability")
 		}
 	}
 
-	s.logger.WithField("prompt_id", p.ID).Debug("Successfully saved prompt with hybrid approach")
-	return nil
+	s.loggerFor(ctx).WithField("prompt_id", p.ID).Debug("Successfully saved prompt with hybrid approach")
+	return eventType, nil
+}
+
+// promptExists reports whether a prompt with the given ID is stored
+func (s *Storage) promptExists(id uuid.UUID) (bool, error) {
+	stmt, _, err := s.db.Prepare("SELECT 1 FROM prompts WHERE id = ?")
+	if err != nil {
+		return false, fmt.Errorf("failed to prepare prompt exists statement: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	_ = stmt.BindText(1, id.String())
+	if stmt.Step() {
+		return true, nil
+	}
+	return false, stmt.Err()
 }
 
 // savePromptMetadata saves the prompt's structured data to SQLite
@@ -164,8 +369,7 @@ func (s *Storage) savePromptMetadata(ctx context.Context, p *models.Prompt) erro
 		return fmt.Errorf("failed to marshal tags: %w", err)
 	}
 
-	hash := sha256.Sum256([]byte(p.Content))
-	contentHash := hex.EncodeToString(hash[:])
+	contentHash := hashContent(p.Content)
 
 	stmt, _, err := s.db.Prepare(`
 		INSERT INTO prompts (
@@ -243,23 +447,55 @@ func (s *Storage) savePromptMetadata(ctx context.Context, p *models.Prompt) erro
 	return nil
 }
 
+// hashContent returns the content_hash of a prompt's content
+func hashContent(content string) string {
+	hash := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(hash[:])
+}
+
+// promptIDByContent returns the ID of the stored prompt other than exclude
+// with the given content, or uuid.Nil if there is none
+func (s *Storage) promptIDByContent(content string, exclude uuid.UUID) (uuid.UUID, error) {
+	stmt, _, err := s.db.Prepare("SELECT id FROM prompts WHERE content_hash = ? AND id <> ?")
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to prepare content lookup: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	_ = stmt.BindText(1, hashContent(content))
+	_ = stmt.BindText(2, exclude.String())
+	if stmt.Step() {
+		id, err := uuid.Parse(stmt.ColumnText(0))
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("invalid prompt ID %q: %w", stmt.ColumnText(0), err)
+		}
+		return id, nil
+	}
+	if err := stmt.Err(); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to look up prompt content: %w", err)
+	}
+	return uuid.Nil, nil
+}
+
 // savePromptEmbedding saves the prompt's embedding to chromem-go
 func (s *Storage) savePromptEmbedding(ctx context.Context, p *models.Prompt) error {
 	// Auto-detect embedding provider and model if not configured
-	if s.currentEmbeddingProvider == "" && p.EmbeddingProvider != "" {
-		s.currentEmbeddingProvider = p.EmbeddingProvider
-		s.logger.WithField("provider", s.currentEmbeddingProvider).Info("Auto-detected embedding provider")
+	if detected := s.embedding.detect(p.EmbeddingProvider, p.EmbeddingModel, len(p.Embedding)); len(detected) > 0 {
+		s.loggerFor(ctx).WithFields(detected).Info("Auto-detected embedding config")
 	}
-	if s.currentEmbeddingModel == "" && p.EmbeddingModel != "" {
-		s.currentEmbeddingModel = p.EmbeddingModel
-		s.logger.WithField("model", s.currentEmbeddingModel).Info("Auto-detected embedding model")
-	}
-	if s.currentEmbeddingDims == 0 && p.Embedding != nil {
-		s.currentEmbeddingDims = len(p.Embedding)
-		s.logger.WithField("dims", s.currentEmbeddingDims).Info("Auto-detected embedding dimensions")
+
+	collection := s.getOrCreateCollection()
+	err := collection.AddDocument(ctx, s.embeddingDocument(p))
+	if err != nil {
+		return fmt.Errorf("failed to add document to vector collection: %w", err)
 	}
 
-	document := chromem.Document{
+	return nil
+}
+
+// embeddingDocument builds the vector collection document for p
+func (s *Storage) embeddingDocument(p *models.Prompt) chromem.Document {
+	return chromem.Document{
 		ID:        p.ID.String(),
 		Embedding: p.Embedding,
 		Metadata: map[string]string{
@@ -274,14 +510,6 @@ func (s *Storage) savePromptEmbedding(ctx context.Context, p *models.Prompt) err
 		},
 		Content: p.Content, // For full-text search capabilities
 	}
-
-	collection := s.getOrCreateCollection()
-	err := collection.AddDocument(ctx, document)
-	if err != nil {
-		return fmt.Errorf("failed to add document to vector collection: %w", err)
-	}
-
-	return nil
 }
 
 // getCollectionName generates a collection name based on embedding config
@@ -303,23 +531,26 @@ func (s *Storage) getCollectionName(provider, model string, dims int) string {
 	return collectionName
 }
 
-// getOrCreateCollection returns the collection for current embedding config
-func (s *Storage) getOrCreateCollection() *chromem.Collection {
+// collectionName returns the collection name for the current embedding config
+func (s *Storage) collectionName() string {
 	// Use default collection name if no embedding config is set
-	collectionName := "prompts"
-	if s.currentEmbeddingProvider != "" && s.currentEmbeddingModel != "" && s.currentEmbeddingDims > 0 {
-		collectionName = s.getCollectionName(
-			s.currentEmbeddingProvider,
-			s.currentEmbeddingModel,
-			s.currentEmbeddingDims,
-		)
+	provider, model, dims := s.embedding.get()
+	if provider != "" && model != "" && dims > 0 {
+		return s.getCollectionName(provider, model, dims)
 	}
+	return "prompts"
+}
+
+// getOrCreateCollection returns the collection for current embedding config
+func (s *Storage) getOrCreateCollection() *chromem.Collection {
+	collectionName := s.collectionName()
+	provider, model, dims := s.embedding.get()
 
 	s.logger.WithFields(logrus.Fields{
 		"collection": collectionName,
-		"provider":   s.currentEmbeddingProvider,
-		"model":      s.currentEmbeddingModel,
-		"dims":       s.currentEmbeddingDims,
+		"provider":   provider,
+		"model":      model,
+		"dims":       dims,
 	}).Debug("Getting or creating collection")
 
 	collection := s.vectors.GetCollection(collectionName, nil)
@@ -332,16 +563,19 @@ func (s *Storage) getOrCreateCollection() *chromem.Collection {
 
 // SearchSimilarPrompts finds prompts with similar embeddings using chromem-go
 func (s *Storage) SearchSimilarPrompts(ctx context.Context, embedding []float32, limit int) ([]*models.Prompt, error) {
+	ctx, span := s.startSpan(ctx, "SearchSimilarPrompts")
+	defer span.End()
+
 	collection := s.getOrCreateCollection()
 	if collection == nil {
-		s.logger.Warn("No vector collection available, falling back to recent prompts")
+		s.loggerFor(ctx).Warn("No vector collection available, falling back to recent prompts")
 		return s.GetHighQualityHistoricalPrompts(ctx, limit)
 	}
 
 	// Check if collection has documents
 	count := collection.Count()
 	if count == 0 {
-		s.logger.Debug("Vector collection is empty, falling back to recent prompts")
+		s.loggerFor(ctx).Debug("Vector collection is empty, falling back to recent prompts")
 		return s.GetHighQualityHistoricalPrompts(ctx, limit)
 	}
 
@@ -361,20 +595,20 @@ func (s *Storage) SearchSimilarPrompts(ctx context.Context, embedding []float32,
 	for _, result := range results {
 		promptID, err := uuid.Parse(result.ID)
 		if err != nil {
-			s.logger.WithError(err).Warn("Invalid prompt ID in vector result")
+			s.loggerFor(ctx).WithError(err).Warn("Invalid prompt ID in vector result")
 			continue
 		}
 
 		prompt, err := s.GetPromptByID(ctx, promptID)
 		if err != nil {
-			s.logger.WithError(err).Warn("Failed to retrieve prompt by ID")
+			s.loggerFor(ctx).WithError(err).Warn("Failed to retrieve prompt by ID")
 			continue
 		}
 
 		prompts = append(prompts, prompt)
 	}
 
-	s.logger.WithFields(logrus.Fields{
+	s.loggerFor(ctx).WithFields(logrus.Fields{
 		"embedding_dim": len(embedding),
 		"results_found": len(prompts),
 		"limit":         limit,
@@ -387,14 +621,14 @@ func (s *Storage) SearchSimilarPrompts(ctx context.Context, embedding []float32,
 func (s *Storage) SearchSimilarHighQualityPrompts(ctx context.Context, embedding []float32, minScore float64, limit int) ([]*models.Prompt, error) {
 	collection := s.getOrCreateCollection()
 	if collection == nil {
-		s.logger.Warn("No vector collection available, falling back to high quality prompts")
+		s.loggerFor(ctx).Warn("No vector collection available, falling back to high quality prompts")
 		return s.GetHighQualityHistoricalPrompts(ctx, limit)
 	}
 
 	// Check if collection has documents
 	count := collection.Count()
 	if count == 0 {
-		s.logger.Debug("Vector collection is empty, falling back to high quality prompts")
+		s.loggerFor(ctx).Debug("Vector collection is empty, falling back to high quality prompts")
 		return s.GetHighQualityHistoricalPrompts(ctx, limit)
 	}
 
@@ -414,13 +648,13 @@ func (s *Storage) SearchSimilarHighQualityPrompts(ctx context.Context, embedding
 	for _, result := range results {
 		promptID, err := uuid.Parse(result.ID)
 		if err != nil {
-			s.logger.WithError(err).Warn("Invalid prompt ID in vector result")
+			s.loggerFor(ctx).WithError(err).Warn("Invalid prompt ID in vector result")
 			continue
 		}
 
 		prompt, err := s.GetPromptByID(ctx, promptID)
 		if err != nil {
-			s.logger.WithError(err).Warn("Failed to retrieve prompt by ID")
+			s.loggerFor(ctx).WithError(err).Warn("Failed to retrieve prompt by ID")
 			continue
 		}
 
@@ -433,7 +667,7 @@ func (s *Storage) SearchSimilarHighQualityPrompts(ctx context.Context, embedding
 		}
 	}
 
-	s.logger.WithFields(logrus.Fields{
+	s.loggerFor(ctx).WithFields(logrus.Fields{
 		"embedding_dim": len(embedding),
 		"results_found": len(prompts),
 		"min_score":     minScore,
@@ -491,6 +725,9 @@ func (s *Storage) GetPromptsWithoutEmbeddings(ctx context.Context, limit int) ([
 
 // SaveInteraction saves a user interaction to the database
 func (s *Storage) SaveInteraction(ctx context.Context, interaction *models.UserInteraction) error {
+	_, span := s.startSpan(ctx, "SaveInteraction")
+	defer span.End()
+
 	if interaction.ID == uuid.Nil {
 		interaction.ID = uuid.New()
 	}
@@ -523,7 +760,10 @@ func (s *Storage) SaveInteraction(ctx context.Context, interaction *models.UserI
 
 // GetPromptByID retrieves a single prompt by its ID
 func (s *Storage) GetPromptByID(ctx context.Context, id uuid.UUID) (*models.Prompt, error) {
-	query := s.baseSelectQuery() + " WHERE id = ? LIMIT 1;"
+	_, span := s.startSpan(ctx, "GetPromptByID")
+	defer span.End()
+
+	query := strings.Replace(s.baseSelectQuery(), ";", " WHERE id = ? LIMIT 1;", 1)
 	stmt, _, err := s.db.Prepare(query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to prepare get prompt by id query: %w", err)
@@ -537,7 +777,7 @@ func (s *Storage) GetPromptByID(ctx context.Context, id uuid.UUID) (*models.Prom
 		return nil, err
 	}
 	if len(prompts) == 0 {
-		return nil, fmt.Errorf("prompt with id %s not found", id)
+		return nil, fmt.Errorf("%w: %s", ErrPromptNotFound, id)
 	}
 	return prompts[0], nil
 }
@@ -575,8 +815,9 @@ func (s *Storage) UpdatePromptRelevanceScore(ctx context.Context, promptID uuid.
 			return fmt.Errorf("failed to execute update relevance score statement: %w", err)
 		}
 	}
+	s.events.Publish(ctx, Event{Type: EventPromptUpdated, PromptID: promptID})
 
-	s.logger.WithFields(logrus.Fields{
+	s.loggerFor(ctx).WithFields(logrus.Fields{
 		"prompt_id":  promptID,
 		"new_score":  newScore,
 		"updated_at": time.Now(),
@@ -622,72 +863,80 @@ func (s *Storage) ListInteractions(ctx context.Context, since time.Time) ([]*mod
 func (s *Storage) scanPrompts(stmt *sqlite3.Stmt) ([]*models.Prompt, error) {
 	var results []*models.Prompt
 	for stmt.Step() {
-		p := &models.Prompt{}
-		p.ID, _ = uuid.Parse(stmt.ColumnText(0))
-		p.Content = stmt.ColumnText(1)
-		p.Phase = models.Phase(stmt.ColumnText(2))
-		p.Provider = stmt.ColumnText(3)
-		p.Model = stmt.ColumnText(4)
-		p.Temperature = stmt.ColumnFloat(5)
-		p.MaxTokens = stmt.ColumnInt(6)
-		p.ActualTokens = stmt.ColumnInt(7)
-
-		var tagsJSON string
-		if stmt.ColumnType(8) != sqlite3.NULL {
-			tagsJSON = stmt.ColumnText(8)
-			_ = json.Unmarshal([]byte(tagsJSON), &p.Tags)
-		}
+		results = append(results, s.scanPrompt(stmt))
+	}
+	if err := stmt.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
 
-		if stmt.ColumnType(9) != sqlite3.NULL {
-			parentID, _ := uuid.Parse(stmt.ColumnText(9))
-			p.ParentID = &parentID
-		}
+// scanPrompt scans the current SQLite row into a Prompt struct
+func (s *Storage) scanPrompt(stmt *sqlite3.Stmt) *models.Prompt {
+	p := &models.Prompt{}
+	p.ID, _ = uuid.Parse(stmt.ColumnText(0))
+	p.Content = stmt.ColumnText(1)
+	p.Phase = models.Phase(stmt.ColumnText(2))
+	p.Provider = stmt.ColumnText(3)
+	p.Model = stmt.ColumnText(4)
+	p.Temperature = stmt.ColumnFloat(5)
+	p.MaxTokens = stmt.ColumnInt(6)
+	p.ActualTokens = stmt.ColumnInt(7)
 
-		if stmt.ColumnType(10) != sqlite3.NULL {
-			p.SessionID, _ = uuid.Parse(stmt.ColumnText(10))
-		}
+	var tagsJSON string
+	if stmt.ColumnType(8) != sqlite3.NULL {
+		tagsJSON = stmt.ColumnText(8)
+		_ = json.Unmarshal([]byte(tagsJSON), &p.Tags)
+	}
 
-		p.SourceType = stmt.ColumnText(11)
-		p.EnhancementMethod = stmt.ColumnText(12)
-		p.RelevanceScore = stmt.ColumnFloat(13)
-		p.UsageCount = stmt.ColumnInt(14)
-		p.GenerationCount = stmt.ColumnInt(15)
+	if stmt.ColumnType(9) != sqlite3.NULL {
+		parentID, _ := uuid.Parse(stmt.ColumnText(9))
+		p.ParentID = &parentID
+	}
 
-		if stmt.ColumnType(16) != sqlite3.NULL {
-			lastUsedUnix := stmt.ColumnInt64(16)
-			lastUsedTime := time.Unix(lastUsedUnix, 0)
-			p.LastUsedAt = &lastUsedTime
-		}
+	if stmt.ColumnType(10) != sqlite3.NULL {
+		p.SessionID, _ = uuid.Parse(stmt.ColumnText(10))
+	}
 
-		p.OriginalInput = stmt.ColumnText(17)
-		p.PersonaUsed = stmt.ColumnText(18)
-		p.TargetModelFamily = stmt.ColumnText(19)
+	p.SourceType = stmt.ColumnText(11)
+	p.EnhancementMethod = stmt.ColumnText(12)
+	p.RelevanceScore = stmt.ColumnFloat(13)
+	p.UsageCount = stmt.ColumnInt(14)
+	p.GenerationCount = stmt.ColumnInt(15)
 
-		if stmt.ColumnType(20) != sqlite3.NULL {
-			createdUnix := stmt.ColumnInt64(20)
-			p.CreatedAt = time.Unix(createdUnix, 0)
-		}
-		if stmt.ColumnType(21) != sqlite3.NULL {
-			updatedUnix := stmt.ColumnInt64(21)
-			p.UpdatedAt = time.Unix(updatedUnix, 0)
-		}
+	if stmt.ColumnType(16) != sqlite3.NULL {
+		lastUsedUnix := stmt.ColumnInt64(16)
+		lastUsedTime := time.Unix(lastUsedUnix, 0)
+		p.LastUsedAt = &lastUsedTime
+	}
 
-		p.EmbeddingModel = stmt.ColumnText(22)
-		p.EmbeddingProvider = stmt.ColumnText(23)
+	p.OriginalInput = stmt.ColumnText(17)
+	p.PersonaUsed = stmt.ColumnText(18)
+	p.TargetModelFamily = stmt.ColumnText(19)
 
-		results = append(results, p)
+	if stmt.ColumnType(20) != sqlite3.NULL {
+		createdUnix := stmt.ColumnInt64(20)
+		p.CreatedAt = time.Unix(createdUnix, 0)
 	}
-	if err := stmt.Err(); err != nil {
-		return nil, err
+	if stmt.ColumnType(21) != sqlite3.NULL {
+		updatedUnix := stmt.ColumnInt64(21)
+		p.UpdatedAt = time.Unix(updatedUnix, 0)
 	}
-	return results, nil
+
+	p.EmbeddingModel = stmt.ColumnText(22)
+	p.EmbeddingProvider = stmt.ColumnText(23)
+
+	return p
 }
 
 // Add missing methods to the Storage interface and implementation
 
 // ListPrompts retrieves a paginated list of prompts
 func (s *Storage) ListPrompts(ctx context.Context, limit, offset int) ([]models.Prompt, error) {
-	s.logger.WithFields(logrus.Fields{
+	ctx, span := s.startSpan(ctx, "ListPrompts")
+	defer span.End()
+
+	s.loggerFor(ctx).WithFields(logrus.Fields{
 		"limit":  limit,
 		"offset": offset,
 	}).Debug("Listing prompts")
@@ -719,9 +968,46 @@ func (s *Storage) ListPrompts(ctx context.Context, limit, offset int) ([]models.
 	return result, nil
 }
 
+// StreamPrompts walks a paginated list of prompts row by row, invoking fn for
+// each one as it is read. Unlike ListPrompts it never holds the full page in
+// memory, which keeps large list responses cheap to serve.
+func (s *Storage) StreamPrompts(ctx context.Context, limit, offset int, fn func(*models.Prompt) error) error {
+	s.loggerFor(ctx).WithFields(logrus.Fields{
+		"limit":  limit,
+		"offset": offset,
+	}).Debug("Streaming prompts")
+
+	if s.db == nil {
+		return fmt.Errorf("database connection not initialized")
+	}
+
+	query := strings.Replace(s.baseSelectQuery(), ";", " ORDER BY created_at DESC LIMIT ? OFFSET ?;", 1)
+	stmt, _, err := s.db.Prepare(query)
+	if err != nil {
+		return fmt.Errorf("failed to prepare stream prompts query: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	_ = stmt.BindInt(1, limit)
+	_ = stmt.BindInt(2, offset)
+
+	for stmt.Step() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(s.scanPrompt(stmt)); err != nil {
+			return err
+		}
+	}
+	if err := stmt.Err(); err != nil {
+		return fmt.Errorf("failed to stream prompts: %w", err)
+	}
+	return nil
+}
+
 // GetPrompt retrieves a single prompt by ID
 func (s *Storage) GetPrompt(ctx context.Context, id string) (*models.Prompt, error) {
-	s.logger.WithField("prompt_id", id).Debug("Getting prompt by ID")
+	s.loggerFor(ctx).WithField("prompt_id", id).Debug("Getting prompt by ID")
 
 	// Parse UUID string
 	promptID, err := uuid.Parse(id)
@@ -735,7 +1021,10 @@ func (s *Storage) GetPrompt(ctx context.Context, id string) (*models.Prompt, err
 
 // SearchPrompts performs text-based search on prompts
 func (s *Storage) SearchPrompts(ctx context.Context, query string, limit int) ([]models.Prompt, error) {
-	s.logger.WithFields(logrus.Fields{
+	ctx, span := s.startSpan(ctx, "SearchPrompts")
+	defer span.End()
+
+	s.loggerFor(ctx).WithFields(logrus.Fields{
 		"query": query,
 		"limit": limit,
 	}).Debug("Searching prompts")
@@ -781,7 +1070,7 @@ func (s *Storage) SearchPrompts(ctx context.Context, query string, limit int) ([
 
 // SearchPromptsWithVector performs semantic search using embeddings
 func (s *Storage) SearchPromptsWithVector(ctx context.Context, embedding []float32, limit int, threshold float64) ([]models.Prompt, error) {
-	s.logger.WithFields(logrus.Fields{
+	s.loggerFor(ctx).WithFields(logrus.Fields{
 		"embedding_dims": len(embedding),
 		"limit":          limit,
 		"threshold":      threshold,
@@ -793,7 +1082,7 @@ func (s *Storage) SearchPromptsWithVector(ctx context.Context, embedding []float
 
 // GetPromptsByTags retrieves prompts with any of the specified tags
 func (s *Storage) GetPromptsByTags(ctx context.Context, tags []string, limit int) ([]models.Prompt, error) {
-	s.logger.WithFields(logrus.Fields{
+	s.loggerFor(ctx).WithFields(logrus.Fields{
 		"tags":  tags,
 		"limit": limit,
 	}).Debug("Getting prompts by tags")
@@ -840,7 +1129,7 @@ func (s *Storage) GetPromptsByTags(ctx context.Context, tags []string, limit int
 
 // GetPromptsByPhase retrieves prompts from a specific alchemical phase
 func (s *Storage) GetPromptsByPhase(ctx context.Context, phase models.Phase, limit int) ([]models.Prompt, error) {
-	s.logger.WithFields(logrus.Fields{
+	s.loggerFor(ctx).WithFields(logrus.Fields{
 		"phase": phase,
 		"limit": limit,
 	}).Debug("Getting prompts by phase")
@@ -870,7 +1159,7 @@ func (s *Storage) GetPromptsByPhase(ctx context.Context, phase models.Phase, lim
 
 // GetPromptsByProvider retrieves prompts generated by a specific provider
 func (s *Storage) GetPromptsByProvider(ctx context.Context, provider string, limit int) ([]models.Prompt, error) {
-	s.logger.WithFields(logrus.Fields{
+	s.loggerFor(ctx).WithFields(logrus.Fields{
 		"provider": provider,
 		"limit":    limit,
 	}).Debug("Getting prompts by provider")
@@ -898,9 +1187,27 @@ func (s *Storage) GetPromptsByProvider(ctx context.Context, provider string, lim
 	return result, nil
 }
 
-// DeletePrompt removes a prompt from storage
+// promptDependents are deleted along with a prompt so they don't outlive it.
+// Each statement binds the prompt ID as ?1.
+var promptDependents = []string{
+	"DELETE FROM prompt_relationships WHERE source_prompt_id = ?1 OR target_prompt_id = ?1",
+	"DELETE FROM user_interactions WHERE prompt_id = ?1",
+	"DELETE FROM prompt_feedback WHERE prompt_id = ?1",
+	"DELETE FROM prompt_details WHERE prompt_id = ?1",
+	"DELETE FROM prompt_inputs WHERE prompt_id = ?1",
+	"DELETE FROM prompt_versions WHERE prompt_id = ?1",
+	// Children outlive their parent, detached from it
+	"UPDATE prompts SET parent_id = NULL WHERE parent_id = ?1",
+}
+
+// DeletePrompt removes a prompt along with its details, its earlier versions
+// and the relationships, interactions, feedback and enhancement history that reference
+// it, all in one transaction. It returns ErrPromptNotFound if there is no such prompt.
 func (s *Storage) DeletePrompt(ctx context.Context, id string) error {
-	s.logger.WithField("prompt_id", id).Debug("Deleting prompt")
+	ctx, span := s.startSpan(ctx, "DeletePrompt")
+	defer span.End()
+
+	s.loggerFor(ctx).WithField("prompt_id", id).Debug("Deleting prompt")
 
 	// Parse UUID string
 	promptID, err := uuid.Parse(id)
@@ -908,47 +1215,176 @@ func (s *Storage) DeletePrompt(ctx context.Context, id string) error {
 		return fmt.Errorf("invalid prompt ID format: %w", err)
 	}
 
-	// Delete from SQLite
-	stmt, _, err := s.db.Prepare("DELETE FROM prompts WHERE id = ?")
+	if err := s.deletePromptRows(promptID); err != nil {
+		return err
+	}
+
+	// Also delete from vector storage if it exists
+	if collection := s.getOrCreateCollection(); collection != nil {
+		if err := collection.Delete(ctx, nil, nil, promptID.String()); err != nil {
+			s.loggerFor(ctx).WithError(err).WithField("prompt_id", promptID).Warn("Failed to delete prompt embedding")
+		}
+	}
+
+	s.loggerFor(ctx).WithField("prompt_id", promptID).Info("Successfully deleted prompt")
+	s.events.Publish(ctx, Event{Type: EventPromptDeleted, PromptID: promptID})
+	return nil
+}
+
+// deletePromptRows deletes a prompt and its dependent rows in a transaction
+func (s *Storage) deletePromptRows(promptID uuid.UUID) (err error) {
+	tx, err := s.db.BeginImmediate()
+	if err != nil {
+		return fmt.Errorf("failed to begin delete prompt transaction: %w", err)
+	}
+	defer tx.End(&err)
+
+	statements := append([]string{}, promptDependents...)
+	// enhancement_history only exists in databases created from the older
+	// documented schema
+	hasHistory, err := s.tableExists("enhancement_history")
 	if err != nil {
-		return fmt.Errorf("failed to prepare delete prompt statement: %w", err)
+		return err
+	}
+	if hasHistory {
+		statements = append(statements, "DELETE FROM enhancement_history WHERE prompt_id = ?1")
+	}
+	for _, query := range statements {
+		if err := s.execWithID(query, promptID); err != nil {
+			return fmt.Errorf("failed to delete prompt dependents: %w", err)
+		}
+	}
+
+	if err := s.execWithID("DELETE FROM prompts WHERE id = ?1", promptID); err != nil {
+		return fmt.Errorf("failed to execute delete prompt statement: %w", err)
+	}
+	if s.db.Changes() == 0 {
+		return fmt.Errorf("%w: %s", ErrPromptNotFound, promptID)
+	}
+	return nil
+}
+
+// execWithID runs a statement whose only parameter is a prompt ID
+func (s *Storage) execWithID(query string, id uuid.UUID) error {
+	stmt, _, err := s.db.Prepare(query)
+	if err != nil {
+		return err
 	}
 	defer func() { _ = stmt.Close() }()
 
-	_ = stmt.BindText(1, promptID.String())
+	_ = stmt.BindText(1, id.String())
+	stmt.Step()
+	return stmt.Err()
+}
 
-	if !stmt.Step() {
-		if err := stmt.Err(); err != nil {
-			return fmt.Errorf("failed to execute delete prompt statement: %w", err)
+// tableExists reports whether the database has a table with the given name
+func (s *Storage) tableExists(name string) (bool, error) {
+	stmt, _, err := s.db.Prepare("SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = ?")
+	if err != nil {
+		return false, fmt.Errorf("failed to prepare table lookup: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	_ = stmt.BindText(1, name)
+	if stmt.Step() {
+		return true, nil
+	}
+	return false, stmt.Err()
+}
+
+// DeletePromptsBefore removes every prompt created before cutoff, with the
+// rows depending on it, and returns how many were deleted. A single cleanup
+// event is published for the batch.
+func (s *Storage) DeletePromptsBefore(ctx context.Context, cutoff time.Time) (int, error) {
+	stmt, _, err := s.db.Prepare("SELECT id FROM prompts WHERE created_at < ?")
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare cleanup statement: %w", err)
+	}
+	_ = stmt.BindInt64(1, cutoff.Unix())
+
+	var ids []uuid.UUID
+	for stmt.Step() {
+		id, err := uuid.Parse(stmt.ColumnText(0))
+		if err != nil {
+			continue
 		}
+		ids = append(ids, id)
+	}
+	err = stmt.Err()
+	_ = stmt.Close()
+	if err != nil {
+		return 0, fmt.Errorf("failed to execute cleanup statement: %w", err)
 	}
 
-	// Also delete from vector storage if it exists
+	// Each prompt goes with its dependent rows, as DeletePrompt does
+	deleted := 0
 	collection := s.getOrCreateCollection()
-	if collection != nil {
-		// chromem-go doesn't have a direct delete method, but we can work around this
-		// by not including it in future queries
-		s.logger.WithField("prompt_id", promptID).Debug("Note: Vector deletion not supported in chromem-go")
+	for _, id := range ids {
+		if err := s.deletePromptRows(id); err != nil {
+			if errors.Is(err, ErrPromptNotFound) {
+				continue
+			}
+			return deleted, err
+		}
+		deleted++
+		if collection != nil {
+			if err := collection.Delete(ctx, nil, nil, id.String()); err != nil {
+				s.loggerFor(ctx).WithError(err).WithField("prompt_id", id).Warn("Failed to delete prompt embedding")
+			}
+		}
 	}
 
-	s.logger.WithField("prompt_id", promptID).Info("Successfully deleted prompt")
-	return nil
+	s.loggerFor(ctx).WithFields(logrus.Fields{
+		"cutoff":  cutoff,
+		"deleted": deleted,
+	}).Info("Cleaned up old prompts")
+	s.events.Publish(ctx, Event{Type: EventPromptsCleanedUp, Count: deleted})
+	return deleted, nil
 }
 
-// UpdatePrompt updates an existing prompt
+// UpdatePrompt updates an existing prompt. The content, tags and generation
+// settings it replaces are kept as the prompt's next version, in the same
+// transaction as the update, so neither is kept without the other.
 func (s *Storage) UpdatePrompt(ctx context.Context, prompt *models.Prompt) error {
-	s.logger.WithField("prompt_id", prompt.ID).Debug("Updating prompt")
+	ctx, span := s.startSpan(ctx, "UpdatePrompt")
+	defer span.End()
 
-	prompt.UpdatedAt = time.Now()
+	s.loggerFor(ctx).WithField("prompt_id", prompt.ID).Debug("Updating prompt")
+
+	tx, err := s.db.BeginImmediate()
+	if err != nil {
+		return fmt.Errorf("failed to begin update prompt transaction: %w", err)
+	}
+	eventType, err := s.updatePrompt(ctx, prompt)
+	tx.End(&err)
+	if err != nil {
+		return err
+	}
+
+	s.publishPrompt(ctx, eventType, prompt)
+	return nil
+}
+
+// updatePrompt snapshots the stored prompt as a version, then saves prompt
+// over it. It must run in a transaction.
+func (s *Storage) updatePrompt(ctx context.Context, prompt *models.Prompt) (EventType, error) {
+	previous, err := s.GetPromptByID(ctx, prompt.ID)
+	if err != nil && !errors.Is(err, ErrPromptNotFound) {
+		return "", err
+	}
+	if previous != nil {
+		if err := s.savePromptVersion(ctx, previous); err != nil {
+			return "", err
+		}
+	}
 
-	// Use the existing SavePrompt method which handles both insert and update
-	// It uses ON CONFLICT to update existing records
-	return s.SavePrompt(ctx, prompt)
+	// savePrompt upserts, so a prompt that isn't stored yet is created
+	return s.savePrompt(ctx, prompt)
 }
 
 // GetPromptsCount returns the total number of prompts
 func (s *Storage) GetPromptsCount(ctx context.Context) (int, error) {
-	s.logger.Debug("Getting prompts count")
+	s.loggerFor(ctx).Debug("Getting prompts count")
 
 	stmt, _, err := s.db.Prepare("SELECT COUNT(*) FROM prompts")
 	if err != nil {
@@ -958,7 +1394,7 @@ func (s *Storage) GetPromptsCount(ctx context.Context) (int, error) {
 
 	if stmt.Step() {
 		count := stmt.ColumnInt(0)
-		s.logger.WithField("count", count).Debug("Retrieved prompts count")
+		s.loggerFor(ctx).WithField("count", count).Debug("Retrieved prompts count")
 		return count, nil
 	}
 
@@ -971,7 +1407,7 @@ func (s *Storage) GetPromptsCount(ctx context.Context) (int, error) {
 
 // GetPopularPrompts returns the most frequently accessed prompts
 func (s *Storage) GetPopularPrompts(ctx context.Context, limit int) ([]models.Prompt, error) {
-	s.logger.WithField("limit", limit).Debug("Getting popular prompts")
+	s.loggerFor(ctx).WithField("limit", limit).Debug("Getting popular prompts")
 
 	// Order by usage_count and generation_count to find most popular prompts
 	query := strings.Replace(s.baseSelectQuery(), ";", " ORDER BY usage_count DESC, generation_count DESC, relevance_score DESC LIMIT ?;", 1)
@@ -998,7 +1434,7 @@ func (s *Storage) GetPopularPrompts(ctx context.Context, limit int) ([]models.Pr
 
 // GetRecentPrompts returns the most recently created prompts
 func (s *Storage) GetRecentPrompts(ctx context.Context, limit int) ([]models.Prompt, error) {
-	s.logger.WithField("limit", limit).Debug("Getting recent prompts")
+	s.loggerFor(ctx).WithField("limit", limit).Debug("Getting recent prompts")
 
 	// Order by created_at to find most recent prompts
 	query := strings.Replace(s.baseSelectQuery(), ";", " ORDER BY created_at DESC LIMIT ?;", 1)
@@ -0,0 +1,145 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeletePromptCascades(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	store, err := NewStorage(t.TempDir(), logger)
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	ctx := context.Background()
+	sessionID := uuid.New()
+	parent := &models.Prompt{Content: "Summarize the report", Phase: models.PhasePrimaMaterial, SessionID: sessionID}
+	require.NoError(t, store.SavePrompt(ctx, parent))
+	child := &models.Prompt{Content: "Summarize the report briefly", Phase: models.PhaseSolutio, SessionID: sessionID, ParentID: &parent.ID}
+	require.NoError(t, store.SavePrompt(ctx, child))
+	require.NoError(t, store.SaveInteraction(ctx, &models.UserInteraction{PromptID: parent.ID, SessionID: sessionID, Action: "chosen"}))
+
+	// Databases created from the older schema also carry enhancement history
+	require.NoError(t, store.db.Exec(`CREATE TABLE enhancement_history (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		prompt_id TEXT NOT NULL,
+		updated_content TEXT NOT NULL
+	)`))
+	require.NoError(t, store.db.Exec("INSERT INTO enhancement_history (prompt_id, updated_content) VALUES ('"+parent.ID.String()+"', 'v2')"))
+
+	count := func(query string) int {
+		stmt, _, err := store.db.Prepare(query)
+		require.NoError(t, err)
+		defer func() { _ = stmt.Close() }()
+		_ = stmt.BindText(1, parent.ID.String())
+		require.True(t, stmt.Step())
+		return stmt.ColumnInt(0)
+	}
+	require.Equal(t, 1, count("SELECT COUNT(*) FROM prompt_relationships WHERE source_prompt_id = ?1 OR target_prompt_id = ?1"))
+
+	require.NoError(t, store.DeletePrompt(ctx, parent.ID.String()))
+
+	assert.Equal(t, 0, count("SELECT COUNT(*) FROM prompts WHERE id = ?"))
+	assert.Equal(t, 0, count("SELECT COUNT(*) FROM prompt_relationships WHERE source_prompt_id = ?1 OR target_prompt_id = ?1"))
+	assert.Equal(t, 0, count("SELECT COUNT(*) FROM user_interactions WHERE prompt_id = ?"))
+	assert.Equal(t, 0, count("SELECT COUNT(*) FROM enhancement_history WHERE prompt_id = ?"))
+
+	orphan, err := store.GetPrompt(ctx, child.ID.String())
+	require.NoError(t, err, "other prompts are untouched")
+	assert.Nil(t, orphan.ParentID, "children are detached from a deleted parent")
+
+	assert.ErrorIs(t, store.DeletePrompt(ctx, parent.ID.String()), ErrPromptNotFound)
+	_, err = store.GetPrompt(ctx, parent.ID.String())
+	assert.ErrorIs(t, err, ErrPromptNotFound)
+}
+
+func TestDeletePromptsBefore(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	store, err := NewStorage(t.TempDir(), logger)
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	ctx := context.Background()
+	old := time.Now().Add(-48 * time.Hour)
+	parent := &models.Prompt{Content: "Old parent", Phase: models.PhasePrimaMaterial, CreatedAt: old}
+	require.NoError(t, store.SavePrompt(ctx, parent))
+	stale := &models.Prompt{Content: "Old child", Phase: models.PhaseSolutio, ParentID: &parent.ID, CreatedAt: old}
+	require.NoError(t, store.SavePrompt(ctx, stale))
+	recent := &models.Prompt{Content: "Recent child", Phase: models.PhaseSolutio, ParentID: &parent.ID}
+	require.NoError(t, store.SavePrompt(ctx, recent))
+	require.NoError(t, store.SaveInteraction(ctx, &models.UserInteraction{PromptID: parent.ID, SessionID: uuid.New(), Action: "chosen"}))
+
+	deleted, err := store.DeletePromptsBefore(ctx, time.Now().Add(-24*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+
+	_, err = store.GetPrompt(ctx, parent.ID.String())
+	assert.ErrorIs(t, err, ErrPromptNotFound)
+	_, err = store.GetPrompt(ctx, stale.ID.String())
+	assert.ErrorIs(t, err, ErrPromptNotFound)
+	kept, err := store.GetPrompt(ctx, recent.ID.String())
+	require.NoError(t, err)
+	assert.Nil(t, kept.ParentID)
+
+	for _, query := range []string{
+		"SELECT COUNT(*) FROM user_interactions WHERE prompt_id = ?1",
+		"SELECT COUNT(*) FROM prompt_relationships WHERE source_prompt_id = ?1 OR target_prompt_id = ?1",
+	} {
+		stmt, _, err := store.db.Prepare(query)
+		require.NoError(t, err)
+		_ = stmt.BindText(1, parent.ID.String())
+		require.True(t, stmt.Step())
+		assert.Equal(t, 0, stmt.ColumnInt(0), query)
+		_ = stmt.Close()
+	}
+}
+
+func TestPromptInputTemplate(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	store, err := NewStorage(t.TempDir(), logger)
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	ctx := context.Background()
+	variables := map[string]string{"language": "Go"}
+	templated := &models.Prompt{
+		Content:        "Write idiomatic Go",
+		Phase:          models.PhasePrimaMaterial,
+		OriginalInput:  "Write Go",
+		InputTemplate:  "Write {{language}}",
+		InputVariables: variables,
+	}
+	require.NoError(t, store.SavePrompt(ctx, templated))
+	plain := &models.Prompt{Content: "Write idiomatic Rust", Phase: models.PhasePrimaMaterial, OriginalInput: "Write Rust"}
+	require.NoError(t, store.SavePrompt(ctx, plain))
+
+	loaded, err := store.GetPrompt(ctx, templated.ID.String())
+	require.NoError(t, err)
+	require.NoError(t, store.LoadPromptDetails(ctx, loaded))
+	assert.Equal(t, "Write Go", loaded.OriginalInput)
+	assert.Equal(t, "Write {{language}}", loaded.InputTemplate)
+	assert.Equal(t, variables, loaded.InputVariables)
+
+	loaded, err = store.GetPrompt(ctx, plain.ID.String())
+	require.NoError(t, err)
+	require.NoError(t, store.LoadPromptDetails(ctx, loaded))
+	assert.Empty(t, loaded.InputTemplate)
+	assert.Nil(t, loaded.InputVariables)
+
+	require.NoError(t, store.DeletePrompt(ctx, templated.ID.String()))
+	stmt, _, err := store.db.Prepare("SELECT COUNT(*) FROM prompt_inputs")
+	require.NoError(t, err)
+	defer func() { _ = stmt.Close() }()
+	require.True(t, stmt.Step())
+	assert.Equal(t, 0, stmt.ColumnInt(0))
+}
@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+)
+
+// TagStat is how often a tag is used and how well the prompts carrying it
+// score
+type TagStat struct {
+	Tag          string  `json:"tag"`
+	PromptCount  int     `json:"prompt_count"`
+	AverageScore float64 `json:"average_score"` // mean relevance score of the prompts
+}
+
+// TagStats counts the prompts carrying each tag, most used first, leaving
+// out tags on fewer than minCount prompts. Tags are stored as a JSON array
+// per prompt, so they are expanded with json_each; a tag repeated within one
+// prompt counts once and malformed tag columns are skipped.
+func (s *Storage) TagStats(ctx context.Context, minCount int) ([]TagStat, error) {
+	_, span := s.startSpan(ctx, "TagStats")
+	defer span.End()
+
+	stmt, _, err := s.db.Prepare(`
+		SELECT tag, COUNT(*), COALESCE(AVG(score), 0)
+		FROM (
+			SELECT DISTINCT p.id, t.value AS tag, p.relevance_score AS score
+			FROM prompts p, json_each(CASE WHEN json_valid(p.tags) THEN p.tags END) t
+			WHERE t.type = 'text' AND t.value <> ''
+		)
+		GROUP BY tag
+		HAVING COUNT(*) >= ?
+		ORDER BY 2 DESC, 1`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare tag statistics query: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+	_ = stmt.BindInt(1, minCount)
+
+	stats := []TagStat{}
+	for stmt.Step() {
+		stats = append(stats, TagStat{
+			Tag:          stmt.ColumnText(0),
+			PromptCount:  stmt.ColumnInt(1),
+			AverageScore: stmt.ColumnFloat(2),
+		})
+	}
+	if err := stmt.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read tag statistics: %w", err)
+	}
+	return stats, nil
+}
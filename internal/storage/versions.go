@@ -0,0 +1,144 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrVersionNotFound is returned when a prompt has no version with the
+// requested number
+var ErrVersionNotFound = errors.New("prompt version not found")
+
+// savePromptVersion records p as its prompt's next version
+func (s *Storage) savePromptVersion(ctx context.Context, p *models.Prompt) error {
+	tagsJSON, err := json.Marshal(p.Tags)
+	if err != nil {
+		return fmt.Errorf("failed to marshal tags: %w", err)
+	}
+
+	stmt, _, err := s.db.Prepare(`
+		INSERT INTO prompt_versions (
+			prompt_id, version, content, tags, provider, model, temperature, max_tokens, created_at
+		) SELECT ?1, COALESCE(MAX(version), 0) + 1, ?2, ?3, ?4, ?5, ?6, ?7, ?8
+		FROM prompt_versions WHERE prompt_id = ?1`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare save prompt version statement: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	_ = stmt.BindText(1, p.ID.String())
+	_ = stmt.BindText(2, p.Content)
+	_ = stmt.BindText(3, string(tagsJSON))
+	_ = stmt.BindText(4, p.Provider)
+	_ = stmt.BindText(5, p.Model)
+	_ = stmt.BindFloat(6, p.Temperature)
+	_ = stmt.BindInt(7, p.MaxTokens)
+	_ = stmt.BindInt64(8, time.Now().Unix())
+
+	stmt.Step()
+	if err := stmt.Err(); err != nil {
+		return fmt.Errorf("failed to execute save prompt version statement: %w", err)
+	}
+	return nil
+}
+
+// GetPromptVersions returns the earlier versions of a prompt, oldest first.
+// It returns ErrPromptNotFound if there is no such prompt.
+func (s *Storage) GetPromptVersions(ctx context.Context, id uuid.UUID) ([]models.PromptVersion, error) {
+	if _, err := s.GetPromptByID(ctx, id); err != nil {
+		return nil, err
+	}
+
+	stmt, _, err := s.db.Prepare(`
+		SELECT version, content, tags, provider, model, temperature, max_tokens, created_at
+		FROM prompt_versions WHERE prompt_id = ? ORDER BY version ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare prompt versions query: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	_ = stmt.BindText(1, id.String())
+
+	versions := []models.PromptVersion{}
+	for stmt.Step() {
+		v := models.PromptVersion{
+			PromptID:    id,
+			Version:     stmt.ColumnInt(0),
+			Content:     stmt.ColumnText(1),
+			Provider:    stmt.ColumnText(3),
+			Model:       stmt.ColumnText(4),
+			Temperature: stmt.ColumnFloat(5),
+			MaxTokens:   stmt.ColumnInt(6),
+			CreatedAt:   time.Unix(stmt.ColumnInt64(7), 0),
+		}
+		_ = json.Unmarshal([]byte(stmt.ColumnText(2)), &v.Tags)
+		versions = append(versions, v)
+	}
+	if err := stmt.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read prompt versions: %w", err)
+	}
+	return versions, nil
+}
+
+// RestorePromptVersion makes an earlier version of a prompt current again.
+// The content it replaces becomes a new version, so no history is lost.
+func (s *Storage) RestorePromptVersion(ctx context.Context, id uuid.UUID, version int) (*models.Prompt, error) {
+	tx, err := s.db.BeginImmediate()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin restore prompt version transaction: %w", err)
+	}
+	prompt, eventType, err := s.restorePromptVersion(ctx, id, version)
+	tx.End(&err)
+	if err != nil {
+		return nil, err
+	}
+	s.publishPrompt(ctx, eventType, prompt)
+
+	s.loggerFor(ctx).WithFields(logrus.Fields{
+		"prompt_id": id,
+		"version":   version,
+	}).Info("Restored prompt version")
+	return prompt, nil
+}
+
+// restorePromptVersion does the work of RestorePromptVersion. It must run
+// in a transaction.
+func (s *Storage) restorePromptVersion(ctx context.Context, id uuid.UUID, version int) (*models.Prompt, EventType, error) {
+	versions, err := s.GetPromptVersions(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+	var restored *models.PromptVersion
+	for i := range versions {
+		if versions[i].Version == version {
+			restored = &versions[i]
+			break
+		}
+	}
+	if restored == nil {
+		return nil, "", fmt.Errorf("%w: %s version %d", ErrVersionNotFound, id, version)
+	}
+
+	prompt, err := s.GetPromptByID(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+	prompt.Content = restored.Content
+	prompt.Tags = restored.Tags
+	prompt.Provider = restored.Provider
+	prompt.Model = restored.Model
+	prompt.Temperature = restored.Temperature
+	prompt.MaxTokens = restored.MaxTokens
+	eventType, err := s.updatePrompt(ctx, prompt)
+	if err != nil {
+		return nil, "", err
+	}
+	return prompt, eventType, nil
+}
@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPromptVersions(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	store, err := NewStorage(t.TempDir(), logger)
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	ctx := context.Background()
+	prompt := &models.Prompt{Content: "first draft", Phase: models.PhaseSolutio, Provider: "openai", Tags: []string{"draft"}, Temperature: 0.7}
+	require.NoError(t, store.SavePrompt(ctx, prompt))
+
+	versions, err := store.GetPromptVersions(ctx, prompt.ID)
+	require.NoError(t, err)
+	assert.Empty(t, versions, "saving a new prompt is not an edit")
+
+	for _, content := range []string{"second draft", "third draft"} {
+		prompt.Content = content
+		prompt.Tags = append(prompt.Tags, content)
+		require.NoError(t, store.UpdatePrompt(ctx, prompt))
+	}
+
+	versions, err = store.GetPromptVersions(ctx, prompt.ID)
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	assert.Equal(t, 1, versions[0].Version)
+	assert.Equal(t, "first draft", versions[0].Content)
+	assert.Equal(t, []string{"draft"}, versions[0].Tags)
+	assert.Equal(t, 0.7, versions[0].Temperature)
+	assert.Equal(t, 2, versions[1].Version)
+	assert.Equal(t, "second draft", versions[1].Content)
+
+	t.Run("restore", func(t *testing.T) {
+		restored, err := store.RestorePromptVersion(ctx, prompt.ID, 1)
+		require.NoError(t, err)
+		assert.Equal(t, "first draft", restored.Content)
+
+		current, err := store.GetPromptByID(ctx, prompt.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "first draft", current.Content)
+		assert.Equal(t, []string{"draft"}, current.Tags)
+
+		versions, err := store.GetPromptVersions(ctx, prompt.ID)
+		require.NoError(t, err)
+		require.Len(t, versions, 3, "the replaced content becomes a new version")
+		assert.Equal(t, 3, versions[2].Version)
+		assert.Equal(t, "third draft", versions[2].Content)
+	})
+
+	t.Run("unknown version", func(t *testing.T) {
+		_, err := store.RestorePromptVersion(ctx, prompt.ID, 9)
+		assert.ErrorIs(t, err, ErrVersionNotFound)
+	})
+
+	t.Run("deleted with the prompt", func(t *testing.T) {
+		require.NoError(t, store.DeletePrompt(ctx, prompt.ID.String()))
+		_, err := store.GetPromptVersions(ctx, prompt.ID)
+		assert.ErrorIs(t, err, ErrPromptNotFound)
+
+		stmt, _, err := store.db.Prepare("SELECT COUNT(*) FROM prompt_versions")
+		require.NoError(t, err)
+		defer func() { _ = stmt.Close() }()
+		require.True(t, stmt.Step())
+		assert.Zero(t, stmt.ColumnInt(0))
+	})
+}
+
+func TestUpdatePromptIsAtomic(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	store, err := NewStorage(t.TempDir(), logger)
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	ctx := context.Background()
+	prompt := &models.Prompt{Content: "first draft", Phase: models.PhaseSolutio, Provider: "openai"}
+	require.NoError(t, store.SavePrompt(ctx, prompt))
+	require.NoError(t, store.UpdatePrompt(ctx, &models.Prompt{ID: prompt.ID, Content: "second draft", Phase: models.PhaseSolutio}))
+
+	current := func() string {
+		stored, err := store.GetPromptByID(ctx, prompt.ID)
+		require.NoError(t, err)
+		return stored.Content
+	}
+	versionCount := func() int {
+		versions, err := store.GetPromptVersions(ctx, prompt.ID)
+		require.NoError(t, err)
+		return len(versions)
+	}
+
+	t.Run("failed version insert leaves the prompt as it was", func(t *testing.T) {
+		require.NoError(t, store.db.Exec(`CREATE TRIGGER fail_versions BEFORE INSERT ON prompt_versions
+			BEGIN SELECT RAISE(ABORT, 'version insert failed'); END`))
+		defer func() { require.NoError(t, store.db.Exec("DROP TRIGGER fail_versions")) }()
+
+		err := store.UpdatePrompt(ctx, &models.Prompt{ID: prompt.ID, Content: "third draft", Phase: models.PhaseSolutio})
+		assert.ErrorContains(t, err, "version insert failed")
+		assert.Equal(t, "second draft", current())
+		assert.Equal(t, 1, versionCount())
+
+		_, err = store.RestorePromptVersion(ctx, prompt.ID, 1)
+		assert.ErrorContains(t, err, "version insert failed")
+		assert.Equal(t, "second draft", current())
+	})
+
+	t.Run("failed update leaves no version behind", func(t *testing.T) {
+		store.contentLimit = contentLimit{maxBytes: 5, mode: OversizeReject}
+		defer func() { store.contentLimit = contentLimit{} }()
+
+		err := store.UpdatePrompt(ctx, &models.Prompt{ID: prompt.ID, Content: "a draft too long to keep", Phase: models.PhaseSolutio})
+		assert.ErrorIs(t, err, ErrContentTooLarge)
+		assert.Equal(t, "second draft", current())
+		assert.Equal(t, 1, versionCount())
+	})
+
+	require.NoError(t, store.UpdatePrompt(ctx, &models.Prompt{ID: prompt.ID, Content: "third draft", Phase: models.PhaseSolutio}))
+	assert.Equal(t, "third draft", current())
+	assert.Equal(t, 2, versionCount())
+}
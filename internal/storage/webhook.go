@@ -0,0 +1,145 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultWebhookTimeout  = 10 * time.Second
+	defaultWebhookAttempts = 3
+
+	// WebhookSignatureHeader carries the hex HMAC-SHA256 of the request body
+	// when the webhook has a secret configured
+	WebhookSignatureHeader = "X-Prompt-Alchemy-Signature"
+	// WebhookEventHeader carries the event type so receivers can route
+	// without decoding the body
+	WebhookEventHeader = "X-Prompt-Alchemy-Event"
+)
+
+// WebhookConfig is a single entry of the events.webhooks config list
+type WebhookConfig struct {
+	URL         string        `mapstructure:"url"`
+	Events      []string      `mapstructure:"events"` // empty subscribes to every event
+	Secret      string        `mapstructure:"secret"`
+	Timeout     time.Duration `mapstructure:"timeout"`
+	MaxAttempts int           `mapstructure:"max_attempts"`
+}
+
+// WebhookSubscriber POSTs storage events as JSON to a configured URL.
+// Deliveries run in the background and are retried with exponential backoff
+// on network errors and 5xx/429 responses.
+type WebhookSubscriber struct {
+	config WebhookConfig
+	events map[EventType]bool
+	client *http.Client
+	logger *logrus.Logger
+	wg     sync.WaitGroup
+}
+
+// NewWebhookSubscriber creates a subscriber for config, filling in defaults
+func NewWebhookSubscriber(config WebhookConfig, logger *logrus.Logger) (*WebhookSubscriber, error) {
+	if config.URL == "" {
+		return nil, fmt.Errorf("webhook url is required")
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultWebhookTimeout
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = defaultWebhookAttempts
+	}
+
+	var events map[EventType]bool
+	if len(config.Events) > 0 {
+		events = make(map[EventType]bool, len(config.Events))
+		for _, e := range config.Events {
+			events[EventType(e)] = true
+		}
+	}
+
+	return &WebhookSubscriber{
+		config: config,
+		events: events,
+		client: &http.Client{Timeout: config.Timeout},
+		logger: logger,
+	}, nil
+}
+
+// HandleEvent queues delivery of event if the webhook subscribes to its type
+func (w *WebhookSubscriber) HandleEvent(_ context.Context, event Event) {
+	if w.events != nil && !w.events[event.Type] {
+		return
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		w.logger.WithError(err).WithField("event", event.Type).Error("Failed to encode webhook payload")
+		return
+	}
+
+	// Delivery outlives the mutation's context, which is often a request
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		if err := w.deliver(event, body); err != nil {
+			w.logger.WithError(err).WithFields(logrus.Fields{
+				"url":      w.config.URL,
+				"event":    event.Type,
+				"event_id": event.ID,
+			}).Warn("Webhook delivery failed")
+		}
+	}()
+}
+
+// Close waits for in-flight deliveries to finish
+func (w *WebhookSubscriber) Close() {
+	w.wg.Wait()
+}
+
+func (w *WebhookSubscriber) deliver(event Event, body []byte) error {
+	b := backoff.WithMaxRetries(backoff.NewExponentialBackOff(), uint64(w.config.MaxAttempts-1))
+
+	return backoff.Retry(func() error {
+		req, err := http.NewRequest(http.MethodPost, w.config.URL, bytes.NewReader(body))
+		if err != nil {
+			return backoff.Permanent(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(WebhookEventHeader, string(event.Type))
+		if w.config.Secret != "" {
+			req.Header.Set(WebhookSignatureHeader, signWebhookBody(w.config.Secret, body))
+		}
+
+		resp, err := w.client.Do(req)
+		if err != nil {
+			return err
+		}
+		_ = resp.Body.Close()
+
+		switch {
+		case resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests:
+			return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+		case resp.StatusCode >= 400:
+			return backoff.Permanent(fmt.Errorf("webhook returned status %d", resp.StatusCode))
+		}
+		return nil
+	}, b)
+}
+
+// signWebhookBody returns the hex HMAC-SHA256 of body keyed by secret
+func signWebhookBody(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
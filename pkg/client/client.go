@@ -58,6 +58,7 @@ type GenerateRequest struct {
 	Tags        []string `json:"tags"`
 	Persona     string   `json:"persona"`
 	TargetModel string   `json:"target_model"`
+	SavePhases  []string `json:"save_phases,omitempty"`
 }
 
 // GenerateResponse represents the response from the generate API
@@ -35,3 +35,57 @@ type LearningFeedback struct {
 	SuggestedImprovement string                 `json:"suggested_improvement,omitempty"`
 	Context              map[string]interface{} `json:"context,omitempty"`
 }
+
+// FeedbackSignal is the kind of outcome a user reports for a prompt
+type FeedbackSignal string
+
+// Feedback signals accepted by POST /api/v1/prompts/{id}/feedback
+const (
+	FeedbackThumbsUp         FeedbackSignal = "thumbs_up"
+	FeedbackThumbsDown       FeedbackSignal = "thumbs_down"
+	FeedbackUsedInProduction FeedbackSignal = "used_in_production"
+	FeedbackConversion       FeedbackSignal = "conversion"
+)
+
+// FeedbackSignals lists every valid feedback signal
+var FeedbackSignals = []FeedbackSignal{FeedbackThumbsUp, FeedbackThumbsDown, FeedbackUsedInProduction, FeedbackConversion}
+
+// IsValid reports whether s is a known feedback signal
+func (s FeedbackSignal) IsValid() bool {
+	for _, signal := range FeedbackSignals {
+		if s == signal {
+			return true
+		}
+	}
+	return false
+}
+
+// PromptFeedback is an outcome a user reported for a prompt
+type PromptFeedback struct {
+	ID        uuid.UUID      `json:"id"`
+	PromptID  uuid.UUID      `json:"prompt_id"`
+	Signal    FeedbackSignal `json:"signal"`
+	Score     *float64       `json:"score,omitempty"` // Optional 0-1 strength of the signal
+	Comment   string         `json:"comment,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// Outcome is the feedback as a 0-1 success value: its score when given,
+// otherwise 0 for thumbs_down and 1 for the positive signals
+func (f *PromptFeedback) Outcome() float64 {
+	if f.Score != nil {
+		return *f.Score
+	}
+	if f.Signal == FeedbackThumbsDown {
+		return 0
+	}
+	return 1
+}
+
+// FeedbackSummary aggregates the feedback reported for a prompt
+type FeedbackSummary struct {
+	Total          int                    `json:"total"`
+	Signals        map[FeedbackSignal]int `json:"signals"`         // count per signal
+	AverageOutcome float64                `json:"average_outcome"` // mean Outcome, 0-1
+	LastFeedbackAt *time.Time             `json:"last_feedback_at,omitempty"`
+}
@@ -0,0 +1,67 @@
+package models
+
+import "time"
+
+// BatchInput represents a single batch generation request
+type BatchInput struct {
+	ID          string            `json:"id" csv:"id"`
+	Input       string            `json:"input" csv:"input"`
+	Phases      string            `json:"phases,omitempty" csv:"phases"`
+	Count       int               `json:"count,omitempty" csv:"count"`
+	Temperature float64           `json:"temperature,omitempty" csv:"temperature"`
+	MaxTokens   int               `json:"max_tokens,omitempty" csv:"max_tokens"`
+	Tags        string            `json:"tags,omitempty" csv:"tags"`
+	Provider    string            `json:"provider,omitempty" csv:"provider"`
+	Persona     string            `json:"persona,omitempty" csv:"persona"`
+	Metadata    map[string]string `json:"metadata,omitempty" csv:"-"`
+}
+
+// BatchResult represents the result of a batch generation
+type BatchResult struct {
+	ID        string        `json:"id"`
+	Input     BatchInput    `json:"input"`
+	Success   bool          `json:"success"`
+	Error     string        `json:"error,omitempty"`
+	Prompts   []Prompt      `json:"prompts,omitempty"`
+	Duration  time.Duration `json:"duration"`
+	Timestamp time.Time     `json:"timestamp"`
+}
+
+// BatchSummary provides overall batch operation statistics
+type BatchSummary struct {
+	TotalInputs     int           `json:"total_inputs"`
+	SuccessfulJobs  int           `json:"successful_jobs"`
+	FailedJobs      int           `json:"failed_jobs"`
+	TotalPrompts    int           `json:"total_prompts"`
+	TotalDuration   time.Duration `json:"total_duration"`
+	AverageDuration time.Duration `json:"average_duration"`
+	StartTime       time.Time     `json:"start_time"`
+	EndTime         time.Time     `json:"end_time"`
+}
+
+// SummarizeBatch totals the results of a batch started at startTime
+func SummarizeBatch(results []BatchResult, startTime time.Time) BatchSummary {
+	summary := BatchSummary{
+		TotalInputs: len(results),
+		StartTime:   startTime,
+		EndTime:     time.Now(),
+	}
+
+	var totalDuration time.Duration
+	for _, result := range results {
+		if result.Success {
+			summary.SuccessfulJobs++
+			summary.TotalPrompts += len(result.Prompts)
+		} else {
+			summary.FailedJobs++
+		}
+		totalDuration += result.Duration
+	}
+
+	summary.TotalDuration = summary.EndTime.Sub(startTime)
+	if summary.TotalInputs > 0 {
+		summary.AverageDuration = totalDuration / time.Duration(summary.TotalInputs)
+	}
+
+	return summary
+}
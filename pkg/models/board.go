@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// BoardState is the flow board layout a web UI session last saved, so pan,
+// zoom and node positions survive a page reload
+type BoardState struct {
+	SessionID string         `json:"session_id"`
+	Viewport  Viewport       `json:"viewport"`
+	Nodes     []NodePosition `json:"nodes,omitempty"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+// Viewport is the board's pan offset, zoom level and visible size
+type Viewport struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Zoom   float64 `json:"zoom"`
+	Width  int     `json:"width"`
+	Height int     `json:"height"`
+}
+
+// NodePosition is where the user has moved a board node
+type NodePosition struct {
+	ID string  `json:"id"`
+	X  float64 `json:"x"`
+	Y  float64 `json:"y"`
+}
+
+// NodePosition returns the stored position of the node with the given ID
+func (b *BoardState) NodePosition(id string) (NodePosition, bool) {
+	for _, node := range b.Nodes {
+		if node.ID == id {
+			return node, true
+		}
+	}
+	return NodePosition{}, false
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ContextDocument is a reference document ingested for retrieval: its text
+// is split into chunks, and the chunks most relevant to a generation's
+// input are given to the phases as context
+type ContextDocument struct {
+	ID         uuid.UUID `json:"id"`
+	Title      string    `json:"title,omitempty"`
+	Source     string    `json:"source,omitempty"`
+	Chunks     int       `json:"chunks"`
+	Characters int       `json:"characters"`
+	Dimensions int       `json:"dimensions"` // length of the chunks' embeddings
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ContextChunk is one chunk of a context document. Similarity is set on
+// chunks retrieved for an input.
+type ContextChunk struct {
+	ID            uuid.UUID `json:"id"`
+	DocumentID    uuid.UUID `json:"document_id"`
+	DocumentTitle string    `json:"document_title,omitempty"`
+	Index         int       `json:"index"` // position within the document, from 0
+	Content       string    `json:"content"`
+	Embedding     []float32 `json:"-"`
+	Similarity    float64   `json:"similarity,omitempty"`
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RenderMarkdown renders prompts as a markdown document for pasting into
+// docs: a section per phase, in the order the phases first appear, holding
+// each of its prompts followed by a footnote naming the provider and model
+// that generated it. Prompts of the same phase are separated by a rule.
+func RenderMarkdown(prompts []Prompt) string {
+	var phases []Phase
+	byPhase := make(map[Phase][]Prompt)
+	for _, p := range prompts {
+		if _, ok := byPhase[p.Phase]; !ok {
+			phases = append(phases, p.Phase)
+		}
+		byPhase[p.Phase] = append(byPhase[p.Phase], p)
+	}
+
+	var b strings.Builder
+	b.WriteString("# Generated Prompts\n")
+	if len(prompts) == 0 {
+		b.WriteString("\nNo prompts were generated.\n")
+		return b.String()
+	}
+	for _, phase := range phases {
+		fmt.Fprintf(&b, "\n## %s\n", phaseTitle(phase))
+		for i, p := range byPhase[phase] {
+			if i > 0 {
+				b.WriteString("\n---\n")
+			}
+			fmt.Fprintf(&b, "\n%s\n", strings.TrimSpace(p.Content))
+			if footnote := markdownFootnote(p); footnote != "" {
+				fmt.Fprintf(&b, "\n*%s*\n", footnote)
+			}
+		}
+	}
+	return b.String()
+}
+
+// phaseTitle turns a phase name like "prima-materia" into a heading
+func phaseTitle(phase Phase) string {
+	if phase == "" {
+		return "Prompts"
+	}
+	words := strings.FieldsFunc(string(phase), func(r rune) bool { return r == '-' || r == '_' })
+	for i, word := range words {
+		words[i] = strings.ToUpper(word[:1]) + word[1:]
+	}
+	return strings.Join(words, " ")
+}
+
+// markdownFootnote describes where a prompt came from, leaving out what is
+// unknown
+func markdownFootnote(p Prompt) string {
+	var parts []string
+	if p.Provider != "" {
+		parts = append(parts, "Provider: "+p.Provider)
+	}
+	if p.Model != "" {
+		parts = append(parts, "Model: "+p.Model)
+	}
+	if p.RelevanceScore > 0 {
+		parts = append(parts, fmt.Sprintf("Score: %.2f", p.RelevanceScore))
+	}
+	return strings.Join(parts, " · ")
+}
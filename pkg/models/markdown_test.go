@@ -0,0 +1,54 @@
+package models
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func TestRenderMarkdown(t *testing.T) {
+	prompts := []Prompt{
+		{
+			Phase:          PhasePrimaMaterial,
+			Provider:       "openai",
+			Model:          "gpt-4o-mini",
+			Content:        "You are a senior Go engineer.\n\nWrite a function that parses CSV files.\n",
+			RelevanceScore: 0.82,
+		},
+		{
+			Phase:    PhaseCoagulatio,
+			Provider: "anthropic",
+			Model:    "claude-3-5-sonnet-20241022",
+			Content:  "Write a streaming CSV parser in Go.\n\n```go\nfunc Parse(r io.Reader) ([][]string, error)\n```",
+		},
+		{
+			Phase:          PhaseCoagulatio,
+			Provider:       "ollama",
+			Content:        "Write a CSV parser in Go that reports line numbers.",
+			RelevanceScore: 0.5,
+		},
+		{
+			Phase:   "code-review",
+			Content: "Review the parser for quoting bugs.",
+		},
+	}
+
+	got := RenderMarkdown(prompts)
+	golden := filepath.Join("testdata", "prompts.md.golden")
+	if *updateGolden {
+		require.NoError(t, os.WriteFile(golden, []byte(got), 0o644))
+	}
+	want, err := os.ReadFile(golden)
+	require.NoError(t, err)
+	assert.Equal(t, string(want), got)
+
+	t.Run("no prompts", func(t *testing.T) {
+		assert.Equal(t, "# Generated Prompts\n\nNo prompts were generated.\n", RenderMarkdown(nil))
+	})
+}
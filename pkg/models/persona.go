@@ -47,6 +47,12 @@ type Persona struct {
 	SystemPrompt       string                            `json:"system_prompt"`
 	Capabilities       []string                          `json:"capabilities"`
 	ModelOptimizations map[ModelFamily]ModelOptimization `json:"model_optimizations"`
+
+	// Set on personas loaded from configuration, whose SystemPrompt is a
+	// fragment appended to each phase's system prompt during generation
+	Custom bool `json:"custom,omitempty"`
+	// Judge weight preset used when a request names no scoring_criteria
+	ScoringCriteria string `json:"scoring_criteria,omitempty"`
 }
 
 // ModelOptimization contains model-specific prompting strategies
@@ -70,19 +76,27 @@ type PersonaPromptContext struct {
 	Examples     []string         `json:"examples"`
 }
 
-// GetPersona returns a persona configuration by type
+// GetPersona returns a persona configuration by type, built-in or custom
 func GetPersona(personaType PersonaType) (*Persona, error) {
 	personas := getBuiltInPersonas()
 	persona, exists := personas[personaType]
 	if !exists {
+		if custom, ok := CustomPersona(personaType); ok {
+			return custom, nil
+		}
 		return nil, fmt.Errorf("unknown persona type: %s", personaType)
 	}
 	return persona, nil
 }
 
-// GetSupportedPersonas returns all supported persona types
+// GetSupportedPersonas returns all supported persona types, the built-in
+// ones followed by the custom ones
 func GetSupportedPersonas() []PersonaType {
-	return []PersonaType{PersonaCode, PersonaWriting, PersonaAnalysis, PersonaGeneric}
+	supported := []PersonaType{PersonaCode, PersonaWriting, PersonaAnalysis, PersonaGeneric}
+	for _, persona := range customPersonaList() {
+		supported = append(supported, persona.Type)
+	}
+	return supported
 }
 
 // DetectModelFamily attempts to detect the model family from a model name
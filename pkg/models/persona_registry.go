@@ -0,0 +1,91 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+// customPersonaName restricts custom persona names to what can be used in
+// flags, config keys and URLs
+var customPersonaName = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+
+// customPersonas holds the personas registered with SetCustomPersonas
+var customPersonas struct {
+	sync.RWMutex
+	byType map[PersonaType]*Persona
+}
+
+// SetCustomPersonas replaces the custom personas GetPersona resolves
+// alongside the built-in ones. Each needs a valid, unique name that isn't a
+// built-in persona's, a description and a system prompt fragment. On error
+// the previous set stays registered.
+func SetCustomPersonas(personas []*Persona) error {
+	builtin := getBuiltInPersonas()
+	byType := make(map[PersonaType]*Persona, len(personas))
+	for _, persona := range personas {
+		if err := validateCustomPersona(persona); err != nil {
+			return fmt.Errorf("custom persona %q: %w", persona.Type, err)
+		}
+		if _, taken := builtin[persona.Type]; taken {
+			return fmt.Errorf("custom persona %q: name is taken by a built-in persona", persona.Type)
+		}
+		if _, taken := byType[persona.Type]; taken {
+			return fmt.Errorf("custom persona %q: defined more than once", persona.Type)
+		}
+		persona.Custom = true
+		byType[persona.Type] = persona
+	}
+
+	customPersonas.Lock()
+	customPersonas.byType = byType
+	customPersonas.Unlock()
+	return nil
+}
+
+// CustomPersona returns the custom persona named personaType, if registered
+func CustomPersona(personaType PersonaType) (*Persona, bool) {
+	customPersonas.RLock()
+	defer customPersonas.RUnlock()
+	persona, ok := customPersonas.byType[personaType]
+	return persona, ok
+}
+
+// ListPersonas returns the built-in personas followed by the custom ones,
+// each in name order
+func ListPersonas() []*Persona {
+	builtin := getBuiltInPersonas()
+	list := make([]*Persona, 0, len(builtin))
+	for _, persona := range builtin {
+		list = append(list, persona)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Type < list[j].Type })
+	return append(list, customPersonaList()...)
+}
+
+// customPersonaList returns the custom personas in name order
+func customPersonaList() []*Persona {
+	customPersonas.RLock()
+	list := make([]*Persona, 0, len(customPersonas.byType))
+	for _, persona := range customPersonas.byType {
+		list = append(list, persona)
+	}
+	customPersonas.RUnlock()
+	sort.Slice(list, func(i, j int) bool { return list[i].Type < list[j].Type })
+	return list
+}
+
+func validateCustomPersona(persona *Persona) error {
+	switch {
+	case !customPersonaName.MatchString(string(persona.Type)):
+		return fmt.Errorf("name must be lowercase letters, digits, '-' or '_'")
+	case persona.Description == "":
+		return fmt.Errorf("description is required")
+	case persona.SystemPrompt == "":
+		return fmt.Errorf("system_prompt is required")
+	case len(persona.SystemPrompt) > MaxSystemPromptLength:
+		return fmt.Errorf("system_prompt is %d bytes, more than the %d allowed", len(persona.SystemPrompt), MaxSystemPromptLength)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetCustomPersonas(t *testing.T) {
+	t.Cleanup(func() { _ = SetCustomPersonas(nil) })
+
+	reviewer := func() *Persona {
+		return &Persona{Type: "reviewer", Name: "Reviewer", Description: "Reviews pull requests", SystemPrompt: "Focus on correctness."}
+	}
+
+	require.NoError(t, SetCustomPersonas([]*Persona{reviewer()}))
+	persona, err := GetPersona("reviewer")
+	require.NoError(t, err)
+	assert.True(t, persona.Custom)
+	assert.Equal(t, "Focus on correctness.", persona.SystemPrompt)
+	assert.Contains(t, GetSupportedPersonas(), PersonaType("reviewer"))
+	assert.NoError(t, ValidateGenerateRequest(GenerateRequest{Input: "review this", Persona: "reviewer"}, nil))
+
+	list := ListPersonas()
+	require.Len(t, list, 5)
+	assert.Equal(t, PersonaType("reviewer"), list[4].Type, "custom personas follow the built-in ones")
+
+	tests := []struct {
+		name   string
+		modify func(p *Persona)
+		want   string
+	}{
+		{"invalid name", func(p *Persona) { p.Type = "Code Reviewer" }, "name must be"},
+		{"built-in name", func(p *Persona) { p.Type = PersonaCode }, "built-in persona"},
+		{"missing description", func(p *Persona) { p.Description = "" }, "description is required"},
+		{"missing system prompt", func(p *Persona) { p.SystemPrompt = "" }, "system_prompt is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			persona := reviewer()
+			tt.modify(persona)
+			assert.ErrorContains(t, SetCustomPersonas([]*Persona{persona}), tt.want)
+		})
+	}
+
+	t.Run("duplicate name", func(t *testing.T) {
+		assert.ErrorContains(t, SetCustomPersonas([]*Persona{reviewer(), reviewer()}), "defined more than once")
+	})
+
+	_, err = GetPersona("reviewer")
+	assert.NoError(t, err, "a rejected set leaves the previous one registered")
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -35,6 +36,12 @@ type Prompt struct {
 	TargetModelFamily string         `json:"target_model_family,omitempty" db:"target_model_family"` // Target model family specified
 	TargetUseCase     string         `json:"target_use_case,omitempty" db:"target_use_case"`         // Target use case (auto-inferred or user-specified)
 
+	// InputTemplate and InputVariables are the templated input and the
+	// variables that resolved it into OriginalInput, for prompts generated
+	// from a template
+	InputTemplate  string            `json:"input_template,omitempty" db:"-"`
+	InputVariables map[string]string `json:"input_variables,omitempty" db:"-"`
+
 	CreatedAt         time.Time       `json:"created_at" db:"created_at"`
 	UpdatedAt         time.Time       `json:"updated_at" db:"updated_at"`
 	Embedding         []float32       `json:"-" db:"embedding"`
@@ -44,6 +51,12 @@ type Prompt struct {
 	Context           []PromptContext `json:"context,omitempty"`
 	ModelMetadata     *ModelMetadata  `json:"model_metadata,omitempty"` // Additional model information
 
+	// ModerationScore is how likely the prompt is harmful, from 0 (safe) to
+	// 1, when moderation is enabled; Flagged is set when it reached
+	// moderation.threshold
+	ModerationScore *float64 `json:"moderation_score,omitempty" db:"-"`
+	Flagged         bool     `json:"flagged,omitempty" db:"-"`
+
 	SessionID uuid.UUID `json:"session_id"`
 
 	// UI display fields
@@ -59,6 +72,8 @@ type ModelMetadata struct {
 	PromptID           uuid.UUID `json:"prompt_id" db:"prompt_id"`
 	GenerationModel    string    `json:"generation_model" db:"generation_model"`
 	GenerationProvider string    `json:"generation_provider" db:"generation_provider"`
+	FallbackFrom       string    `json:"fallback_from,omitempty" db:"-"`      // Primary provider that failed when a fallback served the request
+	ModelAliasedFrom   string    `json:"model_aliased_from,omitempty" db:"-"` // Unavailable model the request asked for when its alias served it
 	EmbeddingModel     string    `json:"embedding_model" db:"embedding_model"`
 	EmbeddingProvider  string    `json:"embedding_provider" db:"embedding_provider"`
 	ModelVersion       string    `json:"model_version,omitempty" db:"model_version"`
@@ -67,8 +82,21 @@ type ModelMetadata struct {
 	InputTokens        int       `json:"input_tokens" db:"input_tokens"`
 	OutputTokens       int       `json:"output_tokens" db:"output_tokens"`
 	TotalTokens        int       `json:"total_tokens" db:"total_tokens"`
-	Cost               float64   `json:"cost,omitempty" db:"cost"` // Cost in USD if available
+	Cost               float64   `json:"cost,omitempty" db:"cost"`        // Cost in USD if available
+	CostEstimated      bool      `json:"cost_estimated,omitempty" db:"-"` // Usage or pricing was unavailable, so Cost is not authoritative
 	CreatedAt          time.Time `json:"created_at" db:"created_at"`
+
+	// Seed is the seed the request asked for; SeedIgnored is set when the
+	// serving provider doesn't support seeding, so the output isn't
+	// reproducible. SystemFingerprint is the provider's backend
+	// configuration, when it reports one.
+	Seed              *int   `json:"seed,omitempty" db:"-"`
+	SeedIgnored       bool   `json:"seed_ignored,omitempty" db:"-"`
+	SystemFingerprint string `json:"system_fingerprint,omitempty" db:"-"`
+
+	// Citations are the source URLs of a web-grounded generation, such as
+	// Perplexity's
+	Citations []string `json:"citations,omitempty" db:"-"`
 }
 
 // Phase represents the alchemical transformation stage
@@ -103,6 +131,20 @@ type PromptRequest struct {
 	Persona       string           `json:"persona,omitempty"`
 	TargetUseCase string           `json:"target_use_case,omitempty"` // Optional: auto-inferred from persona if not provided
 	SessionID     uuid.UUID
+
+	// Variables fill the {{name}} placeholders of Input before any phase
+	// runs; see SubstituteVariables. AllowMissingVariables leaves
+	// placeholders without a value in place instead of failing.
+	Variables             map[string]string `json:"variables,omitempty"`
+	AllowMissingVariables bool              `json:"allow_missing,omitempty"`
+
+	// Seed, when set, is forwarded to providers that support deterministic
+	// sampling
+	Seed *int `json:"seed,omitempty"`
+
+	// ContextDocumentIDs names ingested context documents whose chunks most
+	// relevant to Input are added to Context
+	ContextDocumentIDs []uuid.UUID `json:"context_document_ids,omitempty"`
 }
 
 // GenerateRequest represents a consolidated prompt generation request
@@ -122,12 +164,134 @@ type GenerateRequest struct {
 	TargetUseCase       string            `json:"target_use_case,omitempty"`
 	UseParallel         bool              `json:"use_parallel,omitempty"`
 	Save                bool              `json:"save,omitempty"`
+	SavePhases          []string          `json:"save_phases,omitempty"`
 	UseOptimization     bool              `json:"use_optimization,omitempty"`
 	SimilarityThreshold float64           `json:"similarity_threshold,omitempty"`
 	HistoricalWeight    float64           `json:"historical_weight,omitempty"`
 	EnableJudging       bool              `json:"enable_judging,omitempty"`
 	JudgeProvider       string            `json:"judge_provider,omitempty"`
 	ScoringCriteria     string            `json:"scoring_criteria,omitempty"`
+
+	// AutoSummarizeContext summarizes context beyond
+	// generation.context_token_budget before it reaches the providers
+	AutoSummarizeContext bool `json:"auto_summarize_context,omitempty"`
+
+	// Variables fill the {{name}} placeholders of Input; AllowMissing
+	// leaves placeholders without a value in place instead of failing
+	Variables    map[string]string `json:"variables,omitempty"`
+	AllowMissing bool              `json:"allow_missing,omitempty"`
+
+	// SystemPrompts replaces the system prompt of the phases it names
+	SystemPrompts map[string]string `json:"system_prompts,omitempty"`
+
+	// TruncateOnOverflow cuts a phase input that overflows the provider's
+	// context window to fit instead of failing
+	TruncateOnOverflow bool `json:"truncate_on_overflow,omitempty"`
+
+	// Seed asks providers that support it for deterministic sampling
+	Seed *int `json:"seed,omitempty"`
+
+	// PhaseSelection is the phase selection strategy, one of the
+	// PhaseSelection* values
+	PhaseSelection string `json:"phase_selection,omitempty"`
+}
+
+// Phase selection strategies: all returns every prompt of every phase, best
+// runs each phase on its own and keeps its best prompt, and cascade also
+// feeds each phase's best prompt to the next phase as its input
+const (
+	PhaseSelectionAll     = "all"
+	PhaseSelectionBest    = "best"
+	PhaseSelectionCascade = "cascade"
+)
+
+// SaveSelected can be listed in save_phases to persist the selected prompt,
+// whatever phase it came from
+const SaveSelected = "selected"
+
+// ValidateSavePhases checks that every save_phases entry is a phase name or
+// SaveSelected
+func ValidateSavePhases(savePhases []string) error {
+	for _, entry := range savePhases {
+		switch entry {
+		case SaveSelected, string(PhasePrimaMaterial), string(PhaseSolutio), string(PhaseCoagulatio):
+		default:
+			return fmt.Errorf("invalid save_phases entry %q: must be %s, %s, %s or %s",
+				entry, PhasePrimaMaterial, PhaseSolutio, PhaseCoagulatio, SaveSelected)
+		}
+	}
+	return nil
+}
+
+// MaxSystemPromptLength is the largest system prompt override accepted, in
+// bytes
+const MaxSystemPromptLength = 16 * 1024
+
+// ValidateSystemPrompt checks that a phase's system prompt override is no
+// longer than MaxSystemPromptLength
+func ValidateSystemPrompt(phase Phase, systemPrompt string) error {
+	if len(systemPrompt) > MaxSystemPromptLength {
+		return fmt.Errorf("system prompt for phase %s is %d bytes, more than the %d allowed",
+			phase, len(systemPrompt), MaxSystemPromptLength)
+	}
+	return nil
+}
+
+// SystemPromptOverrides converts a request's system_prompts, keyed by phase
+// name, validating each override
+func SystemPromptOverrides(systemPrompts map[string]string) (map[Phase]string, error) {
+	if len(systemPrompts) == 0 {
+		return nil, nil
+	}
+	overrides := make(map[Phase]string, len(systemPrompts))
+	for name, systemPrompt := range systemPrompts {
+		if err := ValidateSystemPrompt(Phase(name), systemPrompt); err != nil {
+			return nil, err
+		}
+		overrides[Phase(name)] = systemPrompt
+	}
+	return overrides, nil
+}
+
+// PromptsToSave picks the prompts to persist from a generation. An empty
+// savePhases keeps every prompt. Otherwise a prompt is kept when its phase is
+// listed, or when it is the selected prompt and SaveSelected is listed. The
+// returned pointers refer into prompts, except for a selected prompt that is
+// not among them.
+func PromptsToSave(prompts []Prompt, selected *Prompt, savePhases []string) []*Prompt {
+	if len(savePhases) == 0 {
+		toSave := make([]*Prompt, len(prompts))
+		for i := range prompts {
+			toSave[i] = &prompts[i]
+		}
+		return toSave
+	}
+
+	keepPhase := make(map[Phase]bool, len(savePhases))
+	keepSelected := false
+	for _, entry := range savePhases {
+		if entry == SaveSelected {
+			keepSelected = true
+		} else {
+			keepPhase[Phase(entry)] = true
+		}
+	}
+
+	var toSave []*Prompt
+	selectedFound := false
+	for i := range prompts {
+		isSelected := keepSelected && selected != nil && prompts[i].ID == selected.ID
+		if isSelected {
+			selectedFound = true
+		}
+		if keepPhase[prompts[i].Phase] || isSelected {
+			toSave = append(toSave, &prompts[i])
+		}
+	}
+	if keepSelected && selected != nil && !selectedFound {
+		toSave = append(toSave, selected)
+	}
+	return toSave
 }
 
 // GenerateResponse represents a consolidated prompt generation response
@@ -150,6 +314,11 @@ type GenerateMetadata struct {
 	OptimizationUsed bool                   `json:"optimization_used,omitempty"`
 	SimilarPrompts   []SimilarPrompt        `json:"similar_prompts,omitempty"`
 	JudgingResults   map[string]interface{} `json:"judging_results,omitempty"`
+
+	ContextSummarized  bool `json:"context_summarized,omitempty"`
+	ContextTokensSaved int  `json:"context_tokens_saved,omitempty"`
+
+	PhaseTimeouts []PhaseTimeout `json:"phase_timeouts,omitempty"`
 }
 
 // SimilarPrompt represents a prompt that is similar to the generated one
@@ -353,6 +522,20 @@ type PromptRanking struct {
 	EmbeddingDistance float64
 	LengthScore       float64
 	SemanticScore     float64
+	RerankScore       float64 // Relevance from the rerank stage, 0 if not reranked
+
+	// Breakdown lists the factors Score is the sum of, when the ranking was
+	// asked to explain itself
+	Breakdown []ScoreFactor `json:",omitempty"`
+}
+
+// ScoreFactor is one term of a ranking score: a factor's score, the weight
+// it was given and their product, its contribution to the total
+type ScoreFactor struct {
+	Name         string  `json:"name"`
+	Score        float64 `json:"score"`
+	Weight       float64 `json:"weight"`
+	Contribution float64 `json:"contribution"`
 }
 
 // GenerationResult contains the result of prompt generation
@@ -361,9 +544,79 @@ type GenerationResult struct {
 	Rankings []PromptRanking `json:"rankings"`
 	Selected *Prompt         `json:"selected,omitempty"`
 
+	// Set when AutoSummarizeContext shrank an over-budget context
+	ContextSummarized  bool `json:"context_summarized,omitempty"`
+	ContextTokensSaved int  `json:"context_tokens_saved,omitempty"`
+
+	// Phases whose temperature was outside their provider's range
+	TemperatureAdjustments []TemperatureAdjustment `json:"temperature_adjustments,omitempty"`
+
+	// Phases skipped because they ran past generation.phase_timeout
+	PhaseTimeouts []PhaseTimeout `json:"phase_timeouts,omitempty"`
+
+	// Candidates holds every prompt generated when a best or cascade phase
+	// selection kept only the best of each phase in Prompts
+	Candidates []Prompt `json:"candidates,omitempty"`
+
+	// ModerationDropped counts the prompts left out because moderation
+	// scored them at or above moderation.threshold
+	ModerationDropped int `json:"moderation_dropped,omitempty"`
+
+	// RetrievedContext holds the chunks of ContextDocumentIDs added to the
+	// context, most relevant first
+	RetrievedContext []ContextChunk `json:"retrieved_context,omitempty"`
+
+	// Cached is set when the prompts come from an earlier generation for an
+	// input CacheSimilarity similar, found in generation.semantic_cache
+	Cached          bool    `json:"cached,omitempty"`
+	CacheSimilarity float64 `json:"cache_similarity,omitempty"`
+
 	SessionID uuid.UUID
 }
 
+// TemperatureAdjustment records a phase run at a different temperature than
+// requested because its provider doesn't accept the requested one
+type TemperatureAdjustment struct {
+	Phase     Phase   `json:"phase"`
+	Provider  string  `json:"provider"`
+	Requested float64 `json:"requested"`
+	Applied   float64 `json:"applied"`
+}
+
+// PhaseTimeout records a phase whose provider calls ran past
+// generation.phase_timeout. The phase produced no prompts; the phases after
+// it worked from its input instead.
+type PhaseTimeout struct {
+	Phase    Phase  `json:"phase"`
+	Provider string `json:"provider"`
+	Timeout  string `json:"timeout"`
+}
+
+// PhaseEstimate is the projected usage of one phase of a generation
+type PhaseEstimate struct {
+	Phase        Phase   `json:"phase"`
+	Provider     string  `json:"provider"`
+	Model        string  `json:"model"`
+	Calls        int     `json:"calls"`
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	CostUSD      float64 `json:"cost_usd"`
+	// Priced is false when the model has no known price, so CostUSD is 0
+	Priced bool `json:"priced"`
+}
+
+// GenerationEstimate is the projected usage of a generation that hasn't
+// run. Output tokens assume every call uses its full max_tokens, so the
+// totals are an upper bound for all but the input to the first phase.
+type GenerationEstimate struct {
+	Phases       []PhaseEstimate `json:"phases"`
+	InputTokens  int             `json:"input_tokens"`
+	OutputTokens int             `json:"output_tokens"`
+	CostUSD      float64         `json:"cost_usd"`
+	// CostEstimated is set when some phase's model has no known price
+	CostEstimated bool `json:"cost_estimated,omitempty"`
+}
+
 // UserInteraction captures feedback on a prompt (e.g. chosen, skipped, rated).
 type UserInteraction struct {
 	ID        uuid.UUID `json:"id"`
@@ -374,6 +627,39 @@ type UserInteraction struct {
 	Timestamp time.Time `json:"timestamp"`
 }
 
+// Relationship types stored in prompt_relationships
+const (
+	RelationshipDerivedFrom = "derived_from"
+	RelationshipOptimizedTo = "optimized_to"
+	RelationshipSimilarTo   = "similar_to"
+)
+
+// PromptRelationship links two prompts. For derived_from, Source is the
+// newer prompt and Target is the prompt it was generated from.
+type PromptRelationship struct {
+	ID             uuid.UUID `json:"id"`
+	SourcePromptID uuid.UUID `json:"source_prompt_id"`
+	TargetPromptID uuid.UUID `json:"target_prompt_id"`
+	Type           string    `json:"relationship_type"`
+	Strength       float64   `json:"strength"`
+	Context        string    `json:"context,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// PromptVersion is an earlier state of an edited prompt. Versions are
+// numbered from 1 in the order they were replaced.
+type PromptVersion struct {
+	PromptID    uuid.UUID `json:"prompt_id"`
+	Version     int       `json:"version"`
+	Content     string    `json:"content"`
+	Tags        []string  `json:"tags"`
+	Provider    string    `json:"provider"`
+	Model       string    `json:"model"`
+	Temperature float64   `json:"temperature"`
+	MaxTokens   int       `json:"max_tokens"`
+	CreatedAt   time.Time `json:"created_at"` // When the version was replaced
+}
+
 // PhaseConfig maps phases to providers
 type PhaseConfig struct {
 	Phase    Phase
@@ -392,4 +678,38 @@ type GenerateOptions struct {
 	Optimize            bool    `json:"optimize,omitempty"`
 	OptimizeTargetScore float64 `json:"optimize_target_score,omitempty"`
 	OptimizeMaxIter     int     `json:"optimize_max_iterations,omitempty"`
+
+	// AutoSummarizeContext, when set, summarizes Request.Context entries
+	// beyond generation.context_token_budget before any phase runs
+	AutoSummarizeContext bool `json:"auto_summarize_context,omitempty"`
+
+	// SystemPrompts replaces the system prompt of the phases it names,
+	// taking precedence over phases.<name>.system_prompt
+	SystemPrompts map[Phase]string `json:"system_prompts,omitempty"`
+
+	// TruncateOnOverflow retries a phase whose input overflowed the
+	// provider's context window once, with the input cut to fit
+	TruncateOnOverflow bool `json:"truncate_on_overflow,omitempty"`
+
+	// PhaseSelection is the phase selection strategy, PhaseSelectionAll
+	// when empty
+	PhaseSelection string `json:"phase_selection,omitempty"`
+
+	// BypassSemanticCache generates even when generation.semantic_cache
+	// holds a generation for a similar input, and doesn't cache the result
+	BypassSemanticCache bool `json:"bypass_semantic_cache,omitempty"`
+
+	// OnPhaseStart, when set, is called as each phase begins
+	OnPhaseStart func(phase Phase) `json:"-"`
+
+	// OnPhaseComplete, when set, is called with each phase's prompts as soon
+	// as the phase finishes, before the next phase starts
+	OnPhaseComplete func(phase Phase, prompts []Prompt) `json:"-"`
+
+	// OnToken, when set, streams the final phase: it is called with each
+	// piece of text as the provider produces it, index being the variant
+	// it belongs to. Providers that can't stream send each variant's text
+	// in one piece. With UseParallel it may be called from several
+	// goroutines at once.
+	OnToken func(phase Phase, index int, delta string) `json:"-"`
 }
@@ -289,6 +289,42 @@ func TestPrompt_WithComplexContext(t *testing.T) {
 
 // Benchmark tests
 
+func TestValidateSavePhases(t *testing.T) {
+	assert.NoError(t, ValidateSavePhases(nil))
+	assert.NoError(t, ValidateSavePhases([]string{"coagulatio", SaveSelected}))
+	assert.Error(t, ValidateSavePhases([]string{"final"}))
+}
+
+func TestPromptsToSave(t *testing.T) {
+	prompts := []Prompt{
+		{ID: uuid.New(), Phase: PhasePrimaMaterial},
+		{ID: uuid.New(), Phase: PhaseSolutio},
+		{ID: uuid.New(), Phase: PhaseCoagulatio},
+	}
+	selected := prompts[1]
+
+	ids := func(saved []*Prompt) []uuid.UUID {
+		out := make([]uuid.UUID, len(saved))
+		for i, p := range saved {
+			out[i] = p.ID
+		}
+		return out
+	}
+
+	all := PromptsToSave(prompts, &selected, nil)
+	assert.Equal(t, []uuid.UUID{prompts[0].ID, prompts[1].ID, prompts[2].ID}, ids(all))
+	assert.Same(t, &prompts[0], all[0])
+
+	assert.Equal(t, []uuid.UUID{prompts[2].ID}, ids(PromptsToSave(prompts, &selected, []string{"coagulatio"})))
+	assert.Equal(t, []uuid.UUID{prompts[1].ID}, ids(PromptsToSave(prompts, &selected, []string{SaveSelected})))
+	assert.Equal(t, []uuid.UUID{prompts[1].ID, prompts[2].ID}, ids(PromptsToSave(prompts, &selected, []string{SaveSelected, "coagulatio"})))
+	assert.Empty(t, PromptsToSave(prompts, nil, []string{SaveSelected}))
+
+	// A selected prompt that isn't among the generated ones is still saved
+	outside := Prompt{ID: uuid.New(), Phase: PhaseCoagulatio}
+	assert.Equal(t, []uuid.UUID{outside.ID}, ids(PromptsToSave(prompts, &outside, []string{SaveSelected})))
+}
+
 func BenchmarkPrompt_Creation(b *testing.B) {
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrUnresolvedVariables is returned when a template input has placeholders
+// without a value and missing variables are not allowed
+var ErrUnresolvedVariables = errors.New("unresolved template variables")
+
+// variableNamePattern matches the names allowed in {{name}} placeholders
+var variableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// SubstituteVariables replaces the {{name}} placeholders in input with their
+// values. Spaces inside the braces are ignored, and values are inserted as
+// is, without being expanded themselves. A backslash before the opening
+// braces, as in \{{name}}, keeps the placeholder as literal text. Placeholders
+// without a value are an ErrUnresolvedVariables error naming them, unless
+// allowMissing is set, in which case they are left in place.
+func SubstituteVariables(input string, variables map[string]string, allowMissing bool) (string, error) {
+	var out strings.Builder
+	var missing []string
+	seen := map[string]bool{}
+
+	for i := 0; i < len(input); {
+		rest := input[i:]
+		if strings.HasPrefix(rest, `\{{`) {
+			out.WriteString("{{")
+			i += 3
+			continue
+		}
+		if !strings.HasPrefix(rest, "{{") {
+			out.WriteByte(input[i])
+			i++
+			continue
+		}
+
+		end := strings.Index(rest[2:], "}}")
+		if end < 0 {
+			out.WriteString(rest)
+			break
+		}
+		name := strings.TrimSpace(rest[2 : 2+end])
+		if !variableNamePattern.MatchString(name) {
+			// Not a placeholder, e.g. {{ }} or a JSON fragment
+			out.WriteString("{{")
+			i += 2
+			continue
+		}
+
+		placeholder := rest[:end+4]
+		if value, ok := variables[name]; ok {
+			out.WriteString(value)
+		} else {
+			if !seen[name] {
+				seen[name] = true
+				missing = append(missing, name)
+			}
+			out.WriteString(placeholder)
+		}
+		i += len(placeholder)
+	}
+
+	if len(missing) > 0 && !allowMissing {
+		return "", fmt.Errorf("%w: %s", ErrUnresolvedVariables, strings.Join(missing, ", "))
+	}
+	return out.String(), nil
+}
+
+// ResolveInput returns the request's input with its variables substituted.
+// Inputs are only treated as templates when variables are given, so inputs
+// that happen to contain braces are left alone otherwise.
+func (r PromptRequest) ResolveInput() (string, error) {
+	if len(r.Variables) == 0 {
+		return r.Input, nil
+	}
+	return SubstituteVariables(r.Input, r.Variables, r.AllowMissingVariables)
+}
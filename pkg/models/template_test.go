@@ -0,0 +1,64 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubstituteVariables(t *testing.T) {
+	variables := map[string]string{"language": "Go", "framework": "chi", "empty": ""}
+
+	tests := []struct {
+		name         string
+		input        string
+		allowMissing bool
+		want         string
+		wantMissing  string
+	}{
+		{name: "full substitution", input: "Write a {{language}} handler using {{ framework }}", want: "Write a Go handler using chi"},
+		{name: "repeated and empty values", input: "{{language}}/{{language}}{{empty}}", want: "Go/Go"},
+		{name: "escaped placeholder", input: `Keep \{{language}} but fill {{language}}`, want: "Keep {{language}} but fill Go"},
+		{name: "braces that are not placeholders", input: `Return {{"a": 1}} or {{ }} or {{unclosed`, want: `Return {{"a": 1}} or {{ }} or {{unclosed`},
+		{name: "missing variables", input: "Use {{language}} with {{database}} and {{cache}}, then {{database}} again", wantMissing: "database, cache"},
+		{name: "missing variables allowed", input: "Use {{language}} with {{database}}", allowMissing: true, want: "Use Go with {{database}}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SubstituteVariables(tt.input, variables, tt.allowMissing)
+			if tt.wantMissing != "" {
+				require.ErrorIs(t, err, ErrUnresolvedVariables)
+				assert.EqualError(t, err, "unresolved template variables: "+tt.wantMissing)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("values containing placeholders", func(t *testing.T) {
+		got, err := SubstituteVariables("{{a}}", map[string]string{"a": "{{b}}", "b": "no"}, false)
+		require.NoError(t, err)
+		assert.Equal(t, "{{b}}", got)
+	})
+}
+
+func TestPromptRequestResolveInput(t *testing.T) {
+	// Without variables the input is not a template
+	input, err := PromptRequest{Input: "Render {{name}} in Handlebars"}.ResolveInput()
+	require.NoError(t, err)
+	assert.Equal(t, "Render {{name}} in Handlebars", input)
+
+	input, err = PromptRequest{Input: "Hello {{name}}", Variables: map[string]string{"name": "world"}}.ResolveInput()
+	require.NoError(t, err)
+	assert.Equal(t, "Hello world", input)
+
+	_, err = PromptRequest{Input: "{{a}} {{b}}", Variables: map[string]string{"a": "x"}}.ResolveInput()
+	assert.ErrorIs(t, err, ErrUnresolvedVariables)
+
+	input, err = PromptRequest{Input: "{{a}} {{b}}", Variables: map[string]string{"a": "x"}, AllowMissingVariables: true}.ResolveInput()
+	require.NoError(t, err)
+	assert.Equal(t, "x {{b}}", input)
+}
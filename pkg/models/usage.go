@@ -0,0 +1,31 @@
+package models
+
+// UsageSummary totals the token usage and cost of a set of prompts
+type UsageSummary struct {
+	InputTokens  int
+	OutputTokens int
+	CostUSD      float64
+	// Estimated is set when any prompt lacked reported usage or pricing, so
+	// the totals undercount
+	Estimated bool
+}
+
+// SummarizeUsage aggregates ModelMetadata across prompts. Prompts without
+// metadata contribute nothing and mark the summary as estimated.
+func SummarizeUsage(prompts []Prompt) UsageSummary {
+	var summary UsageSummary
+	for _, p := range prompts {
+		meta := p.ModelMetadata
+		if meta == nil {
+			summary.Estimated = true
+			continue
+		}
+		summary.InputTokens += meta.InputTokens
+		summary.OutputTokens += meta.OutputTokens
+		summary.CostUSD += meta.Cost
+		if meta.CostEstimated {
+			summary.Estimated = true
+		}
+	}
+	return summary
+}
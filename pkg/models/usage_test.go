@@ -0,0 +1,33 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummarizeUsage(t *testing.T) {
+	reported := []Prompt{
+		{ModelMetadata: &ModelMetadata{InputTokens: 120, OutputTokens: 300, Cost: 0.0021}},
+		{ModelMetadata: &ModelMetadata{InputTokens: 80, OutputTokens: 200, Cost: 0.0014}},
+	}
+
+	summary := SummarizeUsage(reported)
+	assert.Equal(t, 200, summary.InputTokens)
+	assert.Equal(t, 500, summary.OutputTokens)
+	assert.InDelta(t, 0.0035, summary.CostUSD, 1e-9)
+	assert.False(t, summary.Estimated)
+
+	// A provider that omits usage adds no cost but flags the total
+	withUnreported := append(reported, Prompt{ModelMetadata: &ModelMetadata{InputTokens: 50, CostEstimated: true}})
+	summary = SummarizeUsage(withUnreported)
+	assert.Equal(t, 250, summary.InputTokens)
+	assert.InDelta(t, 0.0035, summary.CostUSD, 1e-9)
+	assert.True(t, summary.Estimated)
+
+	summary = SummarizeUsage(append(reported, Prompt{}))
+	assert.Equal(t, 200, summary.InputTokens)
+	assert.True(t, summary.Estimated)
+
+	assert.Equal(t, UsageSummary{}, SummarizeUsage(nil))
+}
@@ -0,0 +1,107 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Limits of a generate request. A zero count, temperature or max_tokens
+// asks for the entry point's default and is always valid.
+const (
+	MaxGenerateInputLength = 64 * 1024 // bytes
+	MaxGenerateCount       = 100
+	MaxGenerateTemperature = 2.0
+	MaxGenerateTokens      = 256000 // the largest output of any supported model
+	MaxGenerateContext     = 50     // context entries
+)
+
+// FieldErrors maps each invalid field of a request, by its JSON name, to
+// what is wrong with it
+type FieldErrors map[string]string
+
+func (e FieldErrors) Error() string {
+	fields := make([]string, 0, len(e))
+	for field := range e {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for i, field := range fields {
+		fields[i] = field + ": " + e[field]
+	}
+	return "invalid request: " + strings.Join(fields, "; ")
+}
+
+// ValidateGenerateRequest checks a generate request the same way for every
+// entry point, returning FieldErrors listing each violation. knownPhase
+// reports whether a phase can be run, e.g. a custom phase; when nil only
+// the built-in phases are.
+func ValidateGenerateRequest(req GenerateRequest, knownPhase func(Phase) bool) error {
+	if knownPhase == nil {
+		knownPhase = isBuiltinPhase
+	}
+	errs := FieldErrors{}
+
+	switch {
+	case req.Input == "":
+		errs["input"] = "is required"
+	case len(req.Input) > MaxGenerateInputLength:
+		errs["input"] = fmt.Sprintf("is %d bytes, more than the %d allowed", len(req.Input), MaxGenerateInputLength)
+	}
+
+	var unknown []string
+	for _, phase := range req.Phases {
+		if !knownPhase(Phase(phase)) {
+			unknown = append(unknown, fmt.Sprintf("%q", phase))
+		}
+	}
+	if len(unknown) > 0 {
+		errs["phases"] = "unknown phases " + strings.Join(unknown, ", ")
+	}
+
+	var badProviders []string
+	for phase, provider := range req.Providers {
+		switch {
+		case !knownPhase(Phase(phase)):
+			badProviders = append(badProviders, fmt.Sprintf("%q is not a phase", phase))
+		case strings.TrimSpace(provider) == "":
+			badProviders = append(badProviders, fmt.Sprintf("%q has no provider", phase))
+		}
+	}
+	if len(badProviders) > 0 {
+		sort.Strings(badProviders)
+		errs["providers"] = strings.Join(badProviders, ", ")
+	}
+
+	if req.Count < 0 || req.Count > MaxGenerateCount {
+		errs["count"] = fmt.Sprintf("must be between 1 and %d, got %d", MaxGenerateCount, req.Count)
+	}
+	if req.Temperature < 0 || req.Temperature > MaxGenerateTemperature {
+		errs["temperature"] = fmt.Sprintf("must be between 0 and %g, got %g", MaxGenerateTemperature, req.Temperature)
+	}
+	if req.MaxTokens < 0 || req.MaxTokens > MaxGenerateTokens {
+		errs["max_tokens"] = fmt.Sprintf("must be between 1 and %d, got %d", MaxGenerateTokens, req.MaxTokens)
+	}
+	if len(req.Context) > MaxGenerateContext {
+		errs["context"] = fmt.Sprintf("has %d entries, more than the %d allowed", len(req.Context), MaxGenerateContext)
+	}
+	if req.Persona != "" {
+		if _, err := GetPersona(PersonaType(req.Persona)); err != nil {
+			errs["persona"] = fmt.Sprintf("unknown persona %q", req.Persona)
+		}
+	}
+	switch req.PhaseSelection {
+	case "", PhaseSelectionAll, PhaseSelectionBest, PhaseSelectionCascade:
+	default:
+		errs["phase_selection"] = fmt.Sprintf("must be %s, %s or %s, got %q", PhaseSelectionAll, PhaseSelectionBest, PhaseSelectionCascade, req.PhaseSelection)
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func isBuiltinPhase(phase Phase) bool {
+	return phase == PhasePrimaMaterial || phase == PhaseSolutio || phase == PhaseCoagulatio
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateGenerateRequest(t *testing.T) {
+	valid := GenerateRequest{
+		Input:       "Write a CSV parser",
+		Phases:      []string{"prima-materia", "solutio", "coagulatio"},
+		Count:       3,
+		Temperature: 0.7,
+		MaxTokens:   2000,
+		Persona:     "code",
+	}
+
+	tests := []struct {
+		name   string
+		modify func(req *GenerateRequest)
+		field  string
+	}{
+		{"missing input", func(req *GenerateRequest) { req.Input = "" }, "input"},
+		{"input too long", func(req *GenerateRequest) { req.Input = strings.Repeat("a", MaxGenerateInputLength+1) }, "input"},
+		{"unknown phase", func(req *GenerateRequest) { req.Phases = []string{"solutio", "calcinatio"} }, "phases"},
+		{"provider for an unknown phase", func(req *GenerateRequest) { req.Providers = map[string]string{"phase1": "openai"} }, "providers"},
+		{"empty provider", func(req *GenerateRequest) { req.Providers = map[string]string{"solutio": ""} }, "providers"},
+		{"negative count", func(req *GenerateRequest) { req.Count = -1 }, "count"},
+		{"count too large", func(req *GenerateRequest) { req.Count = MaxGenerateCount + 1 }, "count"},
+		{"negative temperature", func(req *GenerateRequest) { req.Temperature = -0.1 }, "temperature"},
+		{"temperature too high", func(req *GenerateRequest) { req.Temperature = 2.5 }, "temperature"},
+		{"negative max_tokens", func(req *GenerateRequest) { req.MaxTokens = -5 }, "max_tokens"},
+		{"max_tokens too large", func(req *GenerateRequest) { req.MaxTokens = MaxGenerateTokens + 1 }, "max_tokens"},
+		{"unknown persona", func(req *GenerateRequest) { req.Persona = "poet" }, "persona"},
+		{"too much context", func(req *GenerateRequest) { req.Context = make([]string, MaxGenerateContext+1) }, "context"},
+		{"unknown phase selection", func(req *GenerateRequest) { req.PhaseSelection = "first" }, "phase_selection"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := valid
+			tt.modify(&req)
+
+			err := ValidateGenerateRequest(req, nil)
+			var fields FieldErrors
+			require.True(t, errors.As(err, &fields), "got %v", err)
+			assert.Len(t, fields, 1)
+			assert.Contains(t, fields, tt.field)
+			assert.Contains(t, err.Error(), tt.field+": ")
+		})
+	}
+
+	t.Run("all valid", func(t *testing.T) {
+		assert.NoError(t, ValidateGenerateRequest(valid, nil))
+	})
+
+	t.Run("zero values ask for defaults", func(t *testing.T) {
+		assert.NoError(t, ValidateGenerateRequest(GenerateRequest{Input: "Write a CSV parser"}, nil))
+	})
+
+	t.Run("every violation is listed", func(t *testing.T) {
+		err := ValidateGenerateRequest(GenerateRequest{Count: -1, Temperature: 3, Persona: "poet"}, nil)
+		var fields FieldErrors
+		require.True(t, errors.As(err, &fields))
+		assert.Equal(t, []string{"count", "input", "persona", "temperature"}, sortedKeys(fields))
+	})
+
+	t.Run("knownPhase accepts custom phases", func(t *testing.T) {
+		req := valid
+		req.Phases = []string{"calcinatio"}
+		assert.NoError(t, ValidateGenerateRequest(req, func(phase Phase) bool { return phase == "calcinatio" }))
+	})
+}
+
+func sortedKeys(fields FieldErrors) []string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -3,6 +3,8 @@ package providers
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/anthropics/anthropic-sdk-go/option"
@@ -48,6 +50,9 @@ func (p *AnthropicProvider) Generate(ctx context.Context, req GenerateRequest) (
 	messages = append(messages, anthropic.NewUserMessage(anthropic.NewTextBlock(req.Prompt)))
 
 	model := p.config.Model
+	if req.Model != "" {
+		model = req.Model
+	}
 	if model == "" {
 		model = "claude-3-5-sonnet-20241022" // Latest Claude 3.5 Sonnet
 	}
@@ -86,7 +91,7 @@ func (p *AnthropicProvider) Generate(ctx context.Context, req GenerateRequest) (
 	// Call the API using the official SDK
 	response, err := p.client.Messages.New(ctx, params)
 	if err != nil {
-		return nil, fmt.Errorf("anthropic API call failed: %w", err)
+		return nil, asContextTooLong(ProviderAnthropic, model, fmt.Errorf("anthropic API call failed: %w", err))
 	}
 
 	// Extract content from response
@@ -111,9 +116,11 @@ func (p *AnthropicProvider) Generate(ctx context.Context, req GenerateRequest) (
 	}
 
 	return &GenerateResponse{
-		Content:    content,
-		TokensUsed: tokensUsed,
-		Model:      string(response.Model),
+		Content:      content,
+		TokensUsed:   tokensUsed,
+		Model:        string(response.Model),
+		InputTokens:  int(response.Usage.InputTokens),
+		OutputTokens: int(response.Usage.OutputTokens),
 	}, nil
 }
 
@@ -133,6 +140,36 @@ func (p *AnthropicProvider) IsAvailable() bool {
 	return p.config.APIKey != ""
 }
 
+// Ping times a models listing request
+func (p *AnthropicProvider) Ping(ctx context.Context) (time.Duration, error) {
+	if !p.IsAvailable() {
+		return 0, ErrNotConfigured
+	}
+	baseURL := p.config.BaseURL
+	if baseURL == "" {
+		baseURL = "https://api.anthropic.com"
+	}
+	return pingHTTP(ctx, strings.TrimSuffix(baseURL, "/")+"/v1/models", map[string]string{
+		"x-api-key":         p.config.APIKey,
+		"anthropic-version": "2023-06-01",
+	})
+}
+
+// ListModels lists the models of the Anthropic API
+func (p *AnthropicProvider) ListModels(ctx context.Context) ([]string, error) {
+	if !p.IsAvailable() {
+		return nil, ErrNotConfigured
+	}
+	baseURL := p.config.BaseURL
+	if baseURL == "" {
+		baseURL = "https://api.anthropic.com"
+	}
+	return listModelsHTTP(ctx, strings.TrimSuffix(baseURL, "/")+"/v1/models?limit=1000", map[string]string{
+		"x-api-key":         p.config.APIKey,
+		"anthropic-version": "2023-06-01",
+	})
+}
+
 // SupportsEmbeddings checks if the provider supports embedding generation
 func (p *AnthropicProvider) SupportsEmbeddings() bool {
 	return false
@@ -0,0 +1,206 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jonwraymond/prompt-alchemy/internal/log"
+	"github.com/jonwraymond/prompt-alchemy/pkg/security"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// DefaultAzureAPIVersion is the Azure OpenAI API version used when none is configured
+const DefaultAzureAPIVersion = "2024-10-21"
+
+// AzureOpenAIProvider implements the Provider interface for Azure OpenAI.
+// Azure serves the OpenAI API shape under per-deployment paths,
+// {endpoint}/openai/deployments/{deployment}/..., with an api-version query
+// parameter and an api-key header instead of a bearer token.
+//
+// Embeddings use their own deployment, which should serve
+// text-embedding-3-small so vectors match the standard 1536-dimension
+// embeddings. Without one, embedding requests are delegated to the
+// standardized provider like the other non-OpenAI providers.
+type AzureOpenAIProvider struct {
+	client   openai.Client
+	config   Config
+	endpoint string
+}
+
+// NewAzureOpenAIProvider creates a new Azure OpenAI provider
+func NewAzureOpenAIProvider(config Config) *AzureOpenAIProvider {
+	if config.APIVersion == "" {
+		config.APIVersion = DefaultAzureAPIVersion
+	}
+
+	endpoint := strings.TrimSuffix(config.Endpoint, "/")
+	if err := security.ValidateBaseURL(endpoint); err != nil {
+		log.GetLogger().Errorf("Invalid endpoint for Azure OpenAI provider: %v", err)
+		endpoint = ""
+	}
+
+	opts := []option.RequestOption{
+		option.WithBaseURL(azureDeploymentURL(endpoint, config.Deployment)),
+		option.WithHeader("api-key", config.APIKey),
+		option.WithQuery("api-version", config.APIVersion),
+		// Azure would treat an OPENAI_API_KEY picked up from the
+		// environment as an Entra ID token and reject the request
+		option.WithHeaderDel("authorization"),
+	}
+
+	return &AzureOpenAIProvider{
+		client:   openai.NewClient(opts...),
+		config:   config,
+		endpoint: endpoint,
+	}
+}
+
+// azureDeploymentURL is the base URL of a deployment's OpenAI-shaped API
+func azureDeploymentURL(endpoint, deployment string) string {
+	return endpoint + "/openai/deployments/" + url.PathEscape(deployment) + "/"
+}
+
+// AzureDeployments returns the deployments configured under providers.azure,
+// which stand in for model names since Azure routes requests by deployment
+func AzureDeployments() []string {
+	deployments := []string{}
+	for _, key := range []string{"providers.azure.deployment", "providers.azure.embedding_deployment"} {
+		if deployment := viper.GetString(key); deployment != "" {
+			deployments = append(deployments, deployment)
+		}
+	}
+	return deployments
+}
+
+// Generate creates a prompt using the configured chat deployment
+func (p *AzureOpenAIProvider) Generate(ctx context.Context, req GenerateRequest) (*GenerateResponse, error) {
+	messages := []openai.ChatCompletionMessageParamUnion{}
+
+	// Add system prompt if provided
+	if req.SystemPrompt != "" {
+		messages = append(messages, openai.SystemMessage(req.SystemPrompt))
+	}
+
+	// Add examples if provided
+	for _, example := range req.Examples {
+		messages = append(messages, openai.UserMessage(example.Input))
+		messages = append(messages, openai.AssistantMessage(example.Output))
+	}
+
+	messages = append(messages, openai.UserMessage(req.Prompt))
+
+	// Azure routes by deployment; the model field is only echoed back
+	params := openai.ChatCompletionNewParams{
+		Model:    openai.ChatModel(p.config.Deployment),
+		Messages: messages,
+	}
+	if req.Temperature > 0 {
+		params.Temperature = openai.Float(req.Temperature)
+	}
+	if req.MaxTokens > 0 {
+		params.MaxTokens = openai.Int(int64(req.MaxTokens))
+	}
+	if req.Seed != nil {
+		params.Seed = openai.Int(int64(*req.Seed))
+	}
+
+	response, err := p.client.Chat.Completions.New(ctx, params)
+	if err != nil {
+		return nil, asContextTooLong(ProviderAzure, p.config.Deployment, fmt.Errorf("azure OpenAI API call failed: %w", err))
+	}
+
+	if len(response.Choices) == 0 {
+		return nil, fmt.Errorf("no choices returned from Azure OpenAI API")
+	}
+
+	model := response.Model
+	if model == "" {
+		model = p.config.Deployment
+	}
+	genResponse := &GenerateResponse{
+		Content:           response.Choices[0].Message.Content,
+		Model:             model,
+		SystemFingerprint: response.SystemFingerprint,
+	}
+	if response.Usage.TotalTokens > 0 {
+		genResponse.TokensUsed = int(response.Usage.TotalTokens)
+		genResponse.InputTokens = int(response.Usage.PromptTokens)
+		genResponse.OutputTokens = int(response.Usage.CompletionTokens)
+	}
+
+	return genResponse, nil
+}
+
+// GetEmbedding uses the configured embedding deployment, or delegates to the
+// standardized provider when none is configured
+func (p *AzureOpenAIProvider) GetEmbedding(ctx context.Context, text string, registry RegistryInterface) ([]float32, error) {
+	logger := log.FromContext(ctx).WithFields(logrus.Fields{
+		"provider": p.Name(),
+	})
+
+	if p.config.EmbeddingDeployment == "" {
+		logger.Info("AzureOpenAIProvider delegating embedding to standardized provider")
+		return getStandardizedEmbedding(ctx, text, registry)
+	}
+
+	response, err := p.client.Embeddings.New(ctx, openai.EmbeddingNewParams{
+		Input: openai.EmbeddingNewParamsInputUnion{
+			OfString: openai.String(text),
+		},
+		Model: openai.EmbeddingModel(p.config.EmbeddingDeployment),
+	}, option.WithBaseURL(azureDeploymentURL(p.endpoint, p.config.EmbeddingDeployment)))
+	if err != nil {
+		logger.WithError(err).Error("AzureOpenAIProvider: Failed to create embedding")
+		return nil, fmt.Errorf("failed to create embedding: %w", err)
+	}
+
+	if len(response.Data) == 0 {
+		return nil, fmt.Errorf("no embedding data returned")
+	}
+
+	embedding := make([]float32, len(response.Data[0].Embedding))
+	for i, v := range response.Data[0].Embedding {
+		embedding[i] = float32(v)
+	}
+	logger.Debugf("AzureOpenAIProvider: Successfully created embedding with length %d", len(embedding))
+
+	return embedding, nil
+}
+
+// Name returns the provider name
+func (p *AzureOpenAIProvider) Name() string {
+	return ProviderAzure
+}
+
+// IsAvailable checks if the provider is configured
+func (p *AzureOpenAIProvider) IsAvailable() bool {
+	return p.config.APIKey != "" && p.endpoint != "" && p.config.Deployment != ""
+}
+
+// Ping times a models listing request
+func (p *AzureOpenAIProvider) Ping(ctx context.Context) (time.Duration, error) {
+	if !p.IsAvailable() {
+		return 0, ErrNotConfigured
+	}
+	query := url.Values{"api-version": {p.config.APIVersion}}
+	return pingHTTP(ctx, p.endpoint+"/openai/models?"+query.Encode(), map[string]string{
+		"api-key": p.config.APIKey,
+	})
+}
+
+// SupportsEmbeddings reports whether an embedding deployment is configured
+func (p *AzureOpenAIProvider) SupportsEmbeddings() bool {
+	return p.config.EmbeddingDeployment != ""
+}
+
+// SupportsStreaming checks if the provider supports streaming generation
+func (p *AzureOpenAIProvider) SupportsStreaming() bool {
+	return true
+}
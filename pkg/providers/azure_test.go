@@ -0,0 +1,173 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// azureRequest is what the mock Azure server saw of a request
+type azureRequest struct {
+	path          string
+	apiVersion    string
+	apiKey        string
+	authorization string
+}
+
+// newAzureServer mocks an Azure OpenAI resource, answering chat completions
+// and embeddings under any deployment
+func newAzureServer(t *testing.T) (*httptest.Server, *[]azureRequest) {
+	t.Helper()
+	var requests []azureRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, azureRequest{
+			path:          r.URL.Path,
+			apiVersion:    r.URL.Query().Get("api-version"),
+			apiKey:        r.Header.Get("api-key"),
+			authorization: r.Header.Get("Authorization"),
+		})
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/openai/models":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": []interface{}{}})
+		case strings.HasSuffix(r.URL.Path, "/embeddings"):
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"object": "list",
+				"data":   []interface{}{map[string]interface{}{"object": "embedding", "index": 0, "embedding": []float64{0.1, 0.2, 0.3}}},
+				"model":  "text-embedding-3-small",
+				"usage":  map[string]interface{}{"prompt_tokens": 2, "total_tokens": 2},
+			})
+		default:
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"id":      "chatcmpl-1",
+				"object":  "chat.completion",
+				"created": 1700000000,
+				"model":   "gpt-4o-2024-08-06",
+				"choices": []interface{}{map[string]interface{}{
+					"index":         0,
+					"message":       map[string]interface{}{"role": "assistant", "content": "Hello from Azure"},
+					"finish_reason": "stop",
+				}},
+				"usage": map[string]interface{}{"prompt_tokens": 10, "completion_tokens": 5, "total_tokens": 15},
+			})
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestAzureOpenAIProvider(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "sk-must-not-be-sent")
+	server, requests := newAzureServer(t)
+	provider := NewAzureOpenAIProvider(Config{
+		APIKey:              "azure-key",
+		Endpoint:            server.URL + "/",
+		Deployment:          "chat-prod",
+		EmbeddingDeployment: "embed-prod",
+		APIVersion:          "2024-06-01",
+	})
+	ctx := context.Background()
+
+	t.Run("generate routes to the chat deployment", func(t *testing.T) {
+		*requests = nil
+		resp, err := provider.Generate(ctx, GenerateRequest{Prompt: "Hello", Temperature: 0.5, MaxTokens: 50})
+		require.NoError(t, err)
+		assert.Equal(t, "Hello from Azure", resp.Content)
+		assert.Equal(t, "gpt-4o-2024-08-06", resp.Model)
+		assert.Equal(t, 15, resp.TokensUsed)
+
+		require.Len(t, *requests, 1)
+		assert.Equal(t, azureRequest{
+			path:       "/openai/deployments/chat-prod/chat/completions",
+			apiVersion: "2024-06-01",
+			apiKey:     "azure-key",
+		}, (*requests)[0], "api-key auth only, no bearer token")
+	})
+
+	t.Run("embeddings route to the embedding deployment", func(t *testing.T) {
+		*requests = nil
+		embedding, err := provider.GetEmbedding(ctx, "Hello", nil)
+		require.NoError(t, err)
+		assert.Equal(t, []float32{0.1, 0.2, 0.3}, embedding)
+
+		require.Len(t, *requests, 1)
+		assert.Equal(t, "/openai/deployments/embed-prod/embeddings", (*requests)[0].path)
+		assert.Equal(t, "2024-06-01", (*requests)[0].apiVersion)
+		assert.Equal(t, "azure-key", (*requests)[0].apiKey)
+	})
+
+	t.Run("ping lists the resource's models", func(t *testing.T) {
+		*requests = nil
+		_, err := provider.Ping(ctx)
+		require.NoError(t, err)
+		require.Len(t, *requests, 1)
+		assert.Equal(t, azureRequest{path: "/openai/models", apiVersion: "2024-06-01", apiKey: "azure-key"}, (*requests)[0])
+	})
+}
+
+func TestNewAzureOpenAIProvider(t *testing.T) {
+	tests := []struct {
+		name           string
+		config         Config
+		wantAvailable  bool
+		wantEmbeddings bool
+		wantAPIVersion string
+	}{
+		{
+			name:           "full config",
+			config:         Config{APIKey: "key", Endpoint: "https://my-resource.openai.azure.com", Deployment: "gpt-4o", EmbeddingDeployment: "embed", APIVersion: "2024-06-01"},
+			wantAvailable:  true,
+			wantEmbeddings: true,
+			wantAPIVersion: "2024-06-01",
+		},
+		{
+			name:           "default api version, embeddings delegated",
+			config:         Config{APIKey: "key", Endpoint: "https://my-resource.openai.azure.com", Deployment: "gpt-4o"},
+			wantAvailable:  true,
+			wantAPIVersion: DefaultAzureAPIVersion,
+		},
+		{
+			name:           "missing deployment",
+			config:         Config{APIKey: "key", Endpoint: "https://my-resource.openai.azure.com"},
+			wantAPIVersion: DefaultAzureAPIVersion,
+		},
+		{
+			name:           "disallowed endpoint",
+			config:         Config{APIKey: "key", Endpoint: "https://evil.example.com", Deployment: "gpt-4o"},
+			wantAPIVersion: DefaultAzureAPIVersion,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := NewAzureOpenAIProvider(tt.config)
+			assert.Equal(t, ProviderAzure, provider.Name())
+			assert.Equal(t, tt.wantAvailable, provider.IsAvailable())
+			assert.Equal(t, tt.wantEmbeddings, provider.SupportsEmbeddings())
+			assert.Equal(t, tt.wantAPIVersion, provider.config.APIVersion)
+		})
+	}
+}
+
+func TestAzureEmbeddingCapability(t *testing.T) {
+	registry := NewRegistry()
+	require.NoError(t, registry.Register(ProviderAzure, NewAzureOpenAIProvider(Config{
+		APIKey:              "key",
+		Endpoint:            "https://my-resource.openai.azure.com",
+		Deployment:          "gpt-4o",
+		EmbeddingDeployment: "embed",
+	})))
+	assert.Equal(t, []string{ProviderAzure}, registry.ListEmbeddingCapableProviders())
+
+	viper.Set("providers.azure.deployment", "gpt-4o")
+	viper.Set("providers.azure.embedding_deployment", "embed")
+	defer viper.Reset()
+	assert.Equal(t, []string{"gpt-4o", "embed"}, AzureDeployments())
+}
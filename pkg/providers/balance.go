@@ -0,0 +1,297 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
+
+	log "github.com/jonwraymond/prompt-alchemy/internal/log"
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/spf13/viper"
+)
+
+// Strategies for spreading a phase's generations across its providers
+const (
+	BalanceRoundRobin     = "round_robin"
+	BalanceWeightedRandom = "weighted_random"
+	BalanceLeastLatency   = "least_latency"
+)
+
+// latencySmoothing is the weight of the newest latency in a provider's
+// moving average
+const latencySmoothing = 0.3
+
+// WeightedProvider is a provider of a phase's pool. Weight is its share of
+// the phase's generations under weighted_random.
+type WeightedProvider struct {
+	Name   string  `json:"name"`
+	Weight float64 `json:"weight"`
+}
+
+// BalanceConfig is the provider pool of a phase and how generations are
+// spread across it
+type BalanceConfig struct {
+	Strategy  string
+	Providers []WeightedProvider
+}
+
+// Contains reports whether name is in the pool
+func (c BalanceConfig) Contains(name string) bool {
+	return slices.ContainsFunc(c.Providers, func(p WeightedProvider) bool { return p.Name == name })
+}
+
+// LoadBalanceConfig reads phases.<phase>.providers and .strategy. Each
+// entry of providers is a provider name or a map with name and weight;
+// weights default to 1, as do weights that aren't positive. The strategy
+// defaults to round_robin.
+func LoadBalanceConfig(phase models.Phase) BalanceConfig {
+	key := "phases." + string(phase)
+	config := BalanceConfig{Strategy: viper.GetString(key + ".strategy")}
+	if config.Strategy == "" {
+		config.Strategy = BalanceRoundRobin
+	}
+
+	var entries []interface{}
+	switch value := viper.Get(key + ".providers").(type) {
+	case []interface{}:
+		entries = value
+	case []string:
+		for _, name := range value {
+			entries = append(entries, name)
+		}
+	}
+	for _, entry := range entries {
+		provider := WeightedProvider{Weight: 1}
+		switch entry := entry.(type) {
+		case string:
+			provider.Name = entry
+		case map[string]interface{}:
+			provider.Name, _ = entry["name"].(string)
+			switch weight := entry["weight"].(type) {
+			case int:
+				provider.Weight = float64(weight)
+			case float64:
+				provider.Weight = weight
+			}
+			if provider.Weight <= 0 {
+				provider.Weight = 1
+			}
+		}
+		if provider.Name != "" {
+			config.Providers = append(config.Providers, provider)
+		}
+	}
+	return config
+}
+
+// Balancer picks which provider of a pool serves each request. It keeps the
+// round-robin position and each provider's latency between requests.
+type Balancer struct {
+	config   BalanceConfig
+	strategy string
+	random   func() float64
+
+	mu      sync.Mutex
+	next    int
+	latency map[string]time.Duration
+}
+
+// NewBalancer creates a balancer for the pool of config. Unknown strategies
+// are treated as round_robin.
+func NewBalancer(config BalanceConfig) *Balancer {
+	strategy := config.Strategy
+	switch strategy {
+	case BalanceRoundRobin, BalanceWeightedRandom, BalanceLeastLatency:
+	default:
+		log.GetLogger().WithField("strategy", strategy).Warn("Unknown provider balancing strategy, using round_robin")
+		strategy = BalanceRoundRobin
+	}
+	return &Balancer{
+		config:   config,
+		strategy: strategy,
+		random:   rand.Float64,
+		latency:  make(map[string]time.Duration),
+	}
+}
+
+// Pick returns the provider to use next among those allow accepts, or
+// among all of them when it accepts none
+func (b *Balancer) Pick(allow func(name string) bool) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	pool := b.config.Providers
+	if len(pool) == 0 {
+		return ""
+	}
+	candidates := make([]int, 0, len(pool))
+	for i, provider := range pool {
+		if allow == nil || allow(provider.Name) {
+			candidates = append(candidates, i)
+		}
+	}
+	if len(candidates) == 0 {
+		for i := range pool {
+			candidates = append(candidates, i)
+		}
+	}
+
+	switch b.strategy {
+	case BalanceWeightedRandom:
+		total := 0.0
+		for _, i := range candidates {
+			total += pool[i].Weight
+		}
+		target := b.random() * total
+		for _, i := range candidates {
+			target -= pool[i].Weight
+			if target < 0 {
+				return pool[i].Name
+			}
+		}
+		return pool[candidates[len(candidates)-1]].Name
+
+	case BalanceLeastLatency:
+		// Providers not measured yet go first, so every one gets measured
+		best := candidates[0]
+		for _, i := range candidates[1:] {
+			if b.latency[pool[i].Name] < b.latency[pool[best].Name] {
+				best = i
+			}
+		}
+		return pool[best].Name
+
+	default:
+		for offset := range pool {
+			i := (b.next + offset) % len(pool)
+			if slices.Contains(candidates, i) {
+				b.next = i + 1
+				return pool[i].Name
+			}
+		}
+		return pool[candidates[0]].Name
+	}
+}
+
+// Observe records how long a request to the provider took
+func (b *Balancer) Observe(name string, latency time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if previous, ok := b.latency[name]; ok {
+		latency = time.Duration(latencySmoothing*float64(latency) + (1-latencySmoothing)*float64(previous))
+	}
+	b.latency[name] = max(latency, 1)
+}
+
+// BalancedProvider spreads generations across the providers of a phase's
+// pool, each wrapped with its own retries and fallback chain. Embeddings,
+// streaming support and Ping are answered by the first provider.
+type BalancedProvider struct {
+	names    []string // the registry names of members
+	members  []Provider
+	balancer *Balancer
+	allow    func(name string) bool // if set, providers it refuses are avoided
+}
+
+// Generate picks a provider and generates with it. The response records
+// which provider served it.
+func (p *BalancedProvider) Generate(ctx context.Context, req GenerateRequest) (*GenerateResponse, error) {
+	name := p.balancer.Pick(func(name string) bool {
+		member := p.member(name)
+		return member != nil && member.IsAvailable() && (p.allow == nil || p.allow(name))
+	})
+	member := p.member(name)
+	if member == nil {
+		name, member = p.names[0], p.members[0]
+	}
+	log.FromContext(ctx).WithField("provider", name).Debug("Balanced provider picked")
+
+	start := time.Now()
+	resp, err := member.Generate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	p.balancer.Observe(name, time.Since(start))
+	if resp.Provider == "" {
+		resp.Provider = member.Name()
+	}
+	return resp, nil
+}
+
+// member returns the member registered as name, or nil
+func (p *BalancedProvider) member(name string) Provider {
+	if i := slices.Index(p.names, name); i >= 0 {
+		return p.members[i]
+	}
+	return nil
+}
+
+// GetEmbedding returns the first provider's embedding
+func (p *BalancedProvider) GetEmbedding(ctx context.Context, text string, registry RegistryInterface) ([]float32, error) {
+	return p.members[0].GetEmbedding(ctx, text, registry)
+}
+
+// Name returns the first provider's name
+func (p *BalancedProvider) Name() string {
+	return p.members[0].Name()
+}
+
+// IsAvailable reports whether any provider of the pool is available
+func (p *BalancedProvider) IsAvailable() bool {
+	return slices.ContainsFunc(p.members, Provider.IsAvailable)
+}
+
+// SupportsEmbeddings reports whether the first provider supports embeddings
+func (p *BalancedProvider) SupportsEmbeddings() bool {
+	return p.members[0].SupportsEmbeddings()
+}
+
+// SupportsStreaming reports whether the first provider supports streaming
+func (p *BalancedProvider) SupportsStreaming() bool {
+	return p.members[0].SupportsStreaming()
+}
+
+// Ping pings the first provider
+func (p *BalancedProvider) Ping(ctx context.Context) (time.Duration, error) {
+	return p.members[0].Ping(ctx)
+}
+
+// GetBalanced returns a provider spreading phase's generations across the
+// registered providers of pool, each with retries and the
+// providers.fallback_chain cascade. The phase's balancer is kept between
+// calls and replaced when its pool changes.
+func (r *Registry) GetBalanced(phase models.Phase, pool BalanceConfig) (Provider, error) {
+	var names []string
+	var members []Provider
+	for _, entry := range pool.Providers {
+		member, err := r.GetWithFallback(entry.Name, FallbackChain(entry.Name)...)
+		if err != nil {
+			log.GetLogger().WithField("phase", phase).Debugf("Skipping unregistered pool provider: %s", entry.Name)
+			continue
+		}
+		names = append(names, entry.Name)
+		members = append(members, member)
+	}
+	switch len(members) {
+	case 0:
+		return nil, errors.New("no provider of the phase's pool is registered")
+	case 1:
+		return members[0], nil
+	}
+
+	r.balancerMu.Lock()
+	defer r.balancerMu.Unlock()
+	if r.balancers == nil {
+		r.balancers = make(map[models.Phase]*Balancer)
+	}
+	balancer, ok := r.balancers[phase]
+	if !ok || !reflect.DeepEqual(balancer.config, pool) {
+		balancer = NewBalancer(pool)
+		r.balancers[phase] = balancer
+	}
+	return &BalancedProvider{names: names, members: members, balancer: balancer, allow: r.Allow}, nil
+}
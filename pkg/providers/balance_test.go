@@ -0,0 +1,126 @@
+package providers
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func pool(strategy string, providers ...WeightedProvider) BalanceConfig {
+	return BalanceConfig{Strategy: strategy, Providers: providers}
+}
+
+func TestLoadBalanceConfig(t *testing.T) {
+	defer viper.Reset()
+	viper.Set("phases.solutio.providers", []interface{}{
+		"openai",
+		map[string]interface{}{"name": "anthropic", "weight": 3},
+		map[string]interface{}{"name": "google", "weight": 0.5},
+		map[string]interface{}{"name": "ollama", "weight": -1},
+	})
+	viper.Set("phases.solutio.strategy", BalanceWeightedRandom)
+
+	config := LoadBalanceConfig(models.PhaseSolutio)
+	assert.Equal(t, BalanceWeightedRandom, config.Strategy)
+	assert.Equal(t, []WeightedProvider{
+		{Name: "openai", Weight: 1},
+		{Name: "anthropic", Weight: 3},
+		{Name: "google", Weight: 0.5},
+		{Name: "ollama", Weight: 1},
+	}, config.Providers)
+	assert.True(t, config.Contains("google"))
+	assert.False(t, config.Contains("grok"))
+
+	assert.Equal(t, BalanceConfig{Strategy: BalanceRoundRobin}, LoadBalanceConfig(models.PhaseCoagulatio))
+}
+
+func TestBalancer(t *testing.T) {
+	t.Run("round robin cycles through the providers", func(t *testing.T) {
+		balancer := NewBalancer(pool(BalanceRoundRobin, WeightedProvider{"a", 1}, WeightedProvider{"b", 1}, WeightedProvider{"c", 1}))
+		var picks []string
+		for range 7 {
+			picks = append(picks, balancer.Pick(nil))
+		}
+		assert.Equal(t, []string{"a", "b", "c", "a", "b", "c", "a"}, picks)
+	})
+
+	t.Run("round robin skips refused providers", func(t *testing.T) {
+		balancer := NewBalancer(pool(BalanceRoundRobin, WeightedProvider{"a", 1}, WeightedProvider{"b", 1}, WeightedProvider{"c", 1}))
+		allow := func(name string) bool { return name != "b" }
+		var picks []string
+		for range 4 {
+			picks = append(picks, balancer.Pick(allow))
+		}
+		assert.Equal(t, []string{"a", "c", "a", "c"}, picks)
+
+		refuseAll := func(string) bool { return false }
+		assert.NotEmpty(t, balancer.Pick(refuseAll), "with every provider refused one is still picked")
+	})
+
+	t.Run("weighted random follows the weights", func(t *testing.T) {
+		balancer := NewBalancer(pool(BalanceWeightedRandom, WeightedProvider{"a", 6}, WeightedProvider{"b", 3}, WeightedProvider{"c", 1}))
+		const calls = 20000
+		counts := map[string]int{}
+		for range calls {
+			counts[balancer.Pick(nil)]++
+		}
+		for name, want := range map[string]float64{"a": 0.6, "b": 0.3, "c": 0.1} {
+			share := float64(counts[name]) / calls
+			assert.LessOrEqual(t, math.Abs(share-want), 0.02, "%s got %.3f of the calls, want %.1f", name, share, want)
+		}
+	})
+
+	t.Run("least latency measures every provider, then prefers the fastest", func(t *testing.T) {
+		balancer := NewBalancer(pool(BalanceLeastLatency, WeightedProvider{"a", 1}, WeightedProvider{"b", 1}))
+		assert.Equal(t, "a", balancer.Pick(nil))
+		balancer.Observe("a", 300*time.Millisecond)
+		assert.Equal(t, "b", balancer.Pick(nil), "unmeasured providers go first")
+		balancer.Observe("b", 100*time.Millisecond)
+		assert.Equal(t, "b", balancer.Pick(nil))
+
+		for range 5 {
+			balancer.Observe("b", time.Second)
+		}
+		assert.Equal(t, "a", balancer.Pick(nil), "a slowing provider loses its lead")
+	})
+
+	t.Run("unknown strategies fall back to round robin", func(t *testing.T) {
+		balancer := NewBalancer(pool("fastest", WeightedProvider{"a", 1}, WeightedProvider{"b", 1}))
+		assert.Equal(t, []string{"a", "b"}, []string{balancer.Pick(nil), balancer.Pick(nil)})
+	})
+}
+
+func TestGetProviderForPhase_Pool(t *testing.T) {
+	defer viper.Reset()
+	viper.Set("phases.prima-materia.providers", []interface{}{"a", "b", "missing"})
+
+	registry := NewRegistry()
+	for _, name := range []string{"a", "b", "c"} {
+		require.NoError(t, registry.Register(name, &TestProvider{name: name, available: true}))
+	}
+	configs := []models.PhaseConfig{{Phase: models.PhasePrimaMaterial, Provider: "a"}}
+
+	var served []string
+	for range 4 {
+		// Each generation gets a provider anew; the rotation carries over
+		provider, err := GetProviderForPhase(configs, models.PhasePrimaMaterial, registry)
+		require.NoError(t, err)
+		resp, err := provider.Generate(context.Background(), GenerateRequest{Prompt: "test"})
+		require.NoError(t, err)
+		served = append(served, resp.Provider)
+	}
+	assert.Equal(t, []string{"a", "b", "a", "b"}, served, "unregistered pool providers are skipped")
+
+	t.Run("a provider outside the pool is used alone", func(t *testing.T) {
+		provider, err := GetProviderForPhase([]models.PhaseConfig{{Phase: models.PhasePrimaMaterial, Provider: "c"}}, models.PhasePrimaMaterial, registry)
+		require.NoError(t, err)
+		assert.IsType(t, &FallbackProvider{}, provider)
+		assert.Equal(t, "c", provider.Name())
+	})
+}
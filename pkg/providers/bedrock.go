@@ -0,0 +1,419 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jonwraymond/prompt-alchemy/internal/log"
+	"github.com/jonwraymond/prompt-alchemy/pkg/security"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+const (
+	DefaultBedrockModel = "claude-3-5-sonnet"
+
+	// bedrockAnthropicVersion is the Messages API version Bedrock expects
+	// in Claude request bodies
+	bedrockAnthropicVersion = "bedrock-2023-05-31"
+)
+
+// DefaultBedrockModelMap maps the short model names accepted in
+// providers.bedrock.model and embedding_model to Bedrock model IDs.
+// providers.bedrock.model_map adds to or overrides it.
+var DefaultBedrockModelMap = map[string]string{
+	"claude-3-5-sonnet":   "anthropic.claude-3-5-sonnet-20240620-v1:0",
+	"claude-3-5-haiku":    "anthropic.claude-3-5-haiku-20241022-v1:0",
+	"claude-3-opus":       "anthropic.claude-3-opus-20240229-v1:0",
+	"claude-3-haiku":      "anthropic.claude-3-haiku-20240307-v1:0",
+	"titan-embed-text-v1": "amazon.titan-embed-text-v1",
+	"titan-embed-text-v2": "amazon.titan-embed-text-v2:0",
+}
+
+// bedrockRegionPattern matches AWS region names such as us-east-1
+var bedrockRegionPattern = regexp.MustCompile(`^[a-z]{2}(-[a-z]+)+-\d+$`)
+
+// BedrockProvider implements the Provider interface for AWS Bedrock. Claude
+// models are called through InvokeModel and InvokeModelWithResponseStream
+// with the Anthropic Messages request shape; Titan models provide
+// embeddings. Requests are signed with SigV4 using credentials from the
+// environment or the shared credentials file.
+//
+// Embeddings are only used when an embedding model is configured. Titan
+// vectors are not comparable with OpenAI's, so by default embedding requests
+// are delegated to the standardized provider like the other non-OpenAI
+// providers.
+type BedrockProvider struct {
+	config         Config
+	client         *http.Client
+	runtimeURL     string // InvokeModel endpoint
+	controlURL     string // ListFoundationModels endpoint, used by Ping
+	model          string
+	embeddingModel string
+	credentials    func() (awsCredentials, error)
+}
+
+// NewBedrockProvider creates a new AWS Bedrock provider for config.Region.
+// config.BaseURL replaces the regional endpoint, e.g. with a VPC endpoint.
+func NewBedrockProvider(config Config) *BedrockProvider {
+	models := make(map[string]string, len(DefaultBedrockModelMap)+len(config.ModelMap))
+	for name, id := range DefaultBedrockModelMap {
+		models[name] = id
+	}
+	for name, id := range config.ModelMap {
+		models[name] = id
+	}
+	resolve := func(name string) string {
+		if id, ok := models[name]; ok {
+			return id
+		}
+		return name
+	}
+
+	model := config.Model
+	if model == "" {
+		model = DefaultBedrockModel
+	}
+
+	p := &BedrockProvider{
+		config:         config,
+		model:          resolve(model),
+		embeddingModel: resolve(config.EmbeddingModel),
+		credentials:    loadAWSCredentials,
+	}
+
+	timeout := time.Duration(config.Timeout) * time.Second
+	if timeout == 0 {
+		timeout = time.Duration(DefaultGenerationTimeout) * time.Second
+	}
+	p.client = &http.Client{Timeout: timeout}
+
+	// Requests are signed for the region even when the endpoint is replaced
+	switch {
+	case !bedrockRegionPattern.MatchString(config.Region):
+		log.GetLogger().Errorf("Invalid region for Bedrock provider: %q", config.Region)
+	case config.BaseURL != "":
+		if err := security.ValidateBaseURL(config.BaseURL); err != nil {
+			log.GetLogger().Errorf("Invalid base URL for Bedrock provider: %v", err)
+			break
+		}
+		p.runtimeURL = strings.TrimSuffix(config.BaseURL, "/")
+		p.controlURL = p.runtimeURL
+	default:
+		p.runtimeURL = "https://bedrock-runtime." + config.Region + ".amazonaws.com"
+		p.controlURL = "https://bedrock." + config.Region + ".amazonaws.com"
+	}
+
+	return p
+}
+
+// BedrockModels returns the short model names known to the Bedrock
+// provider: the defaults plus those configured in providers.bedrock.model_map
+func BedrockModels() []string {
+	names := make([]string, 0, len(DefaultBedrockModelMap))
+	for name := range DefaultBedrockModelMap {
+		names = append(names, name)
+	}
+	for name := range viper.GetStringMapString("providers.bedrock.model_map") {
+		if _, ok := DefaultBedrockModelMap[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// isClaudeModel reports whether a Bedrock model ID is an Anthropic model,
+// including cross-region inference profiles such as us.anthropic.claude-...
+func isClaudeModel(id string) bool {
+	return strings.HasPrefix(id, "anthropic.") || strings.Contains(id, ".anthropic.")
+}
+
+// bedrockMessage is a message of a Claude request
+type bedrockMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// bedrockClaudeRequest is the InvokeModel body for Claude models
+type bedrockClaudeRequest struct {
+	AnthropicVersion string           `json:"anthropic_version"`
+	MaxTokens        int              `json:"max_tokens"`
+	System           string           `json:"system,omitempty"`
+	Messages         []bedrockMessage `json:"messages"`
+	Temperature      *float64         `json:"temperature,omitempty"`
+}
+
+// bedrockClaudeResponse is the InvokeModel response of Claude models
+type bedrockClaudeResponse struct {
+	Model   string `json:"model"`
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	Usage struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+// claudeRequest builds the Claude request body for req
+func (p *BedrockProvider) claudeRequest(req GenerateRequest) ([]byte, error) {
+	if !isClaudeModel(p.model) {
+		return nil, fmt.Errorf("bedrock model %s is not a Claude model; only Claude models can generate", p.model)
+	}
+
+	messages := make([]bedrockMessage, 0, 2*len(req.Examples)+1)
+	for _, example := range req.Examples {
+		messages = append(messages,
+			bedrockMessage{Role: "user", Content: example.Input},
+			bedrockMessage{Role: "assistant", Content: example.Output},
+		)
+	}
+	messages = append(messages, bedrockMessage{Role: "user", Content: req.Prompt})
+
+	body := bedrockClaudeRequest{
+		AnthropicVersion: bedrockAnthropicVersion,
+		MaxTokens:        req.MaxTokens,
+		System:           req.SystemPrompt,
+		Messages:         messages,
+	}
+	if body.MaxTokens <= 0 {
+		body.MaxTokens = 2000
+	}
+	if req.Temperature > 0 {
+		temperature := req.Temperature
+		body.Temperature = &temperature
+	}
+	return json.Marshal(body)
+}
+
+// invoke sends a signed request to a model's operation, such as invoke or
+// invoke-with-response-stream, returning the response if it succeeded
+func (p *BedrockProvider) invoke(ctx context.Context, modelID, operation, accept string, body []byte) (*http.Response, error) {
+	if p.runtimeURL == "" {
+		return nil, ErrNotConfigured
+	}
+	creds, err := p.credentials()
+	if err != nil {
+		return nil, err
+	}
+
+	path := "/model/" + modelID + "/" + operation
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.runtimeURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Bedrock request: %w", err)
+	}
+	// Model IDs contain ':', which AWS expects percent-encoded in the path
+	req.URL.RawPath = strings.TrimSuffix(req.URL.Path, path) + "/model/" + awsURIEncode(modelID) + "/" + operation
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", accept)
+	signV4(req, body, creds, p.config.Region, "bedrock", time.Now())
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("bedrock request failed: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer func() { _ = resp.Body.Close() }()
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("bedrock API returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+	return resp, nil
+}
+
+// Generate creates a prompt using the configured Claude model
+func (p *BedrockProvider) Generate(ctx context.Context, req GenerateRequest) (*GenerateResponse, error) {
+	body, err := p.claudeRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.invoke(ctx, p.model, "invoke", "application/json", body)
+	if err != nil {
+		return nil, asContextTooLong(ProviderBedrock, p.model, fmt.Errorf("bedrock API call failed: %w", err))
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var response bedrockClaudeResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode Bedrock response: %w", err)
+	}
+
+	var content strings.Builder
+	for _, block := range response.Content {
+		if block.Type == "text" {
+			content.WriteString(block.Text)
+		}
+	}
+	if content.Len() == 0 {
+		return nil, fmt.Errorf("no content in Bedrock response")
+	}
+
+	model := response.Model
+	if model == "" {
+		model = p.model
+	}
+	return &GenerateResponse{
+		Content:      content.String(),
+		TokensUsed:   response.Usage.InputTokens + response.Usage.OutputTokens,
+		Model:        model,
+		InputTokens:  response.Usage.InputTokens,
+		OutputTokens: response.Usage.OutputTokens,
+	}, nil
+}
+
+// GenerateStream creates a prompt using InvokeModelWithResponseStream,
+// sending the text on the returned channel as it arrives. The last chunk has
+// Done set, and Error if the stream failed.
+func (p *BedrockProvider) GenerateStream(ctx context.Context, req GenerateRequest) (<-chan GenerateResponseChunk, error) {
+	body, err := p.claudeRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.invoke(ctx, p.model, "invoke-with-response-stream", "application/vnd.amazon.eventstream", body)
+	if err != nil {
+		return nil, asContextTooLong(ProviderBedrock, p.model, fmt.Errorf("bedrock API call failed: %w", err))
+	}
+
+	chunks := make(chan GenerateResponseChunk)
+	go func() {
+		defer close(chunks)
+		defer func() { _ = resp.Body.Close() }()
+
+		send := func(chunk GenerateResponseChunk) bool {
+			select {
+			case chunks <- chunk:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		model := p.model
+		tokens := 0
+		for {
+			event, err := readBedrockStreamEvent(resp.Body)
+			if err == io.EOF {
+				send(GenerateResponseChunk{Model: model, TokensUsed: tokens, Done: true})
+				return
+			}
+			if err != nil {
+				send(GenerateResponseChunk{Model: model, TokensUsed: tokens, Done: true, Error: err})
+				return
+			}
+
+			switch event.Type {
+			case "message_start":
+				if event.Message.Model != "" {
+					model = event.Message.Model
+				}
+				tokens += event.Message.Usage.InputTokens
+			case "content_block_delta":
+				if event.Delta.Text != "" && !send(GenerateResponseChunk{ContentDelta: event.Delta.Text, Model: model}) {
+					return
+				}
+			case "message_delta":
+				tokens += event.Usage.OutputTokens
+			}
+		}
+	}()
+	return chunks, nil
+}
+
+// bedrockTitanEmbeddingResponse is the InvokeModel response of Titan
+// embedding models
+type bedrockTitanEmbeddingResponse struct {
+	Embedding []float32 `json:"embedding"`
+}
+
+// GetEmbedding uses the configured Titan embedding model, or delegates to
+// the standardized provider when none is configured
+func (p *BedrockProvider) GetEmbedding(ctx context.Context, text string, registry RegistryInterface) ([]float32, error) {
+	logger := log.FromContext(ctx).WithFields(logrus.Fields{
+		"provider": p.Name(),
+	})
+
+	if !p.SupportsEmbeddings() {
+		logger.Info("BedrockProvider delegating embedding to standardized provider")
+		return getStandardizedEmbedding(ctx, text, registry)
+	}
+
+	body, err := json.Marshal(map[string]string{"inputText": text})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode embedding request: %w", err)
+	}
+	resp, err := p.invoke(ctx, p.embeddingModel, "invoke", "application/json", body)
+	if err != nil {
+		logger.WithError(err).Error("BedrockProvider: Failed to create embedding")
+		return nil, fmt.Errorf("failed to create embedding: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var response bedrockTitanEmbeddingResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode embedding response: %w", err)
+	}
+	if len(response.Embedding) == 0 {
+		return nil, fmt.Errorf("no embedding data returned")
+	}
+	logger.Debugf("BedrockProvider: Successfully created embedding with length %d", len(response.Embedding))
+
+	return response.Embedding, nil
+}
+
+// Name returns the provider name
+func (p *BedrockProvider) Name() string {
+	return ProviderBedrock
+}
+
+// IsAvailable checks if the provider has an endpoint and AWS credentials
+func (p *BedrockProvider) IsAvailable() bool {
+	if p.runtimeURL == "" {
+		return false
+	}
+	_, err := p.credentials()
+	return err == nil
+}
+
+// Ping times a signed foundation models listing request
+func (p *BedrockProvider) Ping(ctx context.Context) (time.Duration, error) {
+	if !p.IsAvailable() {
+		return 0, ErrNotConfigured
+	}
+	creds, err := p.credentials()
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.controlURL+"/foundation-models?byProvider=anthropic", nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create ping request: %w", err)
+	}
+	signV4(req, nil, creds, p.config.Region, "bedrock", time.Now())
+
+	headers := make(map[string]string, len(req.Header))
+	for name := range req.Header {
+		headers[name] = req.Header.Get(name)
+	}
+	return pingHTTP(ctx, req.URL.String(), headers)
+}
+
+// SupportsEmbeddings reports whether a Titan embedding model is configured
+func (p *BedrockProvider) SupportsEmbeddings() bool {
+	return strings.HasPrefix(p.embeddingModel, "amazon.titan-embed")
+}
+
+// SupportsStreaming reports whether the configured model can stream, which
+// the Claude models do
+func (p *BedrockProvider) SupportsStreaming() bool {
+	return isClaudeModel(p.model)
+}
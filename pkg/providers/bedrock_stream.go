@@ -0,0 +1,161 @@
+package providers
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// maxEventStreamMessage bounds a single event stream message
+const maxEventStreamMessage = 16 << 20
+
+// bedrockStreamEvent is a Claude streaming event, as carried in the chunks
+// of InvokeModelWithResponseStream
+type bedrockStreamEvent struct {
+	Type    string `json:"type"`
+	Message struct {
+		Model string `json:"model"`
+		Usage struct {
+			InputTokens int `json:"input_tokens"`
+		} `json:"usage"`
+	} `json:"message"`
+	Delta struct {
+		Text string `json:"text"`
+	} `json:"delta"`
+	Usage struct {
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+// readBedrockStreamEvent reads event stream messages from r until one
+// carries a Claude event, returning io.EOF at the end of the stream. An
+// exception message is returned as an error.
+func readBedrockStreamEvent(r io.Reader) (*bedrockStreamEvent, error) {
+	for {
+		headers, payload, err := readEventStreamMessage(r)
+		if err != nil {
+			return nil, err
+		}
+
+		switch headers[":message-type"] {
+		case "exception", "error":
+			var body struct {
+				Message string `json:"message"`
+			}
+			_ = json.Unmarshal(payload, &body)
+			kind := headers[":exception-type"]
+			if kind == "" {
+				kind = headers[":error-code"]
+			}
+			return nil, fmt.Errorf("bedrock stream %s: %s", kind, body.Message)
+		case "event":
+			if headers[":event-type"] != "chunk" {
+				continue
+			}
+		default:
+			continue
+		}
+
+		// Each chunk wraps the model's own event, base64-encoded
+		var chunk struct {
+			Bytes []byte `json:"bytes"`
+		}
+		if err := json.Unmarshal(payload, &chunk); err != nil {
+			return nil, fmt.Errorf("failed to decode Bedrock stream chunk: %w", err)
+		}
+		var event bedrockStreamEvent
+		if err := json.Unmarshal(chunk.Bytes, &event); err != nil {
+			return nil, fmt.Errorf("failed to decode Bedrock stream event: %w", err)
+		}
+		return &event, nil
+	}
+}
+
+// readEventStreamMessage reads one message of the AWS event stream
+// encoding: a prelude with the total and header lengths and its CRC, the
+// headers, the payload, and a CRC of the whole message. Only string headers
+// are returned; headers of other types are skipped.
+func readEventStreamMessage(r io.Reader) (map[string]string, []byte, error) {
+	prelude := make([]byte, 12)
+	if _, err := io.ReadFull(r, prelude); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, nil, fmt.Errorf("truncated event stream message")
+		}
+		return nil, nil, err
+	}
+	totalLength := binary.BigEndian.Uint32(prelude[0:4])
+	headersLength := binary.BigEndian.Uint32(prelude[4:8])
+	if crc32.ChecksumIEEE(prelude[0:8]) != binary.BigEndian.Uint32(prelude[8:12]) {
+		return nil, nil, fmt.Errorf("event stream prelude checksum mismatch")
+	}
+	if totalLength < 16 || totalLength > maxEventStreamMessage || headersLength > totalLength-16 {
+		return nil, nil, fmt.Errorf("invalid event stream message length %d", totalLength)
+	}
+
+	message := make([]byte, totalLength)
+	copy(message, prelude)
+	if _, err := io.ReadFull(r, message[12:]); err != nil {
+		return nil, nil, fmt.Errorf("truncated event stream message")
+	}
+	end := totalLength - 4
+	if crc32.ChecksumIEEE(message[:end]) != binary.BigEndian.Uint32(message[end:]) {
+		return nil, nil, fmt.Errorf("event stream message checksum mismatch")
+	}
+
+	headers, err := parseEventStreamHeaders(message[12 : 12+headersLength])
+	if err != nil {
+		return nil, nil, err
+	}
+	return headers, message[12+headersLength : end], nil
+}
+
+// eventStreamValueSizes is the size of each fixed-size header value type
+var eventStreamValueSizes = map[byte]int{
+	0: 0,  // bool true
+	1: 0,  // bool false
+	2: 1,  // byte
+	3: 2,  // short
+	4: 4,  // int
+	5: 8,  // long
+	8: 8,  // timestamp
+	9: 16, // uuid
+}
+
+// parseEventStreamHeaders decodes the string headers of a message
+func parseEventStreamHeaders(data []byte) (map[string]string, error) {
+	headers := map[string]string{}
+	malformed := fmt.Errorf("malformed event stream headers")
+	for len(data) > 0 {
+		nameLength := int(data[0])
+		if len(data) < 1+nameLength+1 {
+			return nil, malformed
+		}
+		name := string(data[1 : 1+nameLength])
+		valueType := data[1+nameLength]
+		data = data[2+nameLength:]
+
+		if size, ok := eventStreamValueSizes[valueType]; ok {
+			if len(data) < size {
+				return nil, malformed
+			}
+			data = data[size:]
+			continue
+		}
+		// Byte array (6) and string (7) values are length-prefixed
+		if (valueType != 6 && valueType != 7) || len(data) < 2 {
+			return nil, malformed
+		}
+		valueLength := int(binary.BigEndian.Uint16(data))
+		if len(data) < 2+valueLength {
+			return nil, malformed
+		}
+		if valueType == 7 {
+			headers[name] = string(data[2 : 2+valueLength])
+		}
+		data = data[2+valueLength:]
+	}
+	return headers, nil
+}
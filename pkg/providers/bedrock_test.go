@@ -0,0 +1,383 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testAWSCredentials = awsCredentials{AccessKeyID: "AKIDTEST", SecretAccessKey: "test-secret", SessionToken: "test-token"}
+
+func TestSignV4(t *testing.T) {
+	// Vectors from the AWS Signature Version 4 test suite
+	creds := awsCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+	now, err := time.Parse(sigV4DateFormat, "20150830T123600Z")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		url       string
+		signature string
+	}{
+		{name: "get-vanilla", url: "https://example.amazonaws.com/", signature: "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"},
+		{name: "get-vanilla-query-order-key-case", url: "https://example.amazonaws.com/?Param2=value2&Param1=value1", signature: "b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, tt.url, nil)
+			require.NoError(t, err)
+			signV4(req, nil, creds, "us-east-1", "service", now)
+			assert.Equal(t, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature="+tt.signature,
+				req.Header.Get("Authorization"))
+			assert.Equal(t, "20150830T123600Z", req.Header.Get("X-Amz-Date"))
+		})
+	}
+}
+
+// verifyBedrockSignature re-signs the request the mock server received and
+// compares the result, so the signature covers what was actually sent,
+// including the percent-encoded model ID in the path
+func verifyBedrockSignature(t *testing.T, r *http.Request, body []byte) {
+	t.Helper()
+	authorization := r.Header.Get("Authorization")
+	require.True(t, strings.HasPrefix(authorization, "AWS4-HMAC-SHA256 Credential=AKIDTEST/"), authorization)
+	assert.Contains(t, authorization, "/us-east-1/bedrock/aws4_request")
+	assert.Contains(t, authorization, "SignedHeaders=accept;content-type;host;x-amz-date;x-amz-security-token")
+	assert.Equal(t, "test-token", r.Header.Get("X-Amz-Security-Token"))
+
+	signedAt, err := time.Parse(sigV4DateFormat, r.Header.Get("X-Amz-Date"))
+	require.NoError(t, err)
+	resigned, err := http.NewRequest(r.Method, "http://"+r.Host+r.URL.RequestURI(), bytes.NewReader(body))
+	require.NoError(t, err)
+	for _, name := range []string{"Accept", "Content-Type"} {
+		resigned.Header.Set(name, r.Header.Get(name))
+	}
+	signV4(resigned, body, testAWSCredentials, "us-east-1", "bedrock", signedAt)
+	assert.Equal(t, resigned.Header.Get("Authorization"), authorization, "signature matches the request as received")
+}
+
+// encodeEventStreamMessage encodes a message with string headers in the AWS
+// event stream format
+func encodeEventStreamMessage(headers map[string]string, payload []byte) []byte {
+	var encodedHeaders bytes.Buffer
+	for name, value := range headers {
+		encodedHeaders.WriteByte(byte(len(name)))
+		encodedHeaders.WriteString(name)
+		encodedHeaders.WriteByte(7)
+		_ = binary.Write(&encodedHeaders, binary.BigEndian, uint16(len(value)))
+		encodedHeaders.WriteString(value)
+	}
+
+	total := 12 + encodedHeaders.Len() + len(payload) + 4
+	message := make([]byte, 0, total)
+	message = binary.BigEndian.AppendUint32(message, uint32(total))
+	message = binary.BigEndian.AppendUint32(message, uint32(encodedHeaders.Len()))
+	message = binary.BigEndian.AppendUint32(message, crc32.ChecksumIEEE(message))
+	message = append(message, encodedHeaders.Bytes()...)
+	message = append(message, payload...)
+	return binary.BigEndian.AppendUint32(message, crc32.ChecksumIEEE(message))
+}
+
+// encodeBedrockChunk wraps a Claude stream event in a Bedrock chunk message
+func encodeBedrockChunk(event string) []byte {
+	payload, _ := json.Marshal(map[string]string{"bytes": base64.StdEncoding.EncodeToString([]byte(event))})
+	return encodeEventStreamMessage(map[string]string{
+		":message-type": "event",
+		":event-type":   "chunk",
+		":content-type": "application/json",
+	}, payload)
+}
+
+// newBedrockServer mocks the Bedrock runtime, checking every request's
+// signature and answering Claude, streaming and Titan requests
+func newBedrockServer(t *testing.T) (*httptest.Server, *[]string) {
+	t.Helper()
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		verifyBedrockSignature(t, r, body)
+		paths = append(paths, r.URL.EscapedPath())
+
+		switch {
+		case strings.HasPrefix(r.URL.EscapedPath(), "/model/amazon.titan"):
+			var request map[string]string
+			require.NoError(t, json.Unmarshal(body, &request))
+			assert.Equal(t, "embed me", request["inputText"])
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"embedding":[0.25,0.5,0.75],"inputTextTokenCount":2}`))
+
+		case strings.HasSuffix(r.URL.Path, "/invoke-with-response-stream"):
+			assert.Equal(t, "application/vnd.amazon.eventstream", r.Header.Get("Accept"))
+			w.Header().Set("Content-Type", "application/vnd.amazon.eventstream")
+			for _, event := range []string{
+				`{"type":"message_start","message":{"model":"claude-3-5-sonnet-20240620","usage":{"input_tokens":12}}}`,
+				`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+				`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello "}}`,
+				`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"from Bedrock"}}`,
+				`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":4}}`,
+				`{"type":"message_stop"}`,
+			} {
+				_, _ = w.Write(encodeBedrockChunk(event))
+			}
+
+		default:
+			var request bedrockClaudeRequest
+			require.NoError(t, json.Unmarshal(body, &request))
+			assert.Equal(t, bedrockAnthropicVersion, request.AnthropicVersion)
+			assert.Equal(t, "Be brief", request.System)
+			assert.Equal(t, []bedrockMessage{
+				{Role: "user", Content: "example in"},
+				{Role: "assistant", Content: "example out"},
+				{Role: "user", Content: "Hello"},
+			}, request.Messages)
+			assert.Equal(t, 50, request.MaxTokens)
+			require.NotNil(t, request.Temperature)
+			assert.Equal(t, 0.5, *request.Temperature)
+
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{
+				"id": "msg_1", "type": "message", "role": "assistant",
+				"model": "claude-3-5-sonnet-20240620",
+				"content": [{"type": "text", "text": "Hello from Bedrock"}],
+				"stop_reason": "end_turn",
+				"usage": {"input_tokens": 12, "output_tokens": 4}
+			}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &paths
+}
+
+func TestBedrockProvider(t *testing.T) {
+	server, paths := newBedrockServer(t)
+	provider := NewBedrockProvider(Config{
+		Region:         "us-east-1",
+		BaseURL:        server.URL,
+		EmbeddingModel: "titan-embed-text-v1",
+	})
+	provider.credentials = func() (awsCredentials, error) { return testAWSCredentials, nil }
+	ctx := context.Background()
+	request := GenerateRequest{
+		SystemPrompt: "Be brief",
+		Prompt:       "Hello",
+		Examples:     []Example{{Input: "example in", Output: "example out"}},
+		Temperature:  0.5,
+		MaxTokens:    50,
+	}
+
+	t.Run("generate invokes the Claude model", func(t *testing.T) {
+		*paths = nil
+		resp, err := provider.Generate(ctx, request)
+		require.NoError(t, err)
+		assert.Equal(t, "Hello from Bedrock", resp.Content)
+		assert.Equal(t, "claude-3-5-sonnet-20240620", resp.Model)
+		assert.Equal(t, 16, resp.TokensUsed)
+		assert.Equal(t, 12, resp.InputTokens)
+		assert.Equal(t, 4, resp.OutputTokens)
+		assert.Equal(t, []string{"/model/anthropic.claude-3-5-sonnet-20240620-v1%3A0/invoke"}, *paths)
+	})
+
+	t.Run("stream decodes event stream chunks", func(t *testing.T) {
+		*paths = nil
+		chunks, err := provider.GenerateStream(ctx, request)
+		require.NoError(t, err)
+
+		var content strings.Builder
+		var last GenerateResponseChunk
+		for chunk := range chunks {
+			content.WriteString(chunk.ContentDelta)
+			last = chunk
+		}
+		require.NoError(t, last.Error)
+		assert.True(t, last.Done)
+		assert.Equal(t, "Hello from Bedrock", content.String())
+		assert.Equal(t, 16, last.TokensUsed)
+		assert.Equal(t, "claude-3-5-sonnet-20240620", last.Model)
+		assert.Equal(t, []string{"/model/anthropic.claude-3-5-sonnet-20240620-v1%3A0/invoke-with-response-stream"}, *paths)
+	})
+
+	t.Run("embeddings invoke the Titan model", func(t *testing.T) {
+		*paths = nil
+		embedding, err := provider.GetEmbedding(ctx, "embed me", nil)
+		require.NoError(t, err)
+		assert.Equal(t, []float32{0.25, 0.5, 0.75}, embedding)
+		assert.Equal(t, []string{"/model/amazon.titan-embed-text-v1/invoke"}, *paths)
+	})
+}
+
+func TestBedrockStreamErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/invoke") {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"message":"The security token included in the request is invalid."}`))
+			return
+		}
+		_, _ = w.Write(encodeBedrockChunk(`{"type":"content_block_delta","delta":{"text":"partial"}}`))
+		_, _ = w.Write(encodeEventStreamMessage(map[string]string{
+			":message-type":   "exception",
+			":exception-type": "throttlingException",
+		}, []byte(`{"message":"Too many requests"}`)))
+	}))
+	defer server.Close()
+
+	provider := NewBedrockProvider(Config{Region: "us-east-1", BaseURL: server.URL})
+	provider.credentials = func() (awsCredentials, error) { return testAWSCredentials, nil }
+	ctx := context.Background()
+
+	_, err := provider.Generate(ctx, GenerateRequest{Prompt: "Hello"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "status 403")
+	assert.Contains(t, err.Error(), "security token")
+
+	chunks, err := provider.GenerateStream(ctx, GenerateRequest{Prompt: "Hello"})
+	require.NoError(t, err)
+	var received []GenerateResponseChunk
+	for chunk := range chunks {
+		received = append(received, chunk)
+	}
+	require.Len(t, received, 2)
+	assert.Equal(t, "partial", received[0].ContentDelta)
+	assert.True(t, received[1].Done)
+	require.Error(t, received[1].Error)
+	assert.Contains(t, received[1].Error.Error(), "throttlingException: Too many requests")
+}
+
+func TestReadEventStreamMessageChecksums(t *testing.T) {
+	message := encodeBedrockChunk(`{"type":"message_stop"}`)
+
+	headers, _, err := readEventStreamMessage(bytes.NewReader(message))
+	require.NoError(t, err)
+	assert.Equal(t, "chunk", headers[":event-type"])
+
+	corrupted := append([]byte(nil), message...)
+	corrupted[len(corrupted)-6] ^= 0xff
+	_, _, err = readEventStreamMessage(bytes.NewReader(corrupted))
+	assert.ErrorContains(t, err, "checksum mismatch")
+
+	_, _, err = readEventStreamMessage(bytes.NewReader(message[:20]))
+	assert.ErrorContains(t, err, "truncated")
+}
+
+func TestNewBedrockProvider(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDTEST")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test-secret")
+
+	tests := []struct {
+		name           string
+		config         Config
+		wantAvailable  bool
+		wantStreaming  bool
+		wantEmbeddings bool
+		wantModel      string
+		wantRuntimeURL string
+	}{
+		{
+			name:           "defaults",
+			config:         Config{Region: "us-east-1"},
+			wantAvailable:  true,
+			wantStreaming:  true,
+			wantModel:      "anthropic.claude-3-5-sonnet-20240620-v1:0",
+			wantRuntimeURL: "https://bedrock-runtime.us-east-1.amazonaws.com",
+		},
+		{
+			name: "model map and titan embeddings",
+			config: Config{
+				Region:         "eu-west-3",
+				Model:          "sonnet",
+				EmbeddingModel: "titan-embed-text-v2",
+				ModelMap:       map[string]string{"sonnet": "eu.anthropic.claude-3-5-sonnet-20240620-v1:0"},
+			},
+			wantAvailable:  true,
+			wantStreaming:  true,
+			wantEmbeddings: true,
+			wantModel:      "eu.anthropic.claude-3-5-sonnet-20240620-v1:0",
+			wantRuntimeURL: "https://bedrock-runtime.eu-west-3.amazonaws.com",
+		},
+		{
+			name:           "non-Claude model cannot stream",
+			config:         Config{Region: "us-east-1", Model: "amazon.titan-text-express-v1"},
+			wantAvailable:  true,
+			wantModel:      "amazon.titan-text-express-v1",
+			wantRuntimeURL: "https://bedrock-runtime.us-east-1.amazonaws.com",
+		},
+		{
+			name:          "missing region",
+			config:        Config{},
+			wantStreaming: true,
+			wantModel:     "anthropic.claude-3-5-sonnet-20240620-v1:0",
+		},
+		{
+			name:          "disallowed endpoint",
+			config:        Config{Region: "us-east-1", BaseURL: "https://evil.example.com"},
+			wantStreaming: true,
+			wantModel:     "anthropic.claude-3-5-sonnet-20240620-v1:0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := NewBedrockProvider(tt.config)
+			assert.Equal(t, ProviderBedrock, provider.Name())
+			assert.Equal(t, tt.wantAvailable, provider.IsAvailable())
+			assert.Equal(t, tt.wantStreaming, provider.SupportsStreaming())
+			assert.Equal(t, tt.wantEmbeddings, provider.SupportsEmbeddings())
+			assert.Equal(t, tt.wantModel, provider.model)
+			assert.Equal(t, tt.wantRuntimeURL, provider.runtimeURL)
+		})
+	}
+}
+
+func TestLoadAWSCredentials(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "credentials")
+	require.NoError(t, os.WriteFile(path, []byte(`
+[default]
+aws_access_key_id = AKIDDEFAULT
+aws_secret_access_key = default-secret
+
+# a comment
+[work]
+aws_access_key_id=AKIDWORK
+aws_secret_access_key=work-secret
+aws_session_token=work-token
+`), 0o600))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", path)
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_SESSION_TOKEN", "")
+	t.Setenv("AWS_PROFILE", "")
+
+	creds, err := loadAWSCredentials()
+	require.NoError(t, err)
+	assert.Equal(t, awsCredentials{AccessKeyID: "AKIDDEFAULT", SecretAccessKey: "default-secret"}, creds)
+
+	t.Setenv("AWS_PROFILE", "work")
+	creds, err = loadAWSCredentials()
+	require.NoError(t, err)
+	assert.Equal(t, awsCredentials{AccessKeyID: "AKIDWORK", SecretAccessKey: "work-secret", SessionToken: "work-token"}, creds)
+
+	t.Setenv("AWS_PROFILE", "missing")
+	_, err = loadAWSCredentials()
+	assert.ErrorIs(t, err, errNoAWSCredentials)
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDENV")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "env-secret")
+	creds, err = loadAWSCredentials()
+	require.NoError(t, err)
+	assert.Equal(t, "AKIDENV", creds.AccessKeyID, "the environment comes first")
+}
@@ -0,0 +1,198 @@
+package providers
+
+import (
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// ModelLimits describes the token limits of a model
+type ModelLimits struct {
+	ContextWindow   int
+	MaxOutputTokens int
+}
+
+// modelLimitEntry matches every model whose name starts with prefix
+type modelLimitEntry struct {
+	prefix string
+	limits ModelLimits
+}
+
+// defaultModels mirrors the model each provider falls back to when none is configured
+var defaultModels = map[string]string{
+	ProviderOpenAI:     "o4-mini",
+	ProviderAnthropic:  "claude-3-5-sonnet-20241022",
+	ProviderGoogle:     "gemini-2.5-flash",
+	ProviderGrok:       "grok-2-1212",
+	ProviderMistral:    DefaultMistralModel,
+	ProviderDeepSeek:   DefaultDeepSeekModel,
+	ProviderCohere:     DefaultCohereModel,
+	ProviderPerplexity: DefaultPerplexityModel,
+	ProviderBedrock:    DefaultBedrockModel,
+}
+
+// modelLimits is the capability table used to validate max_tokens before a
+// request reaches a provider, and to size context window overflows. Entries are matched by longest prefix so dated
+// snapshots (e.g. gpt-4o-2024-08-06) inherit their family's limits. Local
+// Ollama models vary too much to list and are not validated.
+var modelLimits = map[string][]modelLimitEntry{
+	ProviderOpenAI: {
+		{"o4-mini", ModelLimits{200000, 100000}},
+		{"o3", ModelLimits{200000, 100000}},
+		{"o1", ModelLimits{200000, 100000}},
+		{"gpt-4.1", ModelLimits{1047576, 32768}},
+		{"gpt-4o", ModelLimits{128000, 16384}},
+		{"gpt-4-turbo", ModelLimits{128000, 4096}},
+		{"gpt-4", ModelLimits{8192, 8192}},
+		{"gpt-3.5-turbo", ModelLimits{16385, 4096}},
+	},
+	ProviderAnthropic: {
+		{"claude-opus-4", ModelLimits{200000, 32000}},
+		{"claude-sonnet-4", ModelLimits{200000, 64000}},
+		{"claude-3-7-sonnet", ModelLimits{200000, 64000}},
+		{"claude-3-5-sonnet", ModelLimits{200000, 8192}},
+		{"claude-3-5-haiku", ModelLimits{200000, 8192}},
+		{"claude-3-opus", ModelLimits{200000, 4096}},
+		{"claude-3-haiku", ModelLimits{200000, 4096}},
+	},
+	ProviderGoogle: {
+		{"gemini-2.5-pro", ModelLimits{1048576, 65536}},
+		{"gemini-2.5-flash", ModelLimits{1048576, 65536}},
+		{"gemini-2.0-flash", ModelLimits{1048576, 8192}},
+		{"gemini-1.5-pro", ModelLimits{2097152, DefaultMaxProTokens}},
+		{"gemini-1.5-flash", ModelLimits{1048576, DefaultMaxFlashTokens}},
+	},
+	ProviderGrok: {
+		{"grok-4", ModelLimits{256000, 256000}},
+		{"grok-3", ModelLimits{131072, 131072}},
+		{"grok-2", ModelLimits{131072, 131072}},
+	},
+	ProviderMistral: {
+		{"mistral-large", ModelLimits{131072, 131072}},
+		{"mistral-medium", ModelLimits{131072, 131072}},
+		{"mistral-small", ModelLimits{131072, 131072}},
+		{"codestral", ModelLimits{256000, 256000}},
+		{"ministral", ModelLimits{131072, 131072}},
+		{"open-mistral-nemo", ModelLimits{131072, 131072}},
+	},
+	ProviderDeepSeek: {
+		{"deepseek-chat", ModelLimits{128000, 8192}},
+		{"deepseek-reasoner", ModelLimits{128000, 65536}},
+	},
+	ProviderCohere: {
+		{"command-a", ModelLimits{256000, 8000}},
+		{"command-r-plus", ModelLimits{128000, 4000}},
+		{"command-r7b", ModelLimits{128000, 4000}},
+		{"command-r", ModelLimits{128000, 4000}},
+	},
+	ProviderPerplexity: {
+		{"sonar", ModelLimits{128000, 8000}},
+		{"sonar-pro", ModelLimits{200000, 8000}},
+	},
+}
+
+// openRouterVendors maps OpenRouter model vendors onto the tables above
+var openRouterVendors = map[string]string{
+	"openai":     ProviderOpenAI,
+	"anthropic":  ProviderAnthropic,
+	"google":     ProviderGoogle,
+	"x-ai":       ProviderGrok,
+	"mistralai":  ProviderMistral,
+	"deepseek":   ProviderDeepSeek,
+	"cohere":     ProviderCohere,
+	"perplexity": ProviderPerplexity,
+}
+
+// DefaultModel returns the model a provider uses when none is configured
+func DefaultModel(provider string) string {
+	return defaultModels[provider]
+}
+
+// ConfiguredModel returns the model a provider is configured to use,
+// falling back to its default
+func ConfiguredModel(provider string) string {
+	if model := viper.GetString("providers." + provider + ".model"); model != "" {
+		return model
+	}
+	return DefaultModel(provider)
+}
+
+// LookupModelLimits returns the token limits of a provider's model. The
+// second result is false when the model is not in the capability table, in
+// which case callers should not enforce any limit.
+func LookupModelLimits(provider, model string) (ModelLimits, bool) {
+	if provider == ProviderOpenRouter {
+		vendor, name, ok := strings.Cut(model, "/")
+		if !ok {
+			return ModelLimits{}, false
+		}
+		if provider, ok = openRouterVendors[vendor]; !ok {
+			return ModelLimits{}, false
+		}
+		model = name
+	}
+	if provider == ProviderBedrock {
+		// Claude on Bedrock shares Anthropic's limits, whether named by a
+		// short name or a model ID such as us.anthropic.claude-3-5-sonnet-...
+		provider = ProviderAnthropic
+		if _, id, ok := strings.Cut(model, "anthropic."); ok {
+			model = id
+		}
+	}
+
+	var best modelLimitEntry
+	for _, entry := range modelLimits[provider] {
+		if strings.HasPrefix(model, entry.prefix) && len(entry.prefix) > len(best.prefix) {
+			best = entry
+		}
+	}
+	if best.prefix == "" {
+		return ModelLimits{}, false
+	}
+	return best.limits, true
+}
+
+// TemperatureRange is the range of sampling temperatures a provider accepts
+type TemperatureRange struct {
+	Min float64
+	Max float64
+}
+
+// temperatureRanges lists the providers that accept less than the default
+// 0–DefaultMaxTemperature range
+var temperatureRanges = map[string]TemperatureRange{
+	ProviderAnthropic: {0, 1},
+	ProviderMistral:   {0, 1},
+	ProviderBedrock:   {0, 1},
+	ProviderCohere:    {0, 1},
+}
+
+// seedProviders lists the providers that forward GenerateRequest.Seed
+var seedProviders = map[string]bool{
+	ProviderOpenAI: true,
+	ProviderAzure:  true,
+	ProviderGrok:   true,
+	ProviderGoogle: true,
+	ProviderOllama: true,
+	ProviderCohere: true,
+}
+
+// SupportsSeed reports whether a provider forwards a seed for deterministic
+// sampling. Even then, results are best-effort reproducible.
+func SupportsSeed(provider string) bool {
+	return seedProviders[provider]
+}
+
+// LookupTemperatureRange returns the temperatures a provider accepts
+func LookupTemperatureRange(provider string) TemperatureRange {
+	if r, ok := temperatureRanges[provider]; ok {
+		return r
+	}
+	return TemperatureRange{0, DefaultMaxTemperature}
+}
+
+// ClampTemperature limits temperature to the range the provider accepts
+func ClampTemperature(provider string, temperature float64) float64 {
+	r := LookupTemperatureRange(provider)
+	return min(max(temperature, r.Min), r.Max)
+}
@@ -0,0 +1,66 @@
+package providers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookupModelLimits(t *testing.T) {
+	tests := []struct {
+		name      string
+		provider  string
+		model     string
+		wantOut   int
+		wantFound bool
+	}{
+		{name: "exact family", provider: ProviderOpenAI, model: "gpt-4o", wantOut: 16384, wantFound: true},
+		{name: "longest prefix wins", provider: ProviderOpenAI, model: "gpt-4-turbo-2024-04-09", wantOut: 4096, wantFound: true},
+		{name: "dated snapshot", provider: ProviderAnthropic, model: "claude-3-5-sonnet-20241022", wantOut: 8192, wantFound: true},
+		{name: "openrouter vendor mapping", provider: ProviderOpenRouter, model: "anthropic/claude-3-opus", wantOut: 4096, wantFound: true},
+		{name: "openrouter unknown vendor", provider: ProviderOpenRouter, model: "meta-llama/llama-3-70b", wantFound: false},
+		{name: "bedrock short name", provider: ProviderBedrock, model: "claude-3-5-haiku", wantOut: 8192, wantFound: true},
+		{name: "bedrock inference profile", provider: ProviderBedrock, model: "us.anthropic.claude-3-opus-20240229-v1:0", wantOut: 4096, wantFound: true},
+		{name: "bedrock titan", provider: ProviderBedrock, model: "amazon.titan-embed-text-v1", wantFound: false},
+		{name: "ollama is not validated", provider: ProviderOllama, model: "llama3", wantFound: false},
+		{name: "unknown model", provider: ProviderOpenAI, model: "davinci-002", wantFound: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limits, found := LookupModelLimits(tt.provider, tt.model)
+			assert.Equal(t, tt.wantFound, found)
+			assert.Equal(t, tt.wantOut, limits.MaxOutputTokens)
+		})
+	}
+}
+
+func TestDefaultModelHasLimits(t *testing.T) {
+	for _, provider := range []string{ProviderOpenAI, ProviderAnthropic, ProviderGoogle, ProviderGrok, ProviderBedrock} {
+		_, found := LookupModelLimits(provider, DefaultModel(provider))
+		assert.True(t, found, provider)
+	}
+}
+
+func TestClampTemperature(t *testing.T) {
+	tests := []struct {
+		name        string
+		provider    string
+		temperature float64
+		want        float64
+	}{
+		{name: "anthropic caps at 1", provider: ProviderAnthropic, temperature: 1.5, want: 1},
+		{name: "anthropic in range", provider: ProviderAnthropic, temperature: 0.7, want: 0.7},
+		{name: "mistral caps at 1", provider: ProviderMistral, temperature: 1.2, want: 1},
+		{name: "openai allows up to 2", provider: ProviderOpenAI, temperature: 1.5, want: 1.5},
+		{name: "openai caps at 2", provider: ProviderOpenAI, temperature: 2.5, want: 2},
+		{name: "unknown provider uses default", provider: "custom", temperature: 3, want: DefaultMaxTemperature},
+		{name: "negative raised to 0", provider: ProviderGoogle, temperature: -1, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ClampTemperature(tt.provider, tt.temperature))
+		})
+	}
+}
@@ -0,0 +1,345 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jonwraymond/prompt-alchemy/internal/log"
+	"github.com/jonwraymond/prompt-alchemy/pkg/security"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultCohereModel          = "command-a-03-2025"
+	DefaultCohereEmbeddingModel = "embed-english-v3.0"
+	DefaultCohereRerankModel    = "rerank-v3.5"
+
+	defaultCohereBaseURL = "https://api.cohere.com"
+)
+
+// cohereEmbeddingDimensions are the vector sizes of Cohere's embed-v3
+// models, which don't vary with the input
+var cohereEmbeddingDimensions = map[string]int{
+	"embed-english-v3.0":            1024,
+	"embed-multilingual-v3.0":       1024,
+	"embed-english-light-v3.0":      384,
+	"embed-multilingual-light-v3.0": 384,
+}
+
+// CohereProvider implements the Provider interface for Cohere's v2 chat,
+// embed and rerank APIs over plain HTTP.
+//
+// Unlike the other non-OpenAI providers, Cohere embeds natively: embed-v3
+// vectors are 1024-dimensional (384 for the light models), so they can't be
+// mixed with the standard 1536-dimension embeddings of an existing
+// database; reindex after switching. Rerank scores documents against a
+// query in one request, which the Ranker uses when Cohere is the rerank
+// provider.
+type CohereProvider struct {
+	config         Config
+	client         *http.Client
+	baseURL        string
+	model          string
+	embeddingModel string
+	rerankModel    string
+}
+
+// NewCohereProvider creates a new Cohere provider
+func NewCohereProvider(config Config) *CohereProvider {
+	baseURL := config.BaseURL
+	if baseURL == "" {
+		baseURL = defaultCohereBaseURL
+	}
+
+	// Validate the base URL for security
+	if err := security.ValidateBaseURL(baseURL); err != nil {
+		log.GetLogger().Errorf("Invalid base URL for Cohere provider: %v", err)
+		baseURL = defaultCohereBaseURL
+	}
+
+	timeout := time.Duration(config.Timeout) * time.Second
+	if timeout == 0 {
+		timeout = time.Duration(DefaultGenerationTimeout) * time.Second
+	}
+
+	p := &CohereProvider{
+		config:         config,
+		client:         &http.Client{Timeout: timeout},
+		baseURL:        strings.TrimSuffix(baseURL, "/"),
+		model:          config.Model,
+		embeddingModel: config.EmbeddingModel,
+		rerankModel:    config.RerankModel,
+	}
+	if p.model == "" {
+		p.model = DefaultCohereModel
+	}
+	if p.embeddingModel == "" {
+		p.embeddingModel = DefaultCohereEmbeddingModel
+	}
+	if p.rerankModel == "" {
+		p.rerankModel = DefaultCohereRerankModel
+	}
+	return p
+}
+
+// CohereEmbeddingDimensions returns the vector size of a Cohere embedding
+// model, or 0 if it isn't known
+func CohereEmbeddingDimensions(model string) int {
+	return cohereEmbeddingDimensions[model]
+}
+
+// post sends body as JSON to a Cohere API path and decodes the response
+// into out
+func (p *CohereProvider) post(ctx context.Context, path string, body, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode Cohere request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create Cohere request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+p.config.APIKey)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("cohere request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("cohere API returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode Cohere response: %w", err)
+	}
+	return nil
+}
+
+// cohereMessage is a message of a chat request
+type cohereMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// cohereChatRequest is the body of a /v2/chat request
+type cohereChatRequest struct {
+	Model       string          `json:"model"`
+	Messages    []cohereMessage `json:"messages"`
+	Temperature *float64        `json:"temperature,omitempty"`
+	MaxTokens   int             `json:"max_tokens,omitempty"`
+	Seed        *int            `json:"seed,omitempty"`
+}
+
+// cohereTokens counts the tokens of a chat request
+type cohereTokens struct {
+	InputTokens  float64 `json:"input_tokens"`
+	OutputTokens float64 `json:"output_tokens"`
+}
+
+// cohereChatResponse is the response of /v2/chat
+type cohereChatResponse struct {
+	FinishReason string `json:"finish_reason"`
+	Message      struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+	} `json:"message"`
+	Usage struct {
+		BilledUnits cohereTokens `json:"billed_units"`
+		Tokens      cohereTokens `json:"tokens"`
+	} `json:"usage"`
+}
+
+// Generate creates a prompt using Cohere's chat API
+func (p *CohereProvider) Generate(ctx context.Context, req GenerateRequest) (*GenerateResponse, error) {
+	model := p.model
+	if req.Model != "" {
+		model = req.Model
+	}
+	body := cohereChatRequest{Model: model, MaxTokens: req.MaxTokens, Seed: req.Seed}
+	if req.SystemPrompt != "" {
+		body.Messages = append(body.Messages, cohereMessage{Role: "system", Content: req.SystemPrompt})
+	}
+	for _, example := range req.Examples {
+		body.Messages = append(body.Messages,
+			cohereMessage{Role: "user", Content: example.Input},
+			cohereMessage{Role: "assistant", Content: example.Output})
+	}
+	body.Messages = append(body.Messages, cohereMessage{Role: "user", Content: req.Prompt})
+	if req.Temperature > 0 {
+		temperature := ClampTemperature(ProviderCohere, req.Temperature)
+		body.Temperature = &temperature
+	}
+
+	var response cohereChatResponse
+	if err := p.post(ctx, "/v2/chat", body, &response); err != nil {
+		return nil, asContextTooLong(ProviderCohere, model, fmt.Errorf("cohere API call failed: %w", err))
+	}
+
+	var content strings.Builder
+	for _, block := range response.Message.Content {
+		if block.Type == "text" {
+			content.WriteString(block.Text)
+		}
+	}
+	if content.Len() == 0 {
+		return nil, fmt.Errorf("no content in Cohere response (finish reason %q)", response.FinishReason)
+	}
+
+	// tokens counts what the model saw, billed_units what was charged
+	usage := response.Usage.Tokens
+	if usage.InputTokens == 0 && usage.OutputTokens == 0 {
+		usage = response.Usage.BilledUnits
+	}
+	return &GenerateResponse{
+		Content:      content.String(),
+		Model:        model,
+		TokensUsed:   int(usage.InputTokens + usage.OutputTokens),
+		InputTokens:  int(usage.InputTokens),
+		OutputTokens: int(usage.OutputTokens),
+	}, nil
+}
+
+// cohereEmbedRequest is the body of a /v2/embed request
+type cohereEmbedRequest struct {
+	Model          string   `json:"model"`
+	Texts          []string `json:"texts"`
+	InputType      string   `json:"input_type"`
+	EmbeddingTypes []string `json:"embedding_types"`
+}
+
+// cohereEmbedResponse is the response of /v2/embed
+type cohereEmbedResponse struct {
+	Embeddings struct {
+		Float [][]float32 `json:"float"`
+	} `json:"embeddings"`
+}
+
+// GetEmbedding embeds text with the configured embed-v3 model. Prompts and
+// search queries share one vector space, so both are embedded as
+// search_document.
+func (p *CohereProvider) GetEmbedding(ctx context.Context, text string, registry RegistryInterface) ([]float32, error) {
+	logger := log.FromContext(ctx).WithFields(logrus.Fields{
+		"provider": p.Name(),
+	})
+
+	var response cohereEmbedResponse
+	err := p.post(ctx, "/v2/embed", cohereEmbedRequest{
+		Model:          p.embeddingModel,
+		Texts:          []string{text},
+		InputType:      "search_document",
+		EmbeddingTypes: []string{"float"},
+	}, &response)
+	if err != nil {
+		logger.WithError(err).Error("CohereProvider: Failed to create embedding")
+		return nil, fmt.Errorf("failed to create embedding: %w", err)
+	}
+	if len(response.Embeddings.Float) == 0 || len(response.Embeddings.Float[0]) == 0 {
+		return nil, fmt.Errorf("no embedding data returned")
+	}
+
+	embedding := response.Embeddings.Float[0]
+	if dims := CohereEmbeddingDimensions(p.embeddingModel); dims > 0 && len(embedding) != dims {
+		return nil, fmt.Errorf("cohere model %s returned %d dimensions, expected %d", p.embeddingModel, len(embedding), dims)
+	}
+	logger.Debugf("CohereProvider: Successfully created embedding with length %d", len(embedding))
+
+	return embedding, nil
+}
+
+// cohereRerankRequest is the body of a /v2/rerank request
+type cohereRerankRequest struct {
+	Model     string   `json:"model"`
+	Query     string   `json:"query"`
+	Documents []string `json:"documents"`
+	TopN      int      `json:"top_n"`
+}
+
+// cohereRerankResponse is the response of /v2/rerank, ordered by relevance
+type cohereRerankResponse struct {
+	Results []struct {
+		Index          int     `json:"index"`
+		RelevanceScore float64 `json:"relevance_score"`
+	} `json:"results"`
+}
+
+// Rerank scores docs against query with the configured rerank model,
+// returning the scores in the order of docs
+func (p *CohereProvider) Rerank(ctx context.Context, query string, docs []string) ([]float64, error) {
+	if len(docs) == 0 {
+		return []float64{}, nil
+	}
+
+	var response cohereRerankResponse
+	err := p.post(ctx, "/v2/rerank", cohereRerankRequest{
+		Model:     p.rerankModel,
+		Query:     query,
+		Documents: docs,
+		TopN:      len(docs),
+	}, &response)
+	if err != nil {
+		return nil, fmt.Errorf("cohere rerank failed: %w", err)
+	}
+	if len(response.Results) != len(docs) {
+		return nil, fmt.Errorf("cohere rerank returned %d results for %d documents", len(response.Results), len(docs))
+	}
+
+	scores := make([]float64, len(docs))
+	for _, result := range response.Results {
+		if result.Index < 0 || result.Index >= len(docs) {
+			return nil, fmt.Errorf("cohere rerank returned unknown document index %d", result.Index)
+		}
+		scores[result.Index] = result.RelevanceScore
+	}
+	return scores, nil
+}
+
+// Name returns the provider name
+func (p *CohereProvider) Name() string {
+	return ProviderCohere
+}
+
+// IsAvailable checks if the provider is configured
+func (p *CohereProvider) IsAvailable() bool {
+	return p.config.APIKey != ""
+}
+
+// Ping times a models listing request
+func (p *CohereProvider) Ping(ctx context.Context) (time.Duration, error) {
+	if !p.IsAvailable() {
+		return 0, ErrNotConfigured
+	}
+	return pingHTTP(ctx, p.baseURL+"/v1/models", map[string]string{
+		"Authorization": "Bearer " + p.config.APIKey,
+	})
+}
+
+// ListModels lists the chat models of the Cohere API
+func (p *CohereProvider) ListModels(ctx context.Context) ([]string, error) {
+	if !p.IsAvailable() {
+		return nil, ErrNotConfigured
+	}
+	return listModelsHTTP(ctx, p.baseURL+"/v1/models?endpoint=chat&page_size=1000", map[string]string{
+		"Authorization": "Bearer " + p.config.APIKey,
+	})
+}
+
+// SupportsEmbeddings returns true, Cohere embeds with its own models
+func (p *CohereProvider) SupportsEmbeddings() bool {
+	return true
+}
+
+// SupportsStreaming returns false, only whole responses are requested
+func (p *CohereProvider) SupportsStreaming() bool {
+	return false
+}
@@ -0,0 +1,191 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newCohereServer mocks Cohere's v2 API, answering each path with the
+// given response and recording each request body by path
+func newCohereServer(t *testing.T, responses map[string]interface{}) (*httptest.Server, map[string][]map[string]interface{}) {
+	t.Helper()
+	requests := map[string][]map[string]interface{}{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		requests[r.URL.Path] = append(requests[r.URL.Path], body)
+
+		response, ok := responses[r.URL.Path]
+		if !ok {
+			http.Error(w, `{"message":"not found"}`, http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(server.Close)
+	return server, requests
+}
+
+func TestNewCohereProvider(t *testing.T) {
+	provider := NewCohereProvider(Config{APIKey: "test-key"})
+	assert.Equal(t, defaultCohereBaseURL, provider.baseURL)
+	assert.Equal(t, DefaultCohereModel, provider.model)
+	assert.Equal(t, DefaultCohereEmbeddingModel, provider.embeddingModel)
+	assert.Equal(t, DefaultCohereRerankModel, provider.rerankModel)
+
+	provider = NewCohereProvider(Config{APIKey: "test-key", BaseURL: "https://evil.example.com"})
+	assert.Equal(t, defaultCohereBaseURL, provider.baseURL, "disallowed base URL falls back to default")
+
+	assert.Equal(t, ProviderCohere, provider.Name())
+	assert.True(t, provider.IsAvailable())
+	assert.True(t, provider.SupportsEmbeddings())
+	assert.False(t, NewCohereProvider(Config{}).IsAvailable())
+}
+
+func TestCohereProvider_Generate(t *testing.T) {
+	server, requests := newCohereServer(t, map[string]interface{}{
+		"/v2/chat": map[string]interface{}{
+			"id":            "chat-1",
+			"finish_reason": "COMPLETE",
+			"message": map[string]interface{}{
+				"role":    "assistant",
+				"content": []interface{}{map[string]interface{}{"type": "text", "text": "hello there"}},
+			},
+			"usage": map[string]interface{}{
+				"billed_units": map[string]interface{}{"input_tokens": 10, "output_tokens": 4},
+				"tokens":       map[string]interface{}{"input_tokens": 12, "output_tokens": 4},
+			},
+		},
+	})
+	provider := NewCohereProvider(Config{APIKey: "test-key", BaseURL: server.URL})
+	seed := 7
+
+	resp, err := provider.Generate(context.Background(), GenerateRequest{
+		SystemPrompt: "Be brief",
+		Prompt:       "Hello",
+		Examples:     []Example{{Input: "Hi", Output: "Hey"}},
+		Temperature:  1.5,
+		MaxTokens:    100,
+		Seed:         &seed,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "hello there", resp.Content)
+	assert.Equal(t, DefaultCohereModel, resp.Model)
+	assert.Equal(t, 16, resp.TokensUsed)
+	assert.Equal(t, 12, resp.InputTokens)
+	assert.Equal(t, 4, resp.OutputTokens)
+
+	require.Len(t, requests["/v2/chat"], 1)
+	body := requests["/v2/chat"][0]
+	assert.Equal(t, DefaultCohereModel, body["model"])
+	assert.Equal(t, 1.0, body["temperature"], "temperature is clamped to Cohere's range")
+	assert.Equal(t, float64(100), body["max_tokens"])
+	assert.Equal(t, float64(7), body["seed"])
+	messages := body["messages"].([]interface{})
+	require.Len(t, messages, 4)
+	assert.Equal(t, "system", messages[0].(map[string]interface{})["role"])
+	assert.Equal(t, "Hello", messages[3].(map[string]interface{})["content"])
+
+	t.Run("context overflow", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, `{"message":"too many tokens: total number of tokens in the prompt cannot exceed 256000 - received 300000"}`, http.StatusBadRequest)
+		}))
+		defer server.Close()
+		provider := NewCohereProvider(Config{APIKey: "test-key", BaseURL: server.URL})
+
+		_, err := provider.Generate(context.Background(), GenerateRequest{Prompt: "Hello"})
+		var overflow *ContextTooLongError
+		require.ErrorAs(t, err, &overflow)
+		assert.Equal(t, 300000, overflow.InputTokens)
+		assert.Equal(t, 256000, overflow.ContextWindow)
+	})
+}
+
+func TestCohereProvider_GetEmbedding(t *testing.T) {
+	vector := make([]float32, CohereEmbeddingDimensions(DefaultCohereEmbeddingModel))
+	vector[0] = 0.5
+	server, requests := newCohereServer(t, map[string]interface{}{
+		"/v2/embed": map[string]interface{}{
+			"embeddings": map[string]interface{}{"float": [][]float32{vector}},
+		},
+	})
+	provider := NewCohereProvider(Config{APIKey: "test-key", BaseURL: server.URL})
+
+	embedding, err := provider.GetEmbedding(context.Background(), "a prompt", nil)
+	require.NoError(t, err)
+	assert.Len(t, embedding, 1024)
+	assert.Equal(t, float32(0.5), embedding[0])
+
+	require.Len(t, requests["/v2/embed"], 1)
+	body := requests["/v2/embed"][0]
+	assert.Equal(t, DefaultCohereEmbeddingModel, body["model"])
+	assert.Equal(t, []interface{}{"a prompt"}, body["texts"])
+	assert.Equal(t, "search_document", body["input_type"])
+	assert.Equal(t, []interface{}{"float"}, body["embedding_types"])
+
+	t.Run("wrong dimensions fail", func(t *testing.T) {
+		server, _ := newCohereServer(t, map[string]interface{}{
+			"/v2/embed": map[string]interface{}{
+				"embeddings": map[string]interface{}{"float": [][]float32{{0.1, 0.2}}},
+			},
+		})
+		provider := NewCohereProvider(Config{APIKey: "test-key", BaseURL: server.URL})
+
+		_, err := provider.GetEmbedding(context.Background(), "a prompt", nil)
+		assert.ErrorContains(t, err, "expected 1024")
+	})
+
+	t.Run("listed as embedding capable", func(t *testing.T) {
+		registry := NewRegistry()
+		require.NoError(t, registry.Register(ProviderCohere, provider))
+		assert.Equal(t, []string{ProviderCohere}, registry.ListEmbeddingCapableProviders())
+	})
+}
+
+func TestCohereProvider_Rerank(t *testing.T) {
+	server, requests := newCohereServer(t, map[string]interface{}{
+		"/v2/rerank": map[string]interface{}{
+			"results": []interface{}{
+				map[string]interface{}{"index": 2, "relevance_score": 0.9},
+				map[string]interface{}{"index": 0, "relevance_score": 0.4},
+				map[string]interface{}{"index": 1, "relevance_score": 0.1},
+			},
+		},
+	})
+	provider := NewCohereProvider(Config{APIKey: "test-key", BaseURL: server.URL})
+
+	scores, err := provider.Rerank(context.Background(), "parse CSV", []string{"a", "b", "c"})
+	require.NoError(t, err)
+	assert.Equal(t, []float64{0.4, 0.1, 0.9}, scores, "scores follow the order of the documents")
+
+	require.Len(t, requests["/v2/rerank"], 1)
+	body := requests["/v2/rerank"][0]
+	assert.Equal(t, DefaultCohereRerankModel, body["model"])
+	assert.Equal(t, "parse CSV", body["query"])
+	assert.Equal(t, float64(3), body["top_n"])
+
+	t.Run("missing results fail", func(t *testing.T) {
+		_, err := provider.Rerank(context.Background(), "parse CSV", []string{"a", "b"})
+		assert.ErrorContains(t, err, "3 results for 2 documents")
+	})
+
+	t.Run("found through the rate limiter", func(t *testing.T) {
+		limited := NewRateLimitedProvider(provider, RateLimitConfig{RPM: 60}, nil)
+		reranker, ok := AsReranker(limited)
+		require.True(t, ok)
+		scores, err := reranker.Rerank(context.Background(), "parse CSV", []string{"a", "b", "c"})
+		require.NoError(t, err)
+		assert.Len(t, scores, 3)
+
+		_, ok = AsReranker(NewRateLimitedProvider(NewDeepSeekProvider(Config{}), RateLimitConfig{RPM: 60}, nil))
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,119 @@
+package providers
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jonwraymond/prompt-alchemy/pkg/models"
+)
+
+// ErrContextTooLong is matched by every error a provider returns because
+// the request didn't fit the model's context window
+var ErrContextTooLong = errors.New("input exceeds the model's context window")
+
+// ContextTooLongError is a provider's context window overflow. Token counts
+// are taken from the provider's message when it gives them, the window
+// otherwise from the capability table; either is 0 when unknown.
+type ContextTooLongError struct {
+	Provider      string
+	Model         string
+	Phase         models.Phase // set by the engine
+	InputTokens   int
+	ContextWindow int
+	Err           error
+}
+
+func (e *ContextTooLongError) Error() string {
+	var msg strings.Builder
+	msg.WriteString(e.Provider)
+	if e.Model != "" {
+		msg.WriteString(" model " + e.Model)
+	}
+	if e.Phase != "" {
+		msg.WriteString(" (phase " + string(e.Phase) + ")")
+	}
+	switch {
+	case e.InputTokens > 0 && e.ContextWindow > 0:
+		fmt.Fprintf(&msg, ": input of %d tokens exceeds the %d-token context window", e.InputTokens, e.ContextWindow)
+	case e.ContextWindow > 0:
+		fmt.Fprintf(&msg, ": input exceeds the %d-token context window", e.ContextWindow)
+	default:
+		msg.WriteString(": input exceeds the context window")
+	}
+	return msg.String()
+}
+
+// Unwrap returns the provider's own error
+func (e *ContextTooLongError) Unwrap() error { return e.Err }
+
+// Is makes errors.Is(err, ErrContextTooLong) match
+func (e *ContextTooLongError) Is(target error) bool { return target == ErrContextTooLong }
+
+// OverflowTokens returns roughly how many tokens the input must lose to
+// fit, or 0 when the provider didn't report the input size
+func (e *ContextTooLongError) OverflowTokens() int {
+	if e.InputTokens == 0 || e.ContextWindow == 0 {
+		return 0
+	}
+	return max(e.InputTokens-e.ContextWindow, 0)
+}
+
+// contextOverflowPattern matches one provider's overflow message. The
+// input and window groups are the submatch indexes of the token counts, 0
+// when the message doesn't carry them.
+type contextOverflowPattern struct {
+	re            *regexp.Regexp
+	input, window int
+}
+
+// contextOverflowPatterns recognizes the overflow messages of the APIs we
+// call. OpenAI-compatible APIs (OpenAI, Azure, Grok, Mistral, DeepSeek,
+// OpenRouter) and Anthropic's (also served by Bedrock) cover most providers.
+var contextOverflowPatterns = []contextOverflowPattern{
+	// OpenAI: "This model's maximum context length is 8192 tokens. However, your messages resulted in 9000 tokens."
+	{regexp.MustCompile(`maximum context length is (\d+) tokens.*?(?:resulted in|requested) (\d+) tokens`), 2, 1},
+	// Grok: "This model's maximum prompt length is 131072 but the request contains 140000 tokens."
+	{regexp.MustCompile(`maximum prompt length is (\d+) but the request contains (\d+) tokens`), 2, 1},
+	// Anthropic and Bedrock: "prompt is too long: 210000 tokens > 200000 maximum"
+	{regexp.MustCompile(`prompt is too long: (\d+) tokens > (\d+) maximum`), 1, 2},
+	// Google: "The input token count (1200000) exceeds the maximum number of tokens allowed (1048576)."
+	{regexp.MustCompile(`input token count \((\d+)\) exceeds the maximum number of tokens allowed \((\d+)\)`), 1, 2},
+	// Mistral: "Prompt contains 40000 tokens and 0 draft tokens, too large for model with 32768 maximum context length"
+	{regexp.MustCompile(`(?i)prompt contains (\d+) tokens.*?too large for model with (\d+) maximum context length`), 1, 2},
+	// Cohere: "too many tokens: total number of tokens in the prompt cannot exceed 128000 - received 130000"
+	{regexp.MustCompile(`total number of tokens in the prompt cannot exceed (\d+) - received (\d+)`), 2, 1},
+	// Messages without token counts
+	{regexp.MustCompile(`(?i)context_length_exceeded|maximum context length|prompt is too long|input is too long|exceeds the context window`), 0, 0},
+}
+
+// asContextTooLong returns err as a *ContextTooLongError when its message
+// is a context window overflow, and unchanged otherwise
+func asContextTooLong(provider, model string, err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := err.Error()
+	for _, pattern := range contextOverflowPatterns {
+		match := pattern.re.FindStringSubmatch(msg)
+		if match == nil {
+			continue
+		}
+		overflow := &ContextTooLongError{Provider: provider, Model: model, Err: err}
+		if pattern.input > 0 {
+			overflow.InputTokens, _ = strconv.Atoi(match[pattern.input])
+		}
+		if pattern.window > 0 {
+			overflow.ContextWindow, _ = strconv.Atoi(match[pattern.window])
+		}
+		if overflow.ContextWindow == 0 {
+			if limits, ok := LookupModelLimits(provider, model); ok {
+				overflow.ContextWindow = limits.ContextWindow
+			}
+		}
+		return overflow
+	}
+	return err
+}
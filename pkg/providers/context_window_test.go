@@ -0,0 +1,105 @@
+package providers
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAsContextTooLong(t *testing.T) {
+	tests := []struct {
+		name       string
+		provider   string
+		model      string
+		message    string
+		wantInput  int
+		wantWindow int
+	}{
+		{
+			name:     "openai",
+			provider: ProviderOpenAI, model: "gpt-4",
+			message:   "This model's maximum context length is 8192 tokens. However, your messages resulted in 9000 tokens. Please reduce the length of the messages.",
+			wantInput: 9000, wantWindow: 8192,
+		},
+		{
+			name:     "openai with completion",
+			provider: ProviderAzure, model: "my-deployment",
+			message:   "This model's maximum context length is 8192 tokens. However, you requested 9100 tokens (8100 in the messages, 1000 in the completion).",
+			wantInput: 9100, wantWindow: 8192,
+		},
+		{
+			name:     "grok",
+			provider: ProviderGrok, model: "grok-2",
+			message:   "This model's maximum prompt length is 131072 but the request contains 140000 tokens.",
+			wantInput: 140000, wantWindow: 131072,
+		},
+		{
+			name:     "anthropic",
+			provider: ProviderAnthropic, model: "claude-3-5-sonnet-20241022",
+			message:   `{"type":"invalid_request_error","message":"prompt is too long: 210000 tokens > 200000 maximum"}`,
+			wantInput: 210000, wantWindow: 200000,
+		},
+		{
+			name:     "google",
+			provider: ProviderGoogle, model: "gemini-1.5-pro",
+			message:   "The input token count (2100000) exceeds the maximum number of tokens allowed (2097152).",
+			wantInput: 2100000, wantWindow: 2097152,
+		},
+		{
+			name:     "mistral",
+			provider: ProviderMistral, model: "mistral-small",
+			message:   "Prompt contains 40000 tokens and 0 draft tokens, too large for model with 32768 maximum context length",
+			wantInput: 40000, wantWindow: 32768,
+		},
+		{
+			name:     "cohere",
+			provider: ProviderCohere, model: "command-r-08-2024",
+			message:   `{"message":"too many tokens: total number of tokens in the prompt cannot exceed 128000 - received 130000. Try using a shorter prompt"}`,
+			wantInput: 130000, wantWindow: 128000,
+		},
+		{
+			name:     "window from the table",
+			provider: ProviderOpenAI, model: "gpt-4o",
+			message:    `error code: context_length_exceeded`,
+			wantWindow: 128000,
+		},
+		{
+			name:     "unknown window",
+			provider: ProviderOllama, model: "llama3",
+			message: "input is too long for the requested model",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := asContextTooLong(tt.provider, tt.model, fmt.Errorf("API call failed: %w", errors.New(tt.message)))
+			require.ErrorIs(t, err, ErrContextTooLong)
+			var overflow *ContextTooLongError
+			require.ErrorAs(t, err, &overflow)
+			assert.Equal(t, tt.provider, overflow.Provider)
+			assert.Equal(t, tt.model, overflow.Model)
+			assert.Equal(t, tt.wantInput, overflow.InputTokens)
+			assert.Equal(t, tt.wantWindow, overflow.ContextWindow)
+			assert.ErrorContains(t, overflow.Err, tt.message)
+		})
+	}
+
+	t.Run("other errors are unchanged", func(t *testing.T) {
+		original := errors.New("rate limit exceeded")
+		assert.Same(t, original, asContextTooLong(ProviderOpenAI, "gpt-4o", original))
+		assert.NoError(t, asContextTooLong(ProviderOpenAI, "gpt-4o", nil))
+	})
+}
+
+func TestContextTooLongError(t *testing.T) {
+	overflow := &ContextTooLongError{Provider: ProviderOpenAI, Model: "gpt-4", Phase: "solutio", InputTokens: 9000, ContextWindow: 8192}
+	assert.Equal(t, "openai model gpt-4 (phase solutio): input of 9000 tokens exceeds the 8192-token context window", overflow.Error())
+	assert.Equal(t, 808, overflow.OverflowTokens())
+
+	overflow = &ContextTooLongError{Provider: ProviderOllama}
+	assert.Equal(t, "ollama: input exceeds the context window", overflow.Error())
+	assert.Zero(t, overflow.OverflowTokens())
+}
@@ -0,0 +1,200 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jonwraymond/prompt-alchemy/internal/log"
+	"github.com/jonwraymond/prompt-alchemy/pkg/security"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultDeepSeekModel  = "deepseek-chat"
+	DeepSeekReasonerModel = "deepseek-reasoner"
+
+	defaultDeepSeekBaseURL = "https://api.deepseek.com"
+)
+
+// DeepSeekProvider implements the Provider interface for DeepSeek using the
+// OpenAI-compatible SDK, since DeepSeek's chat API shares its shape.
+//
+// deepseek-reasoner ignores sampling parameters and returns its chain of
+// thought in a separate reasoning_content field; only content is the answer.
+// DeepSeek has no embeddings API, so embedding requests are delegated to the
+// standardized provider.
+type DeepSeekProvider struct {
+	client  openai.Client
+	config  Config
+	baseURL string
+}
+
+// NewDeepSeekProvider creates a new DeepSeek provider
+func NewDeepSeekProvider(config Config) *DeepSeekProvider {
+	baseURL := config.BaseURL
+	if baseURL == "" {
+		baseURL = defaultDeepSeekBaseURL
+	}
+
+	// Validate the base URL for security
+	if err := security.ValidateBaseURL(baseURL); err != nil {
+		log.GetLogger().Errorf("Invalid base URL for DeepSeek provider: %v", err)
+		baseURL = defaultDeepSeekBaseURL
+	}
+
+	opts := []option.RequestOption{
+		option.WithAPIKey(config.APIKey),
+		option.WithBaseURL(baseURL),
+	}
+
+	client := openai.NewClient(opts...)
+
+	return &DeepSeekProvider{
+		client:  client,
+		config:  config,
+		baseURL: baseURL,
+	}
+}
+
+// isDeepSeekReasoner reports whether model is one of DeepSeek's reasoning models
+func isDeepSeekReasoner(model string) bool {
+	return strings.HasPrefix(model, DeepSeekReasonerModel)
+}
+
+// Generate creates a prompt using DeepSeek's chat completions API
+func (p *DeepSeekProvider) Generate(ctx context.Context, req GenerateRequest) (*GenerateResponse, error) {
+	logger := log.FromContext(ctx).WithFields(logrus.Fields{
+		"provider": p.Name(),
+	})
+	messages := []openai.ChatCompletionMessageParamUnion{}
+
+	// Add system prompt if provided
+	if req.SystemPrompt != "" {
+		messages = append(messages, openai.SystemMessage(req.SystemPrompt))
+	}
+
+	// Add examples if provided
+	for _, example := range req.Examples {
+		messages = append(messages, openai.UserMessage(example.Input))
+		messages = append(messages, openai.AssistantMessage(example.Output))
+	}
+
+	messages = append(messages, openai.UserMessage(req.Prompt))
+
+	model := p.config.Model
+	if req.Model != "" {
+		model = req.Model
+	}
+	if model == "" {
+		model = DefaultDeepSeekModel
+	}
+	reasoner := isDeepSeekReasoner(model)
+
+	params := openai.ChatCompletionNewParams{
+		Model:    openai.ChatModel(model),
+		Messages: messages,
+	}
+	// The reasoner accepts but ignores temperature; don't send it
+	if req.Temperature > 0 && !reasoner {
+		params.Temperature = openai.Float(req.Temperature)
+	}
+	if req.MaxTokens > 0 {
+		params.MaxTokens = openai.Int(int64(req.MaxTokens))
+	}
+
+	response, err := p.client.Chat.Completions.New(ctx, params)
+	if err != nil {
+		return nil, asContextTooLong(ProviderDeepSeek, model, fmt.Errorf("deepseek API call failed: %w", err))
+	}
+
+	if len(response.Choices) == 0 {
+		return nil, fmt.Errorf("no choices returned from DeepSeek API")
+	}
+
+	message := response.Choices[0].Message
+	if reasoner {
+		reasoning := deepSeekReasoningContent(message.RawJSON())
+		logger.Debugf("DeepSeekProvider: Reasoning of %d characters discarded", len(reasoning))
+		// Reasoning counts against max_tokens; when it used them all there is no answer
+		if message.Content == "" && reasoning != "" {
+			return nil, fmt.Errorf("deepseek model %s spent max_tokens on reasoning before answering", model)
+		}
+	}
+
+	genResponse := &GenerateResponse{
+		Content: message.Content,
+		Model:   model,
+	}
+	if response.Usage.TotalTokens > 0 {
+		genResponse.TokensUsed = int(response.Usage.TotalTokens)
+		genResponse.InputTokens = int(response.Usage.PromptTokens)
+		genResponse.OutputTokens = int(response.Usage.CompletionTokens)
+	}
+
+	return genResponse, nil
+}
+
+// deepSeekReasoningContent returns the reasoning_content the OpenAI SDK
+// doesn't know about from a raw response message
+func deepSeekReasoningContent(raw string) string {
+	var message struct {
+		ReasoningContent string `json:"reasoning_content"`
+	}
+	if raw == "" || json.Unmarshal([]byte(raw), &message) != nil {
+		return ""
+	}
+	return message.ReasoningContent
+}
+
+// GetEmbedding delegates to the standardized provider, DeepSeek has no
+// embeddings API
+func (p *DeepSeekProvider) GetEmbedding(ctx context.Context, text string, registry RegistryInterface) ([]float32, error) {
+	log.FromContext(ctx).WithField("provider", p.Name()).Info("DeepSeekProvider delegating embedding to standardized provider")
+	return getStandardizedEmbedding(ctx, text, registry)
+}
+
+// Name returns the provider name
+func (p *DeepSeekProvider) Name() string {
+	return ProviderDeepSeek
+}
+
+// IsAvailable checks if the provider is configured
+func (p *DeepSeekProvider) IsAvailable() bool {
+	return p.config.APIKey != ""
+}
+
+// Ping times a models listing request
+func (p *DeepSeekProvider) Ping(ctx context.Context) (time.Duration, error) {
+	if !p.IsAvailable() {
+		return 0, ErrNotConfigured
+	}
+	return pingHTTP(ctx, strings.TrimSuffix(p.baseURL, "/")+"/models", map[string]string{
+		"Authorization": "Bearer " + p.config.APIKey,
+	})
+}
+
+// ListModels lists the models of the DeepSeek API
+func (p *DeepSeekProvider) ListModels(ctx context.Context) ([]string, error) {
+	if !p.IsAvailable() {
+		return nil, ErrNotConfigured
+	}
+	return listModelsHTTP(ctx, strings.TrimSuffix(p.baseURL, "/")+"/models", map[string]string{
+		"Authorization": "Bearer " + p.config.APIKey,
+	})
+}
+
+// SupportsEmbeddings returns false, DeepSeek has no embeddings API
+func (p *DeepSeekProvider) SupportsEmbeddings() bool {
+	return false
+}
+
+// SupportsStreaming checks if the provider supports streaming generation
+func (p *DeepSeekProvider) SupportsStreaming() bool {
+	return true
+}
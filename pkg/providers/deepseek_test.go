@@ -0,0 +1,141 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newDeepSeekServer mocks DeepSeek's chat completions API, answering with
+// the given message and recording each request body
+func newDeepSeekServer(t *testing.T, message map[string]interface{}) (*httptest.Server, *[]map[string]interface{}) {
+	t.Helper()
+	var requests []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		requests = append(requests, body)
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":      "chatcmpl-1",
+			"object":  "chat.completion",
+			"created": 1700000000,
+			"model":   body["model"],
+			"choices": []interface{}{map[string]interface{}{
+				"index":         0,
+				"message":       message,
+				"finish_reason": "stop",
+			}},
+			"usage": map[string]interface{}{
+				"prompt_tokens":             12,
+				"completion_tokens":         40,
+				"total_tokens":              52,
+				"completion_tokens_details": map[string]interface{}{"reasoning_tokens": 30},
+			},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestNewDeepSeekProvider(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      Config
+		wantBaseURL string
+	}{
+		{
+			name:        "basic config",
+			config:      Config{APIKey: "test-key"},
+			wantBaseURL: defaultDeepSeekBaseURL,
+		},
+		{
+			name:        "config with model",
+			config:      Config{APIKey: "test-key", Model: DeepSeekReasonerModel},
+			wantBaseURL: defaultDeepSeekBaseURL,
+		},
+		{
+			name:        "disallowed base URL falls back to default",
+			config:      Config{APIKey: "test-key", BaseURL: "https://evil.example.com/v1"},
+			wantBaseURL: defaultDeepSeekBaseURL,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := NewDeepSeekProvider(tt.config)
+			assert.NotNil(t, provider)
+			assert.Equal(t, tt.config, provider.config)
+			assert.Equal(t, tt.wantBaseURL, provider.baseURL)
+		})
+	}
+}
+
+func TestDeepSeekProvider_Basics(t *testing.T) {
+	provider := NewDeepSeekProvider(Config{APIKey: "test-key"})
+	assert.Equal(t, ProviderDeepSeek, provider.Name())
+	assert.True(t, provider.IsAvailable())
+	assert.False(t, provider.SupportsEmbeddings())
+	assert.False(t, NewDeepSeekProvider(Config{}).IsAvailable())
+}
+
+func TestDeepSeekProvider_Generate(t *testing.T) {
+	ctx := context.Background()
+	req := GenerateRequest{Prompt: "Hello", Temperature: 0.7, MaxTokens: 100}
+
+	t.Run("reasoner returns the answer, not the reasoning", func(t *testing.T) {
+		server, requests := newDeepSeekServer(t, map[string]interface{}{
+			"role":              "assistant",
+			"content":           "final answer",
+			"reasoning_content": "let me think about this...",
+		})
+		provider := NewDeepSeekProvider(Config{APIKey: "test-key", BaseURL: server.URL, Model: DeepSeekReasonerModel})
+
+		resp, err := provider.Generate(ctx, req)
+		require.NoError(t, err)
+		assert.Equal(t, "final answer", resp.Content)
+		assert.Equal(t, DeepSeekReasonerModel, resp.Model)
+		assert.Equal(t, 52, resp.TokensUsed)
+		assert.Equal(t, 40, resp.OutputTokens)
+
+		require.Len(t, *requests, 1)
+		assert.NotContains(t, (*requests)[0], "temperature")
+		assert.Equal(t, float64(100), (*requests)[0]["max_tokens"])
+	})
+
+	t.Run("reasoner that used max_tokens reasoning fails", func(t *testing.T) {
+		server, _ := newDeepSeekServer(t, map[string]interface{}{
+			"role":              "assistant",
+			"content":           "",
+			"reasoning_content": "still thinking...",
+		})
+		provider := NewDeepSeekProvider(Config{APIKey: "test-key", BaseURL: server.URL, Model: DeepSeekReasonerModel})
+
+		resp, err := provider.Generate(ctx, req)
+		assert.Nil(t, resp)
+		assert.ErrorContains(t, err, "max_tokens")
+	})
+
+	t.Run("chat sends temperature", func(t *testing.T) {
+		server, requests := newDeepSeekServer(t, map[string]interface{}{
+			"role":    "assistant",
+			"content": "hello there",
+		})
+		provider := NewDeepSeekProvider(Config{APIKey: "test-key", BaseURL: server.URL})
+
+		resp, err := provider.Generate(ctx, req)
+		require.NoError(t, err)
+		assert.Equal(t, "hello there", resp.Content)
+		assert.Equal(t, DefaultDeepSeekModel, resp.Model)
+
+		require.Len(t, *requests, 1)
+		assert.Equal(t, 0.7, (*requests)[0]["temperature"])
+		assert.Equal(t, DefaultDeepSeekModel, (*requests)[0]["model"])
+	})
+}
@@ -3,6 +3,7 @@ package providers
 import (
 	"context"
 	"fmt"
+	"sort"
 
 	log "github.com/jonwraymond/prompt-alchemy/internal/log"
 )
@@ -12,7 +13,7 @@ import (
 // All providers delegate embedding requests to OpenAI text-embedding-3-small (1536d)
 // for maximum search coverage and dimensional compatibility
 func getStandardizedEmbedding(ctx context.Context, text string, registry RegistryInterface) ([]float32, error) {
-	logger := log.GetLogger()
+	logger := log.FromContext(ctx)
 
 	if registry == nil {
 		logger.Error("Registry is nil for standardized embeddings")
@@ -37,3 +38,38 @@ func getStandardizedEmbedding(ctx context.Context, text string, registry Registr
 
 	return provider.GetEmbedding(ctx, text, registry)
 }
+
+// NewQueryEmbedder returns a function that embeds search queries with the
+// first embedding-capable provider (by name) in the registry. It returns nil
+// when no such provider is registered so callers can skip semantic search.
+func NewQueryEmbedder(registry RegistryInterface) func(ctx context.Context, text string) ([]float32, error) {
+	if registry == nil || len(registry.ListEmbeddingCapableProviders()) == 0 {
+		return nil
+	}
+
+	return func(ctx context.Context, text string) ([]float32, error) {
+		name := QueryEmbedderName(registry)
+		if name == "" {
+			return nil, fmt.Errorf("no embedding-capable provider available")
+		}
+		provider, err := registry.Get(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get embedding provider: %w", err)
+		}
+		return provider.GetEmbedding(ctx, text, registry)
+	}
+}
+
+// QueryEmbedderName returns the provider NewQueryEmbedder currently embeds
+// with, or "" when there is none
+func QueryEmbedderName(registry RegistryInterface) string {
+	if registry == nil {
+		return ""
+	}
+	capable := registry.ListEmbeddingCapableProviders()
+	if len(capable) == 0 {
+		return ""
+	}
+	sort.Strings(capable)
+	return capable[0]
+}
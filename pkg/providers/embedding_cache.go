@@ -0,0 +1,198 @@
+package providers
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// EmbeddingCacheConfig bounds the registry's embedding cache
+type EmbeddingCacheConfig struct {
+	Size int           // entries kept, the cache is off if not positive
+	TTL  time.Duration // how long an entry is reused, forever if zero
+}
+
+// LoadEmbeddingCacheConfig reads embeddings.cache.size and
+// embeddings.cache.ttl. embeddings.cache_embeddings set to false turns the
+// cache off whatever its size.
+func LoadEmbeddingCacheConfig() EmbeddingCacheConfig {
+	if viper.IsSet("embeddings.cache_embeddings") && !viper.GetBool("embeddings.cache_embeddings") {
+		return EmbeddingCacheConfig{}
+	}
+	return EmbeddingCacheConfig{
+		Size: viper.GetInt("embeddings.cache.size"),
+		TTL:  max(viper.GetDuration("embeddings.cache.ttl"), 0),
+	}
+}
+
+// EmbeddingCacheStats counts an embedding cache's lookups
+type EmbeddingCacheStats struct {
+	Entries int    `json:"entries"`
+	Hits    uint64 `json:"hits"`
+	Misses  uint64 `json:"misses"`
+}
+
+// embeddingCacheKey identifies an embedding by the provider and model that
+// computed it and a hash of the text, so long texts aren't kept in memory
+type embeddingCacheKey struct {
+	provider string
+	model    string
+	text     [sha256.Size]byte
+}
+
+type embeddingCacheEntry struct {
+	key       embeddingCacheKey
+	embedding []float32
+	expires   time.Time // zero when the entry never expires
+}
+
+// EmbeddingCache is a least recently used cache of embeddings, safe for
+// concurrent use. Semantic search and optimization embed the same queries
+// and inputs again and again; a hit saves the provider call.
+type EmbeddingCache struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	entries map[embeddingCacheKey]*list.Element
+	order   *list.List // most recently used first
+	hits    uint64
+	misses  uint64
+	observe func(hit bool)
+}
+
+// NewEmbeddingCache creates a cache of config.Size entries, or returns nil
+// when the size isn't positive. observe, if set, is told the outcome of
+// each lookup.
+func NewEmbeddingCache(config EmbeddingCacheConfig, observe func(hit bool)) *EmbeddingCache {
+	if config.Size <= 0 {
+		return nil
+	}
+	return &EmbeddingCache{
+		size:    config.Size,
+		ttl:     config.TTL,
+		entries: make(map[embeddingCacheKey]*list.Element),
+		order:   list.New(),
+		observe: observe,
+	}
+}
+
+type embeddingCacheBypassKey struct{}
+
+// WithoutEmbeddingCache returns a context whose embedding requests skip the
+// cache lookup and always reach the provider. The fresh embedding still
+// replaces the cached one.
+func WithoutEmbeddingCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, embeddingCacheBypassKey{}, true)
+}
+
+// Embed returns the cached embedding of text by provider, or has provider
+// compute and cache it. Failed requests aren't cached.
+func (c *EmbeddingCache) Embed(ctx context.Context, provider Provider, text string, registry RegistryInterface) ([]float32, error) {
+	key := embeddingCacheKey{
+		provider: provider.Name(),
+		model:    embeddingCacheModel(provider.Name()),
+		text:     sha256.Sum256([]byte(text)),
+	}
+	if bypass, _ := ctx.Value(embeddingCacheBypassKey{}).(bool); !bypass {
+		if embedding, ok := c.get(key); ok {
+			return embedding, nil
+		}
+	}
+
+	embedding, err := provider.GetEmbedding(ctx, text, registry)
+	if err != nil {
+		return nil, err
+	}
+	c.put(key, embedding)
+	return embedding, nil
+}
+
+// Stats returns the number of cached embeddings and the lookups so far
+func (c *EmbeddingCache) Stats() EmbeddingCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return EmbeddingCacheStats{Entries: c.order.Len(), Hits: c.hits, Misses: c.misses}
+}
+
+func (c *EmbeddingCache) get(key embeddingCacheKey) ([]float32, bool) {
+	c.mu.Lock()
+	element, ok := c.entries[key]
+	if ok {
+		entry := element.Value.(*embeddingCacheEntry)
+		if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+			c.order.Remove(element)
+			delete(c.entries, key)
+			ok = false
+		} else {
+			c.order.MoveToFront(element)
+		}
+	}
+	var embedding []float32
+	if ok {
+		c.hits++
+		embedding = element.Value.(*embeddingCacheEntry).embedding
+	} else {
+		c.misses++
+	}
+	c.mu.Unlock()
+
+	if c.observe != nil {
+		c.observe(ok)
+	}
+	// Callers may modify the embedding they get
+	return append([]float32(nil), embedding...), ok
+}
+
+func (c *EmbeddingCache) put(key embeddingCacheKey, embedding []float32) {
+	entry := &embeddingCacheEntry{key: key, embedding: append([]float32(nil), embedding...)}
+	if c.ttl > 0 {
+		entry.expires = time.Now().Add(c.ttl)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[key]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*embeddingCacheEntry).key)
+	}
+}
+
+// embeddingCacheModel returns the embedding model configured for provider,
+// so changing it doesn't serve embeddings of the previous model
+func embeddingCacheModel(provider string) string {
+	if model := viper.GetString("providers." + provider + ".embedding_model"); model != "" {
+		return model
+	}
+	if model := viper.GetString("embeddings.model"); model != "" {
+		return model
+	}
+	return viper.GetString("generation.default_embedding_model")
+}
+
+// EmbeddingCachedProvider serves a provider's embeddings from a shared
+// EmbeddingCache, leaving its other calls alone
+type EmbeddingCachedProvider struct {
+	Provider
+	cache *EmbeddingCache
+}
+
+// NewEmbeddingCachedProvider wraps provider to embed through cache
+func NewEmbeddingCachedProvider(provider Provider, cache *EmbeddingCache) *EmbeddingCachedProvider {
+	return &EmbeddingCachedProvider{Provider: provider, cache: cache}
+}
+
+// GetEmbedding returns the cached embedding of text, computing it on a miss
+func (p *EmbeddingCachedProvider) GetEmbedding(ctx context.Context, text string, registry RegistryInterface) ([]float32, error) {
+	return p.cache.Embed(ctx, p.Provider, text, registry)
+}
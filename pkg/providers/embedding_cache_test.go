@@ -0,0 +1,110 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbeddingCache(t *testing.T) {
+	defer viper.Reset()
+	viper.Set("embeddings.cache.size", 2)
+
+	var calls atomic.Int32
+	embedder := &TestProvider{
+		name:               "embedder",
+		available:          true,
+		supportsEmbeddings: true,
+		embeddingFunc: func(ctx context.Context, text string, registry RegistryInterface) ([]float32, error) {
+			calls.Add(1)
+			if text == "fail" {
+				return nil, errors.New("provider unavailable")
+			}
+			return []float32{float32(len(text)), 0.5}, nil
+		},
+	}
+	registry := NewRegistry()
+	var hits, misses int
+	registry.SetEmbeddingCacheObserver(func(hit bool) {
+		if hit {
+			hits++
+		} else {
+			misses++
+		}
+	})
+	require.NoError(t, registry.Register("embedder", embedder))
+	provider, err := registry.Get("embedder")
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	t.Run("repeated text hits", func(t *testing.T) {
+		first, err := provider.GetEmbedding(ctx, "sort a list", registry)
+		require.NoError(t, err)
+		second, err := provider.GetEmbedding(ctx, "sort a list", registry)
+		require.NoError(t, err)
+		assert.Equal(t, first, second)
+		assert.Equal(t, int32(1), calls.Load())
+		assert.Equal(t, 1, hits)
+		assert.Equal(t, 1, misses)
+	})
+
+	t.Run("different text misses", func(t *testing.T) {
+		_, err := provider.GetEmbedding(ctx, "parse a date", registry)
+		require.NoError(t, err)
+		assert.Equal(t, int32(2), calls.Load())
+		assert.Equal(t, 2, misses)
+	})
+
+	t.Run("bypassed per call", func(t *testing.T) {
+		_, err := provider.GetEmbedding(WithoutEmbeddingCache(ctx), "sort a list", registry)
+		require.NoError(t, err)
+		assert.Equal(t, int32(3), calls.Load())
+	})
+
+	t.Run("least recently used is evicted", func(t *testing.T) {
+		// The cache holds two entries, so a third evicts "parse a date", used least recently
+		_, err := provider.GetEmbedding(ctx, "render a chart", registry)
+		require.NoError(t, err)
+		calls.Store(0)
+		_, _ = provider.GetEmbedding(ctx, "sort a list", registry)
+		assert.Equal(t, int32(0), calls.Load())
+		_, _ = provider.GetEmbedding(ctx, "parse a date", registry)
+		assert.Equal(t, int32(1), calls.Load(), "evicted to make room")
+	})
+
+	t.Run("errors are not cached", func(t *testing.T) {
+		calls.Store(0)
+		_, err := provider.GetEmbedding(ctx, "fail", registry)
+		require.Error(t, err)
+		_, err = provider.GetEmbedding(ctx, "fail", registry)
+		require.Error(t, err)
+		assert.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("entries expire", func(t *testing.T) {
+		cache := NewEmbeddingCache(EmbeddingCacheConfig{Size: 10, TTL: 20 * time.Millisecond}, nil)
+		calls.Store(0)
+		_, _ = cache.Embed(ctx, embedder, "sort a list", nil)
+		_, _ = cache.Embed(ctx, embedder, "sort a list", nil)
+		assert.Equal(t, int32(1), calls.Load())
+		time.Sleep(30 * time.Millisecond)
+		_, _ = cache.Embed(ctx, embedder, "sort a list", nil)
+		assert.Equal(t, int32(2), calls.Load())
+		assert.Equal(t, EmbeddingCacheStats{Entries: 1, Hits: 1, Misses: 2}, cache.Stats())
+	})
+
+	t.Run("off by default", func(t *testing.T) {
+		viper.Reset()
+		registry := NewRegistry()
+		require.NoError(t, registry.Register("embedder", embedder))
+		provider, err := registry.Get("embedder")
+		require.NoError(t, err)
+		assert.Same(t, embedder, provider)
+	})
+}
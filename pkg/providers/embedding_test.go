@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGetStandardizedEmbedding(t *testing.T) {
@@ -92,3 +93,55 @@ func (m *mockRegistry) ListAvailable() []string {
 func (m *mockRegistry) ListEmbeddingCapableProviders() []string {
 	return []string{}
 }
+
+func TestNewQueryEmbedder(t *testing.T) {
+	t.Run("no embedding provider", func(t *testing.T) {
+		assert.Nil(t, NewQueryEmbedder(nil))
+
+		registry := NewRegistry()
+		require.NoError(t, registry.Register("plain", &TestProvider{name: "plain", available: true}))
+		assert.Nil(t, NewQueryEmbedder(registry), "callers skip semantic search")
+		assert.Empty(t, QueryEmbedderName(registry))
+	})
+
+	t.Run("embeds with the first capable provider by name", func(t *testing.T) {
+		registry := NewRegistry()
+		for _, name := range []string{"zeta", "alpha"} {
+			require.NoError(t, registry.Register(name, &TestProvider{
+				name:               name,
+				available:          true,
+				supportsEmbeddings: true,
+				embeddingFunc: func(ctx context.Context, text string, registry RegistryInterface) ([]float32, error) {
+					if name == "alpha" {
+						return []float32{1, 0}, nil
+					}
+					return []float32{0, 1}, nil
+				},
+			}))
+		}
+
+		embed := NewQueryEmbedder(registry)
+		require.NotNil(t, embed)
+		assert.Equal(t, "alpha", QueryEmbedderName(registry))
+		embedding, err := embed(context.Background(), "query")
+		require.NoError(t, err)
+		assert.Equal(t, []float32{1, 0}, embedding)
+	})
+
+	t.Run("provider errors are returned", func(t *testing.T) {
+		registry := NewRegistry()
+		require.NoError(t, registry.Register("failing", &TestProvider{
+			name:               "failing",
+			available:          true,
+			supportsEmbeddings: true,
+			embeddingFunc: func(ctx context.Context, text string, registry RegistryInterface) ([]float32, error) {
+				return nil, errors.New("embedding quota exceeded")
+			},
+		}))
+
+		embed := NewQueryEmbedder(registry)
+		require.NotNil(t, embed)
+		_, err := embed(context.Background(), "query")
+		assert.ErrorContains(t, err, "embedding quota exceeded")
+	})
+}
@@ -0,0 +1,46 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// Error classes returned by ErrorClass, used as a metric label
+const (
+	ErrorClassRateLimited    = "rate_limited"
+	ErrorClassContextTooLong = "context_too_long"
+	ErrorClassCircuitOpen    = "circuit_open"
+	ErrorClassNotConfigured  = "not_configured"
+	ErrorClassUnauthorized   = "unauthorized"
+	ErrorClassTimeout        = "timeout"
+	ErrorClassCanceled       = "canceled"
+	ErrorClassOther          = "other"
+)
+
+// ErrorClass sorts a provider error into one of a few classes, so errors can
+// be counted without a label per message
+func ErrorClass(err error) string {
+	var netErr net.Error
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrRateLimited):
+		return ErrorClassRateLimited
+	case errors.Is(err, ErrContextTooLong):
+		return ErrorClassContextTooLong
+	case errors.Is(err, ErrCircuitOpen):
+		return ErrorClassCircuitOpen
+	case errors.Is(err, ErrNotConfigured):
+		return ErrorClassNotConfigured
+	case statusCode(err) == http.StatusUnauthorized || statusCode(err) == http.StatusForbidden:
+		return ErrorClassUnauthorized
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorClassTimeout
+	case errors.Is(err, context.Canceled):
+		return ErrorClassCanceled
+	default:
+		return ErrorClassOther
+	}
+}
@@ -0,0 +1,30 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorClass(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{nil, ""},
+		{fmt.Errorf("openai: %w", ErrRateLimited), ErrorClassRateLimited},
+		{&ContextTooLongError{Provider: ProviderOpenAI}, ErrorClassContextTooLong},
+		{fmt.Errorf("provider generation failed: %w", ErrCircuitOpen), ErrorClassCircuitOpen},
+		{ErrNotConfigured, ErrorClassNotConfigured},
+		{errors.New("authentication failed: status code 401"), ErrorClassUnauthorized},
+		{fmt.Errorf("request failed: %w", context.DeadlineExceeded), ErrorClassTimeout},
+		{context.Canceled, ErrorClassCanceled},
+		{errors.New("openai API returned status 500"), ErrorClassOther},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, ErrorClass(tt.err), "%v", tt.err)
+	}
+}
@@ -0,0 +1,261 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	log "github.com/jonwraymond/prompt-alchemy/internal/log"
+	"github.com/ollama/ollama/api"
+	"github.com/openai/openai-go"
+	"github.com/spf13/viper"
+)
+
+// Defaults for retrying transient provider errors
+const (
+	DefaultGenerateRetries   = 2
+	DefaultGenerateBaseDelay = 500 * time.Millisecond
+)
+
+// settingsKeys are entries under "providers" in the config that hold shared
+// settings rather than a provider's configuration
+var settingsKeys = map[string]bool{
+	"fallback_chain": true,
+	"health":         true,
+	"retry":          true,
+	"shadow":         true,
+}
+
+// IsSettingsKey reports whether a key under "providers" is a shared setting,
+// such as fallback_chain, rather than the name of a provider
+func IsSettingsKey(name string) bool {
+	return settingsKeys[name]
+}
+
+// RetryConfig controls how generation requests are retried on transient errors
+type RetryConfig struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+}
+
+// LoadRetryConfig reads providers.retry.max_retries and
+// providers.retry.base_delay, falling back to the defaults when unset
+func LoadRetryConfig() RetryConfig {
+	cfg := RetryConfig{
+		MaxRetries: DefaultGenerateRetries,
+		BaseDelay:  DefaultGenerateBaseDelay,
+	}
+	if viper.IsSet("providers.retry.max_retries") {
+		cfg.MaxRetries = max(viper.GetInt("providers.retry.max_retries"), 0)
+	}
+	if delay := viper.GetDuration("providers.retry.base_delay"); delay > 0 {
+		cfg.BaseDelay = delay
+	}
+	return cfg
+}
+
+// FallbackChain returns the providers.fallback_chain entries to try after
+// primary, in configured order
+func FallbackChain(primary string) []string {
+	var chain []string
+	for _, name := range viper.GetStringSlice("providers.fallback_chain") {
+		if name != primary {
+			chain = append(chain, name)
+		}
+	}
+	return chain
+}
+
+// statusCodePattern matches the status codes in our own HTTP errors and in
+// SDK errors that don't expose a typed status
+var statusCodePattern = regexp.MustCompile(`(?:status code|Error) (\d{3})\b`)
+
+// IsTransientError reports whether err is worth retrying: rate limiting
+// (429 or our own ErrRateLimited), a 500, 502 or 503 from the provider, a
+// timeout, or an open circuit breaker
+func IsTransientError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrRateLimited) || errors.Is(err, ErrCircuitOpen) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	switch statusCode(err) {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable:
+		return true
+	default:
+		return false
+	}
+}
+
+// statusCode extracts the HTTP status code carried by a provider error, or 0
+func statusCode(err error) int {
+	var openaiErr *openai.Error
+	if errors.As(err, &openaiErr) {
+		return openaiErr.StatusCode
+	}
+	var anthropicErr *anthropic.Error
+	if errors.As(err, &anthropicErr) {
+		return anthropicErr.StatusCode
+	}
+	var ollamaErr api.StatusError
+	if errors.As(err, &ollamaErr) {
+		return ollamaErr.StatusCode
+	}
+	if match := statusCodePattern.FindStringSubmatch(err.Error()); match != nil {
+		code, _ := strconv.Atoi(match[1])
+		return code
+	}
+	return 0
+}
+
+// GenerateWithRetry calls provider.Generate, retrying transient errors with
+// exponential backoff. It gives up early once ctx is done.
+func GenerateWithRetry(ctx context.Context, provider Provider, req GenerateRequest, cfg RetryConfig) (*GenerateResponse, error) {
+	logger := log.FromContext(ctx).WithField("provider", provider.Name())
+
+	for attempt := 0; ; attempt++ {
+		resp, err := provider.Generate(ctx, req)
+		if err == nil || attempt >= cfg.MaxRetries || ctx.Err() != nil || !IsTransientError(err) {
+			return resp, err
+		}
+
+		wait := cfg.BaseDelay << attempt
+		logger.WithError(err).WithField("attempt", attempt+1).Warnf("Transient provider error, retrying in %s", wait)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// FallbackProvider generates with the first provider in its chain, retrying
+// transient errors and then cascading to the next provider. Embeddings,
+// streaming support and Ping are always answered by the primary provider.
+type FallbackProvider struct {
+	chain []Provider
+	retry RetryConfig
+	allow func(name string) bool // if set, providers it refuses are skipped
+}
+
+// NewFallbackProvider creates a provider that tries primary, then each fallback in order
+func NewFallbackProvider(retry RetryConfig, primary Provider, fallbacks ...Provider) *FallbackProvider {
+	return &FallbackProvider{
+		chain: append([]Provider{primary}, fallbacks...),
+		retry: retry,
+	}
+}
+
+// Generate tries each provider in the chain until one succeeds. Only
+// transient errors cascade; any other error is returned as is. The response
+// records which provider served it and, after a fallback, which one failed.
+func (p *FallbackProvider) Generate(ctx context.Context, req GenerateRequest) (*GenerateResponse, error) {
+	logger := log.FromContext(ctx)
+	primary := p.chain[0].Name()
+
+	var lastErr error
+	for i, provider := range p.chain {
+		if p.allow != nil && !p.allow(provider.Name()) {
+			logger.WithField("provider", provider.Name()).Debug("Skipping provider with open circuit breaker")
+			lastErr = circuitOpenError(provider.Name())
+			continue
+		}
+		// A fallback may accept a narrower temperature range than the primary
+		attempt := req
+		attempt.Temperature = ClampTemperature(provider.Name(), req.Temperature)
+		resp, err := GenerateWithRetry(ctx, provider, attempt, p.retry)
+		if err == nil {
+			resp.Provider = provider.Name()
+			if i > 0 {
+				resp.FallbackFrom = primary
+			}
+			return resp, nil
+		}
+		lastErr = err
+		if ctx.Err() != nil || !IsTransientError(err) {
+			break
+		}
+		if i+1 < len(p.chain) {
+			logger.WithError(err).WithFields(map[string]interface{}{
+				"provider": provider.Name(),
+				"fallback": p.chain[i+1].Name(),
+			}).Warn("Provider failed, falling back")
+		}
+	}
+	return nil, lastErr
+}
+
+// GetEmbedding returns the primary provider's embedding
+func (p *FallbackProvider) GetEmbedding(ctx context.Context, text string, registry RegistryInterface) ([]float32, error) {
+	return p.chain[0].GetEmbedding(ctx, text, registry)
+}
+
+// Name returns the primary provider's name
+func (p *FallbackProvider) Name() string {
+	return p.chain[0].Name()
+}
+
+// IsAvailable reports whether any provider in the chain is available
+func (p *FallbackProvider) IsAvailable() bool {
+	for _, provider := range p.chain {
+		if provider.IsAvailable() {
+			return true
+		}
+	}
+	return false
+}
+
+// SupportsEmbeddings reports whether the primary provider supports embeddings
+func (p *FallbackProvider) SupportsEmbeddings() bool {
+	return p.chain[0].SupportsEmbeddings()
+}
+
+// SupportsStreaming reports whether the primary provider supports streaming
+func (p *FallbackProvider) SupportsStreaming() bool {
+	return p.chain[0].SupportsStreaming()
+}
+
+// Ping pings the primary provider
+func (p *FallbackProvider) Ping(ctx context.Context) (time.Duration, error) {
+	return p.chain[0].Ping(ctx)
+}
+
+// GetWithFallback returns primary wrapped with retries for transient errors
+// and a cascade through fallbacks. Fallbacks that aren't registered or
+// available are skipped; the primary must exist. Providers whose circuit
+// breaker is open when a request is made are skipped without being called.
+func (r *Registry) GetWithFallback(primary string, fallbacks ...string) (Provider, error) {
+	provider, err := r.Get(primary)
+	if err != nil {
+		return nil, err
+	}
+
+	var chain []Provider
+	for _, name := range fallbacks {
+		r.mu.RLock()
+		fallback, exists := r.providers[name]
+		r.mu.RUnlock()
+		if !exists || !fallback.IsAvailable() {
+			log.GetLogger().Debugf("Skipping unavailable fallback provider: %s", name)
+			continue
+		}
+		chain = append(chain, fallback)
+	}
+	fallback := NewFallbackProvider(LoadRetryConfig(), provider, chain...)
+	fallback.allow = r.Allow
+	return fallback, nil
+}
@@ -0,0 +1,138 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyProvider fails with err for its first failures calls, then succeeds
+func flakyProvider(name string, failures int, err error) (*TestProvider, *int) {
+	calls := 0
+	return &TestProvider{
+		name:      name,
+		available: true,
+		generateFunc: func(ctx context.Context, req GenerateRequest) (*GenerateResponse, error) {
+			calls++
+			if calls <= failures {
+				return nil, err
+			}
+			return &GenerateResponse{Content: "from " + name, Model: name + "-model"}, nil
+		},
+	}, &calls
+}
+
+var errRateLimited = errors.New("retryable error: status code 429")
+
+func TestIsTransientError(t *testing.T) {
+	assert.True(t, IsTransientError(errRateLimited))
+	assert.True(t, IsTransientError(fmt.Errorf("server error: status code 503")))
+	assert.True(t, IsTransientError(fmt.Errorf("google Gemini API call failed: %w", errors.New("Error 500, Message: internal"))))
+	assert.True(t, IsTransientError(fmt.Errorf("call failed: %w", context.DeadlineExceeded)))
+
+	assert.False(t, IsTransientError(nil))
+	assert.False(t, IsTransientError(errors.New("authentication failed: status code 401")))
+	assert.False(t, IsTransientError(errors.New("temperature must be between 0 and 1")))
+}
+
+func TestGenerateWithRetry(t *testing.T) {
+	cfg := RetryConfig{MaxRetries: 3, BaseDelay: time.Millisecond}
+
+	t.Run("succeeds after transient failures", func(t *testing.T) {
+		provider, calls := flakyProvider("openai", 2, errRateLimited)
+		resp, err := GenerateWithRetry(context.Background(), provider, GenerateRequest{Prompt: "test"}, cfg)
+		require.NoError(t, err)
+		assert.Equal(t, "from openai", resp.Content)
+		assert.Equal(t, 3, *calls)
+	})
+
+	t.Run("gives up after max retries", func(t *testing.T) {
+		provider, calls := flakyProvider("openai", 10, errRateLimited)
+		_, err := GenerateWithRetry(context.Background(), provider, GenerateRequest{Prompt: "test"}, cfg)
+		assert.ErrorIs(t, err, errRateLimited)
+		assert.Equal(t, 4, *calls)
+	})
+
+	t.Run("does not retry permanent errors", func(t *testing.T) {
+		provider, calls := flakyProvider("openai", 10, errors.New("authentication failed: status code 401"))
+		_, err := GenerateWithRetry(context.Background(), provider, GenerateRequest{Prompt: "test"}, cfg)
+		assert.Error(t, err)
+		assert.Equal(t, 1, *calls)
+	})
+}
+
+func TestFallbackProvider(t *testing.T) {
+	cfg := RetryConfig{MaxRetries: 1, BaseDelay: time.Millisecond}
+
+	t.Run("primary recovers within its retries", func(t *testing.T) {
+		primary, _ := flakyProvider("openai", 1, errRateLimited)
+		fallback, fallbackCalls := flakyProvider("anthropic", 0, nil)
+
+		resp, err := NewFallbackProvider(cfg, primary, fallback).Generate(context.Background(), GenerateRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, "openai", resp.Provider)
+		assert.Empty(t, resp.FallbackFrom)
+		assert.Equal(t, 0, *fallbackCalls)
+	})
+
+	t.Run("cascades once retries are exhausted", func(t *testing.T) {
+		primary, primaryCalls := flakyProvider("openai", 10, errRateLimited)
+		second, _ := flakyProvider("grok", 10, errors.New("server error: status code 502"))
+		third, _ := flakyProvider("anthropic", 1, errRateLimited)
+
+		resp, err := NewFallbackProvider(cfg, primary, second, third).Generate(context.Background(), GenerateRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, "from anthropic", resp.Content)
+		assert.Equal(t, "anthropic", resp.Provider)
+		assert.Equal(t, "openai", resp.FallbackFrom)
+		assert.Equal(t, 2, *primaryCalls)
+	})
+
+	t.Run("permanent errors do not cascade", func(t *testing.T) {
+		primary, _ := flakyProvider("openai", 10, errors.New("invalid request"))
+		fallback, fallbackCalls := flakyProvider("anthropic", 0, nil)
+
+		_, err := NewFallbackProvider(cfg, primary, fallback).Generate(context.Background(), GenerateRequest{})
+		assert.EqualError(t, err, "invalid request")
+		assert.Equal(t, 0, *fallbackCalls)
+	})
+}
+
+func TestRegistry_GetWithFallback(t *testing.T) {
+	viper.Set("providers.fallback_chain", []string{"openai", "grok", "anthropic"})
+	viper.Set("providers.retry.max_retries", 0)
+	defer viper.Reset()
+
+	registry := NewRegistry()
+	primary, primaryCalls := flakyProvider("openai", 10, errRateLimited)
+	anthropic, _ := flakyProvider("anthropic", 0, nil)
+	require.NoError(t, registry.Register("openai", primary))
+	require.NoError(t, registry.Register("grok", &TestProvider{name: "grok", available: false}))
+	require.NoError(t, registry.Register("anthropic", anthropic))
+
+	assert.Equal(t, []string{"grok", "anthropic"}, FallbackChain("openai"))
+	assert.Equal(t, RetryConfig{MaxRetries: 0, BaseDelay: DefaultGenerateBaseDelay}, LoadRetryConfig())
+
+	provider, err := registry.GetWithFallback("openai", FallbackChain("openai")...)
+	require.NoError(t, err)
+	assert.Equal(t, "openai", provider.Name())
+
+	// grok is unavailable, so the chain goes straight to anthropic
+	resp, err := provider.Generate(context.Background(), GenerateRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "anthropic", resp.Provider)
+	assert.Equal(t, "openai", resp.FallbackFrom)
+	assert.Equal(t, 1, *primaryCalls)
+
+	_, err = registry.GetWithFallback("missing")
+	assert.Error(t, err)
+
+	assert.True(t, IsSettingsKey("fallback_chain"))
+	assert.False(t, IsSettingsKey("openai"))
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	log "github.com/jonwraymond/prompt-alchemy/internal/log"
 	"google.golang.org/genai"
@@ -53,13 +54,16 @@ func (p *GoogleProvider) Generate(ctx context.Context, req GenerateRequest) (*Ge
 
 	// Use configured model or default
 	model := p.config.Model
+	if req.Model != "" {
+		model = req.Model
+	}
 	if model == "" {
 		model = "gemini-2.5-flash" // Default to Gemini 2.5 Flash (correct model name)
 	}
 
 	// Create generation config
 	var config *genai.GenerateContentConfig
-	if req.Temperature > 0 || req.MaxTokens > 0 || req.SystemPrompt != "" {
+	if req.Temperature > 0 || req.MaxTokens > 0 || req.SystemPrompt != "" || req.Seed != nil {
 		config = &genai.GenerateContentConfig{}
 		if req.Temperature > 0 {
 			temp := float32(req.Temperature)
@@ -68,6 +72,10 @@ func (p *GoogleProvider) Generate(ctx context.Context, req GenerateRequest) (*Ge
 		if req.MaxTokens > 0 {
 			config.MaxOutputTokens = int32(req.MaxTokens)
 		}
+		if req.Seed != nil {
+			seed := int32(*req.Seed)
+			config.Seed = &seed
+		}
 		// Add system instruction if provided
 		if req.SystemPrompt != "" {
 			config.SystemInstruction = &genai.Content{
@@ -110,7 +118,7 @@ func (p *GoogleProvider) Generate(ctx context.Context, req GenerateRequest) (*Ge
 	part := genai.NewPartFromText(req.Prompt)
 	result, err := chat.SendMessage(ctx, *part)
 	if err != nil {
-		return nil, fmt.Errorf("google Gemini API call failed: %w", err)
+		return nil, asContextTooLong(ProviderGoogle, model, fmt.Errorf("google Gemini API call failed: %w", err))
 	}
 
 	// Extract content from response
@@ -129,6 +137,8 @@ func (p *GoogleProvider) Generate(ctx context.Context, req GenerateRequest) (*Ge
 	if result.UsageMetadata != nil {
 		totalTokens := result.UsageMetadata.TotalTokenCount
 		response.TokensUsed = int(totalTokens)
+		response.InputTokens = int(result.UsageMetadata.PromptTokenCount)
+		response.OutputTokens = int(result.UsageMetadata.CandidatesTokenCount)
 	}
 
 	return response, nil
@@ -142,7 +152,7 @@ func (p *GoogleProvider) GetEmbedding(ctx context.Context, text string, registry
 
 	// Google Gemini doesn't have a dedicated embedding model like text-embedding-gecko
 	// For now, fallback to another provider
-	logger := log.GetLogger()
+	logger := log.FromContext(ctx)
 	logger.Debug("Google provider doesn't support direct embeddings, falling back to another provider")
 
 	// Try to use OpenAI or another provider for embeddings
@@ -180,6 +190,26 @@ func (p *GoogleProvider) IsAvailable() bool {
 	return p.config.APIKey != ""
 }
 
+// Ping times a models listing request against the Gemini API
+func (p *GoogleProvider) Ping(ctx context.Context) (time.Duration, error) {
+	if !p.IsAvailable() {
+		return 0, ErrNotConfigured
+	}
+	return pingHTTP(ctx, "https://generativelanguage.googleapis.com/v1beta/models?pageSize=1", map[string]string{
+		"x-goog-api-key": p.config.APIKey,
+	})
+}
+
+// ListModels lists the Gemini models of the Generative Language API
+func (p *GoogleProvider) ListModels(ctx context.Context) ([]string, error) {
+	if !p.IsAvailable() {
+		return nil, ErrNotConfigured
+	}
+	return listModelsHTTP(ctx, "https://generativelanguage.googleapis.com/v1beta/models?pageSize=1000", map[string]string{
+		"x-goog-api-key": p.config.APIKey,
+	})
+}
+
 // SupportsEmbeddings checks if the provider supports embeddings
 func (p *GoogleProvider) SupportsEmbeddings() bool {
 	return true
@@ -3,6 +3,8 @@ package providers
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/jonwraymond/prompt-alchemy/internal/log"
 	"github.com/jonwraymond/prompt-alchemy/pkg/security"
@@ -16,8 +18,9 @@ import (
 // Note: Grok requires credits to be purchased before API access is enabled.
 // New accounts must add credits at https://console.x.ai
 type GrokProvider struct {
-	client openai.Client
-	config Config
+	client  openai.Client
+	config  Config
+	baseURL string
 }
 
 // NewGrokProvider creates a new Grok provider
@@ -42,18 +45,22 @@ func NewGrokProvider(config Config) *GrokProvider {
 	client := openai.NewClient(opts...)
 
 	return &GrokProvider{
-		client: client,
-		config: config,
+		client:  client,
+		config:  config,
+		baseURL: baseURL,
 	}
 }
 
 // Generate creates a prompt using Grok (OpenAI-compatible)
 func (p *GrokProvider) Generate(ctx context.Context, req GenerateRequest) (*GenerateResponse, error) {
-	logger := log.GetLogger()
+	logger := log.FromContext(ctx)
 	logger.Debug("GrokProvider: Generating prompt")
 
 	// Determine the model to use
 	model := p.config.Model
+	if req.Model != "" {
+		model = req.Model
+	}
 	if model == "" {
 		model = "grok-2-1212" // Default Grok model as of July 2025
 	}
@@ -83,11 +90,14 @@ func (p *GrokProvider) Generate(ctx context.Context, req GenerateRequest) (*Gene
 	if req.MaxTokens > 0 {
 		params.MaxTokens = openai.Int(int64(req.MaxTokens))
 	}
+	if req.Seed != nil {
+		params.Seed = openai.Int(int64(*req.Seed))
+	}
 
 	// Make the API call
 	response, err := p.client.Chat.Completions.New(ctx, params)
 	if err != nil {
-		return nil, fmt.Errorf("grok API call failed: %w", err)
+		return nil, asContextTooLong(ProviderGrok, model, fmt.Errorf("grok API call failed: %w", err))
 	}
 
 	// Extract the response
@@ -100,13 +110,16 @@ func (p *GrokProvider) Generate(ctx context.Context, req GenerateRequest) (*Gene
 
 	// Build the response
 	genResponse := &GenerateResponse{
-		Content: content,
-		Model:   model,
+		Content:           content,
+		Model:             model,
+		SystemFingerprint: response.SystemFingerprint,
 	}
 
 	// Add usage information if available
 	if response.Usage.TotalTokens > 0 {
 		genResponse.TokensUsed = int(response.Usage.TotalTokens)
+		genResponse.InputTokens = int(response.Usage.PromptTokens)
+		genResponse.OutputTokens = int(response.Usage.CompletionTokens)
 	}
 
 	return genResponse, nil
@@ -114,7 +127,7 @@ func (p *GrokProvider) Generate(ctx context.Context, req GenerateRequest) (*Gene
 
 // GetEmbedding delegates to standardized (Grok doesn't support natively as of July 2025)
 func (p *GrokProvider) GetEmbedding(ctx context.Context, text string, registry RegistryInterface) ([]float32, error) {
-	logger := log.GetLogger().WithFields(logrus.Fields{
+	logger := log.FromContext(ctx).WithFields(logrus.Fields{
 		"provider": p.Name(),
 	})
 	logger.Info("GrokProvider delegating embedding to standardized provider")
@@ -131,6 +144,26 @@ func (p *GrokProvider) IsAvailable() bool {
 	return p.config.APIKey != ""
 }
 
+// Ping times a models listing request
+func (p *GrokProvider) Ping(ctx context.Context) (time.Duration, error) {
+	if !p.IsAvailable() {
+		return 0, ErrNotConfigured
+	}
+	return pingHTTP(ctx, strings.TrimSuffix(p.baseURL, "/")+"/models", map[string]string{
+		"Authorization": "Bearer " + p.config.APIKey,
+	})
+}
+
+// ListModels lists the models of the xAI API
+func (p *GrokProvider) ListModels(ctx context.Context) ([]string, error) {
+	if !p.IsAvailable() {
+		return nil, ErrNotConfigured
+	}
+	return listModelsHTTP(ctx, strings.TrimSuffix(p.baseURL, "/")+"/models", map[string]string{
+		"Authorization": "Bearer " + p.config.APIKey,
+	})
+}
+
 // SupportsEmbeddings checks if the provider supports embedding generation
 func (p *GrokProvider) SupportsEmbeddings() bool {
 	return false
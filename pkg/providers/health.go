@@ -0,0 +1,267 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/jonwraymond/prompt-alchemy/internal/log"
+	"github.com/spf13/viper"
+)
+
+// Defaults for the provider health checks
+const (
+	DefaultHealthInterval         = time.Minute
+	DefaultHealthFailureThreshold = 3
+	DefaultHealthTimeout          = 10 * time.Second
+)
+
+// ErrCircuitOpen is returned instead of calling a provider whose circuit
+// breaker has tripped
+var ErrCircuitOpen = errors.New("provider circuit breaker is open")
+
+// BreakerState is the state of a provider's circuit breaker
+type BreakerState string
+
+// Circuit breaker states
+const (
+	BreakerClosed   BreakerState = "closed"    // healthy, requests pass
+	BreakerOpen     BreakerState = "open"      // failing, requests are refused
+	BreakerHalfOpen BreakerState = "half-open" // cooling down ended, requests pass until the next check
+)
+
+// HealthConfig controls the background provider health checks
+type HealthConfig struct {
+	Interval         time.Duration // time between probes, no checks if zero
+	FailureThreshold int           // consecutive failures that open a breaker
+	Cooldown         time.Duration // time an open breaker waits before half-opening
+	Timeout          time.Duration // limit on each probe
+}
+
+// LoadHealthConfig reads providers.health.interval, .failure_threshold,
+// .cooldown and .timeout, falling back to the defaults when unset. The
+// cooldown defaults to the interval, so a tripped provider is retried by
+// the next probe after it.
+func LoadHealthConfig() HealthConfig {
+	cfg := HealthConfig{
+		Interval:         DefaultHealthInterval,
+		FailureThreshold: DefaultHealthFailureThreshold,
+		Timeout:          DefaultHealthTimeout,
+	}
+	if viper.IsSet("providers.health.interval") {
+		cfg.Interval = max(viper.GetDuration("providers.health.interval"), 0)
+	}
+	if threshold := viper.GetInt("providers.health.failure_threshold"); threshold > 0 {
+		cfg.FailureThreshold = threshold
+	}
+	if timeout := viper.GetDuration("providers.health.timeout"); timeout > 0 {
+		cfg.Timeout = timeout
+	}
+	cfg.Cooldown = cfg.Interval
+	if cooldown := viper.GetDuration("providers.health.cooldown"); cooldown > 0 {
+		cfg.Cooldown = cooldown
+	}
+	return cfg
+}
+
+// ProviderHealth is the last known health of a provider
+type ProviderHealth struct {
+	State               BreakerState `json:"state"`
+	ConsecutiveFailures int          `json:"consecutive_failures"`
+	LastError           string       `json:"last_error,omitempty"`
+	LastChecked         time.Time    `json:"last_checked"`
+	LatencyMS           int64        `json:"latency_ms"`
+}
+
+// CircuitBreaker opens after a number of consecutive failures. Once open it
+// refuses requests until its cooldown has passed, then half-opens: requests
+// pass again, and the next result closes it or opens it anew.
+type CircuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	state     BreakerState
+	openedAt  time.Time
+	health    ProviderHealth
+}
+
+// NewCircuitBreaker creates a closed breaker that opens after threshold
+// consecutive failures
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		threshold: max(threshold, 1),
+		cooldown:  cooldown,
+		state:     BreakerClosed,
+	}
+}
+
+// Allow reports whether a request may be sent
+func (b *CircuitBreaker) Allow() bool {
+	return b.State() != BreakerOpen
+}
+
+// State returns the breaker's state, half-opening it if its cooldown has
+// passed
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.halfOpen(time.Now())
+	return b.state
+}
+
+// Record updates the breaker with the result of a request or probe
+func (b *CircuitBreaker) Record(err error, latency time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.halfOpen(now)
+	b.health.LastChecked = now
+	b.health.LatencyMS = latency.Milliseconds()
+	if err == nil {
+		b.state = BreakerClosed
+		b.health.ConsecutiveFailures = 0
+		b.health.LastError = ""
+		return
+	}
+
+	b.health.ConsecutiveFailures++
+	b.health.LastError = err.Error()
+	if b.state == BreakerHalfOpen || b.health.ConsecutiveFailures >= b.threshold {
+		b.state = BreakerOpen
+		b.openedAt = now
+	}
+}
+
+// Health returns the breaker's state and the result it last recorded
+func (b *CircuitBreaker) Health() ProviderHealth {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.halfOpen(time.Now())
+	health := b.health
+	health.State = b.state
+	return health
+}
+
+func (b *CircuitBreaker) halfOpen(now time.Time) {
+	if b.state == BreakerOpen && now.Sub(b.openedAt) >= b.cooldown {
+		b.state = BreakerHalfOpen
+	}
+}
+
+// StartHealthChecks probes every registered provider now and then every
+// config.Interval until ctx is done, tripping a provider's circuit breaker
+// after config.FailureThreshold consecutive failed probes. It returns
+// immediately; the checks run in the background.
+func (r *Registry) StartHealthChecks(ctx context.Context, config HealthConfig) {
+	if config.Interval <= 0 {
+		log.GetLogger().Info("Provider health checks disabled")
+		return
+	}
+
+	r.healthMu.Lock()
+	r.healthConfig = config
+	r.healthMu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(config.Interval)
+		defer ticker.Stop()
+		for {
+			r.CheckHealth(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// CheckHealth pings every registered provider once, concurrently, and
+// records the results in their circuit breakers. Providers without
+// credentials are skipped.
+func (r *Registry) CheckHealth(ctx context.Context) {
+	r.healthMu.Lock()
+	config := r.healthConfig
+	r.healthMu.Unlock()
+
+	r.mu.RLock()
+	registered := make(map[string]Provider, len(r.providers))
+	for name, provider := range r.providers {
+		registered[name] = provider
+	}
+	r.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	for name, provider := range registered {
+		wg.Add(1)
+		go func(name string, provider Provider) {
+			defer wg.Done()
+			pingCtx, cancel := context.WithTimeout(ctx, config.Timeout)
+			defer cancel()
+
+			latency, err := provider.Ping(pingCtx)
+			if errors.Is(err, ErrNotConfigured) || ctx.Err() != nil {
+				return
+			}
+			r.recordHealth(name, err, latency)
+		}(name, provider)
+	}
+	wg.Wait()
+}
+
+func (r *Registry) recordHealth(name string, err error, latency time.Duration) {
+	r.healthMu.Lock()
+	breaker, exists := r.breakers[name]
+	if !exists {
+		breaker = NewCircuitBreaker(r.healthConfig.FailureThreshold, r.healthConfig.Cooldown)
+		r.breakers[name] = breaker
+	}
+	r.healthMu.Unlock()
+
+	before := breaker.State()
+	breaker.Record(err, latency)
+	if after := breaker.State(); after != before {
+		entry := log.GetLogger().WithField("provider", name).WithField("state", after)
+		if err != nil {
+			entry.WithError(err).Warn("Provider circuit breaker opened")
+		} else {
+			entry.Info("Provider circuit breaker closed")
+		}
+	}
+}
+
+// Allow reports whether requests may be sent to the named provider, which
+// is false while its circuit breaker is open. Providers that haven't been
+// checked are allowed.
+func (r *Registry) Allow(name string) bool {
+	r.healthMu.Lock()
+	breaker, exists := r.breakers[name]
+	r.healthMu.Unlock()
+	return !exists || breaker.Allow()
+}
+
+// Health returns the health of every registered provider. Providers that
+// haven't been checked are reported closed.
+func (r *Registry) Health() map[string]ProviderHealth {
+	names := r.ListProviders()
+	r.healthMu.Lock()
+	defer r.healthMu.Unlock()
+
+	health := make(map[string]ProviderHealth, len(names))
+	for _, name := range names {
+		if breaker, exists := r.breakers[name]; exists {
+			health[name] = breaker.Health()
+		} else {
+			health[name] = ProviderHealth{State: BreakerClosed}
+		}
+	}
+	return health
+}
+
+// circuitOpenError reports a provider skipped because its breaker is open
+func circuitOpenError(name string) error {
+	return fmt.Errorf("%s: %w", name, ErrCircuitOpen)
+}
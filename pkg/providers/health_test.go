@@ -0,0 +1,129 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// probedProvider fails its pings while down is set
+type probedProvider struct {
+	TestProvider
+	down  atomic.Bool
+	calls atomic.Int32
+}
+
+func (p *probedProvider) Ping(ctx context.Context) (time.Duration, error) {
+	if p.down.Load() {
+		return 0, errors.New("connection refused")
+	}
+	return time.Millisecond, nil
+}
+
+func (p *probedProvider) Generate(ctx context.Context, req GenerateRequest) (*GenerateResponse, error) {
+	p.calls.Add(1)
+	return p.TestProvider.Generate(ctx, req)
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	failure := errors.New("timeout")
+
+	t.Run("opens after the threshold", func(t *testing.T) {
+		breaker := NewCircuitBreaker(3, time.Hour)
+		breaker.Record(failure, 0)
+		breaker.Record(failure, 0)
+		assert.Equal(t, BreakerClosed, breaker.State())
+		assert.True(t, breaker.Allow())
+
+		breaker.Record(failure, 0)
+		assert.Equal(t, BreakerOpen, breaker.State())
+		assert.False(t, breaker.Allow())
+		assert.Equal(t, 3, breaker.Health().ConsecutiveFailures)
+		assert.Equal(t, "timeout", breaker.Health().LastError)
+	})
+
+	t.Run("success resets the count", func(t *testing.T) {
+		breaker := NewCircuitBreaker(2, time.Hour)
+		breaker.Record(failure, 0)
+		breaker.Record(nil, time.Millisecond)
+		breaker.Record(failure, 0)
+		assert.Equal(t, BreakerClosed, breaker.State())
+	})
+
+	t.Run("half-opens after the cooldown", func(t *testing.T) {
+		breaker := NewCircuitBreaker(1, 20*time.Millisecond)
+		breaker.Record(failure, 0)
+		require.Equal(t, BreakerOpen, breaker.State())
+
+		time.Sleep(30 * time.Millisecond)
+		assert.Equal(t, BreakerHalfOpen, breaker.State())
+		assert.True(t, breaker.Allow())
+
+		t.Run("and reopens on failure", func(t *testing.T) {
+			breaker.Record(failure, 0)
+			assert.Equal(t, BreakerOpen, breaker.State())
+		})
+
+		t.Run("or closes on success", func(t *testing.T) {
+			time.Sleep(30 * time.Millisecond)
+			require.Equal(t, BreakerHalfOpen, breaker.State())
+			breaker.Record(nil, time.Millisecond)
+			assert.Equal(t, BreakerClosed, breaker.State())
+			assert.Zero(t, breaker.Health().ConsecutiveFailures)
+		})
+	})
+}
+
+func TestRegistryHealthChecks(t *testing.T) {
+	flaky := &probedProvider{TestProvider: TestProvider{name: "flaky", available: true}}
+	backup := &TestProvider{name: "backup", available: true}
+	registry := NewRegistry()
+	require.NoError(t, registry.Register("flaky", flaky))
+	require.NoError(t, registry.Register("backup", backup))
+	registry.healthConfig = HealthConfig{Interval: time.Hour, FailureThreshold: 2, Cooldown: time.Hour, Timeout: time.Second}
+
+	ctx := context.Background()
+	assert.Equal(t, BreakerClosed, registry.Health()["flaky"].State, "unchecked providers are closed")
+
+	flaky.down.Store(true)
+	registry.CheckHealth(ctx)
+	assert.Equal(t, BreakerClosed, registry.Health()["flaky"].State)
+	registry.CheckHealth(ctx)
+	health := registry.Health()
+	assert.Equal(t, BreakerOpen, health["flaky"].State)
+	assert.Equal(t, 2, health["flaky"].ConsecutiveFailures)
+	assert.Equal(t, BreakerClosed, health["backup"].State)
+	assert.False(t, registry.Allow("flaky"))
+
+	t.Run("generation skips the open provider", func(t *testing.T) {
+		provider, err := registry.GetWithFallback("flaky", "backup")
+		require.NoError(t, err)
+		resp, err := provider.Generate(ctx, GenerateRequest{Prompt: "test"})
+		require.NoError(t, err)
+		assert.Equal(t, "backup", resp.Provider)
+		assert.Equal(t, "flaky", resp.FallbackFrom)
+		assert.Zero(t, flaky.calls.Load(), "the open provider is not called")
+
+		alone, err := registry.GetWithFallback("flaky")
+		require.NoError(t, err)
+		_, err = alone.Generate(ctx, GenerateRequest{Prompt: "test"})
+		assert.ErrorIs(t, err, ErrCircuitOpen)
+	})
+
+	t.Run("recovers once probes succeed", func(t *testing.T) {
+		registry.healthMu.Lock()
+		registry.breakers["flaky"].cooldown = 0
+		registry.healthMu.Unlock()
+		assert.Equal(t, BreakerHalfOpen, registry.Health()["flaky"].State)
+
+		flaky.down.Store(false)
+		registry.CheckHealth(ctx)
+		assert.Equal(t, BreakerClosed, registry.Health()["flaky"].State)
+		assert.True(t, registry.Allow("flaky"))
+	})
+}
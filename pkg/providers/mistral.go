@@ -0,0 +1,207 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jonwraymond/prompt-alchemy/internal/log"
+	"github.com/jonwraymond/prompt-alchemy/pkg/security"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultMistralModel           = "mistral-large-latest"
+	DefaultMistralEmbeddingModel  = "mistral-embed"
+	DefaultMistralModerationModel = "mistral-moderation-latest"
+
+	defaultMistralBaseURL = "https://api.mistral.ai/v1"
+)
+
+// MistralProvider implements the Provider interface for Mistral AI using the
+// OpenAI-compatible SDK, since Mistral's chat and embeddings APIs share its shape.
+//
+// Native embeddings are only used when an embedding model is configured.
+// mistral-embed vectors are 1024-dimensional and cannot be mixed with the
+// standard 1536-dimension embeddings, so by default embedding requests are
+// delegated to the standardized provider like the other non-OpenAI providers.
+type MistralProvider struct {
+	client  openai.Client
+	config  Config
+	baseURL string
+}
+
+// NewMistralProvider creates a new Mistral provider
+func NewMistralProvider(config Config) *MistralProvider {
+	baseURL := config.BaseURL
+	if baseURL == "" {
+		baseURL = defaultMistralBaseURL
+	}
+
+	// Validate the base URL for security
+	if err := security.ValidateBaseURL(baseURL); err != nil {
+		log.GetLogger().Errorf("Invalid base URL for Mistral provider: %v", err)
+		baseURL = defaultMistralBaseURL
+	}
+
+	opts := []option.RequestOption{
+		option.WithAPIKey(config.APIKey),
+		option.WithBaseURL(baseURL),
+	}
+
+	client := openai.NewClient(opts...)
+
+	return &MistralProvider{
+		client:  client,
+		config:  config,
+		baseURL: baseURL,
+	}
+}
+
+// Generate creates a prompt using Mistral's chat completions API
+func (p *MistralProvider) Generate(ctx context.Context, req GenerateRequest) (*GenerateResponse, error) {
+	messages := []openai.ChatCompletionMessageParamUnion{}
+
+	// Add system prompt if provided
+	if req.SystemPrompt != "" {
+		messages = append(messages, openai.SystemMessage(req.SystemPrompt))
+	}
+
+	// Add examples if provided
+	for _, example := range req.Examples {
+		messages = append(messages, openai.UserMessage(example.Input))
+		messages = append(messages, openai.AssistantMessage(example.Output))
+	}
+
+	messages = append(messages, openai.UserMessage(req.Prompt))
+
+	model := p.config.Model
+	if req.Model != "" {
+		model = req.Model
+	}
+	if model == "" {
+		model = DefaultMistralModel
+	}
+
+	params := openai.ChatCompletionNewParams{
+		Model:    openai.ChatModel(model),
+		Messages: messages,
+	}
+	if req.Temperature > 0 {
+		params.Temperature = openai.Float(req.Temperature)
+	}
+	if req.MaxTokens > 0 {
+		params.MaxTokens = openai.Int(int64(req.MaxTokens))
+	}
+
+	response, err := p.client.Chat.Completions.New(ctx, params)
+	if err != nil {
+		return nil, asContextTooLong(ProviderMistral, model, fmt.Errorf("mistral API call failed: %w", err))
+	}
+
+	if len(response.Choices) == 0 {
+		return nil, fmt.Errorf("no choices returned from Mistral API")
+	}
+
+	genResponse := &GenerateResponse{
+		Content: response.Choices[0].Message.Content,
+		Model:   model,
+	}
+	if response.Usage.TotalTokens > 0 {
+		genResponse.TokensUsed = int(response.Usage.TotalTokens)
+		genResponse.InputTokens = int(response.Usage.PromptTokens)
+		genResponse.OutputTokens = int(response.Usage.CompletionTokens)
+	}
+
+	return genResponse, nil
+}
+
+// GetEmbedding uses the configured Mistral embedding model, or delegates to
+// the standardized provider when none is configured
+func (p *MistralProvider) GetEmbedding(ctx context.Context, text string, registry RegistryInterface) ([]float32, error) {
+	logger := log.FromContext(ctx).WithFields(logrus.Fields{
+		"provider": p.Name(),
+	})
+
+	if p.config.EmbeddingModel == "" {
+		logger.Info("MistralProvider delegating embedding to standardized provider")
+		return getStandardizedEmbedding(ctx, text, registry)
+	}
+
+	response, err := p.client.Embeddings.New(ctx, openai.EmbeddingNewParams{
+		Input: openai.EmbeddingNewParamsInputUnion{
+			OfString: openai.String(text),
+		},
+		Model: openai.EmbeddingModel(p.config.EmbeddingModel),
+	})
+	if err != nil {
+		logger.WithError(err).Error("MistralProvider: Failed to create embedding")
+		return nil, fmt.Errorf("failed to create embedding: %w", err)
+	}
+
+	if len(response.Data) == 0 {
+		return nil, fmt.Errorf("no embedding data returned")
+	}
+
+	embedding := make([]float32, len(response.Data[0].Embedding))
+	for i, v := range response.Data[0].Embedding {
+		embedding[i] = float32(v)
+	}
+	logger.Debugf("MistralProvider: Successfully created embedding with length %d", len(embedding))
+
+	return embedding, nil
+}
+
+// Name returns the provider name
+func (p *MistralProvider) Name() string {
+	return ProviderMistral
+}
+
+// IsAvailable checks if the provider is configured
+func (p *MistralProvider) IsAvailable() bool {
+	return p.config.APIKey != ""
+}
+
+// Ping times a models listing request
+func (p *MistralProvider) Ping(ctx context.Context) (time.Duration, error) {
+	if !p.IsAvailable() {
+		return 0, ErrNotConfigured
+	}
+	return pingHTTP(ctx, strings.TrimSuffix(p.baseURL, "/")+"/models", map[string]string{
+		"Authorization": "Bearer " + p.config.APIKey,
+	})
+}
+
+// ListModels lists the models of the Mistral API
+func (p *MistralProvider) ListModels(ctx context.Context) ([]string, error) {
+	if !p.IsAvailable() {
+		return nil, ErrNotConfigured
+	}
+	return listModelsHTTP(ctx, strings.TrimSuffix(p.baseURL, "/")+"/models", map[string]string{
+		"Authorization": "Bearer " + p.config.APIKey,
+	})
+}
+
+// Moderate scores text with Mistral's moderation endpoint
+func (p *MistralProvider) Moderate(ctx context.Context, text string) (float64, error) {
+	if !p.IsAvailable() {
+		return 0, ErrNotConfigured
+	}
+	return moderateHTTP(ctx, strings.TrimSuffix(p.baseURL, "/")+"/moderations", DefaultMistralModerationModel, text, map[string]string{
+		"Authorization": "Bearer " + p.config.APIKey,
+	})
+}
+
+// SupportsEmbeddings reports whether a Mistral embedding model is configured
+func (p *MistralProvider) SupportsEmbeddings() bool {
+	return p.config.EmbeddingModel != ""
+}
+
+// SupportsStreaming checks if the provider supports streaming generation
+func (p *MistralProvider) SupportsStreaming() bool {
+	return true
+}
@@ -0,0 +1,81 @@
+package providers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewMistralProvider(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      Config
+		wantBaseURL string
+	}{
+		{
+			name:        "basic config",
+			config:      Config{APIKey: "test-key"},
+			wantBaseURL: defaultMistralBaseURL,
+		},
+		{
+			name:        "config with model",
+			config:      Config{APIKey: "test-key", Model: "codestral-latest"},
+			wantBaseURL: defaultMistralBaseURL,
+		},
+		{
+			name:        "disallowed base URL falls back to default",
+			config:      Config{APIKey: "test-key", BaseURL: "https://evil.example.com/v1"},
+			wantBaseURL: defaultMistralBaseURL,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := NewMistralProvider(tt.config)
+			assert.NotNil(t, provider)
+			assert.Equal(t, tt.config, provider.config)
+			assert.Equal(t, tt.wantBaseURL, provider.baseURL)
+		})
+	}
+}
+
+func TestMistralProvider_Name(t *testing.T) {
+	provider := NewMistralProvider(Config{APIKey: "test-key"})
+	assert.Equal(t, ProviderMistral, provider.Name())
+}
+
+func TestMistralProvider_IsAvailable(t *testing.T) {
+	assert.True(t, NewMistralProvider(Config{APIKey: "test-key"}).IsAvailable())
+	assert.False(t, NewMistralProvider(Config{}).IsAvailable())
+}
+
+func TestMistralProvider_SupportsEmbeddings(t *testing.T) {
+	provider := NewMistralProvider(Config{APIKey: "test-key"})
+	assert.False(t, provider.SupportsEmbeddings())
+
+	provider = NewMistralProvider(Config{APIKey: "test-key", EmbeddingModel: DefaultMistralEmbeddingModel})
+	assert.True(t, provider.SupportsEmbeddings())
+}
+
+func TestMistralProvider_Generate(t *testing.T) {
+	provider := NewMistralProvider(Config{
+		APIKey: "fake-key-for-testing",
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req := GenerateRequest{
+		Prompt:      "Hello, world!",
+		Temperature: 0.7,
+		MaxTokens:   10,
+	}
+
+	// This should fail with authentication error since we're using a fake key
+	resp, err := provider.Generate(ctx, req)
+	assert.Error(t, err)
+	assert.Nil(t, resp)
+	assert.Contains(t, err.Error(), "mistral API")
+}
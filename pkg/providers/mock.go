@@ -0,0 +1,66 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// MockProvider is a Provider for tests whose behavior is set through its
+// function fields. Left unset, it is an available provider named "mock"
+// without embeddings that fails to generate.
+type MockProvider struct {
+	GenerateFunc           func(ctx context.Context, req GenerateRequest) (*GenerateResponse, error)
+	GetEmbeddingFunc       func(ctx context.Context, text string, registry RegistryInterface) ([]float32, error)
+	NameFunc               func() string
+	IsAvailableFunc        func() bool
+	SupportsEmbeddingsFunc func() bool
+}
+
+// Generate calls GenerateFunc
+func (m *MockProvider) Generate(ctx context.Context, req GenerateRequest) (*GenerateResponse, error) {
+	if m.GenerateFunc == nil {
+		return nil, errors.New("mock provider: GenerateFunc not set")
+	}
+	return m.GenerateFunc(ctx, req)
+}
+
+// GetEmbedding calls GetEmbeddingFunc
+func (m *MockProvider) GetEmbedding(ctx context.Context, text string, registry RegistryInterface) ([]float32, error) {
+	if m.GetEmbeddingFunc == nil {
+		return nil, errors.New("mock provider: GetEmbeddingFunc not set")
+	}
+	return m.GetEmbeddingFunc(ctx, text, registry)
+}
+
+// Name calls NameFunc, defaulting to "mock"
+func (m *MockProvider) Name() string {
+	if m.NameFunc == nil {
+		return "mock"
+	}
+	return m.NameFunc()
+}
+
+// IsAvailable calls IsAvailableFunc, defaulting to true
+func (m *MockProvider) IsAvailable() bool {
+	if m.IsAvailableFunc == nil {
+		return true
+	}
+	return m.IsAvailableFunc()
+}
+
+// SupportsEmbeddings calls SupportsEmbeddingsFunc, defaulting to false
+func (m *MockProvider) SupportsEmbeddings() bool {
+	if m.SupportsEmbeddingsFunc == nil {
+		return false
+	}
+	return m.SupportsEmbeddingsFunc()
+}
+
+// SupportsStreaming reports false; MockProvider doesn't stream
+func (m *MockProvider) SupportsStreaming() bool { return false }
+
+// Ping answers at once
+func (m *MockProvider) Ping(ctx context.Context) (time.Duration, error) {
+	return 0, nil
+}
@@ -0,0 +1,183 @@
+package providers
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+
+	"github.com/jonwraymond/prompt-alchemy/internal/log"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// ErrModelUnavailable is returned, wrapped, by providers asked for a model
+// they don't serve
+var ErrModelUnavailable = errors.New("model unavailable")
+
+// modelUnavailablePattern matches the messages providers return for
+// retired or unknown models, e.g. OpenAI's model_not_found and "The model
+// `gpt-4` does not exist", Ollama's "model 'llama2' not found", or
+// Groq-style model_decommissioned
+var modelUnavailablePattern = regexp.MustCompile("(?i)model_not_found|model_decommissioned|invalid[ _]model|unknown model|model not found|" +
+	"model:?\\s+['\"`]?[\\w.:/-]+['\"`]?\\s+(?:is\\s+|has been\\s+|was\\s+)?" +
+	"(?:does not exist|not found|not supported|deprecated|decommissioned|retired|no longer (?:available|supported))")
+
+// IsModelUnavailable reports whether err means the requested model isn't
+// served, as opposed to the request or the provider failing
+func IsModelUnavailable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, ErrModelUnavailable) {
+		return true
+	}
+	switch statusCode(err) {
+	case 0, 400, 404, 410:
+		return modelUnavailablePattern.MatchString(err.Error())
+	}
+	return false
+}
+
+// LoadModelAliases reads providers.<name>.model_aliases, a map from retired
+// model names to the ones that replace them
+func LoadModelAliases(name string) map[string]string {
+	aliases := viper.GetStringMapString("providers." + name + ".model_aliases")
+	for from, to := range aliases {
+		if from == "" || to == "" || from == to {
+			delete(aliases, from)
+		}
+	}
+	return aliases
+}
+
+// ModelAliasProvider retries a request with the aliased model when the
+// provider rejects the model it asked for as unavailable. Substitutions are
+// remembered, so later requests for a retired model go straight to its
+// replacement.
+type ModelAliasProvider struct {
+	Provider
+	name    string
+	aliases map[string]string
+
+	mu      sync.RWMutex
+	retired map[string]string // models found unavailable, to their replacement
+}
+
+// NewModelAliasProvider wraps provider, registered as name, with aliases
+// from LoadModelAliases
+func NewModelAliasProvider(name string, provider Provider, aliases map[string]string) *ModelAliasProvider {
+	return &ModelAliasProvider{
+		Provider: provider,
+		name:     name,
+		aliases:  aliases,
+		retired:  make(map[string]string),
+	}
+}
+
+// Generate generates with the requested model, or its replacement once the
+// provider has rejected it. ModelAliasedFrom is set on the response when the
+// replacement served the request.
+func (p *ModelAliasProvider) Generate(ctx context.Context, req GenerateRequest) (*GenerateResponse, error) {
+	requested := p.requestedModel(req)
+	model := p.resolve(requested)
+	if model != requested {
+		req.Model = model
+	}
+
+	resp, err := p.Provider.Generate(ctx, req)
+	for err != nil && IsModelUnavailable(err) {
+		alias, ok := p.substitute(ctx, model, err)
+		if !ok {
+			break
+		}
+		model, req.Model = alias, alias
+		resp, err = p.Provider.Generate(ctx, req)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if model != requested {
+		resp.ModelAliasedFrom = requested
+		if resp.Model == "" {
+			resp.Model = model
+		}
+	}
+	return resp, nil
+}
+
+// GenerateStream streams with the requested model, or its replacement, like
+// Generate. Only failures to open the stream are retried.
+func (p *ModelAliasProvider) GenerateStream(ctx context.Context, req GenerateRequest) (<-chan GenerateResponseChunk, error) {
+	streamer, ok := AsStreaming(p.Provider)
+	if !ok {
+		return nil, fmt.Errorf("%s does not support streaming", p.Name())
+	}
+	requested := p.requestedModel(req)
+	model := p.resolve(requested)
+	if model != requested {
+		req.Model = model
+	}
+
+	chunks, err := streamer.GenerateStream(ctx, req)
+	for err != nil && IsModelUnavailable(err) {
+		alias, ok := p.substitute(ctx, model, err)
+		if !ok {
+			break
+		}
+		model, req.Model = alias, alias
+		chunks, err = streamer.GenerateStream(ctx, req)
+	}
+	return chunks, err
+}
+
+// requestedModel returns the model req asks for: its own, or the
+// provider's configured one. Requests for the configured model are passed on
+// as they are, without naming it, until it's substituted.
+func (p *ModelAliasProvider) requestedModel(req GenerateRequest) string {
+	return cmp.Or(req.Model, ConfiguredModel(p.name))
+}
+
+// resolve follows remembered substitutions from model to the model that
+// last served in its place
+func (p *ModelAliasProvider) resolve(model string) string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	seen := map[string]bool{model: true}
+	for {
+		next, ok := p.retired[model]
+		if !ok || seen[next] {
+			return model
+		}
+		seen[next] = true
+		model = next
+	}
+}
+
+// substitute returns the alias of model after the provider rejected it with
+// err, remembering the substitution. It returns false when model has no
+// alias, or the alias already failed.
+func (p *ModelAliasProvider) substitute(ctx context.Context, model string, err error) (string, bool) {
+	alias, ok := p.aliases[model]
+	if !ok {
+		return "", false
+	}
+
+	p.mu.Lock()
+	if _, failed := p.retired[alias]; failed {
+		p.mu.Unlock()
+		return "", false
+	}
+	p.retired[model] = alias
+	p.mu.Unlock()
+
+	log.FromContext(ctx).WithError(err).WithFields(logrus.Fields{
+		"provider": p.name,
+		"model":    model,
+		"alias":    alias,
+	}).Warn("Model unavailable, substituting its alias")
+	return alias, true
+}
@@ -0,0 +1,104 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// retiringProvider serves like OpenAI after gpt-4 was retired, rejecting it
+// and recording the model of each request
+func retiringProvider(name string) (*TestProvider, *[]string) {
+	var models []string
+	return &TestProvider{
+		name:      name,
+		available: true,
+		generateFunc: func(ctx context.Context, req GenerateRequest) (*GenerateResponse, error) {
+			model := req.Model
+			if model == "" {
+				model = ConfiguredModel(name)
+			}
+			models = append(models, model)
+			if model == "gpt-4" {
+				return nil, errors.New("openai API call failed: status code 404: The model `gpt-4` does not exist or you do not have access to it")
+			}
+			return &GenerateResponse{Content: "from " + model, Model: model}, nil
+		},
+	}, &models
+}
+
+func TestIsModelUnavailable(t *testing.T) {
+	assert.True(t, IsModelUnavailable(errors.New("status code 404: The model `gpt-4` does not exist")))
+	assert.True(t, IsModelUnavailable(errors.New(`status code 400: {"code":"model_decommissioned"}`)))
+	assert.True(t, IsModelUnavailable(errors.New("model claude-2 is deprecated")))
+	assert.True(t, IsModelUnavailable(fmt.Errorf("ollama: %w", ErrModelUnavailable)))
+
+	assert.False(t, IsModelUnavailable(nil))
+	assert.False(t, IsModelUnavailable(errRateLimited))
+	assert.False(t, IsModelUnavailable(errors.New("status code 401: invalid api key")))
+	assert.False(t, IsModelUnavailable(errors.New("status code 503: model gpt-4o not found")))
+	assert.False(t, IsModelUnavailable(errors.New("the model produced content not supported by the schema")))
+}
+
+func TestModelAliasProvider(t *testing.T) {
+	defer viper.Reset()
+	viper.Set("providers.mock.model_aliases", map[string]string{"gpt-4": "gpt-4o"})
+	ctx := context.Background()
+
+	t.Run("redirects a retired model to its alias", func(t *testing.T) {
+		provider, models := retiringProvider("mock")
+		registry := NewRegistry()
+		require.NoError(t, registry.Register("mock", provider))
+		registered, err := registry.Get("mock")
+		require.NoError(t, err)
+
+		resp, err := registered.Generate(ctx, GenerateRequest{Prompt: "test", Model: "gpt-4"})
+		require.NoError(t, err)
+		assert.Equal(t, "from gpt-4o", resp.Content)
+		assert.Equal(t, "gpt-4o", resp.Model)
+		assert.Equal(t, "gpt-4", resp.ModelAliasedFrom)
+		assert.Equal(t, []string{"gpt-4", "gpt-4o"}, *models)
+
+		// The substitution is remembered
+		resp, err = registered.Generate(ctx, GenerateRequest{Prompt: "test", Model: "gpt-4"})
+		require.NoError(t, err)
+		assert.Equal(t, "gpt-4", resp.ModelAliasedFrom)
+		assert.Equal(t, []string{"gpt-4", "gpt-4o", "gpt-4o"}, *models)
+	})
+
+	t.Run("aliases the configured model", func(t *testing.T) {
+		viper.Set("providers.mock.model", "gpt-4")
+		defer viper.Set("providers.mock.model", "")
+		provider, models := retiringProvider("mock")
+		aliased := NewModelAliasProvider("mock", provider, LoadModelAliases("mock"))
+
+		resp, err := aliased.Generate(ctx, GenerateRequest{Prompt: "test"})
+		require.NoError(t, err)
+		assert.Equal(t, "gpt-4", resp.ModelAliasedFrom)
+		assert.Equal(t, []string{"gpt-4", "gpt-4o"}, *models)
+	})
+
+	t.Run("leaves served models alone", func(t *testing.T) {
+		provider, models := retiringProvider("mock")
+		aliased := NewModelAliasProvider("mock", provider, LoadModelAliases("mock"))
+
+		resp, err := aliased.Generate(ctx, GenerateRequest{Prompt: "test", Model: "gpt-4o-mini"})
+		require.NoError(t, err)
+		assert.Empty(t, resp.ModelAliasedFrom)
+		assert.Equal(t, []string{"gpt-4o-mini"}, *models)
+	})
+
+	t.Run("fails without an alias", func(t *testing.T) {
+		provider, models := retiringProvider("mock")
+		aliased := NewModelAliasProvider("mock", provider, map[string]string{"gpt-3.5-turbo": "gpt-4o-mini"})
+
+		_, err := aliased.Generate(ctx, GenerateRequest{Prompt: "test", Model: "gpt-4"})
+		assert.True(t, IsModelUnavailable(err))
+		assert.Equal(t, []string{"gpt-4"}, *models)
+	})
+}
@@ -0,0 +1,31 @@
+package providers
+
+import "context"
+
+// ModelLister is implemented by providers whose API lists the models they
+// serve
+type ModelLister interface {
+	Provider
+
+	// ListModels returns the IDs of the models the provider's API currently
+	// serves
+	ListModels(ctx context.Context) ([]string, error)
+}
+
+// AsModelLister returns provider as a ModelLister when it implements
+// ListModels, looking through the embedding cache, rate limiter and model
+// aliases. Listing models doesn't spend a rate-limited provider's
+// generation budget.
+func AsModelLister(provider Provider) (ModelLister, bool) {
+	if cached, ok := provider.(*EmbeddingCachedProvider); ok {
+		return AsModelLister(cached.Provider)
+	}
+	if aliased, ok := provider.(*ModelAliasProvider); ok {
+		return AsModelLister(aliased.Provider)
+	}
+	if limited, ok := provider.(*RateLimitedProvider); ok {
+		return AsModelLister(limited.Provider)
+	}
+	lister, ok := provider.(ModelLister)
+	return lister, ok
+}
@@ -0,0 +1,76 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newModelsServer answers GET /models with status and body
+func newModelsServer(t *testing.T, status int, body string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/models", r.URL.Path)
+		assert.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestListModels(t *testing.T) {
+	t.Run("lists models by ID", func(t *testing.T) {
+		server := newModelsServer(t, http.StatusOK, `{"object":"list","data":[{"id":"gpt-4o"},{"id":"o4-mini"}]}`)
+		provider := NewOpenAIProvider(Config{APIKey: "test-key", BaseURL: server.URL})
+
+		models, err := provider.ListModels(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"gpt-4o", "o4-mini"}, models)
+	})
+
+	t.Run("drops the models/ prefix of names", func(t *testing.T) {
+		server := newModelsServer(t, http.StatusOK, `{"models":[{"name":"models/gemini-2.5-flash"},{"name":"command-r"}]}`)
+
+		models, err := listModelsHTTP(context.Background(), server.URL+"/models", map[string]string{"Authorization": "Bearer test-key"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"gemini-2.5-flash", "command-r"}, models)
+	})
+
+	t.Run("reports rate limiting", func(t *testing.T) {
+		server := newModelsServer(t, http.StatusTooManyRequests, `{}`)
+		provider := NewOpenAIProvider(Config{APIKey: "test-key", BaseURL: server.URL})
+
+		_, err := provider.ListModels(context.Background())
+		assert.True(t, errors.Is(err, ErrRateLimited), err)
+	})
+
+	t.Run("fails on other statuses", func(t *testing.T) {
+		server := newModelsServer(t, http.StatusUnauthorized, `{"error":"bad key"}`)
+		provider := NewOpenAIProvider(Config{APIKey: "test-key", BaseURL: server.URL})
+
+		_, err := provider.ListModels(context.Background())
+		assert.ErrorContains(t, err, "status code 401")
+	})
+
+	t.Run("requires an API key", func(t *testing.T) {
+		_, err := NewOpenAIProvider(Config{}).ListModels(context.Background())
+		assert.ErrorIs(t, err, ErrNotConfigured)
+	})
+}
+
+func TestAsModelLister(t *testing.T) {
+	limited := NewRateLimitedProvider(NewMistralProvider(Config{APIKey: "test-key"}), RateLimitConfig{RPM: 60}, nil)
+	lister, ok := AsModelLister(limited)
+	require.True(t, ok)
+	assert.Equal(t, ProviderMistral, lister.Name())
+
+	_, ok = AsModelLister(NewPerplexityProvider(Config{APIKey: "test-key"}))
+	assert.False(t, ok, "Perplexity has no models API")
+}
@@ -0,0 +1,96 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Moderator is implemented by providers with a moderation endpoint, which
+// scores text for harmful content without a chat model
+type Moderator interface {
+	Provider
+
+	// Moderate returns how likely text is harmful, from 0 (safe) to 1: the
+	// highest score across the endpoint's categories
+	Moderate(ctx context.Context, text string) (float64, error)
+}
+
+// AsModerator returns provider as a Moderator when it implements Moderate,
+// looking through the embedding cache, rate limiter and model aliases
+func AsModerator(provider Provider) (Moderator, bool) {
+	if cached, ok := provider.(*EmbeddingCachedProvider); ok {
+		return AsModerator(cached.Provider)
+	}
+	if aliased, ok := provider.(*ModelAliasProvider); ok {
+		return AsModerator(aliased.Provider)
+	}
+	if limited, ok := provider.(*RateLimitedProvider); ok {
+		if _, ok := AsModerator(limited.Provider); !ok {
+			return nil, false
+		}
+	}
+	moderator, ok := provider.(Moderator)
+	return moderator, ok
+}
+
+// Moderate waits for the provider's budget, then moderates text
+func (p *RateLimitedProvider) Moderate(ctx context.Context, text string) (float64, error) {
+	moderator, ok := AsModerator(p.Provider)
+	if !ok {
+		return 0, fmt.Errorf("%s does not support moderation", p.Name())
+	}
+	if err := p.wait(ctx); err != nil {
+		return 0, err
+	}
+	return moderator.Moderate(ctx, text)
+}
+
+// moderateHTTP scores text with the moderation API at url, which OpenAI and
+// Mistral share: one result per input, with a score per category
+func moderateHTTP(ctx context.Context, url, model, text string, headers map[string]string) (float64, error) {
+	body, err := json.Marshal(map[string]interface{}{"model": model, "input": text})
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal moderation request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create moderation request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return 0, fmt.Errorf("failed to moderate: %w", ErrRateLimited)
+	case resp.StatusCode != http.StatusOK:
+		return 0, fmt.Errorf("failed to moderate: status code %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Results []struct {
+			CategoryScores map[string]float64 `json:"category_scores"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return 0, fmt.Errorf("failed to decode moderation response: %w", err)
+	}
+	if len(result.Results) == 0 {
+		return 0, fmt.Errorf("no moderation result returned")
+	}
+	score := 0.0
+	for _, categoryScore := range result.Results[0].CategoryScores {
+		score = max(score, categoryScore)
+	}
+	return min(score, 1), nil
+}
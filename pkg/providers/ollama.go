@@ -59,9 +59,14 @@ func NewOllamaProvider(config Config) *OllamaProvider {
 
 // Generate creates a prompt using Ollama's official API
 func (p *OllamaProvider) Generate(ctx context.Context, req GenerateRequest) (*GenerateResponse, error) {
+	model := p.config.Model
+	if req.Model != "" {
+		model = req.Model
+	}
+
 	// Convert our request to Ollama API format
 	ollamaReq := &api.GenerateRequest{
-		Model:  p.config.Model,
+		Model:  model,
 		Prompt: req.Prompt,
 		Stream: &[]bool{false}[0],
 	}
@@ -79,6 +84,12 @@ func (p *OllamaProvider) Generate(ctx context.Context, req GenerateRequest) (*Ge
 		}
 		ollamaReq.Options["num_predict"] = req.MaxTokens
 	}
+	if req.Seed != nil {
+		if ollamaReq.Options == nil {
+			ollamaReq.Options = make(map[string]interface{})
+		}
+		ollamaReq.Options["seed"] = *req.Seed
+	}
 
 	// Make the API call
 	var response api.GenerateResponse
@@ -88,19 +99,19 @@ func (p *OllamaProvider) Generate(ctx context.Context, req GenerateRequest) (*Ge
 	})
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate completion: %w", err)
+		return nil, asContextTooLong(ProviderOllama, model, fmt.Errorf("failed to generate completion: %w", err))
 	}
 
 	return &GenerateResponse{
 		Content:    response.Response,
-		Model:      p.config.Model,
+		Model:      model,
 		TokensUsed: 0, // Ollama doesn't provide token usage
 	}, nil
 }
 
 // GetEmbedding delegates to standardized embedding to ensure 1536 dimensions
 func (p *OllamaProvider) GetEmbedding(ctx context.Context, text string, registry RegistryInterface) ([]float32, error) {
-	logger := log.GetLogger().WithFields(logrus.Fields{
+	logger := log.FromContext(ctx).WithFields(logrus.Fields{
 		"provider": p.Name(),
 	})
 	logger.Info("OllamaProvider delegating embedding to standardized provider")
@@ -129,6 +140,26 @@ func (p *OllamaProvider) IsAvailable() bool {
 	return err == nil
 }
 
+// Ping times a heartbeat request to the Ollama server
+func (p *OllamaProvider) Ping(ctx context.Context) (time.Duration, error) {
+	start := time.Now()
+	err := p.client.Heartbeat(ctx)
+	return time.Since(start), err
+}
+
+// ListModels lists the models pulled to the Ollama server
+func (p *OllamaProvider) ListModels(ctx context.Context) ([]string, error) {
+	resp, err := p.client.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Ollama models: %w", err)
+	}
+	models := make([]string, 0, len(resp.Models))
+	for _, model := range resp.Models {
+		models = append(models, model.Name)
+	}
+	return models, nil
+}
+
 // SupportsEmbeddings checks if the provider supports embedding generation
 func (p *OllamaProvider) SupportsEmbeddings() bool {
 	return true // Ollama supports embeddings with appropriate models
@@ -3,6 +3,8 @@ package providers
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	log "github.com/jonwraymond/prompt-alchemy/internal/log"
 
@@ -34,8 +36,9 @@ func NewOpenAIProvider(config Config) *OpenAIProvider {
 	}
 }
 
-// Generate creates a prompt using OpenAI's official SDK
-func (p *OpenAIProvider) Generate(ctx context.Context, req GenerateRequest) (*GenerateResponse, error) {
+// chatParams builds the chat completion request for req, returning it with
+// the model it asks for
+func (p *OpenAIProvider) chatParams(req GenerateRequest) (openai.ChatCompletionNewParams, string) {
 	messages := []openai.ChatCompletionMessageParamUnion{}
 
 	// Add system prompt if provided
@@ -54,6 +57,9 @@ func (p *OpenAIProvider) Generate(ctx context.Context, req GenerateRequest) (*Ge
 
 	// Use configured model or default
 	model := p.config.Model
+	if req.Model != "" {
+		model = req.Model
+	}
 	if model == "" {
 		model = "o4-mini" // Default to o4-mini
 	}
@@ -77,11 +83,20 @@ func (p *OpenAIProvider) Generate(ctx context.Context, req GenerateRequest) (*Ge
 			params.MaxTokens = openai.Int(int64(req.MaxTokens))
 		}
 	}
+	if req.Seed != nil {
+		params.Seed = openai.Int(int64(*req.Seed))
+	}
+	return params, model
+}
+
+// Generate creates a prompt using OpenAI's official SDK
+func (p *OpenAIProvider) Generate(ctx context.Context, req GenerateRequest) (*GenerateResponse, error) {
+	params, model := p.chatParams(req)
 
 	// Make the API call
 	response, err := p.client.Chat.Completions.New(ctx, params)
 	if err != nil {
-		return nil, fmt.Errorf("OpenAI API call failed: %w", err)
+		return nil, asContextTooLong(ProviderOpenAI, model, fmt.Errorf("OpenAI API call failed: %w", err))
 	}
 
 	// Extract the response
@@ -94,21 +109,85 @@ func (p *OpenAIProvider) Generate(ctx context.Context, req GenerateRequest) (*Ge
 
 	// Build the response
 	genResponse := &GenerateResponse{
-		Content: content,
-		Model:   model,
+		Content:           content,
+		Model:             model,
+		SystemFingerprint: response.SystemFingerprint,
 	}
 
 	// Add usage information if available
 	if response.Usage.TotalTokens > 0 {
 		genResponse.TokensUsed = int(response.Usage.TotalTokens)
+		genResponse.InputTokens = int(response.Usage.PromptTokens)
+		genResponse.OutputTokens = int(response.Usage.CompletionTokens)
 	}
 
 	return genResponse, nil
 }
 
+// GenerateStream creates a prompt like Generate, sending the text on the
+// returned channel as it arrives. The last chunk has Done set, and Error if
+// the stream failed.
+func (p *OpenAIProvider) GenerateStream(ctx context.Context, req GenerateRequest) (<-chan GenerateResponseChunk, error) {
+	params, model := p.chatParams(req)
+	return streamChatCompletion(ctx, &p.client.Chat.Completions, params, ProviderOpenAI, "OpenAI", model)
+}
+
+// streamChatCompletion streams a chat completion from an OpenAI-compatible
+// API for provider, see OpenAIProvider.GenerateStream. label names the API
+// in errors.
+func streamChatCompletion(ctx context.Context, completions *openai.ChatCompletionService, params openai.ChatCompletionNewParams, provider, label, model string) (<-chan GenerateResponseChunk, error) {
+	params.StreamOptions = openai.ChatCompletionStreamOptionsParam{IncludeUsage: openai.Bool(true)}
+
+	stream := completions.NewStreaming(ctx, params)
+	if err := stream.Err(); err != nil {
+		_ = stream.Close()
+		return nil, asContextTooLong(provider, model, fmt.Errorf("%s API call failed: %w", label, err))
+	}
+
+	chunks := make(chan GenerateResponseChunk)
+	go func() {
+		defer close(chunks)
+		defer func() { _ = stream.Close() }()
+
+		send := func(chunk GenerateResponseChunk) bool {
+			select {
+			case chunks <- chunk:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		tokens := 0
+		fingerprint := ""
+		for stream.Next() {
+			chunk := stream.Current()
+			if chunk.Model != "" {
+				model = chunk.Model
+			}
+			if chunk.SystemFingerprint != "" {
+				fingerprint = chunk.SystemFingerprint
+			}
+			if chunk.Usage.TotalTokens > 0 {
+				tokens = int(chunk.Usage.TotalTokens)
+			}
+			if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
+				if !send(GenerateResponseChunk{ContentDelta: chunk.Choices[0].Delta.Content, Model: model}) {
+					return
+				}
+			}
+		}
+		var err error
+		if stream.Err() != nil {
+			err = fmt.Errorf("%s stream failed: %w", label, stream.Err())
+		}
+		send(GenerateResponseChunk{Model: model, TokensUsed: tokens, Done: true, Error: err, SystemFingerprint: fingerprint})
+	}()
+	return chunks, nil
+}
+
 // GetEmbedding returns embeddings for the given text using OpenAI's embedding API
 func (p *OpenAIProvider) GetEmbedding(ctx context.Context, text string, registry RegistryInterface) ([]float32, error) {
-	logger := log.GetLogger()
+	logger := log.FromContext(ctx)
 	logger.Debug("OpenAIProvider: Getting embedding")
 
 	model := "text-embedding-3-small" // Standard model for all embeddings (1536 dimensions)
@@ -150,6 +229,48 @@ func (p *OpenAIProvider) IsAvailable() bool {
 	return p.config.APIKey != ""
 }
 
+// Ping times a models listing request
+func (p *OpenAIProvider) Ping(ctx context.Context) (time.Duration, error) {
+	if !p.IsAvailable() {
+		return 0, ErrNotConfigured
+	}
+	baseURL := p.config.BaseURL
+	if baseURL == "" {
+		baseURL = "https://api.openai.com/v1"
+	}
+	return pingHTTP(ctx, strings.TrimSuffix(baseURL, "/")+"/models", map[string]string{
+		"Authorization": "Bearer " + p.config.APIKey,
+	})
+}
+
+// ListModels lists the models of the OpenAI API
+func (p *OpenAIProvider) ListModels(ctx context.Context) ([]string, error) {
+	if !p.IsAvailable() {
+		return nil, ErrNotConfigured
+	}
+	baseURL := p.config.BaseURL
+	if baseURL == "" {
+		baseURL = "https://api.openai.com/v1"
+	}
+	return listModelsHTTP(ctx, strings.TrimSuffix(baseURL, "/")+"/models", map[string]string{
+		"Authorization": "Bearer " + p.config.APIKey,
+	})
+}
+
+// Moderate scores text with OpenAI's moderation endpoint
+func (p *OpenAIProvider) Moderate(ctx context.Context, text string) (float64, error) {
+	if !p.IsAvailable() {
+		return 0, ErrNotConfigured
+	}
+	baseURL := p.config.BaseURL
+	if baseURL == "" {
+		baseURL = "https://api.openai.com/v1"
+	}
+	return moderateHTTP(ctx, strings.TrimSuffix(baseURL, "/")+"/moderations", DefaultOpenAIModerationModel, text, map[string]string{
+		"Authorization": "Bearer " + p.config.APIKey,
+	})
+}
+
 // SupportsEmbeddings checks if the provider supports embedding generation
 func (p *OpenAIProvider) SupportsEmbeddings() bool {
 	return true // OpenAI supports embeddings
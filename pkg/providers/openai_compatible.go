@@ -0,0 +1,277 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jonwraymond/prompt-alchemy/internal/log"
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+	"github.com/spf13/viper"
+)
+
+// builtinProviders are the names custom providers can't take
+var builtinProviders = []string{
+	ProviderOpenAI, ProviderAnthropic, ProviderGoogle, ProviderOllama, ProviderOpenRouter, ProviderGrok,
+	ProviderMistral, ProviderAzure, ProviderBedrock, ProviderDeepSeek, ProviderCohere, ProviderPerplexity,
+}
+
+// OpenAICompatibleConfig describes an endpoint speaking the OpenAI chat
+// completions API, such as Together, Groq, Fireworks or LM Studio
+type OpenAICompatibleConfig struct {
+	BaseURL string   `mapstructure:"base_url"`
+	APIKey  string   `mapstructure:"api_key"` // optional for local servers
+	Models  []string `mapstructure:"models"`  // the first is used when a request names none
+
+	// Embeddings is set when the endpoint serves /embeddings with
+	// EmbeddingModel; otherwise embeddings come from another provider
+	Embeddings     bool   `mapstructure:"embeddings"`
+	EmbeddingModel string `mapstructure:"embedding_model"`
+}
+
+// CustomProviderConfig is an entry of providers.custom
+type CustomProviderConfig struct {
+	Name                   string `mapstructure:"name"`
+	OpenAICompatibleConfig `mapstructure:",squash"`
+}
+
+// OpenAICompatibleProvider implements the Provider interface for any
+// OpenAI-compatible endpoint, registered under a name of its own
+type OpenAICompatibleProvider struct {
+	name   string
+	client openai.Client
+	config OpenAICompatibleConfig
+}
+
+// NewOpenAICompatibleProvider creates a provider called name for the
+// endpoint at config.BaseURL
+func NewOpenAICompatibleProvider(name string, config OpenAICompatibleConfig) *OpenAICompatibleProvider {
+	// The key is always set, so a keyless endpoint isn't sent OPENAI_API_KEY
+	client := openai.NewClient(
+		option.WithAPIKey(config.APIKey),
+		option.WithBaseURL(config.BaseURL),
+	)
+	return &OpenAICompatibleProvider{name: name, client: client, config: config}
+}
+
+// LoadCustomProviders reads the endpoints listed under providers.custom,
+// failing when an entry has no name or models, takes the name of a
+// built-in or earlier provider, has no valid http or https base URL, or
+// enables embeddings without an embedding model
+func LoadCustomProviders() ([]CustomProviderConfig, error) {
+	var configs []CustomProviderConfig
+	if err := viper.UnmarshalKey("providers.custom", &configs); err != nil {
+		return nil, fmt.Errorf("invalid providers.custom: %w", err)
+	}
+
+	var errs []error
+	seen := make(map[string]bool)
+	for i, config := range configs {
+		switch {
+		case config.Name == "":
+			errs = append(errs, fmt.Errorf("providers.custom[%d]: name is required", i))
+			continue
+		case slices.Contains(builtinProviders, config.Name):
+			errs = append(errs, fmt.Errorf("providers.custom[%d]: %q is a built-in provider", i, config.Name))
+		case seen[config.Name]:
+			errs = append(errs, fmt.Errorf("providers.custom[%d]: %q is defined more than once", i, config.Name))
+		}
+		seen[config.Name] = true
+
+		if u, err := url.Parse(config.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("providers.custom[%d] (%s): base_url must be an http or https URL", i, config.Name))
+		}
+		if len(config.Models) == 0 {
+			errs = append(errs, fmt.Errorf("providers.custom[%d] (%s): at least one model is required", i, config.Name))
+		}
+		if config.Embeddings && config.EmbeddingModel == "" {
+			errs = append(errs, fmt.Errorf("providers.custom[%d] (%s): embedding_model is required with embeddings", i, config.Name))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return configs, nil
+}
+
+// RegisterCustomProviders registers every endpoint listed under
+// providers.custom, returning their names. Nothing is registered when the
+// list is invalid.
+func RegisterCustomProviders(registry *Registry) ([]string, error) {
+	configs, err := LoadCustomProviders()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(configs))
+	for _, config := range configs {
+		if err := registry.Register(config.Name, NewOpenAICompatibleProvider(config.Name, config.OpenAICompatibleConfig)); err != nil {
+			return names, fmt.Errorf("failed to register custom provider %s: %w", config.Name, err)
+		}
+		names = append(names, config.Name)
+	}
+	return names, nil
+}
+
+// CustomProviderModels returns the models configured for the custom
+// provider called name, or nil if there is none
+func CustomProviderModels(name string) []string {
+	configs, err := LoadCustomProviders()
+	if err != nil {
+		return nil
+	}
+	for _, config := range configs {
+		if config.Name == name {
+			return config.Models
+		}
+	}
+	return nil
+}
+
+// chatParams builds the chat completion request for req, returning it with
+// the model it asks for
+func (p *OpenAICompatibleProvider) chatParams(req GenerateRequest) (openai.ChatCompletionNewParams, string) {
+	messages := []openai.ChatCompletionMessageParamUnion{}
+	if req.SystemPrompt != "" {
+		messages = append(messages, openai.SystemMessage(req.SystemPrompt))
+	}
+	for _, example := range req.Examples {
+		messages = append(messages, openai.UserMessage(example.Input))
+		messages = append(messages, openai.AssistantMessage(example.Output))
+	}
+	messages = append(messages, openai.UserMessage(req.Prompt))
+
+	model := req.Model
+	if model == "" && len(p.config.Models) > 0 {
+		model = p.config.Models[0]
+	}
+
+	params := openai.ChatCompletionNewParams{
+		Model:    openai.ChatModel(model),
+		Messages: messages,
+	}
+	if req.Temperature > 0 {
+		params.Temperature = openai.Float(req.Temperature)
+	}
+	if req.MaxTokens > 0 {
+		params.MaxTokens = openai.Int(int64(req.MaxTokens))
+	}
+	if req.Seed != nil {
+		params.Seed = openai.Int(int64(*req.Seed))
+	}
+	return params, model
+}
+
+// Generate creates a prompt with the endpoint's chat completions API
+func (p *OpenAICompatibleProvider) Generate(ctx context.Context, req GenerateRequest) (*GenerateResponse, error) {
+	params, model := p.chatParams(req)
+
+	response, err := p.client.Chat.Completions.New(ctx, params)
+	if err != nil {
+		return nil, asContextTooLong(p.name, model, fmt.Errorf("%s API call failed: %w", p.name, err))
+	}
+	if len(response.Choices) == 0 {
+		return nil, fmt.Errorf("no choices returned from %s API", p.name)
+	}
+
+	genResponse := &GenerateResponse{
+		Content:           response.Choices[0].Message.Content,
+		Model:             model,
+		SystemFingerprint: response.SystemFingerprint,
+	}
+	if response.Usage.TotalTokens > 0 {
+		genResponse.TokensUsed = int(response.Usage.TotalTokens)
+		genResponse.InputTokens = int(response.Usage.PromptTokens)
+		genResponse.OutputTokens = int(response.Usage.CompletionTokens)
+	}
+	return genResponse, nil
+}
+
+// GenerateStream creates a prompt like Generate, sending the text on the
+// returned channel as it arrives
+func (p *OpenAICompatibleProvider) GenerateStream(ctx context.Context, req GenerateRequest) (<-chan GenerateResponseChunk, error) {
+	params, model := p.chatParams(req)
+	return streamChatCompletion(ctx, &p.client.Chat.Completions, params, p.name, p.name, model)
+}
+
+// GetEmbedding embeds text with the endpoint's embedding model, or
+// delegates to the standardized embedding provider when the endpoint
+// doesn't serve embeddings
+func (p *OpenAICompatibleProvider) GetEmbedding(ctx context.Context, text string, registry RegistryInterface) ([]float32, error) {
+	if !p.config.Embeddings {
+		log.FromContext(ctx).WithField("provider", p.name).Debug("Delegating embedding to standardized provider")
+		return getStandardizedEmbedding(ctx, text, registry)
+	}
+
+	response, err := p.client.Embeddings.New(ctx, openai.EmbeddingNewParams{
+		Input: openai.EmbeddingNewParamsInputUnion{
+			OfString: openai.String(text),
+		},
+		Model: openai.EmbeddingModel(p.config.EmbeddingModel),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create embedding: %w", err)
+	}
+	if len(response.Data) == 0 {
+		return nil, fmt.Errorf("no embedding data returned")
+	}
+
+	embedding := make([]float32, len(response.Data[0].Embedding))
+	for i, v := range response.Data[0].Embedding {
+		embedding[i] = float32(v)
+	}
+	return embedding, nil
+}
+
+// Name returns the name the provider was registered under
+func (p *OpenAICompatibleProvider) Name() string {
+	return p.name
+}
+
+// IsAvailable checks if the provider has an endpoint; the API key is
+// optional
+func (p *OpenAICompatibleProvider) IsAvailable() bool {
+	return p.config.BaseURL != ""
+}
+
+// headers returns the headers authenticating a request to the endpoint
+func (p *OpenAICompatibleProvider) headers() map[string]string {
+	if p.config.APIKey == "" {
+		return nil
+	}
+	return map[string]string{"Authorization": "Bearer " + p.config.APIKey}
+}
+
+// Ping times a models listing request
+func (p *OpenAICompatibleProvider) Ping(ctx context.Context) (time.Duration, error) {
+	if !p.IsAvailable() {
+		return 0, ErrNotConfigured
+	}
+	return pingHTTP(ctx, strings.TrimSuffix(p.config.BaseURL, "/")+"/models", p.headers())
+}
+
+// ListModels lists the configured models, or the endpoint's when none are
+// configured
+func (p *OpenAICompatibleProvider) ListModels(ctx context.Context) ([]string, error) {
+	if len(p.config.Models) > 0 {
+		return slices.Clone(p.config.Models), nil
+	}
+	if !p.IsAvailable() {
+		return nil, ErrNotConfigured
+	}
+	return listModelsHTTP(ctx, strings.TrimSuffix(p.config.BaseURL, "/")+"/models", p.headers())
+}
+
+// SupportsEmbeddings reports whether the endpoint serves embeddings
+func (p *OpenAICompatibleProvider) SupportsEmbeddings() bool {
+	return p.config.Embeddings
+}
+
+// SupportsStreaming checks if the provider supports streaming generation
+func (p *OpenAICompatibleProvider) SupportsStreaming() bool {
+	return true
+}
@@ -0,0 +1,199 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newCompatibleServer mocks an OpenAI-compatible endpoint, recording the
+// model and authorization header of each request
+func newCompatibleServer(t *testing.T) (*httptest.Server, *[]string, *[]string) {
+	t.Helper()
+	var models, auths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		model, _ := body["model"].(string)
+		models = append(models, model)
+		auths = append(auths, r.Header.Get("Authorization"))
+
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/chat/completions":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"id":      "chatcmpl-1",
+				"object":  "chat.completion",
+				"created": 1700000000,
+				"model":   model,
+				"choices": []interface{}{map[string]interface{}{
+					"index":         0,
+					"message":       map[string]interface{}{"role": "assistant", "content": "Hello from " + model},
+					"finish_reason": "stop",
+				}},
+				"usage": map[string]interface{}{"prompt_tokens": 5, "completion_tokens": 7, "total_tokens": 12},
+			})
+		case "/embeddings":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"object": "list",
+				"model":  model,
+				"data": []interface{}{map[string]interface{}{
+					"object":    "embedding",
+					"index":     0,
+					"embedding": []float64{0.25, 0.5, 0.75},
+				}},
+				"usage": map[string]interface{}{"prompt_tokens": 3, "total_tokens": 3},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &models, &auths
+}
+
+func TestRegisterCustomProviders(t *testing.T) {
+	defer viper.Reset()
+	viper.Set("providers.custom", []map[string]interface{}{
+		{
+			"name":     "together",
+			"base_url": "https://api.together.xyz/v1",
+			"api_key":  "together-key",
+			"models":   []string{"meta-llama/Llama-3.3-70B-Instruct-Turbo"},
+		},
+		{
+			"name":            "lmstudio",
+			"base_url":        "http://localhost:1234/v1",
+			"models":          []string{"qwen2.5-7b-instruct", "llama-3.2-3b-instruct"},
+			"embeddings":      true,
+			"embedding_model": "nomic-embed-text-v1.5",
+		},
+	})
+
+	registry := NewRegistry()
+	names, err := RegisterCustomProviders(registry)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"together", "lmstudio"}, names)
+
+	assert.ElementsMatch(t, []string{"together", "lmstudio"}, registry.ListAvailable())
+	assert.Equal(t, []string{"lmstudio"}, registry.ListEmbeddingCapableProviders())
+
+	provider, err := registry.Get("lmstudio")
+	require.NoError(t, err)
+	assert.Equal(t, "lmstudio", provider.Name())
+	lister, ok := AsModelLister(provider)
+	require.True(t, ok)
+	models, err := lister.ListModels(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"qwen2.5-7b-instruct", "llama-3.2-3b-instruct"}, models)
+
+	assert.Equal(t, []string{"qwen2.5-7b-instruct", "llama-3.2-3b-instruct"}, CustomProviderModels("lmstudio"))
+	assert.Nil(t, CustomProviderModels("unknown"))
+}
+
+func TestLoadCustomProviders_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		entry   map[string]interface{}
+		wantErr string
+	}{
+		{
+			name:    "missing name",
+			entry:   map[string]interface{}{"base_url": "http://localhost:1234/v1", "models": []string{"m"}},
+			wantErr: "name is required",
+		},
+		{
+			name:    "built-in name",
+			entry:   map[string]interface{}{"name": ProviderOpenAI, "base_url": "http://localhost:1234/v1", "models": []string{"m"}},
+			wantErr: "built-in provider",
+		},
+		{
+			name:    "invalid base URL",
+			entry:   map[string]interface{}{"name": "local", "base_url": "localhost:1234", "models": []string{"m"}},
+			wantErr: "base_url must be an http or https URL",
+		},
+		{
+			name:    "no models",
+			entry:   map[string]interface{}{"name": "local", "base_url": "http://localhost:1234/v1"},
+			wantErr: "at least one model is required",
+		},
+		{
+			name:    "embeddings without a model",
+			entry:   map[string]interface{}{"name": "local", "base_url": "http://localhost:1234/v1", "models": []string{"m"}, "embeddings": true},
+			wantErr: "embedding_model is required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer viper.Reset()
+			viper.Set("providers.custom", []map[string]interface{}{tt.entry})
+
+			registry := NewRegistry()
+			_, err := RegisterCustomProviders(registry)
+			assert.ErrorContains(t, err, tt.wantErr)
+			assert.Empty(t, registry.ListAvailable())
+		})
+	}
+
+	t.Run("duplicate names", func(t *testing.T) {
+		defer viper.Reset()
+		entry := map[string]interface{}{"name": "local", "base_url": "http://localhost:1234/v1", "models": []string{"m"}}
+		viper.Set("providers.custom", []map[string]interface{}{entry, entry})
+
+		_, err := LoadCustomProviders()
+		assert.ErrorContains(t, err, "defined more than once")
+	})
+}
+
+func TestOpenAICompatibleProvider_Generate(t *testing.T) {
+	server, models, auths := newCompatibleServer(t)
+
+	t.Run("default model with key", func(t *testing.T) {
+		provider := NewOpenAICompatibleProvider("together", OpenAICompatibleConfig{
+			BaseURL: server.URL,
+			APIKey:  "together-key",
+			Models:  []string{"llama-70b", "llama-8b"},
+		})
+		response, err := provider.Generate(context.Background(), GenerateRequest{Prompt: "Hi"})
+		require.NoError(t, err)
+		assert.Equal(t, "Hello from llama-70b", response.Content)
+		assert.Equal(t, "llama-70b", response.Model)
+		assert.Equal(t, 12, response.TokensUsed)
+		assert.Equal(t, "llama-70b", (*models)[len(*models)-1])
+		assert.Equal(t, "Bearer together-key", (*auths)[len(*auths)-1])
+	})
+
+	t.Run("requested model without key", func(t *testing.T) {
+		t.Setenv("OPENAI_API_KEY", "openai-key")
+		provider := NewOpenAICompatibleProvider("lmstudio", OpenAICompatibleConfig{
+			BaseURL: server.URL,
+			Models:  []string{"qwen"},
+		})
+		response, err := provider.Generate(context.Background(), GenerateRequest{Prompt: "Hi", Model: "llama"})
+		require.NoError(t, err)
+		assert.Equal(t, "llama", response.Model)
+		assert.NotContains(t, (*auths)[len(*auths)-1], "openai-key")
+	})
+}
+
+func TestOpenAICompatibleProvider_GetEmbedding(t *testing.T) {
+	server, models, _ := newCompatibleServer(t)
+
+	provider := NewOpenAICompatibleProvider("lmstudio", OpenAICompatibleConfig{
+		BaseURL:        server.URL,
+		Models:         []string{"qwen"},
+		Embeddings:     true,
+		EmbeddingModel: "nomic-embed",
+	})
+	embedding, err := provider.GetEmbedding(context.Background(), "hello", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []float32{0.25, 0.5, 0.75}, embedding)
+	assert.Equal(t, "nomic-embed", (*models)[len(*models)-1])
+}
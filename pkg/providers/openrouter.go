@@ -3,6 +3,7 @@ package providers
 import (
 	"context"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -24,11 +25,16 @@ func NewOpenRouterProvider(config Config) *OpenRouterProvider {
 
 // Generate generates a prompt using the OpenRouter API
 func (p *OpenRouterProvider) Generate(ctx context.Context, req GenerateRequest) (*GenerateResponse, error) {
+	model := p.config.Model
+	if req.Model != "" {
+		model = req.Model
+	}
+
 	// Placeholder implementation
 	return &GenerateResponse{
 		Content:    "This is a placeholder response from the OpenRouter provider.",
 		TokensUsed: 10,
-		Model:      p.config.Model,
+		Model:      model,
 	}, nil
 }
 
@@ -47,6 +53,34 @@ func (p *OpenRouterProvider) IsAvailable() bool {
 	return p.config.APIKey != ""
 }
 
+// Ping times a models listing request
+func (p *OpenRouterProvider) Ping(ctx context.Context) (time.Duration, error) {
+	if !p.IsAvailable() {
+		return 0, ErrNotConfigured
+	}
+	baseURL := p.config.BaseURL
+	if baseURL == "" {
+		baseURL = "https://openrouter.ai/api/v1"
+	}
+	return pingHTTP(ctx, strings.TrimSuffix(baseURL, "/")+"/models", map[string]string{
+		"Authorization": "Bearer " + p.config.APIKey,
+	})
+}
+
+// ListModels lists the models routed by OpenRouter
+func (p *OpenRouterProvider) ListModels(ctx context.Context) ([]string, error) {
+	if !p.IsAvailable() {
+		return nil, ErrNotConfigured
+	}
+	baseURL := p.config.BaseURL
+	if baseURL == "" {
+		baseURL = "https://openrouter.ai/api/v1"
+	}
+	return listModelsHTTP(ctx, strings.TrimSuffix(baseURL, "/")+"/models", map[string]string{
+		"Authorization": "Bearer " + p.config.APIKey,
+	})
+}
+
 // SupportsEmbeddings checks if the provider supports embeddings
 func (p *OpenRouterProvider) SupportsEmbeddings() bool {
 	return true
@@ -0,0 +1,189 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jonwraymond/prompt-alchemy/internal/log"
+	"github.com/jonwraymond/prompt-alchemy/pkg/security"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+)
+
+const (
+	DefaultPerplexityModel = "sonar"
+
+	defaultPerplexityBaseURL = "https://api.perplexity.ai"
+)
+
+// PerplexityProvider implements the Provider interface for Perplexity's
+// web-grounded sonar models using the OpenAI-compatible SDK, since
+// Perplexity's chat API shares its shape.
+//
+// Answers are grounded in a web search; the sources are returned as
+// citations next to the choices, which the SDK doesn't know about, and are
+// passed on in GenerateResponse.Citations. Perplexity has no embeddings API,
+// so embedding requests are delegated to the standardized provider.
+type PerplexityProvider struct {
+	client  openai.Client
+	config  Config
+	baseURL string
+}
+
+// NewPerplexityProvider creates a new Perplexity provider
+func NewPerplexityProvider(config Config) *PerplexityProvider {
+	baseURL := config.BaseURL
+	if baseURL == "" {
+		baseURL = defaultPerplexityBaseURL
+	}
+
+	// Validate the base URL for security
+	if err := security.ValidateBaseURL(baseURL); err != nil {
+		log.GetLogger().Errorf("Invalid base URL for Perplexity provider: %v", err)
+		baseURL = defaultPerplexityBaseURL
+	}
+
+	opts := []option.RequestOption{
+		option.WithAPIKey(config.APIKey),
+		option.WithBaseURL(baseURL),
+	}
+	if config.Timeout > 0 {
+		opts = append(opts, option.WithRequestTimeout(time.Duration(config.Timeout)*time.Second))
+	}
+
+	return &PerplexityProvider{
+		client:  openai.NewClient(opts...),
+		config:  config,
+		baseURL: baseURL,
+	}
+}
+
+// Generate creates a prompt using Perplexity's chat completions API
+func (p *PerplexityProvider) Generate(ctx context.Context, req GenerateRequest) (*GenerateResponse, error) {
+	messages := []openai.ChatCompletionMessageParamUnion{}
+
+	// Add system prompt if provided
+	if req.SystemPrompt != "" {
+		messages = append(messages, openai.SystemMessage(req.SystemPrompt))
+	}
+
+	// Add examples if provided
+	for _, example := range req.Examples {
+		messages = append(messages, openai.UserMessage(example.Input))
+		messages = append(messages, openai.AssistantMessage(example.Output))
+	}
+
+	messages = append(messages, openai.UserMessage(req.Prompt))
+
+	model := p.config.Model
+	if req.Model != "" {
+		model = req.Model
+	}
+	if model == "" {
+		model = DefaultPerplexityModel
+	}
+
+	params := openai.ChatCompletionNewParams{
+		Model:    openai.ChatModel(model),
+		Messages: messages,
+	}
+	if req.Temperature > 0 {
+		params.Temperature = openai.Float(ClampTemperature(ProviderPerplexity, req.Temperature))
+	}
+	if req.MaxTokens > 0 {
+		params.MaxTokens = openai.Int(int64(req.MaxTokens))
+	}
+
+	response, err := p.client.Chat.Completions.New(ctx, params)
+	if err != nil {
+		return nil, asContextTooLong(ProviderPerplexity, model, fmt.Errorf("perplexity API call failed: %w", err))
+	}
+
+	if len(response.Choices) == 0 {
+		return nil, fmt.Errorf("no choices returned from Perplexity API")
+	}
+
+	genResponse := &GenerateResponse{
+		Content:   response.Choices[0].Message.Content,
+		Model:     model,
+		Citations: perplexityCitations(response.RawJSON()),
+	}
+	if response.Usage.TotalTokens > 0 {
+		genResponse.TokensUsed = int(response.Usage.TotalTokens)
+		genResponse.InputTokens = int(response.Usage.PromptTokens)
+		genResponse.OutputTokens = int(response.Usage.CompletionTokens)
+	}
+
+	return genResponse, nil
+}
+
+// perplexityCitations returns the source URLs of a raw response: its
+// citations, or the URLs of its search_results when it has none
+func perplexityCitations(raw string) []string {
+	var response struct {
+		Citations     []string `json:"citations"`
+		SearchResults []struct {
+			URL string `json:"url"`
+		} `json:"search_results"`
+	}
+	if raw == "" || json.Unmarshal([]byte(raw), &response) != nil {
+		return nil
+	}
+	if len(response.Citations) > 0 {
+		return response.Citations
+	}
+	var citations []string
+	for _, result := range response.SearchResults {
+		if result.URL != "" {
+			citations = append(citations, result.URL)
+		}
+	}
+	return citations
+}
+
+// GetEmbedding delegates to the standardized provider, Perplexity has no
+// embeddings API
+func (p *PerplexityProvider) GetEmbedding(ctx context.Context, text string, registry RegistryInterface) ([]float32, error) {
+	log.FromContext(ctx).WithField("provider", p.Name()).Info("PerplexityProvider delegating embedding to standardized provider")
+	return getStandardizedEmbedding(ctx, text, registry)
+}
+
+// Name returns the provider name
+func (p *PerplexityProvider) Name() string {
+	return ProviderPerplexity
+}
+
+// IsAvailable checks if the provider is configured
+func (p *PerplexityProvider) IsAvailable() bool {
+	return p.config.APIKey != ""
+}
+
+// Ping times an unbilled GET of the chat completions endpoint, Perplexity
+// has no models listing; it still checks the API key
+func (p *PerplexityProvider) Ping(ctx context.Context) (time.Duration, error) {
+	if !p.IsAvailable() {
+		return 0, ErrNotConfigured
+	}
+	return pingHTTP(ctx, strings.TrimSuffix(p.baseURL, "/")+"/chat/completions", map[string]string{
+		"Authorization": "Bearer " + p.config.APIKey,
+	})
+}
+
+// SupportsEmbeddings returns false, Perplexity has no embeddings API
+func (p *PerplexityProvider) SupportsEmbeddings() bool {
+	return false
+}
+
+// SupportsStreaming returns false, only whole responses carry citations
+func (p *PerplexityProvider) SupportsStreaming() bool {
+	return false
+}
+
+// PerplexityModels lists the sonar models
+func PerplexityModels() []string {
+	return []string{DefaultPerplexityModel, "sonar-pro", "sonar-reasoning", "sonar-reasoning-pro", "sonar-deep-research"}
+}
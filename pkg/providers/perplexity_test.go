@@ -0,0 +1,108 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newPerplexityServer mocks Perplexity's chat completions API, answering
+// with extra top-level fields such as citations and recording each request
+// body
+func newPerplexityServer(t *testing.T, extra map[string]interface{}) (*httptest.Server, *[]map[string]interface{}) {
+	t.Helper()
+	var requests []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/chat/completions", r.URL.Path)
+		assert.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		requests = append(requests, body)
+
+		response := map[string]interface{}{
+			"id":      "pplx-1",
+			"object":  "chat.completion",
+			"created": 1700000000,
+			"model":   body["model"],
+			"choices": []interface{}{map[string]interface{}{
+				"index":         0,
+				"message":       map[string]interface{}{"role": "assistant", "content": "Go 1.23 added range-over-func [1]."},
+				"finish_reason": "stop",
+			}},
+			"usage": map[string]interface{}{"prompt_tokens": 9, "completion_tokens": 11, "total_tokens": 20},
+		}
+		for key, value := range extra {
+			response[key] = value
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestNewPerplexityProvider(t *testing.T) {
+	provider := NewPerplexityProvider(Config{APIKey: "test-key"})
+	assert.Equal(t, defaultPerplexityBaseURL, provider.baseURL)
+	assert.Equal(t, ProviderPerplexity, provider.Name())
+	assert.True(t, provider.IsAvailable())
+	assert.False(t, provider.SupportsEmbeddings())
+	assert.False(t, NewPerplexityProvider(Config{}).IsAvailable())
+
+	provider = NewPerplexityProvider(Config{APIKey: "test-key", BaseURL: "https://evil.example.com"})
+	assert.Equal(t, defaultPerplexityBaseURL, provider.baseURL, "disallowed base URL falls back to default")
+}
+
+func TestPerplexityProvider_Generate(t *testing.T) {
+	ctx := context.Background()
+	req := GenerateRequest{SystemPrompt: "Cite sources", Prompt: "What changed in Go 1.23?", Temperature: 0.2, MaxTokens: 200}
+
+	t.Run("citations are captured", func(t *testing.T) {
+		server, requests := newPerplexityServer(t, map[string]interface{}{
+			"citations": []string{"https://go.dev/doc/go1.23", "https://go.dev/blog/range-functions"},
+		})
+		provider := NewPerplexityProvider(Config{APIKey: "test-key", BaseURL: server.URL})
+
+		resp, err := provider.Generate(ctx, req)
+		require.NoError(t, err)
+		assert.Equal(t, "Go 1.23 added range-over-func [1].", resp.Content)
+		assert.Equal(t, DefaultPerplexityModel, resp.Model)
+		assert.Equal(t, 20, resp.TokensUsed)
+		assert.Equal(t, []string{"https://go.dev/doc/go1.23", "https://go.dev/blog/range-functions"}, resp.Citations)
+
+		require.Len(t, *requests, 1)
+		body := (*requests)[0]
+		assert.Equal(t, DefaultPerplexityModel, body["model"])
+		assert.Equal(t, 0.2, body["temperature"])
+		assert.Len(t, body["messages"], 2)
+	})
+
+	t.Run("search results stand in for citations", func(t *testing.T) {
+		server, _ := newPerplexityServer(t, map[string]interface{}{
+			"search_results": []interface{}{
+				map[string]interface{}{"title": "Go 1.23 Release Notes", "url": "https://go.dev/doc/go1.23"},
+			},
+		})
+		provider := NewPerplexityProvider(Config{APIKey: "test-key", BaseURL: server.URL, Model: "sonar-pro"})
+
+		resp, err := provider.Generate(ctx, req)
+		require.NoError(t, err)
+		assert.Equal(t, "sonar-pro", resp.Model)
+		assert.Equal(t, []string{"https://go.dev/doc/go1.23"}, resp.Citations)
+	})
+
+	t.Run("responses without citations still work", func(t *testing.T) {
+		server, _ := newPerplexityServer(t, nil)
+		provider := NewPerplexityProvider(Config{APIKey: "test-key", BaseURL: server.URL})
+
+		resp, err := provider.Generate(ctx, req)
+		require.NoError(t, err)
+		assert.Equal(t, "Go 1.23 added range-over-func [1].", resp.Content)
+		assert.Empty(t, resp.Citations)
+	})
+}
@@ -0,0 +1,132 @@
+package providers
+
+import (
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// ModelPricing is a model's price in USD per million tokens
+type ModelPricing struct {
+	Input  float64 `mapstructure:"input"`
+	Output float64 `mapstructure:"output"`
+}
+
+// Cost returns the USD cost of a call with the given token counts
+func (p ModelPricing) Cost(inputTokens, outputTokens int) float64 {
+	return (float64(inputTokens)*p.Input + float64(outputTokens)*p.Output) / 1e6
+}
+
+// modelPriceEntry matches every model whose name starts with prefix
+type modelPriceEntry struct {
+	prefix  string
+	pricing ModelPricing
+}
+
+// modelPrices holds list prices, matched by longest prefix like modelLimits.
+// An empty prefix prices every model of the provider. Prices change; set
+// providers.<name>.pricing to override them.
+var modelPrices = map[string][]modelPriceEntry{
+	ProviderOpenAI: {
+		{"o4-mini", ModelPricing{1.10, 4.40}},
+		{"o3", ModelPricing{2.00, 8.00}},
+		{"o1", ModelPricing{15.00, 60.00}},
+		{"gpt-4.1-nano", ModelPricing{0.10, 0.40}},
+		{"gpt-4.1-mini", ModelPricing{0.40, 1.60}},
+		{"gpt-4.1", ModelPricing{2.00, 8.00}},
+		{"gpt-4o-mini", ModelPricing{0.15, 0.60}},
+		{"gpt-4o", ModelPricing{2.50, 10.00}},
+		{"gpt-4-turbo", ModelPricing{10.00, 30.00}},
+		{"gpt-4", ModelPricing{30.00, 60.00}},
+		{"gpt-3.5-turbo", ModelPricing{0.50, 1.50}},
+	},
+	ProviderAnthropic: {
+		{"claude-opus-4", ModelPricing{15.00, 75.00}},
+		{"claude-sonnet-4", ModelPricing{3.00, 15.00}},
+		{"claude-3-7-sonnet", ModelPricing{3.00, 15.00}},
+		{"claude-3-5-sonnet", ModelPricing{3.00, 15.00}},
+		{"claude-3-5-haiku", ModelPricing{0.80, 4.00}},
+		{"claude-3-opus", ModelPricing{15.00, 75.00}},
+		{"claude-3-haiku", ModelPricing{0.25, 1.25}},
+	},
+	ProviderGoogle: {
+		{"gemini-2.5-pro", ModelPricing{1.25, 10.00}},
+		{"gemini-2.5-flash", ModelPricing{0.30, 2.50}},
+		{"gemini-2.0-flash", ModelPricing{0.10, 0.40}},
+		{"gemini-1.5-pro", ModelPricing{1.25, 5.00}},
+		{"gemini-1.5-flash", ModelPricing{0.075, 0.30}},
+	},
+	ProviderGrok: {
+		{"grok-4", ModelPricing{3.00, 15.00}},
+		{"grok-3", ModelPricing{3.00, 15.00}},
+		{"grok-2", ModelPricing{2.00, 10.00}},
+	},
+	ProviderMistral: {
+		{"mistral-large", ModelPricing{2.00, 6.00}},
+		{"mistral-medium", ModelPricing{0.40, 2.00}},
+		{"mistral-small", ModelPricing{0.10, 0.30}},
+		{"codestral", ModelPricing{0.30, 0.90}},
+		{"ministral", ModelPricing{0.10, 0.10}},
+		{"open-mistral-nemo", ModelPricing{0.15, 0.15}},
+	},
+	ProviderDeepSeek: {
+		{"deepseek-chat", ModelPricing{0.28, 0.42}},
+		{"deepseek-reasoner", ModelPricing{0.28, 0.42}},
+	},
+	ProviderCohere: {
+		{"command-a", ModelPricing{2.50, 10.00}},
+		{"command-r-plus", ModelPricing{2.50, 10.00}},
+		{"command-r7b", ModelPricing{0.0375, 0.15}},
+		{"command-r", ModelPricing{0.15, 0.60}},
+	},
+	ProviderPerplexity: {
+		{"sonar", ModelPricing{1.00, 1.00}},
+		{"sonar-pro", ModelPricing{3.00, 15.00}},
+		{"sonar-reasoning", ModelPricing{1.00, 5.00}},
+		{"sonar-reasoning-pro", ModelPricing{2.00, 8.00}},
+		{"sonar-deep-research", ModelPricing{2.00, 8.00}},
+	},
+	ProviderOllama: {
+		{"", ModelPricing{}}, // local models are free
+	},
+}
+
+// LookupPricing returns the price of a provider's model. Entries under
+// providers.<name>.pricing, keyed by model name or prefix, take precedence
+// over the built-in table. The second result is false when the model has no
+// known price.
+func LookupPricing(provider, model string) (ModelPricing, bool) {
+	var configured map[string]ModelPricing
+	if err := viper.UnmarshalKey("providers."+provider+".pricing", &configured); err == nil && len(configured) > 0 {
+		entries := make([]modelPriceEntry, 0, len(configured))
+		for prefix, pricing := range configured {
+			entries = append(entries, modelPriceEntry{prefix, pricing})
+		}
+		if pricing, ok := matchPrice(entries, model); ok {
+			return pricing, true
+		}
+	}
+
+	if provider == ProviderOpenRouter {
+		vendor, name, ok := strings.Cut(model, "/")
+		if !ok {
+			return ModelPricing{}, false
+		}
+		if provider, ok = openRouterVendors[vendor]; !ok {
+			return ModelPricing{}, false
+		}
+		model = name
+	}
+	return matchPrice(modelPrices[provider], model)
+}
+
+// matchPrice returns the entry with the longest prefix of model
+func matchPrice(entries []modelPriceEntry, model string) (ModelPricing, bool) {
+	best, found := modelPriceEntry{}, false
+	for _, entry := range entries {
+		if strings.HasPrefix(model, entry.prefix) && (!found || len(entry.prefix) > len(best.prefix)) {
+			best, found = entry, true
+		}
+	}
+	return best.pricing, found
+}
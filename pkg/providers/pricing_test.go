@@ -0,0 +1,43 @@
+package providers
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookupPricing(t *testing.T) {
+	defer viper.Reset()
+	viper.Set("providers.openai.pricing", map[string]interface{}{
+		"gpt-4o-mini": map[string]interface{}{"input": 1.0, "output": 2.0},
+	})
+
+	tests := []struct {
+		name      string
+		provider  string
+		model     string
+		want      ModelPricing
+		wantFound bool
+	}{
+		{name: "configured override", provider: ProviderOpenAI, model: "gpt-4o-mini-2024-07-18", want: ModelPricing{1.0, 2.0}, wantFound: true},
+		{name: "built-in longest prefix", provider: ProviderOpenAI, model: "gpt-4o-2024-08-06", want: ModelPricing{2.50, 10.00}, wantFound: true},
+		{name: "openrouter vendor mapping", provider: ProviderOpenRouter, model: "anthropic/claude-3-haiku", want: ModelPricing{0.25, 1.25}, wantFound: true},
+		{name: "ollama is free", provider: ProviderOllama, model: "llama3", want: ModelPricing{}, wantFound: true},
+		{name: "unknown model", provider: ProviderOpenAI, model: "davinci-002", wantFound: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pricing, found := LookupPricing(tt.provider, tt.model)
+			assert.Equal(t, tt.wantFound, found)
+			assert.Equal(t, tt.want, pricing)
+		})
+	}
+}
+
+func TestModelPricingCost(t *testing.T) {
+	pricing := ModelPricing{Input: 3.00, Output: 15.00}
+	assert.InDelta(t, 0.0105, pricing.Cost(1000, 500), 1e-9)
+	assert.Zero(t, pricing.Cost(0, 0))
+}
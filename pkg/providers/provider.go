@@ -3,6 +3,8 @@ package providers
 import (
 	"context"
 	"errors"
+	"sync"
+	"time"
 
 	log "github.com/jonwraymond/prompt-alchemy/internal/log"
 	"github.com/jonwraymond/prompt-alchemy/pkg/models"
@@ -16,6 +18,12 @@ const (
 	ProviderOllama     = "ollama"
 	ProviderOpenRouter = "openrouter"
 	ProviderGrok       = "grok"
+	ProviderMistral    = "mistral"
+	ProviderAzure      = "azure"
+	ProviderBedrock    = "bedrock"
+	ProviderDeepSeek   = "deepseek"
+	ProviderCohere     = "cohere"
+	ProviderPerplexity = "perplexity"
 )
 
 const (
@@ -35,6 +43,8 @@ const (
 
 	DefaultGrokModel = "grok-4"
 
+	DefaultOpenAIModerationModel = "omni-moderation-latest"
+
 	DefaultOllamaModel          = "llama3"
 	DefaultOllamaEmbeddingModel = "nomic-embed-text"
 )
@@ -58,8 +68,15 @@ type Provider interface {
 
 	// SupportsStreaming checks if the provider supports streaming generation
 	SupportsStreaming() bool
+
+	// Ping measures the round-trip latency of a cheap request to the
+	// provider's API, such as listing models
+	Ping(ctx context.Context) (time.Duration, error)
 }
 
+// ErrNotConfigured is returned by Ping when the provider has no credentials
+var ErrNotConfigured = errors.New("provider not configured")
+
 // GenerateRequest represents a request to generate a prompt
 type GenerateRequest struct {
 	SystemPrompt string
@@ -68,6 +85,15 @@ type GenerateRequest struct {
 	Temperature  float64
 	MaxTokens    int
 	Stream       bool
+
+	// Seed, when set, asks for deterministic sampling from providers that
+	// support it (see SupportsSeed); the others ignore it
+	Seed *int
+
+	// Model, when set, replaces the provider's configured model for this
+	// request. Azure and Bedrock, whose models are chosen by deployment and
+	// model_map, ignore it.
+	Model string
 }
 
 // Example represents a few-shot learning example
@@ -81,6 +107,29 @@ type GenerateResponse struct {
 	Content    string
 	TokensUsed int
 	Model      string
+
+	// InputTokens and OutputTokens split TokensUsed when the provider
+	// reports them; both are zero otherwise
+	InputTokens  int
+	OutputTokens int
+
+	// Provider and FallbackFrom are set by FallbackProvider: the provider
+	// that served the request, and the primary it fell back from, if any
+	Provider     string
+	FallbackFrom string
+
+	// ModelAliasedFrom is set by ModelAliasProvider: the unavailable model
+	// the request asked for, when an alias served it instead
+	ModelAliasedFrom string
+
+	// SystemFingerprint identifies the backend configuration that served
+	// the request, when the provider reports one. Seeded requests are only
+	// reproducible while it stays the same.
+	SystemFingerprint string
+
+	// Citations are the URLs of the sources a web-grounded answer is based
+	// on, when the provider returns them
+	Citations []string
 }
 
 // GenerateResponseChunk represents a chunk of a streamed generation response
@@ -90,6 +139,14 @@ type GenerateResponseChunk struct {
 	Model        string
 	Done         bool
 	Error        error
+
+	// SystemFingerprint is set on the last chunk, see GenerateResponse
+	SystemFingerprint string
+
+	// Provider and FallbackFrom are set on the last chunk by a
+	// FallbackProvider, see GenerateResponse
+	Provider     string
+	FallbackFrom string
 }
 
 // Config contains provider configuration
@@ -114,6 +171,19 @@ type Config struct {
 	DefaultEmbeddingModel string `mapstructure:"default_embedding_model"`
 	EmbeddingTimeout      int    `mapstructure:"embedding_timeout"`
 	GenerationTimeout     int    `mapstructure:"generation_timeout"`
+
+	// Azure OpenAI-specific configuration
+	Endpoint            string `mapstructure:"endpoint"`
+	Deployment          string `mapstructure:"deployment"`
+	EmbeddingDeployment string `mapstructure:"embedding_deployment"`
+	APIVersion          string `mapstructure:"api_version"`
+
+	// AWS Bedrock-specific configuration
+	Region   string            `mapstructure:"region"`
+	ModelMap map[string]string `mapstructure:"model_map"` // short model names to Bedrock model IDs
+
+	// Cohere-specific configuration
+	RerankModel string `mapstructure:"rerank_model"`
 }
 
 // RegistryInterface defines the methods needed for ranking (subset of full Registry).
@@ -125,32 +195,114 @@ type RegistryInterface interface {
 
 // Registry manages available providers
 type Registry struct {
+	mu        sync.RWMutex // guards providers, which Replace swaps wholesale
 	providers map[string]Provider
+
+	// told each rate-limited provider's utilization, see SetRateLimitObserver,
+	// and each embedding cache lookup, see SetEmbeddingCacheObserver
+	observerMu             sync.RWMutex
+	rateLimitObserver      func(provider string, utilization float64)
+	embeddingCacheObserver func(hit bool)
+
+	// shared by the embedding providers, nil when embeddings.cache.size is unset
+	embeddingCache *EmbeddingCache
+
+	// circuit breakers fed by the health checks, see StartHealthChecks
+	healthMu     sync.Mutex
+	healthConfig HealthConfig
+	breakers     map[string]*CircuitBreaker
+
+	// balancers of the phases with provider pools, see GetBalanced
+	balancerMu sync.Mutex
+	balancers  map[models.Phase]*Balancer
 }
 
 // NewRegistry creates a new provider registry
 func NewRegistry() *Registry {
-	return &Registry{
+	r := &Registry{
 		providers: make(map[string]Provider),
+		healthConfig: HealthConfig{
+			Interval:         DefaultHealthInterval,
+			FailureThreshold: DefaultHealthFailureThreshold,
+			Cooldown:         DefaultHealthInterval,
+			Timeout:          DefaultHealthTimeout,
+		},
+		breakers: make(map[string]*CircuitBreaker),
 	}
+	r.embeddingCache = NewEmbeddingCache(LoadEmbeddingCacheConfig(), r.observeEmbeddingCache)
+	return r
 }
 
-// Register adds a provider to the registry
+// Register adds a provider to the registry. Providers with
+// providers.<name>.model_aliases set retry retired models with their
+// aliases, those with providers.<name>.rate_limit.rpm set are wrapped with a
+// limiter of their own, and with embeddings.cache.size set, embedding
+// providers share a cache of their embeddings in front of it.
 func (r *Registry) Register(name string, provider Provider) error {
 	logger := log.GetLogger()
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	if _, exists := r.providers[name]; exists {
 		logger.Warnf("Provider %s already registered", name)
 		return errors.New("provider already registered")
 	}
 	logger.Debugf("Registering provider: %s", name)
+	if aliases := LoadModelAliases(name); len(aliases) > 0 {
+		logger.Debugf("Aliasing %d retired models of provider %s", len(aliases), name)
+		provider = NewModelAliasProvider(name, provider, aliases)
+	}
+	if config := LoadRateLimitConfig(name); config.RPM > 0 {
+		logger.Debugf("Rate limiting provider %s to %d requests per minute", name, config.RPM)
+		provider = NewRateLimitedProvider(provider, config, r.observeRateLimit)
+	}
+	if r.embeddingCache != nil && provider.SupportsEmbeddings() {
+		provider = NewEmbeddingCachedProvider(provider, r.embeddingCache)
+	}
 	r.providers[name] = provider
 	return nil
 }
 
+// SetRateLimitObserver sets a function told the utilization of a
+// rate-limited provider's budget, the requests of the last minute as a share
+// of providers.<name>.rate_limit.rpm, each time it admits a request
+func (r *Registry) SetRateLimitObserver(observe func(provider string, utilization float64)) {
+	r.observerMu.Lock()
+	defer r.observerMu.Unlock()
+	r.rateLimitObserver = observe
+}
+
+func (r *Registry) observeRateLimit(provider string, utilization float64) {
+	r.observerMu.RLock()
+	observe := r.rateLimitObserver
+	r.observerMu.RUnlock()
+	if observe != nil {
+		observe(provider, utilization)
+	}
+}
+
+// SetEmbeddingCacheObserver sets a function told whether each lookup in the
+// embedding cache was a hit
+func (r *Registry) SetEmbeddingCacheObserver(observe func(hit bool)) {
+	r.observerMu.Lock()
+	defer r.observerMu.Unlock()
+	r.embeddingCacheObserver = observe
+}
+
+func (r *Registry) observeEmbeddingCache(hit bool) {
+	r.observerMu.RLock()
+	observe := r.embeddingCacheObserver
+	r.observerMu.RUnlock()
+	if observe != nil {
+		observe(hit)
+	}
+}
+
 // Get retrieves a provider by name
 func (r *Registry) Get(name string) (Provider, error) {
 	logger := log.GetLogger()
+	r.mu.RLock()
 	provider, exists := r.providers[name]
+	r.mu.RUnlock()
 	if !exists {
 		logger.Errorf("Provider not found: %s", name)
 		return nil, errors.New("provider not found")
@@ -160,6 +312,8 @@ func (r *Registry) Get(name string) (Provider, error) {
 
 // ListAvailable returns all available providers
 func (r *Registry) ListAvailable() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	available := make([]string, 0)
 	for name, provider := range r.providers {
 		if provider.IsAvailable() {
@@ -170,12 +324,18 @@ func (r *Registry) ListAvailable() []string {
 }
 
 // PhaseConfig maps phases to providers (moved to models)
-// GetProviderForPhase returns the configured provider for a phase
+// GetProviderForPhase returns the configured provider for a phase, wrapped
+// with retries and the providers.fallback_chain cascade. When the provider
+// is one of the phase's pool under phases.<name>.providers, generations are
+// spread across the pool instead.
 func GetProviderForPhase(configs []models.PhaseConfig, phase models.Phase, registry *Registry) (Provider, error) {
 	logger := log.GetLogger()
 	for _, config := range configs {
 		if config.Phase == phase {
-			return registry.Get(config.Provider)
+			if pool := LoadBalanceConfig(phase); len(pool.Providers) > 1 && pool.Contains(config.Provider) {
+				return registry.GetBalanced(phase, pool)
+			}
+			return registry.GetWithFallback(config.Provider, FallbackChain(config.Provider)...)
 		}
 	}
 	logger.WithField("phase", phase).Error("No provider configured for phase")
@@ -210,6 +370,8 @@ func GetEmbeddingProvider(primaryProvider Provider, registry RegistryInterface)
 
 // ListEmbeddingCapableProviders returns all providers that support embeddings
 func (r *Registry) ListEmbeddingCapableProviders() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	capable := make([]string, 0)
 	for name, provider := range r.providers {
 		if provider.IsAvailable() && provider.SupportsEmbeddings() {
@@ -221,6 +383,8 @@ func (r *Registry) ListEmbeddingCapableProviders() []string {
 
 // ListProviders returns a list of all registered provider names
 func (r *Registry) ListProviders() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	names := make([]string, 0, len(r.providers))
 	for name := range r.providers {
 		names = append(names, name)
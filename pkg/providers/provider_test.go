@@ -47,6 +47,10 @@ func (p *TestProvider) SupportsStreaming() bool {
 	return false
 }
 
+func (p *TestProvider) Ping(ctx context.Context) (time.Duration, error) {
+	return 0, nil
+}
+
 func TestProviderRegistry(t *testing.T) {
 	// Create test providers
 	provider1 := &TestProvider{
@@ -0,0 +1,177 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/jonwraymond/prompt-alchemy/internal/log"
+	"github.com/spf13/viper"
+)
+
+// ErrRateLimited is returned instead of waiting when a provider's request
+// budget is exhausted and its rate limit is set to fail fast
+var ErrRateLimited = errors.New("provider rate limit exceeded")
+
+// RateLimitConfig bounds the requests a single provider receives
+type RateLimitConfig struct {
+	RPM      int  // requests per minute, no limit if zero
+	Burst    int  // requests allowed at once before pacing, at least 1
+	FailFast bool // return ErrRateLimited rather than wait for budget
+}
+
+// LoadRateLimitConfig reads providers.<name>.rate_limit.rpm, .burst and
+// .fail_fast
+func LoadRateLimitConfig(name string) RateLimitConfig {
+	prefix := "providers." + name + ".rate_limit."
+	return RateLimitConfig{
+		RPM:      max(viper.GetInt(prefix+"rpm"), 0),
+		Burst:    max(viper.GetInt(prefix+"burst"), 1),
+		FailFast: viper.GetBool(prefix + "fail_fast"),
+	}
+}
+
+// RateLimiter is a token bucket refilled at RPM requests per minute. It
+// also tracks the requests of the last minute to report utilization.
+type RateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration // time to refill one token
+	burst    float64
+	tokens   float64 // negative while callers wait for reserved tokens
+	last     time.Time
+	recent   []time.Time // requests of the last minute, oldest first
+	rpm      int
+	failFast bool
+}
+
+// NewRateLimiter creates a limiter with a full bucket
+func NewRateLimiter(config RateLimitConfig) *RateLimiter {
+	burst := max(config.Burst, 1)
+	return &RateLimiter{
+		interval: time.Minute / time.Duration(max(config.RPM, 1)),
+		burst:    float64(burst),
+		tokens:   float64(burst),
+		last:     time.Now(),
+		rpm:      config.RPM,
+		failFast: config.FailFast,
+	}
+}
+
+// Wait takes a token, blocking until one is available or ctx is done. With
+// FailFast it returns ErrRateLimited instead of blocking.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	l.refill(now)
+	if l.tokens < 1 && l.failFast {
+		l.mu.Unlock()
+		return ErrRateLimited
+	}
+	// Reserve a token now so concurrent callers queue behind each other
+	l.tokens--
+	wait := time.Duration(-l.tokens * float64(l.interval))
+	if wait <= 0 {
+		l.record(now)
+		l.mu.Unlock()
+		return nil
+	}
+	l.mu.Unlock()
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		l.mu.Lock()
+		l.record(time.Now())
+		l.mu.Unlock()
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// Utilization returns the requests of the last minute as a share of RPM
+func (l *RateLimiter) Utilization() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.prune(time.Now())
+	return float64(len(l.recent)) / float64(max(l.rpm, 1))
+}
+
+func (l *RateLimiter) refill(now time.Time) {
+	l.tokens = min(l.tokens+float64(now.Sub(l.last))/float64(l.interval), l.burst)
+	l.last = now
+}
+
+func (l *RateLimiter) record(now time.Time) {
+	l.prune(now)
+	l.recent = append(l.recent, now)
+}
+
+func (l *RateLimiter) prune(now time.Time) {
+	cutoff := now.Add(-time.Minute)
+	i := 0
+	for i < len(l.recent) && l.recent[i].Before(cutoff) {
+		i++
+	}
+	l.recent = l.recent[i:]
+}
+
+// RateLimitedProvider paces a provider's Generate and GetEmbedding calls
+// with its own RateLimiter
+type RateLimitedProvider struct {
+	Provider
+	limiter *RateLimiter
+	observe func(provider string, utilization float64)
+}
+
+// NewRateLimitedProvider wraps provider with a limiter of its own. observe,
+// if set, is told the limiter's utilization after each request is admitted.
+func NewRateLimitedProvider(provider Provider, config RateLimitConfig, observe func(provider string, utilization float64)) *RateLimitedProvider {
+	return &RateLimitedProvider{
+		Provider: provider,
+		limiter:  NewRateLimiter(config),
+		observe:  observe,
+	}
+}
+
+// Generate waits for the provider's budget, then generates
+func (p *RateLimitedProvider) Generate(ctx context.Context, req GenerateRequest) (*GenerateResponse, error) {
+	if err := p.wait(ctx); err != nil {
+		return nil, err
+	}
+	return p.Provider.Generate(ctx, req)
+}
+
+// GetEmbedding waits for the provider's budget, then embeds text
+func (p *RateLimitedProvider) GetEmbedding(ctx context.Context, text string, registry RegistryInterface) ([]float32, error) {
+	if err := p.wait(ctx); err != nil {
+		return nil, err
+	}
+	return p.Provider.GetEmbedding(ctx, text, registry)
+}
+
+// Utilization returns the requests of the last minute as a share of the
+// provider's RPM
+func (p *RateLimitedProvider) Utilization() float64 {
+	return p.limiter.Utilization()
+}
+
+func (p *RateLimitedProvider) wait(ctx context.Context) error {
+	if err := p.limiter.Wait(ctx); err != nil {
+		if errors.Is(err, ErrRateLimited) {
+			log.FromContext(ctx).WithField("provider", p.Name()).Warn("Provider rate limit exhausted")
+			return fmt.Errorf("%s: %w", p.Name(), err)
+		}
+		return err
+	}
+	if p.observe != nil {
+		p.observe(p.Name(), p.limiter.Utilization())
+	}
+	return nil
+}
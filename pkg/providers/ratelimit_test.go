@@ -0,0 +1,119 @@
+package providers
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistryRateLimit(t *testing.T) {
+	defer viper.Reset()
+	// 600 RPM admits one request every 100ms
+	viper.Set("providers.paced.rate_limit.rpm", 600)
+
+	newRegistry := func() *Registry {
+		registry := NewRegistry()
+		require.NoError(t, registry.Register("paced", &TestProvider{name: "paced", available: true, supportsEmbeddings: true}))
+		return registry
+	}
+
+	// fire calls n concurrent requests through the registry's provider and
+	// returns how long they took
+	fire := func(registry *Registry, n int) time.Duration {
+		provider, err := registry.Get("paced")
+		require.NoError(t, err)
+		start := time.Now()
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				if i%2 == 0 {
+					_, err := provider.Generate(context.Background(), GenerateRequest{Prompt: "test"})
+					assert.NoError(t, err)
+				} else {
+					_, err := provider.GetEmbedding(context.Background(), "test", registry)
+					assert.NoError(t, err)
+				}
+			}(i)
+		}
+		wg.Wait()
+		return time.Since(start)
+	}
+
+	t.Run("concurrent calls are paced", func(t *testing.T) {
+		elapsed := fire(newRegistry(), 5)
+		assert.GreaterOrEqual(t, elapsed, 380*time.Millisecond, "the first call is immediate, the other four wait their turn")
+		assert.Less(t, elapsed, 900*time.Millisecond)
+	})
+
+	t.Run("limits are per registered instance", func(t *testing.T) {
+		first, second := newRegistry(), newRegistry()
+		var wg sync.WaitGroup
+		var elapsed [2]time.Duration
+		for i, registry := range []*Registry{first, second} {
+			wg.Add(1)
+			go func(i int, registry *Registry) {
+				defer wg.Done()
+				elapsed[i] = fire(registry, 3)
+			}(i, registry)
+		}
+		wg.Wait()
+		for _, e := range elapsed {
+			assert.Less(t, e, 500*time.Millisecond, "each registry has its own budget")
+		}
+	})
+
+	t.Run("fail fast", func(t *testing.T) {
+		viper.Set("providers.paced.rate_limit.fail_fast", true)
+		defer viper.Set("providers.paced.rate_limit.fail_fast", false)
+
+		provider, err := newRegistry().Get("paced")
+		require.NoError(t, err)
+		_, err = provider.Generate(context.Background(), GenerateRequest{Prompt: "test"})
+		require.NoError(t, err)
+		_, err = provider.Generate(context.Background(), GenerateRequest{Prompt: "test"})
+		assert.ErrorIs(t, err, ErrRateLimited)
+		assert.True(t, IsTransientError(err), "exhausted budgets cascade to fallbacks")
+	})
+
+	t.Run("cancelled wait", func(t *testing.T) {
+		provider, err := newRegistry().Get("paced")
+		require.NoError(t, err)
+		_, err = provider.Generate(context.Background(), GenerateRequest{Prompt: "test"})
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err = provider.Generate(ctx, GenerateRequest{Prompt: "test"})
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("utilization is observed", func(t *testing.T) {
+		registry := newRegistry()
+		observed := map[string]float64{}
+		var mu sync.Mutex
+		registry.SetRateLimitObserver(func(provider string, utilization float64) {
+			mu.Lock()
+			defer mu.Unlock()
+			observed[provider] = utilization
+		})
+
+		fire(registry, 3)
+		assert.InDelta(t, 3.0/600, observed["paced"], 1e-9)
+	})
+
+	t.Run("unlimited providers are not wrapped", func(t *testing.T) {
+		registry := NewRegistry()
+		plain := &TestProvider{name: "plain", available: true}
+		require.NoError(t, registry.Register("plain", plain))
+		provider, err := registry.Get("plain")
+		require.NoError(t, err)
+		assert.Same(t, plain, provider)
+	})
+}
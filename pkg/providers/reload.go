@@ -0,0 +1,129 @@
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	log "github.com/jonwraymond/prompt-alchemy/internal/log"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// RegistryChanges lists the providers a reload added, removed or
+// reconfigured
+type RegistryChanges struct {
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+	Changed []string `json:"changed"`
+}
+
+// Replace swaps in next's providers in one step, so requests see either the
+// old set or the new one. Requests already holding a provider finish with
+// it. A provider in both sets is reported changed when changed(name) says
+// so; the circuit breakers of removed and changed providers start over.
+func (r *Registry) Replace(next *Registry, changed func(name string) bool) RegistryChanges {
+	// next's rate-limited providers and embedding cache report to next's
+	// observers
+	r.observerMu.RLock()
+	next.SetRateLimitObserver(r.rateLimitObserver)
+	next.SetEmbeddingCacheObserver(r.embeddingCacheObserver)
+	r.observerMu.RUnlock()
+
+	next.mu.RLock()
+	incoming := make(map[string]Provider, len(next.providers))
+	for name, provider := range next.providers {
+		incoming[name] = provider
+	}
+	next.mu.RUnlock()
+
+	r.mu.Lock()
+	previous := r.providers
+	r.providers = incoming
+	r.mu.Unlock()
+
+	changes := RegistryChanges{Added: []string{}, Removed: []string{}, Changed: []string{}}
+	for name := range incoming {
+		if _, existed := previous[name]; !existed {
+			changes.Added = append(changes.Added, name)
+		} else if changed != nil && changed(name) {
+			changes.Changed = append(changes.Changed, name)
+		}
+	}
+	for name := range previous {
+		if _, kept := incoming[name]; !kept {
+			changes.Removed = append(changes.Removed, name)
+		}
+	}
+	sort.Strings(changes.Added)
+	sort.Strings(changes.Removed)
+	sort.Strings(changes.Changed)
+
+	r.healthMu.Lock()
+	for _, name := range changes.Removed {
+		delete(r.breakers, name)
+	}
+	for _, name := range changes.Changed {
+		delete(r.breakers, name)
+	}
+	r.healthMu.Unlock()
+	return changes
+}
+
+// Reloader rebuilds a registry from the configuration file while the
+// process keeps serving
+type Reloader struct {
+	mu       sync.Mutex // one reload at a time
+	registry *Registry
+	register func(*Registry) error
+}
+
+// NewReloader returns a Reloader for registry. register populates an empty
+// registry from the current configuration, as at startup.
+func NewReloader(registry *Registry, register func(*Registry) error) *Reloader {
+	return &Reloader{registry: registry, register: register}
+}
+
+// Reload re-reads the configuration file and registers the providers it
+// configures into a new registry, which then replaces the live one's
+// providers. Providers are reported changed when their providers.<name>
+// settings differ. If the file can't be read or registration fails, the
+// live registry is left as it was.
+func (l *Reloader) Reload() (RegistryChanges, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	before := make(map[string]string)
+	for _, name := range l.registry.ListProviders() {
+		before[name] = providerSettings(name)
+	}
+
+	if err := viper.ReadInConfig(); err != nil {
+		return RegistryChanges{}, fmt.Errorf("failed to read config: %w", err)
+	}
+	next := NewRegistry()
+	if err := l.register(next); err != nil {
+		return RegistryChanges{}, fmt.Errorf("failed to register providers: %w", err)
+	}
+
+	changes := l.registry.Replace(next, func(name string) bool {
+		return before[name] != providerSettings(name)
+	})
+	log.GetLogger().WithFields(logrus.Fields{
+		"added":   changes.Added,
+		"removed": changes.Removed,
+		"changed": changes.Changed,
+	}).Info("Reloaded provider configuration")
+	return changes, nil
+}
+
+// providerSettings returns the providers.<name> settings in a comparable
+// form
+func providerSettings(name string) string {
+	settings, err := json.Marshal(viper.Get("providers." + name))
+	if err != nil {
+		return ""
+	}
+	return string(settings)
+}
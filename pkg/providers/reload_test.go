@@ -0,0 +1,72 @@
+package providers
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReloader(t *testing.T) {
+	defer viper.Reset()
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig := func(content string) {
+		require.NoError(t, os.WriteFile(configFile, []byte(content), 0o600))
+	}
+	// register adds a provider for each providers.<name> with an api_key
+	register := func(registry *Registry) error {
+		for name := range viper.GetStringMap("providers") {
+			if viper.GetString("providers."+name+".api_key") != "" {
+				if err := registry.Register(name, &TestProvider{name: name, available: true}); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	writeConfig("providers:\n  alpha:\n    api_key: a1\n  gamma:\n    api_key: g1\n")
+	viper.SetConfigFile(configFile)
+	require.NoError(t, viper.ReadInConfig())
+	registry := NewRegistry()
+	require.NoError(t, register(registry))
+	reloader := NewReloader(registry, register)
+
+	live, err := registry.Get("alpha")
+	require.NoError(t, err)
+	registry.recordHealth("alpha", nil, 0)
+
+	writeConfig("providers:\n  alpha:\n    api_key: a2\n  beta:\n    api_key: b1\n  gamma:\n    api_key: g1\n")
+	changes, err := reloader.Reload()
+	require.NoError(t, err)
+	assert.Equal(t, RegistryChanges{Added: []string{"beta"}, Removed: []string{}, Changed: []string{"alpha"}}, changes)
+	_, err = registry.Get("beta")
+	assert.NoError(t, err, "the new provider is available without a restart")
+	reloaded, err := registry.Get("alpha")
+	require.NoError(t, err)
+	assert.NotSame(t, live, reloaded, "a changed provider is rebuilt")
+	assert.Equal(t, "alpha", live.Name(), "callers holding the old provider can still use it")
+	assert.NotContains(t, registry.breakers, "alpha", "a changed provider's breaker starts over")
+
+	t.Run("a broken config leaves the registry as it was", func(t *testing.T) {
+		writeConfig("providers: [unterminated\n")
+		_, err := reloader.Reload()
+		require.Error(t, err)
+		names := registry.ListProviders()
+		sort.Strings(names)
+		assert.Equal(t, []string{"alpha", "beta", "gamma"}, names)
+	})
+
+	t.Run("removed providers are reported", func(t *testing.T) {
+		writeConfig("providers:\n  beta:\n    api_key: b1\n")
+		changes, err := reloader.Reload()
+		require.NoError(t, err)
+		assert.Equal(t, RegistryChanges{Added: []string{}, Removed: []string{"alpha", "gamma"}, Changed: []string{}}, changes)
+		_, err = registry.Get("alpha")
+		assert.Error(t, err)
+	})
+}
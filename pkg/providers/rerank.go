@@ -0,0 +1,47 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+)
+
+// Reranker is implemented by providers with a dedicated rerank endpoint,
+// which scores documents against a query in one request instead of asking a
+// chat model about each
+type Reranker interface {
+	Provider
+
+	// Rerank returns the relevance of each document to query, from 0
+	// (irrelevant) to 1, in the order of docs
+	Rerank(ctx context.Context, query string, docs []string) ([]float64, error)
+}
+
+// AsReranker returns provider as a Reranker when it implements Rerank,
+// looking through the embedding cache, rate limiter and model aliases
+func AsReranker(provider Provider) (Reranker, bool) {
+	if cached, ok := provider.(*EmbeddingCachedProvider); ok {
+		return AsReranker(cached.Provider)
+	}
+	if aliased, ok := provider.(*ModelAliasProvider); ok {
+		return AsReranker(aliased.Provider)
+	}
+	if limited, ok := provider.(*RateLimitedProvider); ok {
+		if _, ok := AsReranker(limited.Provider); !ok {
+			return nil, false
+		}
+	}
+	reranker, ok := provider.(Reranker)
+	return reranker, ok
+}
+
+// Rerank waits for the provider's budget, then reranks docs
+func (p *RateLimitedProvider) Rerank(ctx context.Context, query string, docs []string) ([]float64, error) {
+	reranker, ok := AsReranker(p.Provider)
+	if !ok {
+		return nil, fmt.Errorf("%s does not support reranking", p.Name())
+	}
+	if err := p.wait(ctx); err != nil {
+		return nil, err
+	}
+	return reranker.Rerank(ctx, query, docs)
+}
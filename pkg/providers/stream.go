@@ -0,0 +1,144 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/jonwraymond/prompt-alchemy/internal/log"
+)
+
+// StreamingProvider is implemented by providers that can send generated text
+// as it arrives rather than all at once
+type StreamingProvider interface {
+	Provider
+
+	// GenerateStream creates a prompt like Generate, sending its text on the
+	// returned channel. The last chunk has Done set, and Error if the
+	// stream failed.
+	GenerateStream(ctx context.Context, req GenerateRequest) (<-chan GenerateResponseChunk, error)
+}
+
+// AsStreaming returns provider as a StreamingProvider when it can stream with
+// its current configuration. Providers that report SupportsStreaming without
+// implementing GenerateStream can't.
+func AsStreaming(provider Provider) (StreamingProvider, bool) {
+	if cached, ok := provider.(*EmbeddingCachedProvider); ok {
+		return AsStreaming(cached.Provider)
+	}
+	if limited, ok := provider.(*RateLimitedProvider); ok {
+		if _, ok := AsStreaming(limited.Provider); !ok {
+			return nil, false
+		}
+	}
+	if fallback, ok := provider.(*FallbackProvider); ok {
+		if _, ok := AsStreaming(fallback.chain[0]); !ok {
+			return nil, false
+		}
+	}
+	if aliased, ok := provider.(*ModelAliasProvider); ok {
+		if _, ok := AsStreaming(aliased.Provider); !ok {
+			return nil, false
+		}
+	}
+	streamer, ok := provider.(StreamingProvider)
+	if !ok || !provider.SupportsStreaming() {
+		return nil, false
+	}
+	return streamer, true
+}
+
+// GenerateStream waits for the provider's budget, then streams from it
+func (p *RateLimitedProvider) GenerateStream(ctx context.Context, req GenerateRequest) (<-chan GenerateResponseChunk, error) {
+	streamer, ok := AsStreaming(p.Provider)
+	if !ok {
+		return nil, fmt.Errorf("%s does not support streaming", p.Name())
+	}
+	if err := p.wait(ctx); err != nil {
+		return nil, err
+	}
+	return streamer.GenerateStream(ctx, req)
+}
+
+// GenerateStream streams from the first provider in the chain that accepts
+// the request. Like Generate, only transient errors cascade, but only when
+// opening the stream: once text has been sent a failure ends the stream.
+// Fallbacks that can't stream generate their output as one chunk. The last
+// chunk records which provider served it and, after a fallback, which one
+// failed.
+func (p *FallbackProvider) GenerateStream(ctx context.Context, req GenerateRequest) (<-chan GenerateResponseChunk, error) {
+	logger := log.FromContext(ctx)
+	primary := p.chain[0].Name()
+
+	var lastErr error
+	for i, provider := range p.chain {
+		if p.allow != nil && !p.allow(provider.Name()) {
+			logger.WithField("provider", provider.Name()).Debug("Skipping provider with open circuit breaker")
+			lastErr = circuitOpenError(provider.Name())
+			continue
+		}
+		attempt := req
+		attempt.Temperature = ClampTemperature(provider.Name(), req.Temperature)
+
+		var chunks <-chan GenerateResponseChunk
+		var err error
+		if streamer, ok := AsStreaming(provider); ok {
+			chunks, err = streamer.GenerateStream(ctx, attempt)
+		} else {
+			chunks, err = generateAsStream(ctx, provider, attempt, p.retry)
+		}
+		if err == nil {
+			fallbackFrom := ""
+			if i > 0 {
+				fallbackFrom = primary
+			}
+			return attributeStream(chunks, provider.Name(), fallbackFrom), nil
+		}
+		lastErr = err
+		if ctx.Err() != nil || !IsTransientError(err) {
+			break
+		}
+		if i+1 < len(p.chain) {
+			logger.WithError(err).WithFields(map[string]interface{}{
+				"provider": provider.Name(),
+				"fallback": p.chain[i+1].Name(),
+			}).Warn("Provider failed to stream, falling back")
+		}
+	}
+	return nil, lastErr
+}
+
+// generateAsStream generates with a provider that can't stream, sending the
+// whole response as the stream's only chunk
+func generateAsStream(ctx context.Context, provider Provider, req GenerateRequest, retry RetryConfig) (<-chan GenerateResponseChunk, error) {
+	resp, err := GenerateWithRetry(ctx, provider, req, retry)
+	if err != nil {
+		return nil, err
+	}
+	chunks := make(chan GenerateResponseChunk, 1)
+	chunks <- GenerateResponseChunk{
+		ContentDelta:      resp.Content,
+		TokensUsed:        resp.TokensUsed,
+		Model:             resp.Model,
+		Done:              true,
+		SystemFingerprint: resp.SystemFingerprint,
+	}
+	close(chunks)
+	return chunks, nil
+}
+
+// attributeStream passes chunks on, marking the last with the provider that
+// served the stream
+func attributeStream(chunks <-chan GenerateResponseChunk, provider, fallbackFrom string) <-chan GenerateResponseChunk {
+	out := make(chan GenerateResponseChunk)
+	go func() {
+		defer close(out)
+		for chunk := range chunks {
+			if chunk.Done {
+				chunk.Provider = provider
+				chunk.FallbackFrom = fallbackFrom
+			}
+			out <- chunk
+		}
+	}()
+	return out
+}
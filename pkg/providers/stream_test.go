@@ -0,0 +1,108 @@
+package providers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// streamingTestProvider streams its response one word at a time
+type streamingTestProvider struct {
+	TestProvider
+	words []string
+}
+
+func (p *streamingTestProvider) SupportsStreaming() bool { return true }
+
+func (p *streamingTestProvider) GenerateStream(ctx context.Context, req GenerateRequest) (<-chan GenerateResponseChunk, error) {
+	chunks := make(chan GenerateResponseChunk, len(p.words)+1)
+	for _, word := range p.words {
+		chunks <- GenerateResponseChunk{ContentDelta: word}
+	}
+	chunks <- GenerateResponseChunk{Done: true}
+	close(chunks)
+	return chunks, nil
+}
+
+func TestAsStreaming(t *testing.T) {
+	plain := &TestProvider{name: "plain", available: true}
+	streaming := &streamingTestProvider{TestProvider: TestProvider{name: "streaming", available: true}, words: []string{"a ", "b"}}
+
+	_, ok := AsStreaming(plain)
+	assert.False(t, ok)
+	_, ok = AsStreaming(NewRateLimitedProvider(plain, RateLimitConfig{RPM: 60}, nil))
+	assert.False(t, ok, "a rate limit does not make a provider stream")
+
+	_, ok = AsStreaming(streaming)
+	assert.True(t, ok)
+
+	streamer, ok := AsStreaming(NewRateLimitedProvider(streaming, RateLimitConfig{RPM: 60}, nil))
+	require.True(t, ok, "rate limited providers keep streaming")
+	chunks, err := streamer.GenerateStream(context.Background(), GenerateRequest{Prompt: "test"})
+	require.NoError(t, err)
+	var content string
+	for chunk := range chunks {
+		content += chunk.ContentDelta
+	}
+	assert.Equal(t, "a b", content)
+}
+
+func TestFallbackProvider_GenerateStream(t *testing.T) {
+	retry := RetryConfig{MaxRetries: 0, BaseDelay: time.Millisecond}
+	collect := func(chunks <-chan GenerateResponseChunk) (string, GenerateResponseChunk) {
+		var content string
+		var last GenerateResponseChunk
+		for chunk := range chunks {
+			content += chunk.ContentDelta
+			last = chunk
+		}
+		return content, last
+	}
+
+	t.Run("streams from the primary", func(t *testing.T) {
+		streaming := &streamingTestProvider{TestProvider: TestProvider{name: "streaming", available: true}, words: []string{"a ", "b"}}
+		fallback := NewFallbackProvider(retry, streaming, &TestProvider{name: "backup", available: true})
+
+		streamer, ok := AsStreaming(fallback)
+		require.True(t, ok, "a fallback chain streams when its primary does")
+		chunks, err := streamer.GenerateStream(context.Background(), GenerateRequest{Prompt: "test"})
+		require.NoError(t, err)
+		content, last := collect(chunks)
+		assert.Equal(t, "a b", content)
+		assert.Equal(t, "streaming", last.Provider)
+		assert.Empty(t, last.FallbackFrom)
+	})
+
+	t.Run("falls back when the primary can't open a stream", func(t *testing.T) {
+		failing := &failingStreamProvider{TestProvider: TestProvider{name: "failing", available: true}}
+		backup, _ := flakyProvider("backup", 0, nil)
+		fallback := NewFallbackProvider(retry, failing, backup)
+
+		chunks, err := fallback.GenerateStream(context.Background(), GenerateRequest{Prompt: "test"})
+		require.NoError(t, err)
+		content, last := collect(chunks)
+		assert.Equal(t, "from backup", content, "a fallback that can't stream sends one chunk")
+		assert.True(t, last.Done)
+		assert.Equal(t, "backup", last.Provider)
+		assert.Equal(t, "failing", last.FallbackFrom)
+	})
+
+	t.Run("a plain primary doesn't stream", func(t *testing.T) {
+		_, ok := AsStreaming(NewFallbackProvider(retry, &TestProvider{name: "plain", available: true}))
+		assert.False(t, ok)
+	})
+}
+
+// failingStreamProvider can stream but is rate limited
+type failingStreamProvider struct {
+	TestProvider
+}
+
+func (p *failingStreamProvider) SupportsStreaming() bool { return true }
+
+func (p *failingStreamProvider) GenerateStream(ctx context.Context, req GenerateRequest) (<-chan GenerateResponseChunk, error) {
+	return nil, errRateLimited
+}
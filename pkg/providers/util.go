@@ -2,19 +2,101 @@ package providers
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/cenkalti/backoff/v4"
 	"github.com/jonwraymond/prompt-alchemy/internal/log"
 )
 
+// pingHTTP times an authenticated GET against url. Any response proves the
+// API is reachable, but rejected credentials and server errors are reported
+// so callers can tell a healthy provider from a merely reachable one.
+func pingHTTP(ctx context.Context, url string, headers map[string]string) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create ping request: %w", err)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	start := time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	elapsed := time.Since(start)
+	if err != nil {
+		return elapsed, err
+	}
+	_ = resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return elapsed, fmt.Errorf("authentication failed: status code %d", resp.StatusCode)
+	case resp.StatusCode >= 500:
+		return elapsed, fmt.Errorf("server error: status code %d", resp.StatusCode)
+	}
+	return elapsed, nil
+}
+
+// modelsPage is a page of a models API. OpenAI-compatible APIs and
+// Anthropic list models by ID under data, Google and Cohere by name under
+// models.
+type modelsPage struct {
+	Data []struct {
+		ID string `json:"id"`
+	} `json:"data"`
+	Models []struct {
+		Name string `json:"name"`
+	} `json:"models"`
+}
+
+// listModelsHTTP lists the models of the API at url with an authenticated
+// GET. Google's "models/" name prefix is dropped, so every provider returns
+// the IDs its generation requests take.
+func listModelsHTTP(ctx context.Context, url string, headers map[string]string) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create models request: %w", err)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return nil, fmt.Errorf("failed to list models: %w", ErrRateLimited)
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("failed to list models: status code %d", resp.StatusCode)
+	}
+
+	var page modelsPage
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return nil, fmt.Errorf("failed to decode models: %w", err)
+	}
+	models := make([]string, 0, len(page.Data)+len(page.Models))
+	for _, model := range page.Data {
+		models = append(models, model.ID)
+	}
+	for _, model := range page.Models {
+		models = append(models, strings.TrimPrefix(model.Name, "models/"))
+	}
+	return models, nil
+}
+
 // WithRetry executes an HTTP request with exponential backoff
 func WithRetry(ctx context.Context, config Config, fn func() (*http.Response, error)) (*http.Response, error) {
 	// Note: backoff library manages retry count internally
 
-	logger := log.GetLogger()
+	logger := log.FromContext(ctx)
 	b := backoff.NewExponentialBackOff()
 	b.MaxElapsedTime = 30 * time.Second
 
@@ -15,11 +15,23 @@ var AllowedHosts = map[string]bool{
 	"api.openrouter.ai":                 true,
 	"openrouter.ai":                     true, // OpenRouter uses this domain too
 	"api.x.ai":                          true,
+	"api.mistral.ai":                    true,
+	"api.deepseek.com":                  true,
+	"api.cohere.com":                    true,
+	"api.perplexity.ai":                 true,
 	"localhost":                         true,
 	"127.0.0.1":                         true,
 	"0.0.0.0":                           true,
 }
 
+// AllowedHostSuffixes lists domains whose every subdomain is allowed, for
+// services that give each customer a host of their own
+var AllowedHostSuffixes = []string{
+	".openai.azure.com",
+	".cognitiveservices.azure.com",
+	".amazonaws.com", // regional AWS endpoints such as bedrock-runtime.us-east-1.amazonaws.com
+}
+
 // ValidateURL checks if a URL is safe to use for HTTP requests
 func ValidateURL(urlStr string) error {
 	if urlStr == "" {
@@ -76,8 +88,17 @@ func ValidateURL(urlStr string) error {
 func isAllowedHost(hostname string) bool {
 	hostname = strings.ToLower(hostname)
 
-	// Direct match only - no automatic subdomain allowance for security
-	return AllowedHosts[hostname]
+	// Direct match only - no automatic subdomain allowance for security,
+	// except under the explicitly listed per-customer domains
+	if AllowedHosts[hostname] {
+		return true
+	}
+	for _, suffix := range AllowedHostSuffixes {
+		if strings.HasSuffix(hostname, suffix) {
+			return true
+		}
+	}
+	return false
 }
 
 // isPrivateIP checks if an IP is in a private range
@@ -26,6 +26,21 @@ func TestValidateURL(t *testing.T) {
 			url:     "http://127.0.0.1:11434/api/generate",
 			wantErr: false,
 		},
+		{
+			name:    "Valid Azure OpenAI resource URL",
+			url:     "https://my-resource.openai.azure.com/openai/deployments/gpt-4o/chat/completions",
+			wantErr: false,
+		},
+		{
+			name:    "Valid AWS Bedrock runtime URL",
+			url:     "https://bedrock-runtime.us-east-1.amazonaws.com/model/anthropic.claude-3-5-sonnet-20240620-v1:0/invoke",
+			wantErr: false,
+		},
+		{
+			name:    "Azure suffix inside another domain",
+			url:     "https://my-resource.openai.azure.com.evil.com/",
+			wantErr: true,
+		},
 
 		// Invalid URLs - SSRF attempts
 		{